@@ -15,6 +15,8 @@ import (
 	"flag"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
@@ -23,12 +25,15 @@ import (
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
 	"github.com/pravega/pravega-operator/pkg/controller"
 	controllerconfig "github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/controller/pravegacluster"
+	"github.com/pravega/pravega-operator/pkg/util"
 	"github.com/pravega/pravega-operator/pkg/version"
 	log "github.com/sirupsen/logrus"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -36,14 +41,32 @@ import (
 )
 
 var (
-	versionFlag bool
-	webhookFlag bool
+	versionFlag    bool
+	webhookFlag    bool
+	namespaceFlag  string
+	leaderElection util.LeaderElectionOptions
+	metrics        util.MetricsOptions
 )
 
+// leaderElectionID is the name of the lock held by the operator leader
+const leaderElectionID = "pravega-operator-lock"
+
 func init() {
 	flag.BoolVar(&versionFlag, "version", false, "Show version and quit")
 	flag.BoolVar(&controllerconfig.TestMode, "test", false, "Enable test mode. Do not use this flag in production")
 	flag.BoolVar(&webhookFlag, "webhook", true, "Enable webhook, the default is enabled.")
+	flag.BoolVar(&controllerconfig.NodeWatch, "node-watch", false, "Enable restarting segment store pods on node annotation changes. Requires get, list and watch permissions on nodes.")
+	flag.BoolVar(&controllerconfig.GrafanaDashboard, "grafana-dashboard", false, "Enable creating a Grafana dashboard ConfigMap for each Pravega cluster.")
+	flag.BoolVar(&controllerconfig.StorageClassCheck, "storage-class-check", false, "Enable checking that the storage classes of the segment store cache and journal claims exist before creating the segment store stateful set.")
+	flag.BoolVar(&controllerconfig.ThroughputStatus, "throughput-status", false, "Enable recording the segment store write throughput, scraped from their Prometheus endpoint, in the cluster status.")
+	flag.DurationVar(&controllerconfig.ThroughputStatusInterval, "throughput-status-interval", time.Minute, "Minimal delay between two throughput samples.")
+	flag.BoolVar(&controllerconfig.ForceDeleteStuckPods, "force-delete-stuck-pods", false, "Enable force deleting the segment store pods stuck terminating during an upgrade for longer than the terminating pod timeout of the cluster.")
+	flag.StringVar(&controllerconfig.HealthAddr, "health-addr", "", "Address of the endpoint summarizing the health of the managed clusters, e.g. :8081. Disabled if empty.")
+	flag.StringVar(&namespaceFlag, "namespace", "", "Comma-separated namespaces whose clusters are reconciled, overriding the WATCH_NAMESPACE environment variable. All the namespaces if both are empty.")
+	flag.DurationVar(&controllerconfig.MaxReconcileBackoff, "max-reconcile-backoff", controllerconfig.DefaultMaxReconcileBackoff, "Maximal delay before requeueing a cluster after consecutive failed reconciles.")
+	flag.IntVar(&controllerconfig.FullReconcileInterval, "full-reconcile-interval", controllerconfig.DefaultFullReconcileInterval, "Number of consecutive reconciles of an unchanged and healthy cluster after which all its child objects are rebuilt and compared again, the others only sync its status. Every reconcile is a full one if below 2.")
+	leaderElection.AddFlags(flag.CommandLine)
+	metrics.AddFlags(flag.CommandLine)
 }
 
 func printVersion() {
@@ -64,13 +87,40 @@ func main() {
 		os.Exit(0)
 	}
 
+	if leaderElection.Lease {
+		if err := leaderElection.Validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := metrics.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
 	if controllerconfig.TestMode {
 		log.Warn("----- Running in test mode. Make sure you are NOT in production -----")
 	}
 
-	namespace, err := k8sutil.GetWatchNamespace()
-	if err != nil {
-		log.Fatal(err, "failed to get watch namespace")
+	namespace := namespaceFlag
+	if namespace == "" {
+		namespace = os.Getenv(k8sutil.WatchNamespaceEnvVar)
+	}
+	controllerconfig.WatchNamespaces = util.ParseWatchNamespaces(namespace)
+	options := manager.Options{MetricsBindAddress: metrics.ManagerBindAddress()}
+	if metrics.Enabled {
+		log.Printf("Serving the operator metrics on %s", metrics.BindAddress)
+	} else {
+		log.Print("Operator metrics disabled")
+	}
+	switch len(controllerconfig.WatchNamespaces) {
+	case 0:
+		log.Print("Watching all namespaces")
+	case 1:
+		options.Namespace = controllerconfig.WatchNamespaces[0]
+		log.Printf("Watching namespace %s", options.Namespace)
+	default:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(controllerconfig.WatchNamespaces)
+		log.Printf("Watching namespaces %s", strings.Join(controllerconfig.WatchNamespaces, ", "))
 	}
 
 	// Get a config to talk to the apiserver
@@ -79,11 +129,14 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Become the leader before proceeding
-	leader.Become(context.TODO(), "pravega-operator-lock")
+	// Become the leader before proceeding, unless the Lease based leader election
+	// is enabled, in which case the manager is only started by the leader
+	if !leaderElection.Lease {
+		leader.Become(context.TODO(), leaderElectionID)
+	}
 
 	// Create a new Cmd to provide shared dependencies and start components
-	mgr, err := manager.New(cfg, manager.Options{Namespace: namespace})
+	mgr, err := manager.New(cfg, options)
 
 	if err != nil {
 		log.Fatal(err)
@@ -101,6 +154,12 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if controllerconfig.HealthAddr != "" {
+		if err := mgr.Add(pravegacluster.Health.HealthServer(controllerconfig.HealthAddr)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	v1beta1.Mgr = mgr
 	if webhookFlag {
 		if err := (&v1beta1.PravegaCluster{}).SetupWebhookWithManager(mgr); err != nil {
@@ -112,7 +171,17 @@ func main() {
 	log.Print("Starting the Cmd")
 
 	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	stop := signals.SetupSignalHandler()
+	if leaderElection.Lease {
+		var operatorNamespace string
+		if operatorNamespace, err = k8sutil.GetOperatorNamespace(); err != nil {
+			log.Fatalf("the Lease based leader election requires running in a cluster: %v", err)
+		}
+		err = util.RunAsLeader(cfg, operatorNamespace, leaderElectionID, leaderElection, stop, mgr.Start)
+	} else {
+		err = mgr.Start(stop)
+	}
+	if err != nil {
 		log.Fatal(err, "manager exited non-zero")
 	}
 }
@@ -11,11 +11,17 @@
 package v1beta1
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+
 	"github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/util"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -31,6 +37,44 @@ const (
 	// Pravega SegmentStore cache volume
 	DefaultPravegaCacheVolumeSize = "20Gi"
 
+	// MinJournalVolumeSize and MaxJournalVolumeSize bound the size of the
+	// Pravega SegmentStore journal volume
+	MinJournalVolumeSize = "1Gi"
+	MaxJournalVolumeSize = "16Ti"
+
+	// JVM flavors selecting the baseline JVM options of the Controller and the
+	// Segment Store. With the custom flavor, the operator sets no baseline option
+	// and only the JVM options of the spec are used.
+	JVMFlavorHotSpot = "hotspot"
+	JVMFlavorTemurin = "temurin"
+	JVMFlavorGraalVM = "graalvm"
+	JVMFlavorOpenJ9  = "openj9"
+	JVMFlavorCustom  = "custom"
+
+	// ConfigMap reconcile policies. With the ignore policy, the operator creates the
+	// configmaps of the Controller and the Segment Store but leaves manual edits in place.
+	ConfigMapReconcilePolicyEnforce = "Enforce"
+	ConfigMapReconcilePolicyIgnore  = "Ignore"
+
+	// Reclaim policies of the cache claims of the segment stores removed by a scale-down.
+	// With the retain policy, the claims are kept and reused if the segment stores are
+	// scaled up again.
+	SegmentStoreCachePVCReclaimPolicyRetain = "Retain"
+	SegmentStoreCachePVCReclaimPolicyDelete = "Delete"
+
+	// Update strategies of the segment stores. With the on delete strategy, the operator
+	// updates the stateful set but leaves the deletion of the outdated pods to the user.
+	SegmentStoreUpdateStrategyRollingUpdate = "RollingUpdate"
+	SegmentStoreUpdateStrategyOnDelete      = "OnDelete"
+
+	// RestartAnnotation is the PravegaCluster annotation whose value changes trigger a
+	// rolling restart of the Controller and the Segment Store pods
+	RestartAnnotation = "pravega.pravega.io/restart"
+
+	// AllowDowngradeAnnotation is the PravegaCluster annotation which, set to "true", lets
+	// the webhook accept a version lower than the current one
+	AllowDowngradeAnnotation = "pravega.pravega.io/allow-downgrade"
+
 	// DefaultPravegaLTSClaimName is the default volume claim name used as Tier 2
 	DefaultPravegaLTSClaimName = "pravega-tier2"
 
@@ -65,6 +109,52 @@ const (
 
 	// DefaultSegmentStoreLimitMemory is the default memory limit for Pravega
 	DefaultSegmentStoreLimitMemory = "2Gi"
+
+	// DefaultJVMMemoryOverheadFactor is the default ratio of the container memory to
+	// the JVM heap and direct memory, when the memory is derived from the JVM options
+	DefaultJVMMemoryOverheadFactor = "1.5"
+
+	// DefaultControllerReadinessProbeInitialDelaySeconds is the default initial delay
+	// of the Controller readiness probe. Controller pods start fast, they are given
+	// up to 20 seconds to become ready
+	DefaultControllerReadinessProbeInitialDelaySeconds = 20
+
+	// DefaultControllerReadinessProbePeriodSeconds is the default period of the
+	// Controller readiness probe
+	DefaultControllerReadinessProbePeriodSeconds = 10
+
+	// DefaultControllerReadinessProbeFailureThreshold is the default failure threshold
+	// of the Controller readiness probe
+	DefaultControllerReadinessProbeFailureThreshold = 3
+
+	// DefaultControllerReadinessProbeTimeoutSeconds is the default timeout of the
+	// Controller readiness probe
+	DefaultControllerReadinessProbeTimeoutSeconds = 60
+
+	// DefaultControllerLivenessProbeInitialDelaySeconds is the default initial delay
+	// of the Controller liveness probe, which starts from the maximum time the pod
+	// can take before becoming ready
+	DefaultControllerLivenessProbeInitialDelaySeconds = 60
+
+	// DefaultControllerLivenessProbePeriodSeconds is the default period of the
+	// Controller liveness probe
+	DefaultControllerLivenessProbePeriodSeconds = 15
+
+	// DefaultControllerLivenessProbeFailureThreshold is the default failure threshold
+	// of the Controller liveness probe. If the pod fails the health check during
+	// 1 minute, Kubernetes restarts it
+	DefaultControllerLivenessProbeFailureThreshold = 4
+
+	// DefaultControllerLivenessProbeTimeoutSeconds is the default timeout of the
+	// Controller liveness probe
+	DefaultControllerLivenessProbeTimeoutSeconds = 1
+
+	// DefaultLoggingSidecarMountPath is the default path where the Controller and
+	// Segment Store write their log files and the logging sidecar tails them
+	DefaultLoggingSidecarMountPath = "/opt/pravega/logs"
+
+	// LoggingSidecarName is the name of the logging sidecar container
+	LoggingSidecarName = "logging-sidecar"
 )
 
 // PravegaSpec defines the configuration of Pravega
@@ -76,11 +166,28 @@ type PravegaSpec struct {
 	ControllerReplicas int32 `json:"controllerReplicas"`
 
 	// SegmentStoreReplicas defines the number of Segment Store replicas.
-	// Defaults to 1.
+	// Defaults to 1. It cannot be scaled down to 0 unless AllowZeroSegmentStores is set.
 	// +kubebuilder:validation:Minimum=0
 	// +optional
 	SegmentStoreReplicas int32 `json:"segmentStoreReplicas"`
 
+	// AllowZeroSegmentStores allows running the cluster without Segment Stores, e.g. to
+	// pause its data plane while keeping its Controllers. Without it, the webhook rejects
+	// scaling the Segment Stores down to 0, and a SegmentStoreReplicas of 0, e.g. unset
+	// or set through the scale subresource, is defaulted to 1. Defaults to false.
+	// +optional
+	AllowZeroSegmentStores bool `json:"allowZeroSegmentStores,omitempty"`
+
+	// SegmentStoreContainerCount is the number of segment containers of the cluster, set
+	// on both the Controller and the Segment Stores. The containers are spread over the
+	// Segment Stores, so it must not be below SegmentStoreReplicas: the Segment Stores
+	// cannot be scaled beyond it, as the extra replicas would host no container. Pravega
+	// does not support changing it on an existing cluster, so it cannot be changed once set,
+	// nor set or unset later. If unset, the Pravega default applies.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SegmentStoreContainerCount *int32 `json:"segmentStoreContainerCount,omitempty"`
+
 	// DebugLogging indicates whether or not debug level logging is enabled.
 	// Defaults to false.
 	// +optional
@@ -91,6 +198,18 @@ type PravegaSpec struct {
 	// +optional
 	Image *ImageSpec `json:"image"`
 
+	// ControllerImage overrides the image of the Controller, e.g. to run a patched
+	// image. The repository and pull policy that are not set fall back to the ones
+	// of Image. The tag is the cluster version.
+	// +optional
+	ControllerImage *ImageSpec `json:"controllerImage,omitempty"`
+
+	// SegmentStoreImage overrides the image of the Segment Store, e.g. to run a patched
+	// image. The repository and pull policy that are not set fall back to the ones
+	// of Image. The tag is the cluster version.
+	// +optional
+	SegmentStoreImage *ImageSpec `json:"segmentStoreImage,omitempty"`
+
 	// Options is the Pravega configuration that is passed to the Pravega processes
 	// as JAVA_OPTS. See the following file for a complete list of options:
 	// https://github.com/pravega/pravega/blob/master/config/config.properties
@@ -109,12 +228,32 @@ type PravegaSpec struct {
 	// +optional
 	SegmentStoreJVMOptions []string `json:"segmentStoreJVMOptions"`
 
+	// JVMFlavor selects the baseline JVM options the operator passes to the Controller
+	// and the Segment Store, to match the JVM distribution of the Pravega image: hotspot,
+	// temurin, graalvm or openj9. ControllerJvmOptions and SegmentStoreJVMOptions are
+	// applied on top of the baseline. The custom flavor sets no baseline option, leaving
+	// the JVM options entirely to ControllerJvmOptions and SegmentStoreJVMOptions.
+	// Defaults to hotspot.
+	// +optional
+	JVMFlavor string `json:"jvmFlavor,omitempty"`
+
 	// CacheVolumeClaimTemplate is the spec to describe PVC for the Pravega cache.
 	// This field is optional. If no PVC spec, stateful containers will use
 	// emptyDir as volume
 	// +optional
 	CacheVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"cacheVolumeClaimTemplate,omitempty"`
 
+	// SegmentStoreJournalVolume declares a dedicated volume, distinct from the cache, for the
+	// Tier 1 data kept locally by the segment store. Each segment store pod gets its own claim.
+	// +optional
+	SegmentStoreJournalVolume *JournalVolumeSpec `json:"segmentStoreJournalVolume,omitempty"`
+
+	// PVCLabels are added to the persistent volume claims of the segment stores, i.e. the
+	// cache and journal claims, and to the FileSystem Tier 2 claim, e.g. for cost
+	// allocation. They cannot override the labels set by the operator.
+	// +optional
+	PVCLabels map[string]string `json:"pvcLabels,omitempty"`
+
 	// LongTermStorage is the configuration of Pravega's tier 2 storage. If no configuration
 	// is provided, it will assume that a PersistentVolumeClaim called "pravega-longterm"
 	// is present and it will use it as Tier 2
@@ -129,6 +268,20 @@ type PravegaSpec struct {
 	// If not specified, Kubernetes will automatically assign the default service account in the namespace
 	SegmentStoreServiceAccountName string `json:"segmentStoreServiceAccountName,omitempty"`
 
+	// ControllerAutomountServiceAccountToken, when false, does not mount the token of the
+	// service account into the Controller pods, which do not use the Kubernetes API.
+	// If unset, the service account decides. Changes roll the Controller pods.
+	// +optional
+	ControllerAutomountServiceAccountToken *bool `json:"controllerAutomountServiceAccountToken,omitempty"`
+
+	// SegmentStoreAutomountServiceAccountToken, when false, does not mount the token of the
+	// service account into the Segment Store pods. The Segment Stores need it to look up
+	// their external address with external access, and the operator publishes a warning
+	// event if it is disabled then. If unset, the service account decides. Changes
+	// restart the Segment Store pods.
+	// +optional
+	SegmentStoreAutomountServiceAccountToken *bool `json:"segmentStoreAutomountServiceAccountToken,omitempty"`
+
 	// ControllerResources specifies the request and limit of resources that controller can have.
 	// ControllerResources includes CPU and memory resources
 	ControllerResources *v1.ResourceRequirements `json:"controllerResources,omitempty"`
@@ -141,32 +294,66 @@ type PravegaSpec struct {
 	// that need to be configured into the ss pod as environmental variables
 	SegmentStoreEnvVars string `json:"segmentStoreEnvVars,omitempty"`
 
+	// ControllerExtraEnv are additional environment variables of the Controller container,
+	// e.g. HTTP_PROXY and NO_PROXY. Variables set by the operator, including the keys of the
+	// Controller configmap, take precedence over variables of the same name, which are
+	// ignored with a Warning event. Changes roll the Controller pods.
+	// +optional
+	ControllerExtraEnv []corev1.EnvVar `json:"controllerExtraEnv,omitempty"`
+
+	// SegmentStoreExtraEnv are additional environment variables of the Segment Store
+	// container, e.g. HTTP_PROXY and NO_PROXY. Variables set by the operator, including the
+	// keys of the Segment Store configmap, take precedence over variables of the same name,
+	// which are ignored with a Warning event. Changes restart the Segment Store pods.
+	// +optional
+	SegmentStoreExtraEnv []corev1.EnvVar `json:"segmentStoreExtraEnv,omitempty"`
+
 	// SegmentStoreSecret specifies whether or not any secret needs to be configured into the ss pod
 	// either as an environment variable or by mounting it to a volume
 	// +optional
 	SegmentStoreSecret *SegmentStoreSecret `json:"segmentStoreSecret"`
 
-	// Type specifies the service type to achieve external access.
-	// Options are "LoadBalancer" and "NodePort".
-	// By default, if external access is enabled, it will use "LoadBalancer"
+	// ControllerExternalServiceType overrides the service type of the external access for
+	// the Controller. Options are "LoadBalancer", "NodePort" and "ClusterIP", which keeps
+	// the Controller internal. Defaults to the type of the external access
+	// +optional
 	ControllerExternalServiceType v1.ServiceType `json:"controllerExtServiceType,omitempty"`
 
 	// Annotations to be added to the external service
 	// +optional
 	ControllerServiceAnnotations map[string]string `json:"controllerSvcAnnotations"`
 
-	// Type specifies the service type to achieve external access.
-	// Options are "LoadBalancer" and "NodePort".
-	// By default, if external access is enabled, it will use "LoadBalancer"
+	// SegmentStoreExternalServiceType overrides the service type of the external access
+	// for the Segment Stores. Options are "LoadBalancer" and "NodePort". Defaults to the
+	// type of the external access
+	// +optional
 	SegmentStoreExternalServiceType v1.ServiceType `json:"segmentStoreExtServiceType,omitempty"`
 
 	// Annotations to be added to the external service
 	// +optional
 	SegmentStoreServiceAnnotations map[string]string `json:"segmentStoreSvcAnnotations"`
 
+	// SegmentStoreHeadlessServiceAnnotations are added to the headless service of the
+	// Segment Store StatefulSet, e.g. for a service mesh. Keys removed from the spec are
+	// removed from the service, while the annotations set on the service by others are
+	// kept. Keys managed by the operator, i.e. the external DNS hostname and the load
+	// balancer tags annotations, are ignored and a warning event is published.
+	// +optional
+	SegmentStoreHeadlessServiceAnnotations map[string]string `json:"segmentStoreHeadlessServiceAnnotations,omitempty"`
+
 	// Specifying this IP would ensure we use same IP address for all the ss services
 	SegmentStoreLoadBalancerIP string `json:"segmentStoreLoadBalancerIP,omitempty"`
 
+	// SegmentStoreExternalTrafficPort is the port of the external Segment Store services
+	// of type LoadBalancer, which the Segment Stores advertise to the clients, e.g. the
+	// port a NAT in front of the load balancers forwards. The container port is unchanged.
+	// The services sharing segmentStoreLoadBalancerIP use consecutive ports from it.
+	// Defaults to 12345.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	SegmentStoreExternalTrafficPort int32 `json:"segmentStoreExternalTrafficPort,omitempty"`
+
 	// SegmentStoreExternalTrafficPolicy defines the ExternalTrafficPolicy it can have cluster or local
 	SegmentStoreExternalTrafficPolicy string `json:"segmentStoreExternalTrafficPolicy,omitempty"`
 
@@ -176,11 +363,282 @@ type PravegaSpec struct {
 	// ControllerSecurityContext holds security configuration that will be applied to a container
 	ControllerSecurityContext *corev1.PodSecurityContext `json:"controllerSecurityContext,omitempty"`
 
-	// The scheduling constraints on Controller pods.
+	// SegmentStoreContainerSecurityContext holds the security configuration of the Segment
+	// Store container, overriding the one of the pod for the fields it sets. Changing it
+	// restarts the Segment Store pods.
+	// +optional
+	SegmentStoreContainerSecurityContext *corev1.SecurityContext `json:"segmentStoreContainerSecurityContext,omitempty"`
+
+	// ControllerContainerSecurityContext holds the security configuration of the Controller
+	// container, overriding the one of the pod for the fields it sets
+	// +optional
+	ControllerContainerSecurityContext *corev1.SecurityContext `json:"controllerContainerSecurityContext,omitempty"`
+
+	// RunAsIdentitySecret is the name of a Secret holding the user and group IDs the
+	// controller and segment store containers run as, under the "runAsUser" and
+	// "runAsGroup" keys, so that they can be managed centrally. The IDs override the
+	// runAsUser and runAsGroup of the security contexts above. If a security context
+	// sets runAsNonRoot, the IDs must be non-zero.
+	// +optional
+	RunAsIdentitySecret string `json:"runAsIdentitySecret,omitempty"`
+
+	// ControllerPodAffinity holds the scheduling constraints of the Controller pods, e.g.
+	// a node affinity requiring a zone. It is merged with the pod anti-affinity spreading
+	// the Controllers across nodes, generated by the operator; a term of the same label
+	// selector and topology key overrides the generated one. Changes roll the Controllers.
+	// +optional
 	ControllerPodAffinity *corev1.Affinity `json:"controllerPodAffinity,omitempty"`
 
-	// The scheduling constraints on Segementstore pods.
+	// SegmentStorePodAffinity holds the scheduling constraints of the Segment Store pods.
+	// It is merged with the pod anti-affinity spreading the Segment Stores across nodes,
+	// generated by the operator; a term of the same label selector and topology key
+	// overrides the generated one. Changes restart the Segment Store pods.
+	// +optional
 	SegmentStorePodAffinity *corev1.Affinity `json:"segmentStorePodAffinity,omitempty"`
+
+	// ControllerPodNodeSelector is the node selector of the Controller pods, which are
+	// only scheduled on the nodes having all these labels
+	// +optional
+	ControllerPodNodeSelector map[string]string `json:"controllerPodNodeSelector,omitempty"`
+
+	// SegmentStorePodNodeSelector is the node selector of the Segment Store pods, which are
+	// only scheduled on the nodes having all these labels
+	// +optional
+	SegmentStorePodNodeSelector map[string]string `json:"segmentStorePodNodeSelector,omitempty"`
+
+	// ControllerDnsPolicy is the DNS policy of the Controller pods, one of ClusterFirst,
+	// ClusterFirstWithHostNet, Default and None. Defaults to the cluster default.
+	// +kubebuilder:validation:Enum=ClusterFirst;ClusterFirstWithHostNet;Default;None
+	// +optional
+	ControllerDnsPolicy corev1.DNSPolicy `json:"controllerDnsPolicy,omitempty"`
+
+	// ControllerDnsConfig is the DNS configuration of the Controller pods, e.g. additional
+	// search domains. It is merged with the configuration of ControllerDnsPolicy.
+	// +optional
+	ControllerDnsConfig *corev1.PodDNSConfig `json:"controllerDnsConfig,omitempty"`
+
+	// SegmentStoreDnsPolicy is the DNS policy of the Segment Store pods, one of
+	// ClusterFirst, ClusterFirstWithHostNet, Default and None. Defaults to the cluster
+	// default.
+	// +kubebuilder:validation:Enum=ClusterFirst;ClusterFirstWithHostNet;Default;None
+	// +optional
+	SegmentStoreDnsPolicy corev1.DNSPolicy `json:"segmentStoreDnsPolicy,omitempty"`
+
+	// SegmentStoreDnsConfig is the DNS configuration of the Segment Store pods, e.g.
+	// additional search domains. It is merged with the configuration of
+	// SegmentStoreDnsPolicy.
+	// +optional
+	SegmentStoreDnsConfig *corev1.PodDNSConfig `json:"segmentStoreDnsConfig,omitempty"`
+
+	// ClusterDomain is the DNS suffix of the Kubernetes cluster, e.g. cluster.internal.
+	// When set, the addresses of the cluster services handed to the Segment Stores, e.g.
+	// the Controller URL, are fully qualified with it. If unset, they are left relative to
+	// the namespace, and resolved through the search domains of the pods. Changes restart
+	// the Segment Store pods.
+	// +optional
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+
+	// SegmentStoreHostNetwork, when true, runs the Segment Store pods on the network of
+	// their node, e.g. for line-rate throughput to the long term storage on bare-metal
+	// deployments. The segment stores then publish the address of their node, and their
+	// DNS policy defaults to ClusterFirstWithHostNet. It cannot be combined with external
+	// access. Defaults to false.
+	// +optional
+	SegmentStoreHostNetwork bool `json:"segmentStoreHostNetwork,omitempty"`
+
+	// SegmentStoreTopologySpreadConstraints control how the Segment Store pods are spread
+	// across the topology domains of the cluster, e.g. availability zones. They are
+	// applied along with SegmentStorePodAffinity, if both are set. Changing them restarts
+	// the segment store pods.
+	// +optional
+	SegmentStoreTopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"segmentStoreTopologySpreadConstraints,omitempty"`
+
+	// InitWaitURL is the http(s) URL of an external dependency, e.g. a metadata service,
+	// that must be reachable before the controller and segment store containers start.
+	// If set, an init container is added to those pods that blocks until the URL responds.
+	// Changing it rolls the controller pods and restarts the segment store pods.
+	// +optional
+	InitWaitURL string `json:"initWaitURL,omitempty"`
+
+	// Metrics overrides the metrics reporting settings of the Controller and Segment
+	// Store set in Options
+	// +optional
+	Metrics *MetricsSpec `json:"metrics,omitempty"`
+
+	// SchedulingPreCheck enables checking, before scaling up, that the per-pod resource
+	// requests of the controller and segment store fit in the allocatable resources of
+	// at least one node. If they don't, the InsufficientResources condition is set,
+	// and checked again on every reconcile until they fit. Defaults to false.
+	// +optional
+	SchedulingPreCheck bool `json:"schedulingPreCheck,omitempty"`
+
+	// ImageCheck enables checking, before creating or upgrading pods, that the Controller
+	// and Segment Store images of the requested version exist in their registry, with a
+	// manifest HEAD request.
+	// If the registry reports the image as unknown, the ImageNotFound condition is set and
+	// no pod is created or upgraded until the image or version is fixed. Leave it disabled for
+	// air-gapped setups where the registry cannot be reached. Defaults to false.
+	// +optional
+	ImageCheck bool `json:"imageCheck,omitempty"`
+
+	// LongTermStorageProbe enables probing the long term storage from the operator, at
+	// most once per probe interval, and reporting the result in the LtsReachable condition
+	// instead of inferring it from the segment store pods. The FileSystem claim must be
+	// bound, the ECS and S3 buckets must answer a HEAD request signed with the keys of
+	// their credentials secret, and the HDFS name node must accept connections.
+	// Defaults to false.
+	// +optional
+	LongTermStorageProbe bool `json:"longTermStorageProbe,omitempty"`
+
+	// LongTermStorageProbeIntervalSeconds is the minimal delay between two probes of the
+	// long term storage. Defaults to 300.
+	// +kubebuilder:validation:Minimum=10
+	// +optional
+	LongTermStorageProbeIntervalSeconds *int32 `json:"longTermStorageProbeIntervalSeconds,omitempty"`
+
+	// ConfigMapReconcilePolicy controls whether the operator overwrites manual edits of the
+	// Controller and Segment Store configmaps (Enforce), or leaves them in place (Ignore),
+	// e.g. for emergency tuning. With Ignore, the configmaps are still created if missing,
+	// the rest of the cluster is managed as usual, and the ConfigMapReconcileIgnored
+	// condition is set. Defaults to Enforce.
+	// +kubebuilder:validation:Enum=Enforce;Ignore
+	// +optional
+	ConfigMapReconcilePolicy string `json:"configMapReconcilePolicy,omitempty"`
+
+	// SegmentStoreRestartNodeAnnotation is the key of a node annotation, e.g. a driver version,
+	// whose changes require the segment store pods running on that node to be restarted.
+	// The segment store pods on the affected nodes are restarted one at a time. This is only
+	// effective when the operator runs with node watching enabled (-node-watch).
+	// +optional
+	SegmentStoreRestartNodeAnnotation string `json:"segmentStoreRestartNodeAnnotation,omitempty"`
+
+	// SegmentStoreTerminationGracePeriodSeconds is the time given to the segment store pods
+	// to shut down gracefully, e.g. to flush their cache to long term storage, before
+	// being killed. If unset, the Kubernetes default of 30 seconds applies.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SegmentStoreTerminationGracePeriodSeconds *int64 `json:"segmentStoreTerminationGracePeriodSeconds,omitempty"`
+
+	// SegmentStoreCachePVCReclaimPolicy controls whether the cache claims of the segment
+	// stores whose ordinal is not below the replica count, e.g. after a scale-down, are
+	// deleted (Delete) or kept (Retain). This only applies to Pravega versions below 0.7,
+	// which use a cache volume claim template. Defaults to Delete.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	SegmentStoreCachePVCReclaimPolicy string `json:"segmentStoreCachePVCReclaimPolicy,omitempty"`
+
+	// SegmentStoreUpdateStrategy controls how the segment store pods pick up upgrades and
+	// pod template changes. With RollingUpdate, the operator restarts the outdated pods one
+	// at a time. With OnDelete, the operator updates the stateful set but never deletes a
+	// pod, e.g. to restart the segment stores during a maintenance of their own. A Warning
+	// event is then published and, during an upgrade, the Upgrading condition is set with
+	// the Manual Restart Required reason until the user has deleted the outdated pods; the
+	// upgrade does not time out meanwhile. Defaults to RollingUpdate.
+	// +kubebuilder:validation:Enum=RollingUpdate;OnDelete
+	// +optional
+	SegmentStoreUpdateStrategy string `json:"segmentStoreUpdateStrategy,omitempty"`
+
+	// SegmentStoreInitContainers are run before the Segment Store container, e.g. to fix the
+	// permissions of a mounted volume. They can mount the volumes of the Segment Store pod,
+	// such as the cache volume, by name. Changes restart the Segment Store pods.
+	// +optional
+	SegmentStoreInitContainers []corev1.Container `json:"segmentStoreInitContainers,omitempty"`
+
+	// SegmentStoreVolumes are added to the Segment Store pods, e.g. a ConfigMap holding
+	// storage tuning files. Their names must differ from the volumes of the operator.
+	// Changes restart the Segment Store pods.
+	// +optional
+	SegmentStoreVolumes []corev1.Volume `json:"segmentStoreVolumes,omitempty"`
+
+	// SegmentStoreVolumeMounts are added to the Segment Store container. They must mount
+	// SegmentStoreVolumes, on paths not used by the mounts of the operator. Changes
+	// restart the Segment Store pods.
+	// +optional
+	SegmentStoreVolumeMounts []corev1.VolumeMount `json:"segmentStoreVolumeMounts,omitempty"`
+
+	// ControllerProbes tunes the readiness and liveness probes of the Controller pods.
+	// Each unset timing defaults to the one the operator has always used.
+	// +optional
+	ControllerProbes *ControllerProbesSpec `json:"controllerProbes,omitempty"`
+
+	// JVMDerivedResources, when set, derives the memory request and limit of the Controller
+	// and Segment Store containers from the -Xmx and -XX:MaxDirectMemorySize JVM options.
+	// It applies to the components whose resources are not set and whose JVM options set -Xmx.
+	// +optional
+	JVMDerivedResources *JVMDerivedResourcesSpec `json:"jvmDerivedResources,omitempty"`
+
+	// ControllerRequestTimeouts overrides the client request and transaction lease
+	// timeouts of the Controller set in Options
+	// +optional
+	ControllerRequestTimeouts *ControllerRequestTimeoutsSpec `json:"controllerRequestTimeouts,omitempty"`
+
+	// SegmentStoreRebalanceProtection, when enabled, makes the segment store pod disruption
+	// budget allow no voluntary disruption, e.g. node drains, while the segment containers
+	// are being rebalanced among the segment stores. The budget is relaxed once the
+	// rebalance completes. This relies on the segment container status, which is read
+	// from zookeeper at most once a minute.
+	// +optional
+	SegmentStoreRebalanceProtection bool `json:"segmentStoreRebalanceProtection,omitempty"`
+
+	// DisablePdb, when true, makes the operator skip the creation of the Controller and
+	// Segment Store pod disruption budgets, and delete the ones it created before. This is
+	// meant for single node clusters, where the budgets block node drains.
+	// Defaults to false.
+	// +optional
+	DisablePdb bool `json:"disablePdb,omitempty"`
+
+	// SegmentStorePdb overrides the disruption budget of the Segment Store pods. Only one
+	// of MinAvailable and MaxUnavailable can be set. When not set, the budget allows one
+	// unavailable segment store, or none if there is a single one.
+	// +optional
+	SegmentStorePdb *SegmentStorePdbSpec `json:"segmentStorePdb,omitempty"`
+
+	// LoggingSidecar, when set, injects a sidecar container into the Controller and
+	// Segment Store pods which tails the log files written to a shared emptyDir volume
+	// and forwards them to the configured destination. This is meant for environments
+	// without a node-level log agent.
+	// +optional
+	LoggingSidecar *LoggingSidecarSpec `json:"loggingSidecar,omitempty"`
+
+	// ControllerCommand overrides the entrypoint of the Controller container, e.g.
+	// ["sleep", "infinity"] to keep a crashing pod around for inspection. This is meant
+	// for debugging only: it bypasses the default launch of the Controller and removes
+	// the liveness probe, so the pods never become ready. The Controller args are
+	// cleared unless ControllerArgs is set.
+	// +optional
+	ControllerCommand []string `json:"controllerCommand,omitempty"`
+
+	// ControllerArgs overrides the arguments of the Controller container. This is meant
+	// for debugging only and bypasses the default launch of the Controller.
+	// +optional
+	ControllerArgs []string `json:"controllerArgs,omitempty"`
+
+	// SegmentStoreCommand overrides the entrypoint of the Segment Store container. This
+	// is meant for debugging only: it bypasses the default launch of the Segment Store
+	// and removes the liveness probe, so the pods never become ready. The Segment Store
+	// args are cleared unless SegmentStoreArgs is set. Changing it restarts the Segment
+	// Store pods.
+	// +optional
+	SegmentStoreCommand []string `json:"segmentStoreCommand,omitempty"`
+
+	// SegmentStoreArgs overrides the arguments of the Segment Store container. This is
+	// meant for debugging only and bypasses the default launch of the Segment Store.
+	// +optional
+	SegmentStoreArgs []string `json:"segmentStoreArgs,omitempty"`
+}
+
+// HasCommandOverride returns true if the command or the args of the Controller or the
+// Segment Store container are overridden.
+func (s *PravegaSpec) HasCommandOverride() bool {
+	return s.hasControllerCommandOverride() || s.hasSegmentStoreCommandOverride()
+}
+
+func (s *PravegaSpec) hasControllerCommandOverride() bool {
+	return len(s.ControllerCommand) > 0 || len(s.ControllerArgs) > 0
+}
+
+func (s *PravegaSpec) hasSegmentStoreCommandOverride() bool {
+	return len(s.SegmentStoreCommand) > 0 || len(s.SegmentStoreArgs) > 0
 }
 
 func (s *PravegaSpec) withDefaults() (changed bool) {
@@ -189,7 +647,7 @@ func (s *PravegaSpec) withDefaults() (changed bool) {
 		s.ControllerReplicas = 1
 	}
 
-	if !config.TestMode && s.SegmentStoreReplicas < 1 {
+	if !config.TestMode && s.SegmentStoreReplicas < 1 && !(s.AllowZeroSegmentStores && s.SegmentStoreReplicas == 0) {
 		changed = true
 		s.SegmentStoreReplicas = 1
 	}
@@ -217,6 +675,26 @@ func (s *PravegaSpec) withDefaults() (changed bool) {
 		s.SegmentStoreJVMOptions = []string{}
 	}
 
+	if s.JVMFlavor == "" {
+		changed = true
+		s.JVMFlavor = JVMFlavorHotSpot
+	}
+
+	if s.ConfigMapReconcilePolicy == "" {
+		changed = true
+		s.ConfigMapReconcilePolicy = ConfigMapReconcilePolicyEnforce
+	}
+
+	if s.SegmentStoreCachePVCReclaimPolicy == "" {
+		changed = true
+		s.SegmentStoreCachePVCReclaimPolicy = SegmentStoreCachePVCReclaimPolicyDelete
+	}
+
+	if s.SegmentStoreUpdateStrategy == "" {
+		changed = true
+		s.SegmentStoreUpdateStrategy = SegmentStoreUpdateStrategyRollingUpdate
+	}
+
 	if s.LongTermStorage == nil {
 		changed = true
 		s.LongTermStorage = &LongTermStorageSpec{}
@@ -226,32 +704,26 @@ func (s *PravegaSpec) withDefaults() (changed bool) {
 		changed = true
 	}
 
-	if s.ControllerResources == nil {
+	if s.JVMDerivedResources != nil && s.JVMDerivedResources.withDefaults() {
 		changed = true
-		s.ControllerResources = &v1.ResourceRequirements{
-			Requests: v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse(DefaultControllerRequestCPU),
-				v1.ResourceMemory: resource.MustParse(DefaultControllerRequestMemory),
-			},
-			Limits: v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse(DefaultControllerLimitCPU),
-				v1.ResourceMemory: resource.MustParse(DefaultControllerLimitMemory),
-			},
-		}
 	}
 
-	if s.SegmentStoreResources == nil {
+	if s.ControllerResources == nil && !s.derivesResources(s.ControllerJvmOptions) {
+		changed = true
+		s.ControllerResources = defaultControllerResources()
+	}
+
+	if s.SegmentStoreResources == nil && !s.derivesResources(s.SegmentStoreJVMOptions) {
+		changed = true
+		s.SegmentStoreResources = defaultSegmentStoreResources()
+	}
+
+	if defaultRequestsToLimits(s.ControllerResources) {
+		changed = true
+	}
+
+	if defaultRequestsToLimits(s.SegmentStoreResources) {
 		changed = true
-		s.SegmentStoreResources = &v1.ResourceRequirements{
-			Requests: v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse(DefaultSegmentStoreRequestCPU),
-				v1.ResourceMemory: resource.MustParse(DefaultSegmentStoreRequestMemory),
-			},
-			Limits: v1.ResourceList{
-				v1.ResourceCPU:    resource.MustParse(DefaultSegmentStoreLimitCPU),
-				v1.ResourceMemory: resource.MustParse(DefaultSegmentStoreLimitMemory),
-			},
-		}
 	}
 
 	if s.SegmentStoreSecret == nil {
@@ -273,6 +745,19 @@ func (s *PravegaSpec) withDefaults() (changed bool) {
 		s.SegmentStoreServiceAnnotations = map[string]string{}
 	}
 
+	if s.ControllerProbes == nil {
+		changed = true
+		s.ControllerProbes = &ControllerProbesSpec{}
+	}
+
+	if s.ControllerProbes.withDefaults() {
+		changed = true
+	}
+
+	if s.LoggingSidecar != nil && s.LoggingSidecar.withDefaults() {
+		changed = true
+	}
+
 	return changed
 }
 
@@ -290,6 +775,326 @@ type SegmentStoreSecret struct {
 	MountPath string `json:"mountPath"`
 }
 
+// MetricsSpec defines how the Pravega components report metrics
+type MetricsSpec struct {
+	// ReportingIntervalSeconds is the interval at which metrics are reported
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	ReportingIntervalSeconds *int32 `json:"reportingIntervalSeconds,omitempty"`
+
+	// Prefix is the namespace prefix prepended to the name of every metric.
+	// It must start with a letter and contain only letters, digits, '_' and '.'
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// ServiceMonitor makes the operator create a Prometheus Operator ServiceMonitor
+	// scraping the Controller and Segment Store metrics
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+}
+
+// ServiceMonitorSpec defines the Prometheus Operator ServiceMonitor of the cluster
+type ServiceMonitorSpec struct {
+	// Enabled creates the ServiceMonitor. It is ignored if the Prometheus Operator
+	// CRDs are not installed
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Interval is the scrape interval, e.g. "30s". Defaults to the interval of
+	// the Prometheus instance
+	// +optional
+	Interval string `json:"interval,omitempty"`
+
+	// Labels are added to the ServiceMonitor, e.g. to match the serviceMonitorSelector
+	// of the Prometheus instance
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (s *ServiceMonitorSpec) IsEnabled() bool {
+	return s != nil && s.Enabled
+}
+
+// ControllerRequestTimeoutsSpec defines how long the Controller waits on client operations
+type ControllerRequestTimeoutsSpec struct {
+	// RequestTimeoutSeconds is the time after which a client request to the Controller
+	// is failed
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	RequestTimeoutSeconds *int32 `json:"requestTimeoutSeconds,omitempty"`
+
+	// TransactionMaxLeaseSeconds is the maximum lease a client can request for a
+	// transaction before it is aborted
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	TransactionMaxLeaseSeconds *int32 `json:"transactionMaxLeaseSeconds,omitempty"`
+}
+
+// ControllerProbesSpec defines the probes of the Controller pods
+type ControllerProbesSpec struct {
+	// ReadinessProbe tunes the probe checking that the Controller REST endpoint is up
+	// +optional
+	ReadinessProbe *ProbeSpec `json:"readinessProbe,omitempty"`
+
+	// LivenessProbe tunes the probe checking that the Controller is healthy
+	// +optional
+	LivenessProbe *ProbeSpec `json:"livenessProbe,omitempty"`
+}
+
+// ProbeSpec defines the timings of a probe
+type ProbeSpec struct {
+	// InitialDelaySeconds is the number of seconds after the container has started
+	// before the probe is initiated
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds"`
+
+	// PeriodSeconds is how often, in seconds, the probe is performed
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds"`
+
+	// FailureThreshold is the number of consecutive failures after which the probe
+	// is considered failed
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold"`
+
+	// TimeoutSeconds is the number of seconds after which the probe times out
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds"`
+}
+
+func (s *ControllerProbesSpec) withDefaults() (changed bool) {
+	if s.ReadinessProbe == nil {
+		changed = true
+		s.ReadinessProbe = &ProbeSpec{}
+	}
+
+	if s.ReadinessProbe.withDefaults(ProbeSpec{
+		InitialDelaySeconds: DefaultControllerReadinessProbeInitialDelaySeconds,
+		PeriodSeconds:       DefaultControllerReadinessProbePeriodSeconds,
+		FailureThreshold:    DefaultControllerReadinessProbeFailureThreshold,
+		TimeoutSeconds:      DefaultControllerReadinessProbeTimeoutSeconds,
+	}) {
+		changed = true
+	}
+
+	if s.LivenessProbe == nil {
+		changed = true
+		s.LivenessProbe = &ProbeSpec{}
+	}
+
+	if s.LivenessProbe.withDefaults(ProbeSpec{
+		InitialDelaySeconds: DefaultControllerLivenessProbeInitialDelaySeconds,
+		PeriodSeconds:       DefaultControllerLivenessProbePeriodSeconds,
+		FailureThreshold:    DefaultControllerLivenessProbeFailureThreshold,
+		TimeoutSeconds:      DefaultControllerLivenessProbeTimeoutSeconds,
+	}) {
+		changed = true
+	}
+
+	return changed
+}
+
+// withDefaults sets each unset timing of the probe to the given default
+func (s *ProbeSpec) withDefaults(defaults ProbeSpec) (changed bool) {
+	if s.InitialDelaySeconds == 0 {
+		changed = true
+		s.InitialDelaySeconds = defaults.InitialDelaySeconds
+	}
+
+	if s.PeriodSeconds == 0 {
+		changed = true
+		s.PeriodSeconds = defaults.PeriodSeconds
+	}
+
+	if s.FailureThreshold == 0 {
+		changed = true
+		s.FailureThreshold = defaults.FailureThreshold
+	}
+
+	if s.TimeoutSeconds == 0 {
+		changed = true
+		s.TimeoutSeconds = defaults.TimeoutSeconds
+	}
+
+	return changed
+}
+
+func defaultControllerResources() *v1.ResourceRequirements {
+	return &v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(DefaultControllerRequestCPU),
+			v1.ResourceMemory: resource.MustParse(DefaultControllerRequestMemory),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(DefaultControllerLimitCPU),
+			v1.ResourceMemory: resource.MustParse(DefaultControllerLimitMemory),
+		},
+	}
+}
+
+func defaultSegmentStoreResources() *v1.ResourceRequirements {
+	return &v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(DefaultSegmentStoreRequestCPU),
+			v1.ResourceMemory: resource.MustParse(DefaultSegmentStoreRequestMemory),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(DefaultSegmentStoreLimitCPU),
+			v1.ResourceMemory: resource.MustParse(DefaultSegmentStoreLimitMemory),
+		},
+	}
+}
+
+// defaultRequestsToLimits sets the missing CPU and memory requests of the resources to
+// their limits, so that the pods are not scheduled without requests. The requests set
+// in the spec are kept
+func defaultRequestsToLimits(resources *v1.ResourceRequirements) (changed bool) {
+	if resources == nil {
+		return false
+	}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		limit, ok := resources.Limits[name]
+		if !ok {
+			continue
+		}
+		if _, ok := resources.Requests[name]; ok {
+			continue
+		}
+		if resources.Requests == nil {
+			resources.Requests = v1.ResourceList{}
+		}
+		resources.Requests[name] = limit.DeepCopy()
+		changed = true
+	}
+	return changed
+}
+
+// LoggingSidecarSpec defines the sidecar forwarding the Controller and Segment Store logs
+type LoggingSidecarSpec struct {
+	// Image is the image of the logging sidecar, e.g. a fluent-bit image
+	Image string `json:"image"`
+
+	// ImagePullPolicy is the pull policy of the logging sidecar image
+	// +optional
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Destination is where the logs are forwarded, e.g. a host:port or a URL. It is
+	// passed to the sidecar in the LOG_DESTINATION environment variable
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// MountPath is the path of the shared log volume in both the main container and the
+	// sidecar. It is passed to the sidecar in the LOG_DIR environment variable.
+	// Defaults to /opt/pravega/logs.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Env is the additional environment of the logging sidecar, e.g. its credentials
+	// +optional
+	Env []v1.EnvVar `json:"env,omitempty"`
+
+	// Resources are the resources of the logging sidecar container
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+func (s *LoggingSidecarSpec) withDefaults() (changed bool) {
+	if s.MountPath == "" {
+		changed = true
+		s.MountPath = DefaultLoggingSidecarMountPath
+	}
+
+	return changed
+}
+
+// SegmentStorePdbSpec defines the disruption budget of the Segment Store pods
+type SegmentStorePdbSpec struct {
+	// MinAvailable is the number or percentage of segment store pods which must remain
+	// available during voluntary disruptions, e.g. node drains
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of segment store pods which can be
+	// unavailable during voluntary disruptions, e.g. node drains
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// JVMDerivedResourcesSpec defines how container resources are derived from the JVM options
+type JVMDerivedResourcesSpec struct {
+	// OverheadFactor is the ratio of the container memory to the sum of the JVM heap and
+	// direct memory, accounting for the memory the JVM uses besides them, e.g. metaspace
+	// and thread stacks. It must be a number greater than or equal to 1. Defaults to 1.5.
+	// +optional
+	OverheadFactor string `json:"overheadFactor,omitempty"`
+}
+
+func (s *JVMDerivedResourcesSpec) withDefaults() (changed bool) {
+	if s.OverheadFactor == "" {
+		changed = true
+		s.OverheadFactor = DefaultJVMMemoryOverheadFactor
+	}
+
+	return changed
+}
+
+// derivesResources reports whether the resources of a component having the given JVM
+// options are derived from them
+func (s *PravegaSpec) derivesResources(jvmOptions []string) bool {
+	if s.JVMDerivedResources == nil {
+		return false
+	}
+	_, found, _ := util.GetJVMMemoryOption(jvmOptions, "-Xmx")
+	return found
+}
+
+// deriveResources computes the resources of a container from its JVM options. The memory
+// request and limit are the sum of the maximum heap and direct memory sizes multiplied by
+// the overhead factor, the CPU request and limit are the given ones
+func (s *PravegaSpec) deriveResources(jvmOptions []string, requestCPU string, limitCPU string) (*v1.ResourceRequirements, error) {
+	factor, err := strconv.ParseFloat(s.JVMDerivedResources.OverheadFactor, 64)
+	if err != nil || math.IsNaN(factor) || factor < 1 {
+		return nil, fmt.Errorf("overheadFactor must be a number greater than or equal to 1, got %s", s.JVMDerivedResources.OverheadFactor)
+	}
+	heap, found, err := util.GetJVMMemoryOption(jvmOptions, "-Xmx")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("-Xmx is not set in the JVM options")
+	}
+	direct, found, err := util.GetJVMMemoryOption(jvmOptions, "-XX:MaxDirectMemorySize=")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		// The JVM limits the direct memory to the maximum heap size by default
+		direct = heap
+	}
+	size := math.Ceil((float64(heap) + float64(direct)) * factor)
+	if heap == 0 || size >= math.MaxInt64 {
+		return nil, fmt.Errorf("derived memory of %.0f bytes is out of range", size)
+	}
+	memory := resource.NewQuantity(int64(size), resource.BinarySI)
+	return &v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(requestCPU),
+			v1.ResourceMemory: *memory,
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(limitCPU),
+			v1.ResourceMemory: *memory,
+		},
+	}, nil
+}
+
 func (s *SegmentStoreSecret) withDefaults() (changed bool) {
 	if s.Secret == "" {
 		s.MountPath = ""
@@ -312,6 +1117,18 @@ func (s *ImageSpec) withDefaults() (changed bool) {
 	return changed
 }
 
+// JournalVolumeSpec defines the PVC backing the segment store journal volume
+type JournalVolumeSpec struct {
+	// StorageClassName is the storage class of the journal claims. If unset, the
+	// default storage class of the cluster is used
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Size is the size of the journal claim of each segment store pod. It must be
+	// between 1Gi and 16Ti
+	Size string `json:"size"`
+}
+
 // LongTermStorageSpec configures the Tier 2 storage type to use with Pravega.
 // If not specified, Tier 2 will be configured in filesystem mode and will try
 // to use a PersistentVolumeClaim with the name "pravega-longterm"
@@ -326,10 +1143,13 @@ type LongTermStorageSpec struct {
 
 	// Hdfs is used to configure an HDFS system as a Tier 2 backend
 	Hdfs *HDFSSpec `json:"hdfs,omitempty"`
+
+	// S3 is used to configure an S3-compatible object store, e.g. MinIO, as a Tier 2 backend
+	S3 *S3Spec `json:"s3,omitempty"`
 }
 
 func (s *LongTermStorageSpec) withDefaults() (changed bool) {
-	if s.FileSystem == nil && s.Ecs == nil && s.Hdfs == nil {
+	if s.FileSystem == nil && s.Ecs == nil && s.Hdfs == nil && s.S3 == nil {
 		changed = true
 		fs := &FileSystemSpec{
 			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
@@ -344,6 +1164,9 @@ func (s *LongTermStorageSpec) withDefaults() (changed bool) {
 
 // FileSystemSpec contains the reference to a PVC.
 type FileSystemSpec struct {
+	// PersistentVolumeClaim is a pre-created claim. The same claim is mounted into every
+	// segment store pod, so all of them share a single Tier 2 volume, which requires the
+	// ReadWriteMany access mode when there is more than one segment store
 	// +optional
 	PersistentVolumeClaim *v1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim"`
 }
@@ -358,6 +1181,52 @@ type ECSSpec struct {
 	Prefix string `json:"prefix"`
 	// +optional
 	Credentials string `json:"credentials"`
+
+	// CaBundleSecret is the name of a secret holding the PEM encoded CA certificates of
+	// the ECS endpoint. The secret is mounted in the segment store pods, which add the
+	// certificates to the JVM truststore. It must exist in the namespace of the cluster
+	// +optional
+	CaBundleSecret string `json:"caBundleSecret,omitempty"`
+
+	// UseV2 selects the newer EXTENDEDS3 options, with which the namespace, the IAM role
+	// and the session token are passed to the segment stores. Defaults to false
+	// +optional
+	UseV2 bool `json:"useV2,omitempty"`
+
+	// Namespace is the ECS namespace of the bucket, requires useV2
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Role is the IAM role assumed by the segment stores, used instead of the static keys
+	// of the credentials secret. It requires useV2 and the namespace
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// SessionToken selects the key of a secret holding the session token of temporary
+	// credentials, requires useV2
+	// +optional
+	SessionToken *v1.SecretKeySelector `json:"sessionToken,omitempty"`
+}
+
+// S3Spec contains the connection details to an S3-compatible object store
+type S3Spec struct {
+	// Bucket is the name of the bucket holding the Tier 2 data
+	Bucket string `json:"bucket"`
+
+	// Endpoint is the http(s) URL of the object store, e.g. http://minio.default:9000
+	Endpoint string `json:"endpoint"`
+
+	// Region is the region of the bucket, exported to the segment store as AWS_REGION
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Prefix is prepended to the names of the objects written by Pravega
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// SecretRef is the name of the secret holding the access and secret keys of the
+	// object store, under the ACCESS_KEY_ID and SECRET_KEY keys
+	SecretRef string `json:"secretRef"`
 }
 
 // HDFSSpec contains the connection details to an HDFS system
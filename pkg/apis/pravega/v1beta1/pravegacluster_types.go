@@ -14,25 +14,35 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	k8s "github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	bkapi "github.com/pravega/bookkeeper-operator/pkg/apis/bookkeeper/v1alpha1"
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/util"
 	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -54,11 +64,58 @@ const (
 	// DefaultServiceType is the default service type for external access
 	DefaultServiceType = corev1.ServiceTypeLoadBalancer
 
+	// DefaultSegmentStoreExternalTrafficPort is the default port of the external Segment
+	// Store services, which is also the container port
+	DefaultSegmentStoreExternalTrafficPort = 12345
+
 	// DefaultPravegaVersion is the default tag used for for the Pravega
 	// Docker image
 	DefaultPravegaVersion = "0.7.0"
+
+	// MinMaintenanceWindowDuration and MaxMaintenanceWindowDuration bound the
+	// duration of a maintenance window
+	MinMaintenanceWindowDuration = time.Minute
+	MaxMaintenanceWindowDuration = 7 * 24 * time.Hour
+
+	// DefaultSegmentStoreUpgradeMaxUnavailable is the default number of segment store
+	// pods upgraded at once
+	DefaultSegmentStoreUpgradeMaxUnavailable = 1
+
+	// DefaultUpgradePodReadyTimeoutSeconds is the default time an upgraded pod may stay
+	// not ready before the upgrade fails
+	DefaultUpgradePodReadyTimeoutSeconds = 600
+
+	// DefaultUpgradeTerminatingPodTimeoutSeconds is the default time a segment store pod
+	// may stay terminating past its deletion grace period during an upgrade
+	DefaultUpgradeTerminatingPodTimeoutSeconds = 300
+
+	// DefaultScalingStallTimeoutSeconds is the default time the cluster may stay with
+	// fewer ready pods than desired, without progress, before its scaling is stalled
+	DefaultScalingStallTimeoutSeconds = 900
+
+	// DefaultLongTermStorageProbeIntervalSeconds is the default minimal delay between two
+	// probes of the long term storage
+	DefaultLongTermStorageProbeIntervalSeconds = 300
+
+	// MinLongTermStorageProbeIntervalSeconds bounds how often the operator probes the
+	// long term storage
+	MinLongTermStorageProbeIntervalSeconds = 10
+
+	// DefaultVersionHistoryLimit is the default number of completed upgrades kept in
+	// the upgrade history of the status
+	DefaultVersionHistoryLimit = 10
+
+	maxLoadBalancerTagKeyLength   = 128
+	maxLoadBalancerTagValueLength = 256
 )
 
+// loadBalancerTagRegexp matches the characters allowed in cloud load balancer tags,
+// excluding the "," and "=" separators of the tags annotation
+var loadBalancerTagRegexp = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/+\-@]*$`)
+
+// metricsPrefixRegexp matches a valid metrics namespace prefix
+var metricsPrefixRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.]*$`)
+
 func init() {
 	SchemeBuilder.Register(&PravegaCluster{}, &PravegaClusterList{})
 }
@@ -75,12 +132,15 @@ type PravegaClusterList struct {
 // Generate CRD using kubebuilder
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.pravega.segmentStoreReplicas,statuspath=.status.segmentStoreReplicas,selectorpath=.status.segmentStoreSelector
 // +kubebuilder:storageversion
 // +kubebuilder:resource:shortName=pk
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.currentVersion`,description="The current pravega version"
 // +kubebuilder:printcolumn:name="Desired Version",type=string,JSONPath=`.spec.version`,description="The desired pravega version"
+// +kubebuilder:printcolumn:name="Target Version",type=string,JSONPath=`.status.targetVersion`,description="The version the pravega cluster is upgrading to"
 // +kubebuilder:printcolumn:name="Desired Members",type=integer,JSONPath=`.status.replicas`,description="The number of desired pravega members"
 // +kubebuilder:printcolumn:name="Ready Members",type=integer,JSONPath=`.status.readyReplicas`,description="The number of ready pravega members"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="PodsReady")].status`,description="Whether all the pravega members are ready"
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // PravegaCluster is the Schema for the pravegaclusters API
 
@@ -138,6 +198,35 @@ type ClusterSpec struct {
 	// +optional
 	Version string `json:"version"`
 
+	// PauseUpgrade stops an upgrade in progress before the next pod is upgraded.
+	// Setting it back to false resumes the upgrade from where it left off.
+	// +optional
+	PauseUpgrade bool `json:"pauseUpgrade,omitempty"`
+
+	// UpgradeConfig tunes how the pods are rolled during an upgrade
+	// +optional
+	UpgradeConfig *UpgradeConfig `json:"upgradeConfig,omitempty"`
+
+	// MaintenanceWindows are the windows during which the operator performs
+	// disruptive actions: starting an upgrade and scaling down the controller or
+	// the segment store. Outside of them these actions are deferred until the
+	// next window. If no window is set, they are performed at any time.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// ScalingStallTimeoutSeconds is how long the cluster may stay with fewer ready pods
+	// than desired, without any pod becoming ready or unready, before the operator sets
+	// the ScalingStalled condition. Upgrades and rollbacks are not covered. Defaults to 900.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ScalingStallTimeoutSeconds *int32 `json:"scalingStallTimeoutSeconds,omitempty"`
+
+	// VersionHistoryLimit is the number of completed upgrades kept in the upgrade
+	// history of the status, the oldest ones being dropped first. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	VersionHistoryLimit *int32 `json:"versionHistoryLimit,omitempty"`
+
 	// BookkeeperUri specifies the hostname/IP address and port in the format
 	// "hostname:port".
 	// comma delimited list of BK server URLs
@@ -152,6 +241,55 @@ type ClusterSpec struct {
 	Pravega *PravegaSpec `json:"pravega"`
 }
 
+// UpgradeConfig tunes how the pods are rolled during an upgrade
+type UpgradeConfig struct {
+	// SegmentStoreMaxUnavailable is the maximum number of segment store pods that can be
+	// unavailable at once during an upgrade, so that several pods are upgraded
+	// concurrently. The segment store disruption budget is never exceeded, unless it
+	// allows no disruption at all, in which case the pods are upgraded one at a time.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SegmentStoreMaxUnavailable *int32 `json:"segmentStoreMaxUnavailable,omitempty"`
+
+	// PodReadyTimeoutSeconds is how long an upgraded pod may stay not ready before the
+	// upgrade is marked as failed, measured for each pod. It also bounds the time the
+	// upgrade waits without progress. Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PodReadyTimeoutSeconds *int32 `json:"podReadyTimeoutSeconds,omitempty"`
+
+	// TerminatingPodTimeoutSeconds is how long a segment store pod may stay terminating
+	// past its deletion grace period during an upgrade, e.g. because its volumes fail to
+	// detach, before the error condition reports it as stuck. Defaults to 300.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TerminatingPodTimeoutSeconds *int32 `json:"terminatingPodTimeoutSeconds,omitempty"`
+}
+
+// MaintenanceWindow is a recurring window opening at the times matched by a cron schedule
+type MaintenanceWindow struct {
+	// Schedule is a cron expression with five fields: minute, hour, day of month,
+	// month and day of week, evaluated in UTC. For example "0 2 * * 6" opens the
+	// window every Saturday at 02:00
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open, e.g. "4h"
+	Duration string `json:"duration"`
+}
+
+func (w MaintenanceWindow) parse() (schedule *util.Schedule, duration time.Duration, ok bool) {
+	schedule, err := util.ParseSchedule(w.Schedule)
+	if err != nil {
+		return nil, 0, false
+	}
+	duration, err = time.ParseDuration(w.Duration)
+	if err != nil {
+		return nil, 0, false
+	}
+	return schedule, duration, true
+}
+
 func (s *ClusterSpec) withDefaults(p *PravegaCluster) (changed bool) {
 	if s.ZookeeperUri == "" {
 		changed = true
@@ -238,13 +376,32 @@ type ExternalAccess struct {
 	// Domain Name to be used for External Access
 	// This value is ignored if External Access is disabled
 	DomainName string `json:"domainName,omitempty"`
+
+	// LoadBalancerTags are the tags to be applied to the cloud load balancers provisioned
+	// for the external services. They are translated into the AWS additional resource
+	// tags annotation on the services of type LoadBalancer.
+	// This value is ignored if External Access is disabled
+	// +optional
+	LoadBalancerTags map[string]string `json:"loadBalancerTags,omitempty"`
+
+	// ExternalTrafficPolicy is the external traffic policy of the external Controller
+	// and SegmentStore services of type LoadBalancer or NodePort. Options are "Cluster"
+	// and "Local", the latter preserving the client source IPs.
+	// If not set, the Controller service uses "Cluster" and the SegmentStore services
+	// use the policy set in segmentStoreExternalTrafficPolicy.
+	// This value is ignored if External Access is disabled
+	// +kubebuilder:validation:Enum=Cluster;Local
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
 }
 
 func (e *ExternalAccess) withDefaults() (changed bool) {
-	if e.Enabled == false && (e.Type != "" || e.DomainName != "") {
+	if e.Enabled == false && (e.Type != "" || e.DomainName != "" || e.LoadBalancerTags != nil || e.ExternalTrafficPolicy != "") {
 		changed = true
 		e.Type = ""
 		e.DomainName = ""
+		e.LoadBalancerTags = nil
+		e.ExternalTrafficPolicy = ""
 	}
 	return changed
 }
@@ -252,12 +409,93 @@ func (e *ExternalAccess) withDefaults() (changed bool) {
 type TLSPolicy struct {
 	// Static TLS means keys/certs are generated by the user and passed to an operator.
 	Static *StaticTLS `json:"static,omitempty"`
+
+	// ReloadOnChange restarts the controller and segment store pods when the data of
+	// their TLS secrets changes, e.g. when the certificates are rotated. A hash of the
+	// secrets is stamped on the pod templates for this purpose
+	// +optional
+	ReloadOnChange bool `json:"reloadOnChange,omitempty"`
 }
 
 type StaticTLS struct {
 	ControllerSecret   string `json:"controllerSecret,omitempty"`
 	SegmentStoreSecret string `json:"segmentStoreSecret,omitempty"`
 	CaBundle           string `json:"caBundle,omitempty"`
+
+	// ControllerKeys are the keys of the controllerSecret holding the certificate, the
+	// private key and the CA certificate of the Controller. The operator sets the Pravega
+	// options locating these files, which then must not be set in the options
+	// +optional
+	ControllerKeys *TLSSecretKeys `json:"controllerKeys,omitempty"`
+
+	// SegmentStoreKeys are the keys of the segmentStoreSecret holding the certificate,
+	// the private key and the CA certificate of the Segment Store
+	// +optional
+	SegmentStoreKeys *TLSSecretKeys `json:"segmentStoreKeys,omitempty"`
+}
+
+// TLSSecretKeys are the keys of a TLS secret holding the PEM encoded files of a component.
+// Each key left empty keeps the corresponding Pravega option as set in the options
+type TLSSecretKeys struct {
+	// Certificate is the key of the server certificate
+	// +optional
+	Certificate string `json:"certificate,omitempty"`
+
+	// PrivateKey is the key of the private key of the server certificate
+	// +optional
+	PrivateKey string `json:"privateKey,omitempty"`
+
+	// CaCertificate is the key of the CA certificate trusted by the component
+	// +optional
+	CaCertificate string `json:"caCertificate,omitempty"`
+}
+
+// The Pravega options locating the certificate, the private key and the CA certificate
+// of the Controller and of the Segment Store
+var (
+	controllerTLSKeyOptions = [3]string{
+		"controller.security.tls.server.certificate.location",
+		"controller.security.tls.server.privateKey.location",
+		"controller.security.tls.trustStore.location",
+	}
+	segmentStoreTLSKeyOptions = [3]string{
+		"pravegaservice.security.tls.server.certificate.location",
+		"pravegaservice.security.tls.server.privateKey.location",
+		"autoScale.controller.connect.security.tls.truststore.location",
+	}
+)
+
+// keyOptions maps the given options of the certificate, the private key and the CA
+// certificate to the keys which are set
+func (k *TLSSecretKeys) keyOptions(options [3]string) map[string]string {
+	keyOptions := map[string]string{}
+	if k == nil {
+		return keyOptions
+	}
+	for i, key := range []string{k.Certificate, k.PrivateKey, k.CaCertificate} {
+		if key != "" {
+			keyOptions[options[i]] = key
+		}
+	}
+	return keyOptions
+}
+
+// ControllerKeyOptions returns the Pravega options of the Controller located by the keys
+// of its TLS secret, mapped to the keys
+func (tp *TLSPolicy) ControllerKeyOptions() map[string]string {
+	if !tp.IsSecureController() {
+		return map[string]string{}
+	}
+	return tp.Static.ControllerKeys.keyOptions(controllerTLSKeyOptions)
+}
+
+// SegmentStoreKeyOptions returns the Pravega options of the Segment Store located by the
+// keys of its TLS secret, mapped to the keys
+func (tp *TLSPolicy) SegmentStoreKeyOptions() map[string]string {
+	if !tp.IsSecureSegmentStore() {
+		return map[string]string{}
+	}
+	return tp.Static.SegmentStoreKeys.keyOptions(segmentStoreTLSKeyOptions)
 }
 
 func (tp *TLSPolicy) IsSecureController() bool {
@@ -290,6 +528,12 @@ type AuthenticationParameters struct {
 	// name of Secret containing Password based Authentication Parameters like username, password and acl
 	// optional - used only by PasswordAuthHandler for authentication
 	PasswordAuthSecret string `json:"passwordAuthSecret,omitempty"`
+
+	// ControllerTokenSecret selects the key of a secret holding the key with which the
+	// Controller signs the delegation tokens verified by the Segment Stores. It is passed
+	// to both components as TOKEN_SIGNING_KEY, in place of the default signing key
+	// +optional
+	ControllerTokenSecret *corev1.SecretKeySelector `json:"controllerTokenSecret,omitempty"`
 }
 
 func (ap *AuthenticationParameters) IsEnabled() bool {
@@ -892,23 +1136,349 @@ func (p *PravegaCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (p *PravegaCluster) ValidateCreate() error {
 	log.Printf("validate create %s", p.Name)
-	return p.ValidatePravegaVersion("")
+	return p.ValidateFields("", Mgr.GetClient())
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (p *PravegaCluster) ValidateUpdate(old runtime.Object) error {
 	log.Printf("validate update %s", p.Name)
-	err := p.ValidatePravegaVersion("")
+	errs := p.fieldErrors("", Mgr.GetClient())
+	if oldCluster, ok := old.(*PravegaCluster); ok {
+		err := p.ValidateVersionChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "version"), p.Spec.Version, err.Error()))
+		}
+		err = p.ValidateSegmentStoreReplicasChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "pravega", "segmentStoreReplicas"), p.Spec.Pravega.SegmentStoreReplicas, err.Error()))
+		}
+		err = p.ValidateJournalVolumeChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "pravega", "segmentStoreJournalVolume"), err.Error()))
+		}
+		err = p.ValidateLoggingSidecarChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "pravega", "loggingSidecar"), err.Error()))
+		}
+		err = p.ValidateSegmentStoreContainerCountChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "pravega", "segmentStoreContainerCount"), err.Error()))
+		}
+		err = p.ValidateJVMDerivedResourcesChange(oldCluster)
+		if err != nil {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "pravega", "jvmDerivedResources"), err.Error()))
+		}
+	}
+	err := p.validateConfigMap()
 	if err != nil {
-		return err
+		errs = append(errs, toFieldError(field.NewPath("spec", "pravega", "options"), nil, err))
+	}
+	return p.invalid(errs)
+}
+
+// ValidateVersionChange rejects lowering the version of the cluster, as Pravega does not
+// support downgrades and an older version may corrupt the metadata written by a newer
+// one. Rolling back a failed upgrade to the previous version is allowed, and so is any
+// downgrade if the cluster has the AllowDowngradeAnnotation set to "true".
+func (p *PravegaCluster) ValidateVersionChange(old *PravegaCluster) error {
+	oldVersion, newVersion := old.Spec.Version, p.Spec.Version
+	if oldVersion == "" || newVersion == "" || oldVersion == newVersion {
+		return nil
+	}
+	if p.Status.IsClusterInUpgradeFailedOrRollbackState() && len(p.Status.VersionHistory) > 0 &&
+		newVersion == p.Status.GetLastVersion() {
+		return nil
+	}
+	if p.downgradeAllowed() {
+		return nil
 	}
-	err = p.validateConfigMap()
+	// Versions in an invalid format are reported by ValidatePravegaVersion
+	downgrade, err := util.CompareVersions(newVersion, oldVersion, "<")
+	if err != nil || !downgrade {
+		return nil
+	}
+	return fmt.Errorf("downgrade from version %s to %s is not supported, set the %s annotation to \"true\" to force it",
+		oldVersion, newVersion, AllowDowngradeAnnotation)
+}
+
+// ValidateSegmentStoreReplicasChange rejects scaling the Segment Stores down to 0, which
+// leaves the Controllers up without any data plane, unless AllowZeroSegmentStores is set.
+// A cluster created without SegmentStoreReplicas also has 0 replicas until the operator
+// defaults them to 1, so that 0 is only rejected if the previous spec was not the same.
+// The minimum does not apply in test mode.
+func (p *PravegaCluster) ValidateSegmentStoreReplicasChange(old *PravegaCluster) error {
+	if config.TestMode || p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStoreReplicas != 0 || p.Spec.Pravega.AllowZeroSegmentStores {
+		return nil
+	}
+	if old.Spec.Pravega != nil && old.Spec.Pravega.SegmentStoreReplicas == 0 && !old.Spec.Pravega.AllowZeroSegmentStores {
+		return nil
+	}
+	return fmt.Errorf("must be at least 1 as a cluster without segment stores serves no data, set spec.pravega.allowZeroSegmentStores to true to run the cluster without segment stores")
+}
+
+// ValidateJournalVolumeChange rejects adding or removing the journal volume of an existing
+// cluster, changing its storage class or decreasing its size, as the volume claim templates
+// of the segment store stateful set cannot be changed. Increasing the size is allowed, the
+// operator expands the existing claims.
+func (p *PravegaCluster) ValidateJournalVolumeChange(old *PravegaCluster) error {
+	var oldJournal, newJournal *JournalVolumeSpec
+	if old.Spec.Pravega != nil {
+		oldJournal = old.Spec.Pravega.SegmentStoreJournalVolume
+	}
+	if p.Spec.Pravega != nil {
+		newJournal = p.Spec.Pravega.SegmentStoreJournalVolume
+	}
+	switch {
+	case oldJournal == nil && newJournal == nil:
+		return nil
+	case oldJournal == nil:
+		return fmt.Errorf("the journal volume cannot be added to an existing cluster")
+	case newJournal == nil:
+		return fmt.Errorf("the journal volume cannot be removed from an existing cluster")
+	case oldJournal.StorageClassName != newJournal.StorageClassName:
+		return fmt.Errorf("the storage class of the journal volume cannot be changed from %q to %q", oldJournal.StorageClassName, newJournal.StorageClassName)
+	}
+	// Sizes in an invalid format are reported by ValidateJournalVolume
+	oldSize, err := resource.ParseQuantity(oldJournal.Size)
 	if err != nil {
-		return err
+		return nil
+	}
+	newSize, err := resource.ParseQuantity(newJournal.Size)
+	if err != nil {
+		return nil
+	}
+	if newSize.Cmp(oldSize) < 0 {
+		return fmt.Errorf("the size of the journal volume can only be increased, from %s", oldJournal.Size)
 	}
 	return nil
 }
 
+// ValidateLoggingSidecarChange rejects enabling, disabling or changing the logging sidecar
+// of an existing cluster, as the sidecar is only added when the Controller deployment and
+// the Segment Store stateful set are created
+func (p *PravegaCluster) ValidateLoggingSidecarChange(old *PravegaCluster) error {
+	var oldSidecar, newSidecar *LoggingSidecarSpec
+	if old.Spec.Pravega != nil && old.Spec.Pravega.LoggingSidecar != nil {
+		oldSidecar = old.Spec.Pravega.LoggingSidecar.DeepCopy()
+		oldSidecar.withDefaults()
+	}
+	if p.Spec.Pravega != nil && p.Spec.Pravega.LoggingSidecar != nil {
+		newSidecar = p.Spec.Pravega.LoggingSidecar.DeepCopy()
+		newSidecar.withDefaults()
+	}
+	if equality.Semantic.DeepEqual(oldSidecar, newSidecar) {
+		return nil
+	}
+	return fmt.Errorf("the logging sidecar cannot be enabled, disabled or changed on an existing cluster")
+}
+
+// ValidateSegmentStoreContainerCountChange rejects changing the number of segment containers
+// of an existing cluster, including setting or unsetting it. Pravega does not support it, the
+// segments would no longer be found in the metadata written by the previous containers.
+func (p *PravegaCluster) ValidateSegmentStoreContainerCountChange(old *PravegaCluster) error {
+	var oldCount, newCount *int32
+	if old.Spec.Pravega != nil {
+		oldCount = old.Spec.Pravega.SegmentStoreContainerCount
+	}
+	if p.Spec.Pravega != nil {
+		newCount = p.Spec.Pravega.SegmentStoreContainerCount
+	}
+	if equality.Semantic.DeepEqual(oldCount, newCount) {
+		return nil
+	}
+	return fmt.Errorf("the number of segment containers cannot be changed on an existing cluster")
+}
+
+// ValidateJVMDerivedResourcesChange rejects enabling the JVM derived resources on an
+// existing cluster when they would not apply to any component. The operator has set the
+// default resources of the existing cluster, which take precedence over the derived ones
+// until they are removed from the spec.
+func (p *PravegaCluster) ValidateJVMDerivedResourcesChange(old *PravegaCluster) error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.JVMDerivedResources == nil ||
+		old.Spec.Pravega == nil || old.Spec.Pravega.JVMDerivedResources != nil {
+		return nil
+	}
+	spec := p.Spec.Pravega
+	if (spec.ControllerResources == nil && spec.derivesResources(spec.ControllerJvmOptions)) ||
+		(spec.SegmentStoreResources == nil && spec.derivesResources(spec.SegmentStoreJVMOptions)) {
+		return nil
+	}
+	return fmt.Errorf("the derived resources would not apply to any component, remove controllerResources or segmentStoreResources and set -Xmx in the JVM options of the component")
+}
+
+// downgradeAllowed returns whether the cluster is annotated to allow downgrades
+func (p *PravegaCluster) downgradeAllowed() bool {
+	return p.Annotations[AllowDowngradeAnnotation] == "true"
+}
+
+// ValidateFields runs the checks of the webhook on creation, reading the supported
+// versions from the given file and the Tier 2 claim with the given client. It does not
+// stop at the first violation, the returned Invalid error lists all the offending fields.
+func (p *PravegaCluster) ValidateFields(filename string, kubeClient client.Client) error {
+	return p.invalid(p.fieldErrors(filename, kubeClient))
+}
+
+// fieldCheck is a check of the webhook, along with the field it reports its error on
+// and the value of the field, if it is a scalar
+type fieldCheck struct {
+	path  *field.Path
+	value interface{}
+	check func() error
+}
+
+// fieldErrors runs all the checks of the webhook and collects their errors
+func (p *PravegaCluster) fieldErrors(filename string, kubeClient client.Client) field.ErrorList {
+	errs := field.ErrorList{}
+	err := p.ValidatePravegaVersion(filename)
+	if err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "version"), p.Spec.Version, err.Error()))
+	}
+	errs = append(errs, p.validateReplicas()...)
+	errs = append(errs, p.validateExternalAccessTypes()...)
+	return append(errs, p.runFieldChecks(kubeClient)...)
+}
+
+// runFieldChecks runs the field checks of the webhook and collects their errors
+func (p *PravegaCluster) runFieldChecks(kubeClient client.Client) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, c := range p.fieldChecks(kubeClient) {
+		err := c.check()
+		if err != nil {
+			errs = append(errs, toFieldError(c.path, c.value, err))
+		}
+	}
+	return errs
+}
+
+// fieldChecks returns the field checks of the webhook. Without a client, the checks
+// reading secrets or claims from the API server only check the spec.
+func (p *PravegaCluster) fieldChecks(kubeClient client.Client) []fieldCheck {
+	specPath := field.NewPath("spec")
+	pravegaPath := specPath.Child("pravega")
+	pravega := p.Spec.Pravega
+	if pravega == nil {
+		pravega = &PravegaSpec{}
+	}
+	externalAccess := p.Spec.ExternalAccess
+	if externalAccess == nil {
+		externalAccess = &ExternalAccess{}
+	}
+	longTermStorage := p.validateLongTermStorageSpec
+	tls := p.ValidateTLS
+	authentication := p.validateAuthenticationSpec
+	if kubeClient != nil {
+		longTermStorage = func() error { return p.ValidateLongTermStorage(kubeClient) }
+		tls = func() error { return p.ValidateTLSSecretKeys(kubeClient) }
+		authentication = func() error { return p.ValidateAuthentication(kubeClient) }
+	}
+	return []fieldCheck{
+		{pravegaPath.Child("longtermStorage"), nil, longTermStorage},
+		{pravegaPath.Child("initWaitURL"), pravega.InitWaitURL, p.ValidateInitWaitURL},
+		{specPath.Child("externalAccess", "loadBalancerTags"), nil, p.ValidateLoadBalancerTags},
+		{specPath.Child("externalAccess", "externalTrafficPolicy"), string(externalAccess.ExternalTrafficPolicy), p.ValidateExternalTrafficPolicy},
+		{pravegaPath.Child("metrics"), nil, p.ValidateMetrics},
+		{pravegaPath, nil, p.ValidateNodeSelectors},
+		{pravegaPath.Child("pvcLabels"), nil, p.ValidatePVCLabels},
+		{pravegaPath.Child("segmentStoreTopologySpreadConstraints"), nil, p.ValidateSegmentStoreTopologySpreadConstraints},
+		{pravegaPath, nil, p.ValidatePodAffinities},
+		{pravegaPath, nil, p.ValidateDNS},
+		{pravegaPath.Child("segmentStoreHostNetwork"), nil, p.ValidateSegmentStoreHostNetwork},
+		{pravegaPath.Child("segmentStoreExternalTrafficPort"), nil, p.ValidateSegmentStoreExternalTrafficPort},
+		{pravegaPath.Child("segmentStoreTerminationGracePeriodSeconds"), pravega.SegmentStoreTerminationGracePeriodSeconds, p.ValidateSegmentStoreTerminationGracePeriod},
+		{pravegaPath.Child("segmentStoreContainerCount"), pravega.SegmentStoreContainerCount, p.ValidateSegmentStoreContainerCount},
+		{pravegaPath.Child("longTermStorageProbeIntervalSeconds"), pravega.LongTermStorageProbeIntervalSeconds, p.ValidateLongTermStorageProbeInterval},
+		{pravegaPath.Child("controllerProbes"), nil, p.ValidateControllerProbes},
+		{pravegaPath.Child("jvmDerivedResources"), nil, p.ValidateJVMDerivedResources},
+		{pravegaPath.Child("controllerRequestTimeouts"), nil, p.ValidateControllerRequestTimeouts},
+		{pravegaPath.Child("segmentStoreJournalVolume"), nil, p.ValidateJournalVolume},
+		{specPath.Child("maintenanceWindows"), nil, p.ValidateMaintenanceWindows},
+		{specPath.Child("upgradeConfig", "segmentStoreMaxUnavailable"), nil, p.ValidateUpgradeConfig},
+		{specPath.Child("scalingStallTimeoutSeconds"), nil, p.ValidateScalingStallTimeout},
+		{specPath.Child("versionHistoryLimit"), nil, p.ValidateVersionHistoryLimit},
+		{pravegaPath.Child("jvmFlavor"), pravega.JVMFlavor, p.ValidateJVMFlavor},
+		{pravegaPath.Child("segmentStoreInitContainers"), nil, p.ValidateSegmentStoreInitContainers},
+		{pravegaPath.Child("segmentStoreVolumeMounts"), nil, p.ValidateSegmentStoreVolumes},
+		{pravegaPath.Child("configMapReconcilePolicy"), pravega.ConfigMapReconcilePolicy, p.ValidateConfigMapReconcilePolicy},
+		{pravegaPath.Child("clusterDomain"), pravega.ClusterDomain, p.ValidateClusterDomain},
+		{pravegaPath.Child("loggingSidecar"), nil, p.ValidateLoggingSidecar},
+		{pravegaPath, nil, p.ValidateCommandOverrides},
+		{pravegaPath, nil, p.ValidateImagePullPolicies},
+		{pravegaPath.Child("segmentStoreCachePVCReclaimPolicy"), pravega.SegmentStoreCachePVCReclaimPolicy, p.ValidateSegmentStoreCachePVCReclaimPolicy},
+		{pravegaPath.Child("segmentStoreUpdateStrategy"), pravega.SegmentStoreUpdateStrategy, p.ValidateSegmentStoreUpdateStrategy},
+		{pravegaPath.Child("segmentStorePdb"), nil, p.ValidateSegmentStorePdb},
+		{specPath.Child("tls"), nil, tls},
+		{specPath.Child("authentication", "controllerTokenSecret"), nil, authentication},
+	}
+}
+
+// toFieldError reports the error of a check on the given field. Errors which already
+// point at a field are kept as is.
+func toFieldError(path *field.Path, value interface{}, err error) *field.Error {
+	if fieldErr, ok := err.(*field.Error); ok {
+		return fieldErr
+	}
+	if value == nil {
+		return field.Forbidden(path, err.Error())
+	}
+	return field.Invalid(path, value, err.Error())
+}
+
+// validateReplicas checks that the number of controller and segment store replicas is
+// not negative
+func (p *PravegaCluster) validateReplicas() field.ErrorList {
+	errs := field.ErrorList{}
+	if p.Spec.Pravega == nil {
+		return errs
+	}
+	path := field.NewPath("spec", "pravega")
+	if p.Spec.Pravega.ControllerReplicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("controllerReplicas"), p.Spec.Pravega.ControllerReplicas, "must not be negative"))
+	}
+	if p.Spec.Pravega.SegmentStoreReplicas < 0 {
+		errs = append(errs, field.Invalid(path.Child("segmentStoreReplicas"), p.Spec.Pravega.SegmentStoreReplicas, "must not be negative"))
+	}
+	return errs
+}
+
+// validateExternalAccessTypes checks that the service types of the external access are
+// ones the operator can expose the cluster with. The Controller can also be kept
+// internal with the ClusterIP type while the Segment Stores are exposed
+func (p *PravegaCluster) validateExternalAccessTypes() field.ErrorList {
+	errs := field.ErrorList{}
+	external := []corev1.ServiceType{corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeNodePort}
+	check := func(path *field.Path, serviceType corev1.ServiceType, supported []corev1.ServiceType) {
+		if serviceType == "" {
+			return
+		}
+		values := []string{}
+		for _, s := range supported {
+			if serviceType == s {
+				return
+			}
+			values = append(values, string(s))
+		}
+		errs = append(errs, field.NotSupported(path, serviceType, values))
+	}
+	if p.Spec.ExternalAccess != nil {
+		check(field.NewPath("spec", "externalAccess", "type"), p.Spec.ExternalAccess.Type, external)
+	}
+	if p.Spec.Pravega != nil {
+		check(field.NewPath("spec", "pravega", "controllerExtServiceType"), p.Spec.Pravega.ControllerExternalServiceType,
+			append(external, corev1.ServiceTypeClusterIP))
+		check(field.NewPath("spec", "pravega", "segmentStoreExtServiceType"), p.Spec.Pravega.SegmentStoreExternalServiceType, external)
+	}
+	return errs
+}
+
+// invalid returns the errors of the checks as a single Invalid error, the way the API
+// server reports the violations of a built-in type
+func (p *PravegaCluster) invalid(errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.NewInvalid(SchemeGroupVersion.WithKind("PravegaCluster").GroupKind(), p.Name, errs)
+}
+
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (p *PravegaCluster) ValidateDelete() error {
 	log.Printf("validate delete %s", p.Name)
@@ -916,6 +1486,27 @@ func (p *PravegaCluster) ValidateDelete() error {
 	return nil
 }
 
+// ValidateSpec runs the checks of the webhook which do not need the API server, e.g. to
+// validate a manifest before applying it. The spec is checked once defaulted, as the
+// operator would, but the cluster is left unchanged. The returned error lists all the
+// violations, each with the offending field.
+func ValidateSpec(p *PravegaCluster) error {
+	errs := field.ErrorList{}
+	if p.Spec.Version != "" {
+		if _, err := util.NormalizeVersion(p.Spec.Version); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "version"), p.Spec.Version,
+				fmt.Sprintf("version %s is not in valid format: %v", p.Spec.Version, err)))
+		}
+	}
+	errs = append(errs, p.validateReplicas()...)
+	errs = append(errs, p.validateExternalAccessTypes()...)
+
+	defaulted := p.DeepCopy()
+	defaulted.WithDefaults()
+	errs = append(errs, defaulted.runFieldChecks(nil)...)
+	return errs.ToAggregate()
+}
+
 func getSupportedVersions(filename string) (map[string]string, error) {
 	var supportedVersions = map[string]string{}
 	filepath := filename
@@ -974,7 +1565,8 @@ func (p *PravegaCluster) ValidatePravegaVersion(filename string) error {
 		return nil
 	}
 
-	if p.Status.IsClusterInErrorState() {
+	// a pod stuck terminating does not fail the upgrade, the cluster can still be updated
+	if p.Status.IsClusterInErrorState() && !p.Status.IsPodStuckTerminating() {
 		return fmt.Errorf("failed to process the request, cluster is in error state.")
 	}
 	// Check if the request has a valid Pravega version
@@ -1004,6 +1596,12 @@ func (p *PravegaCluster) ValidatePravegaVersion(filename string) error {
 	}
 
 	log.Printf("ValidatePravegaVersion:: normFoundVersion %s", normFoundVersion)
+	if p.downgradeAllowed() {
+		if downgrade, _ := util.CompareVersions(requestVersion, p.Status.CurrentVersion, "<"); downgrade {
+			log.Printf("ValidatePravegaVersion:: forced downgrade from %s to %s", p.Status.CurrentVersion, requestVersion)
+			return nil
+		}
+	}
 	upgradeString, ok := supportedVersions[normFoundVersion]
 	if !ok {
 		// It should never happen
@@ -1144,49 +1742,1339 @@ func (p *PravegaCluster) validateConfigMap() error {
 	return nil
 }
 
-//to return name of segmentstore based on the version
-func (p *PravegaCluster) StatefulSetNameForSegmentstore() string {
-	if util.IsVersionBelow07(p.Spec.Version) {
-		return p.StatefulSetNameForSegmentstoreBelow07()
+// ValidateLongTermStorage checks that exactly one Tier 2 backend is set and that the
+// PersistentVolumeClaim configured as FileSystem Tier 2 can be shared by all the segment
+// store pods, i.e. it has the ReadWriteMany access mode when there is more than one
+// segment store. A single segment store may use a ReadWriteOnce claim. If the claim has
+// not been created yet, the access mode check is skipped.
+func (p *PravegaCluster) ValidateLongTermStorage(kubeClient client.Client) error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.LongTermStorage == nil {
+		return nil
 	}
-	return p.StatefulSetNameForSegmentstoreAbove07()
+	err := p.validateLongTermStorageSpec()
+	if err != nil {
+		return err
+	}
+	ecs := p.Spec.Pravega.LongTermStorage.Ecs
+	if ecs != nil && ecs.CaBundleSecret != "" {
+		secret := &corev1.Secret{}
+		err = kubeClient.Get(context.TODO(),
+			types.NamespacedName{Name: ecs.CaBundleSecret, Namespace: p.Namespace}, secret)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Errorf("longtermStorage.ecs.caBundleSecret %s not found in namespace %s", ecs.CaBundleSecret, p.Namespace)
+			}
+			return fmt.Errorf("failed to get secret (%s): %v", ecs.CaBundleSecret, err)
+		}
+	}
+	fs := p.Spec.Pravega.LongTermStorage.FileSystem
+	if fs == nil || fs.PersistentVolumeClaim == nil || p.Spec.Pravega.SegmentStoreReplicas <= 1 {
+		return nil
+	}
+	claimName := fs.PersistentVolumeClaim.ClaimName
+	pvc := &corev1.PersistentVolumeClaim{}
+	err = kubeClient.Get(context.TODO(),
+		types.NamespacedName{Name: claimName, Namespace: p.Namespace}, pvc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("ValidateLongTermStorage:: tier2 pvc %s not found, skipping access mode check", claimName)
+			return nil
+		}
+		return fmt.Errorf("failed to get tier2 pvc (%s): %v", claimName, err)
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return nil
+		}
+	}
+	return fmt.Errorf("tier2 pvc %s must have access mode %s as it is shared by the %d segment store replicas, got %v",
+		claimName, corev1.ReadWriteMany, p.Spec.Pravega.SegmentStoreReplicas, pvc.Spec.AccessModes)
 }
 
-//if version is above or equals to 0.7 this name will be assigned
-func (p *PravegaCluster) StatefulSetNameForSegmentstoreAbove07() string {
-	return fmt.Sprintf("%s-pravega-segment-store", p.Name)
+// validateLongTermStorageSpec runs the checks of the Tier 2 storage which do not need
+// the API server
+func (p *PravegaCluster) validateLongTermStorageSpec() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.LongTermStorage == nil {
+		return nil
+	}
+	lts := p.Spec.Pravega.LongTermStorage
+	err := validateLongTermStorageBackend(lts)
+	if err != nil {
+		return err
+	}
+	if lts.S3 != nil {
+		return validateS3(lts.S3)
+	}
+	if lts.Ecs != nil {
+		return validateECS(lts.Ecs)
+	}
+	return nil
 }
 
-//if version is below 0.7 this name will be assigned
-func (p *PravegaCluster) StatefulSetNameForSegmentstoreBelow07() string {
-	return fmt.Sprintf("%s-pravega-segmentstore", p.Name)
+// validateECS checks that the ECS backend authenticates either with the static keys of
+// the credentials secret or with an IAM role, and that the options of the newer
+// EXTENDEDS3 configuration are only set along with useV2
+func validateECS(ecs *ECSSpec) error {
+	if ecs.CaBundleSecret != "" {
+		if errs := validation.IsDNS1123Subdomain(ecs.CaBundleSecret); len(errs) != 0 {
+			return fmt.Errorf("longtermStorage.ecs.caBundleSecret %s is not a valid secret name: %s", ecs.CaBundleSecret, strings.Join(errs, ", "))
+		}
+	}
+	if ecs.Credentials == "" && ecs.Role == "" {
+		return fmt.Errorf("longtermStorage.ecs must set either credentials, the secret holding the static access and secret keys, or role and namespace to authenticate with IAM")
+	}
+	if !ecs.UseV2 {
+		v2Fields := []string{}
+		if ecs.Namespace != "" {
+			v2Fields = append(v2Fields, "namespace")
+		}
+		if ecs.Role != "" {
+			v2Fields = append(v2Fields, "role")
+		}
+		if ecs.SessionToken != nil {
+			v2Fields = append(v2Fields, "sessionToken")
+		}
+		if len(v2Fields) > 0 {
+			return fmt.Errorf("longtermStorage.ecs.useV2 must be set along with %s", strings.Join(v2Fields, ", "))
+		}
+	}
+	if ecs.Role != "" && ecs.Namespace == "" {
+		return fmt.Errorf("longtermStorage.ecs.namespace must be set along with longtermStorage.ecs.role")
+	}
+	if ecs.SessionToken != nil && (ecs.SessionToken.Name == "" || ecs.SessionToken.Key == "") {
+		return fmt.Errorf("longtermStorage.ecs.sessionToken must set the name and the key of the secret")
+	}
+	return nil
 }
 
-func (p *PravegaCluster) PravegaControllerServiceURL() string {
-	return fmt.Sprintf("tcp://%v.%v:%v", p.ServiceNameForController(), p.Namespace, "9090")
+// validateLongTermStorageBackend checks that exactly one of the Tier 2 backends is set,
+// so that the operator does not have to pick one
+func validateLongTermStorageBackend(lts *LongTermStorageSpec) error {
+	path := field.NewPath("spec", "pravega", "longtermStorage")
+	backends := []string{}
+	if lts.FileSystem != nil {
+		backends = append(backends, "filesystem")
+	}
+	if lts.Ecs != nil {
+		backends = append(backends, "ecs")
+	}
+	if lts.Hdfs != nil {
+		backends = append(backends, "hdfs")
+	}
+	if lts.S3 != nil {
+		backends = append(backends, "s3")
+	}
+	if len(backends) == 0 {
+		return field.Required(path, "one of filesystem, ecs, hdfs and s3 must be set")
+	}
+	if len(backends) > 1 {
+		return field.Forbidden(path, fmt.Sprintf("only one of filesystem, ecs, hdfs and s3 can be set, got %s", strings.Join(backends, " and ")))
+	}
+	return nil
 }
 
-func (p *PravegaCluster) LabelsForController() map[string]string {
-	labels := p.LabelsForPravegaCluster()
-	labels["component"] = "pravega-controller"
-	return labels
+// validateS3 checks that the S3 backend has a bucket, an http(s) endpoint and a secret
+func validateS3(s3 *S3Spec) error {
+	if s3.Bucket == "" {
+		return fmt.Errorf("longtermStorage.s3.bucket must be set")
+	}
+	if s3.SecretRef == "" {
+		return fmt.Errorf("longtermStorage.s3.secretRef must be set")
+	}
+	u, err := url.Parse(s3.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("longtermStorage.s3.endpoint %s must be an http or https URL", s3.Endpoint)
+	}
+	if u.RawQuery != "" {
+		return fmt.Errorf("longtermStorage.s3.endpoint %s must not have a query", s3.Endpoint)
+	}
+	return nil
 }
 
-func (p *PravegaCluster) LabelsForSegmentStore() map[string]string {
-	labels := p.LabelsForPravegaCluster()
-	labels["component"] = "pravega-segmentstore"
-	return labels
+// ValidateInitWaitURL checks that the URL the controller and segment store pods wait on
+// before starting is an absolute http or https URL.
+func (p *PravegaCluster) ValidateInitWaitURL() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.InitWaitURL == "" {
+		return nil
+	}
+	waitURL := p.Spec.Pravega.InitWaitURL
+	u, err := url.Parse(waitURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse init wait url (%s): %v", waitURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("init wait url %s must be an absolute http or https url", waitURL)
+	}
+	return nil
 }
 
-func (pravegaCluster *PravegaCluster) LabelsForPravegaCluster() map[string]string {
-	return map[string]string{
-		"app":             "pravega-cluster",
-		"pravega_cluster": pravegaCluster.Name,
+// ValidateLoadBalancerTags checks that the load balancer tags satisfy the cloud provider
+// constraints on tag keys and values, and can be encoded into the tags annotation.
+func (p *PravegaCluster) ValidateLoadBalancerTags() error {
+	if p.Spec.ExternalAccess == nil {
+		return nil
 	}
+	for key, value := range p.Spec.ExternalAccess.LoadBalancerTags {
+		if key == "" || utf8.RuneCountInString(key) > maxLoadBalancerTagKeyLength {
+			return fmt.Errorf("load balancer tag key %q must be between 1 and %d characters", key, maxLoadBalancerTagKeyLength)
+		}
+		if utf8.RuneCountInString(value) > maxLoadBalancerTagValueLength {
+			return fmt.Errorf("load balancer tag value of %q must be at most %d characters", key, maxLoadBalancerTagValueLength)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return fmt.Errorf("load balancer tag key %q must not start with the reserved prefix aws:", key)
+		}
+		if !loadBalancerTagRegexp.MatchString(key) || !loadBalancerTagRegexp.MatchString(value) {
+			return fmt.Errorf("load balancer tag %q=%q may only contain letters, numbers, spaces and the characters _.:/+-@", key, value)
+		}
+	}
+	return nil
 }
 
-func (p *PravegaCluster) PdbNameForController() string {
-	return fmt.Sprintf("%s-pravega-controller", p.Name)
+// ValidateExternalTrafficPolicy checks that the external traffic policy of the external
+// services is either Cluster or Local.
+func (p *PravegaCluster) ValidateExternalTrafficPolicy() error {
+	if p.Spec.ExternalAccess == nil {
+		return nil
+	}
+	switch p.Spec.ExternalAccess.ExternalTrafficPolicy {
+	case "", corev1.ServiceExternalTrafficPolicyTypeCluster, corev1.ServiceExternalTrafficPolicyTypeLocal:
+		return nil
+	}
+	return fmt.Errorf("external traffic policy %q is invalid, it must be either %s or %s", p.Spec.ExternalAccess.ExternalTrafficPolicy,
+		corev1.ServiceExternalTrafficPolicyTypeCluster, corev1.ServiceExternalTrafficPolicyTypeLocal)
+}
+
+// ValidateMetrics checks that the metrics reporting interval is in range and that
+// the metrics prefix is a valid metric namespace.
+func (p *PravegaCluster) ValidateMetrics() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.Metrics == nil {
+		return nil
+	}
+	metrics := p.Spec.Pravega.Metrics
+	if interval := metrics.ReportingIntervalSeconds; interval != nil && (*interval < 1 || *interval > 3600) {
+		return fmt.Errorf("metrics.reportingIntervalSeconds must be between 1 and 3600, got %d", *interval)
+	}
+	if metrics.Prefix != "" && !metricsPrefixRegexp.MatchString(metrics.Prefix) {
+		return fmt.Errorf("metrics.prefix %s must start with a letter and contain only letters, digits, '_' and '.'", metrics.Prefix)
+	}
+	if sm := metrics.ServiceMonitor; sm != nil {
+		if sm.Interval != "" {
+			if interval, err := time.ParseDuration(sm.Interval); err != nil || interval <= 0 {
+				return fmt.Errorf("metrics.serviceMonitor.interval %s is not a valid positive duration", sm.Interval)
+			}
+		}
+		for key, value := range sm.Labels {
+			if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+				return fmt.Errorf("metrics.serviceMonitor.labels key %s is invalid: %s", key, strings.Join(errs, ", "))
+			}
+			if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+				return fmt.Errorf("metrics.serviceMonitor.labels value %s is invalid: %s", value, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateNodeSelectors checks that the controller and segment store node selectors
+// are made of valid label keys and values.
+func (p *PravegaCluster) ValidateNodeSelectors() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	nodeSelectors := map[string]map[string]string{
+		"controllerPodNodeSelector":   p.Spec.Pravega.ControllerPodNodeSelector,
+		"segmentStorePodNodeSelector": p.Spec.Pravega.SegmentStorePodNodeSelector,
+	}
+	for field, nodeSelector := range nodeSelectors {
+		for key, value := range nodeSelector {
+			if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+				return fmt.Errorf("%s key %s is not a valid label key: %s", field, key, strings.Join(errs, "; "))
+			}
+			if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+				return fmt.Errorf("%s value %s of key %s is not a valid label value: %s", field, value, key, strings.Join(errs, "; "))
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePVCLabels checks that the labels of the persistent volume claims are valid and
+// do not override the segment store labels, which select the claims of the cluster
+func (p *PravegaCluster) ValidatePVCLabels() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	reserved := p.LabelsForSegmentStore()
+	for key, value := range p.Spec.Pravega.PVCLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return fmt.Errorf("pvcLabels key %s is not a valid label key: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+			return fmt.Errorf("pvcLabels value %s of key %s is not a valid label value: %s", value, key, strings.Join(errs, "; "))
+		}
+		if _, ok := reserved[key]; ok {
+			return fmt.Errorf("pvcLabels key %s is reserved by the operator", key)
+		}
+	}
+	return nil
+}
+
+// ValidateDNS checks that the DNS policies of the controller and segment store pods are
+// supported, and that pods with the None policy are given a nameserver
+func (p *PravegaCluster) ValidateDNS() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	for _, dns := range []struct {
+		field  string
+		policy corev1.DNSPolicy
+		config *corev1.PodDNSConfig
+	}{
+		{"controller", p.Spec.Pravega.ControllerDnsPolicy, p.Spec.Pravega.ControllerDnsConfig},
+		{"segmentStore", p.Spec.Pravega.SegmentStoreDnsPolicy, p.Spec.Pravega.SegmentStoreDnsConfig},
+	} {
+		switch dns.policy {
+		case "", corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault:
+		case corev1.DNSNone:
+			if dns.config == nil || len(dns.config.Nameservers) == 0 {
+				return fmt.Errorf("%sDnsConfig.nameservers must be set as %sDnsPolicy is %s", dns.field, dns.field, corev1.DNSNone)
+			}
+		default:
+			return fmt.Errorf("%sDnsPolicy %s is invalid, it must be one of %s, %s, %s and %s", dns.field, dns.policy,
+				corev1.DNSClusterFirst, corev1.DNSClusterFirstWithHostNet, corev1.DNSDefault, corev1.DNSNone)
+		}
+	}
+	return nil
+}
+
+// publishedAddressOptions are the options setting the address published by the Segment
+// Stores, before and since Pravega 0.7
+var publishedAddressOptions = []string{
+	"pravegaservice.publishedIPAddress",
+	"pravegaservice.service.published.host.nameOrIp",
+}
+
+// ValidateSegmentStoreHostNetwork checks that the segment stores on the host network are
+// not exposed through external services, that the address they publish, which is the
+// one of their node, is not also set in the options, and that their security context
+// does not set network sysctls, which Kubernetes rejects for pods on the host network.
+func (p *PravegaCluster) ValidateSegmentStoreHostNetwork() error {
+	if p.Spec.Pravega == nil || !p.Spec.Pravega.SegmentStoreHostNetwork {
+		return nil
+	}
+	if p.Spec.ExternalAccess != nil && p.Spec.ExternalAccess.Enabled {
+		return fmt.Errorf("segmentStoreHostNetwork cannot be enabled along with externalAccess, the segment stores publish the address of their node")
+	}
+	for _, key := range publishedAddressOptions {
+		if _, ok := p.Spec.Pravega.Options[key]; ok {
+			return fmt.Errorf("option %s cannot be set along with segmentStoreHostNetwork", key)
+		}
+		for _, option := range p.Spec.Pravega.SegmentStoreJVMOptions {
+			if strings.HasPrefix(option, "-D"+key+"=") {
+				return fmt.Errorf("option %s cannot be set along with segmentStoreHostNetwork", key)
+			}
+		}
+	}
+	if securityContext := p.Spec.Pravega.SegmentStoreSecurityContext; securityContext != nil {
+		for _, sysctl := range securityContext.Sysctls {
+			if strings.HasPrefix(sysctl.Name, "net.") {
+				return fmt.Errorf("sysctl %s cannot be set along with segmentStoreHostNetwork, the pods share the network namespace of their node", sysctl.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSegmentStoreExternalTrafficPort checks that the ports of the external segment
+// store services are valid ports, and that the services are load balancers, as the
+// segment stores behind NodePort services advertise their node port
+func (p *PravegaCluster) ValidateSegmentStoreExternalTrafficPort() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStoreExternalTrafficPort == 0 {
+		return nil
+	}
+	port := p.Spec.Pravega.SegmentStoreExternalTrafficPort
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("segmentStoreExternalTrafficPort must be between 1 and 65535, got %d", port)
+	}
+	if p.Spec.Pravega.SegmentStoreLoadBalancerIP != "" && port+p.Spec.Pravega.SegmentStoreReplicas-1 > 65535 {
+		return fmt.Errorf("segmentStoreExternalTrafficPort %d leaves no port for the %d segment stores sharing segmentStoreLoadBalancerIP",
+			port, p.Spec.Pravega.SegmentStoreReplicas)
+	}
+	serviceType := p.Spec.Pravega.SegmentStoreExternalServiceType
+	if serviceType == "" && p.Spec.ExternalAccess != nil {
+		serviceType = p.Spec.ExternalAccess.Type
+	}
+	if serviceType == corev1.ServiceTypeNodePort {
+		return fmt.Errorf("segmentStoreExternalTrafficPort cannot be set with NodePort services, the segment stores advertise their node port")
+	}
+	return nil
+}
+
+// SegmentStoreExternalTrafficPort returns the port of the external service of the
+// segment store with the given ordinal. The services sharing segmentStoreLoadBalancerIP
+// use consecutive ports
+func (p *PravegaCluster) SegmentStoreExternalTrafficPort(ordinal int32) int32 {
+	port := int32(DefaultSegmentStoreExternalTrafficPort)
+	if p.Spec.Pravega.SegmentStoreExternalTrafficPort != 0 {
+		port = p.Spec.Pravega.SegmentStoreExternalTrafficPort
+	}
+	if p.Spec.Pravega.SegmentStoreLoadBalancerIP != "" {
+		return port + ordinal
+	}
+	return port
+}
+
+// ValidateSegmentStoreTopologySpreadConstraints checks that the segment store spread
+// constraints have a topology key and a positive max skew
+func (p *PravegaCluster) ValidateSegmentStoreTopologySpreadConstraints() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	for i, constraint := range p.Spec.Pravega.SegmentStoreTopologySpreadConstraints {
+		if constraint.TopologyKey == "" {
+			return fmt.Errorf("segmentStoreTopologySpreadConstraints[%d].topologyKey must be set", i)
+		}
+		if constraint.MaxSkew < 1 {
+			return fmt.Errorf("segmentStoreTopologySpreadConstraints[%d].maxSkew must be greater than 0, got %d", i, constraint.MaxSkew)
+		}
+	}
+	return nil
+}
+
+// ValidatePodAffinities checks the terms of the controller and segment store pod
+// affinities, which are merged with the anti-affinity generated by the operator
+func (p *PravegaCluster) ValidatePodAffinities() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	if err := validateAffinity("controllerPodAffinity", p.Spec.Pravega.ControllerPodAffinity); err != nil {
+		return err
+	}
+	return validateAffinity("segmentStorePodAffinity", p.Spec.Pravega.SegmentStorePodAffinity)
+}
+
+func validateAffinity(path string, affinity *corev1.Affinity) error {
+	if affinity == nil {
+		return nil
+	}
+	if nodeAffinity := affinity.NodeAffinity; nodeAffinity != nil {
+		path := path + ".nodeAffinity"
+		if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			if len(required.NodeSelectorTerms) == 0 {
+				return fmt.Errorf("%s.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms must not be empty", path)
+			}
+			for i, term := range required.NodeSelectorTerms {
+				if err := validateNodeSelectorTerm(fmt.Sprintf("%s.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[%d]", path, i), term); err != nil {
+					return err
+				}
+			}
+		}
+		for i, preferred := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			termPath := fmt.Sprintf("%s.preferredDuringSchedulingIgnoredDuringExecution[%d]", path, i)
+			if err := validateAffinityWeight(termPath, preferred.Weight); err != nil {
+				return err
+			}
+			if err := validateNodeSelectorTerm(termPath+".preference", preferred.Preference); err != nil {
+				return err
+			}
+		}
+	}
+	if podAffinity := affinity.PodAffinity; podAffinity != nil {
+		err := validatePodAffinityTerms(path+".podAffinity", podAffinity.RequiredDuringSchedulingIgnoredDuringExecution, podAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		if err != nil {
+			return err
+		}
+	}
+	if podAntiAffinity := affinity.PodAntiAffinity; podAntiAffinity != nil {
+		err := validatePodAffinityTerms(path+".podAntiAffinity", podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAffinityWeight(path string, weight int32) error {
+	if weight < 1 || weight > 100 {
+		return fmt.Errorf("%s.weight must be between 1 and 100, got %d", path, weight)
+	}
+	return nil
+}
+
+func validateNodeSelectorTerm(path string, term corev1.NodeSelectorTerm) error {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return fmt.Errorf("%s must have at least one match expression or field", path)
+	}
+	for i, requirement := range term.MatchExpressions {
+		requirementPath := fmt.Sprintf("%s.matchExpressions[%d]", path, i)
+		if errs := validation.IsQualifiedName(requirement.Key); len(errs) != 0 {
+			return fmt.Errorf("%s key %s is not a valid label key: %s", requirementPath, requirement.Key, strings.Join(errs, "; "))
+		}
+		if err := validateNodeSelectorRequirement(requirementPath, requirement); err != nil {
+			return err
+		}
+	}
+	for i, requirement := range term.MatchFields {
+		requirementPath := fmt.Sprintf("%s.matchFields[%d]", path, i)
+		if requirement.Key != "metadata.name" {
+			return fmt.Errorf("%s key must be metadata.name, got %s", requirementPath, requirement.Key)
+		}
+		if err := validateNodeSelectorRequirement(requirementPath, requirement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNodeSelectorRequirement(path string, requirement corev1.NodeSelectorRequirement) error {
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+		if len(requirement.Values) == 0 {
+			return fmt.Errorf("%s values must not be empty with the %s operator", path, requirement.Operator)
+		}
+	case corev1.NodeSelectorOpExists, corev1.NodeSelectorOpDoesNotExist:
+		if len(requirement.Values) != 0 {
+			return fmt.Errorf("%s values must be empty with the %s operator", path, requirement.Operator)
+		}
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if len(requirement.Values) != 1 {
+			return fmt.Errorf("%s must have a single value with the %s operator", path, requirement.Operator)
+		}
+		if _, err := strconv.ParseInt(requirement.Values[0], 10, 64); err != nil {
+			return fmt.Errorf("%s value must be an integer with the %s operator, got %s", path, requirement.Operator, requirement.Values[0])
+		}
+	default:
+		return fmt.Errorf("%s operator %s is not supported", path, requirement.Operator)
+	}
+	return nil
+}
+
+func validatePodAffinityTerms(path string, required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) error {
+	for i, term := range required {
+		if err := validatePodAffinityTerm(fmt.Sprintf("%s.requiredDuringSchedulingIgnoredDuringExecution[%d]", path, i), term); err != nil {
+			return err
+		}
+	}
+	for i, weighted := range preferred {
+		termPath := fmt.Sprintf("%s.preferredDuringSchedulingIgnoredDuringExecution[%d]", path, i)
+		if err := validateAffinityWeight(termPath, weighted.Weight); err != nil {
+			return err
+		}
+		if err := validatePodAffinityTerm(termPath+".podAffinityTerm", weighted.PodAffinityTerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePodAffinityTerm(path string, term corev1.PodAffinityTerm) error {
+	if term.TopologyKey == "" {
+		return fmt.Errorf("%s.topologyKey must be set", path)
+	}
+	if errs := validation.IsQualifiedName(term.TopologyKey); len(errs) != 0 {
+		return fmt.Errorf("%s.topologyKey %s is not a valid label key: %s", path, term.TopologyKey, strings.Join(errs, "; "))
+	}
+	if _, err := metav1.LabelSelectorAsSelector(term.LabelSelector); err != nil {
+		return fmt.Errorf("%s.labelSelector is invalid: %v", path, err)
+	}
+	return nil
+}
+
+// ValidateLongTermStorageProbeInterval checks that the long term storage is not probed
+// more often than every 10 seconds
+func (p *PravegaCluster) ValidateLongTermStorageProbeInterval() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.LongTermStorageProbeIntervalSeconds == nil {
+		return nil
+	}
+	if interval := *p.Spec.Pravega.LongTermStorageProbeIntervalSeconds; interval < MinLongTermStorageProbeIntervalSeconds {
+		return fmt.Errorf("longTermStorageProbeIntervalSeconds must be at least %d, got %d", MinLongTermStorageProbeIntervalSeconds, interval)
+	}
+	return nil
+}
+
+// ValidateSegmentStoreTerminationGracePeriod checks that the segment store termination
+// grace period, if set, is not negative.
+func (p *PravegaCluster) ValidateSegmentStoreTerminationGracePeriod() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds == nil {
+		return nil
+	}
+	if *p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds < 0 {
+		return fmt.Errorf("segmentStoreTerminationGracePeriodSeconds must not be negative, got %d", *p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds)
+	}
+	return nil
+}
+
+// containerCountOptions are the options setting the number of segment containers, on the
+// Controller and the Segment Stores, before and since Pravega 0.7
+var containerCountOptions = []string{
+	"controller.containerCount",
+	"controller.container.count",
+	"pravegaservice.containerCount",
+	"pravegaservice.container.count",
+}
+
+// ValidateSegmentStoreContainerCount checks that there are at least as many segment
+// containers as segment stores, and that the count is not also set in the options.
+func (p *PravegaCluster) ValidateSegmentStoreContainerCount() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStoreContainerCount == nil {
+		return nil
+	}
+	count := *p.Spec.Pravega.SegmentStoreContainerCount
+	if count < 1 {
+		return fmt.Errorf("segmentStoreContainerCount must be positive, got %d", count)
+	}
+	if count < p.Spec.Pravega.SegmentStoreReplicas {
+		return fmt.Errorf("segmentStoreContainerCount %d must not be below segmentStoreReplicas %d", count, p.Spec.Pravega.SegmentStoreReplicas)
+	}
+	for _, key := range containerCountOptions {
+		if _, ok := p.Spec.Pravega.Options[key]; ok {
+			return fmt.Errorf("option %s cannot be set along with segmentStoreContainerCount", key)
+		}
+	}
+	return nil
+}
+
+// ValidateControllerProbes checks that the controller probes have a non zero failure
+// threshold and no negative timings.
+func (p *PravegaCluster) ValidateControllerProbes() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.ControllerProbes == nil {
+		return nil
+	}
+	probes := []struct {
+		name  string
+		probe *ProbeSpec
+	}{
+		{"readinessProbe", p.Spec.Pravega.ControllerProbes.ReadinessProbe},
+		{"livenessProbe", p.Spec.Pravega.ControllerProbes.LivenessProbe},
+	}
+	for _, named := range probes {
+		name, probe := named.name, named.probe
+		if probe == nil {
+			continue
+		}
+		if probe.FailureThreshold < 1 {
+			return fmt.Errorf("controllerProbes.%s.failureThreshold must be greater than 0, got %d", name, probe.FailureThreshold)
+		}
+		if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.TimeoutSeconds < 0 {
+			return fmt.Errorf("controllerProbes.%s timings must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// ValidateJVMDerivedResources checks that the resources derived from the JVM options
+// of the components whose resources are not set can be computed.
+func (p *PravegaCluster) ValidateJVMDerivedResources() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.JVMDerivedResources == nil {
+		return nil
+	}
+	spec := p.Spec.Pravega
+	if spec.ControllerResources == nil && spec.derivesResources(spec.ControllerJvmOptions) {
+		_, err := spec.deriveResources(spec.ControllerJvmOptions, DefaultControllerRequestCPU, DefaultControllerLimitCPU)
+		if err != nil {
+			return fmt.Errorf("failed to derive controller resources from the JVM options: %v", err)
+		}
+	}
+	if spec.SegmentStoreResources == nil && spec.derivesResources(spec.SegmentStoreJVMOptions) {
+		_, err := spec.deriveResources(spec.SegmentStoreJVMOptions, DefaultSegmentStoreRequestCPU, DefaultSegmentStoreLimitCPU)
+		if err != nil {
+			return fmt.Errorf("failed to derive segment store resources from the JVM options: %v", err)
+		}
+	}
+	return nil
+}
+
+// ValidateControllerRequestTimeouts checks that the controller request timeouts are in range
+func (p *PravegaCluster) ValidateControllerRequestTimeouts() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.ControllerRequestTimeouts == nil {
+		return nil
+	}
+	timeouts := p.Spec.Pravega.ControllerRequestTimeouts
+	if timeout := timeouts.RequestTimeoutSeconds; timeout != nil && (*timeout < 1 || *timeout > 3600) {
+		return fmt.Errorf("controllerRequestTimeouts.requestTimeoutSeconds must be between 1 and 3600, got %d", *timeout)
+	}
+	if lease := timeouts.TransactionMaxLeaseSeconds; lease != nil && (*lease < 1 || *lease > 86400) {
+		return fmt.Errorf("controllerRequestTimeouts.transactionMaxLeaseSeconds must be between 1 and 86400, got %d", *lease)
+	}
+	return nil
+}
+
+// ValidateUpgradeConfig checks that at least one segment store pod can be upgraded at once
+// and that the pod ready and terminating pod timeouts are positive
+func (p *PravegaCluster) ValidateUpgradeConfig() error {
+	if p.Spec.UpgradeConfig == nil {
+		return nil
+	}
+	if max := p.Spec.UpgradeConfig.SegmentStoreMaxUnavailable; max != nil && *max < 1 {
+		return fmt.Errorf("upgradeConfig.segmentStoreMaxUnavailable must be at least 1, got %d", *max)
+	}
+	if timeout := p.Spec.UpgradeConfig.PodReadyTimeoutSeconds; timeout != nil && *timeout < 1 {
+		return fmt.Errorf("upgradeConfig.podReadyTimeoutSeconds must be at least 1, got %d", *timeout)
+	}
+	if timeout := p.Spec.UpgradeConfig.TerminatingPodTimeoutSeconds; timeout != nil && *timeout < 1 {
+		return fmt.Errorf("upgradeConfig.terminatingPodTimeoutSeconds must be at least 1, got %d", *timeout)
+	}
+	return nil
+}
+
+// ValidateScalingStallTimeout checks that the scaling stall timeout is positive
+func (p *PravegaCluster) ValidateScalingStallTimeout() error {
+	if timeout := p.Spec.ScalingStallTimeoutSeconds; timeout != nil && *timeout < 1 {
+		return fmt.Errorf("scalingStallTimeoutSeconds must be at least 1, got %d", *timeout)
+	}
+	return nil
+}
+
+// ValidateVersionHistoryLimit checks that the upgrade history keeps at least one entry
+func (p *PravegaCluster) ValidateVersionHistoryLimit() error {
+	if limit := p.Spec.VersionHistoryLimit; limit != nil && *limit < 1 {
+		return fmt.Errorf("versionHistoryLimit must be at least 1, got %d", *limit)
+	}
+	return nil
+}
+
+// ValidateMaintenanceWindows checks that the maintenance windows have a valid
+// schedule matching at least once and a duration between MinMaintenanceWindowDuration
+// and MaxMaintenanceWindowDuration.
+func (p *PravegaCluster) ValidateMaintenanceWindows() error {
+	for i, window := range p.Spec.MaintenanceWindows {
+		schedule, err := util.ParseSchedule(window.Schedule)
+		if err != nil {
+			return fmt.Errorf("maintenanceWindows[%d].schedule is invalid: %v", i, err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			return fmt.Errorf("maintenanceWindows[%d].schedule %q never matches", i, window.Schedule)
+		}
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return fmt.Errorf("maintenanceWindows[%d].duration %s is not a valid duration: %v", i, window.Duration, err)
+		}
+		if duration < MinMaintenanceWindowDuration || duration > MaxMaintenanceWindowDuration {
+			return fmt.Errorf("maintenanceWindows[%d].duration must be between %v and %v, got %s", i, MinMaintenanceWindowDuration, MaxMaintenanceWindowDuration, window.Duration)
+		}
+	}
+	return nil
+}
+
+// InMaintenanceWindow returns true if disruptive actions can be performed at the
+// given time, that is if no maintenance window is set or one of them is open.
+func (p *PravegaCluster) InMaintenanceWindow(now time.Time) bool {
+	if len(p.Spec.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, window := range p.Spec.MaintenanceWindows {
+		schedule, duration, ok := window.parse()
+		if !ok {
+			continue
+		}
+		// The window is open if it started within the last duration
+		if start := schedule.Next(now.Add(-duration)); !start.IsZero() && !start.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextMaintenanceWindow returns the start time of the next maintenance window
+// after the given time, or the zero time if there is none.
+func (p *PravegaCluster) NextMaintenanceWindow(now time.Time) time.Time {
+	var next time.Time
+	for _, window := range p.Spec.MaintenanceWindows {
+		schedule, _, ok := window.parse()
+		if !ok {
+			continue
+		}
+		if start := schedule.Next(now); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// ValidateJVMFlavor checks that the JVM flavor is one of the supported flavors
+func (p *PravegaCluster) ValidateJVMFlavor() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	switch p.Spec.Pravega.JVMFlavor {
+	case "", JVMFlavorHotSpot, JVMFlavorTemurin, JVMFlavorGraalVM, JVMFlavorOpenJ9, JVMFlavorCustom:
+		return nil
+	}
+	return fmt.Errorf("jvmFlavor %s is not supported, it must be one of %s, %s, %s, %s and %s", p.Spec.Pravega.JVMFlavor,
+		JVMFlavorHotSpot, JVMFlavorTemurin, JVMFlavorGraalVM, JVMFlavorOpenJ9, JVMFlavorCustom)
+}
+
+// ValidateConfigMapReconcilePolicy checks that the configmap reconcile policy is either
+// Enforce or Ignore
+func (p *PravegaCluster) ValidateConfigMapReconcilePolicy() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	switch p.Spec.Pravega.ConfigMapReconcilePolicy {
+	case "", ConfigMapReconcilePolicyEnforce, ConfigMapReconcilePolicyIgnore:
+		return nil
+	}
+	return fmt.Errorf("configMapReconcilePolicy %s is invalid, it must be either %s or %s", p.Spec.Pravega.ConfigMapReconcilePolicy,
+		ConfigMapReconcilePolicyEnforce, ConfigMapReconcilePolicyIgnore)
+}
+
+// ValidateClusterDomain checks that the cluster domain, if set, is a DNS name without a
+// trailing dot
+func (p *PravegaCluster) ValidateClusterDomain() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.ClusterDomain == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(p.Spec.Pravega.ClusterDomain); len(errs) != 0 {
+		return fmt.Errorf("clusterDomain %s is not a valid DNS name: %s", p.Spec.Pravega.ClusterDomain, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ValidateSegmentStoreCachePVCReclaimPolicy checks that the cache claim reclaim policy is
+// either Retain or Delete
+func (p *PravegaCluster) ValidateSegmentStoreCachePVCReclaimPolicy() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	switch p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy {
+	case "", SegmentStoreCachePVCReclaimPolicyRetain, SegmentStoreCachePVCReclaimPolicyDelete:
+		return nil
+	}
+	return fmt.Errorf("segmentStoreCachePVCReclaimPolicy %s is invalid, it must be either %s or %s", p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy,
+		SegmentStoreCachePVCReclaimPolicyRetain, SegmentStoreCachePVCReclaimPolicyDelete)
+}
+
+// ValidateSegmentStoreUpdateStrategy checks that the segment store update strategy is
+// either RollingUpdate or OnDelete
+func (p *PravegaCluster) ValidateSegmentStoreUpdateStrategy() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	switch p.Spec.Pravega.SegmentStoreUpdateStrategy {
+	case "", SegmentStoreUpdateStrategyRollingUpdate, SegmentStoreUpdateStrategyOnDelete:
+		return nil
+	}
+	return fmt.Errorf("segmentStoreUpdateStrategy %s is invalid, it must be either %s or %s", p.Spec.Pravega.SegmentStoreUpdateStrategy,
+		SegmentStoreUpdateStrategyRollingUpdate, SegmentStoreUpdateStrategyOnDelete)
+}
+
+// ValidateSegmentStorePdb checks that the segment store disruption budget sets only one
+// of minAvailable and maxUnavailable, as a non-negative number or a percentage
+func (p *PravegaCluster) ValidateSegmentStorePdb() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStorePdb == nil {
+		return nil
+	}
+	budget := p.Spec.Pravega.SegmentStorePdb
+	if budget.MinAvailable != nil && budget.MaxUnavailable != nil {
+		return fmt.Errorf("segmentStorePdb.minAvailable and segmentStorePdb.maxUnavailable cannot be both set")
+	}
+	for _, value := range []struct {
+		name  string
+		value *intstr.IntOrString
+	}{
+		{"minAvailable", budget.MinAvailable},
+		{"maxUnavailable", budget.MaxUnavailable},
+	} {
+		if value.value == nil {
+			continue
+		}
+		// Scaled to a total of 100, a percentage is its own value
+		scaled, err := intstr.GetValueFromIntOrPercent(value.value, 100, true)
+		if err != nil || scaled < 0 || (value.value.Type == intstr.String && scaled > 100) {
+			return fmt.Errorf("segmentStorePdb.%s %s must be a non-negative number or a percentage between 0%% and 100%%", value.name, value.value.String())
+		}
+	}
+	return nil
+}
+
+// ValidateSegmentStoreInitContainers checks that the segment store init containers have
+// an image and a unique name, distinct from the names of the containers of the operator
+func (p *PravegaCluster) ValidateSegmentStoreInitContainers() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	names := map[string]bool{
+		"pravega-segmentstore": true,
+		"wait-for-dependency":  true,
+		LoggingSidecarName:     true,
+	}
+	for _, container := range p.Spec.Pravega.SegmentStoreInitContainers {
+		if container.Name == "" {
+			return fmt.Errorf("segmentStoreInitContainers must have a name")
+		}
+		if names[container.Name] {
+			return fmt.Errorf("segmentStoreInitContainers name %s is already in use", container.Name)
+		}
+		names[container.Name] = true
+		if container.Image == "" {
+			return fmt.Errorf("segmentStoreInitContainers %s must have an image", container.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateSegmentStoreVolumes checks that the segment store volumes have unique names,
+// distinct from the volumes of the operator, and that the volume mounts mount them on
+// absolute paths not used by the mounts of the operator
+func (p *PravegaCluster) ValidateSegmentStoreVolumes() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	reserved := map[string]bool{
+		"heap-dump":          true,
+		"cache":              true,
+		"journal":            true,
+		"tier2":              true,
+		"ss-secret":          true,
+		"tls-secret":         true,
+		"ca-bundle":          true,
+		"logs":               true,
+		"auth-passwd-secret": true,
+	}
+	volumes := map[string]bool{}
+	for _, volume := range p.Spec.Pravega.SegmentStoreVolumes {
+		if volume.Name == "" {
+			return fmt.Errorf("segmentStoreVolumes must have a name")
+		}
+		if reserved[volume.Name] {
+			return fmt.Errorf("segmentStoreVolumes name %s is used by the operator", volume.Name)
+		}
+		if volumes[volume.Name] {
+			return fmt.Errorf("segmentStoreVolumes name %s is already in use", volume.Name)
+		}
+		volumes[volume.Name] = true
+	}
+	paths := map[string]bool{
+		"/tmp/dumpfile/heap":           true,
+		"/tmp/pravega/cache":           true,
+		"/tmp/pravega/journal":         true,
+		"/mnt/tier2":                   true,
+		"/etc/secret-volume":           true,
+		"/etc/secret-volume/ca-bundle": true,
+	}
+	if secret := p.Spec.Pravega.SegmentStoreSecret; secret != nil && strings.TrimSpace(secret.MountPath) != "" {
+		paths[path.Clean(strings.TrimSpace(secret.MountPath))] = true
+	}
+	if sidecar := p.Spec.Pravega.LoggingSidecar; sidecar != nil {
+		mountPath := sidecar.MountPath
+		if mountPath == "" {
+			mountPath = DefaultLoggingSidecarMountPath
+		}
+		paths[path.Clean(mountPath)] = true
+	}
+	for _, mount := range p.Spec.Pravega.SegmentStoreVolumeMounts {
+		if !volumes[mount.Name] {
+			return fmt.Errorf("segmentStoreVolumeMounts %s does not mount any of the segmentStoreVolumes", mount.Name)
+		}
+		if !strings.HasPrefix(mount.MountPath, "/") {
+			return fmt.Errorf("segmentStoreVolumeMounts %s mountPath must be an absolute path, got %s", mount.Name, mount.MountPath)
+		}
+		mountPath := path.Clean(mount.MountPath)
+		if paths[mountPath] {
+			return fmt.Errorf("segmentStoreVolumeMounts %s mountPath %s is already in use", mount.Name, mount.MountPath)
+		}
+		paths[mountPath] = true
+	}
+	return nil
+}
+
+// ValidateLoggingSidecar checks that the logging sidecar has an image and that the shared
+// log volume is mounted on an absolute path
+func (p *PravegaCluster) ValidateLoggingSidecar() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.LoggingSidecar == nil {
+		return nil
+	}
+	sidecar := p.Spec.Pravega.LoggingSidecar
+	if sidecar.Image == "" {
+		return fmt.Errorf("loggingSidecar.image must be set")
+	}
+	if sidecar.MountPath != "" && !strings.HasPrefix(sidecar.MountPath, "/") {
+		return fmt.Errorf("loggingSidecar.mountPath must be an absolute path, got %s", sidecar.MountPath)
+	}
+	for _, env := range sidecar.Env {
+		if env.Name == "LOG_DIR" || env.Name == "LOG_DESTINATION" {
+			return fmt.Errorf("loggingSidecar.env must not set %s, it is set by the operator", env.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateCommandOverrides checks that the debugging command overrides of the Controller
+// and the Segment Store have no empty entries, and that they are not combined with
+// settings relying on the default launch of Pravega: the logging sidecar forwards the
+// log files it writes, and the segment stores publish their external address from it
+func (p *PravegaCluster) ValidateCommandOverrides() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	pravega := p.Spec.Pravega
+	overrides := []struct {
+		field  string
+		values []string
+	}{
+		{"controllerCommand", pravega.ControllerCommand},
+		{"controllerArgs", pravega.ControllerArgs},
+		{"segmentStoreCommand", pravega.SegmentStoreCommand},
+		{"segmentStoreArgs", pravega.SegmentStoreArgs},
+	}
+	for _, o := range overrides {
+		for i, value := range o.values {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s[%d] must not be empty", o.field, i)
+			}
+		}
+	}
+	if !pravega.HasCommandOverride() {
+		return nil
+	}
+	if pravega.LoggingSidecar != nil {
+		return fmt.Errorf("the command overrides cannot be combined with loggingSidecar, which relies on the default launch of Pravega")
+	}
+	if pravega.hasSegmentStoreCommandOverride() && p.Spec.ExternalAccess != nil && p.Spec.ExternalAccess.Enabled {
+		return fmt.Errorf("segmentStoreCommand and segmentStoreArgs cannot be combined with externalAccess, which relies on the default launch of the Segment Store")
+	}
+	return nil
+}
+
+// ValidateImagePullPolicies checks that the pull policies of the Pravega images and of the
+// logging sidecar image are either Always, IfNotPresent or Never
+func (p *PravegaCluster) ValidateImagePullPolicies() error {
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	check := func(field string, policy corev1.PullPolicy) error {
+		switch policy {
+		case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+			return nil
+		}
+		return fmt.Errorf("%s %s is invalid, it must be one of %s, %s and %s", field, policy,
+			corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever)
+	}
+	for _, image := range []struct {
+		field string
+		spec  *ImageSpec
+	}{
+		{"image", p.Spec.Pravega.Image},
+		{"controllerImage", p.Spec.Pravega.ControllerImage},
+		{"segmentStoreImage", p.Spec.Pravega.SegmentStoreImage},
+	} {
+		if image.spec == nil {
+			continue
+		}
+		if err := check(image.field+".pullPolicy", image.spec.PullPolicy); err != nil {
+			return err
+		}
+	}
+	if p.Spec.Pravega.LoggingSidecar != nil {
+		return check("loggingSidecar.imagePullPolicy", p.Spec.Pravega.LoggingSidecar.ImagePullPolicy)
+	}
+	return nil
+}
+
+// ValidateTLS checks that the TLS secrets have valid names, and that the secret of the
+// Controller, respectively of the Segment Store, is set if TLS is enabled on it through
+// the options
+func (p *PravegaCluster) ValidateTLS() error {
+	var static StaticTLS
+	if p.Spec.TLS != nil && p.Spec.TLS.Static != nil {
+		static = *p.Spec.TLS.Static
+	}
+	secrets := []struct {
+		field string
+		name  string
+	}{
+		{"tls.static.controllerSecret", static.ControllerSecret},
+		{"tls.static.segmentStoreSecret", static.SegmentStoreSecret},
+		{"tls.static.caBundle", static.CaBundle},
+	}
+	for _, secret := range secrets {
+		if secret.name == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(secret.name); len(errs) != 0 {
+			return fmt.Errorf("%s %s is not a valid secret name: %s", secret.field, secret.name, strings.Join(errs, ", "))
+		}
+	}
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	options := p.Spec.Pravega.Options
+	for _, key := range []string{"controller.security.tls.enable", "controller.auth.tlsEnabled"} {
+		if options[key] == "true" && static.ControllerSecret == "" {
+			return fmt.Errorf("tls.static.controllerSecret must be set as %s is true", key)
+		}
+	}
+	for _, key := range []string{"pravegaservice.security.tls.enable", "pravegaservice.enableTls"} {
+		if options[key] == "true" && static.SegmentStoreSecret == "" {
+			return fmt.Errorf("tls.static.segmentStoreSecret must be set as %s is true", key)
+		}
+	}
+	return p.validateTLSKeys(static)
+}
+
+// validateTLSKeys checks the keys of the TLS secrets, which must be valid secret keys of
+// a set secret, and must not be set along with the options they set
+func (p *PravegaCluster) validateTLSKeys(static StaticTLS) error {
+	components := []struct {
+		field   string
+		secret  string
+		keys    *TLSSecretKeys
+		options [3]string
+	}{
+		{"tls.static.controllerKeys", static.ControllerSecret, static.ControllerKeys, controllerTLSKeyOptions},
+		{"tls.static.segmentStoreKeys", static.SegmentStoreSecret, static.SegmentStoreKeys, segmentStoreTLSKeyOptions},
+	}
+	for _, component := range components {
+		if component.keys == nil {
+			continue
+		}
+		keys := []struct {
+			field string
+			key   string
+		}{
+			{"certificate", component.keys.Certificate},
+			{"privateKey", component.keys.PrivateKey},
+			{"caCertificate", component.keys.CaCertificate},
+		}
+		for _, key := range keys {
+			if key.key == "" {
+				continue
+			}
+			if component.secret == "" {
+				return fmt.Errorf("%s.%s requires the secret of the component to be set", component.field, key.field)
+			}
+			if errs := validation.IsConfigMapKey(key.key); len(errs) != 0 {
+				return fmt.Errorf("%s.%s %s is not a valid secret key: %s", component.field, key.field, key.key, strings.Join(errs, ", "))
+			}
+		}
+		if p.Spec.Pravega == nil {
+			continue
+		}
+		for option := range component.keys.keyOptions(component.options) {
+			if _, ok := p.Spec.Pravega.Options[option]; ok {
+				return fmt.Errorf("option %s cannot be set along with %s", option, component.field)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateTLSSecretKeys runs the TLS checks, and checks that the keys set in
+// tls.static.controllerKeys and tls.static.segmentStoreKeys exist in their secrets
+func (p *PravegaCluster) ValidateTLSSecretKeys(kubeClient client.Client) error {
+	err := p.ValidateTLS()
+	if err != nil || p.Spec.TLS == nil || p.Spec.TLS.Static == nil {
+		return err
+	}
+	static := p.Spec.TLS.Static
+	components := []struct {
+		field      string
+		secret     string
+		keyOptions map[string]string
+	}{
+		{"tls.static.controllerSecret", static.ControllerSecret, p.Spec.TLS.ControllerKeyOptions()},
+		{"tls.static.segmentStoreSecret", static.SegmentStoreSecret, p.Spec.TLS.SegmentStoreKeyOptions()},
+	}
+	for _, component := range components {
+		if len(component.keyOptions) == 0 {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err = kubeClient.Get(context.TODO(), types.NamespacedName{Name: component.secret, Namespace: p.Namespace}, secret)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Errorf("%s %s not found in namespace %s", component.field, component.secret, p.Namespace)
+			}
+			return fmt.Errorf("failed to get secret (%s): %v", component.secret, err)
+		}
+		keys := []string{}
+		for _, key := range component.keyOptions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := secret.Data[key]; !ok {
+				return fmt.Errorf("%s %s has no key %s", component.field, component.secret, key)
+			}
+		}
+	}
+	return nil
+}
+
+// tokenSigningKeyOptions are the Pravega options setting the key signing the delegation
+// tokens, which are managed by authentication.controllerTokenSecret when it is set
+var tokenSigningKeyOptions = []string{
+	"controller.security.auth.delegationToken.signingKey.basis",
+	"controller.auth.tokenSigningKey",
+	"autoScale.controller.security.auth.tokenSigningKey",
+	"autoScale.security.auth.token.signingKey.basis",
+	"autoScale.tokenSigningKey",
+}
+
+// ValidateAuthentication checks the token signing key secret, and that it exists along
+// with its key when authentication is enabled
+func (p *PravegaCluster) ValidateAuthentication(kubeClient client.Client) error {
+	err := p.validateAuthenticationSpec()
+	if err != nil {
+		return err
+	}
+	auth := p.Spec.Authentication
+	if !auth.IsEnabled() || auth.ControllerTokenSecret == nil {
+		return nil
+	}
+	ref := auth.ControllerTokenSecret
+	secret := &corev1.Secret{}
+	err = kubeClient.Get(context.TODO(), types.NamespacedName{Name: ref.Name, Namespace: p.Namespace}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("authentication.controllerTokenSecret %s not found in namespace %s", ref.Name, p.Namespace)
+		}
+		return fmt.Errorf("failed to get secret (%s): %v", ref.Name, err)
+	}
+	if _, ok := secret.Data[ref.Key]; !ok {
+		return fmt.Errorf("authentication.controllerTokenSecret %s has no key %s", ref.Name, ref.Key)
+	}
+	return nil
+}
+
+// validateAuthenticationSpec runs the checks of the authentication which do not need the
+// API server. The token signing key options must not be set along with the secret, so
+// that the key is only configured in one place
+func (p *PravegaCluster) validateAuthenticationSpec() error {
+	if p.Spec.Authentication == nil || p.Spec.Authentication.ControllerTokenSecret == nil {
+		return nil
+	}
+	ref := p.Spec.Authentication.ControllerTokenSecret
+	if errs := validation.IsDNS1123Subdomain(ref.Name); len(errs) != 0 {
+		return fmt.Errorf("authentication.controllerTokenSecret %s is not a valid secret name: %s", ref.Name, strings.Join(errs, ", "))
+	}
+	if ref.Key == "" {
+		return fmt.Errorf("authentication.controllerTokenSecret.key must be set")
+	}
+	if p.Spec.Pravega == nil {
+		return nil
+	}
+	for _, key := range tokenSigningKeyOptions {
+		if _, ok := p.Spec.Pravega.Options[key]; ok {
+			return fmt.Errorf("option %s cannot be set along with authentication.controllerTokenSecret", key)
+		}
+	}
+	return nil
+}
+
+// ValidateJournalVolume checks that the journal volume has a size between
+// MinJournalVolumeSize and MaxJournalVolumeSize and a valid storage class name.
+func (p *PravegaCluster) ValidateJournalVolume() error {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.SegmentStoreJournalVolume == nil {
+		return nil
+	}
+	journal := p.Spec.Pravega.SegmentStoreJournalVolume
+	size, err := resource.ParseQuantity(journal.Size)
+	if err != nil {
+		return fmt.Errorf("segmentStoreJournalVolume.size %s is not a valid quantity: %v", journal.Size, err)
+	}
+	if size.Cmp(resource.MustParse(MinJournalVolumeSize)) < 0 || size.Cmp(resource.MustParse(MaxJournalVolumeSize)) > 0 {
+		return fmt.Errorf("segmentStoreJournalVolume.size must be between %s and %s, got %s", MinJournalVolumeSize, MaxJournalVolumeSize, journal.Size)
+	}
+	if journal.StorageClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(journal.StorageClassName); len(errs) != 0 {
+			return fmt.Errorf("segmentStoreJournalVolume.storageClassName %s is not a valid name: %s", journal.StorageClassName, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// ValidateRunAsIdentity checks that the user and group IDs read from the
+// runAsIdentitySecret are not negative, and are non-zero if the security context
+// of the controller or of the segment store sets runAsNonRoot.
+func (p *PravegaCluster) ValidateRunAsIdentity(identity *RunAsIdentity) error {
+	if identity.RunAsUser < 0 {
+		return fmt.Errorf("runAsUser must not be negative, got %d", identity.RunAsUser)
+	}
+	if identity.RunAsGroup != nil && *identity.RunAsGroup < 0 {
+		return fmt.Errorf("runAsGroup must not be negative, got %d", *identity.RunAsGroup)
+	}
+	if !p.runAsNonRoot() {
+		return nil
+	}
+	if identity.RunAsUser == 0 {
+		return fmt.Errorf("runAsUser must be non-zero as runAsNonRoot is set")
+	}
+	if identity.RunAsGroup != nil && *identity.RunAsGroup == 0 {
+		return fmt.Errorf("runAsGroup must be non-zero as runAsNonRoot is set")
+	}
+	return nil
+}
+
+// runAsNonRoot checks whether the pod or container security context of the controller
+// or of the segment store requires the containers to run as a non-root user
+func (p *PravegaCluster) runAsNonRoot() bool {
+	for _, securityContext := range []*corev1.PodSecurityContext{p.Spec.Pravega.ControllerSecurityContext, p.Spec.Pravega.SegmentStoreSecurityContext} {
+		if securityContext != nil && securityContext.RunAsNonRoot != nil && *securityContext.RunAsNonRoot {
+			return true
+		}
+	}
+	for _, securityContext := range []*corev1.SecurityContext{p.Spec.Pravega.ControllerContainerSecurityContext, p.Spec.Pravega.SegmentStoreContainerSecurityContext} {
+		if securityContext != nil && securityContext.RunAsNonRoot != nil && *securityContext.RunAsNonRoot {
+			return true
+		}
+	}
+	return false
+}
+
+//to return name of segmentstore based on the version
+func (p *PravegaCluster) StatefulSetNameForSegmentstore() string {
+	if util.IsVersionBelow07(p.Spec.Version) {
+		return p.StatefulSetNameForSegmentstoreBelow07()
+	}
+	return p.StatefulSetNameForSegmentstoreAbove07()
+}
+
+//if version is above or equals to 0.7 this name will be assigned
+func (p *PravegaCluster) StatefulSetNameForSegmentstoreAbove07() string {
+	return fmt.Sprintf("%s-pravega-segment-store", p.Name)
+}
+
+//if version is below 0.7 this name will be assigned
+func (p *PravegaCluster) StatefulSetNameForSegmentstoreBelow07() string {
+	return fmt.Sprintf("%s-pravega-segmentstore", p.Name)
+}
+
+// PravegaControllerServiceURL returns the URL of the Controller service, fully qualified
+// with the cluster domain if set
+func (p *PravegaCluster) PravegaControllerServiceURL() string {
+	if p.Spec.Pravega != nil && p.Spec.Pravega.ClusterDomain != "" {
+		return fmt.Sprintf("tcp://%v.%v.svc.%v:%v", p.ServiceNameForController(), p.Namespace, p.Spec.Pravega.ClusterDomain, "9090")
+	}
+	return fmt.Sprintf("tcp://%v.%v:%v", p.ServiceNameForController(), p.Namespace, "9090")
+}
+
+func (p *PravegaCluster) ServiceMonitorName() string {
+	return fmt.Sprintf("%s-pravega", p.Name)
+}
+
+func (p *PravegaCluster) LabelsForController() map[string]string {
+	labels := p.LabelsForPravegaCluster()
+	labels["component"] = "pravega-controller"
+	return labels
+}
+
+func (p *PravegaCluster) LabelsForSegmentStore() map[string]string {
+	labels := p.LabelsForPravegaCluster()
+	labels["component"] = "pravega-segmentstore"
+	return labels
+}
+
+func (pravegaCluster *PravegaCluster) LabelsForPravegaCluster() map[string]string {
+	return map[string]string{
+		"app":             "pravega-cluster",
+		"pravega_cluster": pravegaCluster.Name,
+	}
+}
+
+func (p *PravegaCluster) PdbNameForController() string {
+	return fmt.Sprintf("%s-pravega-controller", p.Name)
 }
 
 func (p *PravegaCluster) ConfigMapNameForController() string {
@@ -1232,6 +3120,59 @@ func (p *PravegaCluster) ConfigMapNameForSegmentstore() string {
 	return fmt.Sprintf("%s-pravega-segmentstore", p.Name)
 }
 
+func (p *PravegaCluster) ConfigMapNameForGrafanaDashboard() string {
+	return fmt.Sprintf("%s-pravega-dashboard", p.Name)
+}
+
+// MetricName returns the Prometheus name of the given Pravega metric, e.g.
+// "segmentstore_segment_write_bytes_total", under the metrics prefix of the
+// cluster. Prometheus replaces the '.' of the prefix with '_'.
+func (p *PravegaCluster) MetricName(name string) string {
+	prefix := "pravega"
+	if p.Spec.Pravega != nil {
+		if p.Spec.Pravega.Metrics != nil && p.Spec.Pravega.Metrics.Prefix != "" {
+			prefix = p.Spec.Pravega.Metrics.Prefix
+		} else if option := p.Spec.Pravega.Options["metrics.metricsPrefix"]; option != "" {
+			prefix = option
+		}
+	}
+	return strings.Replace(prefix, ".", "_", -1) + "_" + name
+}
+
+// ConfigMapNameForRendered returns the name of the ConfigMap holding the configuration
+// rendered by the operator for the Controller and the Segment Store
+func (p *PravegaCluster) ConfigMapNameForRendered() string {
+	return fmt.Sprintf("%s-pravega-rendered", p.Name)
+}
+
+// ControllerResourceRequirements returns the resources of the controller container,
+// derived from the controller JVM options when they are not set
+func (p *PravegaCluster) ControllerResourceRequirements() *corev1.ResourceRequirements {
+	if p.Spec.Pravega.ControllerResources != nil {
+		return p.Spec.Pravega.ControllerResources
+	}
+	resources, err := p.Spec.Pravega.deriveResources(p.Spec.Pravega.ControllerJvmOptions, DefaultControllerRequestCPU, DefaultControllerLimitCPU)
+	if err != nil {
+		// Rejected by the webhook, fall back to the default resources
+		return defaultControllerResources()
+	}
+	return resources
+}
+
+// SegmentStoreResourceRequirements returns the resources of the segment store container,
+// derived from the segment store JVM options when they are not set
+func (p *PravegaCluster) SegmentStoreResourceRequirements() *corev1.ResourceRequirements {
+	if p.Spec.Pravega.SegmentStoreResources != nil {
+		return p.Spec.Pravega.SegmentStoreResources
+	}
+	resources, err := p.Spec.Pravega.deriveResources(p.Spec.Pravega.SegmentStoreJVMOptions, DefaultSegmentStoreRequestCPU, DefaultSegmentStoreLimitCPU)
+	if err != nil {
+		// Rejected by the webhook, fall back to the default resources
+		return defaultSegmentStoreResources()
+	}
+	return resources
+}
+
 func (p *PravegaCluster) GetClusterExpectedSize() (size int) {
 	return int(p.Spec.Pravega.ControllerReplicas + p.Spec.Pravega.SegmentStoreReplicas)
 }
@@ -1247,6 +3188,111 @@ func (p *PravegaCluster) PravegaTargetImage() (string, error) {
 	return fmt.Sprintf("%s:%s", p.Spec.Pravega.Image.Repository, p.Status.TargetVersion), nil
 }
 
+// ControllerImage returns the Controller image of the cluster version
+func (p *PravegaCluster) ControllerImage() string {
+	return fmt.Sprintf("%s:%s", p.componentImage(p.Spec.Pravega.ControllerImage).Repository, p.Spec.Version)
+}
+
+// ControllerTargetImage returns the Controller image of the version the cluster is upgrading to
+func (p *PravegaCluster) ControllerTargetImage() (string, error) {
+	if p.Status.TargetVersion == "" {
+		return "", fmt.Errorf("target version is not set")
+	}
+	return fmt.Sprintf("%s:%s", p.componentImage(p.Spec.Pravega.ControllerImage).Repository, p.Status.TargetVersion), nil
+}
+
+// ControllerImagePullPolicy returns the pull policy of the Controller image
+func (p *PravegaCluster) ControllerImagePullPolicy() corev1.PullPolicy {
+	return p.componentImage(p.Spec.Pravega.ControllerImage).PullPolicy
+}
+
+// SegmentStoreUpgradeMaxUnavailable returns the maximum number of segment store pods
+// that can be unavailable at once during an upgrade
+func (p *PravegaCluster) SegmentStoreUpgradeMaxUnavailable() int32 {
+	if p.Spec.UpgradeConfig == nil || p.Spec.UpgradeConfig.SegmentStoreMaxUnavailable == nil {
+		return DefaultSegmentStoreUpgradeMaxUnavailable
+	}
+	return *p.Spec.UpgradeConfig.SegmentStoreMaxUnavailable
+}
+
+// UpgradePodReadyTimeout returns how long an upgraded pod may stay not ready before the
+// upgrade fails
+func (p *PravegaCluster) UpgradePodReadyTimeout() time.Duration {
+	if p.Spec.UpgradeConfig == nil || p.Spec.UpgradeConfig.PodReadyTimeoutSeconds == nil {
+		return DefaultUpgradePodReadyTimeoutSeconds * time.Second
+	}
+	return time.Duration(*p.Spec.UpgradeConfig.PodReadyTimeoutSeconds) * time.Second
+}
+
+// UpgradeTerminatingPodTimeout returns how long a segment store pod may stay terminating
+// past its deletion grace period during an upgrade before it is reported as stuck
+func (p *PravegaCluster) UpgradeTerminatingPodTimeout() time.Duration {
+	if p.Spec.UpgradeConfig == nil || p.Spec.UpgradeConfig.TerminatingPodTimeoutSeconds == nil {
+		return DefaultUpgradeTerminatingPodTimeoutSeconds * time.Second
+	}
+	return time.Duration(*p.Spec.UpgradeConfig.TerminatingPodTimeoutSeconds) * time.Second
+}
+
+// LongTermStorageProbeInterval returns the minimal delay between two probes of the long
+// term storage
+func (p *PravegaCluster) LongTermStorageProbeInterval() time.Duration {
+	if p.Spec.Pravega == nil || p.Spec.Pravega.LongTermStorageProbeIntervalSeconds == nil {
+		return DefaultLongTermStorageProbeIntervalSeconds * time.Second
+	}
+	return time.Duration(*p.Spec.Pravega.LongTermStorageProbeIntervalSeconds) * time.Second
+}
+
+// ScalingStallTimeout returns how long the cluster may stay with fewer ready pods than
+// desired, without progress, before its scaling is stalled
+func (p *PravegaCluster) ScalingStallTimeout() time.Duration {
+	if p.Spec.ScalingStallTimeoutSeconds == nil {
+		return DefaultScalingStallTimeoutSeconds * time.Second
+	}
+	return time.Duration(*p.Spec.ScalingStallTimeoutSeconds) * time.Second
+}
+
+// VersionHistoryLimit returns the number of completed upgrades kept in the upgrade
+// history of the status
+func (p *PravegaCluster) VersionHistoryLimit() int {
+	if p.Spec.VersionHistoryLimit == nil {
+		return DefaultVersionHistoryLimit
+	}
+	return int(*p.Spec.VersionHistoryLimit)
+}
+
+// SegmentStoreImage returns the Segment Store image of the cluster version
+func (p *PravegaCluster) SegmentStoreImage() string {
+	return fmt.Sprintf("%s:%s", p.componentImage(p.Spec.Pravega.SegmentStoreImage).Repository, p.Spec.Version)
+}
+
+// SegmentStoreTargetImage returns the Segment Store image of the version the cluster is upgrading to
+func (p *PravegaCluster) SegmentStoreTargetImage() (string, error) {
+	if p.Status.TargetVersion == "" {
+		return "", fmt.Errorf("target version is not set")
+	}
+	return fmt.Sprintf("%s:%s", p.componentImage(p.Spec.Pravega.SegmentStoreImage).Repository, p.Status.TargetVersion), nil
+}
+
+// SegmentStoreImagePullPolicy returns the pull policy of the Segment Store image
+func (p *PravegaCluster) SegmentStoreImagePullPolicy() corev1.PullPolicy {
+	return p.componentImage(p.Spec.Pravega.SegmentStoreImage).PullPolicy
+}
+
+// componentImage returns the image of a component, whose repository and pull policy
+// fall back to the ones of the Pravega image if they are not overridden
+func (p *PravegaCluster) componentImage(override *ImageSpec) ImageSpec {
+	image := *p.Spec.Pravega.Image
+	if override != nil {
+		if override.Repository != "" {
+			image.Repository = override.Repository
+		}
+		if override.PullPolicy != "" {
+			image.PullPolicy = override.PullPolicy
+		}
+	}
+	return image
+}
+
 // Wait for pods in cluster to be terminated
 func (p *PravegaCluster) WaitForClusterToTerminate(kubeClient client.Client) (err error) {
 	listOptions := &client.ListOptions{
@@ -14,11 +14,16 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -98,6 +103,75 @@ var _ = Describe("PravegaCluster Types Spec", func() {
 		})
 	})
 
+	Context("WithDefaults for resource requests", func() {
+		var resources *corev1.ResourceRequirements
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should set the requests to the limits when only limits are set", func() {
+			p.Spec.Pravega.SegmentStoreResources = &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+			}
+			Ω(p.WithDefaults()).Should(BeTrue())
+			resources = p.Spec.Pravega.SegmentStoreResources
+			Ω(resources.Requests.Cpu().String()).Should(Equal("2"))
+			Ω(resources.Requests.Memory().String()).Should(Equal("4Gi"))
+		})
+		It("should only set the missing requests", func() {
+			p.Spec.Pravega.ControllerResources = &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("500m"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			}
+			p.WithDefaults()
+			resources = p.Spec.Pravega.ControllerResources
+			Ω(resources.Requests.Cpu().String()).Should(Equal("500m"))
+			Ω(resources.Requests.Memory().String()).Should(Equal("1Gi"))
+		})
+		It("should keep the requests when only requests are set", func() {
+			p.Spec.Pravega.ControllerResources = &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			}
+			p.WithDefaults()
+			resources = p.Spec.Pravega.ControllerResources
+			Ω(resources.Requests.Cpu().String()).Should(Equal("500m"))
+			Ω(resources.Limits).Should(BeEmpty())
+		})
+		It("should keep the requests when both requests and limits are set", func() {
+			p.Spec.Pravega.SegmentStoreResources = &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+			}
+			p.WithDefaults()
+			resources = p.Spec.Pravega.SegmentStoreResources
+			Ω(resources.Requests.Cpu().String()).Should(Equal("1"))
+			Ω(resources.Requests.Memory().String()).Should(Equal("2Gi"))
+		})
+		It("should not set requests when neither requests nor limits are set", func() {
+			p.Spec.Pravega.SegmentStoreResources = &corev1.ResourceRequirements{}
+			p.WithDefaults()
+			Ω(p.Spec.Pravega.SegmentStoreResources.Requests).Should(BeEmpty())
+		})
+	})
+
 	Context("ValidatePravegaVersion", func() {
 		var (
 			p     *v1beta1.PravegaCluster
@@ -274,6 +348,22 @@ var _ = Describe("PravegaCluster Types Spec", func() {
 				Ω(strings.ContainsAny(err.Error(), "failed to process the request, cluster is in error state")).Should(Equal(true))
 			})
 		})
+		Context("validation while a pod is stuck terminating during the upgrade", func() {
+			var (
+				err error
+			)
+			BeforeEach(func() {
+				p.Status.CurrentVersion = "0.7.0"
+				p.Status.SetUpgradingConditionTrue(" ", " ")
+				p.Status.SetErrorConditionTrue(v1beta1.PodStuckTerminatingReason, "pod default-pravega-segmentstore-0")
+				p.Spec.Version = "0.7.1"
+				p.Status.TargetVersion = "0.7.1"
+				err = p.ValidatePravegaVersion("filename")
+			})
+			It("should return nil", func() {
+				Ω(err).To(BeNil())
+			})
+		})
 		Context("validation while cluster in upgradefailed state", func() {
 			var (
 				err error
@@ -312,6 +402,247 @@ var _ = Describe("PravegaCluster Types Spec", func() {
 		})
 	})
 
+	Context("ValidateJournalVolumeChange", func() {
+		var p, old *v1beta1.PravegaCluster
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			old.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{StorageClassName: "fast-ssd", Size: "50Gi"}
+			p = old.DeepCopy()
+		})
+		It("should accept an unchanged journal volume", func() {
+			Ω(p.ValidateJournalVolumeChange(old)).Should(BeNil())
+		})
+		It("should accept increasing the size", func() {
+			p.Spec.Pravega.SegmentStoreJournalVolume.Size = "100Gi"
+			Ω(p.ValidateJournalVolumeChange(old)).Should(BeNil())
+		})
+		It("should reject decreasing the size", func() {
+			p.Spec.Pravega.SegmentStoreJournalVolume.Size = "20Gi"
+			Ω(p.ValidateJournalVolumeChange(old)).Should(MatchError(ContainSubstring("can only be increased")))
+		})
+		It("should reject changing the storage class", func() {
+			p.Spec.Pravega.SegmentStoreJournalVolume.StorageClassName = "standard"
+			Ω(p.ValidateJournalVolumeChange(old)).ShouldNot(BeNil())
+		})
+		It("should reject removing the journal volume", func() {
+			p.Spec.Pravega.SegmentStoreJournalVolume = nil
+			Ω(p.ValidateJournalVolumeChange(old)).Should(MatchError(ContainSubstring("cannot be removed")))
+		})
+		It("should reject adding the journal volume", func() {
+			old.Spec.Pravega.SegmentStoreJournalVolume = nil
+			Ω(p.ValidateJournalVolumeChange(old)).Should(MatchError(ContainSubstring("cannot be added")))
+		})
+	})
+
+	Context("ValidateLoggingSidecarChange", func() {
+		var p, old *v1beta1.PravegaCluster
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			old.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{Image: "fluent/fluent-bit:1.5"}
+			p = old.DeepCopy()
+		})
+		It("should accept the defaulting of the sidecar", func() {
+			p.WithDefaults()
+			Ω(p.ValidateLoggingSidecarChange(old)).Should(BeNil())
+		})
+		It("should reject disabling the sidecar", func() {
+			p.Spec.Pravega.LoggingSidecar = nil
+			Ω(p.ValidateLoggingSidecarChange(old)).ShouldNot(BeNil())
+		})
+		It("should reject enabling the sidecar", func() {
+			old.Spec.Pravega.LoggingSidecar = nil
+			Ω(p.ValidateLoggingSidecarChange(old)).ShouldNot(BeNil())
+		})
+		It("should reject changing the sidecar", func() {
+			p.Spec.Pravega.LoggingSidecar.Destination = "logs.example.com:24224"
+			Ω(p.ValidateLoggingSidecarChange(old)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("ValidateSegmentStoreContainerCountChange", func() {
+		var p, old *v1beta1.PravegaCluster
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			count := int32(8)
+			old.Spec.Pravega.SegmentStoreContainerCount = &count
+			p = old.DeepCopy()
+		})
+		It("should accept an unchanged count", func() {
+			Ω(p.ValidateSegmentStoreContainerCountChange(old)).Should(BeNil())
+		})
+		It("should reject changing the count", func() {
+			count := int32(16)
+			p.Spec.Pravega.SegmentStoreContainerCount = &count
+			Ω(p.ValidateSegmentStoreContainerCountChange(old)).Should(MatchError(ContainSubstring("cannot be changed")))
+		})
+		It("should reject unsetting the count", func() {
+			p.Spec.Pravega.SegmentStoreContainerCount = nil
+			Ω(p.ValidateSegmentStoreContainerCountChange(old)).ShouldNot(BeNil())
+		})
+		It("should reject setting the count", func() {
+			old.Spec.Pravega.SegmentStoreContainerCount = nil
+			Ω(p.ValidateSegmentStoreContainerCountChange(old)).ShouldNot(BeNil())
+		})
+	})
+
+	Context("ValidateJVMDerivedResourcesChange", func() {
+		var p, old *v1beta1.PravegaCluster
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			old.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Xmx4g"}
+			p = old.DeepCopy()
+			p.Spec.Pravega.JVMDerivedResources = &v1beta1.JVMDerivedResourcesSpec{OverheadFactor: "1.5"}
+		})
+		It("should reject enabling the derived resources over the defaulted resources", func() {
+			Ω(p.ValidateJVMDerivedResourcesChange(old)).Should(MatchError(ContainSubstring("would not apply to any component")))
+		})
+		It("should accept enabling the derived resources along with removing the resources", func() {
+			p.Spec.Pravega.SegmentStoreResources = nil
+			Ω(p.ValidateJVMDerivedResourcesChange(old)).Should(BeNil())
+		})
+		It("should accept a cluster already deriving its resources", func() {
+			old.Spec.Pravega.JVMDerivedResources = &v1beta1.JVMDerivedResourcesSpec{OverheadFactor: "1.2"}
+			Ω(p.ValidateJVMDerivedResourcesChange(old)).Should(BeNil())
+		})
+	})
+
+	Context("ValidateSegmentStoreReplicasChange", func() {
+		var p, old *v1beta1.PravegaCluster
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			old.Spec.Pravega.SegmentStoreReplicas = 3
+			p = old.DeepCopy()
+		})
+		It("should reject scaling the segment stores down to zero", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).Should(MatchError(ContainSubstring("set spec.pravega.allowZeroSegmentStores to true")))
+		})
+		It("should accept one segment store", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 1
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).Should(BeNil())
+		})
+		It("should accept a large number of segment stores", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 200
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).Should(BeNil())
+		})
+		It("should accept zero segment stores when allowed", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			p.Spec.Pravega.AllowZeroSegmentStores = true
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).Should(BeNil())
+		})
+		It("should reject no longer allowing zero segment stores while there are none", func() {
+			old.Spec.Pravega.SegmentStoreReplicas = 0
+			old.Spec.Pravega.AllowZeroSegmentStores = true
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).ShouldNot(BeNil())
+		})
+		It("should accept the update of a cluster whose replicas are not defaulted yet", func() {
+			old.Spec.Pravega.SegmentStoreReplicas = 0
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			Ω(p.ValidateSegmentStoreReplicasChange(old)).Should(BeNil())
+		})
+		It("should default zero segment stores to one unless allowed", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			Ω(p.WithDefaults()).Should(BeTrue())
+			Ω(p.Spec.Pravega.SegmentStoreReplicas).Should(Equal(int32(1)))
+			p.Spec.Pravega.SegmentStoreReplicas = 0
+			p.Spec.Pravega.AllowZeroSegmentStores = true
+			p.WithDefaults()
+			Ω(p.Spec.Pravega.SegmentStoreReplicas).Should(Equal(int32(0)))
+		})
+	})
+
+	Context("ValidateVersionChange", func() {
+		var (
+			p, old *v1beta1.PravegaCluster
+			err    error
+		)
+		BeforeEach(func() {
+			old = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			old.WithDefaults()
+			old.Spec.Version = "0.10.0"
+			p = old.DeepCopy()
+		})
+		Context("same version", func() {
+			BeforeEach(func() {
+				err = p.ValidateVersionChange(old)
+			})
+			It("should return nil", func() {
+				Ω(err).To(BeNil())
+			})
+		})
+		Context("upgrade", func() {
+			BeforeEach(func() {
+				p.Spec.Version = "0.10.1"
+				err = p.ValidateVersionChange(old)
+			})
+			It("should return nil", func() {
+				Ω(err).To(BeNil())
+			})
+		})
+		Context("downgrade", func() {
+			BeforeEach(func() {
+				p.Spec.Version = "0.9.0"
+				err = p.ValidateVersionChange(old)
+			})
+			It("should return error", func() {
+				Ω(err).NotTo(BeNil())
+				Ω(err.Error()).To(ContainSubstring("downgrade from version 0.10.0 to 0.9.0 is not supported"))
+			})
+		})
+		Context("downgrade with the allow downgrade annotation", func() {
+			BeforeEach(func() {
+				p.Spec.Version = "0.9.0"
+				p.Annotations = map[string]string{v1beta1.AllowDowngradeAnnotation: "true"}
+				err = p.ValidateVersionChange(old)
+			})
+			It("should return nil", func() {
+				Ω(err).To(BeNil())
+			})
+		})
+		Context("rollback of a failed upgrade", func() {
+			BeforeEach(func() {
+				p.Status.Init()
+				p.Status.AddToVersionHistory("0.9.0")
+				p.Status.SetErrorConditionTrue("UpgradeFailed", " ")
+				p.Spec.Version = "0.9.0"
+				err = p.ValidateVersionChange(old)
+			})
+			It("should return nil", func() {
+				Ω(err).To(BeNil())
+			})
+		})
+	})
+
 	Context("Setting TLS and Autentication to nil", func() {
 		BeforeEach(func() {
 			p.Spec.Version = "0.6.0"
@@ -432,43 +763,1909 @@ var _ = Describe("PravegaCluster Types Spec", func() {
 		})
 
 	})
-	Context("checking event generation utility", func() {
+	Context("ValidateConfigMapReconcilePolicy", func() {
 		BeforeEach(func() {
 			p.WithDefaults()
 		})
-		message := "upgrade failed"
-		event := p.NewEvent("UPGRADE_ERROR", v1beta1.UpgradeErrorReason, message, "Error")
-		It("Event size should not be zero", func() {
-			Ω(event.Size()).ShouldNot(Equal(0))
+		It("should default to Enforce", func() {
+			Ω(p.Spec.Pravega.ConfigMapReconcilePolicy).Should(Equal(v1beta1.ConfigMapReconcilePolicyEnforce))
+			Ω(p.ValidateConfigMapReconcilePolicy()).Should(BeNil())
 		})
-
-		event = p.NewApplicationEvent("UPGRADE_ERROR", v1beta1.UpgradeErrorReason, message, "Error")
-		It("Event size should not be zero", func() {
-			Ω(event.Size()).ShouldNot(Equal(0))
+		It("should accept Ignore", func() {
+			p.Spec.Pravega.ConfigMapReconcilePolicy = v1beta1.ConfigMapReconcilePolicyIgnore
+			Ω(p.ValidateConfigMapReconcilePolicy()).Should(BeNil())
+		})
+		It("should reject an unknown policy", func() {
+			p.Spec.Pravega.ConfigMapReconcilePolicy = "Skip"
+			Ω(p.ValidateConfigMapReconcilePolicy()).ShouldNot(BeNil())
 		})
 	})
-
-	Context("WaitForClusterToTerminate", func() {
-		var (
-			client client.Client
-			err    error
-			p1     *v1beta1.PravegaCluster
-		)
-
+	Context("ValidateClusterDomain", func() {
 		BeforeEach(func() {
-			p1 = &v1beta1.PravegaCluster{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "default",
+			p.WithDefaults()
+		})
+		It("should accept an unset domain and keep the relative controller url", func() {
+			Ω(p.ValidateClusterDomain()).Should(BeNil())
+			Ω(p.PravegaControllerServiceURL()).Should(Equal("tcp://" + p.ServiceNameForController() + "." + p.Namespace + ":9090"))
+		})
+		It("should qualify the controller url with the domain", func() {
+			p.Spec.Pravega.ClusterDomain = "cluster.internal"
+			Ω(p.ValidateClusterDomain()).Should(BeNil())
+			Ω(p.PravegaControllerServiceURL()).Should(Equal("tcp://" + p.ServiceNameForController() + "." + p.Namespace + ".svc.cluster.internal:9090"))
+		})
+		It("should reject a domain which is not a DNS name", func() {
+			p.Spec.Pravega.ClusterDomain = "cluster_internal"
+			Ω(p.ValidateClusterDomain()).ShouldNot(BeNil())
+		})
+		It("should reject a trailing dot", func() {
+			p.Spec.Pravega.ClusterDomain = "cluster.internal."
+			Ω(p.ValidateClusterDomain()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateSegmentStoreCachePVCReclaimPolicy", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should default to Delete", func() {
+			Ω(p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy).Should(Equal(v1beta1.SegmentStoreCachePVCReclaimPolicyDelete))
+			Ω(p.ValidateSegmentStoreCachePVCReclaimPolicy()).Should(BeNil())
+		})
+		It("should accept Retain", func() {
+			p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy = v1beta1.SegmentStoreCachePVCReclaimPolicyRetain
+			Ω(p.ValidateSegmentStoreCachePVCReclaimPolicy()).Should(BeNil())
+		})
+		It("should reject an unknown policy", func() {
+			p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy = "Recycle"
+			Ω(p.ValidateSegmentStoreCachePVCReclaimPolicy()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateSegmentStoreUpdateStrategy", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should default to RollingUpdate", func() {
+			Ω(p.Spec.Pravega.SegmentStoreUpdateStrategy).Should(Equal(v1beta1.SegmentStoreUpdateStrategyRollingUpdate))
+			Ω(p.ValidateSegmentStoreUpdateStrategy()).Should(BeNil())
+		})
+		It("should accept OnDelete", func() {
+			p.Spec.Pravega.SegmentStoreUpdateStrategy = v1beta1.SegmentStoreUpdateStrategyOnDelete
+			Ω(p.ValidateSegmentStoreUpdateStrategy()).Should(BeNil())
+		})
+		It("should reject an unknown strategy", func() {
+			p.Spec.Pravega.SegmentStoreUpdateStrategy = "Partitioned"
+			Ω(p.ValidateSegmentStoreUpdateStrategy()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateImagePullPolicies", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept the default pull policy", func() {
+			Ω(p.Spec.Pravega.Image.PullPolicy).Should(Equal(corev1.PullAlways))
+			Ω(p.ValidateImagePullPolicies()).Should(BeNil())
+		})
+		It("should accept a component override without pull policy", func() {
+			p.Spec.Pravega.SegmentStoreImage = &v1beta1.ImageSpec{Repository: "example/pravega-patched"}
+			p.Spec.Pravega.ControllerImage = &v1beta1.ImageSpec{PullPolicy: corev1.PullIfNotPresent}
+			Ω(p.ValidateImagePullPolicies()).Should(BeNil())
+		})
+		It("should reject an unknown pull policy", func() {
+			p.Spec.Pravega.SegmentStoreImage = &v1beta1.ImageSpec{PullPolicy: "Sometimes"}
+			err := p.ValidateImagePullPolicies()
+			Ω(err.Error()).Should(Equal("segmentStoreImage.pullPolicy Sometimes is invalid, it must be one of Always, IfNotPresent and Never"))
+		})
+		It("should reject an unknown logging sidecar pull policy", func() {
+			p.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{Image: "fluent/fluent-bit", ImagePullPolicy: "always"}
+			Ω(p.ValidateImagePullPolicies()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidatePVCLabels", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept valid labels", func() {
+			p.Spec.Pravega.PVCLabels = map[string]string{"example.com/cost-center": "streaming"}
+			Ω(p.ValidatePVCLabels()).Should(BeNil())
+		})
+		It("should reject an invalid label value", func() {
+			p.Spec.Pravega.PVCLabels = map[string]string{"cost-center": "stream processing"}
+			err := p.ValidatePVCLabels()
+			Ω(err.Error()).Should(ContainSubstring("pvcLabels value stream processing of key cost-center is not a valid label value"))
+		})
+		It("should reject the labels set by the operator", func() {
+			p.Spec.Pravega.PVCLabels = map[string]string{"component": "cache"}
+			err := p.ValidatePVCLabels()
+			Ω(err.Error()).Should(Equal("pvcLabels key component is reserved by the operator"))
+		})
+	})
+	Context("ValidateDNS", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept the cluster default", func() {
+			Ω(p.ValidateDNS()).Should(BeNil())
+		})
+		It("should accept a supported policy with additional search domains", func() {
+			p.Spec.Pravega.SegmentStoreDnsPolicy = corev1.DNSClusterFirst
+			p.Spec.Pravega.SegmentStoreDnsConfig = &corev1.PodDNSConfig{Searches: []string{"storage.example.com"}}
+			Ω(p.ValidateDNS()).Should(BeNil())
+		})
+		It("should reject an unknown policy", func() {
+			p.Spec.Pravega.ControllerDnsPolicy = "ClusterLast"
+			Ω(p.ValidateDNS()).ShouldNot(BeNil())
+		})
+		It("should reject the None policy without nameserver", func() {
+			p.Spec.Pravega.ControllerDnsPolicy = corev1.DNSNone
+			Ω(p.ValidateDNS()).ShouldNot(BeNil())
+			p.Spec.Pravega.ControllerDnsConfig = &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}}
+			Ω(p.ValidateDNS()).Should(BeNil())
+		})
+	})
+	Context("ValidateSegmentStoreHostNetwork", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.SegmentStoreHostNetwork = true
+		})
+		It("should accept the host network without external access", func() {
+			Ω(p.ValidateSegmentStoreHostNetwork()).Should(BeNil())
+		})
+		It("should reject the host network along with external access", func() {
+			p.Spec.ExternalAccess.Enabled = true
+			err := p.ValidateSegmentStoreHostNetwork()
+			Ω(err.Error()).Should(ContainSubstring("segmentStoreHostNetwork cannot be enabled along with externalAccess"))
+		})
+		It("should reject a published address set in the options", func() {
+			p.Spec.Pravega.Options["pravegaservice.service.published.host.nameOrIp"] = "10.0.0.1"
+			err := p.ValidateSegmentStoreHostNetwork()
+			Ω(err.Error()).Should(Equal("option pravegaservice.service.published.host.nameOrIp cannot be set along with segmentStoreHostNetwork"))
+		})
+		It("should reject a published address set in the JVM options", func() {
+			p.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Dpravegaservice.publishedIPAddress=10.0.0.1"}
+			err := p.ValidateSegmentStoreHostNetwork()
+			Ω(err.Error()).Should(Equal("option pravegaservice.publishedIPAddress cannot be set along with segmentStoreHostNetwork"))
+		})
+		It("should reject network sysctls in the segment store security context", func() {
+			p.Spec.Pravega.SegmentStoreSecurityContext = &corev1.PodSecurityContext{
+				Sysctls: []corev1.Sysctl{{Name: "net.ipv4.tcp_keepalive_time", Value: "600"}},
+			}
+			err := p.ValidateSegmentStoreHostNetwork()
+			Ω(err.Error()).Should(ContainSubstring("sysctl net.ipv4.tcp_keepalive_time cannot be set along with segmentStoreHostNetwork"))
+		})
+		It("should accept other sysctls and an fsGroup in the segment store security context", func() {
+			fsGroup := int64(1000)
+			p.Spec.Pravega.SegmentStoreSecurityContext = &corev1.PodSecurityContext{
+				FSGroup: &fsGroup,
+				Sysctls: []corev1.Sysctl{{Name: "kernel.shm_rmid_forced", Value: "1"}},
+			}
+			Ω(p.ValidateSegmentStoreHostNetwork()).Should(BeNil())
+		})
+	})
+	Context("ValidateSegmentStoreExternalTrafficPort", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.ExternalAccess.Enabled = true
+			p.Spec.Pravega.SegmentStoreReplicas = 3
+			p.Spec.Pravega.SegmentStoreExternalTrafficPort = 31000
+		})
+		It("should accept a port for load balancers", func() {
+			Ω(p.ValidateSegmentStoreExternalTrafficPort()).Should(BeNil())
+			Ω(p.SegmentStoreExternalTrafficPort(2)).Should(Equal(int32(31000)))
+		})
+		It("should default to the container port", func() {
+			p.Spec.Pravega.SegmentStoreExternalTrafficPort = 0
+			Ω(p.ValidateSegmentStoreExternalTrafficPort()).Should(BeNil())
+			Ω(p.SegmentStoreExternalTrafficPort(0)).Should(Equal(int32(12345)))
+		})
+		It("should reject a port out of range", func() {
+			p.Spec.Pravega.SegmentStoreExternalTrafficPort = 70000
+			err := p.ValidateSegmentStoreExternalTrafficPort()
+			Ω(err.Error()).Should(ContainSubstring("must be between 1 and 65535"))
+		})
+		It("should reject consecutive ports out of range with a shared LoadBalancerIP", func() {
+			p.Spec.Pravega.SegmentStoreLoadBalancerIP = "10.240.12.18"
+			p.Spec.Pravega.SegmentStoreExternalTrafficPort = 65534
+			Ω(p.ValidateSegmentStoreExternalTrafficPort()).ShouldNot(BeNil())
+			p.Spec.Pravega.SegmentStoreExternalTrafficPort = 65533
+			Ω(p.ValidateSegmentStoreExternalTrafficPort()).Should(BeNil())
+			Ω(p.SegmentStoreExternalTrafficPort(2)).Should(Equal(int32(65535)))
+		})
+		It("should reject a port for NodePort services", func() {
+			p.Spec.ExternalAccess.Type = corev1.ServiceTypeNodePort
+			err := p.ValidateSegmentStoreExternalTrafficPort()
+			Ω(err.Error()).Should(ContainSubstring("cannot be set with NodePort services"))
+		})
+	})
+	Context("ValidateSegmentStoreTopologySpreadConstraints", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.SegmentStoreTopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       "topology.kubernetes.io/zone",
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
 				},
 			}
-			p1.WithDefaults()
-			s := scheme.Scheme
-			s.AddKnownTypes(v1beta1.SchemeGroupVersion, p1)
-			client = fake.NewFakeClient(p1)
-			err = p1.WaitForClusterToTerminate(client)
 		})
-		It("should  be nil", func() {
-			Ω(err).Should(BeNil())
+		It("should accept a constraint with a topology key", func() {
+			Ω(p.ValidateSegmentStoreTopologySpreadConstraints()).Should(BeNil())
+		})
+		It("should reject a constraint with an empty topology key", func() {
+			p.Spec.Pravega.SegmentStoreTopologySpreadConstraints[0].TopologyKey = ""
+			Ω(p.ValidateSegmentStoreTopologySpreadConstraints()).ShouldNot(BeNil())
+		})
+		It("should reject a constraint without skew", func() {
+			p.Spec.Pravega.SegmentStoreTopologySpreadConstraints[0].MaxSkew = 0
+			Ω(p.ValidateSegmentStoreTopologySpreadConstraints()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidatePodAffinities", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.SegmentStorePodAffinity = &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key:      "topology.kubernetes.io/zone",
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{"us-east-1a"},
+							}},
+						}},
+					},
+				},
+			}
+		})
+		It("should accept the defaulted anti-affinity and a node affinity", func() {
+			Ω(p.ValidatePodAffinities()).Should(BeNil())
+		})
+		It("should reject a node selector requirement without values", func() {
+			p.Spec.Pravega.SegmentStorePodAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values = nil
+			Ω(p.ValidatePodAffinities()).Should(MatchError("segmentStorePodAffinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0] values must not be empty with the In operator"))
+		})
+		It("should reject an empty node selector term", func() {
+			p.Spec.Pravega.SegmentStorePodAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions = nil
+			Ω(p.ValidatePodAffinities()).Should(MatchError(ContainSubstring("must have at least one match expression or field")))
+		})
+		It("should reject a non integer value with the Gt operator", func() {
+			requirement := &p.Spec.Pravega.SegmentStorePodAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0]
+			requirement.Operator = corev1.NodeSelectorOpGt
+			requirement.Values = []string{"high"}
+			Ω(p.ValidatePodAffinities()).Should(MatchError(ContainSubstring("value must be an integer with the Gt operator")))
+		})
+		It("should reject a pod anti-affinity term without topology key", func() {
+			p.Spec.Pravega.ControllerPodAffinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey = ""
+			Ω(p.ValidatePodAffinities()).Should(MatchError("controllerPodAffinity.podAntiAffinity.preferredDuringSchedulingIgnoredDuringExecution[0].podAffinityTerm.topologyKey must be set"))
+		})
+		It("should reject a weight out of range", func() {
+			p.Spec.Pravega.ControllerPodAffinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].Weight = 0
+			Ω(p.ValidatePodAffinities()).Should(MatchError(ContainSubstring("weight must be between 1 and 100, got 0")))
+		})
+		It("should reject an invalid label selector", func() {
+			p.Spec.Pravega.SegmentStorePodAffinity.PodAffinity = &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+					LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Matches"}}},
+					TopologyKey:   "kubernetes.io/hostname",
+				}},
+			}
+			Ω(p.ValidatePodAffinities()).Should(MatchError(ContainSubstring("segmentStorePodAffinity.podAffinity.requiredDuringSchedulingIgnoredDuringExecution[0].labelSelector is invalid")))
+		})
+	})
+	Context("ValidateSegmentStorePdb", func() {
+		var (
+			minAvailable   intstr.IntOrString
+			maxUnavailable intstr.IntOrString
+		)
+		BeforeEach(func() {
+			p.WithDefaults()
+			minAvailable = intstr.FromString("50%")
+			maxUnavailable = intstr.FromInt(1)
+		})
+		It("should accept either minAvailable or maxUnavailable", func() {
+			p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MinAvailable: &minAvailable}
+			Ω(p.ValidateSegmentStorePdb()).Should(BeNil())
+			p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MaxUnavailable: &maxUnavailable}
+			Ω(p.ValidateSegmentStorePdb()).Should(BeNil())
+		})
+		It("should reject both minAvailable and maxUnavailable", func() {
+			p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MinAvailable: &minAvailable, MaxUnavailable: &maxUnavailable}
+			Ω(p.ValidateSegmentStorePdb()).ShouldNot(BeNil())
+		})
+		It("should reject a negative number or an invalid percentage", func() {
+			maxUnavailable = intstr.FromInt(-1)
+			p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MaxUnavailable: &maxUnavailable}
+			Ω(p.ValidateSegmentStorePdb()).ShouldNot(BeNil())
+			minAvailable = intstr.FromString("150%")
+			p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MinAvailable: &minAvailable}
+			Ω(p.ValidateSegmentStorePdb()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateSegmentStoreVolumes", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.SegmentStoreVolumes = []corev1.Volume{
+				{
+					Name: "storage-tuning",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "storage-tuning"},
+						},
+					},
+				},
+			}
+		})
+		It("should accept a mount of a declared volume", func() {
+			p.Spec.Pravega.SegmentStoreVolumeMounts = []corev1.VolumeMount{
+				{Name: "storage-tuning", MountPath: "/etc/storage-tuning"},
+			}
+			Ω(p.ValidateSegmentStoreVolumes()).Should(BeNil())
+		})
+		It("should reject a mount of an undeclared volume", func() {
+			p.Spec.Pravega.SegmentStoreVolumeMounts = []corev1.VolumeMount{
+				{Name: "tuning", MountPath: "/etc/storage-tuning"},
+			}
+			err := p.ValidateSegmentStoreVolumes()
+			Ω(err.Error()).Should(Equal("segmentStoreVolumeMounts tuning does not mount any of the segmentStoreVolumes"))
+		})
+		It("should reject the name of a volume of the operator", func() {
+			p.Spec.Pravega.SegmentStoreVolumes[0].Name = "tier2"
+			err := p.ValidateSegmentStoreVolumes()
+			Ω(err.Error()).Should(Equal("segmentStoreVolumes name tier2 is used by the operator"))
+		})
+		It("should reject a mount path of the operator", func() {
+			p.Spec.Pravega.SegmentStoreVolumeMounts = []corev1.VolumeMount{
+				{Name: "storage-tuning", MountPath: "/etc/secret-volume/"},
+			}
+			err := p.ValidateSegmentStoreVolumes()
+			Ω(err.Error()).Should(ContainSubstring("mountPath /etc/secret-volume/ is already in use"))
+		})
+	})
+	Context("ValidateSegmentStoreInitContainers", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept uniquely named init containers", func() {
+			p.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+				{Name: "fix-permissions", Image: "busybox"},
+				{Name: "warm-up", Image: "busybox"},
+			}
+			Ω(p.ValidateSegmentStoreInitContainers()).Should(BeNil())
+		})
+		It("should reject duplicate names", func() {
+			p.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+				{Name: "fix-permissions", Image: "busybox"},
+				{Name: "fix-permissions", Image: "busybox"},
+			}
+			Ω(p.ValidateSegmentStoreInitContainers()).ShouldNot(BeNil())
+		})
+		It("should reject the name of the segment store container", func() {
+			p.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+				{Name: "pravega-segmentstore", Image: "busybox"},
+			}
+			Ω(p.ValidateSegmentStoreInitContainers()).ShouldNot(BeNil())
+		})
+		It("should reject a missing image", func() {
+			p.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+				{Name: "fix-permissions"},
+			}
+			Ω(p.ValidateSegmentStoreInitContainers()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateLoggingSidecar", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{Image: "fluent/fluent-bit:1.5"}
+			p.WithDefaults()
+		})
+		It("should default the mount path", func() {
+			Ω(p.Spec.Pravega.LoggingSidecar.MountPath).Should(Equal(v1beta1.DefaultLoggingSidecarMountPath))
+			Ω(p.ValidateLoggingSidecar()).Should(BeNil())
+		})
+		It("should reject a missing image", func() {
+			p.Spec.Pravega.LoggingSidecar.Image = ""
+			Ω(p.ValidateLoggingSidecar()).ShouldNot(BeNil())
+		})
+		It("should reject a relative mount path", func() {
+			p.Spec.Pravega.LoggingSidecar.MountPath = "logs"
+			Ω(p.ValidateLoggingSidecar()).ShouldNot(BeNil())
+		})
+		It("should reject overriding the operator provided environment", func() {
+			p.Spec.Pravega.LoggingSidecar.Env = []corev1.EnvVar{{Name: "LOG_DIR", Value: "/tmp"}}
+			Ω(p.ValidateLoggingSidecar()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateCommandOverrides", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept no override", func() {
+			Ω(p.Spec.Pravega.HasCommandOverride()).Should(BeFalse())
+			Ω(p.ValidateCommandOverrides()).Should(BeNil())
+		})
+		It("should accept a command and args override", func() {
+			p.Spec.Pravega.ControllerCommand = []string{"sleep"}
+			p.Spec.Pravega.ControllerArgs = []string{"infinity"}
+			p.Spec.Pravega.SegmentStoreCommand = []string{"sleep", "infinity"}
+			Ω(p.Spec.Pravega.HasCommandOverride()).Should(BeTrue())
+			Ω(p.ValidateCommandOverrides()).Should(BeNil())
+		})
+		It("should reject an empty entry", func() {
+			p.Spec.Pravega.SegmentStoreArgs = []string{"segmentstore", " "}
+			Ω(p.ValidateCommandOverrides()).Should(MatchError("segmentStoreArgs[1] must not be empty"))
+		})
+		It("should reject an override along with the logging sidecar", func() {
+			p.Spec.Pravega.ControllerArgs = []string{"controller", "--debug"}
+			p.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{Image: "fluent/fluent-bit:1.5"}
+			Ω(p.ValidateCommandOverrides()).Should(MatchError(ContainSubstring("loggingSidecar")))
+		})
+		It("should reject a segment store override along with external access", func() {
+			p.Spec.ExternalAccess.Enabled = true
+			p.Spec.Pravega.ControllerCommand = []string{"sleep", "infinity"}
+			Ω(p.ValidateCommandOverrides()).Should(BeNil())
+			p.Spec.Pravega.SegmentStoreCommand = []string{"sleep", "infinity"}
+			Ω(p.ValidateCommandOverrides()).Should(MatchError(ContainSubstring("externalAccess")))
+		})
+	})
+	Context("ValidateSpec", func() {
+		It("should accept a minimal spec without defaulting it", func() {
+			Ω(v1beta1.ValidateSpec(&p)).Should(BeNil())
+			Ω(p.Spec.Pravega).Should(BeNil())
+		})
+		It("should list every violation", func() {
+			p.Spec.Version = "latest"
+			p.Spec.Pravega = &v1beta1.PravegaSpec{
+				SegmentStoreReplicas: -1,
+				JVMFlavor:            "zulu",
+				Options: map[string]string{
+					"controller.security.tls.enable": "true",
+				},
+			}
+			err := v1beta1.ValidateSpec(&p)
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("version latest is not in valid format"))
+			Ω(err.Error()).Should(ContainSubstring("spec.pravega.segmentStoreReplicas: Invalid value: -1: must not be negative"))
+			Ω(err.Error()).Should(ContainSubstring("jvmFlavor"))
+			Ω(err.Error()).Should(ContainSubstring("tls.static.controllerSecret must be set"))
+			Ω(p.Spec.Pravega.ConfigMapReconcilePolicy).Should(BeEmpty())
+		})
+	})
+	Context("ValidateFields", func() {
+		var (
+			err    error
+			causes []metav1.StatusCause
+		)
+		BeforeEach(func() {
+			file, _ := os.Create("fields-versions")
+			file.WriteString("0.7.0:0.7.0 \n")
+			file.Close()
+			p.WithDefaults()
+		})
+		AfterEach(func() {
+			os.Remove("fields-versions")
+		})
+		JustBeforeEach(func() {
+			err = p.ValidateFields("fields-versions", fake.NewFakeClient())
+			causes = nil
+			if statusErr, ok := err.(*errors.StatusError); ok {
+				causes = statusErr.ErrStatus.Details.Causes
+			}
+		})
+		Context("with a valid spec", func() {
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+		Context("with three independent errors", func() {
+			BeforeEach(func() {
+				p.Spec.Version = "0.9.0"
+				p.Spec.Pravega.SegmentStoreReplicas = -1
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeClusterIP
+			})
+			It("should report all of them as an Invalid error", func() {
+				Ω(errors.IsInvalid(err)).Should(BeTrue())
+				Ω(causes).Should(HaveLen(3))
+				Ω(causes[0].Field).Should(Equal("spec.version"))
+				Ω(causes[1].Field).Should(Equal("spec.pravega.segmentStoreReplicas"))
+				Ω(causes[2].Field).Should(Equal("spec.externalAccess.type"))
+			})
+		})
+		Context("with two Tier 2 backends", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage.Hdfs = &v1beta1.HDFSSpec{Uri: "hdfs://hdfs:8020", Root: "/pravega"}
+			})
+			It("should report the error on the longtermStorage field", func() {
+				Ω(causes).Should(HaveLen(1))
+				Ω(causes[0].Field).Should(Equal("spec.pravega.longtermStorage"))
+			})
+		})
+		Context("with the controller kept internal", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				p.Spec.Pravega.ControllerExternalServiceType = corev1.ServiceTypeClusterIP
+				p.Spec.Pravega.SegmentStoreExternalServiceType = corev1.ServiceTypeNodePort
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+		Context("with an internal segment store service type", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.Pravega.SegmentStoreExternalServiceType = corev1.ServiceTypeClusterIP
+			})
+			It("should report the error on the segmentStoreExtServiceType field", func() {
+				Ω(causes).Should(HaveLen(1))
+				Ω(causes[0].Field).Should(Equal("spec.pravega.segmentStoreExtServiceType"))
+			})
+		})
+	})
+	Context("ValidateTLS", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		It("should accept the secrets of the enabled components", func() {
+			p.Spec.TLS.Static.SegmentStoreSecret = "segmentstore-tls"
+			p.Spec.Pravega.Options["pravegaservice.security.tls.enable"] = "true"
+			Ω(p.ValidateTLS()).Should(BeNil())
+		})
+		It("should reject an invalid secret name", func() {
+			p.Spec.TLS.Static.ControllerSecret = "Controller_TLS"
+			Ω(p.ValidateTLS()).ShouldNot(BeNil())
+		})
+		It("should reject TLS enabled on the segment store without its secret", func() {
+			p.Spec.Pravega.Options["pravegaservice.enableTls"] = "true"
+			Ω(p.ValidateTLS()).ShouldNot(BeNil())
+		})
+	})
+	Context("Component images", func() {
+		BeforeEach(func() {
+			p.Spec.Version = "0.8.0"
+			p.WithDefaults()
+		})
+		It("should use the Pravega image by default", func() {
+			Ω(p.ControllerImage()).To(Equal("pravega/pravega:0.8.0"))
+			Ω(p.SegmentStoreImage()).To(Equal("pravega/pravega:0.8.0"))
+			Ω(p.SegmentStoreImagePullPolicy()).To(Equal(p.Spec.Pravega.Image.PullPolicy))
+		})
+		It("should override the segment store image only", func() {
+			p.Spec.Pravega.SegmentStoreImage = &v1beta1.ImageSpec{Repository: "example/pravega-patched"}
+			Ω(p.ControllerImage()).To(Equal("pravega/pravega:0.8.0"))
+			Ω(p.SegmentStoreImage()).To(Equal("example/pravega-patched:0.8.0"))
+			Ω(p.SegmentStoreImagePullPolicy()).To(Equal(p.Spec.Pravega.Image.PullPolicy))
+		})
+		It("should use the overridden image for the target version", func() {
+			p.Spec.Pravega.ControllerImage = &v1beta1.ImageSpec{Repository: "example/controller", PullPolicy: corev1.PullIfNotPresent}
+			p.Status.TargetVersion = "0.9.0"
+			image, err := p.ControllerTargetImage()
+			Ω(err).Should(BeNil())
+			Ω(image).To(Equal("example/controller:0.9.0"))
+			Ω(p.ControllerImagePullPolicy()).To(Equal(corev1.PullIfNotPresent))
+		})
+	})
+	Context("checking event generation utility", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+		message := "upgrade failed"
+		event := p.NewEvent("UPGRADE_ERROR", v1beta1.UpgradeErrorReason, message, "Error")
+		It("Event size should not be zero", func() {
+			Ω(event.Size()).ShouldNot(Equal(0))
+		})
+
+		event = p.NewApplicationEvent("UPGRADE_ERROR", v1beta1.UpgradeErrorReason, message, "Error")
+		It("Event size should not be zero", func() {
+			Ω(event.Size()).ShouldNot(Equal(0))
+		})
+	})
+
+	Context("WaitForClusterToTerminate", func() {
+		var (
+			client client.Client
+			err    error
+			p1     *v1beta1.PravegaCluster
+		)
+
+		BeforeEach(func() {
+			p1 = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+			}
+			p1.WithDefaults()
+			s := scheme.Scheme
+			s.AddKnownTypes(v1beta1.SchemeGroupVersion, p1)
+			client = fake.NewFakeClient(p1)
+			err = p1.WaitForClusterToTerminate(client)
+		})
+		It("should  be nil", func() {
+			Ω(err).Should(BeNil())
+		})
+	})
+
+	Context("ValidateAuthentication", func() {
+		var (
+			secret *corev1.Secret
+			err    error
+		)
+
+		BeforeEach(func() {
+			p.Namespace = "default"
+			p.WithDefaults()
+			p.Spec.Authentication = &v1beta1.AuthenticationParameters{
+				Enabled: true,
+				ControllerTokenSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+					Key:                  "signing-key",
+				},
+			}
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "token-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"signing-key": []byte("key"),
+				},
+			}
+		})
+
+		It("should return nil if the secret has the key", func() {
+			err = p.ValidateAuthentication(fake.NewFakeClient(secret))
+			Ω(err).Should(BeNil())
+		})
+		It("should return nil if authentication is disabled", func() {
+			p.Spec.Authentication.Enabled = false
+			err = p.ValidateAuthentication(fake.NewFakeClient())
+			Ω(err).Should(BeNil())
+		})
+		It("should return error if the secret is missing", func() {
+			err = p.ValidateAuthentication(fake.NewFakeClient())
+			Ω(err.Error()).To(Equal("authentication.controllerTokenSecret token-secret not found in namespace default"))
+		})
+		It("should return error if the secret has no such key", func() {
+			p.Spec.Authentication.ControllerTokenSecret.Key = "other-key"
+			err = p.ValidateAuthentication(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(Equal("authentication.controllerTokenSecret token-secret has no key other-key"))
+		})
+		It("should return error if the signing key is also set in the options", func() {
+			p.Spec.Pravega.Options["autoScale.controller.security.auth.tokenSigningKey"] = "key"
+			err = p.ValidateAuthentication(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(Equal("option autoScale.controller.security.auth.tokenSigningKey cannot be set along with authentication.controllerTokenSecret"))
+		})
+	})
+
+	Context("ValidateTLSSecretKeys", func() {
+		var (
+			secret *corev1.Secret
+			err    error
+		)
+
+		BeforeEach(func() {
+			p.Namespace = "default"
+			p.WithDefaults()
+			p.Spec.TLS.Static.ControllerSecret = "controller-tls"
+			p.Spec.TLS.Static.ControllerKeys = &v1beta1.TLSSecretKeys{
+				Certificate:   "tls.crt",
+				PrivateKey:    "tls.key",
+				CaCertificate: "ca.crt",
+			}
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "controller-tls",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"tls.crt": []byte("cert"),
+					"tls.key": []byte("key"),
+					"ca.crt":  []byte("ca"),
+				},
+			}
+		})
+
+		It("should return nil if the secret has the keys", func() {
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient(secret))
+			Ω(err).Should(BeNil())
+		})
+		It("should not look the secret up without keys", func() {
+			p.Spec.TLS.Static.ControllerKeys = nil
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient())
+			Ω(err).Should(BeNil())
+		})
+		It("should return error if the secret is missing", func() {
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient())
+			Ω(err.Error()).To(Equal("tls.static.controllerSecret controller-tls not found in namespace default"))
+		})
+		It("should return error if the secret has no such key", func() {
+			p.Spec.TLS.Static.ControllerKeys.PrivateKey = "server.key"
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(Equal("tls.static.controllerSecret controller-tls has no key server.key"))
+		})
+		It("should return error if the keys are set without the secret", func() {
+			p.Spec.TLS.Static.SegmentStoreKeys = &v1beta1.TLSSecretKeys{Certificate: "tls.crt"}
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(Equal("tls.static.segmentStoreKeys.certificate requires the secret of the component to be set"))
+		})
+		It("should return error if a key is not a valid secret key", func() {
+			p.Spec.TLS.Static.ControllerKeys.Certificate = "tls/crt"
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(ContainSubstring("tls.static.controllerKeys.certificate tls/crt is not a valid secret key"))
+		})
+		It("should return error if an option located by a key is also set", func() {
+			p.Spec.Pravega.Options["controller.security.tls.server.certificate.location"] = "/etc/secret-volume/tls.crt"
+			err = p.ValidateTLSSecretKeys(fake.NewFakeClient(secret))
+			Ω(err.Error()).To(Equal("option controller.security.tls.server.certificate.location cannot be set along with tls.static.controllerKeys"))
+		})
+	})
+
+	Context("ValidateLongTermStorage", func() {
+		var (
+			pvc *corev1.PersistentVolumeClaim
+			err error
+		)
+
+		BeforeEach(func() {
+			p.Namespace = "default"
+			p.WithDefaults()
+			pvc = &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      v1beta1.DefaultPravegaLTSClaimName,
+					Namespace: "default",
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				},
+			}
+		})
+
+		Context("tier2 pvc with ReadWriteMany access mode", func() {
+			BeforeEach(func() {
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("tier2 pvc with ReadWriteOnce access mode and several segment stores", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreReplicas = 3
+				pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+			})
+			It("should return error", func() {
+				Ω(err).Should(MatchError("tier2 pvc pravega-tier2 must have access mode ReadWriteMany as it is shared by the 3 segment store replicas, got [ReadWriteOnce]"))
+			})
+		})
+
+		Context("tier2 pvc with ReadWriteOnce access mode and a single segment store", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreReplicas = 1
+				pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("tier2 pvc not created yet", func() {
+			BeforeEach(func() {
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("more than one backend set", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage.S3 = &v1beta1.S3Spec{
+					Endpoint:  "http://minio:9000",
+					Bucket:    "pravega",
+					SecretRef: "minio-creds",
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "only one of filesystem, ecs, hdfs and s3")).Should(Equal(true))
+			})
+		})
+
+		Context("each combination of two backends", func() {
+			It("should return a field path error naming both backends", func() {
+				fs := p.Spec.Pravega.LongTermStorage.FileSystem
+				ecs := &v1beta1.ECSSpec{ConfigUri: "http://ecs:9020", Bucket: "pravega", Credentials: "ecs-creds"}
+				hdfs := &v1beta1.HDFSSpec{Uri: "hdfs://hdfs:8020/", Root: "/pravega", ReplicationFactor: 3}
+				s3 := &v1beta1.S3Spec{Endpoint: "http://minio:9000", Bucket: "pravega", SecretRef: "minio-creds"}
+				combinations := map[string]*v1beta1.LongTermStorageSpec{
+					"filesystem and ecs":  {FileSystem: fs, Ecs: ecs},
+					"filesystem and hdfs": {FileSystem: fs, Hdfs: hdfs},
+					"filesystem and s3":   {FileSystem: fs, S3: s3},
+					"ecs and hdfs":        {Ecs: ecs, Hdfs: hdfs},
+					"ecs and s3":          {Ecs: ecs, S3: s3},
+					"hdfs and s3":         {Hdfs: hdfs, S3: s3},
+				}
+				for backends, lts := range combinations {
+					p.Spec.Pravega.LongTermStorage = lts
+					err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+					Ω(err).ShouldNot(BeNil())
+					Ω(err.Error()).To(HavePrefix("spec.pravega.longtermStorage: Forbidden"))
+					Ω(err.Error()).To(HaveSuffix("got " + backends))
+				}
+			})
+		})
+
+		Context("no backend set", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(pvc))
+			})
+			It("should return error", func() {
+				Ω(err.Error()).To(HavePrefix("spec.pravega.longtermStorage: Required value"))
+				Ω(strings.Contains(err.Error(), "one of filesystem, ecs, hdfs and s3 must be set")).Should(Equal(true))
+			})
+		})
+
+		Context("valid s3 backend", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					S3: &v1beta1.S3Spec{
+						Endpoint:  "https://s3.example.com",
+						Bucket:    "pravega",
+						SecretRef: "s3-creds",
+					},
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("s3 backend without bucket", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					S3: &v1beta1.S3Spec{
+						Endpoint:  "https://s3.example.com",
+						SecretRef: "s3-creds",
+					},
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "longtermStorage.s3.bucket must be set")).Should(Equal(true))
+			})
+		})
+
+		Context("ecs backend with a ca bundle secret", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					Ecs: &v1beta1.ECSSpec{
+						ConfigUri:      "https://ecs.example.com:9021?namespace=pravega",
+						Bucket:         "pravega",
+						Credentials:    "ecs-creds",
+						CaBundleSecret: "ecs-ca",
+					},
+				}
+			})
+			It("should return nil if the secret exists", func() {
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ecs-ca",
+						Namespace: "default",
+					},
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient(secret))
+				Ω(err).Should(BeNil())
+			})
+			It("should return error if the secret is missing", func() {
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(err.Error()).To(Equal("longtermStorage.ecs.caBundleSecret ecs-ca not found in namespace default"))
+			})
+			It("should return error if the secret name is invalid", func() {
+				p.Spec.Pravega.LongTermStorage.Ecs.CaBundleSecret = "ECS_CA"
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(strings.Contains(err.Error(), "is not a valid secret name")).Should(Equal(true))
+			})
+		})
+
+		Context("ecs backend authentication", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					Ecs: &v1beta1.ECSSpec{
+						ConfigUri: "https://ecs.example.com:9021",
+						Bucket:    "pravega",
+					},
+				}
+			})
+			It("should return error without credentials nor role", func() {
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(strings.Contains(err.Error(), "must set either credentials")).Should(Equal(true))
+			})
+			It("should return nil with an IAM role", func() {
+				ecs := p.Spec.Pravega.LongTermStorage.Ecs
+				ecs.UseV2 = true
+				ecs.Namespace = "pravega"
+				ecs.Role = "urn:ecs:iam::pravega:role/segmentstore"
+				ecs.SessionToken = &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "ecs-token"},
+					Key:                  "token",
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(err).Should(BeNil())
+			})
+			It("should return error if the role is set without useV2", func() {
+				p.Spec.Pravega.LongTermStorage.Ecs.Namespace = "pravega"
+				p.Spec.Pravega.LongTermStorage.Ecs.Role = "urn:ecs:iam::pravega:role/segmentstore"
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(err.Error()).To(Equal("longtermStorage.ecs.useV2 must be set along with namespace, role"))
+			})
+			It("should return error if the role is set without the namespace", func() {
+				p.Spec.Pravega.LongTermStorage.Ecs.UseV2 = true
+				p.Spec.Pravega.LongTermStorage.Ecs.Role = "urn:ecs:iam::pravega:role/segmentstore"
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+				Ω(err.Error()).To(Equal("longtermStorage.ecs.namespace must be set along with longtermStorage.ecs.role"))
+			})
+		})
+
+		Context("s3 backend with an invalid endpoint", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					S3: &v1beta1.S3Spec{
+						Endpoint:  "s3.example.com",
+						Bucket:    "pravega",
+						SecretRef: "s3-creds",
+					},
+				}
+				err = p.ValidateLongTermStorage(fake.NewFakeClient())
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be an http or https URL")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateInitWaitURL", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("init wait url not set", func() {
+			BeforeEach(func() {
+				err = p.ValidateInitWaitURL()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("valid http url", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.InitWaitURL = "http://metadata.example.com:8080/health"
+				err = p.ValidateInitWaitURL()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("url without scheme", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.InitWaitURL = "metadata.example.com:8080"
+				err = p.ValidateInitWaitURL()
+			})
+			It("should return error", func() {
+				Ω(err).ShouldNot(BeNil())
+			})
+		})
+
+		Context("url with unsupported scheme", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.InitWaitURL = "tcp://metadata.example.com:8080"
+				err = p.ValidateInitWaitURL()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be an absolute http or https url")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateLoadBalancerTags", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.ExternalAccess.Enabled = true
+		})
+
+		Context("valid tags", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+					"cost-center": "1234",
+					"team":        "data streaming",
+				}
+				err = p.ValidateLoadBalancerTags()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("tag key with reserved prefix", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+					"aws:owner": "finops",
+				}
+				err = p.ValidateLoadBalancerTags()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "reserved prefix")).Should(Equal(true))
+			})
+		})
+
+		Context("tag value with a separator", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+					"owner": "a,b",
+				}
+				err = p.ValidateLoadBalancerTags()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "may only contain")).Should(Equal(true))
+			})
+		})
+
+		Context("tag key too long", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+					strings.Repeat("k", 129): "value",
+				}
+				err = p.ValidateLoadBalancerTags()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be between 1 and 128 characters")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateExternalTrafficPolicy", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.ExternalAccess.Enabled = true
+		})
+
+		Context("policy not set", func() {
+			BeforeEach(func() {
+				err = p.ValidateExternalTrafficPolicy()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("local policy", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+				err = p.ValidateExternalTrafficPolicy()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("unknown policy", func() {
+			BeforeEach(func() {
+				p.Spec.ExternalAccess.ExternalTrafficPolicy = "Nearest"
+				err = p.ValidateExternalTrafficPolicy()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be either Cluster or Local")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateMetrics", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("valid metrics settings", func() {
+			BeforeEach(func() {
+				interval := int32(60)
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					ReportingIntervalSeconds: &interval,
+					Prefix:                   "pravega.prod_1",
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("reporting interval out of range", func() {
+			BeforeEach(func() {
+				interval := int32(0)
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					ReportingIntervalSeconds: &interval,
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "reportingIntervalSeconds must be between 1 and 3600")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid prefix", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					Prefix: "1pravega-prod",
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must start with a letter")).Should(Equal(true))
+			})
+		})
+
+		Context("valid service monitor", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					ServiceMonitor: &v1beta1.ServiceMonitorSpec{
+						Enabled:  true,
+						Interval: "30s",
+						Labels:   map[string]string{"release": "prometheus"},
+					},
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("invalid service monitor interval", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					ServiceMonitor: &v1beta1.ServiceMonitorSpec{
+						Enabled:  true,
+						Interval: "30",
+					},
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "metrics.serviceMonitor.interval 30 is not a valid positive duration")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid service monitor label", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+					ServiceMonitor: &v1beta1.ServiceMonitorSpec{
+						Enabled: true,
+						Labels:  map[string]string{"release": "prometheus operator"},
+					},
+				}
+				err = p.ValidateMetrics()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "metrics.serviceMonitor.labels value prometheus operator is invalid")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("MetricName", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should use the default prefix", func() {
+			Ω(p.MetricName("segmentstore_segment_write_bytes_total")).Should(Equal("pravega_segmentstore_segment_write_bytes_total"))
+		})
+		It("should use the prefix option", func() {
+			p.Spec.Pravega.Options["metrics.metricsPrefix"] = "raw"
+			Ω(p.MetricName("segmentstore_segment_write_bytes_total")).Should(Equal("raw_segmentstore_segment_write_bytes_total"))
+		})
+		It("should prefer the typed prefix and replace its dots", func() {
+			p.Spec.Pravega.Options["metrics.metricsPrefix"] = "raw"
+			p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{Prefix: "pravega.prod"}
+			Ω(p.MetricName("segmentstore_segment_write_bytes_total")).Should(Equal("pravega_prod_segmentstore_segment_write_bytes_total"))
+		})
+	})
+
+	Context("ValidateNodeSelectors", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("default node selectors", func() {
+			BeforeEach(func() {
+				err = p.ValidateNodeSelectors()
+			})
+			It("should not be set", func() {
+				Ω(p.Spec.Pravega.ControllerPodNodeSelector).Should(BeNil())
+				Ω(p.Spec.Pravega.SegmentStorePodNodeSelector).Should(BeNil())
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("valid node selectors", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerPodNodeSelector = map[string]string{
+					"node-role.kubernetes.io/controller": "",
+				}
+				p.Spec.Pravega.SegmentStorePodNodeSelector = map[string]string{
+					"disktype": "ssd",
+				}
+				err = p.ValidateNodeSelectors()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("invalid label key", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStorePodNodeSelector = map[string]string{
+					"disk type": "ssd",
+				}
+				err = p.ValidateNodeSelectors()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "segmentStorePodNodeSelector key disk type is not a valid label key")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid label value", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerPodNodeSelector = map[string]string{
+					"disktype": "ssd drive",
+				}
+				err = p.ValidateNodeSelectors()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "is not a valid label value")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateSegmentStoreTerminationGracePeriod", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("grace period not set", func() {
+			BeforeEach(func() {
+				err = p.ValidateSegmentStoreTerminationGracePeriod()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("valid grace period", func() {
+			BeforeEach(func() {
+				gracePeriod := int64(120)
+				p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds = &gracePeriod
+				err = p.ValidateSegmentStoreTerminationGracePeriod()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("negative grace period", func() {
+			BeforeEach(func() {
+				gracePeriod := int64(-1)
+				p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds = &gracePeriod
+				err = p.ValidateSegmentStoreTerminationGracePeriod()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "segmentStoreTerminationGracePeriodSeconds must not be negative")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateScalingStallTimeout", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should default to 15 minutes", func() {
+			Ω(p.ValidateScalingStallTimeout()).Should(BeNil())
+			Ω(p.ScalingStallTimeout()).Should(Equal(15 * time.Minute))
+		})
+		It("should return the configured timeout", func() {
+			timeout := int32(120)
+			p.Spec.ScalingStallTimeoutSeconds = &timeout
+			Ω(p.ValidateScalingStallTimeout()).Should(BeNil())
+			Ω(p.ScalingStallTimeout()).Should(Equal(2 * time.Minute))
+		})
+		It("should return error if below 1", func() {
+			timeout := int32(0)
+			p.Spec.ScalingStallTimeoutSeconds = &timeout
+			Ω(p.ValidateScalingStallTimeout()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateVersionHistoryLimit", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should default to 10 entries", func() {
+			Ω(p.ValidateVersionHistoryLimit()).Should(BeNil())
+			Ω(p.VersionHistoryLimit()).Should(Equal(10))
+		})
+		It("should return the configured limit", func() {
+			limit := int32(3)
+			p.Spec.VersionHistoryLimit = &limit
+			Ω(p.ValidateVersionHistoryLimit()).Should(BeNil())
+			Ω(p.VersionHistoryLimit()).Should(Equal(3))
+		})
+		It("should return error if below 1", func() {
+			limit := int32(0)
+			p.Spec.VersionHistoryLimit = &limit
+			Ω(p.ValidateVersionHistoryLimit()).ShouldNot(BeNil())
+		})
+	})
+	Context("ValidateUpgradeConfig", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should upgrade one segment store pod at a time if not set", func() {
+			Ω(p.ValidateUpgradeConfig()).Should(BeNil())
+			Ω(p.SegmentStoreUpgradeMaxUnavailable()).Should(Equal(int32(1)))
+		})
+		It("should return the configured maximum", func() {
+			max := int32(3)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{SegmentStoreMaxUnavailable: &max}
+			Ω(p.ValidateUpgradeConfig()).Should(BeNil())
+			Ω(p.SegmentStoreUpgradeMaxUnavailable()).Should(Equal(int32(3)))
+		})
+		It("should return error if below 1", func() {
+			max := int32(0)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{SegmentStoreMaxUnavailable: &max}
+			err := p.ValidateUpgradeConfig()
+			Ω(err.Error()).To(Equal("upgradeConfig.segmentStoreMaxUnavailable must be at least 1, got 0"))
+		})
+		It("should wait 10 minutes for an upgraded pod if not set", func() {
+			Ω(p.UpgradePodReadyTimeout()).Should(Equal(10 * time.Minute))
+		})
+		It("should return the configured pod ready timeout", func() {
+			timeout := int32(1800)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{PodReadyTimeoutSeconds: &timeout}
+			Ω(p.ValidateUpgradeConfig()).Should(BeNil())
+			Ω(p.UpgradePodReadyTimeout()).Should(Equal(30 * time.Minute))
+		})
+		It("should return error if the pod ready timeout is below 1", func() {
+			timeout := int32(0)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{PodReadyTimeoutSeconds: &timeout}
+			err := p.ValidateUpgradeConfig()
+			Ω(err.Error()).To(Equal("upgradeConfig.podReadyTimeoutSeconds must be at least 1, got 0"))
+		})
+		It("should wait 5 minutes for a terminating pod if not set", func() {
+			Ω(p.UpgradeTerminatingPodTimeout()).Should(Equal(5 * time.Minute))
+		})
+		It("should return the configured terminating pod timeout", func() {
+			timeout := int32(60)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{TerminatingPodTimeoutSeconds: &timeout}
+			Ω(p.ValidateUpgradeConfig()).Should(BeNil())
+			Ω(p.UpgradeTerminatingPodTimeout()).Should(Equal(time.Minute))
+		})
+		It("should return error if the terminating pod timeout is below 1", func() {
+			timeout := int32(0)
+			p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{TerminatingPodTimeoutSeconds: &timeout}
+			err := p.ValidateUpgradeConfig()
+			Ω(err.Error()).To(Equal("upgradeConfig.terminatingPodTimeoutSeconds must be at least 1, got 0"))
+		})
+	})
+
+	Context("ValidateLongTermStorageProbeInterval", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		It("should probe every 5 minutes if not set", func() {
+			Ω(p.ValidateLongTermStorageProbeInterval()).Should(BeNil())
+			Ω(p.LongTermStorageProbeInterval()).Should(Equal(5 * time.Minute))
+		})
+		It("should return the configured interval", func() {
+			interval := int32(60)
+			p.Spec.Pravega.LongTermStorageProbeIntervalSeconds = &interval
+			Ω(p.ValidateLongTermStorageProbeInterval()).Should(BeNil())
+			Ω(p.LongTermStorageProbeInterval()).Should(Equal(time.Minute))
+		})
+		It("should return error if the interval is below 10 seconds", func() {
+			interval := int32(5)
+			p.Spec.Pravega.LongTermStorageProbeIntervalSeconds = &interval
+			err := p.ValidateLongTermStorageProbeInterval()
+			Ω(err.Error()).To(Equal("longTermStorageProbeIntervalSeconds must be at least 10, got 5"))
+		})
+	})
+
+	Context("ValidateSegmentStoreContainerCount", func() {
+		BeforeEach(func() {
+			p.WithDefaults()
+			p.Spec.Pravega.SegmentStoreReplicas = 3
+		})
+
+		It("should return nil if not set", func() {
+			Ω(p.ValidateSegmentStoreContainerCount()).Should(BeNil())
+		})
+		It("should return nil with at least one container per segment store", func() {
+			count := int32(3)
+			p.Spec.Pravega.SegmentStoreContainerCount = &count
+			Ω(p.ValidateSegmentStoreContainerCount()).Should(BeNil())
+		})
+		It("should return error with fewer containers than segment stores", func() {
+			count := int32(2)
+			p.Spec.Pravega.SegmentStoreContainerCount = &count
+			err := p.ValidateSegmentStoreContainerCount()
+			Ω(err.Error()).To(Equal("segmentStoreContainerCount 2 must not be below segmentStoreReplicas 3"))
+		})
+		It("should return error if not positive", func() {
+			count := int32(0)
+			p.Spec.Pravega.SegmentStoreContainerCount = &count
+			err := p.ValidateSegmentStoreContainerCount()
+			Ω(err.Error()).To(Equal("segmentStoreContainerCount must be positive, got 0"))
+		})
+		It("should return error if the count is also set in the options", func() {
+			count := int32(8)
+			p.Spec.Pravega.SegmentStoreContainerCount = &count
+			p.Spec.Pravega.Options["pravegaservice.container.count"] = "8"
+			err := p.ValidateSegmentStoreContainerCount()
+			Ω(err.Error()).To(Equal("option pravegaservice.container.count cannot be set along with segmentStoreContainerCount"))
+		})
+	})
+
+	Context("ValidateControllerProbes", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("default probes", func() {
+			BeforeEach(func() {
+				err = p.ValidateControllerProbes()
+			})
+			It("should default to the previous probe timings", func() {
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.InitialDelaySeconds).Should(Equal(int32(20)))
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.TimeoutSeconds).Should(Equal(int32(60)))
+				Ω(p.Spec.Pravega.ControllerProbes.LivenessProbe.InitialDelaySeconds).Should(Equal(int32(60)))
+				Ω(p.Spec.Pravega.ControllerProbes.LivenessProbe.PeriodSeconds).Should(Equal(int32(15)))
+				Ω(p.Spec.Pravega.ControllerProbes.LivenessProbe.FailureThreshold).Should(Equal(int32(4)))
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("partially set probe", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerProbes.ReadinessProbe = &v1beta1.ProbeSpec{
+					TimeoutSeconds: 30,
+				}
+				p.WithDefaults()
+				err = p.ValidateControllerProbes()
+			})
+			It("should default the unset timings", func() {
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.InitialDelaySeconds).Should(Equal(int32(20)))
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.PeriodSeconds).Should(Equal(int32(10)))
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.FailureThreshold).Should(Equal(int32(3)))
+				Ω(p.Spec.Pravega.ControllerProbes.ReadinessProbe.TimeoutSeconds).Should(Equal(int32(30)))
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("invalid readiness and liveness probes", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerProbes.ReadinessProbe.FailureThreshold = -1
+				p.Spec.Pravega.ControllerProbes.LivenessProbe.FailureThreshold = -1
+				err = p.ValidateControllerProbes()
+			})
+			It("should report the readiness probe first", func() {
+				Ω(err.Error()).Should(ContainSubstring("controllerProbes.readinessProbe.failureThreshold"))
+			})
+		})
+
+		Context("zero failure threshold", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerProbes.ReadinessProbe = &v1beta1.ProbeSpec{
+					InitialDelaySeconds: 60,
+					PeriodSeconds:       10,
+					TimeoutSeconds:      30,
+				}
+				err = p.ValidateControllerProbes()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "controllerProbes.readinessProbe.failureThreshold must be greater than 0")).Should(Equal(true))
+			})
+		})
+
+		Context("negative timings", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.ControllerProbes.LivenessProbe.InitialDelaySeconds = -1
+				err = p.ValidateControllerProbes()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "controllerProbes.livenessProbe timings must not be negative")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateJVMDerivedResources", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.Spec.Pravega = &v1beta1.PravegaSpec{
+				SegmentStoreJVMOptions: []string{"-Xmx4g", "-XX:MaxDirectMemorySize=2g"},
+				JVMDerivedResources:    &v1beta1.JVMDerivedResourcesSpec{},
+			}
+			p.WithDefaults()
+		})
+
+		Context("derivation enabled", func() {
+			BeforeEach(func() {
+				err = p.ValidateJVMDerivedResources()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should default the overhead factor", func() {
+				Ω(p.Spec.Pravega.JVMDerivedResources.OverheadFactor).Should(Equal("1.5"))
+			})
+			It("should leave the segment store resources unset", func() {
+				Ω(p.Spec.Pravega.SegmentStoreResources).Should(BeNil())
+			})
+			It("should derive the segment store memory from the heap and direct memory", func() {
+				resources := p.SegmentStoreResourceRequirements()
+				memory := resources.Requests[corev1.ResourceMemory]
+				Ω(memory.Value()).Should(Equal(int64(9 << 30)))
+				memory = resources.Limits[corev1.ResourceMemory]
+				Ω(memory.Value()).Should(Equal(int64(9 << 30)))
+				cpu := resources.Requests[corev1.ResourceCPU]
+				Ω(cpu.String()).Should(Equal(v1beta1.DefaultSegmentStoreRequestCPU))
+			})
+			It("should use the default controller resources without -Xmx", func() {
+				Ω(p.Spec.Pravega.ControllerResources).ShouldNot(BeNil())
+				memory := p.ControllerResourceRequirements().Limits[corev1.ResourceMemory]
+				Ω(memory.String()).Should(Equal(v1beta1.DefaultControllerLimitMemory))
+			})
+		})
+
+		Context("explicit resources", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreResources = &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("16Gi"),
+					},
+				}
+			})
+			It("should not derive the resources", func() {
+				memory := p.SegmentStoreResourceRequirements().Limits[corev1.ResourceMemory]
+				Ω(memory.String()).Should(Equal("16Gi"))
+			})
+		})
+
+		Context("default direct memory", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Xmx2g"}
+				p.Spec.Pravega.JVMDerivedResources.OverheadFactor = "1.25"
+			})
+			It("should count the direct memory as large as the heap", func() {
+				memory := p.SegmentStoreResourceRequirements().Requests[corev1.ResourceMemory]
+				Ω(memory.Value()).Should(Equal(int64(5 << 30)))
+			})
+		})
+
+		Context("invalid overhead factor", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.JVMDerivedResources.OverheadFactor = "0.8"
+				err = p.ValidateJVMDerivedResources()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "overheadFactor must be a number greater than or equal to 1")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid heap size", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Xmx4gb"}
+				err = p.ValidateJVMDerivedResources()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "failed to derive segment store resources from the JVM options")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateControllerRequestTimeouts", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("valid request timeouts", func() {
+			BeforeEach(func() {
+				timeout := int32(120)
+				lease := int32(600)
+				p.Spec.Pravega.ControllerRequestTimeouts = &v1beta1.ControllerRequestTimeoutsSpec{
+					RequestTimeoutSeconds:      &timeout,
+					TransactionMaxLeaseSeconds: &lease,
+				}
+				err = p.ValidateControllerRequestTimeouts()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("request timeout out of range", func() {
+			BeforeEach(func() {
+				timeout := int32(0)
+				p.Spec.Pravega.ControllerRequestTimeouts = &v1beta1.ControllerRequestTimeoutsSpec{
+					RequestTimeoutSeconds: &timeout,
+				}
+				err = p.ValidateControllerRequestTimeouts()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "requestTimeoutSeconds must be between 1 and 3600")).Should(Equal(true))
+			})
+		})
+
+		Context("transaction max lease out of range", func() {
+			BeforeEach(func() {
+				lease := int32(100000)
+				p.Spec.Pravega.ControllerRequestTimeouts = &v1beta1.ControllerRequestTimeoutsSpec{
+					TransactionMaxLeaseSeconds: &lease,
+				}
+				err = p.ValidateControllerRequestTimeouts()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "transactionMaxLeaseSeconds must be between 1 and 86400")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateJournalVolume", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("valid journal volume", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+					StorageClassName: "fast-ssd",
+					Size:             "50Gi",
+				}
+				err = p.ValidateJournalVolume()
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("invalid size", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+					Size: "fifty",
+				}
+				err = p.ValidateJournalVolume()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "is not a valid quantity")).Should(Equal(true))
+			})
+		})
+
+		Context("size too small", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+					Size: "100Mi",
+				}
+				err = p.ValidateJournalVolume()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be between 1Gi and 16Ti")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid storage class name", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+					StorageClassName: "Fast_SSD",
+					Size:             "50Gi",
+				}
+				err = p.ValidateJournalVolume()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "is not a valid name")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateMaintenanceWindows", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("valid maintenance windows", func() {
+			BeforeEach(func() {
+				p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: "4h"},
+					{Schedule: "*/30 22-23 1-7 * *", Duration: "30m"},
+				}
+				err = p.ValidateMaintenanceWindows()
+			})
+			It("should not return error", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("invalid schedule", func() {
+			BeforeEach(func() {
+				p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: "4h"},
+					{Schedule: "0 25 * * *", Duration: "4h"},
+				}
+				err = p.ValidateMaintenanceWindows()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "maintenanceWindows[1].schedule is invalid")).Should(Equal(true))
+			})
+		})
+
+		Context("schedule which never matches", func() {
+			BeforeEach(func() {
+				p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+					{Schedule: "0 0 31 4 *", Duration: "4h"},
+				}
+				err = p.ValidateMaintenanceWindows()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "never matches")).Should(Equal(true))
+			})
+		})
+
+		Context("invalid duration", func() {
+			BeforeEach(func() {
+				p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: "four hours"},
+				}
+				err = p.ValidateMaintenanceWindows()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "is not a valid duration")).Should(Equal(true))
+			})
+		})
+
+		Context("duration too long", func() {
+			BeforeEach(func() {
+				p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+					{Schedule: "0 2 * * 6", Duration: "200h"},
+				}
+				err = p.ValidateMaintenanceWindows()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must be between 1m0s and 168h0m0s")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("InMaintenanceWindow", func() {
+		// Saturday
+		now := time.Date(2020, 10, 17, 3, 30, 0, 0, time.UTC)
+
+		It("should allow disruptive actions when no window is set", func() {
+			Ω(p.InMaintenanceWindow(now)).Should(BeTrue())
+			Ω(p.NextMaintenanceWindow(now).IsZero()).Should(BeTrue())
+		})
+		It("should be open during a window", func() {
+			p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{{Schedule: "0 2 * * 6", Duration: "2h"}}
+			Ω(p.InMaintenanceWindow(now)).Should(BeTrue())
+		})
+		It("should be closed after a window", func() {
+			p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{{Schedule: "0 2 * * 6", Duration: "1h"}}
+			Ω(p.InMaintenanceWindow(now)).Should(BeFalse())
+			Ω(p.NextMaintenanceWindow(now)).Should(Equal(time.Date(2020, 10, 24, 2, 0, 0, 0, time.UTC)))
+		})
+		It("should return the earliest next window", func() {
+			p.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{
+				{Schedule: "0 2 * * 6", Duration: "1h"},
+				{Schedule: "0 22 * * *", Duration: "1h"},
+			}
+			Ω(p.InMaintenanceWindow(now)).Should(BeFalse())
+			Ω(p.NextMaintenanceWindow(now)).Should(Equal(time.Date(2020, 10, 17, 22, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Context("ValidateJVMFlavor", func() {
+		var err error
+
+		BeforeEach(func() {
+			p.WithDefaults()
+		})
+
+		Context("supported flavors", func() {
+			It("should not return error", func() {
+				for _, flavor := range []string{v1beta1.JVMFlavorHotSpot, v1beta1.JVMFlavorTemurin, v1beta1.JVMFlavorGraalVM, v1beta1.JVMFlavorOpenJ9, v1beta1.JVMFlavorCustom} {
+					p.Spec.Pravega.JVMFlavor = flavor
+					Ω(p.ValidateJVMFlavor()).Should(BeNil())
+				}
+			})
+		})
+
+		Context("unsupported flavor", func() {
+			BeforeEach(func() {
+				p.Spec.Pravega.JVMFlavor = "zulu"
+				err = p.ValidateJVMFlavor()
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "jvmFlavor zulu is not supported")).Should(Equal(true))
+			})
+		})
+	})
+
+	Context("ValidateRunAsIdentity", func() {
+		var (
+			group int64
+			err   error
+		)
+
+		BeforeEach(func() {
+			p.WithDefaults()
+			group = 0
+		})
+
+		Context("root user without runAsNonRoot", func() {
+			BeforeEach(func() {
+				err = p.ValidateRunAsIdentity(&v1beta1.RunAsIdentity{RunAsUser: 0, RunAsGroup: &group})
+			})
+			It("should return nil", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+
+		Context("negative user", func() {
+			BeforeEach(func() {
+				err = p.ValidateRunAsIdentity(&v1beta1.RunAsIdentity{RunAsUser: -1})
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "must not be negative")).Should(Equal(true))
+			})
+		})
+
+		Context("root group with runAsNonRoot", func() {
+			BeforeEach(func() {
+				nonRoot := true
+				p.Spec.Pravega.SegmentStoreSecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot}
+				err = p.ValidateRunAsIdentity(&v1beta1.RunAsIdentity{RunAsUser: 1000, RunAsGroup: &group})
+			})
+			It("should return error", func() {
+				Ω(strings.Contains(err.Error(), "runAsGroup must be non-zero")).Should(Equal(true))
+			})
 		})
 	})
 })
@@ -15,22 +15,116 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ClusterConditionType string
 
 const (
-	ClusterConditionPodsReady ClusterConditionType = "PodsReady"
-	ClusterConditionUpgrading                      = "Upgrading"
-	ClusterConditionRollback                       = "RollbackInProgress"
-	ClusterConditionError                          = "Error"
+	ClusterConditionPodsReady                 ClusterConditionType = "PodsReady"
+	ClusterConditionUpgrading                                      = "Upgrading"
+	ClusterConditionRollback                                       = "RollbackInProgress"
+	ClusterConditionError                                          = "Error"
+	ClusterConditionInsufficientResources                          = "InsufficientResources"
+	ClusterConditionImageNotFound                                  = "ImageNotFound"
+	ClusterConditionConfigMapReconcileIgnored                      = "ConfigMapReconcileIgnored"
+	ClusterConditionLtsReachable                                   = "LtsReachable"
+	ClusterConditionStorageClassNotFound                           = "StorageClassNotFound"
+	ClusterConditionPodsFailed                                     = "PodsFailed"
+	ClusterConditionScaleDownDeferred                              = "ScaleDownDeferred"
+	ClusterConditionScalingStalled                                 = "ScalingStalled"
+	ClusterConditionVolumeExpansionBlocked                         = "VolumeExpansionBlocked"
 
 	// Reasons for cluster upgrading condition
 	UpdatingControllerReason   = "Updating Controller"
 	UpdatingSegmentstoreReason = "Updating Segmentstore"
 	UpdatingBookkeeperReason   = "Updating Bookkeeper"
 	UpgradeErrorReason         = "Upgrade Error"
+	UpgradePausedReason        = "Paused"
 	RollbackErrorReason        = "Rollback Error"
+
+	// Reasons for cluster error condition
+	UpgradeFailedReason       = "UpgradeFailed"
+	RollbackFailedReason      = "RollbackFailed"
+	PodStuckTerminatingReason = "PodStuckTerminating"
+
+	// Reason of the upgrading condition while the segment store upgrade waits for the pods
+	// to be ready after the controller upgrade
+	WaitingForControllerReason = "Waiting For Controller"
+
+	// Reason of the upgrading condition and of the event published when the segment
+	// store pods must be deleted by the user, with the OnDelete update strategy
+	ManualRestartRequiredReason = "Manual Restart Required"
+
+	// Reason of the event published when the cluster becomes ready
+	ClusterReadyReason = "ClusterReady"
+
+	// Reason of the event published when a user annotation uses an operator-managed key
+	AnnotationConflictReason = "Annotation Conflict"
+
+	// Reason of the event published when an extra environment variable uses an
+	// operator-managed name
+	ExtraEnvConflictReason = "Extra Env Conflict"
+
+	// Reason of the event published when the service account token is not mounted into
+	// the pods of a component which needs it
+	ServiceAccountTokenConflictReason = "Service Account Token Conflict"
+
+	// Reason of the event published when the number of segment containers changes on an
+	// existing cluster
+	ContainerCountChangedReason = "Container Count Changed"
+
+	// Reason of the event published when the Segment Stores of a cluster are scaled down
+	// to 0 without allowZeroSegmentStores, e.g. through the scale subresource, and the
+	// operator scales them back to 1
+	ZeroSegmentStoresReason = "Zero Segment Stores"
+
+	// Reasons for cluster insufficient resources condition
+	InsufficientControllerResourcesReason   = "Insufficient Controller Resources"
+	InsufficientSegmentstoreResourcesReason = "Insufficient Segmentstore Resources"
+
+	// Reason for cluster image not found condition
+	PravegaImageNotFoundReason = "Pravega Image Not Found"
+
+	// Reason for cluster configmap reconcile ignored condition
+	ConfigMapReconcilePolicyIgnoreReason = "ConfigMap Reconcile Policy Ignore"
+
+	// Reasons for cluster long term storage reachable condition
+	LtsClaimNotBoundReason     = "Tier 2 Claim Not Bound"
+	SegmentStoresFailingReason = "Segment Stores Failing"
+	LtsProbeFailedReason       = "Tier 2 Probe Failed"
+
+	// Reasons for cluster storage class not found condition
+	StorageClassNotFoundReason = "Storage Class Not Found"
+	ClaimPendingReason         = "Claim Pending"
+
+	// Reason for cluster pods failed condition
+	PodsNotProgressingReason = "Pods Not Progressing"
+
+	// Reason for cluster scale-down deferred condition
+	ControllerAvailabilityReason = "Controller Availability"
+
+	// Reasons for cluster scaling stalled condition, along with ClaimPendingReason
+	PodsPendingReason  = "Pods Pending"
+	PodsNotReadyReason = "Pods Not Ready"
+
+	// Reason for cluster volume expansion blocked condition
+	VolumeExpansionNotAllowedReason = "Volume Expansion Not Allowed"
+
+	// Phases reported while the operator reconciles the cluster
+	ReconcilePhaseValidating            = "Validating"
+	ReconcilePhaseUpgradingController   = "UpgradingController"
+	ReconcilePhaseUpgradingSegmentStore = "UpgradingSegmentStore"
+	ReconcilePhaseScaling               = "Scaling"
+	ReconcilePhaseIdle                  = "Idle"
+)
+
+// Components whose last successful reconcile time is reported in the status
+const (
+	ComponentController   = "controller"
+	ComponentSegmentStore = "segmentStore"
+	ComponentServices     = "services"
+	ComponentConfigMaps   = "configMaps"
 )
 
 // ClusterStatus defines the observed state of PravegaCluster
@@ -47,6 +141,17 @@ type ClusterStatus struct {
 
 	VersionHistory []string `json:"versionHistory,omitempty"`
 
+	// UpgradeHistory lists the versions the cluster was upgraded to along with the time
+	// each upgrade completed, oldest first. It keeps at most spec.versionHistoryLimit
+	// entries
+	// +optional
+	UpgradeHistory []VersionHistoryEntry `json:"upgradeHistory,omitempty"`
+
+	// UpgradePodsRemaining is the number of pods of the component being upgraded
+	// that still run the previous version
+	// +optional
+	UpgradePodsRemaining int32 `json:"upgradePodsRemaining,omitempty"`
+
 	// Replicas is the number of desired replicas in the cluster
 	// +optional
 	Replicas int32 `json:"replicas"`
@@ -59,9 +164,154 @@ type ClusterStatus struct {
 	// +optional
 	ReadyReplicas int32 `json:"readyReplicas"`
 
+	// SegmentStoreReplicas is the number of segment store pods in the cluster, reported
+	// as the current replicas by the scale subresource
+	// +optional
+	SegmentStoreReplicas int32 `json:"segmentStoreReplicas,omitempty"`
+
+	// SegmentStoreSelector is the label selector of the segment store pods, with which
+	// the scale subresource lets autoscalers find them
+	// +optional
+	SegmentStoreSelector string `json:"segmentStoreSelector,omitempty"`
+
+	// LastReadinessChangeTime is the last time the number of ready or desired pods of the
+	// cluster changed, from which a scaling without progress is detected
+	// +optional
+	LastReadinessChangeTime string `json:"lastReadinessChangeTime,omitempty"`
+
 	// Members is the Pravega members in the cluster
 	// +optional
 	Members MembersStatus `json:"members"`
+
+	// ObservedGeneration is the generation of the spec the child objects of the cluster
+	// were last fully reconciled with
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReconcilePhase is the phase the operator is currently in while reconciling
+	// the cluster: Validating, UpgradingController, UpgradingSegmentStore, Scaling or Idle
+	// +optional
+	ReconcilePhase string `json:"reconcilePhase,omitempty"`
+
+	// SegmentContainers is the number of segment containers hosted by each segment
+	// store, as assigned by the controller in zookeeper
+	// +optional
+	SegmentContainers []SegmentContainerStatus `json:"segmentContainers,omitempty"`
+
+	// Throughput is the aggregate write throughput of the segment stores, sampled from
+	// their metrics when the operator runs with throughput status enabled
+	// +optional
+	Throughput *ThroughputStatus `json:"throughput,omitempty"`
+
+	// LtsLastProbeTime is the time of the last probe of the long term storage, when
+	// the long term storage probe is enabled
+	// +optional
+	LtsLastProbeTime string `json:"ltsLastProbeTime,omitempty"`
+
+	// Maintenance lists the disruptive actions deferred until the next maintenance
+	// window. It is not set if no action is deferred
+	// +optional
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+
+	// RunAsIdentity is the user and group IDs read from the runAsIdentitySecret and
+	// applied to the controller and segment store pods
+	// +optional
+	RunAsIdentity *RunAsIdentity `json:"runAsIdentity,omitempty"`
+
+	// TLSSecretHashes is the hash of the data of the controller and segment store TLS
+	// secrets, stamped on the pod templates when reloadOnChange is set
+	// +optional
+	TLSSecretHashes *TLSSecretHashes `json:"tlsSecretHashes,omitempty"`
+
+	// SegmentStoreEndpoints maps the name of each segment store pod to the host:port
+	// of its external service, once its load balancer is provisioned. It is only set
+	// when external access is enabled
+	// +optional
+	SegmentStoreEndpoints map[string]string `json:"segmentStoreEndpoints,omitempty"`
+
+	// SegmentStoreContainerCount is the segmentStoreContainerCount of the spec as of the
+	// last reconcile, so that its changes can be detected. It is not set if unset in the spec
+	// +optional
+	SegmentStoreContainerCount int32 `json:"segmentStoreContainerCount,omitempty"`
+
+	// ControllerEndpoint is the host:port of the gRPC port of the controller external
+	// service, once its load balancer is provisioned. It is only set when external
+	// access is enabled
+	// +optional
+	ControllerEndpoint string `json:"controllerEndpoint,omitempty"`
+
+	// ComponentReconcileTimes maps each component, i.e. controller, segmentStore, services
+	// and configMaps, to the last time its resources were reconciled successfully. A
+	// component whose time lags behind the others is failing to reconcile
+	// +optional
+	ComponentReconcileTimes map[string]metav1.Time `json:"componentReconcileTimes,omitempty"`
+}
+
+// VersionHistoryEntry is a version the cluster was upgraded to
+type VersionHistoryEntry struct {
+	// Version is the version the cluster was upgraded to
+	Version string `json:"version"`
+
+	// CompletionTime is the time the upgrade to the version completed
+	CompletionTime metav1.Time `json:"completionTime"`
+}
+
+// TLSSecretHashes is the hash of the data of the TLS secrets mounted in the pods
+type TLSSecretHashes struct {
+	// Controller is the hash of the controller TLS secret
+	// +optional
+	Controller string `json:"controller,omitempty"`
+
+	// SegmentStore is the hash of the segment store TLS secret and of the CA bundle
+	// +optional
+	SegmentStore string `json:"segmentStore,omitempty"`
+}
+
+// RunAsIdentity is the user and group IDs the controller and segment store containers run as
+type RunAsIdentity struct {
+	// RunAsUser is the user ID the containers run as
+	RunAsUser int64 `json:"runAsUser"`
+
+	// RunAsGroup is the primary group ID of the containers. If not set, the group
+	// of the security context or of the image is used
+	// +optional
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+}
+
+// MaintenanceStatus lists the disruptive actions deferred until the next maintenance window
+type MaintenanceStatus struct {
+	// DeferredActions are the disruptive actions waiting for a maintenance window,
+	// e.g. "upgrade to 0.8.0"
+	DeferredActions []string `json:"deferredActions,omitempty"`
+
+	// NextWindowStart is the time the next maintenance window opens
+	NextWindowStart string `json:"nextWindowStart,omitempty"`
+}
+
+// ThroughputStatus is the aggregate write throughput of the segment stores
+type ThroughputStatus struct {
+	// WriteBytesPerSecond is the rate of bytes written to the segment stores between
+	// the last two samples. It is 0 after a counter reset, e.g. a segment store restart
+	WriteBytesPerSecond int64 `json:"writeBytesPerSecond"`
+
+	// WriteBytesTotal is the sum of the write byte counters of the segment stores at
+	// the last sample
+	WriteBytesTotal int64 `json:"writeBytesTotal"`
+
+	// LastSampleTime is the time of the last sample
+	LastSampleTime string `json:"lastSampleTime,omitempty"`
+}
+
+// SegmentContainerStatus is the number of segment containers hosted by a segment store
+type SegmentContainerStatus struct {
+	// Ordinal is the ordinal of the segment store pod
+	Ordinal int32 `json:"ordinal"`
+
+	// PodName is the name of the segment store pod
+	PodName string `json:"podName"`
+
+	// ContainerCount is the number of segment containers hosted by the segment store
+	ContainerCount int32 `json:"containerCount"`
 }
 
 // MembersStatus is the status of the members of the cluster with both
@@ -73,6 +323,14 @@ type MembersStatus struct {
 	// +optional
 	// +nullable
 	Unready []string `json:"unready"`
+	// Failed lists the unready members which have not been ready for longer than the
+	// failure threshold of the operator, e.g. pods in CrashLoopBackOff
+	// +optional
+	Failed []string `json:"failed,omitempty"`
+	// Versions maps the name of each member to the image tag of its Pravega container,
+	// telling apart the members still on the former version during an upgrade
+	// +optional
+	Versions map[string]string `json:"versions,omitempty"`
 }
 
 // ClusterCondition shows the current condition of a Pravega cluster.
@@ -159,6 +417,96 @@ func (ps *ClusterStatus) SetRollbackConditionFalse() {
 	ps.setClusterCondition(*c)
 }
 
+func (ps *ClusterStatus) SetInsufficientResourcesConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionInsufficientResources, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetInsufficientResourcesConditionFalse() {
+	c := newClusterCondition(ClusterConditionInsufficientResources, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetImageNotFoundConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionImageNotFound, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetImageNotFoundConditionFalse() {
+	c := newClusterCondition(ClusterConditionImageNotFound, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetVolumeExpansionBlockedConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionVolumeExpansionBlocked, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetVolumeExpansionBlockedConditionFalse() {
+	c := newClusterCondition(ClusterConditionVolumeExpansionBlocked, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetStorageClassNotFoundConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionStorageClassNotFound, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetStorageClassNotFoundConditionFalse() {
+	c := newClusterCondition(ClusterConditionStorageClassNotFound, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetConfigMapReconcileIgnoredConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionConfigMapReconcileIgnored, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetConfigMapReconcileIgnoredConditionFalse() {
+	c := newClusterCondition(ClusterConditionConfigMapReconcileIgnored, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetLtsReachableConditionTrue() {
+	c := newClusterCondition(ClusterConditionLtsReachable, corev1.ConditionTrue, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetLtsReachableConditionFalse(reason, message string) {
+	c := newClusterCondition(ClusterConditionLtsReachable, corev1.ConditionFalse, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetPodsFailedConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionPodsFailed, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetPodsFailedConditionFalse() {
+	c := newClusterCondition(ClusterConditionPodsFailed, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetScaleDownDeferredConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionScaleDownDeferred, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetScaleDownDeferredConditionFalse() {
+	c := newClusterCondition(ClusterConditionScaleDownDeferred, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetScalingStalledConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionScalingStalled, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetScalingStalledConditionFalse() {
+	c := newClusterCondition(ClusterConditionScalingStalled, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
 func newClusterCondition(condType ClusterConditionType, status corev1.ConditionStatus, reason, message string) *ClusterCondition {
 	return &ClusterCondition{
 		Type:               condType,
@@ -203,6 +551,56 @@ func (ps *ClusterStatus) setClusterCondition(newCondition ClusterCondition) {
 	ps.Conditions[position] = *existingCondition
 }
 
+// IsSegmentContainerRebalanceInProgress returns true if the segment containers are
+// unevenly spread among the segment stores. A balanced assignment differs by at most
+// one container between segment stores, so a larger difference means the controller
+// is still moving containers, e.g. after a segment store restart or a scale event.
+func (ps *ClusterStatus) IsSegmentContainerRebalanceInProgress() bool {
+	if len(ps.SegmentContainers) < 2 {
+		return false
+	}
+	min, max := ps.SegmentContainers[0].ContainerCount, ps.SegmentContainers[0].ContainerCount
+	for _, status := range ps.SegmentContainers[1:] {
+		if status.ContainerCount < min {
+			min = status.ContainerCount
+		}
+		if status.ContainerCount > max {
+			max = status.ContainerCount
+		}
+	}
+	return max-min > 1
+}
+
+// AddDeferredAction records a disruptive action deferred until the maintenance
+// window opening at next
+func (ps *ClusterStatus) AddDeferredAction(action string, next time.Time) {
+	if ps.Maintenance == nil {
+		ps.Maintenance = &MaintenanceStatus{}
+	}
+	ps.Maintenance.DeferredActions = append(ps.Maintenance.DeferredActions, action)
+	if !next.IsZero() {
+		ps.Maintenance.NextWindowStart = next.Format(time.RFC3339)
+	}
+}
+
+// SetComponentReconciled records the time the resources of the component were last
+// reconciled successfully
+func (ps *ClusterStatus) SetComponentReconciled(component string, now time.Time) {
+	if ps.ComponentReconcileTimes == nil {
+		ps.ComponentReconcileTimes = map[string]metav1.Time{}
+	}
+	ps.ComponentReconcileTimes[component] = metav1.NewTime(now)
+}
+
+// AddToUpgradeHistory records the completion of the upgrade to the version, dropping the
+// oldest entries of the upgrade history beyond the limit
+func (ps *ClusterStatus) AddToUpgradeHistory(version string, now time.Time, limit int) {
+	ps.UpgradeHistory = append(ps.UpgradeHistory, VersionHistoryEntry{Version: version, CompletionTime: metav1.NewTime(now)})
+	if limit > 0 && len(ps.UpgradeHistory) > limit {
+		ps.UpgradeHistory = ps.UpgradeHistory[len(ps.UpgradeHistory)-limit:]
+	}
+}
+
 func (ps *ClusterStatus) AddToVersionHistory(version string) {
 	lastIndex := len(ps.VersionHistory) - 1
 	if version != "" && ps.VersionHistory[lastIndex] != version {
@@ -229,7 +627,7 @@ func (ps *ClusterStatus) IsClusterInUpgradeFailedState() bool {
 	if errorCondition == nil {
 		return false
 	}
-	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == "UpgradeFailed" {
+	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == UpgradeFailedReason {
 		return true
 	}
 	return false
@@ -269,7 +667,7 @@ func (ps *ClusterStatus) IsClusterInRollbackFailedState() bool {
 	if errorCondition == nil {
 		return false
 	}
-	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == "RollbackFailed" {
+	if errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == RollbackFailedReason {
 		return true
 	}
 	return false
@@ -283,6 +681,48 @@ func (ps *ClusterStatus) IsClusterInReadyState() bool {
 	return false
 }
 
+// IsClusterInPodsFailedState reports whether the operator found pods of the cluster
+// failing to make progress
+func (ps *ClusterStatus) IsClusterInPodsFailedState() bool {
+	_, failedCondition := ps.GetClusterCondition(ClusterConditionPodsFailed)
+	return failedCondition != nil && failedCondition.Status == corev1.ConditionTrue
+}
+
+// IsClusterScalingStalled reports whether the operator found the scaling of the cluster
+// stalled
+func (ps *ClusterStatus) IsClusterScalingStalled() bool {
+	_, stalledCondition := ps.GetClusterCondition(ClusterConditionScalingStalled)
+	return stalledCondition != nil && stalledCondition.Status == corev1.ConditionTrue
+}
+
+// IsPodStuckTerminating reports whether the operator found a segment store pod stuck
+// terminating during an upgrade
+func (ps *ClusterStatus) IsPodStuckTerminating() bool {
+	_, errorCondition := ps.GetClusterCondition(ClusterConditionError)
+	return errorCondition != nil && errorCondition.Status == corev1.ConditionTrue && errorCondition.Reason == PodStuckTerminatingReason
+}
+
+// IsLtsUnreachable reports whether the operator found the long term storage unreachable.
+// It is false as long as the reachability is unknown, e.g. while the cluster starts.
+func (ps *ClusterStatus) IsLtsUnreachable() bool {
+	_, condition := ps.GetClusterCondition(ClusterConditionLtsReachable)
+	return condition != nil && condition.Status == corev1.ConditionFalse
+}
+
+// IsImageNotFound reports whether the operator found the image of the requested version
+// missing from its registry
+func (ps *ClusterStatus) IsImageNotFound() bool {
+	_, condition := ps.GetClusterCondition(ClusterConditionImageNotFound)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// IsStorageClassNotFound reports whether the operator found a storage class of the segment
+// store claims missing before creating the segment store stateful set
+func (ps *ClusterStatus) IsStorageClassNotFound() bool {
+	_, condition := ps.GetClusterCondition(ClusterConditionStorageClassNotFound)
+	return condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == StorageClassNotFoundReason
+}
+
 func (ps *ClusterStatus) UpdateProgress(reason, updatedReplicas string) {
 	if ps.IsClusterInUpgradingState() {
 		// Set the upgrade condition reason to be UpgradingBookkeeperReason, message to be 0
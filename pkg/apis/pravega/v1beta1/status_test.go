@@ -11,6 +11,8 @@
 package v1beta1_test
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -317,4 +319,140 @@ var _ = Describe("PravegaCluster Status", func() {
 			})
 		})
 	})
+
+	Context("checking for upgrade history", func() {
+		var status v1beta1.ClusterStatus
+		start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		upgrade := func(versions ...string) {
+			for i, version := range versions {
+				status.AddToUpgradeHistory(version, start.Add(time.Duration(i)*time.Hour), 3)
+			}
+		}
+		BeforeEach(func() {
+			status = v1beta1.ClusterStatus{}
+		})
+		It("should record the upgrades in the order they completed", func() {
+			upgrade("0.6.0", "0.7.0")
+			Ω(status.UpgradeHistory).Should(HaveLen(2))
+			Ω(status.UpgradeHistory[0].Version).Should(Equal("0.6.0"))
+			Ω(status.UpgradeHistory[1].Version).Should(Equal("0.7.0"))
+			Ω(status.UpgradeHistory[1].CompletionTime.Time).Should(Equal(start.Add(time.Hour)))
+		})
+		It("should drop the oldest upgrades beyond the limit", func() {
+			upgrade("0.6.0", "0.7.0", "0.7.1", "0.8.0", "0.9.0")
+			Ω(status.UpgradeHistory).Should(HaveLen(3))
+			Ω(status.UpgradeHistory[0].Version).Should(Equal("0.7.1"))
+			Ω(status.UpgradeHistory[2].Version).Should(Equal("0.9.0"))
+			Ω(status.UpgradeHistory[2].CompletionTime.Time).Should(Equal(start.Add(4 * time.Hour)))
+		})
+	})
+
+	Context("checking for long term storage reachability", func() {
+		var status v1beta1.ClusterStatus
+		BeforeEach(func() {
+			status = v1beta1.ClusterStatus{}
+			status.Init()
+		})
+		It("should not report the long term storage unreachable while unknown", func() {
+			Ω(status.IsLtsUnreachable()).To(Equal(false))
+		})
+		It("should report the long term storage unreachable with the reason", func() {
+			status.SetLtsReachableConditionFalse(v1beta1.LtsClaimNotBoundReason, "tier2 pvc pravega-tier2 is not bound")
+			Ω(status.IsLtsUnreachable()).To(Equal(true))
+			_, condition := status.GetClusterCondition(v1beta1.ClusterConditionLtsReachable)
+			Ω(condition.Reason).To(Equal(v1beta1.LtsClaimNotBoundReason))
+		})
+		It("should not report the long term storage unreachable once reachable", func() {
+			status.SetLtsReachableConditionFalse(v1beta1.SegmentStoresFailingReason, "")
+			status.SetLtsReachableConditionTrue()
+			Ω(status.IsLtsUnreachable()).To(Equal(false))
+		})
+	})
+
+	Context("checking the condition accessors on crafted statuses", func() {
+		status := func(conditions ...v1beta1.ClusterCondition) v1beta1.ClusterStatus {
+			return v1beta1.ClusterStatus{Conditions: conditions}
+		}
+		condition := func(conditionType v1beta1.ClusterConditionType, conditionStatus corev1.ConditionStatus, reason string) v1beta1.ClusterCondition {
+			return v1beta1.ClusterCondition{Type: conditionType, Status: conditionStatus, Reason: reason}
+		}
+		It("should report no state for a status without conditions", func() {
+			s := status()
+			Ω(s.IsClusterInReadyState()).To(Equal(false))
+			Ω(s.IsClusterInUpgradingState()).To(Equal(false))
+			Ω(s.IsClusterInRollbackState()).To(Equal(false))
+			Ω(s.IsClusterInErrorState()).To(Equal(false))
+			Ω(s.IsClusterInUpgradeFailedState()).To(Equal(false))
+			Ω(s.IsClusterInRollbackFailedState()).To(Equal(false))
+			Ω(s.IsClusterInPodsFailedState()).To(Equal(false))
+			Ω(s.IsClusterScalingStalled()).To(Equal(false))
+		})
+		It("should report the ready state from the pods ready condition", func() {
+			Ω(status(condition(v1beta1.ClusterConditionPodsReady, corev1.ConditionTrue, "")).IsClusterInReadyState()).To(Equal(true))
+			Ω(status(condition(v1beta1.ClusterConditionPodsReady, corev1.ConditionFalse, "")).IsClusterInReadyState()).To(Equal(false))
+		})
+		It("should report the upgrading state from the upgrading condition", func() {
+			Ω(status(condition(v1beta1.ClusterConditionUpgrading, corev1.ConditionTrue, v1beta1.UpdatingControllerReason)).IsClusterInUpgradingState()).To(Equal(true))
+			Ω(status(condition(v1beta1.ClusterConditionUpgrading, corev1.ConditionFalse, "")).IsClusterInUpgradingState()).To(Equal(false))
+		})
+		It("should report the rollback state from the rollback condition", func() {
+			Ω(status(condition(v1beta1.ClusterConditionRollback, corev1.ConditionTrue, "")).IsClusterInRollbackState()).To(Equal(true))
+			Ω(status(condition(v1beta1.ClusterConditionRollback, corev1.ConditionFalse, "")).IsClusterInRollbackState()).To(Equal(false))
+		})
+		It("should report the upgrade failed state only with the upgrade failed reason", func() {
+			s := status(condition(v1beta1.ClusterConditionError, corev1.ConditionTrue, v1beta1.UpgradeFailedReason))
+			Ω(s.IsClusterInErrorState()).To(Equal(true))
+			Ω(s.IsClusterInUpgradeFailedState()).To(Equal(true))
+			Ω(s.IsClusterInRollbackFailedState()).To(Equal(false))
+			Ω(s.IsClusterInUpgradeFailedOrRollbackState()).To(Equal(true))
+		})
+		It("should report the rollback failed state only with the rollback failed reason", func() {
+			s := status(condition(v1beta1.ClusterConditionError, corev1.ConditionTrue, v1beta1.RollbackFailedReason))
+			Ω(s.IsClusterInErrorState()).To(Equal(true))
+			Ω(s.IsClusterInRollbackFailedState()).To(Equal(true))
+			Ω(s.IsClusterInUpgradeFailedState()).To(Equal(false))
+		})
+		It("should report a pod stuck terminating only with the pod stuck terminating reason", func() {
+			s := status(condition(v1beta1.ClusterConditionError, corev1.ConditionTrue, v1beta1.PodStuckTerminatingReason))
+			Ω(s.IsPodStuckTerminating()).To(Equal(true))
+			Ω(s.IsClusterInUpgradeFailedOrRollbackState()).To(Equal(false))
+			Ω(status(condition(v1beta1.ClusterConditionError, corev1.ConditionTrue, v1beta1.UpgradeFailedReason)).IsPodStuckTerminating()).To(Equal(false))
+			Ω(status(condition(v1beta1.ClusterConditionError, corev1.ConditionFalse, v1beta1.PodStuckTerminatingReason)).IsPodStuckTerminating()).To(Equal(false))
+		})
+		It("should not report a failed state from a false error condition", func() {
+			s := status(condition(v1beta1.ClusterConditionError, corev1.ConditionFalse, v1beta1.UpgradeFailedReason))
+			Ω(s.IsClusterInErrorState()).To(Equal(false))
+			Ω(s.IsClusterInUpgradeFailedState()).To(Equal(false))
+		})
+		It("should report the pods failed state from the pods failed condition", func() {
+			Ω(status(condition(v1beta1.ClusterConditionPodsFailed, corev1.ConditionTrue, v1beta1.PodsNotProgressingReason)).IsClusterInPodsFailedState()).To(Equal(true))
+			Ω(status(condition(v1beta1.ClusterConditionPodsFailed, corev1.ConditionFalse, "")).IsClusterInPodsFailedState()).To(Equal(false))
+		})
+		It("should report the stalled scaling from the scaling stalled condition", func() {
+			Ω(status(condition(v1beta1.ClusterConditionScalingStalled, corev1.ConditionTrue, v1beta1.PodsPendingReason)).IsClusterScalingStalled()).To(Equal(true))
+			Ω(status(condition(v1beta1.ClusterConditionScalingStalled, corev1.ConditionFalse, "")).IsClusterScalingStalled()).To(Equal(false))
+		})
+	})
+
+	Context("checking for segment container rebalance", func() {
+		counts := func(containerCounts ...int32) []v1beta1.SegmentContainerStatus {
+			statuses := []v1beta1.SegmentContainerStatus{}
+			for i, count := range containerCounts {
+				statuses = append(statuses, v1beta1.SegmentContainerStatus{Ordinal: int32(i), ContainerCount: count})
+			}
+			return statuses
+		}
+		It("should not report a rebalance with a single segment store", func() {
+			p.Status.SegmentContainers = counts(8)
+			Ω(p.Status.IsSegmentContainerRebalanceInProgress()).To(Equal(false))
+		})
+		It("should not report a rebalance when the containers are evenly spread", func() {
+			p.Status.SegmentContainers = counts(3, 2, 3)
+			Ω(p.Status.IsSegmentContainerRebalanceInProgress()).To(Equal(false))
+		})
+		It("should report a rebalance when the containers are unevenly spread", func() {
+			p.Status.SegmentContainers = counts(4, 4, 0)
+			Ω(p.Status.IsSegmentContainerRebalanceInProgress()).To(Equal(true))
+		})
+	})
 })
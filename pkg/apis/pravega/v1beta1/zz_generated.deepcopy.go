@@ -6,12 +6,19 @@ package v1beta1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthenticationParameters) DeepCopyInto(out *AuthenticationParameters) {
 	*out = *in
+	if in.ControllerTokenSecret != nil {
+		in, out := &in.ControllerTokenSecret, &out.ControllerTokenSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -47,7 +54,7 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	if in.ExternalAccess != nil {
 		in, out := &in.ExternalAccess, &out.ExternalAccess
 		*out = new(ExternalAccess)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.TLS != nil {
 		in, out := &in.TLS, &out.TLS
@@ -57,6 +64,26 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	if in.Authentication != nil {
 		in, out := &in.Authentication, &out.Authentication
 		*out = new(AuthenticationParameters)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeConfig != nil {
+		in, out := &in.UpgradeConfig, &out.UpgradeConfig
+		*out = new(UpgradeConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScalingStallTimeoutSeconds != nil {
+		in, out := &in.ScalingStallTimeoutSeconds, &out.ScalingStallTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VersionHistoryLimit != nil {
+		in, out := &in.VersionHistoryLimit, &out.VersionHistoryLimit
+		*out = new(int32)
 		**out = **in
 	}
 	if in.Pravega != nil {
@@ -90,7 +117,53 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UpgradeHistory != nil {
+		in, out := &in.UpgradeHistory, &out.UpgradeHistory
+		*out = make([]VersionHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Members.DeepCopyInto(&out.Members)
+	if in.SegmentContainers != nil {
+		in, out := &in.SegmentContainers, &out.SegmentContainers
+		*out = make([]SegmentContainerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Throughput != nil {
+		in, out := &in.Throughput, &out.Throughput
+		*out = new(ThroughputStatus)
+		**out = **in
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RunAsIdentity != nil {
+		in, out := &in.RunAsIdentity, &out.RunAsIdentity
+		*out = new(RunAsIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSSecretHashes != nil {
+		in, out := &in.TLSSecretHashes, &out.TLSSecretHashes
+		*out = new(TLSSecretHashes)
+		**out = **in
+	}
+	if in.SegmentStoreEndpoints != nil {
+		in, out := &in.SegmentStoreEndpoints, &out.SegmentStoreEndpoints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ComponentReconcileTimes != nil {
+		in, out := &in.ComponentReconcileTimes, &out.ComponentReconcileTimes
+		*out = make(map[string]metav1.Time, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	return
 }
 
@@ -104,9 +177,66 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerProbesSpec) DeepCopyInto(out *ControllerProbesSpec) {
+	*out = *in
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(ProbeSpec)
+		**out = **in
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(ProbeSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerProbesSpec.
+func (in *ControllerProbesSpec) DeepCopy() *ControllerProbesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerProbesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerRequestTimeoutsSpec) DeepCopyInto(out *ControllerRequestTimeoutsSpec) {
+	*out = *in
+	if in.RequestTimeoutSeconds != nil {
+		in, out := &in.RequestTimeoutSeconds, &out.RequestTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TransactionMaxLeaseSeconds != nil {
+		in, out := &in.TransactionMaxLeaseSeconds, &out.TransactionMaxLeaseSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRequestTimeoutsSpec.
+func (in *ControllerRequestTimeoutsSpec) DeepCopy() *ControllerRequestTimeoutsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerRequestTimeoutsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ECSSpec) DeepCopyInto(out *ECSSpec) {
 	*out = *in
+	if in.SessionToken != nil {
+		in, out := &in.SessionToken, &out.SessionToken
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -123,6 +253,13 @@ func (in *ECSSpec) DeepCopy() *ECSSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalAccess) DeepCopyInto(out *ExternalAccess) {
 	*out = *in
+	if in.LoadBalancerTags != nil {
+		in, out := &in.LoadBalancerTags, &out.LoadBalancerTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -189,6 +326,66 @@ func (in *ImageSpec) DeepCopy() *ImageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JVMDerivedResourcesSpec) DeepCopyInto(out *JVMDerivedResourcesSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JVMDerivedResourcesSpec.
+func (in *JVMDerivedResourcesSpec) DeepCopy() *JVMDerivedResourcesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JVMDerivedResourcesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JournalVolumeSpec) DeepCopyInto(out *JournalVolumeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JournalVolumeSpec.
+func (in *JournalVolumeSpec) DeepCopy() *JournalVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JournalVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingSidecarSpec) DeepCopyInto(out *LoggingSidecarSpec) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingSidecarSpec.
+func (in *LoggingSidecarSpec) DeepCopy() *LoggingSidecarSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingSidecarSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LongTermStorageSpec) DeepCopyInto(out *LongTermStorageSpec) {
 	*out = *in
@@ -200,13 +397,18 @@ func (in *LongTermStorageSpec) DeepCopyInto(out *LongTermStorageSpec) {
 	if in.Ecs != nil {
 		in, out := &in.Ecs, &out.Ecs
 		*out = new(ECSSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Hdfs != nil {
 		in, out := &in.Hdfs, &out.Hdfs
 		*out = new(HDFSSpec)
 		**out = **in
 	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Spec)
+		**out = **in
+	}
 	return
 }
 
@@ -220,6 +422,43 @@ func (in *LongTermStorageSpec) DeepCopy() *LongTermStorageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceStatus) DeepCopyInto(out *MaintenanceStatus) {
+	*out = *in
+	if in.DeferredActions != nil {
+		in, out := &in.DeferredActions, &out.DeferredActions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceStatus.
+func (in *MaintenanceStatus) DeepCopy() *MaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MembersStatus) DeepCopyInto(out *MembersStatus) {
 	*out = *in
@@ -233,6 +472,18 @@ func (in *MembersStatus) DeepCopyInto(out *MembersStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Failed != nil {
+		in, out := &in.Failed, &out.Failed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -246,6 +497,32 @@ func (in *MembersStatus) DeepCopy() *MembersStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsSpec) DeepCopyInto(out *MetricsSpec) {
+	*out = *in
+	if in.ReportingIntervalSeconds != nil {
+		in, out := &in.ReportingIntervalSeconds, &out.ReportingIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsSpec.
+func (in *MetricsSpec) DeepCopy() *MetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PravegaCluster) DeepCopyInto(out *PravegaCluster) {
 	*out = *in
@@ -315,6 +592,16 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(ImageSpec)
 		**out = **in
 	}
+	if in.ControllerImage != nil {
+		in, out := &in.ControllerImage, &out.ControllerImage
+		*out = new(ImageSpec)
+		**out = **in
+	}
+	if in.SegmentStoreImage != nil {
+		in, out := &in.SegmentStoreImage, &out.SegmentStoreImage
+		*out = new(ImageSpec)
+		**out = **in
+	}
 	if in.Options != nil {
 		in, out := &in.Options, &out.Options
 		*out = make(map[string]string, len(*in))
@@ -337,11 +624,33 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(v1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SegmentStoreJournalVolume != nil {
+		in, out := &in.SegmentStoreJournalVolume, &out.SegmentStoreJournalVolume
+		*out = new(JournalVolumeSpec)
+		**out = **in
+	}
+	if in.PVCLabels != nil {
+		in, out := &in.PVCLabels, &out.PVCLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.LongTermStorage != nil {
 		in, out := &in.LongTermStorage, &out.LongTermStorage
 		*out = new(LongTermStorageSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ControllerAutomountServiceAccountToken != nil {
+		in, out := &in.ControllerAutomountServiceAccountToken, &out.ControllerAutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SegmentStoreAutomountServiceAccountToken != nil {
+		in, out := &in.SegmentStoreAutomountServiceAccountToken, &out.SegmentStoreAutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
 	if in.ControllerResources != nil {
 		in, out := &in.ControllerResources, &out.ControllerResources
 		*out = new(v1.ResourceRequirements)
@@ -352,6 +661,20 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ControllerExtraEnv != nil {
+		in, out := &in.ControllerExtraEnv, &out.ControllerExtraEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SegmentStoreExtraEnv != nil {
+		in, out := &in.SegmentStoreExtraEnv, &out.SegmentStoreExtraEnv
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.SegmentStoreSecret != nil {
 		in, out := &in.SegmentStoreSecret, &out.SegmentStoreSecret
 		*out = new(SegmentStoreSecret)
@@ -371,6 +694,13 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.SegmentStoreHeadlessServiceAnnotations != nil {
+		in, out := &in.SegmentStoreHeadlessServiceAnnotations, &out.SegmentStoreHeadlessServiceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.SegmentStoreSecurityContext != nil {
 		in, out := &in.SegmentStoreSecurityContext, &out.SegmentStoreSecurityContext
 		*out = new(v1.PodSecurityContext)
@@ -381,6 +711,16 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(v1.PodSecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.SegmentStoreContainerSecurityContext != nil {
+		in, out := &in.SegmentStoreContainerSecurityContext, &out.SegmentStoreContainerSecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControllerContainerSecurityContext != nil {
+		in, out := &in.ControllerContainerSecurityContext, &out.ControllerContainerSecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ControllerPodAffinity != nil {
 		in, out := &in.ControllerPodAffinity, &out.ControllerPodAffinity
 		*out = new(v1.Affinity)
@@ -391,6 +731,123 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ControllerPodNodeSelector != nil {
+		in, out := &in.ControllerPodNodeSelector, &out.ControllerPodNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SegmentStorePodNodeSelector != nil {
+		in, out := &in.SegmentStorePodNodeSelector, &out.SegmentStorePodNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ControllerDnsConfig != nil {
+		in, out := &in.ControllerDnsConfig, &out.ControllerDnsConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SegmentStoreDnsConfig != nil {
+		in, out := &in.SegmentStoreDnsConfig, &out.SegmentStoreDnsConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SegmentStoreTopologySpreadConstraints != nil {
+		in, out := &in.SegmentStoreTopologySpreadConstraints, &out.SegmentStoreTopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(MetricsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LongTermStorageProbeIntervalSeconds != nil {
+		in, out := &in.LongTermStorageProbeIntervalSeconds, &out.LongTermStorageProbeIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SegmentStoreTerminationGracePeriodSeconds != nil {
+		in, out := &in.SegmentStoreTerminationGracePeriodSeconds, &out.SegmentStoreTerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SegmentStoreContainerCount != nil {
+		in, out := &in.SegmentStoreContainerCount, &out.SegmentStoreContainerCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SegmentStoreInitContainers != nil {
+		in, out := &in.SegmentStoreInitContainers, &out.SegmentStoreInitContainers
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SegmentStoreVolumes != nil {
+		in, out := &in.SegmentStoreVolumes, &out.SegmentStoreVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SegmentStoreVolumeMounts != nil {
+		in, out := &in.SegmentStoreVolumeMounts, &out.SegmentStoreVolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ControllerProbes != nil {
+		in, out := &in.ControllerProbes, &out.ControllerProbes
+		*out = new(ControllerProbesSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JVMDerivedResources != nil {
+		in, out := &in.JVMDerivedResources, &out.JVMDerivedResources
+		*out = new(JVMDerivedResourcesSpec)
+		**out = **in
+	}
+	if in.ControllerRequestTimeouts != nil {
+		in, out := &in.ControllerRequestTimeouts, &out.ControllerRequestTimeouts
+		*out = new(ControllerRequestTimeoutsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoggingSidecar != nil {
+		in, out := &in.LoggingSidecar, &out.LoggingSidecar
+		*out = new(LoggingSidecarSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SegmentStorePdb != nil {
+		in, out := &in.SegmentStorePdb, &out.SegmentStorePdb
+		*out = new(SegmentStorePdbSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControllerCommand != nil {
+		in, out := &in.ControllerCommand, &out.ControllerCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ControllerArgs != nil {
+		in, out := &in.ControllerArgs, &out.ControllerArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SegmentStoreCommand != nil {
+		in, out := &in.SegmentStoreCommand, &out.SegmentStoreCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SegmentStoreArgs != nil {
+		in, out := &in.SegmentStoreArgs, &out.SegmentStoreArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -404,6 +861,101 @@ func (in *PravegaSpec) DeepCopy() *PravegaSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeSpec) DeepCopyInto(out *ProbeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeSpec.
+func (in *ProbeSpec) DeepCopy() *ProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunAsIdentity) DeepCopyInto(out *RunAsIdentity) {
+	*out = *in
+	if in.RunAsGroup != nil {
+		in, out := &in.RunAsGroup, &out.RunAsGroup
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunAsIdentity.
+func (in *RunAsIdentity) DeepCopy() *RunAsIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(RunAsIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Spec) DeepCopyInto(out *S3Spec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Spec.
+func (in *S3Spec) DeepCopy() *S3Spec {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Spec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SegmentContainerStatus) DeepCopyInto(out *SegmentContainerStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SegmentContainerStatus.
+func (in *SegmentContainerStatus) DeepCopy() *SegmentContainerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SegmentContainerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SegmentStorePdbSpec) DeepCopyInto(out *SegmentStorePdbSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SegmentStorePdbSpec.
+func (in *SegmentStorePdbSpec) DeepCopy() *SegmentStorePdbSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SegmentStorePdbSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SegmentStoreSecret) DeepCopyInto(out *SegmentStoreSecret) {
 	*out = *in
@@ -420,9 +972,42 @@ func (in *SegmentStoreSecret) DeepCopy() *SegmentStoreSecret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorSpec) DeepCopyInto(out *ServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorSpec.
+func (in *ServiceMonitorSpec) DeepCopy() *ServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticTLS) DeepCopyInto(out *StaticTLS) {
 	*out = *in
+	if in.ControllerKeys != nil {
+		in, out := &in.ControllerKeys, &out.ControllerKeys
+		*out = new(TLSSecretKeys)
+		**out = **in
+	}
+	if in.SegmentStoreKeys != nil {
+		in, out := &in.SegmentStoreKeys, &out.SegmentStoreKeys
+		*out = new(TLSSecretKeys)
+		**out = **in
+	}
 	return
 }
 
@@ -442,7 +1027,7 @@ func (in *TLSPolicy) DeepCopyInto(out *TLSPolicy) {
 	if in.Static != nil {
 		in, out := &in.Static, &out.Static
 		*out = new(StaticTLS)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -456,3 +1041,99 @@ func (in *TLSPolicy) DeepCopy() *TLSPolicy {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecretHashes) DeepCopyInto(out *TLSSecretHashes) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecretHashes.
+func (in *TLSSecretHashes) DeepCopy() *TLSSecretHashes {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecretHashes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSecretKeys) DeepCopyInto(out *TLSSecretKeys) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSecretKeys.
+func (in *TLSSecretKeys) DeepCopy() *TLSSecretKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSecretKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThroughputStatus) DeepCopyInto(out *ThroughputStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThroughputStatus.
+func (in *ThroughputStatus) DeepCopy() *ThroughputStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThroughputStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeConfig) DeepCopyInto(out *UpgradeConfig) {
+	*out = *in
+	if in.SegmentStoreMaxUnavailable != nil {
+		in, out := &in.SegmentStoreMaxUnavailable, &out.SegmentStoreMaxUnavailable
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodReadyTimeoutSeconds != nil {
+		in, out := &in.PodReadyTimeoutSeconds, &out.PodReadyTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TerminatingPodTimeoutSeconds != nil {
+		in, out := &in.TerminatingPodTimeoutSeconds, &out.TerminatingPodTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeConfig.
+func (in *UpgradeConfig) DeepCopy() *UpgradeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionHistoryEntry) DeepCopyInto(out *VersionHistoryEntry) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionHistoryEntry.
+func (in *VersionHistoryEntry) DeepCopy() *VersionHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -76,7 +76,9 @@ var _ = Describe("PravegaCluster DeepCopy", func() {
 			p2.Status = *p1.Status.DeepCopy()
 			p1.Status.Members.Ready = []string{"bookie-0", "bookie-1"}
 			p1.Status.Members.Unready = []string{"bookie-3", "bookie-2"}
+			p1.Status.Members.Versions = map[string]string{"bookie-0": "0.9.0"}
 			p2.Status.Members = *p1.Status.Members.DeepCopy()
+			p1.Status.Members.Versions["bookie-0"] = "0.8.0"
 			p1.Spec.ExternalAccess.DomainName = "example.com"
 			p2.Spec.ExternalAccess = p1.Spec.ExternalAccess.DeepCopy()
 			p1.Spec.TLS.Static.ControllerSecret = "controller-secret"
@@ -145,6 +147,9 @@ var _ = Describe("PravegaCluster DeepCopy", func() {
 		It("checking  unready members", func() {
 			Ω(p2.Status.Members.Unready[0]).To(Equal("bookie-3"))
 		})
+		It("checking member versions", func() {
+			Ω(p2.Status.Members.Versions["bookie-0"]).To(Equal("0.9.0"))
+		})
 		It("checking  external access domain name", func() {
 			Ω(p2.Spec.ExternalAccess.DomainName).To(Equal("example.com"))
 		})
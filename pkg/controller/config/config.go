@@ -10,8 +10,61 @@
 
 package config
 
+import "time"
+
 // TestMode enables test mode in the operator and applies
 // the following changes:
 // - Disables Pravega Controller minimum number of replicas
 // - Disables Segment Store minimum number of replicas
 var TestMode bool
+
+// NodeWatch enables watching the nodes in the operator, so that segment store
+// pods are restarted when the node annotation configured in the cluster spec
+// changes. It requires get, list and watch permissions on nodes.
+var NodeWatch bool
+
+// GrafanaDashboard enables creating, for each cluster, a ConfigMap holding a
+// Grafana dashboard, labeled so that the Grafana sidecar imports it.
+var GrafanaDashboard bool
+
+// StorageClassCheck enables checking that the storage classes of the segment store
+// cache and journal claims exist before creating the segment store stateful set.
+// It requires the get permission on storage classes.
+var StorageClassCheck bool
+
+// ThroughputStatus enables sampling the write throughput of the segment stores
+// from their Prometheus endpoint and recording it in the cluster status, at
+// most once per ThroughputStatusInterval. Each sample scrapes every segment
+// store pod.
+var ThroughputStatus bool
+
+// ThroughputStatusInterval is the minimal delay between two throughput samples
+var ThroughputStatusInterval time.Duration
+
+// ForceDeleteStuckPods enables deleting, with no grace period, the segment store pods
+// found stuck terminating during an upgrade, e.g. because their volumes fail to detach.
+// Only the pods of the segment store stateful set of the cluster are deleted.
+var ForceDeleteStuckPods bool
+
+// HealthAddr is the address of the operator health endpoint, summarizing the
+// health of the managed clusters. The endpoint is disabled if empty.
+var HealthAddr string
+
+// DefaultMaxReconcileBackoff is the default of MaxReconcileBackoff
+const DefaultMaxReconcileBackoff = 5 * time.Minute
+
+// MaxReconcileBackoff caps the delay before requeueing a cluster after consecutive
+// failed reconciles, which doubles on each failure
+var MaxReconcileBackoff = DefaultMaxReconcileBackoff
+
+// DefaultFullReconcileInterval is the default of FullReconcileInterval
+const DefaultFullReconcileInterval = 10
+
+// FullReconcileInterval is the number of consecutive reconciles of an unchanged and
+// healthy cluster after which its child objects are rebuilt and compared again. The
+// other reconciles only sync its status. Every reconcile is a full one if it is below 2.
+var FullReconcileInterval int
+
+// WatchNamespaces are the namespaces whose clusters are reconciled by the operator,
+// all the namespaces if empty. The cache of the manager is scoped to them.
+var WatchNamespaces []string
@@ -13,6 +13,9 @@ package pravega
 const (
 	cacheVolumeName        = "cache"
 	cacheVolumeMountPoint  = "/tmp/pravega/cache"
+	journalVolumeName      = "journal"
+	journalMountPoint      = "/tmp/pravega/journal"
+	journalDirectoryOption = "bookkeeper.journal.directory"
 	ltsFileMountPoint      = "/mnt/tier2"
 	ltsVolumeName          = "tier2"
 	segmentStoreKind       = "pravega-segmentstore"
@@ -26,4 +29,42 @@ const (
 	authVolumeName         = "auth-passwd-secret"
 	authMountDir           = "/etc/auth-passwd-volume"
 	defaultTokenSigningKey = "secret"
+	tokenSigningKeyEnv     = "TOKEN_SIGNING_KEY"
+	hostIPEnv              = "HOST_IP"
+	javaOptsEnv            = "JAVA_OPTS"
+	initWaitContainerName  = "wait-for-dependency"
+	initWaitURLEnv         = "WAIT_URL"
+	logsVolumeName         = "logs"
+	logDirEnv              = "LOG_DIR"
+	logDestinationEnv      = "LOG_DESTINATION"
+
+	// Label looked for by the Grafana sidecar to import dashboards
+	grafanaDashboardLabelKey   = "grafana_dashboard"
+	grafanaDashboardLabelValue = "1"
 )
+
+// TLSSecretHashAnnotationKey is the pod template annotation holding the hash of the TLS
+// secrets mounted in the pods, set when the TLS reloadOnChange option is enabled
+const TLSSecretHashAnnotationKey = "pravega.tlsSecretHash"
+
+// ConfigMapHashAnnotationKey is the pod template annotation holding the hash of the
+// configmap of the component, e.g. after a change of the Zookeeper URI, a new value
+// restarts the pods
+const ConfigMapHashAnnotationKey = "pravega.configMapHash"
+
+// SegmentStoreRESTPortName is the name of the headless service port serving the segment
+// store metrics scraped by the ServiceMonitor
+const SegmentStoreRESTPortName = "rest"
+
+// AppliedAnnotationsAnnotationKey is the headless service annotation listing the keys of
+// the headless service annotations of the spec, so that the keys removed from the spec
+// are removed from the service while the annotations set by others are kept
+const AppliedAnnotationsAnnotationKey = "pravega.appliedAnnotations"
+
+// LoadBalancerTagsAnnotationKey is the external service annotation holding the tags of
+// its cloud load balancer, set from the load balancer tags of the external access
+const LoadBalancerTagsAnnotationKey = "service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"
+
+// RestartAnnotationKey is the pod template annotation holding the value of the restart
+// annotation of the cluster, a new value rolls the pods
+const RestartAnnotationKey = "pravega.restart"
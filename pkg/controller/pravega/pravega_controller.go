@@ -11,7 +11,10 @@
 package pravega
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 
@@ -24,6 +27,10 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// ControllerMinAvailable is the number of controller pods kept available by the pod
+// disruption budget, the rolling updates and the scale-downs of the controller deployment
+const ControllerMinAvailable = 1
+
 func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 	zero := int32(0)
 	timeout := int32(600)
@@ -41,6 +48,7 @@ func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 			ProgressDeadlineSeconds: &timeout,
 			Replicas:                &p.Spec.Pravega.ControllerReplicas,
 			RevisionHistoryLimit:    &zero,
+			Strategy:                MakeControllerDeploymentStrategy(),
 			Template:                MakeControllerPodTemplate(p),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: p.LabelsForController(),
@@ -49,11 +57,36 @@ func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 	}
 }
 
+// MakeControllerDeploymentStrategy returns the rolling update strategy of the controller
+// deployment, which starts each new pod before taking an old one down, so that the
+// ready controllers never drop below the pod disruption budget
+func MakeControllerDeploymentStrategy() appsv1.DeploymentStrategy {
+	maxUnavailable := intstr.FromInt(0)
+	maxSurge := intstr.FromInt(1)
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &maxUnavailable,
+			MaxSurge:       &maxSurge,
+		},
+	}
+}
+
 func MakeControllerPodTemplate(p *api.PravegaCluster) corev1.PodTemplateSpec {
+	annotations := map[string]string{
+		"pravega.version":          p.Spec.Version,
+		ConfigMapHashAnnotationKey: ConfigMapHash(MakeControllerConfigMap(p)),
+	}
+	if hashes := p.Status.TLSSecretHashes; hashes != nil && hashes.Controller != "" {
+		annotations[TLSSecretHashAnnotationKey] = hashes.Controller
+	}
+	if restart := p.Annotations[api.RestartAnnotation]; restart != "" {
+		annotations[RestartAnnotationKey] = restart
+	}
 	return corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      p.LabelsForController(),
-			Annotations: map[string]string{"pravega.version": p.Spec.Version},
+			Annotations: annotations,
 		},
 		Spec: *makeControllerPodSpec(p),
 	}
@@ -64,8 +97,9 @@ func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
 		Containers: []corev1.Container{
 			{
 				Name:            "pravega-controller",
-				Image:           p.PravegaImage(),
-				ImagePullPolicy: p.Spec.Pravega.Image.PullPolicy,
+				Image:           p.ControllerImage(),
+				ImagePullPolicy: p.ControllerImagePullPolicy(),
+				SecurityContext: p.Spec.Pravega.ControllerContainerSecurityContext,
 				Args: []string{
 					"controller",
 				},
@@ -88,22 +122,24 @@ func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
 						},
 					},
 				},
+				Env: controllerEnv(p),
 				VolumeMounts: []corev1.VolumeMount{
 					{
 						Name:      heapDumpName,
 						MountPath: heapDumpDir,
 					},
 				},
-				Resources: *p.Spec.Pravega.ControllerResources,
+				Resources: *p.ControllerResourceRequirements(),
 				ReadinessProbe: &corev1.Probe{
 					Handler: corev1.Handler{
 						Exec: &corev1.ExecAction{
 							Command: util.ControllerReadinessCheck(10080, p.Spec.Authentication.IsEnabled()),
 						},
 					},
-					// Controller pods start fast. We give it up to 20 seconds to become ready.
-					InitialDelaySeconds: 20,
-					TimeoutSeconds:      60,
+					InitialDelaySeconds: p.Spec.Pravega.ControllerProbes.ReadinessProbe.InitialDelaySeconds,
+					PeriodSeconds:       p.Spec.Pravega.ControllerProbes.ReadinessProbe.PeriodSeconds,
+					FailureThreshold:    p.Spec.Pravega.ControllerProbes.ReadinessProbe.FailureThreshold,
+					TimeoutSeconds:      p.Spec.Pravega.ControllerProbes.ReadinessProbe.TimeoutSeconds,
 					SuccessThreshold:    3,
 				},
 				LivenessProbe: &corev1.Probe{
@@ -112,17 +148,18 @@ func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
 							Command: util.HealthcheckCommand(9090),
 						},
 					},
-					// We start the liveness probe from the maximum time the pod can take
-					// before becoming ready.
-					// If the pod fails the health check during 1 minute, Kubernetes
-					// will restart it.
-					InitialDelaySeconds: 60,
-					PeriodSeconds:       15,
-					FailureThreshold:    4,
+					InitialDelaySeconds: p.Spec.Pravega.ControllerProbes.LivenessProbe.InitialDelaySeconds,
+					PeriodSeconds:       p.Spec.Pravega.ControllerProbes.LivenessProbe.PeriodSeconds,
+					FailureThreshold:    p.Spec.Pravega.ControllerProbes.LivenessProbe.FailureThreshold,
+					TimeoutSeconds:      p.Spec.Pravega.ControllerProbes.LivenessProbe.TimeoutSeconds,
 				},
 			},
 		},
-		Affinity: p.Spec.Pravega.ControllerPodAffinity,
+		Affinity:                     util.MergeAffinity(util.PodAntiAffinity("pravega-controller", p.GetName()), p.Spec.Pravega.ControllerPodAffinity),
+		NodeSelector:                 p.Spec.Pravega.ControllerPodNodeSelector,
+		DNSPolicy:                    p.Spec.Pravega.ControllerDnsPolicy,
+		DNSConfig:                    p.Spec.Pravega.ControllerDnsConfig,
+		AutomountServiceAccountToken: p.Spec.Pravega.ControllerAutomountServiceAccountToken,
 		Volumes: []corev1.Volume{
 			{
 				Name: heapDumpName,
@@ -142,6 +179,11 @@ func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
 
 	configureControllerTLSSecrets(podSpec, p)
 	configureAuthSecrets(podSpec, p)
+	configureInitWait(podSpec, p)
+	configureLoggingSidecar(podSpec, p)
+	configureRunAsIdentity(podSpec, p)
+	configureExtraEnv(podSpec, p.Spec.Pravega.ControllerExtraEnv, ControllerExtraEnvConflicts(p))
+	configureCommandOverride(&podSpec.Containers[0], p.Spec.Pravega.ControllerCommand, p.Spec.Pravega.ControllerArgs)
 	return podSpec
 }
 
@@ -158,6 +200,54 @@ func configureAuthSecrets(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
 	}
 }
 
+// controllerEnv returns the environment variables of the Controller container set by
+// the operator outside of the configmap
+func controllerEnv(p *api.PravegaCluster) []corev1.EnvVar {
+	return append(authEnv(p), extraJavaOptsEnv(tokenSigningKeyOptions(p, "controller.security.auth.delegationToken.signingKey.basis"))...)
+}
+
+// authEnv returns the environment variables of the Controller and the Segment Store read
+// from the authentication secrets, i.e. the key signing the delegation tokens
+func authEnv(p *api.PravegaCluster) []corev1.EnvVar {
+	if !p.Spec.Authentication.IsEnabled() || p.Spec.Authentication.ControllerTokenSecret == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:      tokenSigningKeyEnv,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: p.Spec.Authentication.ControllerTokenSecret},
+		},
+	}
+}
+
+// tokenSigningKeyOptions returns the JVM options passing the key read by authEnv to the
+// given Pravega options, which the images do not set from the environment by themselves
+func tokenSigningKeyOptions(p *api.PravegaCluster, options ...string) []string {
+	if authEnv(p) == nil {
+		return nil
+	}
+	var jvmOpts []string
+	for _, option := range options {
+		jvmOpts = append(jvmOpts, fmt.Sprintf("-D%s=$(%s)", option, tokenSigningKeyEnv))
+	}
+	return jvmOpts
+}
+
+// extraJavaOptsEnv returns the environment variable appending JVM options which differ
+// between the pods or are read from secrets to the JAVA_OPTS of the configmap. Kubernetes
+// expands the variables they refer to when starting the container
+func extraJavaOptsEnv(jvmOpts []string) []corev1.EnvVar {
+	if len(jvmOpts) == 0 {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  javaOptsEnv,
+			Value: fmt.Sprintf("$(%s) %s", javaOptsEnv, strings.Join(jvmOpts, " ")),
+		},
+	}
+}
+
 func addSecretVolumeWithMount(podSpec *corev1.PodSpec, p *api.PravegaCluster,
 	volumeName string, secretName string,
 	mountName string, mountDir string) {
@@ -177,35 +267,194 @@ func addSecretVolumeWithMount(podSpec *corev1.PodSpec, p *api.PravegaCluster,
 	})
 }
 
-func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
-	javaOpts := []string{
-		"-Dpravegaservice.clusterName=" + p.Name,
+// configureInitWait adds an init container that blocks the pod from starting
+// until the configured init wait URL is reachable. It runs the image and pull policy
+// of the component container, so that it only changes along with it
+func configureInitWait(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	if p.Spec.Pravega.InitWaitURL == "" {
+		return
 	}
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:            initWaitContainerName,
+		Image:           podSpec.Containers[0].Image,
+		ImagePullPolicy: podSpec.Containers[0].ImagePullPolicy,
+		Command: []string{"/bin/sh", "-c",
+			fmt.Sprintf("until curl -s -o /dev/null \"$%s\"; do echo waiting for $%s; sleep 2; done", initWaitURLEnv, initWaitURLEnv)},
+		Env: []corev1.EnvVar{
+			{
+				Name:  initWaitURLEnv,
+				Value: p.Spec.Pravega.InitWaitURL,
+			},
+		},
+	})
+}
 
-	jvmOpts := []string{
-		"-Xms512m",
-		"-XX:+ExitOnOutOfMemoryError",
-		"-XX:+CrashOnOutOfMemoryError",
-		"-XX:+HeapDumpOnOutOfMemoryError",
-		"-XX:HeapDumpPath=" + heapDumpDir,
-		"-Dpravegaservice.clusterName=" + p.Name,
+// configureCommandOverride replaces the command or the args of a Pravega container for
+// debugging. A command override also clears the default args, and the liveness probe is
+// removed so that the kubelet does not restart a container which no longer serves it
+func configureCommandOverride(container *corev1.Container, command []string, args []string) {
+	if len(command) == 0 && len(args) == 0 {
+		return
+	}
+	if len(command) > 0 {
+		container.Command = command
 	}
+	container.Args = args
+	container.LivenessProbe = nil
+}
 
-	if match, _ := util.CompareVersions(p.Spec.Version, "0.4.0", ">="); match {
-		// Pravega < 0.4 uses a Java version that does not support the options below
-		jvmOpts = append(jvmOpts,
-			"-XX:+UnlockExperimentalVMOptions",
-			"-XX:+UseContainerSupport",
-			"-XX:MaxRAMPercentage=50.0",
-		)
+// configureLoggingSidecar shares an emptyDir log volume between the main container and
+// a sidecar tailing and forwarding the log files written to it
+func configureLoggingSidecar(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	sidecar := p.Spec.Pravega.LoggingSidecar
+	if sidecar == nil {
+		return
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: logsVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      logsVolumeName,
+		MountPath: sidecar.MountPath,
+	})
+	container := corev1.Container{
+		Name:            api.LoggingSidecarName,
+		Image:           sidecar.Image,
+		ImagePullPolicy: sidecar.ImagePullPolicy,
+		Env: append([]corev1.EnvVar{
+			{
+				Name:  logDirEnv,
+				Value: sidecar.MountPath,
+			},
+			{
+				Name:  logDestinationEnv,
+				Value: sidecar.Destination,
+			},
+		}, sidecar.Env...),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      logsVolumeName,
+				MountPath: sidecar.MountPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	if sidecar.Resources != nil {
+		container.Resources = *sidecar.Resources.DeepCopy()
 	}
+	podSpec.Containers = append(podSpec.Containers, container)
+}
 
-	javaOpts = append(javaOpts, util.OverrideDefaultJVMOptions(jvmOpts, p.Spec.Pravega.ControllerJvmOptions)...)
+// configureRunAsIdentity applies the user and group IDs read from the runAsIdentitySecret
+// to the pod security context. The security context of the spec is left unchanged.
+func configureRunAsIdentity(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	identity := p.Status.RunAsIdentity
+	if p.Spec.Pravega.RunAsIdentitySecret == "" || identity == nil {
+		return
+	}
+	securityContext := &corev1.PodSecurityContext{}
+	if podSpec.SecurityContext != nil {
+		securityContext = podSpec.SecurityContext.DeepCopy()
+	}
+	runAsUser := identity.RunAsUser
+	securityContext.RunAsUser = &runAsUser
+	if identity.RunAsGroup != nil {
+		runAsGroup := *identity.RunAsGroup
+		securityContext.RunAsGroup = &runAsGroup
+	}
+	podSpec.SecurityContext = securityContext
+}
+
+// configureExtraEnv appends the extra environment variables of the spec to the main
+// container, except for the conflicting ones set by the operator
+func configureExtraEnv(podSpec *corev1.PodSpec, extraEnv []corev1.EnvVar, conflicts []string) {
+	ignored := make(map[string]bool, len(conflicts))
+	for _, name := range conflicts {
+		ignored[name] = true
+	}
+	for _, env := range extraEnv {
+		if ignored[env.Name] {
+			continue
+		}
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, *env.DeepCopy())
+	}
+}
 
+// ConfigMapHash returns the hex encoded SHA-256 hash of the data of the configmap
+func ConfigMapHash(configMap *corev1.ConfigMap) string {
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s\x00%s\x00", key, configMap.Data[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ControllerExtraEnvConflicts returns the sorted names of the extra environment variables
+// of the Controller that are set by the operator, through the configmap or the
+// authentication secrets, and are ignored
+func ControllerExtraEnvConflicts(p *api.PravegaCluster) []string {
+	return extraEnvConflicts(p.Spec.Pravega.ControllerExtraEnv, MakeControllerConfigMap(p).Data, controllerEnv(p))
+}
+
+func extraEnvConflicts(extraEnv []corev1.EnvVar, configMapData map[string]string, env []corev1.EnvVar) []string {
+	managed := map[string]bool{}
+	for key := range configMapData {
+		managed[key] = true
+	}
+	for _, e := range env {
+		managed[e.Name] = true
+	}
+	conflicts := []string{}
+	for _, e := range extraEnv {
+		if managed[e.Name] {
+			conflicts = append(conflicts, e.Name)
+			// Only report a name declared twice once
+			delete(managed, e.Name)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
+	javaOpts := []string{
+		"-Dpravegaservice.clusterName=" + p.Name,
+	}
+
+	jvmOpts := baselineJVMOptions(p, "-Xms512m")
+
+	options := map[string]string{}
 	for name, value := range p.Spec.Pravega.Options {
-		javaOpts = append(javaOpts, fmt.Sprintf("-D%v=%v", name, value))
+		options[name] = value
+	}
+	for name, value := range getMetricsOptions(p.Spec.Pravega) {
+		options[name] = value
+	}
+	for name, value := range getControllerRequestTimeoutOptions(p.Spec.Pravega) {
+		options[name] = value
+	}
+	for name, value := range getContainerCountOptions(p) {
+		options[name] = value
+	}
+	for name, value := range getTLSKeyOptions(p.Spec.TLS.ControllerKeyOptions()) {
+		options[name] = value
 	}
 
+	for name, value := range options {
+		jvmOpts = append(jvmOpts, fmt.Sprintf("-D%v=%v", name, value))
+	}
+	// The JVM options of the spec override the options with the same name, which would
+	// otherwise be set twice and apply depending on their position once sorted
+	javaOpts = append(javaOpts, util.OverrideDefaultJVMOptions(jvmOpts, p.Spec.Pravega.ControllerJvmOptions)...)
+
 	sort.Strings(javaOpts)
 
 	authEnabledStr := fmt.Sprint(p.Spec.Authentication.IsEnabled())
@@ -216,10 +465,13 @@ func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 		"REST_SERVER_PORT":       "10080",
 		"CONTROLLER_SERVER_PORT": "9090",
 		"AUTHORIZATION_ENABLED":  authEnabledStr,
-		"TOKEN_SIGNING_KEY":      defaultTokenSigningKey,
 		"TLS_ENABLED":            "false",
 		"WAIT_FOR":               p.Spec.ZookeeperUri,
 	}
+	// The signing key of the secret is read from the environment of the pods
+	if authEnv(p) == nil {
+		configData[tokenSigningKeyEnv] = defaultTokenSigningKey
+	}
 
 	if p.Spec.Pravega.DebugLogging {
 		configData["log.level"] = "DEBUG"
@@ -239,6 +491,133 @@ func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 	return configMap
 }
 
+// getTLSKeyOptions points the options located by the keys of a TLS secret at the files
+// of the keys, where the secret is mounted
+func getTLSKeyOptions(keyOptions map[string]string) map[string]string {
+	options := map[string]string{}
+	for name, key := range keyOptions {
+		options[name] = path.Join(tlsMountDir, key)
+	}
+	return options
+}
+
+func getMetricsOptions(pravegaSpec *api.PravegaSpec) map[string]string {
+	options := map[string]string{}
+	metrics := pravegaSpec.Metrics
+	if metrics == nil {
+		return options
+	}
+	if metrics.ReportingIntervalSeconds != nil {
+		options["metrics.outputFrequencySeconds"] = fmt.Sprint(*metrics.ReportingIntervalSeconds)
+	}
+	if metrics.Prefix != "" {
+		options["metrics.metricsPrefix"] = metrics.Prefix
+	}
+	return options
+}
+
+// getContainerCountOptions returns the options setting the number of segment containers,
+// which must be the same on the Controller and the Segment Stores, so both get both
+// options. The options were renamed in Pravega 0.7.
+func getContainerCountOptions(p *api.PravegaCluster) map[string]string {
+	options := map[string]string{}
+	count := p.Spec.Pravega.SegmentStoreContainerCount
+	if count == nil {
+		return options
+	}
+	if util.IsVersionBelow07(p.Spec.Version) {
+		options["controller.containerCount"] = fmt.Sprint(*count)
+		options["pravegaservice.containerCount"] = fmt.Sprint(*count)
+	} else {
+		options["controller.container.count"] = fmt.Sprint(*count)
+		options["pravegaservice.container.count"] = fmt.Sprint(*count)
+	}
+	return options
+}
+
+// baselineJVMOptions returns the JVM options of the JVM flavor of the cluster, on
+// top of which the JVM options of the spec are applied. initialHeap is the -Xms
+// option of the component.
+func baselineJVMOptions(p *api.PravegaCluster, initialHeap string) []string {
+	clusterName := "-Dpravegaservice.clusterName=" + p.Name
+	switch p.Spec.Pravega.JVMFlavor {
+	case api.JVMFlavorCustom:
+		return []string{}
+	case api.JVMFlavorTemurin:
+		// Container support is enabled by default since Java 10
+		return []string{
+			initialHeap,
+			"-XX:+UseG1GC",
+			"-XX:+ExitOnOutOfMemoryError",
+			"-XX:+CrashOnOutOfMemoryError",
+			"-XX:+HeapDumpOnOutOfMemoryError",
+			"-XX:HeapDumpPath=" + heapDumpDir,
+			"-XX:MaxRAMPercentage=50.0",
+			clusterName,
+		}
+	case api.JVMFlavorGraalVM:
+		return []string{
+			initialHeap,
+			"-XX:+UnlockExperimentalVMOptions",
+			"-XX:+EnableJVMCI",
+			"-XX:+UseJVMCICompiler",
+			"-XX:+ExitOnOutOfMemoryError",
+			"-XX:+CrashOnOutOfMemoryError",
+			"-XX:+HeapDumpOnOutOfMemoryError",
+			"-XX:HeapDumpPath=" + heapDumpDir,
+			"-XX:MaxRAMPercentage=50.0",
+			clusterName,
+		}
+	case api.JVMFlavorOpenJ9:
+		// OpenJ9 has no -XX:+HeapDumpOnOutOfMemoryError, heap dumps are configured
+		// through -Xdump
+		return []string{
+			initialHeap,
+			"-Xtune:virtualized",
+			"-XX:+ExitOnOutOfMemoryError",
+			"-Xdump:heap:events=systhrow,filter=java/lang/OutOfMemoryError,file=" + heapDumpDir + "/heapdump.%pid.phd",
+			"-XX:+UseContainerSupport",
+			"-XX:MaxRAMPercentage=50.0",
+			clusterName,
+		}
+	}
+
+	jvmOpts := []string{
+		initialHeap,
+		"-XX:+ExitOnOutOfMemoryError",
+		"-XX:+CrashOnOutOfMemoryError",
+		"-XX:+HeapDumpOnOutOfMemoryError",
+		"-XX:HeapDumpPath=" + heapDumpDir,
+		clusterName,
+	}
+
+	if match, _ := util.CompareVersions(p.Spec.Version, "0.4.0", ">="); match {
+		// Pravega < 0.4 uses a Java version that does not support the options below
+		jvmOpts = append(jvmOpts,
+			"-XX:+UnlockExperimentalVMOptions",
+			"-XX:+UseContainerSupport",
+			"-XX:MaxRAMPercentage=50.0",
+		)
+	}
+	return jvmOpts
+}
+
+func getControllerRequestTimeoutOptions(pravegaSpec *api.PravegaSpec) map[string]string {
+	options := map[string]string{}
+	timeouts := pravegaSpec.ControllerRequestTimeouts
+	if timeouts == nil {
+		return options
+	}
+	if timeouts.RequestTimeoutSeconds != nil {
+		options["controller.request.timeout.seconds"] = fmt.Sprint(*timeouts.RequestTimeoutSeconds)
+	}
+	if timeouts.TransactionMaxLeaseSeconds != nil {
+		// The Controller expects the maximum lease in milliseconds
+		options["controller.transaction.lease.count.max"] = fmt.Sprint(int64(*timeouts.TransactionMaxLeaseSeconds) * 1000)
+	}
+	return options
+}
+
 func getControllerServiceType(pravegaCluster *api.PravegaCluster) (serviceType corev1.ServiceType) {
 	if pravegaCluster.Spec.Pravega.ControllerExternalServiceType == "" {
 		if pravegaCluster.Spec.ExternalAccess.Type == "" {
@@ -257,9 +636,10 @@ func MakeControllerService(p *api.PravegaCluster) *corev1.Service {
 		for k, v := range p.Spec.Pravega.ControllerServiceAnnotations {
 			annotationMap[k] = v
 		}
+		annotationMap = addLoadBalancerTagsAnnotation(annotationMap, p, serviceType)
 	}
 
-	return &corev1.Service{
+	service := &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
 			APIVersion: "v1",
@@ -285,10 +665,17 @@ func MakeControllerService(p *api.PravegaCluster) *corev1.Service {
 			Selector: p.LabelsForController(),
 		},
 	}
+	if serviceType == corev1.ServiceTypeLoadBalancer || serviceType == corev1.ServiceTypeNodePort {
+		service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+		if p.Spec.ExternalAccess.ExternalTrafficPolicy != "" {
+			service.Spec.ExternalTrafficPolicy = p.Spec.ExternalAccess.ExternalTrafficPolicy
+		}
+	}
+	return service
 }
 
 func MakeControllerPodDisruptionBudget(p *api.PravegaCluster) *policyv1beta1.PodDisruptionBudget {
-	minAvailable := intstr.FromInt(1)
+	minAvailable := intstr.FromInt(ControllerMinAvailable)
 	return &policyv1beta1.PodDisruptionBudget{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PodDisruptionBudget",
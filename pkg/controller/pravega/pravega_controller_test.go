@@ -12,14 +12,17 @@ package pravega_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -127,11 +130,150 @@ var _ = Describe("Controller", func() {
 					Ω(cm.Data["log.level"]).Should(Equal("DEBUG"))
 				})
 
+				It("should add the metrics settings to the config-map", func() {
+					interval := int32(10)
+					p.Spec.Pravega.Options["metrics.metricsPrefix"] = "raw"
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+						ReportingIntervalSeconds: &interval,
+						Prefix:                   "pravega_prod",
+					}
+					cm := pravega.MakeControllerConfigMap(p)
+					Ω(cm.Data["JAVA_OPTS"]).To(ContainSubstring("-Dmetrics.outputFrequencySeconds=10"))
+					Ω(cm.Data["JAVA_OPTS"]).To(ContainSubstring("-Dmetrics.metricsPrefix=pravega_prod"))
+					Ω(cm.Data["JAVA_OPTS"]).NotTo(ContainSubstring("-Dmetrics.metricsPrefix=raw"))
+				})
+
+				It("should add the request timeout settings to the config-map", func() {
+					timeout := int32(120)
+					lease := int32(300)
+					p.Spec.Pravega.Options["controller.request.timeout.seconds"] = "30"
+					p.Spec.Pravega.ControllerRequestTimeouts = &v1beta1.ControllerRequestTimeoutsSpec{
+						RequestTimeoutSeconds:      &timeout,
+						TransactionMaxLeaseSeconds: &lease,
+					}
+					cm := pravega.MakeControllerConfigMap(p)
+					Ω(cm.Data["JAVA_OPTS"]).To(ContainSubstring("-Dcontroller.request.timeout.seconds=120"))
+					Ω(cm.Data["JAVA_OPTS"]).To(ContainSubstring("-Dcontroller.transaction.lease.count.max=300000"))
+					Ω(cm.Data["JAVA_OPTS"]).NotTo(ContainSubstring("-Dcontroller.request.timeout.seconds=30"))
+				})
+
+				It("should read the token signing key from the controller token secret", func() {
+					Ω(pravega.MakeControllerConfigMap(p).Data["TOKEN_SIGNING_KEY"]).To(Equal("secret"))
+					p.Spec.Authentication.ControllerTokenSecret = &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+						Key:                  "signing-key",
+					}
+					Ω(pravega.MakeControllerConfigMap(p).Data).NotTo(HaveKey("TOKEN_SIGNING_KEY"))
+					env := pravega.MakeControllerPodTemplate(p).Spec.Containers[0].Env
+					Ω(env).To(HaveLen(2))
+					Ω(env[0].Name).To(Equal("TOKEN_SIGNING_KEY"))
+					Ω(env[0].ValueFrom.SecretKeyRef.Name).To(Equal("token-secret"))
+					Ω(env[0].ValueFrom.SecretKeyRef.Key).To(Equal("signing-key"))
+					Ω(env[1].Name).To(Equal("JAVA_OPTS"))
+					Ω(env[1].Value).To(Equal("$(JAVA_OPTS) -Dcontroller.security.auth.delegationToken.signingKey.basis=$(TOKEN_SIGNING_KEY)"))
+				})
+
+				It("should locate the files of the tls secret keys in the options", func() {
+					Ω(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"]).NotTo(ContainSubstring("certificate.location"))
+					p.Spec.TLS.Static.ControllerKeys = &v1beta1.TLSSecretKeys{
+						Certificate:   "tls.crt",
+						PrivateKey:    "tls.key",
+						CaCertificate: "ca.crt",
+					}
+					javaOpts := pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).To(ContainSubstring("-Dcontroller.security.tls.server.certificate.location=/etc/secret-volume/tls.crt"))
+					Ω(javaOpts).To(ContainSubstring("-Dcontroller.security.tls.server.privateKey.location=/etc/secret-volume/tls.key"))
+					Ω(javaOpts).To(ContainSubstring("-Dcontroller.security.tls.trustStore.location=/etc/secret-volume/ca.crt"))
+					mountPaths := []string{}
+					for _, mount := range pravega.MakeControllerPodTemplate(p).Spec.Containers[0].VolumeMounts {
+						mountPaths = append(mountPaths, mount.MountPath)
+					}
+					Ω(mountPaths).To(ContainElement("/etc/secret-volume"))
+				})
+
+				It("should set the segment container count on the controller", func() {
+					count := int32(8)
+					p.Spec.Pravega.SegmentStoreContainerCount = &count
+					javaOpts := strings.Fields(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"])
+					Ω(javaOpts).To(ContainElement("-Dcontroller.containerCount=8"))
+					Ω(javaOpts).To(ContainElement("-Dpravegaservice.containerCount=8"))
+					// the options were renamed in 0.7
+					p.Spec.Version = "0.7.0"
+					javaOpts = strings.Fields(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"])
+					Ω(javaOpts).To(ContainElement("-Dcontroller.container.count=8"))
+					Ω(javaOpts).To(ContainElement("-Dpravegaservice.container.count=8"))
+				})
+
+				It("should default to the hotspot JVM flavor", func() {
+					Ω(p.Spec.Pravega.JVMFlavor).Should(Equal(v1beta1.JVMFlavorHotSpot))
+				})
+
+				It("should add the baseline JVM options of each flavor to the config-map", func() {
+					heapDump := "-XX:HeapDumpPath=/tmp/dumpfile/heap"
+					baselines := map[string][]string{
+						v1beta1.JVMFlavorHotSpot: {"-Xms512m", "-XX:+ExitOnOutOfMemoryError", "-XX:+CrashOnOutOfMemoryError",
+							"-XX:+HeapDumpOnOutOfMemoryError", heapDump, "-XX:+UnlockExperimentalVMOptions", "-XX:+UseContainerSupport"},
+						v1beta1.JVMFlavorTemurin: {"-Xms512m", "-XX:+UseG1GC", "-XX:+ExitOnOutOfMemoryError", "-XX:+CrashOnOutOfMemoryError",
+							"-XX:+HeapDumpOnOutOfMemoryError", heapDump},
+						v1beta1.JVMFlavorGraalVM: {"-Xms512m", "-XX:+UnlockExperimentalVMOptions", "-XX:+EnableJVMCI", "-XX:+UseJVMCICompiler",
+							"-XX:+ExitOnOutOfMemoryError", "-XX:+CrashOnOutOfMemoryError", "-XX:+HeapDumpOnOutOfMemoryError", heapDump},
+						v1beta1.JVMFlavorOpenJ9: {"-Xms512m", "-Xtune:virtualized", "-XX:+ExitOnOutOfMemoryError", "-XX:+UseContainerSupport",
+							"-Xdump:heap:events=systhrow,filter=java/lang/OutOfMemoryError,file=/tmp/dumpfile/heap/heapdump.%pid.phd"},
+					}
+					for flavor, baseline := range baselines {
+						p.Spec.Pravega.JVMFlavor = flavor
+						javaOpts := strings.Fields(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"])
+						for _, option := range baseline {
+							Ω(javaOpts).To(ContainElement(option), "flavor %s", flavor)
+						}
+						// the options of the spec are applied on top of the baseline
+						Ω(javaOpts).To(ContainElement("-XX:MaxDirectMemorySize=1g"), "flavor %s", flavor)
+						Ω(javaOpts).To(ContainElement("-XX:MaxRAMPercentage=50.0"), "flavor %s", flavor)
+					}
+					p.Spec.Pravega.JVMFlavor = v1beta1.JVMFlavorOpenJ9
+					Ω(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"]).NotTo(ContainSubstring("HeapDumpOnOutOfMemoryError"))
+				})
+
+				It("should let the JVM options of the spec override the other options", func() {
+					p.Spec.Pravega.Options["controller.retention.frequency.minutes"] = "30"
+					p.Spec.Pravega.ControllerJvmOptions = append(p.Spec.Pravega.ControllerJvmOptions,
+						"-Dcontroller.retention.frequency.minutes=10", "-Xmx1g", "-Xmx512m", "-Xms1g")
+					javaOpts := strings.Fields(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"])
+					Ω(javaOpts).To(ContainElement("-Dcontroller.retention.frequency.minutes=10"))
+					Ω(javaOpts).NotTo(ContainElement("-Dcontroller.retention.frequency.minutes=30"))
+					Ω(javaOpts).To(ContainElement("-Xmx512m"))
+					Ω(javaOpts).NotTo(ContainElement("-Xmx1g"))
+					Ω(javaOpts).To(ContainElement("-Xms1g"))
+					Ω(javaOpts).NotTo(ContainElement("-Xms512m"))
+				})
+
+				It("should only pass the JVM options of the spec with the custom flavor", func() {
+					p.Spec.Pravega.JVMFlavor = v1beta1.JVMFlavorCustom
+					javaOpts := strings.Fields(pravega.MakeControllerConfigMap(p).Data["JAVA_OPTS"])
+					Ω(javaOpts).To(ContainElement("-XX:MaxDirectMemorySize=1g"))
+					Ω(javaOpts).NotTo(ContainElement("-Xms512m"))
+					Ω(javaOpts).NotTo(ContainElement("-XX:+ExitOnOutOfMemoryError"))
+					Ω(javaOpts).To(ContainElement("-Dpravegaservice.clusterName=default"))
+				})
+
 				It("should create the deployment", func() {
 					deploy := pravega.MakeControllerDeployment(p)
 					Ω(*deploy.Spec.Replicas).Should(Equal(int32(2)))
 				})
 
+				It("should roll the deployment without taking a ready controller down", func() {
+					deploy := pravega.MakeControllerDeployment(p)
+					Ω(deploy.Spec.Strategy.Type).Should(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+					Ω(deploy.Spec.Strategy.RollingUpdate).ShouldNot(BeNil())
+					Ω(*deploy.Spec.Strategy.RollingUpdate.MaxUnavailable).Should(Equal(intstr.FromInt(0)))
+					Ω(*deploy.Spec.Strategy.RollingUpdate.MaxSurge).Should(Equal(intstr.FromInt(1)))
+				})
+
+				It("should keep as many controllers available as the pod disruption budget", func() {
+					pdb := pravega.MakeControllerPodDisruptionBudget(p)
+					Ω(*pdb.Spec.MinAvailable).Should(Equal(intstr.FromInt(pravega.ControllerMinAvailable)))
+				})
+
 				It("should create the service", func() {
 					svc := pravega.MakeControllerService(p)
 					Ω(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
@@ -160,6 +302,219 @@ var _ = Describe("Controller", func() {
 					Ω(svc.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
 				})
 			})
+			Context("Controller with init wait url", func() {
+				It("should not add an init container by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.InitContainers).To(BeEmpty())
+				})
+				It("should add an init container waiting on the url", func() {
+					p.Spec.Pravega.InitWaitURL = "http://metadata.example.com:8080/health"
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.InitContainers).To(HaveLen(1))
+					initContainer := podTemplate.Spec.InitContainers[0]
+					Ω(initContainer.Name).To(Equal("wait-for-dependency"))
+					Ω(initContainer.Image).To(Equal(p.ControllerImage()))
+					Ω(initContainer.ImagePullPolicy).To(Equal(p.ControllerImagePullPolicy()))
+					Ω(initContainer.Env).To(ContainElement(corev1.EnvVar{Name: "WAIT_URL", Value: "http://metadata.example.com:8080/health"}))
+					Ω(initContainer.Command[2]).To(ContainSubstring("until curl"))
+				})
+			})
+
+			Context("Controller probes", func() {
+				It("should use the default probe timings", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					readiness := podTemplate.Spec.Containers[0].ReadinessProbe
+					Ω(readiness.InitialDelaySeconds).To(Equal(int32(20)))
+					Ω(readiness.TimeoutSeconds).To(Equal(int32(60)))
+					Ω(readiness.SuccessThreshold).To(Equal(int32(3)))
+					liveness := podTemplate.Spec.Containers[0].LivenessProbe
+					Ω(liveness.InitialDelaySeconds).To(Equal(int32(60)))
+					Ω(liveness.PeriodSeconds).To(Equal(int32(15)))
+					Ω(liveness.FailureThreshold).To(Equal(int32(4)))
+				})
+				It("should use the configured probe timings", func() {
+					p.Spec.Pravega.ControllerProbes.ReadinessProbe = &v1beta1.ProbeSpec{
+						InitialDelaySeconds: 90,
+						PeriodSeconds:       20,
+						FailureThreshold:    6,
+						TimeoutSeconds:      30,
+					}
+					deployment := pravega.MakeControllerDeployment(p)
+					readiness := deployment.Spec.Template.Spec.Containers[0].ReadinessProbe
+					Ω(readiness.InitialDelaySeconds).To(Equal(int32(90)))
+					Ω(readiness.PeriodSeconds).To(Equal(int32(20)))
+					Ω(readiness.FailureThreshold).To(Equal(int32(6)))
+					Ω(readiness.TimeoutSeconds).To(Equal(int32(30)))
+				})
+			})
+
+			Context("Controller with run as identity", func() {
+				It("should not apply the recorded identity without the secret", func() {
+					p.Status.RunAsIdentity = &v1beta1.RunAsIdentity{RunAsUser: 1000}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(*podTemplate.Spec.SecurityContext.RunAsUser).To(Equal(int64(0)))
+				})
+				It("should propagate the user and group IDs to the pod", func() {
+					group := int64(2000)
+					p.Spec.Pravega.RunAsIdentitySecret = "pravega-identity"
+					p.Status.RunAsIdentity = &v1beta1.RunAsIdentity{RunAsUser: 1000, RunAsGroup: &group}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(*podTemplate.Spec.SecurityContext.RunAsUser).To(Equal(int64(1000)))
+					Ω(*podTemplate.Spec.SecurityContext.RunAsGroup).To(Equal(int64(2000)))
+					Ω(*p.Spec.Pravega.ControllerSecurityContext.RunAsUser).To(Equal(int64(0)))
+				})
+			})
+
+			Context("Controller with security contexts", func() {
+				It("should propagate the fsGroup to the pod template", func() {
+					fsGroup := int64(1000)
+					p.Spec.Pravega.ControllerSecurityContext = &corev1.PodSecurityContext{FSGroup: &fsGroup}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(*podTemplate.Spec.SecurityContext.FSGroup).To(Equal(int64(1000)))
+				})
+				It("should apply the container security context to the controller container", func() {
+					readOnly := true
+					p.Spec.Pravega.ControllerContainerSecurityContext = &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnly}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(*podTemplate.Spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem).To(Equal(true))
+				})
+			})
+
+			Context("Controller with TLS secret hash", func() {
+				It("should not add the annotation by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Annotations).NotTo(HaveKey(pravega.TLSSecretHashAnnotationKey))
+				})
+				It("should stamp the recorded hash on the pod template", func() {
+					p.Status.TLSSecretHashes = &v1beta1.TLSSecretHashes{Controller: "abc", SegmentStore: "def"}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Annotations[pravega.TLSSecretHashAnnotationKey]).To(Equal("abc"))
+				})
+			})
+
+			Context("Controller with extra environment variables", func() {
+				It("should not set environment variables by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.Containers[0].Env).To(BeEmpty())
+					Ω(pravega.ControllerExtraEnvConflicts(p)).To(BeEmpty())
+				})
+				It("should append the extra environment except the operator-managed variables", func() {
+					p.Spec.Pravega.ControllerExtraEnv = []corev1.EnvVar{
+						{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+						{Name: "JAVA_OPTS", Value: "-Xmx1g"},
+						{Name: "NO_PROXY", Value: ".svc,.cluster.local"},
+					}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+						{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+						{Name: "NO_PROXY", Value: ".svc,.cluster.local"},
+					}))
+					Ω(pravega.ControllerExtraEnvConflicts(p)).To(Equal([]string{"JAVA_OPTS"}))
+				})
+			})
+
+			Context("Controller with restart annotation", func() {
+				It("should not add the annotation by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Annotations).NotTo(HaveKey(pravega.RestartAnnotationKey))
+				})
+				It("should stamp the value of the cluster annotation on the pod template", func() {
+					p.Annotations = map[string]string{v1beta1.RestartAnnotation: "1"}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Annotations[pravega.RestartAnnotationKey]).To(Equal("1"))
+				})
+			})
+
+			Context("Controller with logging sidecar", func() {
+				It("should not add a sidecar by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.Containers).To(HaveLen(1))
+				})
+				It("should share the log volume with the sidecar", func() {
+					p.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{
+						Image:       "fluent/fluent-bit:1.5",
+						Destination: "logs.example.com:24224",
+					}
+					p.WithDefaults()
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.Containers).To(HaveLen(2))
+					Ω(podTemplate.Spec.Containers[0].Name).To(Equal("pravega-controller"))
+					Ω(podTemplate.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "logs", MountPath: "/opt/pravega/logs"}))
+					sidecar := podTemplate.Spec.Containers[1]
+					Ω(sidecar.Name).To(Equal("logging-sidecar"))
+					Ω(sidecar.Image).To(Equal("fluent/fluent-bit:1.5"))
+					Ω(sidecar.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "logs", MountPath: "/opt/pravega/logs", ReadOnly: true}))
+					Ω(sidecar.Env).To(ContainElement(corev1.EnvVar{Name: "LOG_DESTINATION", Value: "logs.example.com:24224"}))
+					Ω(podTemplate.Spec.Volumes).To(ContainElement(corev1.Volume{
+						Name:         "logs",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}))
+				})
+			})
+
+			Context("Controller with node selector", func() {
+				It("should not set a node selector by default", func() {
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.NodeSelector).To(BeNil())
+				})
+				It("should set the node selector on the pod template", func() {
+					p.Spec.Pravega.ControllerPodNodeSelector = map[string]string{"disktype": "ssd"}
+					podTemplate := pravega.MakeControllerPodTemplate(p)
+					Ω(podTemplate.Spec.NodeSelector).To(Equal(map[string]string{"disktype": "ssd"}))
+				})
+			})
+
+			Context("Controller with pod affinity", func() {
+				var zone *corev1.NodeAffinity
+				BeforeEach(func() {
+					zone = &corev1.NodeAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+							Weight: 10,
+							Preference: corev1.NodeSelectorTerm{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      "topology.kubernetes.io/zone",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"us-east-1a"},
+								}},
+							},
+						}},
+					}
+				})
+				It("should spread the controllers across nodes by default", func() {
+					affinity := pravega.MakeControllerPodTemplate(p).Spec.Affinity
+					Ω(affinity.NodeAffinity).To(BeNil())
+					Ω(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+				})
+				It("should keep the operator anti-affinity along with the user node affinity", func() {
+					p.Spec.Pravega.ControllerPodAffinity = &corev1.Affinity{NodeAffinity: zone}
+					affinity := pravega.MakeControllerPodTemplate(p).Spec.Affinity
+					Ω(affinity.NodeAffinity).To(Equal(zone))
+					preferred := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+					Ω(preferred).To(HaveLen(1))
+					Ω(preferred[0].PodAffinityTerm.LabelSelector.MatchExpressions).To(ContainElement(metav1.LabelSelectorRequirement{
+						Key:      "component",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"pravega-controller"},
+					}))
+				})
+			})
+
+			Context("Controller with a command override", func() {
+				It("should launch the controller by default", func() {
+					container := pravega.MakeControllerPodTemplate(p).Spec.Containers[0]
+					Ω(container.Command).To(BeEmpty())
+					Ω(container.Args).To(Equal([]string{"controller"}))
+					Ω(container.LivenessProbe).ToNot(BeNil())
+				})
+				It("should replace the command and the args", func() {
+					p.Spec.Pravega.ControllerCommand = []string{"sleep"}
+					p.Spec.Pravega.ControllerArgs = []string{"infinity"}
+					container := pravega.MakeControllerPodTemplate(p).Spec.Containers[0]
+					Ω(container.Command).To(Equal([]string{"sleep"}))
+					Ω(container.Args).To(Equal([]string{"infinity"}))
+					Ω(container.LivenessProbe).To(BeNil())
+				})
+			})
 		})
 
 		Context("Controller Svc Type Load Balancer", func() {
@@ -247,6 +602,66 @@ var _ = Describe("Controller", func() {
 					svc := pravega.MakeControllerService(p)
 					Ω(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
 				})
+
+				It("should leave the DNS policy to the cluster default", func() {
+					deploy := pravega.MakeControllerDeployment(p)
+					Ω(deploy.Spec.Template.Spec.DNSPolicy).To(BeEmpty())
+					Ω(deploy.Spec.Template.Spec.DNSConfig).To(BeNil())
+				})
+
+				It("should set the DNS policy and config of the pods", func() {
+					p.Spec.Pravega.ControllerDnsPolicy = corev1.DNSNone
+					p.Spec.Pravega.ControllerDnsConfig = &corev1.PodDNSConfig{
+						Nameservers: []string{"10.0.0.10"},
+						Searches:    []string{"storage.example.com"},
+					}
+					deploy := pravega.MakeControllerDeployment(p)
+					Ω(deploy.Spec.Template.Spec.DNSPolicy).To(Equal(corev1.DNSNone))
+					Ω(deploy.Spec.Template.Spec.DNSConfig.Nameservers).To(Equal([]string{"10.0.0.10"}))
+				})
+
+				It("should change the configmap hash of the pods with the zookeeper uri", func() {
+					before := pravega.MakeControllerPodTemplate(p).Annotations[pravega.ConfigMapHashAnnotationKey]
+					p.Spec.ZookeeperUri = "zookeeper-2-client:2181"
+					after := pravega.MakeControllerPodTemplate(p).Annotations[pravega.ConfigMapHashAnnotationKey]
+					Ω(before).NotTo(BeEmpty())
+					Ω(after).NotTo(Equal(before))
+					Ω(after).To(Equal(pravega.ConfigMapHash(pravega.MakeControllerConfigMap(p))))
+				})
+
+				It("should leave the token automounting to the service account by default", func() {
+					deploy := pravega.MakeControllerDeployment(p)
+					Ω(deploy.Spec.Template.Spec.AutomountServiceAccountToken).To(BeNil())
+				})
+
+				It("should not mount the service account token when disabled", func() {
+					automount := false
+					p.Spec.Pravega.ControllerAutomountServiceAccountToken = &automount
+					deploy := pravega.MakeControllerDeployment(p)
+					Ω(*deploy.Spec.Template.Spec.AutomountServiceAccountToken).To(BeFalse())
+				})
+
+				It("should translate the load balancer tags into the tags annotation", func() {
+					p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+						"team":        "streaming",
+						"cost-center": "1234",
+					}
+					svc := pravega.MakeControllerService(p)
+					Ω(svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"]).To(Equal("cost-center=1234,team=streaming"))
+					Ω(svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-type"]).To(Equal("nlb"))
+					Ω(p.Spec.Pravega.ControllerServiceAnnotations).NotTo(HaveKey("service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"))
+				})
+
+				It("should use the Cluster external traffic policy by default", func() {
+					svc := pravega.MakeControllerService(p)
+					Ω(svc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeCluster))
+				})
+
+				It("should use the configured external traffic policy", func() {
+					p.Spec.ExternalAccess.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+					svc := pravega.MakeControllerService(p)
+					Ω(svc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeLocal))
+				})
 			})
 		})
 	})
@@ -0,0 +1,95 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	"encoding/json"
+	"fmt"
+
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// dashboardPanel describes a time series panel of the dashboard, plotting the
+// per second rate of a Pravega counter. The metric name excludes the metrics prefix.
+type dashboardPanel struct {
+	title  string
+	metric string
+	unit   string
+}
+
+var dashboardPanels = []dashboardPanel{
+	{title: "Segment Store Write Throughput", metric: "segmentstore_segment_write_bytes_total", unit: "Bps"},
+	{title: "Segment Store Read Throughput", metric: "segmentstore_segment_read_bytes_total", unit: "Bps"},
+	{title: "Segment Store Write Events", metric: "segmentstore_segment_write_events_total", unit: "ops"},
+	{title: "Controller Streams Created", metric: "controller_stream_created_total", unit: "ops"},
+}
+
+// MakeGrafanaDashboardConfigMap returns the ConfigMap holding the Grafana dashboard
+// of the cluster. It carries the label the Grafana sidecar looks for, so that the
+// dashboard is imported automatically.
+func MakeGrafanaDashboardConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
+	labels := p.LabelsForPravegaCluster()
+	labels[grafanaDashboardLabelKey] = grafanaDashboardLabelValue
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.ConfigMapNameForGrafanaDashboard(),
+			Labels:    labels,
+			Namespace: p.Namespace,
+		},
+		Data: map[string]string{
+			fmt.Sprintf("pravega-%s.json", p.Name): makeGrafanaDashboard(p),
+		},
+	}
+}
+
+func makeGrafanaDashboard(p *api.PravegaCluster) string {
+	panels := []map[string]interface{}{}
+	for i, panel := range dashboardPanels {
+		panels = append(panels, map[string]interface{}{
+			"id":    i + 1,
+			"type":  "timeseries",
+			"title": panel.title,
+			"gridPos": map[string]int{
+				"h": 8,
+				"w": 12,
+				"x": (i % 2) * 12,
+				"y": (i / 2) * 8,
+			},
+			"fieldConfig": map[string]interface{}{
+				"defaults": map[string]string{"unit": panel.unit},
+			},
+			"targets": []map[string]string{
+				{
+					"refId":        "A",
+					"expr":         fmt.Sprintf("sum(rate(%s{namespace=%q}[5m]))", p.MetricName(panel.metric), p.Namespace),
+					"legendFormat": panel.title,
+				},
+			},
+		})
+	}
+	dashboard := map[string]interface{}{
+		"title":         fmt.Sprintf("Pravega %s/%s", p.Namespace, p.Name),
+		"tags":          []string{"pravega"},
+		"schemaVersion": 27,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-1h", "to": "now"},
+		"panels":        panels,
+	}
+	// Marshalling maps of strings, ints and slices cannot fail
+	data, _ := json.MarshalIndent(dashboard, "", "  ")
+	return string(data)
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RedactedValue replaces the value of the sensitive options in the rendered configuration
+const RedactedValue = "<redacted>"
+
+// sensitiveOptionWords are the words which, found in the name of an option, mark its
+// value as sensitive
+var sensitiveOptionWords = []string{"password", "passwd", "secret", "token", "credential", "accesskey"}
+
+// MakeRenderedConfigMap returns the ConfigMap holding the configuration the operator
+// hands to the Controller and the Segment Store, once the defaults, the options and the
+// JVM options of the spec are merged. For each component, it holds the Pravega options,
+// the other JVM options and the environment variables read from secrets, which are
+// referenced by secret name and key. The values of sensitive options are redacted.
+func MakeRenderedConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
+	data := map[string]string{}
+	components := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+		template  corev1.PodTemplateSpec
+	}{
+		{"controller", MakeControllerConfigMap(p), MakeControllerPodTemplate(p)},
+		{"segmentstore", MakeSegmentstoreConfigMap(p), MakeSegmentStorePodTemplate(p)},
+	}
+	for _, component := range components {
+		options, jvmOptions := renderJavaOpts(component.configMap.Data[javaOptsEnv])
+		data[component.name+".options"] = strings.Join(options, "\n")
+		data[component.name+".jvmOptions"] = strings.Join(jvmOptions, "\n")
+		data[component.name+".secrets"] = strings.Join(secretReferences(component.template.Spec.Containers[0]), "\n")
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.ConfigMapNameForRendered(),
+			Labels:    p.LabelsForPravegaCluster(),
+			Namespace: p.Namespace,
+		},
+		Data: data,
+	}
+}
+
+// renderJavaOpts splits the JAVA_OPTS into the sorted name=value lines of the Pravega
+// options, set with -D, and the other JVM options
+func renderJavaOpts(javaOpts string) (options []string, jvmOptions []string) {
+	for _, option := range strings.Fields(javaOpts) {
+		if !strings.HasPrefix(option, "-D") {
+			jvmOptions = append(jvmOptions, option)
+			continue
+		}
+		name, value := strings.TrimPrefix(option, "-D"), ""
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		if isSensitiveOption(name) {
+			value = RedactedValue
+		}
+		options = append(options, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(options)
+	return options, jvmOptions
+}
+
+// isSensitiveOption returns true if the name of the option suggests its value is a secret
+func isSensitiveOption(name string) bool {
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveOptionWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretReferences returns the environment variables of the container read from secrets,
+// as NAME=secret:<secret>/<key> lines, and the secrets whose keys are all read into the
+// environment, as *=secret:<secret> lines
+func secretReferences(container corev1.Container) []string {
+	references := []string{}
+	for _, env := range container.Env {
+		if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+		ref := env.ValueFrom.SecretKeyRef
+		references = append(references, fmt.Sprintf("%s=secret:%s/%s", env.Name, ref.Name, ref.Key))
+	}
+	for _, source := range container.EnvFrom {
+		if source.SecretRef == nil {
+			continue
+		}
+		references = append(references, fmt.Sprintf("%s*=secret:%s", source.Prefix, source.SecretRef.Name))
+	}
+	sort.Strings(references)
+	return references
+}
@@ -20,6 +20,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -44,6 +45,8 @@ func MakeSegmentStoreStatefulSet(p *api.PravegaCluster) *appsv1.StatefulSet {
 			ServiceName:         "pravega-segmentstore",
 			Replicas:            &p.Spec.Pravega.SegmentStoreReplicas,
 			PodManagementPolicy: appsv1.OrderedReadyPodManagement,
+			// The operator rolls the pods itself, unless the segmentStoreUpdateStrategy
+			// leaves their deletion to the user
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
 				Type: appsv1.OnDeleteStatefulSetStrategyType,
 			},
@@ -56,14 +59,27 @@ func MakeSegmentStoreStatefulSet(p *api.PravegaCluster) *appsv1.StatefulSet {
 	if util.IsVersionBelow07(p.Spec.Version) {
 		statefulSet.Spec.VolumeClaimTemplates = makeCacheVolumeClaimTemplate(p)
 	}
+	if p.Spec.Pravega.SegmentStoreJournalVolume != nil {
+		statefulSet.Spec.VolumeClaimTemplates = append(statefulSet.Spec.VolumeClaimTemplates, makeJournalVolumeClaimTemplate(p))
+	}
 	return statefulSet
 }
 
 func MakeSegmentStorePodTemplate(p *api.PravegaCluster) corev1.PodTemplateSpec {
+	annotations := map[string]string{
+		"pravega.version":          p.Spec.Version,
+		ConfigMapHashAnnotationKey: ConfigMapHash(MakeSegmentstoreConfigMap(p)),
+	}
+	if hashes := p.Status.TLSSecretHashes; hashes != nil && hashes.SegmentStore != "" {
+		annotations[TLSSecretHashAnnotationKey] = hashes.SegmentStore
+	}
+	if restart := p.Annotations[api.RestartAnnotation]; restart != "" {
+		annotations[RestartAnnotationKey] = restart
+	}
 	return corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      p.LabelsForSegmentStore(),
-			Annotations: map[string]string{"pravega.version": p.Spec.Version},
+			Annotations: annotations,
 		},
 		Spec: makeSegmentstorePodSpec(p),
 	}
@@ -106,8 +122,9 @@ func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
 		Containers: []corev1.Container{
 			{
 				Name:            "pravega-segmentstore",
-				Image:           p.PravegaImage(),
-				ImagePullPolicy: p.Spec.Pravega.Image.PullPolicy,
+				Image:           p.SegmentStoreImage(),
+				ImagePullPolicy: p.SegmentStoreImagePullPolicy(),
+				SecurityContext: p.Spec.Pravega.SegmentStoreContainerSecurityContext,
 				Args: []string{
 					"segmentstore",
 				},
@@ -118,9 +135,9 @@ func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
 					},
 				},
 				EnvFrom:      environment,
-				Env:          util.DownwardAPIEnv(),
+				Env:          segmentStoreEnv(p),
 				VolumeMounts: MakeSegmentStoreVolumeMount(p),
-				Resources:    *p.Spec.Pravega.SegmentStoreResources,
+				Resources:    *p.SegmentStoreResourceRequirements(),
 				ReadinessProbe: &corev1.Probe{
 					Handler: corev1.Handler{
 						Exec: &corev1.ExecAction{
@@ -151,7 +168,14 @@ func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
 				},
 			},
 		},
-		Affinity: p.Spec.Pravega.SegmentStorePodAffinity,
+		Affinity:                      util.MergeAffinity(util.PodAntiAffinity("pravega-segmentstore", p.GetName()), p.Spec.Pravega.SegmentStorePodAffinity),
+		TopologySpreadConstraints:     p.Spec.Pravega.SegmentStoreTopologySpreadConstraints,
+		NodeSelector:                  p.Spec.Pravega.SegmentStorePodNodeSelector,
+		HostNetwork:                   p.Spec.Pravega.SegmentStoreHostNetwork,
+		DNSPolicy:                     segmentStoreDnsPolicy(p),
+		DNSConfig:                     p.Spec.Pravega.SegmentStoreDnsConfig,
+		TerminationGracePeriodSeconds: p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds,
+		AutomountServiceAccountToken:  p.Spec.Pravega.SegmentStoreAutomountServiceAccountToken,
 		Volumes: []corev1.Volume{
 			{
 				Name: heapDumpName,
@@ -178,9 +202,42 @@ func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
 
 	configureLTSFilesystem(&podSpec, p.Spec.Pravega)
 
+	configureInitWait(&podSpec, p)
+
+	configureSegmentStoreInitContainers(&podSpec, p)
+
+	configureSegmentStoreVolumes(&podSpec, p)
+
+	configureLoggingSidecar(&podSpec, p)
+
+	configureRunAsIdentity(&podSpec, p)
+
+	configureExtraEnv(&podSpec, p.Spec.Pravega.SegmentStoreExtraEnv, SegmentStoreExtraEnvConflicts(p))
+
+	configureCommandOverride(&podSpec.Containers[0], p.Spec.Pravega.SegmentStoreCommand, p.Spec.Pravega.SegmentStoreArgs)
+
 	return podSpec
 }
 
+// configureSegmentStoreInitContainers appends the user provided init containers, which
+// run after the operator ones and before the segment store container
+func configureSegmentStoreInitContainers(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	for _, container := range p.Spec.Pravega.SegmentStoreInitContainers {
+		podSpec.InitContainers = append(podSpec.InitContainers, *container.DeepCopy())
+	}
+}
+
+// configureSegmentStoreVolumes appends the user provided volumes to the pod and their
+// mounts to the segment store container
+func configureSegmentStoreVolumes(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	for _, volume := range p.Spec.Pravega.SegmentStoreVolumes {
+		podSpec.Volumes = append(podSpec.Volumes, *volume.DeepCopy())
+	}
+	for _, mount := range p.Spec.Pravega.SegmentStoreVolumeMounts {
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, *mount.DeepCopy())
+	}
+}
+
 func MakeSegmentStoreVolumeMount(p *api.PravegaCluster) []corev1.VolumeMount {
 	volumeMount := []corev1.VolumeMount{
 		{
@@ -194,6 +251,12 @@ func MakeSegmentStoreVolumeMount(p *api.PravegaCluster) []corev1.VolumeMount {
 			MountPath: cacheVolumeMountPoint,
 		})
 	}
+	if p.Spec.Pravega.SegmentStoreJournalVolume != nil {
+		volumeMount = append(volumeMount, corev1.VolumeMount{
+			Name:      journalVolumeName,
+			MountPath: journalMountPoint,
+		})
+	}
 	return volumeMount
 }
 
@@ -202,29 +265,34 @@ func MakeSegmentstoreConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 		"-Dpravegaservice.clusterName=" + p.Name,
 	}
 
-	jvmOpts := []string{
-		"-Xms1g",
-		"-XX:+ExitOnOutOfMemoryError",
-		"-XX:+CrashOnOutOfMemoryError",
-		"-XX:+HeapDumpOnOutOfMemoryError",
-		"-XX:HeapDumpPath=" + heapDumpDir,
-		"-Dpravegaservice.clusterName=" + p.Name,
-	}
+	jvmOpts := baselineJVMOptions(p, "-Xms1g")
 
-	if match, _ := util.CompareVersions(p.Spec.Version, "0.4.0", ">="); match {
-		// Pravega < 0.4 uses a Java version that does not support the options below
-		jvmOpts = append(jvmOpts,
-			"-XX:+UnlockExperimentalVMOptions",
-			"-XX:+UseContainerSupport",
-			"-XX:MaxRAMPercentage=50.0",
-		)
+	options := map[string]string{}
+	for name, value := range p.Spec.Pravega.Options {
+		options[name] = value
+	}
+	for name, value := range getJournalOptions(p.Spec.Pravega) {
+		options[name] = value
+	}
+	for name, value := range getExternalAccessOptions(p) {
+		options[name] = value
+	}
+	for name, value := range getMetricsOptions(p.Spec.Pravega) {
+		options[name] = value
+	}
+	for name, value := range getContainerCountOptions(p) {
+		options[name] = value
+	}
+	for name, value := range getTLSKeyOptions(p.Spec.TLS.SegmentStoreKeyOptions()) {
+		options[name] = value
 	}
 
-	javaOpts = append(javaOpts, util.OverrideDefaultJVMOptions(jvmOpts, p.Spec.Pravega.SegmentStoreJVMOptions)...)
-
-	for name, value := range p.Spec.Pravega.Options {
-		javaOpts = append(javaOpts, fmt.Sprintf("-D%v=%v", name, value))
+	for name, value := range options {
+		jvmOpts = append(jvmOpts, fmt.Sprintf("-D%v=%v", name, value))
 	}
+	// The JVM options of the spec override the options with the same name, which would
+	// otherwise be set twice and apply depending on their position once sorted
+	javaOpts = append(javaOpts, util.OverrideDefaultJVMOptions(jvmOpts, p.Spec.Pravega.SegmentStoreJVMOptions)...)
 
 	sort.Strings(javaOpts)
 
@@ -272,12 +340,41 @@ func makeCacheVolumeClaimTemplate(p *api.PravegaCluster) []corev1.PersistentVolu
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      cacheVolumeName,
 				Namespace: p.Namespace,
+				Labels:    p.Spec.Pravega.PVCLabels,
 			},
 			Spec: *p.Spec.Pravega.CacheVolumeClaimTemplate,
 		},
 	}
 }
 
+// makeJournalVolumeClaimTemplate returns the claim template of the dedicated journal
+// volume. The size has been validated by the webhook, an invalid one results in an
+// empty request
+func makeJournalVolumeClaimTemplate(p *api.PravegaCluster) corev1.PersistentVolumeClaim {
+	journal := p.Spec.Pravega.SegmentStoreJournalVolume
+	size, _ := resource.ParseQuantity(journal.Size)
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      journalVolumeName,
+			Namespace: p.Namespace,
+			Labels:    p.Spec.Pravega.PVCLabels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	if journal.StorageClassName != "" {
+		storageClassName := journal.StorageClassName
+		claim.Spec.StorageClassName = &storageClassName
+	}
+	return claim
+}
+
 func getTier2StorageOptions(pravegaSpec *api.PravegaSpec) map[string]string {
 	if pravegaSpec.LongTermStorage.FileSystem != nil {
 		return map[string]string{
@@ -287,13 +384,41 @@ func getTier2StorageOptions(pravegaSpec *api.PravegaSpec) map[string]string {
 	}
 
 	if pravegaSpec.LongTermStorage.Ecs != nil {
-		// EXTENDEDS3_ACCESS_KEY_ID & EXTENDEDS3_SECRET_KEY will come from secret storage
-		return map[string]string{
+		// EXTENDEDS3_ACCESS_KEY_ID & EXTENDEDS3_SECRET_KEY will come from secret storage,
+		// unless an IAM role is assumed
+		ecs := pravegaSpec.LongTermStorage.Ecs
+		options := map[string]string{
 			"TIER2_STORAGE":        "EXTENDEDS3",
-			"EXTENDEDS3_CONFIGURI": pravegaSpec.LongTermStorage.Ecs.ConfigUri,
-			"EXTENDEDS3_BUCKET":    pravegaSpec.LongTermStorage.Ecs.Bucket,
-			"EXTENDEDS3_PREFIX":    pravegaSpec.LongTermStorage.Ecs.Prefix,
+			"EXTENDEDS3_CONFIGURI": ecs.ConfigUri,
+			"EXTENDEDS3_BUCKET":    ecs.Bucket,
+			"EXTENDEDS3_PREFIX":    ecs.Prefix,
 		}
+		if ecs.UseV2 {
+			options["EXTENDEDS3_USEV2"] = "true"
+			if ecs.Namespace != "" {
+				options["EXTENDEDS3_NAMESPACE"] = ecs.Namespace
+			}
+			if ecs.Role != "" {
+				options["EXTENDEDS3_ROLE"] = ecs.Role
+			}
+		}
+		return options
+	}
+
+	if pravegaSpec.LongTermStorage.S3 != nil {
+		// EXTENDEDS3_ACCESS_KEY_ID & EXTENDEDS3_SECRET_KEY will come from secret storage.
+		// Smart client load balancing only applies to ECS, it is disabled for other stores
+		s3 := pravegaSpec.LongTermStorage.S3
+		options := map[string]string{
+			"TIER2_STORAGE":        "EXTENDEDS3",
+			"EXTENDEDS3_CONFIGURI": s3.Endpoint + "?smartClient=false",
+			"EXTENDEDS3_BUCKET":    s3.Bucket,
+			"EXTENDEDS3_PREFIX":    s3.Prefix,
+		}
+		if s3.Region != "" {
+			options["AWS_REGION"] = s3.Region
+		}
+		return options
 	}
 
 	if pravegaSpec.LongTermStorage.Hdfs != nil {
@@ -307,8 +432,36 @@ func getTier2StorageOptions(pravegaSpec *api.PravegaSpec) map[string]string {
 	return make(map[string]string)
 }
 
+// getExternalAccessOptions returns the port the segment stores advertise behind their
+// external services, when it differs from the default one. The segment stores sharing
+// segmentStoreLoadBalancerIP advertise the port of their own service, which the image
+// looks up when starting
+func getExternalAccessOptions(p *api.PravegaCluster) map[string]string {
+	options := map[string]string{}
+	port := p.Spec.Pravega.SegmentStoreExternalTrafficPort
+	if !p.Spec.ExternalAccess.Enabled || port == 0 || p.Spec.Pravega.SegmentStoreLoadBalancerIP != "" {
+		return options
+	}
+	if util.IsVersionBelow07(p.Spec.Version) {
+		options["pravegaservice.publishedPort"] = fmt.Sprint(port)
+	} else {
+		options["pravegaservice.service.published.port"] = fmt.Sprint(port)
+	}
+	return options
+}
+
+// getJournalOptions points the Tier 1 data kept locally by the segment store to the
+// journal volume
+func getJournalOptions(pravegaSpec *api.PravegaSpec) map[string]string {
+	options := map[string]string{}
+	if pravegaSpec.SegmentStoreJournalVolume != nil {
+		options[journalDirectoryOption] = journalMountPoint
+	}
+	return options
+}
+
 func configureTier2Secrets(environment []corev1.EnvFromSource, pravegaSpec *api.PravegaSpec) []corev1.EnvFromSource {
-	if pravegaSpec.LongTermStorage.Ecs != nil {
+	if pravegaSpec.LongTermStorage.Ecs != nil && pravegaSpec.LongTermStorage.Ecs.Credentials != "" {
 		return append(environment, corev1.EnvFromSource{
 			Prefix: "EXTENDEDS3_",
 			SecretRef: &corev1.SecretEnvSource{
@@ -319,9 +472,89 @@ func configureTier2Secrets(environment []corev1.EnvFromSource, pravegaSpec *api.
 		})
 	}
 
+	if pravegaSpec.LongTermStorage.S3 != nil {
+		return append(environment, corev1.EnvFromSource{
+			Prefix: "EXTENDEDS3_",
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: pravegaSpec.LongTermStorage.S3.SecretRef,
+				},
+			},
+		})
+	}
+
 	return environment
 }
 
+// segmentStoreEnv returns the environment variables of the Segment Store container set
+// by the operator outside of the configmap
+func segmentStoreEnv(p *api.PravegaCluster) []corev1.EnvVar {
+	env := append(util.DownwardAPIEnv(), tier2Env(p.Spec.Pravega)...)
+	env = append(env, authEnv(p)...)
+	env = append(env, hostNetworkEnv(p)...)
+	jvmOpts := append(hostNetworkOptions(p), tokenSigningKeyOptions(p,
+		"autoScale.security.auth.token.signingKey.basis",
+		"autoScale.controller.security.auth.tokenSigningKey")...)
+	return append(env, extraJavaOptsEnv(jvmOpts)...)
+}
+
+// segmentStoreDnsPolicy returns the DNS policy of the segment store pods. On the host
+// network, the pods only resolve the cluster services with ClusterFirstWithHostNet.
+func segmentStoreDnsPolicy(p *api.PravegaCluster) corev1.DNSPolicy {
+	if p.Spec.Pravega.SegmentStoreHostNetwork && p.Spec.Pravega.SegmentStoreDnsPolicy == "" {
+		return corev1.DNSClusterFirstWithHostNet
+	}
+	return p.Spec.Pravega.SegmentStoreDnsPolicy
+}
+
+// hostNetworkEnv returns the environment variables of the segment stores on the host
+// network, i.e. the IP address of their node
+func hostNetworkEnv(p *api.PravegaCluster) []corev1.EnvVar {
+	if !p.Spec.Pravega.SegmentStoreHostNetwork {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name: hostIPEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "status.hostIP",
+				},
+			},
+		},
+	}
+}
+
+// hostNetworkOptions returns the JVM options of the segment stores on the host network,
+// which publish the IP address of their node. As the address differs between the pods,
+// the option is appended to the JAVA_OPTS of the configmap through the environment
+func hostNetworkOptions(p *api.PravegaCluster) []string {
+	if !p.Spec.Pravega.SegmentStoreHostNetwork {
+		return nil
+	}
+	publishedAddress := "pravegaservice.service.published.host.nameOrIp"
+	if util.IsVersionBelow07(p.Spec.Version) {
+		publishedAddress = "pravegaservice.publishedIPAddress"
+	}
+	return []string{fmt.Sprintf("-D%s=$(%s)", publishedAddress, hostIPEnv)}
+}
+
+// tier2Env returns the environment variables of the segment store read from secrets
+// of the Tier 2 storage, i.e. the ECS session token
+func tier2Env(pravegaSpec *api.PravegaSpec) []corev1.EnvVar {
+	ecs := pravegaSpec.LongTermStorage.Ecs
+	if ecs == nil || !ecs.UseV2 || ecs.SessionToken == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:      "EXTENDEDS3_SESSION_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: ecs.SessionToken},
+		},
+	}
+}
+
 func configureLTSFilesystem(podSpec *corev1.PodSpec, pravegaSpec *api.PravegaSpec) {
 
 	if pravegaSpec.LongTermStorage.FileSystem != nil {
@@ -379,7 +612,11 @@ func configureSegmentstoreTLSSecret(podSpec *corev1.PodSpec, p *api.PravegaClust
 }
 
 func configureCaBundleSecret(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
-	if p.Spec.TLS.IsCaBundlePresent() {
+	ecsCaBundle := ""
+	if lts := p.Spec.Pravega.LongTermStorage; lts != nil && lts.Ecs != nil {
+		ecsCaBundle = lts.Ecs.CaBundleSecret
+	}
+	if p.Spec.TLS.IsCaBundlePresent() && (ecsCaBundle == "" || ecsCaBundle == p.Spec.TLS.Static.CaBundle) {
 		vol := corev1.Volume{
 			Name: caBundleVolumeName,
 			VolumeSource: corev1.VolumeSource{
@@ -394,34 +631,136 @@ func configureCaBundleSecret(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
 			Name:      caBundleVolumeName,
 			MountPath: caBundleMountDir,
 		})
+		return
 	}
+	if ecsCaBundle == "" {
+		return
+	}
+	// The segment store adds the certificates found in the CA bundle directory to the
+	// JVM truststore, the ECS bundle is projected there along with the TLS one if any
+	names := []string{ecsCaBundle}
+	if p.Spec.TLS.IsCaBundlePresent() {
+		names = append([]string{p.Spec.TLS.Static.CaBundle}, names...)
+	}
+	sources := []corev1.VolumeProjection{}
+	for _, name := range names {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: caBundleVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      caBundleVolumeName,
+		MountPath: caBundleMountDir,
+		ReadOnly:  true,
+	})
+}
+
+// operatorManagedAnnotations are the service annotations managed by the operator,
+// which cannot be set through the headless service annotations
+var operatorManagedAnnotations = []string{externalDNSAnnotationKey, LoadBalancerTagsAnnotationKey, AppliedAnnotationsAnnotationKey}
+
+// IsOperatorManagedAnnotation checks whether a service annotation is managed by the
+// operator
+func IsOperatorManagedAnnotation(key string) bool {
+	for _, managed := range operatorManagedAnnotations {
+		if key == managed {
+			return true
+		}
+	}
+	return false
 }
 
 func MakeSegmentStoreHeadlessService(p *api.PravegaCluster) *corev1.Service {
+	var annotationMap map[string]string
+	if len(p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations) != 0 {
+		annotationMap = cloneMap(p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations)
+		keys := make([]string, 0, len(annotationMap))
+		for key := range annotationMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range operatorManagedAnnotations {
+			delete(annotationMap, key)
+		}
+		annotationMap[AppliedAnnotationsAnnotationKey] = strings.Join(keys, ",")
+	}
+	ports := []corev1.ServicePort{
+		{
+			Name:     "server",
+			Port:     12345,
+			Protocol: "TCP",
+		},
+	}
+	if p.Spec.Pravega.Metrics != nil && p.Spec.Pravega.Metrics.ServiceMonitor.IsEnabled() {
+		// The REST port serves the metrics scraped by the ServiceMonitor
+		ports = append(ports, corev1.ServicePort{
+			Name:     SegmentStoreRESTPortName,
+			Port:     util.SegmentStoreRESTPort,
+			Protocol: "TCP",
+		})
+	}
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      p.HeadlessServiceNameForSegmentStore(),
-			Namespace: p.Namespace,
-			Labels:    p.LabelsForSegmentStore(),
+			Name:        p.HeadlessServiceNameForSegmentStore(),
+			Namespace:   p.Namespace,
+			Labels:      p.LabelsForSegmentStore(),
+			Annotations: annotationMap,
 		},
 		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name:     "server",
-					Port:     12345,
-					Protocol: "TCP",
-				},
-			},
+			Ports:     ports,
 			Selector:  p.LabelsForSegmentStore(),
 			ClusterIP: corev1.ClusterIPNone,
 		},
 	}
 }
 
+// HeadlessServiceAnnotationConflicts returns the sorted keys of the headless service
+// annotations that collide with operator-managed annotations, and are ignored
+func HeadlessServiceAnnotationConflicts(p *api.PravegaCluster) []string {
+	conflicts := []string{}
+	for _, key := range operatorManagedAnnotations {
+		if _, ok := p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations[key]; ok {
+			conflicts = append(conflicts, key)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// SegmentStoreExtraEnvConflicts returns the sorted names of the extra environment
+// variables of the Segment Store that are set by the operator, through the configmap, the
+// downward API or the Tier 2 and authentication secrets, and are ignored
+func SegmentStoreExtraEnvConflicts(p *api.PravegaCluster) []string {
+	return extraEnvConflicts(p.Spec.Pravega.SegmentStoreExtraEnv, MakeSegmentstoreConfigMap(p).Data, segmentStoreEnv(p))
+}
+
+// SegmentStoreServiceAccountTokenConflicts returns the operator-managed features of the
+// Segment Store which use the Kubernetes API, and do not work when the service account
+// token is not mounted into its pods
+func SegmentStoreServiceAccountTokenConflicts(p *api.PravegaCluster) []string {
+	conflicts := []string{}
+	token := p.Spec.Pravega.SegmentStoreAutomountServiceAccountToken
+	if token == nil || *token {
+		return conflicts
+	}
+	if p.Spec.ExternalAccess.Enabled {
+		conflicts = append(conflicts, "externalAccess")
+	}
+	return conflicts
+}
+
 func getSSServiceType(pravegaCluster *api.PravegaCluster) (serviceType corev1.ServiceType) {
 	if pravegaCluster.Spec.Pravega.SegmentStoreExternalServiceType == "" {
 		if pravegaCluster.Spec.ExternalAccess.Type == "" {
@@ -443,6 +782,23 @@ func cloneMap(sourceMap map[string]string) (annotationMap map[string]string) {
 	return annotationMap
 }
 
+// addLoadBalancerTagsAnnotation returns the annotations of an external service along
+// with the annotation tagging its cloud load balancer, if load balancer tags are set
+func addLoadBalancerTagsAnnotation(annotationMap map[string]string, p *api.PravegaCluster, serviceType corev1.ServiceType) map[string]string {
+	tags := p.Spec.ExternalAccess.LoadBalancerTags
+	if serviceType != corev1.ServiceTypeLoadBalancer || len(tags) == 0 {
+		return annotationMap
+	}
+	tagList := make([]string, 0, len(tags))
+	for key, value := range tags {
+		tagList = append(tagList, key+"="+value)
+	}
+	sort.Strings(tagList)
+	annotationMap = cloneMap(annotationMap)
+	annotationMap[LoadBalancerTagsAnnotationKey] = strings.Join(tagList, ",")
+	return annotationMap
+}
+
 func generateDNSAnnotationForSvc(domainName string, podName string) (dnsAnnotationValue string) {
 	var ssFQDN string
 	if domainName != "" {
@@ -468,6 +824,7 @@ func MakeSegmentStoreExternalServices(p *api.PravegaCluster) []*corev1.Service {
 			annotationMap = cloneMap(p.Spec.Pravega.SegmentStoreServiceAnnotations)
 			annotationMap[externalDNSAnnotationKey] = annotationValue
 		}
+		annotationMap = addLoadBalancerTagsAnnotation(annotationMap, p, serviceType)
 		service = &corev1.Service{
 			TypeMeta: metav1.TypeMeta{
 				Kind:       "Service",
@@ -484,7 +841,7 @@ func MakeSegmentStoreExternalServices(p *api.PravegaCluster) []*corev1.Service {
 				Ports: []corev1.ServicePort{
 					{
 						Name:       "server",
-						Port:       12345,
+						Port:       p.SegmentStoreExternalTrafficPort(i),
 						Protocol:   "TCP",
 						TargetPort: intstr.FromInt(12345),
 					},
@@ -495,13 +852,14 @@ func MakeSegmentStoreExternalServices(p *api.PravegaCluster) []*corev1.Service {
 				},
 			},
 		}
-		if strings.EqualFold(p.Spec.Pravega.SegmentStoreExternalTrafficPolicy, "Cluster") == true {
+		if p.Spec.ExternalAccess.ExternalTrafficPolicy != "" {
+			service.Spec.ExternalTrafficPolicy = p.Spec.ExternalAccess.ExternalTrafficPolicy
+		} else if strings.EqualFold(p.Spec.Pravega.SegmentStoreExternalTrafficPolicy, "Cluster") == true {
 			service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
 		} else {
 			service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
 		}
 		if p.Spec.Pravega.SegmentStoreLoadBalancerIP != "" {
-			service.Spec.Ports[0].Port = 12345 + i
 			service.Spec.LoadBalancerIP = p.Spec.Pravega.SegmentStoreLoadBalancerIP
 		}
 		services[i] = service
@@ -510,12 +868,21 @@ func MakeSegmentStoreExternalServices(p *api.PravegaCluster) []*corev1.Service {
 }
 
 func MakeSegmentstorePodDisruptionBudget(p *api.PravegaCluster) *policyv1beta1.PodDisruptionBudget {
-	var maxUnavailable intstr.IntOrString
-
-	if p.Spec.Pravega.SegmentStoreReplicas == int32(1) {
-		maxUnavailable = intstr.FromInt(0)
+	var minAvailable, maxUnavailable *intstr.IntOrString
+	budget := p.Spec.Pravega.SegmentStorePdb
+
+	if p.Spec.Pravega.SegmentStoreRebalanceProtection && p.Status.IsSegmentContainerRebalanceInProgress() {
+		// Evicting a segment store would move its containers again before the
+		// rebalance completes
+		maxUnavailable = intOrStringPtr(intstr.FromInt(0))
+	} else if budget != nil && budget.MinAvailable != nil {
+		minAvailable = intOrStringPtr(*budget.MinAvailable)
+	} else if budget != nil && budget.MaxUnavailable != nil {
+		maxUnavailable = intOrStringPtr(*budget.MaxUnavailable)
+	} else if p.Spec.Pravega.SegmentStoreReplicas == int32(1) {
+		maxUnavailable = intOrStringPtr(intstr.FromInt(0))
 	} else {
-		maxUnavailable = intstr.FromInt(1)
+		maxUnavailable = intOrStringPtr(intstr.FromInt(1))
 	}
 
 	return &policyv1beta1.PodDisruptionBudget{
@@ -528,10 +895,15 @@ func MakeSegmentstorePodDisruptionBudget(p *api.PravegaCluster) *policyv1beta1.P
 			Namespace: p.Namespace,
 		},
 		Spec: policyv1beta1.PodDisruptionBudgetSpec{
-			MaxUnavailable: &maxUnavailable,
+			MinAvailable:   minAvailable,
+			MaxUnavailable: maxUnavailable,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: p.LabelsForSegmentStore(),
 			},
 		},
 	}
 }
+
+func intOrStringPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
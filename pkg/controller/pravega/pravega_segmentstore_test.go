@@ -12,6 +12,7 @@ package pravega_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
@@ -20,6 +21,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -147,12 +149,133 @@ var _ = Describe("PravegaSegmentstore", func() {
 					_ = pravega.MakeSegmentstoreConfigMap(p)
 					Ω(err).Should(BeNil())
 				})
+				It("should locate the files of the tls secret keys in the options", func() {
+					p.Spec.TLS.Static.SegmentStoreKeys = &v1beta1.TLSSecretKeys{
+						Certificate: "tls.crt",
+						PrivateKey:  "tls.key",
+					}
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).To(ContainSubstring("-Dpravegaservice.security.tls.server.certificate.location=/etc/secret-volume/tls.crt"))
+					Ω(javaOpts).To(ContainSubstring("-Dpravegaservice.security.tls.server.privateKey.location=/etc/secret-volume/tls.key"))
+					Ω(javaOpts).NotTo(ContainSubstring("truststore.location"))
+				})
+				It("should mount the tls ca bundle", func() {
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					Ω(podSpec.Volumes[len(podSpec.Volumes)-1].Name).To(Equal("ca-bundle"))
+					Ω(podSpec.Volumes[len(podSpec.Volumes)-1].Secret.SecretName).To(Equal("ecs-cert"))
+				})
+				It("should project the ecs ca bundle along with the tls one", func() {
+					p.Spec.Pravega.LongTermStorage.Ecs.CaBundleSecret = "ecs-ca"
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					var volume *corev1.Volume
+					for i := range podSpec.Volumes {
+						if podSpec.Volumes[i].Name == "ca-bundle" {
+							volume = &podSpec.Volumes[i]
+						}
+					}
+					Ω(volume.Projected.Sources).To(HaveLen(2))
+					Ω(volume.Projected.Sources[0].Secret.Name).To(Equal("ecs-cert"))
+					Ω(volume.Projected.Sources[1].Secret.Name).To(Equal("ecs-ca"))
+					for _, mount := range podSpec.Containers[0].VolumeMounts {
+						if mount.Name == "ca-bundle" {
+							Ω(mount.MountPath).To(Equal("/etc/secret-volume/ca-bundle"))
+							Ω(mount.ReadOnly).To(BeTrue())
+						}
+					}
+				})
+				It("should mount the ecs ca bundle without tls", func() {
+					p.Spec.TLS = nil
+					p.Spec.Pravega.LongTermStorage.Ecs.CaBundleSecret = "ecs-ca"
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					volume := podSpec.Volumes[len(podSpec.Volumes)-1]
+					Ω(volume.Name).To(Equal("ca-bundle"))
+					Ω(volume.Projected.Sources).To(HaveLen(1))
+					Ω(volume.Projected.Sources[0].Secret.Name).To(Equal("ecs-ca"))
+				})
 				It("should create a config-map with empty tier2", func() {
 					p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{}
 					cm := pravega.MakeSegmentstoreConfigMap(p)
 					Ω(cm.Data["TIER2_STORAGE"]).To(Equal(""))
 					Ω(err).Should(BeNil())
 				})
+				It("should create a config-map with s3 as tier2", func() {
+					p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+						S3: &v1beta1.S3Spec{
+							Endpoint:  "http://minio.default.svc:9000",
+							Bucket:    "pravega",
+							Prefix:    "tier2",
+							Region:    "us-east-1",
+							SecretRef: "minio-creds",
+						},
+					}
+					cm := pravega.MakeSegmentstoreConfigMap(p)
+					Ω(cm.Data["TIER2_STORAGE"]).To(Equal("EXTENDEDS3"))
+					Ω(cm.Data["EXTENDEDS3_CONFIGURI"]).To(Equal("http://minio.default.svc:9000?smartClient=false"))
+					Ω(cm.Data["EXTENDEDS3_BUCKET"]).To(Equal("pravega"))
+					Ω(cm.Data["EXTENDEDS3_PREFIX"]).To(Equal("tier2"))
+					Ω(cm.Data["AWS_REGION"]).To(Equal("us-east-1"))
+				})
+				It("should load the s3 credentials from the secret", func() {
+					p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+						S3: &v1beta1.S3Spec{
+							Endpoint:  "http://minio.default.svc:9000",
+							Bucket:    "pravega",
+							SecretRef: "minio-creds",
+						},
+					}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					envFrom := podTemplate.Spec.Containers[0].EnvFrom
+					Ω(envFrom[len(envFrom)-1].Prefix).To(Equal("EXTENDEDS3_"))
+					Ω(envFrom[len(envFrom)-1].SecretRef.Name).To(Equal("minio-creds"))
+				})
+				It("should pass the ecs v2 options and session token with an iam role", func() {
+					p.Spec.Pravega.LongTermStorage.Ecs = &v1beta1.ECSSpec{
+						ConfigUri: "https://ecs.example.com:9021",
+						Bucket:    "pravega",
+						UseV2:     true,
+						Namespace: "pravega",
+						Role:      "urn:ecs:iam::pravega:role/segmentstore",
+						SessionToken: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "ecs-token"},
+							Key:                  "token",
+						},
+					}
+					cm := pravega.MakeSegmentstoreConfigMap(p)
+					Ω(cm.Data["EXTENDEDS3_USEV2"]).To(Equal("true"))
+					Ω(cm.Data["EXTENDEDS3_NAMESPACE"]).To(Equal("pravega"))
+					Ω(cm.Data["EXTENDEDS3_ROLE"]).To(Equal("urn:ecs:iam::pravega:role/segmentstore"))
+					container := pravega.MakeSegmentStorePodTemplate(p).Spec.Containers[0]
+					for _, envFrom := range container.EnvFrom {
+						Ω(envFrom.Prefix).NotTo(Equal("EXTENDEDS3_"))
+					}
+					token := container.Env[len(container.Env)-1]
+					Ω(token.Name).To(Equal("EXTENDEDS3_SESSION_TOKEN"))
+					Ω(token.ValueFrom.SecretKeyRef.Name).To(Equal("ecs-token"))
+					Ω(token.ValueFrom.SecretKeyRef.Key).To(Equal("token"))
+				})
+				It("should read the token signing key from the controller token secret", func() {
+					p.Spec.Authentication.ControllerTokenSecret = &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+						Key:                  "signing-key",
+					}
+					env := pravega.MakeSegmentStorePodTemplate(p).Spec.Containers[0].Env
+					key := env[len(env)-2]
+					Ω(key.Name).To(Equal("TOKEN_SIGNING_KEY"))
+					Ω(key.ValueFrom.SecretKeyRef.Name).To(Equal("token-secret"))
+					Ω(key.ValueFrom.SecretKeyRef.Key).To(Equal("signing-key"))
+				})
+				It("should pass the token signing key to the auto scaler options", func() {
+					p.Spec.Authentication.ControllerTokenSecret = &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+						Key:                  "signing-key",
+					}
+					env := pravega.MakeSegmentStorePodTemplate(p).Spec.Containers[0].Env
+					javaOpts := env[len(env)-1]
+					Ω(javaOpts.Name).To(Equal("JAVA_OPTS"))
+					Ω(javaOpts.Value).To(HavePrefix("$(JAVA_OPTS) "))
+					Ω(strings.Fields(javaOpts.Value)).To(ContainElement("-DautoScale.security.auth.token.signingKey.basis=$(TOKEN_SIGNING_KEY)"))
+					Ω(strings.Fields(javaOpts.Value)).To(ContainElement("-DautoScale.controller.security.auth.tokenSigningKey=$(TOKEN_SIGNING_KEY)"))
+				})
 				It("should create a stateful set", func() {
 					_ = pravega.MakeSegmentStoreStatefulSet(p)
 					Ω(err).Should(BeNil())
@@ -164,6 +287,213 @@ var _ = Describe("PravegaSegmentstore", func() {
 					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
 					Ω(fmt.Sprintf("%v", *podTemplate.Spec.SecurityContext.RunAsUser)).To(Equal("0"))
 				})
+				It("should propagate the fsGroup to the pod template", func() {
+					fsGroup := int64(1000)
+					p.Spec.Pravega.SegmentStoreSecurityContext = &corev1.PodSecurityContext{FSGroup: &fsGroup}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(*podTemplate.Spec.SecurityContext.FSGroup).To(Equal(int64(1000)))
+				})
+				It("should apply the container security context to the segment store container", func() {
+					escalation := false
+					p.Spec.Pravega.SegmentStoreContainerSecurityContext = &corev1.SecurityContext{AllowPrivilegeEscalation: &escalation}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(*podTemplate.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation).To(Equal(false))
+				})
+				It("should run the init containers before the segment store", func() {
+					p.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+						{
+							Name:  "fix-permissions",
+							Image: "busybox",
+						},
+					}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					initContainers := podTemplate.Spec.InitContainers
+					Ω(initContainers[len(initContainers)-1].Name).To(Equal("fix-permissions"))
+				})
+				It("should use the segment store image override", func() {
+					p.Spec.Pravega.SegmentStoreImage = &v1beta1.ImageSpec{Repository: "example/pravega-patched"}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.Containers[0].Image).To(Equal("example/pravega-patched:" + p.Spec.Version))
+					Ω(pravega.MakeControllerPodTemplate(p).Spec.Containers[0].Image).To(Equal(p.PravegaImage()))
+				})
+				It("should propagate the run as identity to the pod", func() {
+					group := int64(2000)
+					p.Spec.Pravega.RunAsIdentitySecret = "pravega-identity"
+					p.Status.RunAsIdentity = &v1beta1.RunAsIdentity{RunAsUser: 1000, RunAsGroup: &group}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(*podTemplate.Spec.SecurityContext.RunAsUser).To(Equal(int64(1000)))
+					Ω(*podTemplate.Spec.SecurityContext.RunAsGroup).To(Equal(int64(2000)))
+					Ω(*p.Spec.Pravega.SegmentStoreSecurityContext.RunAsUser).To(Equal(int64(0)))
+				})
+				It("should add an init container waiting on the init wait url", func() {
+					p.Spec.Pravega.InitWaitURL = "https://metadata.example.com/health"
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.InitContainers).To(HaveLen(1))
+					Ω(podTemplate.Spec.InitContainers[0].Name).To(Equal("wait-for-dependency"))
+					Ω(podTemplate.Spec.InitContainers[0].Env[0].Value).To(Equal("https://metadata.example.com/health"))
+					Ω(podTemplate.Spec.InitContainers[0].Image).To(Equal(p.SegmentStoreImage()))
+					Ω(podTemplate.Spec.InitContainers[0].ImagePullPolicy).To(Equal(p.SegmentStoreImagePullPolicy()))
+				})
+				It("should add the logging sidecar after the segment store", func() {
+					p.Spec.Pravega.LoggingSidecar = &v1beta1.LoggingSidecarSpec{
+						Image:     "fluent/fluent-bit:1.5",
+						MountPath: "/var/log/pravega",
+					}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.Containers).To(HaveLen(2))
+					Ω(podTemplate.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "logs", MountPath: "/var/log/pravega"}))
+					Ω(podTemplate.Spec.Containers[1].Name).To(Equal("logging-sidecar"))
+					Ω(podTemplate.Spec.Containers[1].Env).To(ContainElement(corev1.EnvVar{Name: "LOG_DIR", Value: "/var/log/pravega"}))
+				})
+				It("should append the extra environment after the operator-managed variables", func() {
+					p.Spec.Pravega.SegmentStoreExtraEnv = []corev1.EnvVar{
+						{Name: "POD_NAME", Value: "segmentstore"},
+						{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+						{Name: "JAVA_OPTS", Value: "-Xmx1g"},
+					}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					env := podTemplate.Spec.Containers[0].Env
+					Ω(env).To(HaveLen(3))
+					Ω(env[0].Name).To(Equal("POD_NAME"))
+					Ω(env[0].ValueFrom).NotTo(BeNil())
+					Ω(env[1].Name).To(Equal("POD_NAMESPACE"))
+					Ω(env[2]).To(Equal(corev1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"}))
+					Ω(pravega.SegmentStoreExtraEnvConflicts(p)).To(Equal([]string{"JAVA_OPTS", "POD_NAME"}))
+				})
+				It("should set the segment store node selector on the pod template", func() {
+					p.Spec.Pravega.SegmentStorePodNodeSelector = map[string]string{"disktype": "ssd"}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.NodeSelector).To(Equal(map[string]string{"disktype": "ssd"}))
+				})
+				It("should merge the segment store pod affinity with the operator anti-affinity", func() {
+					zone := corev1.NodeSelectorRequirement{
+						Key:      "topology.kubernetes.io/zone",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"us-east-1a"},
+					}
+					bookies := corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bookkeeper-cluster"}},
+						TopologyKey:   "kubernetes.io/hostname",
+					}
+					p.Spec.Pravega.SegmentStorePodAffinity = &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{zone}}},
+							},
+						},
+						PodAntiAffinity: &corev1.PodAntiAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{bookies},
+						},
+					}
+					affinity := pravega.MakeSegmentStorePodTemplate(p).Spec.Affinity
+					Ω(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{zone}))
+					Ω(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal([]corev1.PodAffinityTerm{bookies}))
+					preferred := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+					Ω(preferred).To(HaveLen(1))
+					Ω(preferred[0].PodAffinityTerm.TopologyKey).To(Equal("kubernetes.io/hostname"))
+					Ω(preferred[0].PodAffinityTerm.LabelSelector.MatchExpressions).To(ContainElement(metav1.LabelSelectorRequirement{
+						Key:      "component",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"pravega-segmentstore"},
+					}))
+				})
+				It("should add the headless service annotations except operator-managed ones", func() {
+					p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations = map[string]string{
+						"mesh.example.com/inject":                   "true",
+						"external-dns.alpha.kubernetes.io/hostname": "ss.example.com.",
+					}
+					svc := pravega.MakeSegmentStoreHeadlessService(p)
+					Ω(svc.Annotations).To(Equal(map[string]string{
+						"mesh.example.com/inject":    "true",
+						"pravega.appliedAnnotations": "external-dns.alpha.kubernetes.io/hostname,mesh.example.com/inject",
+					}))
+					Ω(pravega.HeadlessServiceAnnotationConflicts(p)).To(Equal([]string{"external-dns.alpha.kubernetes.io/hostname"}))
+				})
+				It("should not set a termination grace period by default", func() {
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.TerminationGracePeriodSeconds).To(BeNil())
+				})
+				It("should set the termination grace period on the pod template", func() {
+					gracePeriod := int64(180)
+					p.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds = &gracePeriod
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					Ω(*sts.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(180)))
+				})
+				It("should not add a journal volume by default", func() {
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					for _, claim := range sts.Spec.VolumeClaimTemplates {
+						Ω(claim.Name).NotTo(Equal("journal"))
+					}
+				})
+				It("should add the journal volume claim template and mount", func() {
+					p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+						StorageClassName: "fast-ssd",
+						Size:             "50Gi",
+					}
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					claims := sts.Spec.VolumeClaimTemplates
+					claim := claims[len(claims)-1]
+					Ω(claim.Name).To(Equal("journal"))
+					Ω(*claim.Spec.StorageClassName).To(Equal("fast-ssd"))
+					Ω(claim.Spec.Resources.Requests.Storage().String()).To(Equal("50Gi"))
+					Ω(sts.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+						Name:      "journal",
+						MountPath: "/tmp/pravega/journal",
+					}))
+				})
+				It("should point the journal option to the journal volume", func() {
+					Ω(pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]).NotTo(ContainSubstring("bookkeeper.journal.directory"))
+					p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{Size: "50Gi"}
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).To(ContainSubstring("-Dbookkeeper.journal.directory=/tmp/pravega/journal"))
+				})
+				It("should set the segment container count on the segment store", func() {
+					count := int32(8)
+					p.Spec.Pravega.SegmentStoreContainerCount = &count
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).To(ContainSubstring("-Dcontroller.containerCount=8"))
+					Ω(javaOpts).To(ContainSubstring("-Dpravegaservice.containerCount=8"))
+				})
+				It("should use the storage class of the cache volume claim template below 0.7", func() {
+					storageClassName := "local-nvme"
+					p.Spec.Version = "0.6.1"
+					p.Spec.Pravega.CacheVolumeClaimTemplate.StorageClassName = &storageClassName
+					p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+						StorageClassName: "fast-ssd",
+						Size:             "50Gi",
+					}
+					claims := pravega.MakeSegmentStoreStatefulSet(p).Spec.VolumeClaimTemplates
+					Ω(claims).To(HaveLen(2))
+					Ω(claims[0].Name).To(Equal("cache"))
+					Ω(*claims[0].Spec.StorageClassName).To(Equal("local-nvme"))
+					Ω(*claims[1].Spec.StorageClassName).To(Equal("fast-ssd"))
+				})
+				It("should add the segment store volumes and their mounts", func() {
+					volume := corev1.Volume{
+						Name: "storage-tuning",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "storage-tuning"},
+							},
+						},
+					}
+					mount := corev1.VolumeMount{Name: "storage-tuning", MountPath: "/etc/storage-tuning", ReadOnly: true}
+					p.Spec.Pravega.SegmentStoreVolumes = []corev1.Volume{volume}
+					p.Spec.Pravega.SegmentStoreVolumeMounts = []corev1.VolumeMount{mount}
+					podSpec := pravega.MakeSegmentStorePodTemplate(p).Spec
+					Ω(podSpec.Volumes).To(ContainElement(volume))
+					Ω(podSpec.Containers[0].VolumeMounts).To(ContainElement(mount))
+				})
+				It("should add the pvc labels to the volume claim templates", func() {
+					p.Spec.Version = "0.6.1"
+					p.Spec.Pravega.PVCLabels = map[string]string{"cost-center": "streaming"}
+					p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{Size: "50Gi"}
+					claims := pravega.MakeSegmentStoreStatefulSet(p).Spec.VolumeClaimTemplates
+					Ω(claims).To(HaveLen(2))
+					for _, claim := range claims {
+						Ω(claim.Labels).To(HaveKeyWithValue("cost-center", "streaming"))
+					}
+				})
 			})
 		})
 
@@ -273,6 +603,35 @@ var _ = Describe("PravegaSegmentstore", func() {
 					Ω(p.Spec.ExternalAccess.Type).Should(Equal(corev1.ServiceTypeClusterIP))
 				})
 
+				It("should add the metrics settings to the config-map", func() {
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+						Prefix: "pravega_prod",
+					}
+					cm := pravega.MakeSegmentstoreConfigMap(p)
+					Ω(cm.Data["JAVA_OPTS"]).To(ContainSubstring("-Dmetrics.metricsPrefix=pravega_prod"))
+					Ω(cm.Data["JAVA_OPTS"]).NotTo(ContainSubstring("metrics.outputFrequencySeconds"))
+				})
+
+				It("should mount the shared tier2 claim in every segment store pod", func() {
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					podSpec := sts.Spec.Template.Spec
+					var tier2Volumes []corev1.Volume
+					for _, vol := range podSpec.Volumes {
+						if vol.Name == "tier2" {
+							tier2Volumes = append(tier2Volumes, vol)
+						}
+					}
+					Ω(tier2Volumes).To(HaveLen(1))
+					Ω(tier2Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("claim"))
+					Ω(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+						Name:      "tier2",
+						MountPath: "/mnt/tier2",
+					}))
+					for _, claim := range sts.Spec.VolumeClaimTemplates {
+						Ω(claim.Name).NotTo(Equal("tier2"))
+					}
+				})
+
 			})
 		})
 
@@ -397,6 +756,18 @@ var _ = Describe("PravegaSegmentstore", func() {
 					Ω(svc[0].Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeCluster))
 				})
 			})
+			Context("Create External service with the external access ExternalTrafficPolicy", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreExternalTrafficPolicy = "cluster"
+					p.Spec.ExternalAccess.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+				})
+				It("should take precedence over SegmentStoreExternalTrafficPolicy", func() {
+					svcs := pravega.MakeSegmentStoreExternalServices(p)
+					for _, svc := range svcs {
+						Ω(svc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeLocal))
+					}
+				})
+			})
 			Context("Create External service with LoadBalancerIP", func() {
 				BeforeEach(func() {
 					p.Spec.Pravega.SegmentStoreLoadBalancerIP = "10.240.12.18"
@@ -404,6 +775,58 @@ var _ = Describe("PravegaSegmentstore", func() {
 				It("should create external service with LoadBalancerIP", func() {
 					svc := pravega.MakeSegmentStoreExternalServices(p)
 					Ω(svc[0].Spec.LoadBalancerIP).To(Equal("10.240.12.18"))
+					Ω(svc[1].Spec.Ports[0].Port).To(Equal(int32(12346)))
+				})
+			})
+			Context("Create External service behind a NAT", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreExternalServiceType = corev1.ServiceTypeLoadBalancer
+					p.Spec.Pravega.SegmentStoreExternalTrafficPort = 31000
+				})
+				It("should expose the external port and forward it to the container port", func() {
+					svcs := pravega.MakeSegmentStoreExternalServices(p)
+					for _, svc := range svcs {
+						Ω(svc.Spec.Ports[0].Port).To(Equal(int32(31000)))
+						Ω(svc.Spec.Ports[0].TargetPort.IntValue()).To(Equal(12345))
+					}
+					podTemplate := pravega.MakeSegmentStorePodTemplate(p)
+					Ω(podTemplate.Spec.Containers[0].Ports[0].ContainerPort).To(Equal(int32(12345)))
+				})
+				It("should advertise the external port", func() {
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).To(ContainSubstring("-Dpravegaservice.publishedPort=31000"))
+				})
+				It("should use consecutive ports along with a shared LoadBalancerIP", func() {
+					p.Spec.Pravega.SegmentStoreLoadBalancerIP = "10.240.12.18"
+					svcs := pravega.MakeSegmentStoreExternalServices(p)
+					Ω(svcs[0].Spec.Ports[0].Port).To(Equal(int32(31000)))
+					Ω(svcs[3].Spec.Ports[0].Port).To(Equal(int32(31003)))
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).NotTo(ContainSubstring("publishedPort"))
+				})
+				It("should not advertise the port without external access", func() {
+					p.Spec.ExternalAccess.Enabled = false
+					javaOpts := pravega.MakeSegmentstoreConfigMap(p).Data["JAVA_OPTS"]
+					Ω(javaOpts).NotTo(ContainSubstring("publishedPort"))
+				})
+			})
+			Context("Create External service with load balancer tags", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreExternalServiceType = corev1.ServiceTypeLoadBalancer
+					p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{
+						"owner": "finops",
+					}
+				})
+				It("should add the tags annotation to every external service", func() {
+					svcs := pravega.MakeSegmentStoreExternalServices(p)
+					for _, svc := range svcs {
+						Ω(svc.Annotations["service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"]).To(Equal("owner=finops"))
+					}
+				})
+				It("should not add the tags annotation to NodePort services", func() {
+					p.Spec.Pravega.SegmentStoreExternalServiceType = corev1.ServiceTypeNodePort
+					svcs := pravega.MakeSegmentStoreExternalServices(p)
+					Ω(svcs[0].Annotations).NotTo(HaveKey("service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"))
 				})
 			})
 			Context("Create External service with external service type empty", func() {
@@ -418,6 +841,143 @@ var _ = Describe("PravegaSegmentstore", func() {
 					Ω(err).Should(BeNil())
 				})
 			})
+			Context("Create stateful set with a DNS policy", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreDnsPolicy = corev1.DNSClusterFirst
+					p.Spec.Pravega.SegmentStoreDnsConfig = &corev1.PodDNSConfig{
+						Searches: []string{"storage.example.com"},
+					}
+				})
+				It("should set the DNS policy and config of the pods", func() {
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					Ω(podSpec.DNSPolicy).Should(Equal(corev1.DNSClusterFirst))
+					Ω(podSpec.DNSConfig.Searches).Should(Equal([]string{"storage.example.com"}))
+				})
+			})
+			Context("Create configmap with a cluster domain", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.ClusterDomain = "cluster.internal"
+				})
+				It("should hand the fully qualified controller url to the segment stores", func() {
+					cm := pravega.MakeSegmentstoreConfigMap(p)
+					Ω(cm.Data["CONTROLLER_URL"]).Should(HaveSuffix(".svc.cluster.internal:9090"))
+				})
+			})
+			Context("Create stateful set on the host network", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreHostNetwork = true
+				})
+				It("should run the pods on the host network with the matching DNS policy", func() {
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					Ω(podSpec.HostNetwork).Should(BeTrue())
+					Ω(podSpec.DNSPolicy).Should(Equal(corev1.DNSClusterFirstWithHostNet))
+				})
+				It("should keep the DNS policy of the spec", func() {
+					p.Spec.Pravega.SegmentStoreDnsPolicy = corev1.DNSDefault
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					Ω(podSpec.DNSPolicy).Should(Equal(corev1.DNSDefault))
+				})
+				It("should publish the address of the node", func() {
+					env := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec.Containers[0].Env
+					var hostIP, javaOpts *corev1.EnvVar
+					for i := range env {
+						switch env[i].Name {
+						case "HOST_IP":
+							hostIP = &env[i]
+						case "JAVA_OPTS":
+							javaOpts = &env[i]
+						}
+					}
+					Ω(hostIP.ValueFrom.FieldRef.FieldPath).Should(Equal("status.hostIP"))
+					Ω(javaOpts.Value).Should(HavePrefix("$(JAVA_OPTS) -Dpravegaservice."))
+					Ω(javaOpts.Value).Should(HaveSuffix("=$(HOST_IP)"))
+				})
+			})
+			Context("Create stateful set without the service account token", func() {
+				BeforeEach(func() {
+					automount := false
+					p.Spec.Pravega.SegmentStoreAutomountServiceAccountToken = &automount
+				})
+				It("should not mount the service account token", func() {
+					podSpec := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec
+					Ω(*podSpec.AutomountServiceAccountToken).Should(BeFalse())
+				})
+				It("should report external access as needing the token", func() {
+					p.Spec.ExternalAccess.Enabled = true
+					Ω(pravega.SegmentStoreServiceAccountTokenConflicts(p)).Should(Equal([]string{"externalAccess"}))
+				})
+				It("should not report conflicts without external access", func() {
+					p.Spec.ExternalAccess.Enabled = false
+					Ω(pravega.SegmentStoreServiceAccountTokenConflicts(p)).Should(BeEmpty())
+				})
+			})
+			Context("Create stateful set with a command override", func() {
+				It("should launch the segment store by default", func() {
+					container := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec.Containers[0]
+					Ω(container.Command).Should(BeEmpty())
+					Ω(container.Args).Should(Equal([]string{"segmentstore"}))
+					Ω(container.LivenessProbe).ShouldNot(BeNil())
+				})
+				It("should replace the command and clear the default args", func() {
+					p.Spec.Pravega.SegmentStoreCommand = []string{"sleep", "infinity"}
+					container := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec.Containers[0]
+					Ω(container.Command).Should(Equal([]string{"sleep", "infinity"}))
+					Ω(container.Args).Should(BeEmpty())
+					Ω(container.LivenessProbe).Should(BeNil())
+					Ω(container.ReadinessProbe).ShouldNot(BeNil())
+				})
+				It("should replace the args only", func() {
+					p.Spec.Pravega.SegmentStoreArgs = []string{"segmentstore", "--debug"}
+					container := pravega.MakeSegmentStoreStatefulSet(p).Spec.Template.Spec.Containers[0]
+					Ω(container.Command).Should(BeEmpty())
+					Ω(container.Args).Should(Equal([]string{"segmentstore", "--debug"}))
+					Ω(container.LivenessProbe).Should(BeNil())
+				})
+			})
+			Context("Create stateful set with topology spread constraints", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStorePodAffinity = &corev1.Affinity{
+						PodAntiAffinity: &corev1.PodAntiAffinity{},
+					}
+					p.Spec.Pravega.SegmentStoreTopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+						{
+							MaxSkew:           1,
+							TopologyKey:       "topology.kubernetes.io/zone",
+							WhenUnsatisfiable: corev1.DoNotSchedule,
+						},
+					}
+				})
+				It("should apply both the constraints and the affinity", func() {
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					Ω(sts.Spec.Template.Spec.TopologySpreadConstraints).Should(HaveLen(1))
+					Ω(sts.Spec.Template.Spec.TopologySpreadConstraints[0].TopologyKey).Should(Equal("topology.kubernetes.io/zone"))
+					Ω(sts.Spec.Template.Spec.Affinity.PodAntiAffinity).ShouldNot(BeNil())
+				})
+			})
+			Context("Create pod disruption budget with a segment store budget", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SegmentStoreReplicas = 5
+				})
+				It("should allow one unavailable segment store by default", func() {
+					pdb := pravega.MakeSegmentstorePodDisruptionBudget(p)
+					Ω(pdb.Spec.MinAvailable).Should(BeNil())
+					Ω(pdb.Spec.MaxUnavailable.IntValue()).Should(Equal(1))
+				})
+				It("should set minAvailable", func() {
+					minAvailable := intstr.FromString("60%")
+					p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MinAvailable: &minAvailable}
+					pdb := pravega.MakeSegmentstorePodDisruptionBudget(p)
+					Ω(*pdb.Spec.MinAvailable).Should(Equal(minAvailable))
+					Ω(pdb.Spec.MaxUnavailable).Should(BeNil())
+				})
+				It("should set maxUnavailable", func() {
+					maxUnavailable := intstr.FromInt(2)
+					p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MaxUnavailable: &maxUnavailable}
+					pdb := pravega.MakeSegmentstorePodDisruptionBudget(p)
+					Ω(pdb.Spec.MinAvailable).Should(BeNil())
+					Ω(pdb.Spec.MaxUnavailable.IntValue()).Should(Equal(2))
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,80 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceMonitorGVK is the kind of the Prometheus Operator ServiceMonitor. The
+// operator does not depend on the Prometheus Operator types and builds the
+// ServiceMonitor as an unstructured object.
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// MakeServiceMonitor returns the ServiceMonitor scraping the "rest" port of the
+// controller service and of the segment store headless service, which both serve
+// the Prometheus metrics.
+func MakeServiceMonitor(p *api.PravegaCluster) *unstructured.Unstructured {
+	sm := p.Spec.Pravega.Metrics.ServiceMonitor
+	labels := map[string]interface{}{}
+	for key, value := range sm.Labels {
+		labels[key] = value
+	}
+	for key, value := range p.LabelsForPravegaCluster() {
+		labels[key] = value
+	}
+	selectorLabels := map[string]interface{}{}
+	for key, value := range p.LabelsForPravegaCluster() {
+		selectorLabels[key] = value
+	}
+	endpoint := map[string]interface{}{
+		"port": "rest",
+		"path": util.PrometheusMetricsPath,
+	}
+	if sm.Interval != "" {
+		endpoint["interval"] = sm.Interval
+	}
+
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":      p.ServiceMonitorName(),
+				"namespace": p.Namespace,
+				"labels":    labels,
+			},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": selectorLabels,
+					"matchExpressions": []interface{}{
+						map[string]interface{}{
+							"key":      "component",
+							"operator": "In",
+							"values":   []interface{}{"pravega-controller", "pravega-segmentstore"},
+						},
+					},
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{p.Namespace},
+				},
+				"endpoints": []interface{}{endpoint},
+			},
+		},
+	}
+	u.SetGroupVersionKind(ServiceMonitorGVK)
+	return u
+}
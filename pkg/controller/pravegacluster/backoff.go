@@ -0,0 +1,67 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// MinReconcileBackoff is the delay before requeueing a cluster after its first failed
+// reconcile. It doubles on each consecutive failure, up to config.MaxReconcileBackoff
+const MinReconcileBackoff = time.Second
+
+// ReconcileBackoff counts the consecutive failed reconciles of the managed clusters, to
+// requeue them with an exponential backoff
+type ReconcileBackoff struct {
+	mutex    sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// Backoff is the reconcile backoff of the clusters managed by the operator
+var Backoff = NewReconcileBackoff()
+
+// NewReconcileBackoff returns a reconcile backoff without any failure
+func NewReconcileBackoff() *ReconcileBackoff {
+	return &ReconcileBackoff{failures: map[types.NamespacedName]int{}}
+}
+
+// Next records a failed reconcile of the cluster and returns the delay before its next
+// reconcile
+func (b *ReconcileBackoff) Next(name types.NamespacedName) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	failures := b.failures[name]
+	b.failures[name] = failures + 1
+	max := config.MaxReconcileBackoff
+	if max < MinReconcileBackoff {
+		max = MinReconcileBackoff
+	}
+	delay := MinReconcileBackoff
+	for i := 0; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// Reset forgets the failed reconciles of the cluster, after a successful reconcile or
+// once it no longer exists
+func (b *ReconcileBackoff) Reset(name types.NamespacedName) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.failures, name)
+}
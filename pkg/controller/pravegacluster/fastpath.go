@@ -0,0 +1,261 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReconcileFastPath records, for each managed cluster, the fingerprint of its last full
+// reconcile and the number of reconciles which only synced its status since, so that an
+// unchanged and healthy cluster is not rebuilt on every resync
+type ReconcileFastPath struct {
+	mutex    sync.Mutex
+	clusters map[types.NamespacedName]*fastPathState
+}
+
+type fastPathState struct {
+	fingerprint string
+	skipped     int
+}
+
+// FastPath is the reconcile fast path of the clusters managed by the operator
+var FastPath = NewReconcileFastPath()
+
+// NewReconcileFastPath returns a reconcile fast path without any full reconcile recorded
+func NewReconcileFastPath() *ReconcileFastPath {
+	return &ReconcileFastPath{clusters: map[types.NamespacedName]*fastPathState{}}
+}
+
+// Skip returns true if the full reconcile of the cluster can be skipped, i.e. if its
+// fingerprint is the one of its last full reconcile and fewer than
+// config.FullReconcileInterval - 1 reconciles were skipped since. It counts the skipped one
+func (f *ReconcileFastPath) Skip(name types.NamespacedName, fingerprint string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	state, ok := f.clusters[name]
+	if !ok || state.fingerprint != fingerprint || state.skipped >= config.FullReconcileInterval-1 {
+		return false
+	}
+	state.skipped++
+	return true
+}
+
+// Record saves the fingerprint of a successful full reconcile of the cluster
+func (f *ReconcileFastPath) Record(name types.NamespacedName, fingerprint string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.clusters[name] = &fastPathState{fingerprint: fingerprint}
+}
+
+// Reset forgets the last full reconcile of the cluster, after a failed reconcile or once
+// it no longer exists, so that its next reconcile is a full one
+func (f *ReconcileFastPath) Reset(name types.NamespacedName) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.clusters, name)
+}
+
+// canSkipFullReconcile returns true if only the status of the cluster needs to be synced:
+// its spec was fully reconciled, it is steady, and neither its metadata nor its child
+// objects changed since its last full reconcile
+func (r *ReconcilePravegaCluster) canSkipFullReconcile(p *pravegav1beta1.PravegaCluster) bool {
+	if config.FullReconcileInterval < 2 || !p.DeletionTimestamp.IsZero() ||
+		p.Generation != p.Status.ObservedGeneration || !clusterSteady(p) {
+		return false
+	}
+	fingerprint, err := r.clusterFingerprint(p)
+	if err != nil {
+		log.Printf("failed to fingerprint cluster (%s), reconciling it fully: %v", p.Name, err)
+		return false
+	}
+	return FastPath.Skip(types.NamespacedName{Namespace: p.Namespace, Name: p.Name}, fingerprint)
+}
+
+// recordFullReconcile records the fingerprint of the cluster after a successful full
+// reconcile, from which the next reconciles can be skipped
+func (r *ReconcilePravegaCluster) recordFullReconcile(p *pravegav1beta1.PravegaCluster) {
+	name := types.NamespacedName{Namespace: p.Namespace, Name: p.Name}
+	if config.FullReconcileInterval < 2 {
+		return
+	}
+	fingerprint, err := r.clusterFingerprint(p)
+	if err != nil {
+		log.Printf("failed to fingerprint cluster (%s): %v", p.Name, err)
+		FastPath.Reset(name)
+		return
+	}
+	FastPath.Record(name, fingerprint)
+}
+
+// clusterSteady returns true if the status of the cluster leaves nothing for a full
+// reconcile to act upon: all its pods are ready, it is neither upgrading nor rolling
+// back, no action is deferred and none of its conditions reports a problem
+func clusterSteady(p *pravegav1beta1.PravegaCluster) bool {
+	if clusterHealth(&p.Status) != ClusterHealthy || p.Status.Maintenance != nil {
+		return false
+	}
+	for _, condition := range p.Status.Conditions {
+		switch condition.Type {
+		case pravegav1beta1.ClusterConditionPodsReady:
+			if condition.Status != corev1.ConditionTrue {
+				return false
+			}
+		case pravegav1beta1.ClusterConditionLtsReachable:
+			if condition.Status == corev1.ConditionFalse {
+				return false
+			}
+		case pravegav1beta1.ClusterConditionConfigMapReconcileIgnored:
+			// reports the configmap reconcile policy, not a problem
+		default:
+			if condition.Status == corev1.ConditionTrue {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// clusterFingerprint hashes the generation and the annotations of the cluster, along with
+// the resource versions of the child objects it controls and of the secrets it references,
+// and the restart annotation of the nodes of its segment stores. Any update of the spec, of
+// the annotations, e.g. the restart one, of a child object, e.g. an external edit, of a
+// secret, e.g. a TLS certificate rotation, or of a node restart annotation changes it.
+func (r *ReconcilePravegaCluster) clusterFingerprint(p *pravegav1beta1.PravegaCluster) (string, error) {
+	entries := []string{fmt.Sprintf("generation=%d", p.Generation)}
+	for key, value := range p.Annotations {
+		entries = append(entries, fmt.Sprintf("annotation/%s=%s", key, value))
+	}
+	for _, name := range referencedSecrets(p) {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, secret)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get secret (%s): %v", name, err)
+		}
+		entries = append(entries, fmt.Sprintf("Secret/%s=%s", name, secret.ResourceVersion))
+	}
+	nodeEntries, err := r.nodeAnnotationEntries(p)
+	if err != nil {
+		return "", err
+	}
+	entries = append(entries, nodeEntries...)
+	lists := map[string]runtime.Object{
+		"StatefulSet":         &appsv1.StatefulSetList{},
+		"Deployment":          &appsv1.DeploymentList{},
+		"Service":             &corev1.ServiceList{},
+		"ConfigMap":           &corev1.ConfigMapList{},
+		"PodDisruptionBudget": &policyv1beta1.PodDisruptionBudgetList{},
+	}
+	for kind, list := range lists {
+		// The children are not all labeled with the cluster, they are selected by owner
+		err := r.client.List(context.TODO(), list, client.InNamespace(p.Namespace))
+		if err != nil {
+			return "", fmt.Errorf("failed to list %s objects: %v", kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return "", err
+		}
+		for _, item := range items {
+			object, err := meta.Accessor(item)
+			if err != nil {
+				return "", err
+			}
+			if metav1.IsControlledBy(object, p) {
+				entries = append(entries, fmt.Sprintf("%s/%s=%s", kind, object.GetName(), object.GetResourceVersion()))
+			}
+		}
+	}
+	sort.Strings(entries)
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// referencedSecrets returns the names of the secrets the full reconcile of the cluster
+// reads: the TLS secrets, the long term storage CA bundle, the run as identity secret and
+// the controller token secret
+func referencedSecrets(p *pravegav1beta1.PravegaCluster) []string {
+	names := []string{}
+	if tls := p.Spec.TLS; tls != nil && tls.Static != nil {
+		names = append(names, tls.Static.ControllerSecret, tls.Static.SegmentStoreSecret, tls.Static.CaBundle)
+	}
+	if pravega := p.Spec.Pravega; pravega != nil {
+		if lts := pravega.LongTermStorage; lts != nil && lts.Ecs != nil {
+			names = append(names, lts.Ecs.CaBundleSecret)
+		}
+		names = append(names, pravega.RunAsIdentitySecret)
+	}
+	if auth := p.Spec.Authentication; auth != nil && auth.ControllerTokenSecret != nil {
+		names = append(names, auth.ControllerTokenSecret.Name)
+	}
+	referenced := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			referenced = append(referenced, name)
+		}
+	}
+	return referenced
+}
+
+// nodeAnnotationEntries returns the value of the restart node annotation on the node of
+// each segment store pod, if the pods are restarted on its changes
+func (r *ReconcilePravegaCluster) nodeAnnotationEntries(p *pravegav1beta1.PravegaCluster) ([]string, error) {
+	annotation := p.Spec.Pravega.SegmentStoreRestartNodeAnnotation
+	if !config.NodeWatch || annotation == "" {
+		return nil, nil
+	}
+	podList := &corev1.PodList{}
+	listOps := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(p.LabelsForSegmentStore()),
+	}
+	err := r.client.List(context.TODO(), podList, listOps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segment store pods: %v", err)
+	}
+	entries := []string{}
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		node := &corev1.Node{}
+		err = r.clusterScoped().Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node (%s): %v", pod.Spec.NodeName, err)
+		}
+		entries = append(entries, fmt.Sprintf("Node/%s/%s=%s", pod.Name, node.Name, node.Annotations[annotation]))
+	}
+	return entries, nil
+}
@@ -0,0 +1,254 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newSteadyCluster returns a defaulted cluster with the given number of segment stores,
+// along with its ready pods. Authentication is enabled so that the operator does not
+// query the segment containers of a controller which cannot be reached.
+func newSteadyCluster(name string, segmentStores int32) (*v1beta1.PravegaCluster, []runtime.Object) {
+	p := &v1beta1.PravegaCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+	}
+	p.WithDefaults()
+	p.Spec.Pravega.SegmentStoreReplicas = segmentStores
+	p.Spec.Authentication.Enabled = true
+
+	objects := []runtime.Object{p}
+	pod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: p.Namespace, Labels: labels},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	for i := int32(0); i < p.Spec.Pravega.ControllerReplicas; i++ {
+		objects = append(objects, pod(fmt.Sprintf("%s-%d", p.DeploymentNameForController(), i), p.LabelsForController()))
+	}
+	for i := int32(0); i < segmentStores; i++ {
+		objects = append(objects, pod(fmt.Sprintf("%s-%d", p.StatefulSetNameForSegmentstore(), i), p.LabelsForSegmentStore()))
+	}
+	return p, objects
+}
+
+var _ = Describe("Reconcile fast path", func() {
+	var (
+		s      = scheme.Scheme
+		p      *v1beta1.PravegaCluster
+		name   types.NamespacedName
+		req    reconcile.Request
+		client client.Client
+		r      *ReconcilePravegaCluster
+	)
+
+	skipped := func() int {
+		state, ok := FastPath.clusters[name]
+		if !ok {
+			return -1
+		}
+		return state.skipped
+	}
+
+	reconcileCluster := func() {
+		res, err := r.Reconcile(req)
+		Ω(err).Should(BeNil())
+		Ω(res.RequeueAfter).Should(Equal(ReconcileTime))
+	}
+
+	BeforeEach(func() {
+		var objects []runtime.Object
+		p, objects = newSteadyCluster("example", 1)
+		s.AddKnownTypes(v1beta1.SchemeGroupVersion, p)
+		name = types.NamespacedName{Namespace: p.Namespace, Name: p.Name}
+		req = reconcile.Request{NamespacedName: name}
+		client = fake.NewFakeClient(objects...)
+		r = &ReconcilePravegaCluster{client: client, scheme: s}
+		config.FullReconcileInterval = 3
+		reconcileCluster()
+	})
+
+	AfterEach(func() {
+		config.FullReconcileInterval = 0
+		FastPath.Reset(name)
+	})
+
+	It("should record the full reconcile of the steady cluster", func() {
+		foundPravega := &v1beta1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), name, foundPravega)).Should(Succeed())
+		Ω(foundPravega.Status.ObservedGeneration).Should(Equal(foundPravega.Generation))
+		Ω(clusterSteady(foundPravega)).Should(BeTrue())
+		Ω(skipped()).Should(Equal(0))
+	})
+
+	It("should only sync the status of the unchanged cluster", func() {
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(1))
+	})
+
+	It("should fully reconcile the cluster every full reconcile interval", func() {
+		reconcileCluster()
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(2))
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+	})
+
+	It("should fully reconcile the cluster once a child object is edited", func() {
+		deploy := &appsv1.Deployment{}
+		Ω(client.Get(context.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: p.DeploymentNameForController()}, deploy)).Should(Succeed())
+		replicas := int32(3)
+		deploy.Spec.Replicas = &replicas
+		Ω(client.Update(context.TODO(), deploy)).Should(Succeed())
+
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+		Ω(client.Get(context.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: p.DeploymentNameForController()}, deploy)).Should(Succeed())
+		Ω(*deploy.Spec.Replicas).Should(Equal(int32(1)))
+	})
+
+	It("should fully reconcile the cluster once its annotations change", func() {
+		foundPravega := &v1beta1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), name, foundPravega)).Should(Succeed())
+		foundPravega.Annotations = map[string]string{"example.com/touched": "true"}
+		Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+	})
+
+	It("should change the fingerprint once a referenced secret changes", func() {
+		p.Spec.Pravega.RunAsIdentitySecret = "identity"
+		before, err := r.clusterFingerprint(p)
+		Ω(err).Should(BeNil())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "identity", Namespace: p.Namespace},
+			Data:       map[string][]byte{"uid": []byte("1000")},
+		}
+		Ω(client.Create(context.TODO(), secret)).Should(Succeed())
+		created, err := r.clusterFingerprint(p)
+		Ω(err).Should(BeNil())
+		Ω(created).ShouldNot(Equal(before))
+
+		secret.Data["uid"] = []byte("2000")
+		Ω(client.Update(context.TODO(), secret)).Should(Succeed())
+		updated, err := r.clusterFingerprint(p)
+		Ω(err).Should(BeNil())
+		Ω(updated).ShouldNot(Equal(created))
+	})
+
+	It("should change the fingerprint once a node restart annotation changes", func() {
+		config.NodeWatch = true
+		defer func() { config.NodeWatch = false }()
+		p.Spec.Pravega.SegmentStoreRestartNodeAnnotation = "example.com/kernel"
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{"example.com/kernel": "5.4"}},
+		}
+		Ω(client.Create(context.TODO(), node)).Should(Succeed())
+		pod := &corev1.Pod{}
+		Ω(client.Get(context.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: p.StatefulSetNameForSegmentstore() + "-0"}, pod)).Should(Succeed())
+		pod.Spec.NodeName = node.Name
+		Ω(client.Update(context.TODO(), pod)).Should(Succeed())
+		before, err := r.clusterFingerprint(p)
+		Ω(err).Should(BeNil())
+
+		node.Annotations["example.com/kernel"] = "5.10"
+		Ω(client.Update(context.TODO(), node)).Should(Succeed())
+		after, err := r.clusterFingerprint(p)
+		Ω(err).Should(BeNil())
+		Ω(after).ShouldNot(Equal(before))
+	})
+
+	It("should fully reconcile the cluster while it is not steady", func() {
+		pod := &corev1.Pod{}
+		Ω(client.Get(context.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: p.StatefulSetNameForSegmentstore() + "-0"}, pod)).Should(Succeed())
+		pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		Ω(client.Update(context.TODO(), pod)).Should(Succeed())
+
+		// the status sync records the unready pod, from which the cluster is not steady
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(1))
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+	})
+
+	It("should always fully reconcile the cluster below an interval of 2", func() {
+		config.FullReconcileInterval = 1
+		reconcileCluster()
+		reconcileCluster()
+		Ω(skipped()).Should(Equal(0))
+	})
+})
+
+// benchmarkReconcile reconciles a deployed and ready cluster of the given number of segment
+// stores, with the given full reconcile interval
+func benchmarkReconcile(b *testing.B, segmentStores int32, fullReconcileInterval int) {
+	p, objects := newSteadyCluster("benchmark", segmentStores)
+	s := scheme.Scheme
+	s.AddKnownTypes(v1beta1.SchemeGroupVersion, p)
+	r := &ReconcilePravegaCluster{client: fake.NewFakeClient(objects...), scheme: s}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: p.Namespace, Name: p.Name}}
+
+	previous := config.FullReconcileInterval
+	config.FullReconcileInterval = fullReconcileInterval
+	defer func() {
+		config.FullReconcileInterval = previous
+		FastPath.Reset(req.NamespacedName)
+	}()
+
+	// deploys the cluster and records its ready status
+	res, err := r.Reconcile(req)
+	if err != nil || res.RequeueAfter != ReconcileTime {
+		b.Fatalf("failed to deploy the cluster: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Reconcile(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReconcileFull(b *testing.B) {
+	benchmarkReconcile(b, 10, 1)
+}
+
+func BenchmarkReconcileFastPath(b *testing.B) {
+	benchmarkReconcile(b, 10, math.MaxInt32)
+}
@@ -0,0 +1,171 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// HealthPath is the path of the operator health endpoint
+const HealthPath = "/healthz/clusters"
+
+// Health of a managed cluster, as reported by the operator health endpoint
+const (
+	ClusterHealthy   = "healthy"
+	ClusterDegraded  = "degraded"
+	ClusterUnhealthy = "unhealthy"
+)
+
+// ClusterHealth is the health of a managed cluster as of its last reconcile
+type ClusterHealth struct {
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	Health        string `json:"health"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+	Replicas      int32  `json:"replicas"`
+	// ReplicaGap is the number of desired pods which are not ready
+	ReplicaGap int32 `json:"replicaGap"`
+	// ScalingStalled is true while the ScalingStalled condition of the cluster is set
+	ScalingStalled bool `json:"scalingStalled"`
+}
+
+// HealthSummary is the response of the operator health endpoint
+type HealthSummary struct {
+	Healthy   int             `json:"healthy"`
+	Degraded  int             `json:"degraded"`
+	Unhealthy int             `json:"unhealthy"`
+	Clusters  []ClusterHealth `json:"clusters"`
+}
+
+// ClusterHealthStore caches the health of the managed clusters, recorded by the
+// reconciler, for the operator health endpoint
+type ClusterHealthStore struct {
+	mutex    sync.RWMutex
+	clusters map[types.NamespacedName]ClusterHealth
+}
+
+// Health is the store of the health of the clusters managed by the operator
+var Health = NewClusterHealthStore()
+
+// NewClusterHealthStore returns an empty cluster health store
+func NewClusterHealthStore() *ClusterHealthStore {
+	return &ClusterHealthStore{clusters: map[types.NamespacedName]ClusterHealth{}}
+}
+
+// Set records the health of the cluster from its status
+func (s *ClusterHealthStore) Set(p *pravegav1beta1.PravegaCluster) {
+	health := ClusterHealth{
+		Namespace:     p.Namespace,
+		Name:          p.Name,
+		Health:        clusterHealth(&p.Status),
+		ReadyReplicas: p.Status.ReadyReplicas,
+		Replicas:      p.Status.Replicas,
+	}
+	if p.Status.ReadyReplicas < p.Status.Replicas {
+		health.ReplicaGap = p.Status.Replicas - p.Status.ReadyReplicas
+	}
+	if _, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionScalingStalled); condition != nil {
+		health.ScalingStalled = condition.Status == corev1.ConditionTrue
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clusters[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}] = health
+}
+
+// Delete forgets the health of a cluster that no longer exists
+func (s *ClusterHealthStore) Delete(name types.NamespacedName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.clusters, name)
+}
+
+// Summary counts the clusters per health, and lists them sorted by namespace and name
+func (s *ClusterHealthStore) Summary() HealthSummary {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	summary := HealthSummary{Clusters: []ClusterHealth{}}
+	for _, health := range s.clusters {
+		switch health.Health {
+		case ClusterHealthy:
+			summary.Healthy++
+		case ClusterDegraded:
+			summary.Degraded++
+		default:
+			summary.Unhealthy++
+		}
+		summary.Clusters = append(summary.Clusters, health)
+	}
+	sort.Slice(summary.Clusters, func(i, j int) bool {
+		if summary.Clusters[i].Namespace != summary.Clusters[j].Namespace {
+			return summary.Clusters[i].Namespace < summary.Clusters[j].Namespace
+		}
+		return summary.Clusters[i].Name < summary.Clusters[j].Name
+	})
+	return summary
+}
+
+// ServeHTTP returns the health summary of the managed clusters as JSON
+func (s *ClusterHealthStore) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(s.Summary())
+	if err != nil {
+		log.Printf("failed to write the cluster health summary: %v", err)
+	}
+}
+
+// HealthServer returns a runnable serving the health endpoint of the store on the
+// given address until the manager stops
+func (s *ClusterHealthStore) HealthServer(addr string) manager.Runnable {
+	return manager.RunnableFunc(func(stop <-chan struct{}) error {
+		mux := http.NewServeMux()
+		mux.Handle(HealthPath, s)
+		server := &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			<-stop
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(ctx)
+		}()
+		log.Printf("serving the cluster health on %s%s", addr, HealthPath)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+}
+
+// clusterHealth classifies a cluster from its status. A cluster is unhealthy when in
+// error, when its long term storage is unreachable or when none of its pods is ready, and
+// degraded while some of its pods are not ready or while it is upgrading or rolling back.
+func clusterHealth(status *pravegav1beta1.ClusterStatus) string {
+	if status.IsClusterInErrorState() || status.IsLtsUnreachable() || (status.Replicas > 0 && status.ReadyReplicas == 0) {
+		return ClusterUnhealthy
+	}
+	if !status.IsClusterInReadyState() || status.IsClusterInUpgradingState() || status.IsClusterInRollbackState() {
+		return ClusterDegraded
+	}
+	return ClusterHealthy
+}
@@ -0,0 +1,102 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster health endpoint", func() {
+	var (
+		store    *ClusterHealthStore
+		response *httptest.ResponseRecorder
+		summary  HealthSummary
+	)
+
+	newCluster := func(name string, readyReplicas int32) *v1beta1.PravegaCluster {
+		p := &v1beta1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+		}
+		p.Status.Init()
+		p.Status.Replicas = 3
+		p.Status.ReadyReplicas = readyReplicas
+		if readyReplicas == 3 {
+			p.Status.SetPodsReadyConditionTrue()
+		}
+		return p
+	}
+
+	BeforeEach(func() {
+		store = NewClusterHealthStore()
+		store.Set(newCluster("ready", 3))
+		store.Set(newCluster("partially-ready", 2))
+		store.Set(newCluster("down", 0))
+		failed := newCluster("failed", 3)
+		failed.Status.SetErrorConditionTrue("UpgradeFailed", "")
+		store.Set(failed)
+		store.Set(newCluster("deleted", 3))
+		store.Delete(types.NamespacedName{Namespace: "default", Name: "deleted"})
+
+		response = httptest.NewRecorder()
+		store.ServeHTTP(response, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+		summary = HealthSummary{}
+		_ = json.Unmarshal(response.Body.Bytes(), &summary)
+	})
+
+	It("should return the summary as JSON", func() {
+		Ω(response.Code).Should(Equal(http.StatusOK))
+		Ω(response.Header().Get("Content-Type")).Should(Equal("application/json"))
+	})
+
+	It("should count the clusters per health", func() {
+		Ω(summary.Healthy).Should(Equal(1))
+		Ω(summary.Degraded).Should(Equal(1))
+		Ω(summary.Unhealthy).Should(Equal(2))
+	})
+
+	It("should list the clusters sorted by name", func() {
+		Ω(summary.Clusters).Should(HaveLen(4))
+		Ω(summary.Clusters[0].Name).Should(Equal("down"))
+		Ω(summary.Clusters[0].Health).Should(Equal(ClusterUnhealthy))
+		Ω(summary.Clusters[2].Name).Should(Equal("partially-ready"))
+		Ω(summary.Clusters[2].Health).Should(Equal(ClusterDegraded))
+		Ω(summary.Clusters[2].ReadyReplicas).Should(Equal(int32(2)))
+	})
+
+	It("should report the replica gap and the stalled scalings", func() {
+		Ω(summary.Clusters[2].ReplicaGap).Should(Equal(int32(1)))
+		Ω(summary.Clusters[2].ScalingStalled).Should(BeFalse())
+		Ω(summary.Clusters[3].ReplicaGap).Should(Equal(int32(0)))
+
+		stalled := newCluster("partially-ready", 2)
+		stalled.Status.SetScalingStalledConditionTrue(v1beta1.PodsPendingReason, "")
+		store.Set(stalled)
+		Ω(store.Summary().Clusters[2].ScalingStalled).Should(BeTrue())
+	})
+
+	It("should reject other methods than GET", func() {
+		response = httptest.NewRecorder()
+		store.ServeHTTP(response, httptest.NewRequest(http.MethodPost, HealthPath, nil))
+		Ω(response.Code).Should(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -12,27 +12,41 @@ package pravegacluster
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
 	"github.com/pravega/pravega-operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -42,6 +56,54 @@ import (
 // ReconcileTime is the delay between reconciliations
 const ReconcileTime = 30 * time.Second
 
+// FailedPodThreshold is how long a member can stay unready before it is reported as
+// failed. It exceeds the 5 minutes a segment store may take to become ready.
+const FailedPodThreshold = 10 * time.Minute
+
+// nodeAnnotationValueKey is the segment store pod annotation recording the value of
+// the restart node annotation when the pod started
+const nodeAnnotationValueKey = "pravega.nodeAnnotationValue"
+
+// Annotations of the cluster ready event summarizing the cluster
+const (
+	clusterReadyVersionAnnotation              = "pravega.version"
+	clusterReadyControllerReplicasAnnotation   = "pravega.controllerReplicas"
+	clusterReadySegmentStoreReplicasAnnotation = "pravega.segmentStoreReplicas"
+)
+
+// Keys of the runAsIdentitySecret holding the user and group IDs of the containers
+const (
+	runAsUserKey  = "runAsUser"
+	runAsGroupKey = "runAsGroup"
+)
+
+// externalServicesFinalizer is the finalizer deleting the external services of a cluster
+// being deleted
+const externalServicesFinalizer = "cleanUpExternalServices"
+
+// cacheClaimTemplateName is the name of the cache volume claim template of the segment
+// store stateful set, for Pravega versions below 0.7
+const cacheClaimTemplateName = "cache"
+
+// segmentStoreWriteBytesMetric is the segment store counter of the bytes written,
+// without the metrics prefix of the cluster
+const segmentStoreWriteBytesMetric = "segmentstore_segment_write_bytes_total"
+
+// segmentStoreMetricsPort is the port the segment store metrics are scraped on
+var segmentStoreMetricsPort = util.SegmentStoreRESTPort
+
+// imageExists checks that an image exists in its registry
+var imageExists = util.ImageExists
+
+// probeBucket and probeAddress check that the long term storage can be reached
+var (
+	probeBucket  = util.ProbeBucket
+	probeAddress = util.ProbeAddress
+)
+
+// defaultHdfsPort is the port of the HDFS name node when its uri has none
+const defaultHdfsPort = "8020"
+
 // Add creates a new PravegaCluster Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -50,7 +112,18 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcilePravegaCluster{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcilePravegaCluster{client: mgr.GetClient(), clusterReader: clusterScopedReader(mgr), scheme: mgr.GetScheme()}
+}
+
+// clusterScopedReader returns the reader of the cluster-scoped objects, i.e. the nodes
+// and the storage classes. The cache of several namespaces cannot get them, having no
+// cache for the empty namespace, and lists them once per namespace, so they are read
+// from the API server then
+func clusterScopedReader(mgr manager.Manager) client.Reader {
+	if len(config.WatchNamespaces) > 1 {
+		return mgr.GetAPIReader()
+	}
+	return mgr.GetClient()
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -62,14 +135,96 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 
 	// Watch for changes to primary resource PravegaCluster
-	err = c.Watch(&source.Kind{Type: &pravegav1beta1.PravegaCluster{}}, &handler.EnqueueRequestForObject{})
+	err = c.Watch(&source.Kind{Type: &pravegav1beta1.PravegaCluster{}}, &handler.EnqueueRequestForObject{}, inWatchedNamespaces)
 	if err != nil {
 		return err
 	}
 
+	if config.NodeWatch {
+		// Watch for annotation changes on nodes, so that segment stores can be
+		// restarted as soon as the restart node annotation changes
+		err = c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+				return clustersRestartingOnNodeAnnotation(mgr.GetClient())
+			}),
+		}, nodeAnnotationsChanged)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// inWatchedNamespaces filters the events to the objects of the watched namespaces. The
+// cache is already scoped to them, this guards against clusters read from elsewhere.
+var inWatchedNamespaces = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return namespaceWatched(e.Meta.GetNamespace())
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return namespaceWatched(e.Meta.GetNamespace())
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return namespaceWatched(e.Meta.GetNamespace())
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return namespaceWatched(e.MetaNew.GetNamespace())
+	},
+}
+
+// namespaceWatched returns true if the clusters of the namespace are reconciled
+func namespaceWatched(namespace string) bool {
+	if len(config.WatchNamespaces) == 0 {
+		return true
+	}
+	for _, watched := range config.WatchNamespaces {
+		if namespace == watched {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeAnnotationsChanged filters the node events to the updates of their annotations
+var nodeAnnotationsChanged = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return false
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return false
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+	},
+}
+
+// clustersRestartingOnNodeAnnotation returns the reconcile requests of the clusters
+// that restart their segment stores on node annotation changes
+func clustersRestartingOnNodeAnnotation(c client.Client) []reconcile.Request {
+	clusterList := &pravegav1beta1.PravegaClusterList{}
+	err := c.List(context.TODO(), clusterList)
+	if err != nil {
+		log.Printf("failed to list pravega clusters: %v", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cluster := range clusterList.Items {
+		if !namespaceWatched(cluster.Namespace) {
+			continue
+		}
+		if cluster.Spec.Pravega != nil && cluster.Spec.Pravega.SegmentStoreRestartNodeAnnotation != "" {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
 var _ reconcile.Reconciler = &ReconcilePravegaCluster{}
 
 // ReconcilePravegaCluster reconciles a PravegaCluster object
@@ -77,7 +232,17 @@ type ReconcilePravegaCluster struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
-	scheme *runtime.Scheme
+	// clusterReader reads the cluster-scoped objects, see clusterScopedReader. The client
+	// is used when it is not set
+	clusterReader client.Reader
+	scheme        *runtime.Scheme
+}
+
+func (r *ReconcilePravegaCluster) clusterScoped() client.Reader {
+	if r.clusterReader == nil {
+		return r.client
+	}
+	return r.clusterReader
 }
 
 // Reconcile reads that state of the cluster for a PravegaCluster object and makes changes based on the state read
@@ -97,6 +262,10 @@ func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (reconcil
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
 			log.Printf("PravegaCluster %s/%s not found. Ignoring since object must be deleted\n", request.Namespace, request.Name)
+			Health.Delete(request.NamespacedName)
+			Backoff.Reset(request.NamespacedName)
+			FastPath.Reset(request.NamespacedName)
+			forgetSegmentContainerSync(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -105,6 +274,7 @@ func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (reconcil
 	}
 
 	// Set default configuration for unspecified values
+	scaledToZero := segmentStoresScaledToZero(pravegaCluster)
 	changed := pravegaCluster.WithDefaults()
 	if changed {
 		log.Printf("Setting default settings for pravega-cluster: %s", request.Name)
@@ -112,28 +282,68 @@ func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (reconcil
 			log.Printf("Error applying defaults on Pravega Cluster %v", err)
 			return reconcile.Result{}, err
 		}
+		if scaledToZero {
+			r.publishZeroSegmentStoresEvent(pravegaCluster)
+		}
 		return reconcile.Result{Requeue: true}, nil
 	}
 
-	err = r.run(pravegaCluster)
-	if err != nil {
-		log.Printf("failed to reconcile pravega cluster (%s): %v", pravegaCluster.Name, err)
-		return reconcile.Result{}, err
+	if r.canSkipFullReconcile(pravegaCluster) {
+		log.Printf("PravegaCluster %s/%s unchanged and healthy, only syncing its status\n", request.Namespace, request.Name)
+		err = r.reconcileClusterStatus(pravegaCluster)
+	} else {
+		err = r.run(pravegaCluster)
+		if err == nil {
+			r.recordFullReconcile(pravegaCluster)
+		}
 	}
+	Health.Set(pravegaCluster)
+	if err != nil {
+		FastPath.Reset(request.NamespacedName)
+		r.recordComponentReconcileTimes(pravegaCluster)
+		// The error is not returned, as the rate limiter of the controller would then
+		// ignore the delay, so that a transient failure does not requeue immediately
+		delay := Backoff.Next(request.NamespacedName)
+		log.Printf("failed to reconcile pravega cluster (%s), retrying in %v: %v", pravegaCluster.Name, delay, err)
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	Backoff.Reset(request.NamespacedName)
 	return reconcile.Result{RequeueAfter: ReconcileTime}, nil
 }
 
 func (r *ReconcilePravegaCluster) run(p *pravegav1beta1.PravegaCluster) (err error) {
 
+	// The upgrading and scaling phases last across reconciles, until the upgrade is over
+	// or all the desired pods are ready
+	if p.Status.ReconcilePhase == "" || p.Status.ReconcilePhase == pravegav1beta1.ReconcilePhaseIdle {
+		p.Status.ReconcilePhase = pravegav1beta1.ReconcilePhaseValidating
+	}
+
 	err = r.reconcileFinalizers(p)
 	if err != nil {
 		return fmt.Errorf("failed to reconcile finalizers %v", err)
 	}
+	if !p.DeletionTimestamp.IsZero() {
+		// the resources of a cluster being deleted must not be recreated
+		return nil
+	}
 
 	err = r.reconcileConfigMap(p)
 	if err != nil {
 		return fmt.Errorf("failed to reconcile configMap %v", err)
 	}
+	p.Status.SetComponentReconciled(pravegav1beta1.ComponentConfigMaps, time.Now())
+	r.syncSegmentStoreContainerCount(p)
+
+	err = r.reconcileRenderedConfigMap(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile rendered configMap %v", err)
+	}
+
+	err = r.reconcileGrafanaDashboard(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile grafana dashboard %v", err)
+	}
 
 	err = r.reconcilePdb(p)
 	if err != nil {
@@ -144,12 +354,35 @@ func (r *ReconcilePravegaCluster) run(p *pravegav1beta1.PravegaCluster) (err err
 	if err != nil {
 		return fmt.Errorf("failed to reconcile service %v", err)
 	}
+	p.Status.SetComponentReconciled(pravegav1beta1.ComponentServices, time.Now())
+
+	err = r.reconcileServiceMonitor(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile service monitor %v", err)
+	}
+
+	r.checkPravegaImage(p)
+
+	r.checkStorageClasses(p)
+
+	err = r.reconcileRunAsIdentity(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile run as identity: %v", err)
+	}
+
+	err = r.reconcileTLSSecretHashes(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile tls secret hashes: %v", err)
+	}
 
 	err = r.deployCluster(p)
 	if err != nil {
 		return fmt.Errorf("failed to deploy cluster: %v", err)
 	}
 
+	// The deferred actions are recorded again below as long as they are deferred
+	p.Status.Maintenance = nil
+
 	err = r.syncClusterSize(p)
 	if err != nil {
 		return fmt.Errorf("failed to sync cluster size: %v", err)
@@ -167,6 +400,13 @@ func (r *ReconcilePravegaCluster) run(p *pravegav1beta1.PravegaCluster) (err err
 		return fmt.Errorf("Rollback attempt failed: %v", err)
 	}
 
+	err = r.syncNodeAnnotationRestart(p)
+	if err != nil {
+		return fmt.Errorf("failed to restart segment stores on node annotation change: %v", err)
+	}
+
+	// The child objects are reconciled with the current spec
+	p.Status.ObservedGeneration = p.Generation
 	err = r.reconcileClusterStatus(p)
 	if err != nil {
 		return fmt.Errorf("failed to reconcile cluster status: %v", err)
@@ -174,15 +414,64 @@ func (r *ReconcilePravegaCluster) run(p *pravegav1beta1.PravegaCluster) (err err
 	return nil
 }
 
+// recordComponentReconcileTimes saves the reconcile times of the components reconciled
+// before a failure, and the phase the reconcile failed in, which are otherwise saved
+// along with the cluster status, so that the component failing to reconcile stands out
+func (r *ReconcilePravegaCluster) recordComponentReconcileTimes(p *pravegav1beta1.PravegaCluster) {
+	current := &pravegav1beta1.PravegaCluster{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.Name, Namespace: p.Namespace}, current)
+	if err != nil {
+		log.Printf("failed to get cluster (%s) to record the component reconcile times: %v", p.Name, err)
+		return
+	}
+	current.Status.ComponentReconcileTimes = p.Status.ComponentReconcileTimes
+	current.Status.ReconcilePhase = p.Status.ReconcilePhase
+	err = r.client.Status().Update(context.TODO(), current)
+	if err != nil {
+		log.Printf("failed to record the component reconcile times of cluster (%s): %v", p.Name, err)
+	}
+}
+
+// setReconcilePhase enters one of the long-running phases, upgrading or scaling, which
+// last across reconciles. The phase is saved right away when it changes, so that it can
+// be observed while the stage is in progress, and is otherwise saved along with the
+// cluster status.
+func (r *ReconcilePravegaCluster) setReconcilePhase(p *pravegav1beta1.PravegaCluster, phase string) {
+	if p.Status.ReconcilePhase == phase {
+		return
+	}
+	p.Status.ReconcilePhase = phase
+	err := r.client.Status().Update(context.TODO(), p)
+	if err != nil {
+		log.Printf("failed to update reconcile phase of cluster (%s) to %s: %v", p.Name, phase, err)
+	}
+}
+
 func (r *ReconcilePravegaCluster) reconcileFinalizers(p *pravegav1beta1.PravegaCluster) (err error) {
 	if p.DeletionTimestamp.IsZero() {
-		if !util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
-			p.ObjectMeta.Finalizers = append(p.ObjectMeta.Finalizers, util.ZkFinalizer)
+		count := len(p.ObjectMeta.Finalizers)
+		for _, finalizer := range []string{util.ZkFinalizer, externalServicesFinalizer} {
+			if !util.ContainsString(p.ObjectMeta.Finalizers, finalizer) {
+				p.ObjectMeta.Finalizers = append(p.ObjectMeta.Finalizers, finalizer)
+			}
+		}
+		if len(p.ObjectMeta.Finalizers) != count {
 			if err = r.client.Update(context.TODO(), p); err != nil {
 				return fmt.Errorf("failed to add the finalizer (%s): %v", p.Name, err)
 			}
 		}
 	} else {
+		if util.ContainsString(p.ObjectMeta.Finalizers, externalServicesFinalizer) {
+			// the finalizer is only removed once all the services are gone, so that a
+			// partial deletion is completed by the next reconcile
+			if err = r.deleteExternalServices(p); err != nil {
+				return fmt.Errorf("failed to delete the external services (%s): %v", p.Name, err)
+			}
+			p.ObjectMeta.Finalizers = util.RemoveString(p.ObjectMeta.Finalizers, externalServicesFinalizer)
+			if err = r.client.Update(context.TODO(), p); err != nil {
+				return fmt.Errorf("failed to update Pravega object (%s): %v", p.Name, err)
+			}
+		}
 		if util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
 			p.ObjectMeta.Finalizers = util.RemoveString(p.ObjectMeta.Finalizers, util.ZkFinalizer)
 			if err = r.client.Update(context.TODO(), p); err != nil {
@@ -203,8 +492,36 @@ func (r *ReconcilePravegaCluster) reconcileFinalizers(p *pravegav1beta1.PravegaC
 	return nil
 }
 
+// deleteExternalServices deletes the LoadBalancer and NodePort services of the cluster,
+// so that their cloud load balancers are released along with the cluster. Services
+// already deleted are skipped
+func (r *ReconcilePravegaCluster) deleteExternalServices(p *pravegav1beta1.PravegaCluster) error {
+	serviceList := &corev1.ServiceList{}
+	listOps := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(p.LabelsForPravegaCluster()),
+	}
+	err := r.client.List(context.TODO(), serviceList, listOps)
+	if err != nil {
+		return err
+	}
+	for i := range serviceList.Items {
+		svc := &serviceList.Items[i]
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer && svc.Spec.Type != corev1.ServiceTypeNodePort {
+			continue
+		}
+		err = r.client.Delete(context.TODO(), svc)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete svc (%s): %v", svc.Name, err)
+		}
+	}
+	return nil
+}
+
 func (r *ReconcilePravegaCluster) reconcileConfigMap(p *pravegav1beta1.PravegaCluster) (err error) {
 
+	syncConfigMapReconcileIgnoredCondition(p)
+
 	err = r.reconcileControllerConfigMap(p)
 	if err != nil {
 		return err
@@ -219,6 +536,55 @@ func (r *ReconcilePravegaCluster) reconcileConfigMap(p *pravegav1beta1.PravegaCl
 
 }
 
+// syncSegmentStoreContainerCount records the number of segment containers of the spec in
+// the status, and publishes a warning event when it changes on a deployed cluster, which
+// Pravega does not support. The webhook rejects the change, but it may be disabled.
+func (r *ReconcilePravegaCluster) syncSegmentStoreContainerCount(p *pravegav1beta1.PravegaCluster) {
+	count := int32(0)
+	if p.Spec.Pravega.SegmentStoreContainerCount != nil {
+		count = *p.Spec.Pravega.SegmentStoreContainerCount
+	}
+	previous := p.Status.SegmentStoreContainerCount
+	p.Status.SegmentStoreContainerCount = count
+	if count == previous || (previous == 0 && p.Status.CurrentVersion == "") {
+		return
+	}
+	from, to := fmt.Sprint(previous), fmt.Sprint(count)
+	if previous == 0 {
+		from = "the default"
+	}
+	if count == 0 {
+		to = "the default"
+	}
+	message := fmt.Sprintf("segmentStoreContainerCount changed from %s to %s, which Pravega does not support on an existing cluster", from, to)
+	log.Printf("cluster (%s): %s", p.Name, message)
+	event := p.NewEvent("CONTAINER_COUNT_CHANGED", pravegav1beta1.ContainerCountChangedReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing container count changed event to k8s. %v", err)
+	}
+}
+
+// syncConfigMapReconcileIgnoredCondition sets the ConfigMapReconcileIgnored condition while the
+// configmap reconcile policy is Ignore, and clears it once the policy is Enforce again
+func syncConfigMapReconcileIgnoredCondition(p *pravegav1beta1.PravegaCluster) {
+	if configMapReconcileIgnored(p) {
+		p.Status.SetConfigMapReconcileIgnoredConditionTrue(pravegav1beta1.ConfigMapReconcilePolicyIgnoreReason,
+			"manual edits of the controller and segment store configmaps are not overwritten")
+		return
+	}
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionConfigMapReconcileIgnored)
+	if condition != nil && condition.Status == corev1.ConditionTrue {
+		p.Status.SetConfigMapReconcileIgnoredConditionFalse()
+	}
+}
+
+// configMapReconcileIgnored returns true if the configmaps must be left as they are
+// once created
+func configMapReconcileIgnored(p *pravegav1beta1.PravegaCluster) bool {
+	return p.Spec.Pravega.ConfigMapReconcilePolicy == pravegav1beta1.ConfigMapReconcilePolicyIgnore
+}
+
 func (r *ReconcilePravegaCluster) reconcileControllerConfigMap(p *pravegav1beta1.PravegaCluster) (err error) {
 
 	currentConfigMap := &corev1.ConfigMap{}
@@ -237,16 +603,126 @@ func (r *ReconcilePravegaCluster) reconcileControllerConfigMap(p *pravegav1beta1
 		err = r.client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForController(), Namespace: p.Namespace}, currentConfigMap)
 		eq := util.CompareConfigMap(currentConfigMap, configMap)
 		if !eq {
+			if configMapReconcileIgnored(p) {
+				log.Printf("leaving configmap (%s) as edited, the configmap reconcile policy is %s", configMap.Name, pravegav1beta1.ConfigMapReconcilePolicyIgnore)
+				return nil
+			}
 			err := r.client.Update(context.TODO(), configMap)
 			if err != nil {
 				return err
 			}
-			//restarting controller pods
-			err = r.restartDeploymentPod(p)
-			if err != nil {
-				return err
+			// The new configmap hash rolls the controller pods, which get the resources
+			// matching the new JVM options
+			return r.syncControllerPodTemplate(p, true)
+		}
+	}
+	return nil
+}
+
+// reconcileRenderedConfigMap creates the ConfigMap holding the configuration rendered
+// for the Controller and the Segment Store, and overwrites it on every reconcile, so that
+// it reflects the current spec and any edit of the ConfigMap is reverted
+func (r *ReconcilePravegaCluster) reconcileRenderedConfigMap(p *pravegav1beta1.PravegaCluster) (err error) {
+	configMap := pravega.MakeRenderedConfigMap(p)
+	controllerutil.SetControllerReference(p, configMap, r.scheme)
+	currentConfigMap := &corev1.ConfigMap{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: configMap.Name, Namespace: p.Namespace}, currentConfigMap)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		err = r.client.Create(context.TODO(), configMap)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create rendered configmap (%s): %v", configMap.Name, err)
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(currentConfigMap.Data, configMap.Data) || !reflect.DeepEqual(currentConfigMap.Labels, configMap.Labels) {
+		currentConfigMap.Data = configMap.Data
+		currentConfigMap.Labels = configMap.Labels
+		err = r.client.Update(context.TODO(), currentConfigMap)
+		if err != nil {
+			return fmt.Errorf("failed to update rendered configmap (%s): %v", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileGrafanaDashboard creates the ConfigMap holding the Grafana dashboard of
+// the cluster, and keeps it up to date, when enabled in the operator
+func (r *ReconcilePravegaCluster) reconcileGrafanaDashboard(p *pravegav1beta1.PravegaCluster) (err error) {
+	if !config.GrafanaDashboard {
+		return nil
+	}
+	configMap := pravega.MakeGrafanaDashboardConfigMap(p)
+	controllerutil.SetControllerReference(p, configMap, r.scheme)
+	currentConfigMap := &corev1.ConfigMap{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: configMap.Name, Namespace: p.Namespace}, currentConfigMap)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		err = r.client.Create(context.TODO(), configMap)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create grafana dashboard configmap (%s): %v", configMap.Name, err)
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(currentConfigMap.Data, configMap.Data) || !reflect.DeepEqual(currentConfigMap.Labels, configMap.Labels) {
+		currentConfigMap.Data = configMap.Data
+		currentConfigMap.Labels = configMap.Labels
+		err = r.client.Update(context.TODO(), currentConfigMap)
+		if err != nil {
+			return fmt.Errorf("failed to update grafana dashboard configmap (%s): %v", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileServiceMonitor creates or updates the ServiceMonitor of the cluster if it
+// is enabled, and deletes it otherwise. Nothing is done if the ServiceMonitor CRD of
+// the Prometheus Operator is not installed.
+func (r *ReconcilePravegaCluster) reconcileServiceMonitor(p *pravegav1beta1.PravegaCluster) (err error) {
+	currentMonitor := &unstructured.Unstructured{}
+	currentMonitor.SetGroupVersionKind(pravega.ServiceMonitorGVK)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceMonitorName(), Namespace: p.Namespace}, currentMonitor)
+	if meta.IsNoMatchError(err) {
+		if p.Spec.Pravega.Metrics != nil && p.Spec.Pravega.Metrics.ServiceMonitor.IsEnabled() {
+			log.Printf("cannot create the service monitor of cluster (%s): the monitoring.coreos.com CRDs are not installed", p.Name)
+		}
+		return nil
+	}
+	found := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get service monitor (%s): %v", p.ServiceMonitorName(), err)
+	}
+
+	if p.Spec.Pravega.Metrics == nil || !p.Spec.Pravega.Metrics.ServiceMonitor.IsEnabled() {
+		if found {
+			err = r.client.Delete(context.TODO(), currentMonitor)
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete service monitor (%s): %v", currentMonitor.GetName(), err)
 			}
 		}
+		return nil
+	}
+
+	monitor := pravega.MakeServiceMonitor(p)
+	controllerutil.SetControllerReference(p, monitor, r.scheme)
+	if !found {
+		err = r.client.Create(context.TODO(), monitor)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create service monitor (%s): %v", monitor.GetName(), err)
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(currentMonitor.Object["spec"], monitor.Object["spec"]) || !reflect.DeepEqual(currentMonitor.GetLabels(), monitor.GetLabels()) {
+		currentMonitor.Object["spec"] = monitor.Object["spec"]
+		currentMonitor.SetLabels(monitor.GetLabels())
+		err = r.client.Update(context.TODO(), currentMonitor)
+		if err != nil {
+			return fmt.Errorf("failed to update service monitor (%s): %v", currentMonitor.GetName(), err)
+		}
 	}
 	return nil
 }
@@ -269,21 +745,32 @@ func (r *ReconcilePravegaCluster) reconcileSegmentStoreConfigMap(p *pravegav1bet
 		err = r.client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForSegmentstore(), Namespace: p.Namespace}, currentConfigMap)
 		eq := util.CompareConfigMap(currentConfigMap, configMap)
 		if !eq {
-			err := r.client.Update(context.TODO(), configMap)
-			if err != nil {
-				return err
+			if configMapReconcileIgnored(p) {
+				log.Printf("leaving configmap (%s) as edited, the configmap reconcile policy is %s", configMap.Name, pravegav1beta1.ConfigMapReconcilePolicyIgnore)
+				return nil
 			}
-			//restarting sts pods
-			err = r.restartStsPod(p)
+			err := r.client.Update(context.TODO(), configMap)
 			if err != nil {
 				return err
 			}
+			// The new configmap hash restarts the segment store pods, which get the
+			// resources matching the new JVM options
+			return r.syncSegmentStorePodTemplate(p, true)
 		}
 	}
 	return nil
 }
 
 func (r *ReconcilePravegaCluster) reconcilePdb(p *pravegav1beta1.PravegaCluster) (err error) {
+	if p.Spec.Pravega.DisablePdb {
+		for _, name := range []string{p.PdbNameForController(), p.PdbNameForSegmentstore()} {
+			err = r.deletePdb(p, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	err = r.reconcileControllerPdb(p)
 	if err != nil {
@@ -299,6 +786,28 @@ func (r *ReconcilePravegaCluster) reconcilePdb(p *pravegav1beta1.PravegaCluster)
 
 }
 
+// deletePdb deletes the pod disruption budget with the given name, if it exists and was
+// created by the operator for the cluster
+func (r *ReconcilePravegaCluster) deletePdb(p *pravegav1beta1.PravegaCluster, name string) error {
+	pdb := &policyv1beta1.PodDisruptionBudget{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, pdb)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get pdb (%s): %v", name, err)
+	}
+	if !metav1.IsControlledBy(pdb, p) {
+		return nil
+	}
+	log.Printf("deleting pdb (%s) as the pod disruption budgets are disabled", name)
+	err = r.client.Delete(context.TODO(), pdb)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pdb (%s): %v", name, err)
+	}
+	return nil
+}
+
 func (r *ReconcilePravegaCluster) reconcileControllerPdb(p *pravegav1beta1.PravegaCluster) (err error) {
 
 	pdb := pravega.MakeControllerPodDisruptionBudget(p)
@@ -314,9 +823,29 @@ func (r *ReconcilePravegaCluster) reconcileSegmentStorePdb(p *pravegav1beta1.Pra
 	pdb := pravega.MakeSegmentstorePodDisruptionBudget(p)
 	controllerutil.SetControllerReference(p, pdb, r.scheme)
 	err = r.client.Create(context.TODO(), pdb)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	if err == nil {
+		return nil
+	}
+	if !errors.IsAlreadyExists(err) {
 		return err
 	}
+
+	// The budget changes with the number of replicas, while a rebalance is in progress
+	// and with the budget of the spec
+	currentPdb := &policyv1beta1.PodDisruptionBudget{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: pdb.Name, Namespace: p.Namespace}, currentPdb)
+	if err != nil {
+		return fmt.Errorf("failed to get pdb (%s): %v", pdb.Name, err)
+	}
+	if !reflect.DeepEqual(currentPdb.Spec.MinAvailable, pdb.Spec.MinAvailable) || !reflect.DeepEqual(currentPdb.Spec.MaxUnavailable, pdb.Spec.MaxUnavailable) {
+		log.Printf("updating pdb (%s) to min available %v and max unavailable %v", pdb.Name, pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable)
+		currentPdb.Spec.MinAvailable = pdb.Spec.MinAvailable
+		currentPdb.Spec.MaxUnavailable = pdb.Spec.MaxUnavailable
+		err = r.client.Update(context.TODO(), currentPdb)
+		if err != nil {
+			return fmt.Errorf("failed to update pdb (%s): %v", pdb.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -344,34 +873,275 @@ func (r *ReconcilePravegaCluster) reconcileControllerService(p *pravegav1beta1.P
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return err
 	}
+	if err == nil {
+		return nil
+	}
+
+	currentService := &corev1.Service{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: p.Namespace}, currentService)
+	if err != nil {
+		return fmt.Errorf("failed to get service (%s): %v", service.Name, err)
+	}
+	if service.Spec.ExternalTrafficPolicy != "" {
+		err = r.syncExternalTrafficPolicy(currentService, service)
+		if err != nil {
+			return err
+		}
+	}
+	return r.syncLoadBalancerTags(currentService, service)
+}
 
+// syncLoadBalancerTags updates the annotation tagging the cloud load balancer of an
+// existing external service in place, and removes it once the tags are cleared
+func (r *ReconcilePravegaCluster) syncLoadBalancerTags(currentService *corev1.Service, service *corev1.Service) error {
+	key := pravega.LoadBalancerTagsAnnotationKey
+	current, currentOk := currentService.Annotations[key]
+	desired, desiredOk := service.Annotations[key]
+	if current == desired && currentOk == desiredOk {
+		return nil
+	}
+	log.Printf("updating load balancer tags of service (%s) to '%s'", currentService.Name, desired)
+	if desiredOk {
+		if currentService.Annotations == nil {
+			currentService.Annotations = map[string]string{}
+		}
+		currentService.Annotations[key] = desired
+	} else {
+		delete(currentService.Annotations, key)
+	}
+	err := r.client.Update(context.TODO(), currentService)
+	if err != nil {
+		return fmt.Errorf("failed to update service (%s): %v", currentService.Name, err)
+	}
 	return nil
 }
 
-func (r *ReconcilePravegaCluster) reconcileSegmentStoreService(p *pravegav1beta1.PravegaCluster) (err error) {
+// syncExternalTrafficPolicy updates the external traffic policy of an existing external
+// service in place, the service and the pods behind it are not recreated
+func (r *ReconcilePravegaCluster) syncExternalTrafficPolicy(currentService *corev1.Service, service *corev1.Service) error {
+	if currentService.Spec.ExternalTrafficPolicy == service.Spec.ExternalTrafficPolicy {
+		return nil
+	}
+	log.Printf("updating external traffic policy of service (%s) from %s to %s", currentService.Name,
+		currentService.Spec.ExternalTrafficPolicy, service.Spec.ExternalTrafficPolicy)
+	currentService.Spec.ExternalTrafficPolicy = service.Spec.ExternalTrafficPolicy
+	err := r.client.Update(context.TODO(), currentService)
+	if err != nil {
+		return fmt.Errorf("failed to update service (%s): %v", currentService.Name, err)
+	}
+	return nil
+}
+
+// syncExternalServicePort updates the port of the external service, keeping the node
+// port allocated to it
+func (r *ReconcilePravegaCluster) syncExternalServicePort(currentService *corev1.Service, service *corev1.Service) error {
+	if len(currentService.Spec.Ports) == 0 || currentService.Spec.Ports[0].Port == service.Spec.Ports[0].Port {
+		return nil
+	}
+	log.Printf("updating port of service (%s) from %d to %d", currentService.Name,
+		currentService.Spec.Ports[0].Port, service.Spec.Ports[0].Port)
+	currentService.Spec.Ports[0].Port = service.Spec.Ports[0].Port
+	err := r.client.Update(context.TODO(), currentService)
+	if err != nil {
+		return fmt.Errorf("failed to update service (%s): %v", currentService.Name, err)
+	}
+	return nil
+}
+
+// reconcileSegmentStoreHeadlessService creates the segment store headless service and
+// applies the user supplied annotations to it. The keys of these annotations are recorded
+// on the service, so that the keys removed from the spec are removed from the service,
+// while the annotations set by others, e.g. a service mesh, are kept.
+func (r *ReconcilePravegaCluster) reconcileSegmentStoreHeadlessService(p *pravegav1beta1.PravegaCluster) (err error) {
 	headlessService := pravega.MakeSegmentStoreHeadlessService(p)
 	controllerutil.SetControllerReference(p, headlessService, r.scheme)
-	err = r.client.Create(context.TODO(), headlessService)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return err
+	currentService := &corev1.Service{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: headlessService.Name, Namespace: p.Namespace}, currentService)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		err = r.client.Create(context.TODO(), headlessService)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		r.publishAnnotationConflictEvent(p)
+		return nil
+	}
+	updated := false
+	appliedKey := pravega.AppliedAnnotationsAnnotationKey
+	keysChanged := currentService.Annotations[appliedKey] != headlessService.Annotations[appliedKey]
+	if applied := currentService.Annotations[appliedKey]; applied != "" {
+		for _, key := range strings.Split(applied, ",") {
+			if _, ok := headlessService.Annotations[key]; ok || pravega.IsOperatorManagedAnnotation(key) {
+				continue
+			}
+			if _, ok := currentService.Annotations[key]; ok {
+				delete(currentService.Annotations, key)
+				updated = true
+			}
+		}
+	}
+	if _, ok := headlessService.Annotations[appliedKey]; !ok {
+		if _, ok := currentService.Annotations[appliedKey]; ok {
+			delete(currentService.Annotations, appliedKey)
+			updated = true
+		}
+	}
+	for key, value := range headlessService.Annotations {
+		if current, ok := currentService.Annotations[key]; !ok || current != value {
+			if currentService.Annotations == nil {
+				currentService.Annotations = map[string]string{}
+			}
+			currentService.Annotations[key] = value
+			updated = true
+		}
+	}
+	// The REST port is added once the service monitor is enabled, and removed once it is
+	// disabled
+	for _, port := range headlessService.Spec.Ports {
+		if !hasServicePort(currentService.Spec.Ports, port.Name) {
+			currentService.Spec.Ports = append(currentService.Spec.Ports, port)
+			updated = true
+		}
+	}
+	if !hasServicePort(headlessService.Spec.Ports, pravega.SegmentStoreRESTPortName) {
+		ports := make([]corev1.ServicePort, 0, len(currentService.Spec.Ports))
+		for _, port := range currentService.Spec.Ports {
+			if port.Name != pravega.SegmentStoreRESTPortName {
+				ports = append(ports, port)
+			}
+		}
+		if len(ports) != len(currentService.Spec.Ports) {
+			currentService.Spec.Ports = ports
+			updated = true
+		}
+	}
+	if updated {
+		err = r.client.Update(context.TODO(), currentService)
+		if err != nil {
+			return fmt.Errorf("failed to update service (%s): %v", currentService.Name, err)
+		}
+	}
+	if keysChanged {
+		r.publishAnnotationConflictEvent(p)
 	}
+	return nil
+}
 
-	if p.Spec.ExternalAccess.Enabled {
-		currentservice := &corev1.Service{}
-		services := pravega.MakeSegmentStoreExternalServices(p)
-		for _, service := range services {
-			controllerutil.SetControllerReference(p, service, r.scheme)
-			err := r.client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: p.Namespace}, currentservice)
-			if err != nil {
-				if errors.IsNotFound(err) {
-					err = r.client.Create(context.TODO(), service)
-					if err != nil && !errors.IsAlreadyExists(err) {
-						return err
-					}
+// hasServicePort checks whether a service has a port with the given name
+func hasServicePort(ports []corev1.ServicePort, name string) bool {
+	for _, port := range ports {
+		if port.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// publishAnnotationConflictEvent publishes a warning event when headless service
+// annotations collide with operator-managed ones. It is published when the service is
+// created and when the keys of the annotations of the spec change, not on every reconcile
+func (r *ReconcilePravegaCluster) publishAnnotationConflictEvent(p *pravegav1beta1.PravegaCluster) {
+	conflicts := pravega.HeadlessServiceAnnotationConflicts(p)
+	if len(conflicts) == 0 {
+		return
+	}
+	message := fmt.Sprintf("Ignoring segmentStoreHeadlessServiceAnnotations %s managed by the operator", strings.Join(conflicts, ", "))
+	event := p.NewEvent("ANNOTATION_CONFLICT", pravegav1beta1.AnnotationConflictReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing annotation conflict event to k8s. %v", err)
+	}
+}
+
+// publishExtraEnvConflictEvent publishes a warning event when extra environment variables
+// collide with operator-managed ones. It is published when the environment is applied,
+// not on every reconcile
+func (r *ReconcilePravegaCluster) publishExtraEnvConflictEvent(p *pravegav1beta1.PravegaCluster, field string, conflicts []string) {
+	if len(conflicts) == 0 {
+		return
+	}
+	message := fmt.Sprintf("Ignoring %s %s managed by the operator", field, strings.Join(conflicts, ", "))
+	event := p.NewEvent("EXTRA_ENV_CONFLICT", pravegav1beta1.ExtraEnvConflictReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing extra environment conflict event to k8s. %v", err)
+	}
+}
+
+// segmentStoresScaledToZero returns true if the segment stores of an already reconciled
+// cluster were scaled down to 0 without allowZeroSegmentStores, bypassing the webhook,
+// e.g. through the scale subresource. The defaults scale them back to 1. A new cluster
+// without segmentStoreReplicas also has 0 replicas, which are silently defaulted.
+func segmentStoresScaledToZero(p *pravegav1beta1.PravegaCluster) bool {
+	return !config.TestMode && p.Spec.Pravega != nil && p.Status.ObservedGeneration > 0 &&
+		p.Spec.Pravega.SegmentStoreReplicas == 0 && !p.Spec.Pravega.AllowZeroSegmentStores
+}
+
+// publishZeroSegmentStoresEvent publishes a warning event when the segment stores of the
+// cluster were scaled down to 0 without allowZeroSegmentStores and scaled back to 1
+func (r *ReconcilePravegaCluster) publishZeroSegmentStoresEvent(p *pravegav1beta1.PravegaCluster) {
+	message := "segmentStoreReplicas must be at least 1 as a cluster without segment stores serves no data, scaled back to 1. Set allowZeroSegmentStores to true to run the cluster without segment stores"
+	event := p.NewEvent("ZERO_SEGMENT_STORES", pravegav1beta1.ZeroSegmentStoresReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing zero segment stores event to k8s. %v", err)
+	}
+}
+
+// publishServiceAccountTokenConflictEvent publishes a warning event when the service
+// account token is not mounted into the segment store pods while features of the
+// operator need it. It is published when the pod template is applied, not on every
+// reconcile
+func (r *ReconcilePravegaCluster) publishServiceAccountTokenConflictEvent(p *pravegav1beta1.PravegaCluster) {
+	conflicts := pravega.SegmentStoreServiceAccountTokenConflicts(p)
+	if len(conflicts) == 0 {
+		return
+	}
+	message := fmt.Sprintf("segmentStoreAutomountServiceAccountToken is false but %s needs the service account token of the segment stores", strings.Join(conflicts, ", "))
+	event := p.NewEvent("SERVICE_ACCOUNT_TOKEN_CONFLICT", pravegav1beta1.ServiceAccountTokenConflictReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing service account token conflict event to k8s. %v", err)
+	}
+}
+
+func (r *ReconcilePravegaCluster) reconcileSegmentStoreService(p *pravegav1beta1.PravegaCluster) (err error) {
+	err = r.reconcileSegmentStoreHeadlessService(p)
+	if err != nil {
+		return err
+	}
+
+	if p.Spec.ExternalAccess.Enabled {
+		currentservice := &corev1.Service{}
+		services := pravega.MakeSegmentStoreExternalServices(p)
+		for _, service := range services {
+			controllerutil.SetControllerReference(p, service, r.scheme)
+			err := r.client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: p.Namespace}, currentservice)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					err = r.client.Create(context.TODO(), service)
+					if err != nil && !errors.IsAlreadyExists(err) {
+						return err
+					}
 				}
 			} else {
 				eq := reflect.DeepEqual(currentservice.Annotations["external-dns.alpha.kubernetes.io/hostname"], service.Annotations["external-dns.alpha.kubernetes.io/hostname"])
-				if !eq {
+				if eq {
+					err = r.syncExternalTrafficPolicy(currentservice, service)
+					if err != nil {
+						return err
+					}
+					err = r.syncExternalServicePort(currentservice, service)
+					if err != nil {
+						return err
+					}
+					err = r.syncLoadBalancerTags(currentservice, service)
+					if err != nil {
+						return err
+					}
+				} else {
 					err := r.client.Delete(context.TODO(), currentservice)
 					if err != nil {
 						return err
@@ -432,6 +1202,7 @@ func (r *ReconcilePravegaCluster) deployCluster(p *pravegav1beta1.PravegaCluster
 		log.Printf("failed to deploy controller: %v", err)
 		return err
 	}
+	p.Status.SetComponentReconciled(pravegav1beta1.ComponentController, time.Now())
 
 	/*this check is to avoid creation of a new segmentstore when the CurrentVersion is below 07 and target version is above 07
 	  as we are doing it in the upgrade path*/
@@ -442,12 +1213,17 @@ func (r *ReconcilePravegaCluster) deployCluster(p *pravegav1beta1.PravegaCluster
 			log.Printf("failed to deploy segment store: %v", err)
 			return err
 		}
+		p.Status.SetComponentReconciled(pravegav1beta1.ComponentSegmentStore, time.Now())
 
 		if !util.IsVersionBelow07(p.Spec.Version) {
 			newsts := &appsv1.StatefulSet{}
 			name := p.StatefulSetNameForSegmentstoreAbove07()
 			err = r.client.Get(context.TODO(),
 				types.NamespacedName{Name: name, Namespace: p.Namespace}, newsts)
+			if errors.IsNotFound(err) {
+				// the creation of the stateful set is held back
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("failed to get stateful-set (%s): %v", newsts.Name, err)
 			}
@@ -538,11 +1314,118 @@ func (r *ReconcilePravegaCluster) deleteOldSegmentStoreIfExists(p *pravegav1beta
 func (r *ReconcilePravegaCluster) deployController(p *pravegav1beta1.PravegaCluster) (err error) {
 
 	deployment := pravega.MakeControllerDeployment(p)
+	held, err := r.creationHeldBack(p, &appsv1.Deployment{}, deployment.Name, controllerCreationHold(p))
+	if err != nil || held {
+		return err
+	}
 	controllerutil.SetControllerReference(p, deployment, r.scheme)
 	err = r.client.Create(context.TODO(), deployment)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		return r.syncControllerPodTemplate(p, false)
+	}
+	r.publishExtraEnvConflictEvent(p, "controllerExtraEnv", pravega.ControllerExtraEnvConflicts(p))
+	return nil
+}
+
+// syncControllerPodTemplate applies node selector, affinity, probe, resource and configmap changes
+// to the controller deployment in place, the deployment rolls the controller pods.
+// configMapChanged tells that the configmap of the controller was just updated
+func (r *ReconcilePravegaCluster) syncControllerPodTemplate(p *pravegav1beta1.PravegaCluster, configMapChanged bool) (err error) {
+	deployment := pravega.MakeControllerDeployment(p)
+	deploy := &appsv1.Deployment{}
+	err = r.client.Get(context.TODO(),
+		types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+	if err != nil {
 		return err
 	}
+	updated := false
+	// The deployments created by earlier operator versions use the default strategy
+	if !reflect.DeepEqual(deploy.Spec.Strategy, deployment.Spec.Strategy) {
+		deploy.Spec.Strategy = deployment.Spec.Strategy
+		updated = true
+	}
+	nodeSelector := deployment.Spec.Template.Spec.NodeSelector
+	if nodeSelectorChanged(deploy.Spec.Template.Spec.NodeSelector, nodeSelector) {
+		deploy.Spec.Template.Spec.NodeSelector = nodeSelector
+		updated = true
+	}
+	affinity := deployment.Spec.Template.Spec.Affinity
+	if !reflect.DeepEqual(deploy.Spec.Template.Spec.Affinity, affinity) {
+		deploy.Spec.Template.Spec.Affinity = affinity
+		updated = true
+	}
+	automount := deployment.Spec.Template.Spec.AutomountServiceAccountToken
+	if !reflect.DeepEqual(deploy.Spec.Template.Spec.AutomountServiceAccountToken, automount) {
+		deploy.Spec.Template.Spec.AutomountServiceAccountToken = automount
+		updated = true
+	}
+	if len(deploy.Spec.Template.Spec.Containers) > 0 {
+		current := &deploy.Spec.Template.Spec.Containers[0]
+		desired := deployment.Spec.Template.Spec.Containers[0]
+		if probeTimingsChanged(current.ReadinessProbe, desired.ReadinessProbe) ||
+			probeTimingsChanged(current.LivenessProbe, desired.LivenessProbe) {
+			current.ReadinessProbe = desired.ReadinessProbe
+			current.LivenessProbe = desired.LivenessProbe
+			updated = true
+		}
+		if resourcesChanged(current.Resources, desired.Resources) {
+			current.Resources = desired.Resources
+			updated = true
+		}
+		if envChanged(current.Env, desired.Env) {
+			current.Env = desired.Env
+			updated = true
+			r.publishExtraEnvConflictEvent(p, "controllerExtraEnv", pravega.ControllerExtraEnvConflicts(p))
+		}
+		if !reflect.DeepEqual(current.SecurityContext, desired.SecurityContext) {
+			current.SecurityContext = desired.SecurityContext
+			updated = true
+		}
+		if commandChanged(current, &desired) {
+			current.Command = desired.Command
+			current.Args = desired.Args
+			current.LivenessProbe = desired.LivenessProbe
+			updated = true
+		}
+	}
+	securityContext := deployment.Spec.Template.Spec.SecurityContext
+	if podSecurityContextChanged(deploy.Spec.Template.Spec.SecurityContext, securityContext) {
+		deploy.Spec.Template.Spec.SecurityContext = securityContext
+		updated = true
+	}
+	if dnsChanged(&deploy.Spec.Template.Spec, &deployment.Spec.Template.Spec) {
+		deploy.Spec.Template.Spec.DNSPolicy = deployment.Spec.Template.Spec.DNSPolicy
+		deploy.Spec.Template.Spec.DNSConfig = deployment.Spec.Template.Spec.DNSConfig
+		updated = true
+	}
+	initContainers := deployment.Spec.Template.Spec.InitContainers
+	if !upgradeInProgress(p) && initContainersChanged(deploy.Spec.Template.Spec.InitContainers, initContainers) {
+		deploy.Spec.Template.Spec.InitContainers = initContainers
+		updated = true
+	}
+	if syncPodTemplateAnnotation(&deploy.Spec.Template, pravega.TLSSecretHashAnnotationKey, deployment.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]) {
+		updated = true
+	}
+	hash := deployment.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+	if _, ok := deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]; (ok || configMapChanged) &&
+		syncConfigMapHash(p, &deploy.Spec.Template, hash) {
+		updated = true
+	}
+	// Removing the restart annotation from the cluster does not restart the pods
+	requested := deployment.Spec.Template.Annotations[pravega.RestartAnnotationKey]
+	if requested != "" && !restartDeferred(p, &deploy.Spec.Template) &&
+		syncPodTemplateAnnotation(&deploy.Spec.Template, pravega.RestartAnnotationKey, requested) {
+		updated = true
+	}
+	if updated {
+		err = r.client.Update(context.TODO(), deploy)
+		if err != nil {
+			return fmt.Errorf("failed to update pod template of deployment (%s): %v", deploy.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -554,6 +1437,10 @@ func (r *ReconcilePravegaCluster) deploySegmentStore(p *pravegav1beta1.PravegaCl
 			controllerutil.SetControllerReference(p, &statefulSet.Spec.VolumeClaimTemplates[i], r.scheme)
 		}
 	}
+	held, err := r.creationHeldBack(p, &appsv1.StatefulSet{}, statefulSet.Name, segmentStoreCreationHold(p))
+	if err != nil || held {
+		return err
+	}
 
 	err = r.client.Create(context.TODO(), statefulSet)
 	if err != nil {
@@ -574,125 +1461,554 @@ func (r *ReconcilePravegaCluster) deploySegmentStore(p *pravegav1beta1.PravegaCl
 				if err != nil {
 					return err
 				}
+				return nil
+			}
+			err = r.syncStatefulSetPvcSize(p, sts)
+			if err != nil {
+				return err
 			}
+			err = r.syncPvcLabels(p, sts)
+			if err != nil {
+				return err
+			}
+			return r.syncSegmentStorePodTemplate(p, false)
 		}
 	}
+	r.publishExtraEnvConflictEvent(p, "segmentStoreExtraEnv", pravega.SegmentStoreExtraEnvConflicts(p))
+	r.publishServiceAccountTokenConflictEvent(p)
 	return nil
 }
 
-func hasOldVersionOwnerReference(ownerreference []metav1.OwnerReference) bool {
-	for _, value := range ownerreference {
-		if value.Kind == "PravegaCluster" && value.APIVersion == "pravega.pravega.io/v1alpha1" {
+// syncSegmentStorePodTemplate applies node selector, termination grace period and
+// resource changes to the segment store stateful set in place. As the stateful set
+// uses the OnDelete update strategy, segment store pods pick them up when they are
+// recreated, except for init container and configmap changes which restart the pods
+// one at a time.
+// configMapChanged tells that the configmap of the segment store was just updated
+func (r *ReconcilePravegaCluster) syncSegmentStorePodTemplate(p *pravegav1beta1.PravegaCluster, configMapChanged bool) (err error) {
+	statefulSet := pravega.MakeSegmentStoreStatefulSet(p)
+	sts := &appsv1.StatefulSet{}
+	err = r.client.Get(context.TODO(),
+		types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+	if err != nil {
+		return err
+	}
+	updated := false
+	nodeSelector := statefulSet.Spec.Template.Spec.NodeSelector
+	if nodeSelectorChanged(sts.Spec.Template.Spec.NodeSelector, nodeSelector) {
+		sts.Spec.Template.Spec.NodeSelector = nodeSelector
+		updated = true
+	}
+	gracePeriod := statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if gracePeriod == nil {
+		// The API server defaults an unset grace period, so an unset one syncs back to it
+		defaultGracePeriod := int64(corev1.DefaultTerminationGracePeriodSeconds)
+		gracePeriod = &defaultGracePeriod
+	}
+	if !reflect.DeepEqual(sts.Spec.Template.Spec.TerminationGracePeriodSeconds, gracePeriod) {
+		sts.Spec.Template.Spec.TerminationGracePeriodSeconds = gracePeriod
+		updated = true
+	}
+	if len(sts.Spec.Template.Spec.Containers) > 0 {
+		current := &sts.Spec.Template.Spec.Containers[0]
+		desired := statefulSet.Spec.Template.Spec.Containers[0]
+		if resourcesChanged(current.Resources, desired.Resources) {
+			current.Resources = desired.Resources
+			updated = true
+		}
+	}
+	// The init containers, the volumes, the spread constraints, the affinity, the host
+	// network, the service account token, the environment, the pod and container security
+	// contexts and the command only take effect when the pods restart
+	restart := ""
+	securityContext := statefulSet.Spec.Template.Spec.SecurityContext
+	if podSecurityContextChanged(sts.Spec.Template.Spec.SecurityContext, securityContext) {
+		sts.Spec.Template.Spec.SecurityContext = securityContext
+		updated = true
+		restart = "a pod security context change"
+	}
+	if len(sts.Spec.Template.Spec.Containers) > 0 {
+		current := &sts.Spec.Template.Spec.Containers[0]
+		desired := statefulSet.Spec.Template.Spec.Containers[0]
+		if envChanged(current.Env, desired.Env) {
+			current.Env = desired.Env
+			updated = true
+			restart = "an extra environment change"
+			r.publishExtraEnvConflictEvent(p, "segmentStoreExtraEnv", pravega.SegmentStoreExtraEnvConflicts(p))
+		}
+		if !reflect.DeepEqual(current.SecurityContext, desired.SecurityContext) {
+			current.SecurityContext = desired.SecurityContext
+			updated = true
+			restart = "a container security context change"
+		}
+		if commandChanged(current, &desired) {
+			current.Command = desired.Command
+			current.Args = desired.Args
+			current.LivenessProbe = desired.LivenessProbe
+			updated = true
+			restart = "a command change"
+		}
+	}
+	initContainers := statefulSet.Spec.Template.Spec.InitContainers
+	if !upgradeInProgress(p) && initContainersChanged(sts.Spec.Template.Spec.InitContainers, initContainers) {
+		sts.Spec.Template.Spec.InitContainers = initContainers
+		updated = true
+		restart = "an init containers change"
+	}
+	if len(sts.Spec.Template.Spec.Containers) > 0 {
+		volumes, mounts := syncableVolumes(sts, statefulSet)
+		if volumesChanged(sts.Spec.Template.Spec.Volumes, volumes) ||
+			!reflect.DeepEqual(sts.Spec.Template.Spec.Containers[0].VolumeMounts, mounts) {
+			sts.Spec.Template.Spec.Volumes = volumes
+			sts.Spec.Template.Spec.Containers[0].VolumeMounts = mounts
+			updated = true
+			restart = "a volumes change"
+		}
+	}
+	constraints := statefulSet.Spec.Template.Spec.TopologySpreadConstraints
+	if topologySpreadConstraintsChanged(sts.Spec.Template.Spec.TopologySpreadConstraints, constraints) {
+		sts.Spec.Template.Spec.TopologySpreadConstraints = constraints
+		updated = true
+		restart = "a topology spread constraints change"
+	}
+	affinity := statefulSet.Spec.Template.Spec.Affinity
+	if !reflect.DeepEqual(sts.Spec.Template.Spec.Affinity, affinity) {
+		sts.Spec.Template.Spec.Affinity = affinity
+		updated = true
+		restart = "an affinity change"
+	}
+	podSpec := statefulSet.Spec.Template.Spec
+	if sts.Spec.Template.Spec.HostNetwork != podSpec.HostNetwork {
+		sts.Spec.Template.Spec.HostNetwork = podSpec.HostNetwork
+		sts.Spec.Template.Spec.DNSPolicy = podSpec.DNSPolicy
+		updated = true
+		restart = "a host network change"
+	}
+	if dnsChanged(&sts.Spec.Template.Spec, &podSpec) {
+		sts.Spec.Template.Spec.DNSPolicy = podSpec.DNSPolicy
+		sts.Spec.Template.Spec.DNSConfig = podSpec.DNSConfig
+		updated = true
+		restart = "a DNS settings change"
+	}
+	if !reflect.DeepEqual(sts.Spec.Template.Spec.AutomountServiceAccountToken, podSpec.AutomountServiceAccountToken) {
+		sts.Spec.Template.Spec.AutomountServiceAccountToken = podSpec.AutomountServiceAccountToken
+		updated = true
+		restart = "a service account token change"
+		r.publishServiceAccountTokenConflictEvent(p)
+	}
+	hash := statefulSet.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]
+	if syncPodTemplateAnnotation(&sts.Spec.Template, pravega.TLSSecretHashAnnotationKey, hash) {
+		updated = true
+		if hash != "" {
+			restart = "a TLS secret change"
+		}
+	}
+	_, hashed := sts.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+	if syncConfigMapHash(p, &sts.Spec.Template, statefulSet.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]) {
+		updated = true
+		if hashed || configMapChanged {
+			restart = "a configmap change"
+		}
+	}
+	requested := statefulSet.Spec.Template.Annotations[pravega.RestartAnnotationKey]
+	if requested != "" && !restartDeferred(p, &sts.Spec.Template) &&
+		syncPodTemplateAnnotation(&sts.Spec.Template, pravega.RestartAnnotationKey, requested) {
+		updated = true
+		restart = "a restart request"
+	}
+	if updated {
+		err = r.client.Update(context.TODO(), sts)
+		if err != nil {
+			return fmt.Errorf("failed to update pod template of stateful-set (%s): %v", sts.Name, err)
+		}
+	}
+	if restart != "" {
+		if segmentStoreRestartManual(p) {
+			r.publishManualRestartEvent(p, restart)
+			return nil
+		}
+		// The outdated pods are deleted one at a time by syncSegmentStoreImage, which
+		// compares their revision with the update revision of the stateful set
+		log.Printf("rolling segment store pods of stateful-set (%s) after %s", sts.Name, restart)
+	}
+	return nil
+}
+
+// syncConfigMapHash sets the configmap hash annotation of the pod template, and reports
+// whether the template changed. The hash is left as is during upgrades and rollbacks,
+// which replace the pod templates, and when the configmaps are not reconciled. The pod
+// templates created by earlier operator versions have no hash: the segment stores get it
+// without restarting, the controller along with the next configmap change
+func syncConfigMapHash(p *pravegav1beta1.PravegaCluster, template *corev1.PodTemplateSpec, hash string) bool {
+	if p.Status.IsClusterInUpgradingState() || p.Status.IsClusterInRollbackState() || configMapReconcileIgnored(p) {
+		return false
+	}
+	return syncPodTemplateAnnotation(template, pravega.ConfigMapHashAnnotationKey, hash)
+}
+
+// syncPodTemplateAnnotation sets the annotation of the pod template to the given value,
+// or removes it if the value is empty, and reports whether the template changed
+func syncPodTemplateAnnotation(template *corev1.PodTemplateSpec, key string, value string) bool {
+	current, ok := template.Annotations[key]
+	if value == "" {
+		if ok {
+			delete(template.Annotations, key)
+		}
+		return ok
+	}
+	if current == value {
+		return false
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[key] = value
+	return true
+}
+
+// restartDeferred reports whether a restart requested with the restart annotation has
+// to wait for the end of an upgrade or a rollback, which already restarts the pods
+func restartDeferred(p *pravegav1beta1.PravegaCluster, template *corev1.PodTemplateSpec) bool {
+	if !p.Status.IsClusterInUpgradingState() && !p.Status.IsClusterInRollbackState() {
+		return false
+	}
+	if template.Annotations[pravega.RestartAnnotationKey] != p.Annotations[pravegav1beta1.RestartAnnotation] {
+		log.Printf("deferring the restart of cluster %s until the end of the upgrade", p.Name)
+	}
+	return true
+}
+
+// upgradeInProgress reports whether the cluster is upgrading or rolling back. The init
+// containers run the image of the component, which the upgrade rolls out along with the
+// rest of the pod template, so they are not synced meanwhile
+func upgradeInProgress(p *pravegav1beta1.PravegaCluster) bool {
+	return p.Status.IsClusterInUpgradingState() || p.Status.IsClusterInRollbackState()
+}
+
+// initContainersChanged reports whether the desired init containers differ from the
+// current ones. Fields defaulted by the API server are not compared
+func initContainersChanged(current []corev1.Container, desired []corev1.Container) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	for i := range desired {
+		c, d := current[i], desired[i]
+		if c.Name != d.Name || c.Image != d.Image ||
+			!reflect.DeepEqual(c.Command, d.Command) ||
+			!reflect.DeepEqual(c.Args, d.Args) ||
+			envChanged(c.Env, d.Env) ||
+			!reflect.DeepEqual(c.VolumeMounts, d.VolumeMounts) ||
+			resourcesChanged(c.Resources, d.Resources) {
 			return true
 		}
 	}
 	return false
 }
 
-func (r *ReconcilePravegaCluster) restartStsPod(p *pravegav1beta1.PravegaCluster) error {
-
-	currentSts := &appsv1.StatefulSet{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, currentSts)
-	if err != nil {
-		return err
+// envChanged reports whether the desired environment variables differ from the current
+// ones, including the secret, configmap or field their values are read from
+func envChanged(current []corev1.EnvVar, desired []corev1.EnvVar) bool {
+	if len(current) != len(desired) {
+		return true
 	}
-	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
-		MatchLabels: currentSts.Spec.Template.Labels,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to convert label selector: %v", err)
+	for i := range desired {
+		if current[i].Name != desired[i].Name || current[i].Value != desired[i].Value ||
+			!reflect.DeepEqual(defaultedEnvVarSource(current[i].ValueFrom), defaultedEnvVarSource(desired[i].ValueFrom)) {
+			return true
+		}
 	}
-	podList := &corev1.PodList{}
-	podlistOps := &client.ListOptions{
-		Namespace:     currentSts.Namespace,
-		LabelSelector: selector,
+	return false
+}
+
+// defaultedEnvVarSource returns a copy of the source with the field reference API version
+// the API server defaults to, so that an unset version does not count as a change
+func defaultedEnvVarSource(source *corev1.EnvVarSource) *corev1.EnvVarSource {
+	if source == nil || source.FieldRef == nil || source.FieldRef.APIVersion != "" {
+		return source
 	}
-	err = r.client.List(context.TODO(), podList, podlistOps)
-	if err != nil {
-		return err
+	source = source.DeepCopy()
+	source.FieldRef.APIVersion = "v1"
+	return source
+}
+
+// volumesChanged reports whether the desired volumes differ from the current ones by name
+// or by the object or path they refer to. The other fields of the volume sources are not
+// compared as the API server defaults some of them, e.g. the mode of the files
+func volumesChanged(current []corev1.Volume, desired []corev1.Volume) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	for i := range desired {
+		if current[i].Name != desired[i].Name || volumeSourceRef(current[i]) != volumeSourceRef(desired[i]) {
+			return true
+		}
 	}
+	return false
+}
 
-	for _, podItem := range podList.Items {
-		err := r.client.Delete(context.TODO(), &podItem)
-		if err != nil {
-			return err
-		} else {
-			start := time.Now()
-			pod := &corev1.Pod{}
-			err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
-			for util.IsPodReady(pod) {
-				if time.Since(start) > 10*time.Minute {
-					return fmt.Errorf("failed to delete Segmentstore pod (%s) for 10 mins ", podItem.ObjectMeta.Name)
-				}
-				err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
+// syncableVolumes returns the volumes of the desired stateful set and the mounts of its
+// segment store container which can be applied to the current one. The volume claim
+// templates cannot be changed, so the mounts of the claims are left as they are, and the
+// volumes still mounted by the other containers, which are not synced, are kept
+func syncableVolumes(current *appsv1.StatefulSet, desired *appsv1.StatefulSet) ([]corev1.Volume, []corev1.VolumeMount) {
+	volumes := append([]corev1.Volume{}, desired.Spec.Template.Spec.Volumes...)
+	volumeNames := map[string]bool{}
+	for _, volume := range volumes {
+		volumeNames[volume.Name] = true
+	}
+	for _, container := range current.Spec.Template.Spec.Containers[1:] {
+		for _, mount := range container.VolumeMounts {
+			if volumeNames[mount.Name] {
+				continue
 			}
-			start = time.Now()
-			err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
-			for !util.IsPodReady(pod) {
-				if time.Since(start) > 10*time.Minute {
-					return fmt.Errorf("failed to get Segmentstore pod (%s) as ready for 10 mins ", podItem.ObjectMeta.Name)
+			for _, volume := range current.Spec.Template.Spec.Volumes {
+				if volume.Name == mount.Name {
+					volumes = append(volumes, volume)
+					volumeNames[volume.Name] = true
 				}
-				err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
 			}
 		}
 	}
-	return nil
+
+	claims := map[string]bool{}
+	for _, template := range current.Spec.VolumeClaimTemplates {
+		claims[template.Name] = true
+	}
+	for _, template := range desired.Spec.VolumeClaimTemplates {
+		claims[template.Name] = true
+	}
+	currentMounts := current.Spec.Template.Spec.Containers[0].VolumeMounts
+	claimMount := func(name string) (corev1.VolumeMount, bool) {
+		for _, mount := range currentMounts {
+			if mount.Name == name {
+				return mount, true
+			}
+		}
+		return corev1.VolumeMount{}, false
+	}
+	var mounts []corev1.VolumeMount
+	kept := map[string]bool{}
+	for _, mount := range desired.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if !claims[mount.Name] {
+			mounts = append(mounts, mount)
+			continue
+		}
+		if currentMount, ok := claimMount(mount.Name); ok && !kept[mount.Name] {
+			mounts = append(mounts, currentMount)
+			kept[mount.Name] = true
+		}
+	}
+	for _, mount := range currentMounts {
+		if claims[mount.Name] && !kept[mount.Name] {
+			mounts = append(mounts, mount)
+			kept[mount.Name] = true
+		}
+	}
+	return volumes, mounts
 }
 
-func (r *ReconcilePravegaCluster) restartDeploymentPod(p *pravegav1beta1.PravegaCluster) error {
+// volumeSourceRef returns the kind and name of the config map, secret or claim, or the
+// host path, the volume refers to
+func volumeSourceRef(volume corev1.Volume) string {
+	switch {
+	case volume.ConfigMap != nil:
+		return "configMap/" + volume.ConfigMap.Name
+	case volume.Secret != nil:
+		return "secret/" + volume.Secret.SecretName
+	case volume.PersistentVolumeClaim != nil:
+		return "persistentVolumeClaim/" + volume.PersistentVolumeClaim.ClaimName
+	case volume.HostPath != nil:
+		return "hostPath/" + volume.HostPath.Path
+	case volume.EmptyDir != nil:
+		return "emptyDir"
+	case volume.Projected != nil:
+		return "projected"
+	}
+	return ""
+}
 
-	currentDeployment := &appsv1.Deployment{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, currentDeployment)
-	if err != nil {
-		return err
+// podSecurityContextChanged reports whether the desired pod security context, which
+// includes the identity of the runAsIdentitySecret, differs from the current one, no
+// security context and an empty one being the same
+func podSecurityContextChanged(current *corev1.PodSecurityContext, desired *corev1.PodSecurityContext) bool {
+	empty := &corev1.PodSecurityContext{}
+	if current == nil {
+		current = empty
 	}
-	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
-		MatchLabels: currentDeployment.Spec.Template.Labels,
-	})
+	if desired == nil {
+		desired = empty
+	}
+	return !reflect.DeepEqual(current, desired)
+}
+
+// dnsChanged reports whether the desired DNS policy or configuration differs from the
+// current one. No DNS policy is the ClusterFirst policy the API server defaults to.
+func dnsChanged(current *corev1.PodSpec, desired *corev1.PodSpec) bool {
+	currentPolicy, desiredPolicy := current.DNSPolicy, desired.DNSPolicy
+	if currentPolicy == "" {
+		currentPolicy = corev1.DNSClusterFirst
+	}
+	if desiredPolicy == "" {
+		desiredPolicy = corev1.DNSClusterFirst
+	}
+	return currentPolicy != desiredPolicy || !reflect.DeepEqual(current.DNSConfig, desired.DNSConfig)
+}
+
+// nodeSelectorChanged reports whether the desired node selector differs from the
+// current one, an empty node selector being equivalent to none
+func nodeSelectorChanged(current map[string]string, desired map[string]string) bool {
+	if len(current) == 0 && len(desired) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, desired)
+}
+
+// topologySpreadConstraintsChanged reports whether the desired spread constraints differ
+// from the current ones, no constraint and an empty list being the same
+func topologySpreadConstraintsChanged(current []corev1.TopologySpreadConstraint, desired []corev1.TopologySpreadConstraint) bool {
+	if len(current) == 0 && len(desired) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, desired)
+}
+
+// commandChanged reports whether the command or the args of the desired container differ
+// from the current ones, or whether a command override added or removed the liveness probe
+func commandChanged(current *corev1.Container, desired *corev1.Container) bool {
+	return stringsChanged(current.Command, desired.Command) ||
+		stringsChanged(current.Args, desired.Args) ||
+		(current.LivenessProbe == nil) != (desired.LivenessProbe == nil)
+}
+
+func stringsChanged(current []string, desired []string) bool {
+	if len(current) == 0 && len(desired) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, desired)
+}
+
+// probeTimingsChanged reports whether the tunable timings of the desired probe differ
+// from the current one. Fields defaulted by the API server are not compared
+func probeTimingsChanged(current *corev1.Probe, desired *corev1.Probe) bool {
+	if current == nil || desired == nil {
+		return current != desired
+	}
+	return current.InitialDelaySeconds != desired.InitialDelaySeconds ||
+		current.PeriodSeconds != desired.PeriodSeconds ||
+		current.FailureThreshold != desired.FailureThreshold ||
+		current.TimeoutSeconds != desired.TimeoutSeconds
+}
+
+// resourcesChanged reports whether the desired resources differ from the current ones
+func resourcesChanged(current corev1.ResourceRequirements, desired corev1.ResourceRequirements) bool {
+	return resourceListChanged(current.Requests, desired.Requests) || resourceListChanged(current.Limits, desired.Limits)
+}
+
+func resourceListChanged(current corev1.ResourceList, desired corev1.ResourceList) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	for name, quantity := range desired {
+		currentQuantity, ok := current[name]
+		if !ok || currentQuantity.Cmp(quantity) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOldVersionOwnerReference(ownerreference []metav1.OwnerReference) bool {
+	for _, value := range ownerreference {
+		if value.Kind == "PravegaCluster" && value.APIVersion == "pravega.pravega.io/v1alpha1" {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentStoreRestartManual returns true if the segment store pods must be deleted by
+// the user rather than by the operator to pick up stateful set changes
+func segmentStoreRestartManual(p *pravegav1beta1.PravegaCluster) bool {
+	return p.Spec.Pravega.SegmentStoreUpdateStrategy == pravegav1beta1.SegmentStoreUpdateStrategyOnDelete
+}
+
+// publishManualRestartEvent publishes a warning event telling the user to delete the
+// segment store pods, as the operator does not restart them with the OnDelete strategy
+func (r *ReconcilePravegaCluster) publishManualRestartEvent(p *pravegav1beta1.PravegaCluster, change string) {
+	message := fmt.Sprintf("The segment store pods of stateful-set (%s) must be deleted to pick up %s, the segmentStoreUpdateStrategy is %s",
+		p.StatefulSetNameForSegmentstore(), change, pravegav1beta1.SegmentStoreUpdateStrategyOnDelete)
+	log.Print(message)
+	event := p.NewEvent("MANUAL_RESTART_REQUIRED", pravegav1beta1.ManualRestartRequiredReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
 	if err != nil {
-		return fmt.Errorf("failed to convert label selector: %v", err)
+		log.Printf("Error publishing manual restart event to k8s. %v", err)
+	}
+}
+
+// syncNodeAnnotationRestart restarts the segment store pods running on nodes whose
+// restart annotation changed. The annotation value each pod started with is recorded
+// on the pod, and a single pod is restarted per reconcile while all the others are ready.
+func (r *ReconcilePravegaCluster) syncNodeAnnotationRestart(p *pravegav1beta1.PravegaCluster) error {
+	annotation := p.Spec.Pravega.SegmentStoreRestartNodeAnnotation
+	if !config.NodeWatch || annotation == "" {
+		return nil
+	}
+	if p.Status.IsClusterInUpgradingState() || p.Status.IsClusterInRollbackState() {
+		return nil
 	}
+
 	podList := &corev1.PodList{}
-	podlistOps := &client.ListOptions{
-		Namespace:     currentDeployment.Namespace,
-		LabelSelector: selector,
+	listOps := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(p.LabelsForSegmentStore()),
 	}
-	err = r.client.List(context.TODO(), podList, podlistOps)
+	err := r.client.List(context.TODO(), podList, listOps)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to list segment store pods: %v", err)
 	}
 
-	for _, podItem := range podList.Items {
-		err := r.client.Delete(context.TODO(), &podItem)
+	var outdated []corev1.Pod
+	allReady := true
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !util.IsPodReady(pod) {
+			allReady = false
+		}
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		node := &corev1.Node{}
+		err = r.clusterScoped().Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node)
 		if err != nil {
-			return err
-		} else {
-			start := time.Now()
-			pod := &corev1.Pod{}
-			err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
-			for util.IsPodReady(pod) {
-				if time.Since(start) > 10*time.Minute {
-					return fmt.Errorf("failed to delete controller pod (%s) for 10 mins ", podItem.ObjectMeta.Name)
-				}
-				err = r.client.Get(context.TODO(), types.NamespacedName{Name: podItem.ObjectMeta.Name, Namespace: podItem.ObjectMeta.Namespace}, pod)
+			return fmt.Errorf("failed to get node (%s): %v", pod.Spec.NodeName, err)
+		}
+		current := node.Annotations[annotation]
+		seen, recorded := pod.Annotations[nodeAnnotationValueKey]
+		if !recorded {
+			if pod.Annotations == nil {
+				pod.Annotations = map[string]string{}
 			}
-			deploy := &appsv1.Deployment{}
-			name := p.DeploymentNameForController()
-			err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
+			pod.Annotations[nodeAnnotationValueKey] = current
+			err = r.client.Update(context.TODO(), pod)
 			if err != nil {
-				return fmt.Errorf("failed to get deployment (%s): %v", deploy.Name, err)
-			}
-			start = time.Now()
-			for deploy.Status.ReadyReplicas != deploy.Status.Replicas {
-				if time.Since(start) > 10*time.Minute {
-					return fmt.Errorf("failed to make controller pod ready for 10 mins ")
-				}
-				err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
-				if err != nil {
-					return fmt.Errorf("failed to get deployment (%s): %v", deploy.Name, err)
-				}
+				return fmt.Errorf("failed to record node annotation on pod (%s): %v", pod.Name, err)
 			}
+			continue
 		}
+		if seen != current {
+			outdated = append(outdated, *pod)
+		}
+	}
+
+	if len(outdated) == 0 || !allReady {
+		return nil
+	}
+	pod := &outdated[0]
+	log.Printf("restarting segment store pod (%s): annotation %s of node (%s) changed from %q",
+		pod.Name, annotation, pod.Spec.NodeName, pod.Annotations[nodeAnnotationValueKey])
+	err = r.client.Delete(context.TODO(), pod)
+	if err != nil {
+		return fmt.Errorf("failed to delete segment store pod (%s): %v", pod.Name, err)
 	}
 	return nil
 }
@@ -722,9 +2038,21 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreSize(p *pravegav1beta1.Pravega
 		return fmt.Errorf("failed to get stateful-set (%s): %v", sts.Name, err)
 	}
 
-	if *sts.Spec.Replicas != p.Spec.Pravega.SegmentStoreReplicas {
-		scaleDown := int32(0)
-		if p.Spec.Pravega.SegmentStoreReplicas < *sts.Spec.Replicas {
+	reason := pravegav1beta1.InsufficientSegmentstoreResourcesReason
+	if p.Spec.Pravega.SegmentStoreReplicas > *sts.Spec.Replicas || hasInsufficientResources(p, reason) {
+		err = r.checkNodeAllocatable(p, p.SegmentStoreResourceRequirements(), reason, "segment store")
+		if err != nil {
+			return err
+		}
+	}
+
+	if *sts.Spec.Replicas != p.Spec.Pravega.SegmentStoreReplicas {
+		if p.Spec.Pravega.SegmentStoreReplicas < *sts.Spec.Replicas && r.deferDisruptiveAction(p, "segment store scale-down") {
+			return nil
+		}
+		r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseScaling)
+		scaleDown := int32(0)
+		if p.Spec.Pravega.SegmentStoreReplicas < *sts.Spec.Replicas {
 			scaleDown = *sts.Spec.Replicas - p.Spec.Pravega.SegmentStoreReplicas
 		}
 		sts.Spec.Replicas = &(p.Spec.Pravega.SegmentStoreReplicas)
@@ -733,14 +2061,6 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreSize(p *pravegav1beta1.Pravega
 			return fmt.Errorf("failed to update size of stateful-set (%s): %v", sts.Name, err)
 		}
 
-		/*We skip calling syncStatefulSetPvc() during upgrade/rollback from version 07*/
-		if !r.IsClusterUpgradingTo07(p) && !r.IsClusterRollbackingFrom07(p) {
-			err = r.syncStatefulSetPvc(sts)
-			if err != nil {
-				return fmt.Errorf("failed to sync pvcs of stateful-set (%s): %v", sts.Name, err)
-			}
-		}
-
 		if p.Spec.ExternalAccess.Enabled && scaleDown > 0 {
 			err = r.syncStatefulSetExternalServices(sts)
 			if err != nil {
@@ -748,6 +2068,17 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreSize(p *pravegav1beta1.Pravega
 			}
 		}
 	}
+
+	// The claims are synced on every reconcile, so that the ones whose deletion failed
+	// are cleaned up later. We skip calling syncStatefulSetPvc() during upgrade/rollback
+	// from version 07
+	if !r.IsClusterUpgradingTo07(p) && !r.IsClusterRollbackingFrom07(p) {
+		retainCache := p.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy == pravegav1beta1.SegmentStoreCachePVCReclaimPolicyRetain
+		err = r.syncStatefulSetPvc(sts, retainCache)
+		if err != nil {
+			return fmt.Errorf("failed to sync pvcs of stateful-set (%s): %v", sts.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -759,17 +2090,435 @@ func (r *ReconcilePravegaCluster) syncControllerSize(p *pravegav1beta1.PravegaCl
 		return fmt.Errorf("failed to get deployment (%s): %v", deploy.Name, err)
 	}
 
+	reason := pravegav1beta1.InsufficientControllerResourcesReason
+	if p.Spec.Pravega.ControllerReplicas > *deploy.Spec.Replicas || hasInsufficientResources(p, reason) {
+		err = r.checkNodeAllocatable(p, p.ControllerResourceRequirements(), reason, "controller")
+		if err != nil {
+			return err
+		}
+	}
+
 	if *deploy.Spec.Replicas != p.Spec.Pravega.ControllerReplicas {
+		if p.Spec.Pravega.ControllerReplicas < *deploy.Spec.Replicas && r.deferDisruptiveAction(p, "controller scale-down") {
+			return nil
+		}
+		if p.Spec.Pravega.ControllerReplicas < *deploy.Spec.Replicas && deferControllerScaleDown(p, deploy) {
+			return nil
+		}
+		r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseScaling)
 		deploy.Spec.Replicas = &(p.Spec.Pravega.ControllerReplicas)
 		err = r.client.Update(context.TODO(), deploy)
 		if err != nil {
 			return fmt.Errorf("failed to update size of deployment (%s): %v", deploy.Name, err)
 		}
 	}
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionScaleDownDeferred)
+	if condition != nil && condition.Status == corev1.ConditionTrue {
+		p.Status.SetScaleDownDeferredConditionFalse()
+	}
+	return nil
+}
+
+// deferControllerScaleDown returns true if the controller scale-down could leave fewer
+// ready controllers than the pod disruption budget allows, counting the removed pods as
+// ready ones, and sets the ScaleDownDeferred condition. The scale-down is retried on the
+// next reconcile. It is not deferred when no controller is ready, as there is no
+// availability left to protect and the scale-down may be needed to recover the cluster.
+func deferControllerScaleDown(p *pravegav1beta1.PravegaCluster, deploy *appsv1.Deployment) bool {
+	current, desired := *deploy.Spec.Replicas, p.Spec.Pravega.ControllerReplicas
+	required := int32(pravega.ControllerMinAvailable)
+	if desired < required {
+		required = desired
+	}
+	ready := deploy.Status.ReadyReplicas
+	if ready == 0 || ready-(current-desired) >= required {
+		return false
+	}
+	message := fmt.Sprintf("scale-down of the controllers from %d to %d deferred, %d of %d controllers are ready and %d must stay available",
+		current, desired, ready, current, required)
+	log.Printf("cluster %s: %s", p.Name, message)
+	p.Status.SetScaleDownDeferredConditionTrue(pravegav1beta1.ControllerAvailabilityReason, message)
+	return true
+}
+
+// deferDisruptiveAction returns true if a disruptive action has to wait for the
+// next maintenance window, and records it in the status.
+func (r *ReconcilePravegaCluster) deferDisruptiveAction(p *pravegav1beta1.PravegaCluster, action string) bool {
+	now := time.Now()
+	if p.InMaintenanceWindow(now) {
+		return false
+	}
+	next := p.NextMaintenanceWindow(now)
+	log.Printf("deferring %s of cluster %s until the next maintenance window at %s", action, p.Name, next.Format(time.RFC3339))
+	p.Status.AddDeferredAction(action, next)
+	return true
+}
+
+// reconcileRunAsIdentity reads the user and group IDs from the runAsIdentitySecret and
+// records them in the status, from which they are applied to the pod templates. The
+// pods are not deployed or updated while the IDs are missing or invalid.
+func (r *ReconcilePravegaCluster) reconcileRunAsIdentity(p *pravegav1beta1.PravegaCluster) error {
+	name := p.Spec.Pravega.RunAsIdentitySecret
+	if name == "" {
+		p.Status.RunAsIdentity = nil
+		return nil
+	}
+	secret := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, secret)
+	if err != nil {
+		return fmt.Errorf("failed to get secret (%s): %v", name, err)
+	}
+	identity := &pravegav1beta1.RunAsIdentity{}
+	value, ok := secret.Data[runAsUserKey]
+	if !ok {
+		return fmt.Errorf("secret (%s) has no %s key", name, runAsUserKey)
+	}
+	identity.RunAsUser, err = strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s of secret (%s) is not an integer: %v", runAsUserKey, name, err)
+	}
+	if value, ok = secret.Data[runAsGroupKey]; ok {
+		runAsGroup, err := strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s of secret (%s) is not an integer: %v", runAsGroupKey, name, err)
+		}
+		identity.RunAsGroup = &runAsGroup
+	}
+	err = p.ValidateRunAsIdentity(identity)
+	if err != nil {
+		return fmt.Errorf("invalid identity in secret (%s): %v", name, err)
+	}
+	p.Status.RunAsIdentity = identity
+	return nil
+}
+
+// reconcileTLSSecretHashes records in the status a hash of the data of the TLS secrets
+// when reloadOnChange is set. The hashes are stamped on the pod templates, so that a
+// change of the secrets, e.g. a certificate rotation, restarts the pods.
+func (r *ReconcilePravegaCluster) reconcileTLSSecretHashes(p *pravegav1beta1.PravegaCluster) (err error) {
+	tls := p.Spec.TLS
+	if tls == nil || tls.Static == nil || !tls.ReloadOnChange {
+		p.Status.TLSSecretHashes = nil
+		return nil
+	}
+	hashes := &pravegav1beta1.TLSSecretHashes{}
+	if tls.IsSecureController() {
+		hashes.Controller, err = r.secretHash(p.Namespace, tls.Static.ControllerSecret)
+		if err != nil {
+			return err
+		}
+	}
+	segmentStoreSecrets := []string{}
+	if tls.IsSecureSegmentStore() {
+		segmentStoreSecrets = append(segmentStoreSecrets, tls.Static.SegmentStoreSecret)
+	}
+	if tls.IsCaBundlePresent() {
+		segmentStoreSecrets = append(segmentStoreSecrets, tls.Static.CaBundle)
+	}
+	if lts := p.Spec.Pravega.LongTermStorage; lts != nil && lts.Ecs != nil && lts.Ecs.CaBundleSecret != "" &&
+		lts.Ecs.CaBundleSecret != tls.Static.CaBundle {
+		segmentStoreSecrets = append(segmentStoreSecrets, lts.Ecs.CaBundleSecret)
+	}
+	if len(segmentStoreSecrets) > 0 {
+		hashes.SegmentStore, err = r.secretHash(p.Namespace, segmentStoreSecrets...)
+		if err != nil {
+			return err
+		}
+	}
+	p.Status.TLSSecretHashes = hashes
 	return nil
 }
 
-func (r *ReconcilePravegaCluster) syncStatefulSetPvc(sts *appsv1.StatefulSet) error {
+// secretHash returns the hex encoded SHA-256 hash of the data of the given secrets
+func (r *ReconcilePravegaCluster) secretHash(namespace string, names ...string) (string, error) {
+	hash := sha256.New()
+	for _, name := range names {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, secret)
+		if err != nil {
+			return "", fmt.Errorf("failed to get secret (%s): %v", name, err)
+		}
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(hash, "%s\x00", name)
+		for _, key := range keys {
+			fmt.Fprintf(hash, "%s\x00%d\x00", key, len(secret.Data[key]))
+			hash.Write(secret.Data[key])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// imageCheckInterval is how long the registry is not asked again about an image that
+// was not found or could not be checked. Images found are not checked again
+const imageCheckInterval = 10 * time.Minute
+
+// imageCheck is the result of the registry check of an image
+type imageCheck struct {
+	exists    bool
+	err       error
+	checkedAt time.Time
+}
+
+// imageChecks caches the registry checks per image and tag, so that the registry is
+// not asked on every reconcile
+var imageChecks = struct {
+	sync.Mutex
+	results map[string]imageCheck
+}{results: map[string]imageCheck{}}
+
+// checkImage returns the cached registry check of the image, and asks the registry
+// again once a failed check is older than imageCheckInterval
+func checkImage(image string) (bool, error) {
+	imageChecks.Lock()
+	defer imageChecks.Unlock()
+	result, ok := imageChecks.results[image]
+	if ok && (result.exists || time.Since(result.checkedAt) < imageCheckInterval) {
+		return result.exists, result.err
+	}
+	exists, err := imageExists(image)
+	imageChecks.results[image] = imageCheck{exists: exists, err: err, checkedAt: time.Now()}
+	return exists, err
+}
+
+// checkPravegaImage checks that the Controller and Segment Store images of the requested
+// version exist in their registry, and sets the ImageNotFound condition and publishes a
+// warning event if one doesn't. The condition then holds back the creation of the
+// Controller deployment and the Segment Store stateful set, and the upgrades, while the
+// existing pods are still reconciled. The pods are deployed as usual if the registry
+// cannot be checked, e.g. when it requires credentials.
+func (r *ReconcilePravegaCluster) checkPravegaImage(p *pravegav1beta1.PravegaCluster) {
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionImageNotFound)
+	notFound := condition != nil && condition.Status == corev1.ConditionTrue
+	if !p.Spec.Pravega.ImageCheck {
+		if notFound {
+			p.Status.SetImageNotFoundConditionFalse()
+		}
+		return
+	}
+
+	images := []string{p.ControllerImage()}
+	if p.SegmentStoreImage() != p.ControllerImage() {
+		images = append(images, p.SegmentStoreImage())
+	}
+	missing := ""
+	for _, image := range images {
+		exists, err := checkImage(image)
+		if err != nil {
+			log.Printf("failed to check image (%s) of cluster (%s): %v", image, p.Name, err)
+			continue
+		}
+		if !exists {
+			missing = image
+			break
+		}
+	}
+	if missing == "" {
+		if notFound {
+			p.Status.SetImageNotFoundConditionFalse()
+		}
+		return
+	}
+
+	message := fmt.Sprintf("image %s was not found in its registry", missing)
+	if notFound && condition.Message == message {
+		return
+	}
+	log.Printf("cluster (%s): %s", p.Name, message)
+	p.Status.SetImageNotFoundConditionTrue(pravegav1beta1.PravegaImageNotFoundReason, message)
+	event := p.NewEvent("IMAGE_NOT_FOUND", pravegav1beta1.PravegaImageNotFoundReason, message, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing image not found event to k8s. %v", err)
+	}
+}
+
+// creationHeldBack reports whether the named object of the cluster does not exist yet
+// and must not be created, as the given reason holds it back. Existing objects are
+// synced as usual, and nothing is held back without a reason.
+func (r *ReconcilePravegaCluster) creationHeldBack(p *pravegav1beta1.PravegaCluster, obj runtime.Object, name string, reason string) (bool, error) {
+	if reason == "" {
+		return false, nil
+	}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, obj)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+	log.Printf("not creating %s of cluster (%s) as %s", name, p.Name, reason)
+	return true, nil
+}
+
+// controllerCreationHold returns why the creation of the controller deployment is held
+// back, if it is
+func controllerCreationHold(p *pravegav1beta1.PravegaCluster) string {
+	if p.Status.IsImageNotFound() {
+		return "its image was not found"
+	}
+	return ""
+}
+
+// segmentStoreCreationHold returns why the creation of the segment store stateful set is
+// held back, if it is
+func segmentStoreCreationHold(p *pravegav1beta1.PravegaCluster) string {
+	if p.Status.IsImageNotFound() {
+		return "its image was not found"
+	}
+	if p.Status.IsStorageClassNotFound() {
+		return "a storage class of its claims was not found"
+	}
+	return ""
+}
+
+// checkStorageClasses checks, when the operator runs with the storage class check, that
+// the storage classes of the segment store cache and journal claims exist, and sets the
+// StorageClassNotFound condition if one doesn't. The condition then holds back the
+// creation of the segment store stateful set, whose claims would stay pending.
+func (r *ReconcilePravegaCluster) checkStorageClasses(p *pravegav1beta1.PravegaCluster) {
+	if !config.StorageClassCheck {
+		return
+	}
+	missing := ""
+	for _, claim := range segmentStoreClaimStorageClasses(p) {
+		storageClass := &storagev1.StorageClass{}
+		err := r.clusterScoped().Get(context.TODO(), types.NamespacedName{Name: claim.storageClass}, storageClass)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				missing = fmt.Sprintf("storage class %s of the %s claims was not found", claim.storageClass, claim.volume)
+				break
+			}
+			log.Printf("failed to check storage class (%s) of cluster (%s): %v", claim.storageClass, p.Name, err)
+		}
+	}
+	if missing == "" {
+		_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionStorageClassNotFound)
+		if condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == pravegav1beta1.StorageClassNotFoundReason {
+			p.Status.SetStorageClassNotFoundConditionFalse()
+		}
+		return
+	}
+
+	log.Printf("cluster (%s): %s", p.Name, missing)
+	p.Status.SetStorageClassNotFoundConditionTrue(pravegav1beta1.StorageClassNotFoundReason, missing)
+}
+
+// claimStorageClass is the storage class requested by the claims of a segment store volume
+type claimStorageClass struct {
+	volume       string
+	storageClass string
+}
+
+// segmentStoreClaimStorageClasses returns the storage classes set explicitly for the claims
+// of the segment store, i.e. the cache claims below Pravega 0.7 and the journal claims.
+// The claims without a storage class use the default one.
+func segmentStoreClaimStorageClasses(p *pravegav1beta1.PravegaCluster) []claimStorageClass {
+	var claims []claimStorageClass
+	cache := p.Spec.Pravega.CacheVolumeClaimTemplate
+	if util.IsVersionBelow07(p.Spec.Version) && cache != nil && cache.StorageClassName != nil && *cache.StorageClassName != "" {
+		claims = append(claims, claimStorageClass{volume: "cache", storageClass: *cache.StorageClassName})
+	}
+	journal := p.Spec.Pravega.SegmentStoreJournalVolume
+	if journal != nil && journal.StorageClassName != "" {
+		claims = append(claims, claimStorageClass{volume: "journal", storageClass: journal.StorageClassName})
+	}
+	return claims
+}
+
+// syncClaimPendingCondition sets the StorageClassNotFound condition while a segment store
+// claim is pending because its storage class does not exist, as its pod then stays
+// pending without any other error. It clears the condition once no claim is in this case.
+func (r *ReconcilePravegaCluster) syncClaimPendingCondition(p *pravegav1beta1.PravegaCluster) {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	listOps := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(p.LabelsForSegmentStore()),
+	}
+	err := r.client.List(context.TODO(), pvcList, listOps)
+	if err != nil {
+		log.Printf("failed to list segment store pvcs of cluster (%s): %v", p.Name, err)
+		return
+	}
+	sort.Slice(pvcList.Items, func(i, j int) bool {
+		return pvcList.Items[i].Name < pvcList.Items[j].Name
+	})
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if pvc.Status.Phase != corev1.ClaimPending || pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+			continue
+		}
+		name := *pvc.Spec.StorageClassName
+		err = r.clusterScoped().Get(context.TODO(), types.NamespacedName{Name: name}, &storagev1.StorageClass{})
+		if errors.IsNotFound(err) {
+			p.Status.SetStorageClassNotFoundConditionTrue(pravegav1beta1.ClaimPendingReason,
+				fmt.Sprintf("pvc %s is pending, its storage class %s was not found", pvc.Name, name))
+			return
+		}
+	}
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionStorageClassNotFound)
+	if condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == pravegav1beta1.ClaimPendingReason {
+		p.Status.SetStorageClassNotFoundConditionFalse()
+	}
+}
+
+// checkNodeAllocatable compares the per-pod resource requests of a component against
+// the allocatable resources of the nodes, and sets the InsufficientResources condition
+// if no node can fit a single pod. It only warns, scaling proceeds regardless. The
+// condition is cleared once a node can fit the pod, the requests are removed or the
+// pre-check is disabled.
+func (r *ReconcilePravegaCluster) checkNodeAllocatable(p *pravegav1beta1.PravegaCluster, resources *corev1.ResourceRequirements, reason string, component string) error {
+	if !p.Spec.Pravega.SchedulingPreCheck || resources == nil || len(resources.Requests) == 0 {
+		if hasInsufficientResources(p, reason) {
+			p.Status.SetInsufficientResourcesConditionFalse()
+		}
+		return nil
+	}
+	nodeList := &corev1.NodeList{}
+	err := r.clusterScoped().List(context.TODO(), nodeList)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %v", err)
+	}
+	if len(nodeList.Items) == 0 {
+		return nil
+	}
+
+	if util.FitsOnAnyNode(resources.Requests, nodeList.Items) {
+		if hasInsufficientResources(p, reason) {
+			p.Status.SetInsufficientResourcesConditionFalse()
+		}
+		return nil
+	}
+
+	var requests []string
+	for name, quantity := range resources.Requests {
+		requests = append(requests, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(requests)
+	message := fmt.Sprintf("%s pods request %s, which exceeds the allocatable resources of every node",
+		component, strings.Join(requests, ","))
+	log.Printf("cluster (%s): %s", p.Name, message)
+	p.Status.SetInsufficientResourcesConditionTrue(reason, message)
+	return nil
+}
+
+// hasInsufficientResources returns true if the InsufficientResources condition is set
+// for the component of the given reason. The condition is then evaluated again on every
+// reconcile, and not only before a scale-up, so that it does not outlive the shortage.
+func hasInsufficientResources(p *pravegav1beta1.PravegaCluster, reason string) bool {
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionInsufficientResources)
+	return condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == reason
+}
+
+// syncStatefulSetPvc deletes the persistent volume claims of the stateful set whose ordinal
+// is not below its replica count. Only the claims carrying the labels of the stateful set
+// pods and named <claim template>-<stateful set>-<ordinal> are deleted, so that the claims
+// of another stateful set of the cluster are left alone. The cache claims are kept if
+// retainCache is set.
+func (r *ReconcilePravegaCluster) syncStatefulSetPvc(sts *appsv1.StatefulSet, retainCache bool) error {
 	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
 		MatchLabels: sts.Spec.Template.Labels,
 	})
@@ -788,23 +2537,240 @@ func (r *ReconcilePravegaCluster) syncStatefulSetPvc(sts *appsv1.StatefulSet) er
 	}
 
 	for _, pvcItem := range pvcList.Items {
-		if util.IsOrphan(pvcItem.Name, *sts.Spec.Replicas) {
-			pvcDelete := &corev1.PersistentVolumeClaim{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      pvcItem.Name,
-					Namespace: pvcItem.Namespace,
-				},
-			}
+		template, ok := claimTemplateOf(pvcItem.Name, sts)
+		if !ok || !util.IsOrphan(pvcItem.Name, *sts.Spec.Replicas) {
+			continue
+		}
+		if template == cacheClaimTemplateName && retainCache {
+			continue
+		}
+		pvcDelete := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pvcItem.Name,
+				Namespace: pvcItem.Namespace,
+			},
+		}
 
-			err = r.client.Delete(context.TODO(), pvcDelete)
+		log.Printf("deleting pvc (%s) of statefulset (%s) scaled to %d replicas", pvcItem.Name, sts.Name, *sts.Spec.Replicas)
+		err = r.client.Delete(context.TODO(), pvcDelete)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pvc: %v", err)
+		}
+	}
+	return nil
+}
+
+// claimTemplateOf returns the name of the volume claim template of the stateful set the
+// claim was created from, if the claim is named <claim template>-<stateful set>-<ordinal>
+func claimTemplateOf(claimName string, sts *appsv1.StatefulSet) (string, bool) {
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		prefix := template.Name + "-" + sts.Name + "-"
+		if !strings.HasPrefix(claimName, prefix) {
+			continue
+		}
+		ordinal := strings.TrimPrefix(claimName, prefix)
+		if _, err := strconv.ParseUint(ordinal, 10, 32); err == nil {
+			return template.Name, true
+		}
+	}
+	return "", false
+}
+
+// syncStatefulSetPvcSize expands the persistent volume claims of the segment stores
+// whose claim template requests more storage than they have. The volume claim templates
+// of the stateful set are immutable and are left unchanged. Claims are never shrunk.
+// Claims whose storage class does not allow volume expansion are skipped, and the
+// VolumeExpansionBlocked condition is set until none is left.
+func (r *ReconcilePravegaCluster) syncStatefulSetPvcSize(p *pravegav1beta1.PravegaCluster, sts *appsv1.StatefulSet) error {
+	blocked := ""
+	defer func() {
+		r.syncVolumeExpansionBlockedCondition(p, blocked)
+	}()
+	templates := pravega.MakeSegmentStoreStatefulSet(p).Spec.VolumeClaimTemplates
+	if len(templates) == 0 {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: sts.Spec.Template.Labels,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	pvclistOps := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), pvcList, pvclistOps)
+	if err != nil {
+		return err
+	}
+	sort.Slice(pvcList.Items, func(i, j int) bool {
+		return pvcList.Items[i].Name < pvcList.Items[j].Name
+	})
+
+	for _, template := range templates {
+		size, ok := template.Spec.Resources.Requests[corev1.ResourceStorage]
+		if !ok {
+			continue
+		}
+		// The claims of a template are named <template>-<stateful set>-<ordinal>
+		prefix := fmt.Sprintf("%s-%s-", template.Name, sts.Name)
+		for i := range pvcList.Items {
+			pvc := &pvcList.Items[i]
+			if !strings.HasPrefix(pvc.Name, prefix) {
+				continue
+			}
+			current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			if size.Cmp(current) <= 0 {
+				continue
+			}
+			reason, err := r.volumeExpansionBlocked(pvc)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				if blocked == "" {
+					blocked = reason
+				}
+				continue
+			}
+			log.Printf("expanding pvc (%s) from %s to %s", pvc.Name, current.String(), size.String())
+			if pvc.Spec.Resources.Requests == nil {
+				pvc.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+			err = r.client.Update(context.TODO(), pvc)
 			if err != nil {
-				return fmt.Errorf("failed to delete pvc: %v", err)
+				return fmt.Errorf("failed to expand pvc (%s): %v", pvc.Name, err)
 			}
 		}
 	}
 	return nil
 }
 
+// syncPvcLabels adds the pvc labels to the claims already created from the volume claim
+// templates of the segment stores, which are immutable, and to the FileSystem Tier 2
+// claim. Labels are only added or updated, labels removed from the spec are left on the
+// claims.
+func (r *ReconcilePravegaCluster) syncPvcLabels(p *pravegav1beta1.PravegaCluster, sts *appsv1.StatefulSet) error {
+	labels := p.Spec.Pravega.PVCLabels
+	if len(labels) == 0 {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: sts.Spec.Template.Labels,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	pvclistOps := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), pvcList, pvclistOps)
+	if err != nil {
+		return err
+	}
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if _, ok := claimTemplateOf(pvc.Name, sts); !ok {
+			continue
+		}
+		err = r.addPvcLabels(pvc, labels)
+		if err != nil {
+			return err
+		}
+	}
+
+	lts := p.Spec.Pravega.LongTermStorage
+	if lts == nil || lts.FileSystem == nil || lts.FileSystem.PersistentVolumeClaim == nil {
+		return nil
+	}
+	claimName := lts.FileSystem.PersistentVolumeClaim.ClaimName
+	pvc := &corev1.PersistentVolumeClaim{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: claimName, Namespace: p.Namespace}, pvc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get tier2 pvc (%s): %v", claimName, err)
+	}
+	return r.addPvcLabels(pvc, labels)
+}
+
+// addPvcLabels sets the labels on the claim, if it does not have them yet
+func (r *ReconcilePravegaCluster) addPvcLabels(pvc *corev1.PersistentVolumeClaim, labels map[string]string) error {
+	changed := false
+	for key, value := range labels {
+		if current, ok := pvc.Labels[key]; ok && current == value {
+			continue
+		}
+		if pvc.Labels == nil {
+			pvc.Labels = map[string]string{}
+		}
+		pvc.Labels[key] = value
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	log.Printf("adding labels to pvc (%s)", pvc.Name)
+	err := r.client.Update(context.TODO(), pvc)
+	if err != nil {
+		return fmt.Errorf("failed to label pvc (%s): %v", pvc.Name, err)
+	}
+	return nil
+}
+
+// volumeExpansionBlocked returns why the claim cannot be expanded, if its storage class
+// does not allow volume expansion
+func (r *ReconcilePravegaCluster) volumeExpansionBlocked(pvc *corev1.PersistentVolumeClaim) (string, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return fmt.Sprintf("pvc (%s) has no storage class and cannot be expanded", pvc.Name), nil
+	}
+	name := *pvc.Spec.StorageClassName
+	storageClass := &storagev1.StorageClass{}
+	err := r.clusterScoped().Get(context.TODO(), types.NamespacedName{Name: name}, storageClass)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("pvc (%s) cannot be expanded: storage class (%s) was not found", pvc.Name, name), nil
+		}
+		return "", fmt.Errorf("failed to get storage class (%s) of pvc (%s): %v", name, pvc.Name, err)
+	}
+	if storageClass.AllowVolumeExpansion == nil || !*storageClass.AllowVolumeExpansion {
+		return fmt.Sprintf("pvc (%s) cannot be expanded: storage class (%s) does not allow volume expansion", pvc.Name, name), nil
+	}
+	return "", nil
+}
+
+// syncVolumeExpansionBlockedCondition sets the VolumeExpansionBlocked condition, and
+// publishes a warning event when it is set or its message changes, while a claim cannot
+// be expanded. It clears the condition once no claim is in this case.
+func (r *ReconcilePravegaCluster) syncVolumeExpansionBlockedCondition(p *pravegav1beta1.PravegaCluster, blocked string) {
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionVolumeExpansionBlocked)
+	set := condition != nil && condition.Status == corev1.ConditionTrue
+	if blocked == "" {
+		if set {
+			p.Status.SetVolumeExpansionBlockedConditionFalse()
+		}
+		return
+	}
+	if set && condition.Message == blocked {
+		return
+	}
+	log.Printf("cluster (%s): %s", p.Name, blocked)
+	p.Status.SetVolumeExpansionBlockedConditionTrue(pravegav1beta1.VolumeExpansionNotAllowedReason, blocked)
+	event := p.NewEvent("VOLUME_EXPANSION_BLOCKED", pravegav1beta1.VolumeExpansionNotAllowedReason, blocked, "Warning")
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing volume expansion blocked event to k8s. %v", err)
+	}
+}
+
 func (r *ReconcilePravegaCluster) syncStatefulSetExternalServices(sts *appsv1.StatefulSet) error {
 	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
 		MatchLabels: sts.Spec.Template.Labels,
@@ -859,16 +2825,28 @@ func (r *ReconcilePravegaCluster) reconcileClusterStatus(p *pravegav1beta1.Prave
 	var (
 		readyMembers   []string
 		unreadyMembers []string
+		failedMembers  []string
+		versions       map[string]string
 	)
 
+	now := time.Now()
 	for _, p := range podList.Items {
+		if versions == nil {
+			versions = map[string]string{}
+		}
+		versions[p.Name] = util.GetPodImageTag(&p)
 		if util.IsPodReady(&p) {
 			readyMembers = append(readyMembers, p.Name)
 		} else {
 			unreadyMembers = append(unreadyMembers, p.Name)
+			if util.IsPodFailed(&p, FailedPodThreshold, now) {
+				failedMembers = append(failedMembers, p.Name)
+			}
 		}
 	}
 
+	wasReady := p.Status.IsClusterInReadyState()
+	readinessChanged := p.Status.ReadyReplicas != int32(len(readyMembers)) || p.Status.Replicas != int32(expectedSize)
 	if len(readyMembers) == expectedSize {
 		p.Status.SetPodsReadyConditionTrue()
 	} else {
@@ -878,16 +2856,555 @@ func (r *ReconcilePravegaCluster) reconcileClusterStatus(p *pravegav1beta1.Prave
 	p.Status.Replicas = int32(expectedSize)
 	p.Status.CurrentReplicas = int32(len(podList.Items))
 	p.Status.ReadyReplicas = int32(len(readyMembers))
+	syncSegmentStoreScaleStatus(p, podList.Items)
 	p.Status.Members.Ready = readyMembers
 	p.Status.Members.Unready = unreadyMembers
+	p.Status.Members.Failed = failedMembers
+	p.Status.Members.Versions = versions
+	syncPodsFailedCondition(p)
+	r.syncScalingStalledCondition(p, podList.Items, readinessChanged, now)
+
+	r.syncSegmentContainerStatus(p, podList.Items, now)
+	r.syncSegmentStoreEndpoints(p)
+	r.syncControllerEndpoint(p)
+	r.syncThroughputStatus(p, podList.Items, now)
+	r.syncLtsReachableCondition(p, podList.Items, now)
+	r.syncClaimPendingCondition(p)
+
+	// Scaling lasts until all the desired pods are ready, and the upgrade
+	// phases until the upgrade or rollback is over
+	if !p.Status.IsClusterInUpgradingState() && !p.Status.IsClusterInRollbackState() &&
+		(p.Status.ReconcilePhase != pravegav1beta1.ReconcilePhaseScaling || len(readyMembers) == expectedSize) {
+		p.Status.ReconcilePhase = pravegav1beta1.ReconcilePhaseIdle
+	}
 
 	err = r.client.Status().Update(context.TODO(), p)
 	if err != nil {
 		return fmt.Errorf("failed to update cluster status: %v", err)
 	}
+
+	// The event is published once the ready transition is recorded, so that it is
+	// published once per transition
+	if !wasReady && p.Status.IsClusterInReadyState() {
+		r.publishClusterReadyEvent(p)
+	}
 	return nil
 }
 
+// syncSegmentStoreScaleStatus sets the number of segment store pods and their selector,
+// which the scale subresource of the cluster reports
+func syncSegmentStoreScaleStatus(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod) {
+	selector := labels.SelectorFromSet(p.LabelsForSegmentStore())
+	segmentStores := int32(0)
+	for i := range pods {
+		if selector.Matches(labels.Set(pods[i].Labels)) {
+			segmentStores++
+		}
+	}
+	p.Status.SegmentStoreReplicas = segmentStores
+	p.Status.SegmentStoreSelector = selector.String()
+}
+
+// syncPodsFailedCondition sets the pods failed condition while some members are failed,
+// and clears it once they all recover
+func syncPodsFailedCondition(p *pravegav1beta1.PravegaCluster) {
+	failed := p.Status.Members.Failed
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		p.Status.SetPodsFailedConditionTrue(pravegav1beta1.PodsNotProgressingReason,
+			fmt.Sprintf("%d pods not ready for more than %v: %s", len(failed), FailedPodThreshold, strings.Join(failed, ", ")))
+		return
+	}
+	if _, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionPodsFailed); condition != nil {
+		p.Status.SetPodsFailedConditionFalse()
+	}
+}
+
+// syncScalingStalledCondition records the last time the number of ready or desired pods
+// changed, and sets the ScalingStalled condition while fewer pods than desired have been
+// ready since then for longer than the scaling stall timeout. The condition is cleared
+// as soon as a pod becomes ready or unready, or the desired size changes.
+func (r *ReconcilePravegaCluster) syncScalingStalledCondition(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod, readinessChanged bool, now time.Time) {
+	if readinessChanged || p.Status.LastReadinessChangeTime == "" {
+		p.Status.LastReadinessChangeTime = now.UTC().Format(time.RFC3339)
+	}
+	stalled := false
+	if p.Status.ReadyReplicas < p.Status.Replicas && !p.Status.IsClusterInUpgradingState() && !p.Status.IsClusterInRollbackState() {
+		since, err := time.Parse(time.RFC3339, p.Status.LastReadinessChangeTime)
+		stalled = err == nil && now.Sub(since) > p.ScalingStallTimeout()
+	}
+	if stalled {
+		reason, cause := r.scalingStallCause(p, pods)
+		p.Status.SetScalingStalledConditionTrue(reason, fmt.Sprintf("%d of %d pods ready for more than %v, %s",
+			p.Status.ReadyReplicas, p.Status.Replicas, p.ScalingStallTimeout(), cause))
+		return
+	}
+	_, condition := p.Status.GetClusterCondition(pravegav1beta1.ClusterConditionScalingStalled)
+	if condition != nil && condition.Status == corev1.ConditionTrue {
+		p.Status.SetScalingStalledConditionFalse()
+	}
+}
+
+// scalingStallCause returns the reason and the cause of a stalled scaling: the pending
+// claims of the segment stores if any, else the pending pods, else the unready pods
+func (r *ReconcilePravegaCluster) scalingStallCause(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod) (string, string) {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	listOps := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(p.LabelsForSegmentStore()),
+	}
+	err := r.client.List(context.TODO(), pvcList, listOps)
+	if err != nil {
+		log.Printf("failed to list segment store pvcs of cluster (%s): %v", p.Name, err)
+	}
+	var pendingClaims, pendingPods, unreadyPods []string
+	for _, pvc := range pvcList.Items {
+		if pvc.Status.Phase == corev1.ClaimPending {
+			pendingClaims = append(pendingClaims, pvc.Name)
+		}
+	}
+	for i := range pods {
+		if util.IsPodReady(&pods[i]) {
+			continue
+		}
+		unreadyPods = append(unreadyPods, pods[i].Name)
+		if pods[i].Status.Phase == corev1.PodPending {
+			pendingPods = append(pendingPods, pods[i].Name)
+		}
+	}
+	sort.Strings(pendingClaims)
+	sort.Strings(pendingPods)
+	sort.Strings(unreadyPods)
+	switch {
+	case len(pendingClaims) > 0:
+		return pravegav1beta1.ClaimPendingReason, "pending pvcs: " + strings.Join(pendingClaims, ", ")
+	case len(pendingPods) > 0:
+		return pravegav1beta1.PodsPendingReason, "pending pods: " + strings.Join(pendingPods, ", ")
+	case len(unreadyPods) > 0:
+		return pravegav1beta1.PodsNotReadyReason, "unready pods: " + strings.Join(unreadyPods, ", ")
+	default:
+		return pravegav1beta1.PodsPendingReason, fmt.Sprintf("%d pods not created", p.Status.Replicas-p.Status.CurrentReplicas)
+	}
+}
+
+// publishClusterReadyEvent publishes an event summarizing the cluster that became ready.
+// The version and replica counts are also set as annotations of the event, for
+// automation to consume them.
+func (r *ReconcilePravegaCluster) publishClusterReadyEvent(p *pravegav1beta1.PravegaCluster) {
+	version := p.Status.CurrentVersion
+	if version == "" {
+		version = p.Spec.Version
+	}
+	message := fmt.Sprintf("Pravega cluster %s is ready: version %s, %d controller replicas, %d segment store replicas",
+		p.Name, version, p.Spec.Pravega.ControllerReplicas, p.Spec.Pravega.SegmentStoreReplicas)
+	event := p.NewEvent("CLUSTER_READY", pravegav1beta1.ClusterReadyReason, message, "Normal")
+	event.Annotations = map[string]string{
+		clusterReadyVersionAnnotation:              version,
+		clusterReadyControllerReplicasAnnotation:   strconv.Itoa(int(p.Spec.Pravega.ControllerReplicas)),
+		clusterReadySegmentStoreReplicasAnnotation: strconv.Itoa(int(p.Spec.Pravega.SegmentStoreReplicas)),
+	}
+	err := r.client.Create(context.TODO(), event)
+	if err != nil {
+		log.Printf("Error publishing cluster ready event to k8s. %v", err)
+	}
+}
+
+// segmentContainerSyncInterval is how long the segment container counts are kept before
+// the mapping is read again from zookeeper
+const segmentContainerSyncInterval = time.Minute
+
+// segmentContainerSyncs records when the segment container mapping of each cluster was
+// last read, so that zookeeper is not asked on every reconcile
+var segmentContainerSyncs = struct {
+	sync.Mutex
+	times map[types.NamespacedName]time.Time
+}{times: map[types.NamespacedName]time.Time{}}
+
+// segmentContainerSyncDue returns whether the segment container mapping of the cluster
+// is to be read again, and records the read
+func segmentContainerSyncDue(key types.NamespacedName, now time.Time) bool {
+	segmentContainerSyncs.Lock()
+	defer segmentContainerSyncs.Unlock()
+	if last, ok := segmentContainerSyncs.times[key]; ok && now.Sub(last) < segmentContainerSyncInterval {
+		return false
+	}
+	segmentContainerSyncs.times[key] = now
+	return true
+}
+
+func forgetSegmentContainerSync(key types.NamespacedName) {
+	segmentContainerSyncs.Lock()
+	defer segmentContainerSyncs.Unlock()
+	delete(segmentContainerSyncs.times, key)
+}
+
+// syncSegmentContainerStatus records the number of segment containers hosted by each
+// segment store, as assigned by the controller in zookeeper. The mapping is read at most
+// every segmentContainerSyncInterval, once a controller pod is ready, and failures keep
+// the last recorded counts.
+func (r *ReconcilePravegaCluster) syncSegmentContainerStatus(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod, now time.Time) {
+	controllerReady := false
+	var segmentStorePods []corev1.Pod
+	for _, pod := range pods {
+		switch pod.Labels["component"] {
+		case "pravega-controller":
+			controllerReady = controllerReady || util.IsPodReady(&pod)
+		case "pravega-segmentstore":
+			segmentStorePods = append(segmentStorePods, pod)
+		}
+	}
+	if !controllerReady || !segmentContainerSyncDue(types.NamespacedName{Name: p.Name, Namespace: p.Namespace}, now) {
+		return
+	}
+	mapping, err := util.GetSegmentContainerMapping(p.Spec.ZookeeperUri, p.Name)
+	if err != nil {
+		log.Printf("failed to sync segment container status of cluster (%s): %v", p.Name, err)
+		return
+	}
+	p.Status.SegmentContainers = segmentContainerCounts(mapping, segmentStorePods)
+}
+
+// syncSegmentStoreEndpoints records the address of the external service of each segment
+// store once its load balancer is provisioned, so that clients can discover them. Failures
+// keep the last recorded endpoints.
+func (r *ReconcilePravegaCluster) syncSegmentStoreEndpoints(p *pravegav1beta1.PravegaCluster) {
+	if p.Spec.ExternalAccess == nil || !p.Spec.ExternalAccess.Enabled {
+		p.Status.SegmentStoreEndpoints = nil
+		return
+	}
+	endpoints := map[string]string{}
+	for i := int32(0); i < p.Spec.Pravega.SegmentStoreReplicas; i++ {
+		service := &corev1.Service{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForSegmentStore(i), Namespace: p.Namespace}, service)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Printf("failed to sync segment store endpoints of cluster (%s): %v", p.Name, err)
+			return
+		}
+		if len(service.Spec.Ports) == 0 {
+			continue
+		}
+		endpoint := loadBalancerEndpoint(service, service.Spec.Ports[0].Port)
+		if endpoint == "" {
+			continue
+		}
+		podName := fmt.Sprintf("%s-%d", p.StatefulSetNameForSegmentstore(), i)
+		endpoints[podName] = endpoint
+	}
+	if len(endpoints) == 0 {
+		endpoints = nil
+	}
+	p.Status.SegmentStoreEndpoints = endpoints
+}
+
+// syncControllerEndpoint records the address of the gRPC port of the controller external
+// service once its load balancer is provisioned. Failures keep the last recorded endpoint.
+func (r *ReconcilePravegaCluster) syncControllerEndpoint(p *pravegav1beta1.PravegaCluster) {
+	if p.Spec.ExternalAccess == nil || !p.Spec.ExternalAccess.Enabled {
+		p.Status.ControllerEndpoint = ""
+		return
+	}
+	service := &corev1.Service{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForController(), Namespace: p.Namespace}, service)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			p.Status.ControllerEndpoint = ""
+			return
+		}
+		log.Printf("failed to sync controller endpoint of cluster (%s): %v", p.Name, err)
+		return
+	}
+	for _, port := range service.Spec.Ports {
+		if port.Name == "grpc" {
+			p.Status.ControllerEndpoint = loadBalancerEndpoint(service, port.Port)
+			return
+		}
+	}
+	p.Status.ControllerEndpoint = ""
+}
+
+// loadBalancerEndpoint returns the host:port of the given port of a service, the host
+// being the hostname of its load balancer or its IP address if it has no hostname. It
+// is empty until the load balancer is provisioned.
+func loadBalancerEndpoint(service *corev1.Service, port int32) string {
+	ingress := service.Status.LoadBalancer.Ingress
+	if len(ingress) == 0 {
+		return ""
+	}
+	host := ingress[0].Hostname
+	if host == "" {
+		host = ingress[0].IP
+	}
+	if host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// syncThroughputStatus samples the write throughput of the segment stores, at most once
+// per throughput status interval. The sample is skipped when a ready segment store
+// cannot be scraped, as a partial sum would be taken for a counter reset.
+func (r *ReconcilePravegaCluster) syncThroughputStatus(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod, now time.Time) {
+	if !config.ThroughputStatus {
+		return
+	}
+	last := p.Status.Throughput
+	if last != nil {
+		sampleTime, err := time.Parse(time.RFC3339, last.LastSampleTime)
+		if err == nil && now.Sub(sampleTime) < config.ThroughputStatusInterval {
+			return
+		}
+	}
+	total := 0.0
+	scraped := false
+	for _, pod := range pods {
+		if pod.Labels["component"] != "pravega-segmentstore" || !util.IsPodReady(&pod) || pod.Status.PodIP == "" {
+			continue
+		}
+		url := fmt.Sprintf("http://%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(segmentStoreMetricsPort)))
+		value, err := util.GetPrometheusCounter(url, p.MetricName(segmentStoreWriteBytesMetric))
+		if err != nil {
+			log.Printf("failed to sync throughput status of cluster (%s) from pod (%s): %v", p.Name, pod.Name, err)
+			return
+		}
+		total += value
+		scraped = true
+	}
+	if !scraped {
+		return
+	}
+	p.Status.Throughput = nextThroughputStatus(last, int64(total), now)
+}
+
+// nextThroughputStatus computes the throughput from the previous sample and the
+// current total of the write byte counters
+func nextThroughputStatus(last *pravegav1beta1.ThroughputStatus, total int64, now time.Time) *pravegav1beta1.ThroughputStatus {
+	next := &pravegav1beta1.ThroughputStatus{
+		WriteBytesTotal: total,
+		LastSampleTime:  now.UTC().Format(time.RFC3339),
+	}
+	if last == nil {
+		return next
+	}
+	sampleTime, err := time.Parse(time.RFC3339, last.LastSampleTime)
+	if err != nil {
+		return next
+	}
+	elapsed := now.Sub(sampleTime).Seconds()
+	// The counters dropped when a segment store restarted, the rate is known at the next sample
+	if elapsed <= 0 || total < last.WriteBytesTotal {
+		return next
+	}
+	next.WriteBytesPerSecond = int64(float64(total-last.WriteBytesTotal) / elapsed)
+	return next
+}
+
+// segmentContainerCounts matches the hosts of the container mapping with the segment
+// store pods, by pod IP or pod name, and counts the containers of each pod ordinal
+func segmentContainerCounts(mapping map[string][]int32, pods []corev1.Pod) []pravegav1beta1.SegmentContainerStatus {
+	counts := []pravegav1beta1.SegmentContainerStatus{}
+	for _, pod := range pods {
+		index := strings.LastIndex(pod.Name, "-")
+		ordinal, err := strconv.Atoi(pod.Name[index+1:])
+		if err != nil {
+			continue
+		}
+		count := int32(0)
+		for host, containers := range mapping {
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if (pod.Status.PodIP != "" && host == pod.Status.PodIP) || host == pod.Name || strings.HasPrefix(host, pod.Name+".") {
+				count += int32(len(containers))
+			}
+		}
+		counts = append(counts, pravegav1beta1.SegmentContainerStatus{
+			Ordinal:        int32(ordinal),
+			PodName:        pod.Name,
+			ContainerCount: count,
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Ordinal < counts[j].Ordinal
+	})
+	return counts
+}
+
+// syncLtsReachableCondition sets the LtsReachable condition. The long term storage is
+// unreachable if its claim is not bound, or if no segment store is ready and all of them
+// restart, as the segment stores fail to start without it. It is reachable once a segment
+// store is ready. Otherwise, e.g. while the segment stores start, the condition is left
+// unchanged.
+func (r *ReconcilePravegaCluster) syncLtsReachableCondition(p *pravegav1beta1.PravegaCluster, pods []corev1.Pod, now time.Time) {
+	if p.Spec.Pravega.LongTermStorageProbe {
+		r.probeLongTermStorage(p, now)
+		return
+	}
+	lts := p.Spec.Pravega.LongTermStorage
+	if lts != nil && lts.FileSystem != nil && lts.FileSystem.PersistentVolumeClaim != nil {
+		claimName := lts.FileSystem.PersistentVolumeClaim.ClaimName
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: claimName, Namespace: p.Namespace}, pvc)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Printf("failed to get tier2 pvc (%s): %v", claimName, err)
+			return
+		}
+		if err != nil || pvc.Status.Phase != corev1.ClaimBound {
+			p.Status.SetLtsReachableConditionFalse(pravegav1beta1.LtsClaimNotBoundReason,
+				fmt.Sprintf("tier2 pvc %s is not bound", claimName))
+			return
+		}
+	}
+
+	selector := labels.SelectorFromSet(p.LabelsForSegmentStore())
+	failing := []string{}
+	segmentStores := 0
+	for i := range pods {
+		pod := &pods[i]
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		segmentStores++
+		if util.IsPodReady(pod) {
+			p.Status.SetLtsReachableConditionTrue()
+			return
+		}
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.RestartCount > 0 {
+				failing = append(failing, pod.Name)
+				break
+			}
+		}
+	}
+	if segmentStores > 0 && len(failing) == segmentStores {
+		sort.Strings(failing)
+		p.Status.SetLtsReachableConditionFalse(pravegav1beta1.SegmentStoresFailingReason,
+			fmt.Sprintf("segment store pods %s restart without becoming ready, check that they can reach the long term storage", strings.Join(failing, ", ")))
+	}
+}
+
+// probeLongTermStorage checks, at most once per probe interval, that the operator can reach
+// the long term storage and sets the LtsReachable condition from the result
+func (r *ReconcilePravegaCluster) probeLongTermStorage(p *pravegav1beta1.PravegaCluster, now time.Time) {
+	if last, err := time.Parse(time.RFC3339, p.Status.LtsLastProbeTime); err == nil && now.Sub(last) < p.LongTermStorageProbeInterval() {
+		return
+	}
+	p.Status.LtsLastProbeTime = now.UTC().Format(time.RFC3339)
+
+	reason, err := r.checkLongTermStorage(p)
+	if err != nil {
+		log.Printf("long term storage of cluster (%s) is not reachable: %v", p.Name, err)
+		p.Status.SetLtsReachableConditionFalse(reason, err.Error())
+		return
+	}
+	p.Status.SetLtsReachableConditionTrue()
+}
+
+// checkLongTermStorage returns an error, and the reason of the LtsReachable condition, if the
+// long term storage cannot be reached. The keys are read from the referenced secrets and
+// never logged
+func (r *ReconcilePravegaCluster) checkLongTermStorage(p *pravegav1beta1.PravegaCluster) (string, error) {
+	lts := p.Spec.Pravega.LongTermStorage
+	switch {
+	case lts == nil:
+		return "", nil
+	case lts.FileSystem != nil:
+		if lts.FileSystem.PersistentVolumeClaim == nil {
+			return "", nil
+		}
+		claimName := lts.FileSystem.PersistentVolumeClaim.ClaimName
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: claimName, Namespace: p.Namespace}, pvc)
+		if err != nil && !errors.IsNotFound(err) {
+			return pravegav1beta1.LtsProbeFailedReason, fmt.Errorf("failed to get tier2 pvc (%s): %v", claimName, err)
+		}
+		if err != nil || pvc.Status.Phase != corev1.ClaimBound {
+			return pravegav1beta1.LtsClaimNotBoundReason, fmt.Errorf("tier2 pvc %s is not bound", claimName)
+		}
+		return "", nil
+	case lts.S3 != nil:
+		probe := util.BucketProbe{
+			Endpoint: lts.S3.Endpoint,
+			Region:   lts.S3.Region,
+			Bucket:   lts.S3.Bucket,
+		}
+		if err := r.readBucketKeys(p.Namespace, lts.S3.SecretRef, &probe); err != nil {
+			return pravegav1beta1.LtsProbeFailedReason, err
+		}
+		return pravegav1beta1.LtsProbeFailedReason, probeBucket(probe)
+	case lts.Ecs != nil:
+		ecs := lts.Ecs
+		probe := util.BucketProbe{
+			Endpoint:  ecs.ConfigUri,
+			Bucket:    ecs.Bucket,
+			Namespace: ecs.Namespace,
+		}
+		if probe.Namespace == "" {
+			// without useV2, the namespace is an option of the config uri
+			if configUri, err := url.Parse(ecs.ConfigUri); err == nil {
+				probe.Namespace = configUri.Query().Get("namespace")
+			}
+		}
+		if ecs.Credentials != "" {
+			if err := r.readBucketKeys(p.Namespace, ecs.Credentials, &probe); err != nil {
+				return pravegav1beta1.LtsProbeFailedReason, err
+			}
+		}
+		if ecs.SessionToken != nil {
+			token, err := r.readSecretKey(p.Namespace, ecs.SessionToken.Name, ecs.SessionToken.Key)
+			if err != nil {
+				return pravegav1beta1.LtsProbeFailedReason, err
+			}
+			probe.SessionToken = token
+		}
+		if ecs.CaBundleSecret != "" {
+			secret := &corev1.Secret{}
+			err := r.client.Get(context.TODO(), types.NamespacedName{Name: ecs.CaBundleSecret, Namespace: p.Namespace}, secret)
+			if err != nil {
+				return pravegav1beta1.LtsProbeFailedReason, fmt.Errorf("failed to get the CA bundle secret %s: %v", ecs.CaBundleSecret, err)
+			}
+			keys := make([]string, 0, len(secret.Data))
+			for key := range secret.Data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				probe.CaBundle = append(append(probe.CaBundle, secret.Data[key]...), '\n')
+			}
+		}
+		return pravegav1beta1.LtsProbeFailedReason, probeBucket(probe)
+	case lts.Hdfs != nil:
+		return pravegav1beta1.LtsProbeFailedReason, probeAddress(lts.Hdfs.Uri, defaultHdfsPort)
+	}
+	return "", nil
+}
+
+// readBucketKeys reads the access and secret keys of the object store from the secret,
+// which the segment stores get as the EXTENDEDS3_ACCESS_KEY_ID and EXTENDEDS3_SECRET_KEY variables
+func (r *ReconcilePravegaCluster) readBucketKeys(namespace, name string, probe *util.BucketProbe) (err error) {
+	if probe.AccessKey, err = r.readSecretKey(namespace, name, "ACCESS_KEY_ID"); err != nil {
+		return err
+	}
+	probe.SecretKey, err = r.readSecretKey(namespace, name, "SECRET_KEY")
+	return err
+}
+
+// readSecretKey returns the value of the key of the secret. The error never holds the value
+func (r *ReconcilePravegaCluster) readSecretKey(namespace, name, key string) (string, error) {
+	secret := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %v", name, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no %s key", name, key)
+	}
+	return string(value), nil
+}
+
 func (r *ReconcilePravegaCluster) rollbackFailedUpgrade(p *pravegav1beta1.PravegaCluster) error {
 	if r.isRollbackTriggered(p) {
 		// start rollback to previous version
@@ -13,21 +13,39 @@ package pravegacluster
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	. "github.com/onsi/ginkgo"
@@ -39,163 +57,3291 @@ func TestPravega(t *testing.T) {
 	RunSpecs(t, "Pravega cluster")
 }
 
+// serviceMonitorClient serves the ServiceMonitors, which the fake client cannot
+// decode, and records the created ones. If not installed, it fails as if the
+// monitoring.coreos.com CRDs were missing.
+type serviceMonitorClient struct {
+	client.Client
+	installed bool
+	created   []*unstructured.Unstructured
+}
+
+func (c *serviceMonitorClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind() == pravega.ServiceMonitorGVK {
+		if !c.installed {
+			return &meta.NoKindMatchError{GroupKind: pravega.ServiceMonitorGVK.GroupKind()}
+		}
+		return errors.NewNotFound(schema.GroupResource{Group: "monitoring.coreos.com", Resource: "servicemonitors"}, key.Name)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *serviceMonitorClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind() == pravega.ServiceMonitorGVK {
+		c.created = append(c.created, u)
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
 var _ = Describe("PravegaCluster Controller", func() {
 	const (
 		Name      = "example"
 		Namespace = "default"
 	)
 
-	var (
-		s = scheme.Scheme
-		r *ReconcilePravegaCluster
-	)
+	var (
+		s = scheme.Scheme
+		r *ReconcilePravegaCluster
+	)
+
+	Context("Reconcile", func() {
+		var (
+			req reconcile.Request
+			res reconcile.Result
+			p   *v1beta1.PravegaCluster
+		)
+
+		BeforeEach(func() {
+			req = reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      Name,
+					Namespace: Namespace,
+				},
+			}
+			p = &v1beta1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      Name,
+					Namespace: Namespace,
+				},
+			}
+			s.AddKnownTypes(v1beta1.SchemeGroupVersion, p)
+		})
+		Context("Should have Reconcile Result false when request namespace does not contain pravega cluster", func() {
+			var (
+				client client.Client
+				err    error
+			)
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				req.NamespacedName.Namespace = "temp"
+				res, err = r.Reconcile(req)
+			})
+			It("should have false in reconcile result", func() {
+				Ω(res.Requeue).To(Equal(false))
+				Ω(err).To(BeNil())
+			})
+		})
+
+		Context("Delete STS with sts not present", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				//1st reconcile
+				foundPravega = &v1beta1.PravegaCluster{}
+				_, _ = r.Reconcile(req)
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				err = r.deleteSTS(foundPravega)
+			})
+			It("shouldn't error", func() {
+				Ω(err).Should(BeNil())
+			})
+		})
+		Context("deleteOldSegmentStoreIfExist ", func() {
+			var (
+				client       client.Client
+				err, err1    error
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				//1st reconcile
+				foundPravega = &v1beta1.PravegaCluster{}
+				_, _ = r.Reconcile(req)
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				pvc := &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cache-example-pravega-segmentstore-0",
+						Namespace: p.Namespace,
+					},
+				}
+				r.client.Create(context.TODO(), pvc)
+				svc := &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "example-pravega-segmentstore-0",
+						Namespace: p.Namespace,
+					},
+				}
+				r.client.Create(context.TODO(), svc)
+				sts := &appsv1.StatefulSet{}
+				foundPravega.Spec.Version = "0.5.0"
+				foundPravega.Spec.Pravega.SegmentStoreReplicas = 2
+				foundPravega.Spec.ExternalAccess.Enabled = true
+				foundPravega.WithDefaults()
+				sts = pravega.MakeSegmentStoreStatefulSet(foundPravega)
+				extService := &corev1.Service{}
+				pravega.MakeSegmentStoreExternalServices(foundPravega)
+				extService.Name = p.ServiceNameForSegmentStoreBelow07(int32(0))
+				r.client.Create(context.TODO(), extService)
+				svcName := p.ServiceNameForSegmentStoreBelow07(int32(0))
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: svcName, Namespace: foundPravega.Namespace}, extService)
+				r.client.Create(context.TODO(), sts)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: sts.Name, Namespace: foundPravega.Namespace}, sts)
+				err = r.deleteOldSegmentStoreIfExists(foundPravega)
+				foundPravega.Spec.ExternalAccess.Enabled = false
+				err1 = r.deleteOldSegmentStoreIfExists(foundPravega)
+			})
+			It("shouldn't error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("shouldn't error", func() {
+				Ω(err1).Should(BeNil())
+			})
+		})
+		Context("syncControllerSize", func() {
+			var (
+				client       client.Client
+				err, err1    error
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				//1st reconcile
+				foundPravega = &v1beta1.PravegaCluster{}
+				_, _ = r.Reconcile(req)
+
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				deploy := &appsv1.Deployment{}
+
+				r.client.Create(context.TODO(), deploy)
+				name := p.DeploymentNameForController()
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
+
+				foundPravega.Spec.Pravega.ControllerReplicas = 2
+				err1 = r.syncControllerSize(foundPravega)
+				_, _ = r.Reconcile(req)
+				err = r.syncControllerSize(foundPravega)
+
+			})
+			It("should not give error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should give error", func() {
+				Ω(strings.ContainsAny(err1.Error(), "failed to get deployment")).Should(Equal(true))
+			})
+		})
+		Context("disruptive actions gated by maintenance windows", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				deploy       *appsv1.Deployment
+				closedWindow v1beta1.MaintenanceWindow
+				openWindow   v1beta1.MaintenanceWindow
+			)
+
+			getDeployment := func() *appsv1.Deployment {
+				d := &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: Namespace}, d)
+				return d
+			}
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+
+				// The closed window opens for one minute, 30 minutes from now
+				closedWindow = v1beta1.MaintenanceWindow{
+					Schedule: fmt.Sprintf("%d * * * *", (time.Now().UTC().Minute()+30)%60),
+					Duration: "1m",
+				}
+				openWindow = v1beta1.MaintenanceWindow{
+					Schedule: "* * * * *",
+					Duration: "1h",
+				}
+
+				deploy = getDeployment()
+				replicas := int32(3)
+				deploy.Spec.Replicas = &replicas
+				deploy.Status.ReadyReplicas = 3
+				_ = client.Update(context.TODO(), deploy)
+				foundPravega.Spec.Pravega.ControllerReplicas = 1
+			})
+
+			Context("outside of the maintenance windows", func() {
+				var err error
+				BeforeEach(func() {
+					foundPravega.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{closedWindow}
+					err = r.syncControllerSize(foundPravega)
+				})
+				It("should defer the scale-down", func() {
+					Ω(err).Should(BeNil())
+					Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(3)))
+				})
+				It("should record the deferral in the status", func() {
+					Ω(foundPravega.Status.Maintenance).ShouldNot(BeNil())
+					Ω(foundPravega.Status.Maintenance.DeferredActions).Should(Equal([]string{"controller scale-down"}))
+					next, err := time.Parse(time.RFC3339, foundPravega.Status.Maintenance.NextWindowStart)
+					Ω(err).Should(BeNil())
+					Ω(next.After(time.Now())).Should(BeTrue())
+				})
+				It("should not defer a scale-up", func() {
+					foundPravega.Status.Maintenance = nil
+					foundPravega.Spec.Pravega.ControllerReplicas = 4
+					err = r.syncControllerSize(foundPravega)
+					Ω(err).Should(BeNil())
+					Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(4)))
+					Ω(foundPravega.Status.Maintenance).Should(BeNil())
+				})
+				It("should defer the start of an upgrade", func() {
+					foundPravega.Status.Maintenance = nil
+					foundPravega.Status.CurrentVersion = foundPravega.Spec.Version
+					foundPravega.Spec.Version = "0.8.0"
+					foundPravega.Status.SetUpgradingConditionFalse()
+					foundPravega.Status.SetPodsReadyConditionTrue()
+					err = r.syncClusterVersion(foundPravega)
+					Ω(err).Should(BeNil())
+					Ω(foundPravega.Status.TargetVersion).Should(Equal(""))
+					Ω(foundPravega.Status.Maintenance.DeferredActions).Should(Equal([]string{"upgrade to 0.8.0"}))
+				})
+			})
+
+			Context("during a maintenance window", func() {
+				var err error
+				BeforeEach(func() {
+					foundPravega.Spec.MaintenanceWindows = []v1beta1.MaintenanceWindow{closedWindow, openWindow}
+					err = r.syncControllerSize(foundPravega)
+				})
+				It("should scale down", func() {
+					Ω(err).Should(BeNil())
+					Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(1)))
+					Ω(foundPravega.Status.Maintenance).Should(BeNil())
+				})
+				It("should start an upgrade", func() {
+					foundPravega.Status.CurrentVersion = foundPravega.Spec.Version
+					foundPravega.Spec.Version = "0.8.0"
+					foundPravega.Status.SetUpgradingConditionFalse()
+					foundPravega.Status.SetPodsReadyConditionTrue()
+					err = r.syncClusterVersion(foundPravega)
+					Ω(err).Should(BeNil())
+					Ω(foundPravega.Status.TargetVersion).Should(Equal("0.8.0"))
+				})
+			})
+		})
+		Context("controller scale-down availability", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			getDeployment := func() *appsv1.Deployment {
+				d := &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: Namespace}, d)
+				return d
+			}
+
+			setDeployment := func(replicas, ready int32) {
+				d := getDeployment()
+				d.Spec.Replicas = &replicas
+				d.Status.ReadyReplicas = ready
+				_ = client.Update(context.TODO(), d)
+			}
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.Spec.Pravega.ControllerReplicas = 1
+			})
+
+			It("should scale down when the remaining controllers are ready", func() {
+				setDeployment(3, 3)
+				err := r.syncControllerSize(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(1)))
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionScaleDownDeferred)
+				Ω(condition).Should(BeNil())
+			})
+
+			It("should defer a scale-down which could leave no ready controller", func() {
+				setDeployment(3, 2)
+				err := r.syncControllerSize(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(3)))
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionScaleDownDeferred)
+				Ω(condition).ShouldNot(BeNil())
+				Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(condition.Reason).Should(Equal(v1beta1.ControllerAvailabilityReason))
+				Ω(condition.Message).Should(ContainSubstring("2 of 3 controllers are ready"))
+			})
+
+			It("should not defer a scale-down when no controller is ready", func() {
+				setDeployment(3, 0)
+				err := r.syncControllerSize(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(1)))
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionScaleDownDeferred)
+				Ω(condition).Should(BeNil())
+			})
+
+			It("should clear the condition once the scale-down is done", func() {
+				setDeployment(3, 2)
+				_ = r.syncControllerSize(foundPravega)
+				setDeployment(3, 3)
+				err := r.syncControllerSize(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(1)))
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionScaleDownDeferred)
+				Ω(condition.Status).Should(Equal(corev1.ConditionFalse))
+			})
+
+			It("should not defer a scale-up", func() {
+				setDeployment(1, 0)
+				foundPravega.Spec.Pravega.ControllerReplicas = 2
+				err := r.syncControllerSize(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(*getDeployment().Spec.Replicas).Should(Equal(int32(2)))
+			})
+
+			It("should apply the rolling update strategy to an existing deployment", func() {
+				d := getDeployment()
+				d.Spec.Strategy = appsv1.DeploymentStrategy{}
+				_ = client.Update(context.TODO(), d)
+				err := r.syncControllerPodTemplate(foundPravega, false)
+				Ω(err).Should(BeNil())
+				Ω(getDeployment().Spec.Strategy).Should(Equal(pravega.MakeControllerDeploymentStrategy()))
+			})
+		})
+		Context("node selector change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+				deploy       *appsv1.Deployment
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.ControllerPodNodeSelector = map[string]string{"disktype": "ssd"}
+				foundPravega.Spec.Pravega.SegmentStorePodNodeSelector = map[string]string{"disktype": "nvme"}
+				err = r.deployCluster(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the segment store pod template node selector", func() {
+				Ω(sts.Spec.Template.Spec.NodeSelector).Should(Equal(map[string]string{"disktype": "nvme"}))
+			})
+			It("should update the controller pod template node selector", func() {
+				Ω(deploy.Spec.Template.Spec.NodeSelector).Should(Equal(map[string]string{"disktype": "ssd"}))
+			})
+		})
+		Context("controller probes change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				deploy       *appsv1.Deployment
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.ControllerProbes.ReadinessProbe.InitialDelaySeconds = 90
+				foundPravega.Spec.Pravega.ControllerProbes.ReadinessProbe.FailureThreshold = 10
+				err = r.deployController(foundPravega)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the readiness probe in place", func() {
+				probe := deploy.Spec.Template.Spec.Containers[0].ReadinessProbe
+				Ω(probe.InitialDelaySeconds).Should(Equal(int32(90)))
+				Ω(probe.FailureThreshold).Should(Equal(int32(10)))
+			})
+		})
+		Context("controller extra environment change", func() {
+			var (
+				client         client.Client
+				err            error
+				foundPravega   *v1beta1.PravegaCluster
+				deploy         *appsv1.Deployment
+				conflictEvents []corev1.Event
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.ControllerExtraEnv = []corev1.EnvVar{
+					{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+					{Name: "ZK_URL", Value: "zookeeper.example.com:2181"},
+				}
+				err = r.deployController(foundPravega)
+				_ = r.deployController(foundPravega)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				events := &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+				conflictEvents = nil
+				for _, event := range events.Items {
+					if event.Reason == v1beta1.ExtraEnvConflictReason {
+						conflictEvents = append(conflictEvents, event)
+					}
+				}
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should add the extra environment to the pod template", func() {
+				Ω(deploy.Spec.Template.Spec.Containers[0].Env).Should(Equal([]corev1.EnvVar{
+					{Name: "HTTP_PROXY", Value: "http://proxy.example.com:3128"},
+				}))
+			})
+			It("should publish a single warning event for the operator-managed variable", func() {
+				Ω(conflictEvents).Should(HaveLen(1))
+				Ω(conflictEvents[0].Type).Should(Equal("Warning"))
+				Ω(conflictEvents[0].Message).Should(ContainSubstring("ZK_URL"))
+			})
+			It("should not change the pod template when the environment is unchanged", func() {
+				Ω(envChanged(nil, []corev1.EnvVar{})).Should(BeFalse())
+			})
+			It("should detect a change of the referenced secret key", func() {
+				secretEnv := func(key string) []corev1.EnvVar {
+					return []corev1.EnvVar{{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"},
+							Key:                  key,
+						},
+					}}}
+				}
+				Ω(envChanged(secretEnv("password"), secretEnv("password"))).Should(BeFalse())
+				Ω(envChanged(secretEnv("password"), secretEnv("token"))).Should(BeTrue())
+			})
+			It("should not report the defaulted field reference version as a change", func() {
+				fieldEnv := func(version string) []corev1.EnvVar {
+					return []corev1.EnvVar{{Name: "HOST_IP", ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{APIVersion: version, FieldPath: "status.hostIP"},
+					}}}
+				}
+				Ω(envChanged(fieldEnv("v1"), fieldEnv(""))).Should(BeFalse())
+			})
+		})
+		Context("segment store resources derived from the JVM options", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreResources = nil
+				foundPravega.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Xmx2g", "-XX:MaxDirectMemorySize=2g"}
+				foundPravega.Spec.Pravega.JVMDerivedResources = &v1beta1.JVMDerivedResourcesSpec{OverheadFactor: "1.5"}
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the container memory in place", func() {
+				resources := sts.Spec.Template.Spec.Containers[0].Resources
+				memory := resources.Requests[corev1.ResourceMemory]
+				Ω(memory.Value()).Should(Equal(int64(6 << 30)))
+				memory = resources.Limits[corev1.ResourceMemory]
+				Ω(memory.Value()).Should(Equal(int64(6 << 30)))
+			})
+		})
+		Context("segment store termination grace period change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				gracePeriod := int64(300)
+				foundPravega.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds = &gracePeriod
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the stateful set in place", func() {
+				Ω(*sts.Spec.Template.Spec.TerminationGracePeriodSeconds).Should(Equal(int64(300)))
+			})
+			It("should sync back to the default once cleared", func() {
+				foundPravega.Spec.Pravega.SegmentStoreTerminationGracePeriodSeconds = nil
+				err = r.deploySegmentStore(foundPravega)
+				Ω(err).Should(BeNil())
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(*sts.Spec.Template.Spec.TerminationGracePeriodSeconds).Should(Equal(int64(30)))
+			})
+		})
+		Context("segment store init containers change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreInitContainers = []corev1.Container{
+					{
+						Name:    "fix-permissions",
+						Image:   "busybox",
+						Command: []string{"chown", "-R", "1000:1000", "/tmp/pravega/cache"},
+					},
+				}
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should add the init container to the stateful set", func() {
+				initContainers := sts.Spec.Template.Spec.InitContainers
+				Ω(initContainers).Should(HaveLen(1))
+				Ω(initContainers[0].Name).Should(Equal("fix-permissions"))
+			})
+			It("should not change the stateful set when the init containers are unchanged", func() {
+				Ω(initContainersChanged(sts.Spec.Template.Spec.InitContainers, foundPravega.Spec.Pravega.SegmentStoreInitContainers)).Should(BeFalse())
+			})
+			It("should not sync the init containers while the cluster is rolling back", func() {
+				foundPravega.Status.SetRollbackConditionTrue("", "")
+				foundPravega.Spec.Pravega.SegmentStoreInitContainers = nil
+				Ω(r.syncSegmentStorePodTemplate(foundPravega, false)).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(sts.Spec.Template.Spec.InitContainers).Should(HaveLen(1))
+			})
+		})
+		Context("segment store volumes change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreVolumes = []corev1.Volume{
+					{
+						Name: "storage-tuning",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "storage-tuning"},
+							},
+						},
+					},
+				}
+				foundPravega.Spec.Pravega.SegmentStoreVolumeMounts = []corev1.VolumeMount{
+					{Name: "storage-tuning", MountPath: "/etc/storage-tuning"},
+				}
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should add the volume and its mount to the stateful set", func() {
+				Ω(sts.Spec.Template.Spec.Volumes).Should(ContainElement(foundPravega.Spec.Pravega.SegmentStoreVolumes[0]))
+				Ω(sts.Spec.Template.Spec.Containers[0].VolumeMounts).Should(ContainElement(foundPravega.Spec.Pravega.SegmentStoreVolumeMounts[0]))
+			})
+			It("should ignore the fields defaulted by the API server", func() {
+				desired := sts.Spec.Template.Spec.DeepCopy().Volumes
+				mode := int32(420)
+				for i := range sts.Spec.Template.Spec.Volumes {
+					if configMap := sts.Spec.Template.Spec.Volumes[i].ConfigMap; configMap != nil {
+						configMap.DefaultMode = &mode
+					}
+				}
+				Ω(volumesChanged(sts.Spec.Template.Spec.Volumes, desired)).Should(BeFalse())
+				for i := range desired {
+					if desired[i].Name == "storage-tuning" {
+						desired[i].ConfigMap.Name = "storage-tuning-v2"
+					}
+				}
+				Ω(volumesChanged(sts.Spec.Template.Spec.Volumes, desired)).Should(BeTrue())
+			})
+			It("should not mount a journal claim the stateful set does not have", func() {
+				foundPravega.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{Size: "20Gi"}
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(sts.Spec.VolumeClaimTemplates).Should(BeEmpty())
+				for _, mount := range sts.Spec.Template.Spec.Containers[0].VolumeMounts {
+					Ω(mount.Name).ShouldNot(Equal("journal"))
+				}
+				Ω(sts.Spec.Template.Spec.Containers[0].VolumeMounts).Should(ContainElement(foundPravega.Spec.Pravega.SegmentStoreVolumeMounts[0]))
+			})
+			It("should keep the volumes of the containers which are not synced", func() {
+				logs := corev1.Volume{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+				current := sts.DeepCopy()
+				current.Spec.Template.Spec.Volumes = append(current.Spec.Template.Spec.Volumes, logs)
+				current.Spec.Template.Spec.Containers = append(current.Spec.Template.Spec.Containers, corev1.Container{
+					Name:         "logging-sidecar",
+					VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/opt/pravega/logs", ReadOnly: true}},
+				})
+				volumes, _ := syncableVolumes(current, sts)
+				Ω(volumes).Should(ContainElement(logs))
+			})
+		})
+		Context("segment store topology spread constraints change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+				constraints  []corev1.TopologySpreadConstraint
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				constraints = []corev1.TopologySpreadConstraint{
+					{
+						MaxSkew:           1,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: foundPravega.LabelsForSegmentStore(),
+						},
+					},
+				}
+				foundPravega.Spec.Pravega.SegmentStoreTopologySpreadConstraints = constraints
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should add the constraints to the stateful set", func() {
+				Ω(sts.Spec.Template.Spec.TopologySpreadConstraints).Should(Equal(constraints))
+			})
+			It("should not change the stateful set when the constraints are unchanged", func() {
+				Ω(topologySpreadConstraintsChanged(sts.Spec.Template.Spec.TopologySpreadConstraints, constraints)).Should(BeFalse())
+				Ω(topologySpreadConstraintsChanged(nil, []corev1.TopologySpreadConstraint{})).Should(BeFalse())
+			})
+		})
+		Context("pod affinity change", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				zone         *corev1.NodeAffinity
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				zone = &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key:      "topology.kubernetes.io/zone",
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{"us-east-1a"},
+							}},
+						}},
+					},
+				}
+				foundPravega.Spec.Pravega.ControllerPodAffinity = &corev1.Affinity{NodeAffinity: zone}
+				foundPravega.Spec.Pravega.SegmentStorePodAffinity = &corev1.Affinity{NodeAffinity: zone}
+			})
+			It("should update the affinity of the controller deployment", func() {
+				Ω(r.deployController(foundPravega)).Should(BeNil())
+				deploy := &appsv1.Deployment{}
+				Ω(client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)).Should(BeNil())
+				Ω(deploy.Spec.Template.Spec.Affinity.NodeAffinity).Should(Equal(zone))
+				Ω(deploy.Spec.Template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).Should(HaveLen(1))
+			})
+			It("should update the affinity of the segment store stateful set", func() {
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				sts := &appsv1.StatefulSet{}
+				Ω(client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)).Should(BeNil())
+				Ω(sts.Spec.Template.Spec.Affinity.NodeAffinity).Should(Equal(zone))
+				Ω(sts.Spec.Template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).Should(HaveLen(1))
+			})
+		})
+		Context("command override change", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.ControllerCommand = []string{"sleep", "infinity"}
+				foundPravega.Spec.Pravega.SegmentStoreCommand = []string{"sleep", "infinity"}
+			})
+			It("should override the command of the controller deployment", func() {
+				Ω(r.deployController(foundPravega)).Should(BeNil())
+				deploy := &appsv1.Deployment{}
+				Ω(client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)).Should(BeNil())
+				container := deploy.Spec.Template.Spec.Containers[0]
+				Ω(container.Command).Should(Equal([]string{"sleep", "infinity"}))
+				Ω(container.Args).Should(BeEmpty())
+				Ω(container.LivenessProbe).Should(BeNil())
+			})
+			It("should override the command of the segment store stateful set", func() {
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				sts := &appsv1.StatefulSet{}
+				Ω(client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)).Should(BeNil())
+				container := sts.Spec.Template.Spec.Containers[0]
+				Ω(container.Command).Should(Equal([]string{"sleep", "infinity"}))
+				Ω(container.Args).Should(BeEmpty())
+				Ω(container.LivenessProbe).Should(BeNil())
+			})
+			It("should restore the default launch once the override is removed", func() {
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				foundPravega.Spec.Pravega.SegmentStoreCommand = nil
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				sts := &appsv1.StatefulSet{}
+				Ω(client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)).Should(BeNil())
+				container := sts.Spec.Template.Spec.Containers[0]
+				Ω(container.Command).Should(BeEmpty())
+				Ω(container.Args).Should(Equal([]string{"segmentstore"}))
+				Ω(container.LivenessProbe).ShouldNot(BeNil())
+			})
+		})
+		Context("segment store host network change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreHostNetwork = true
+				err = r.deploySegmentStore(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should move the pod template to the host network", func() {
+				Ω(sts.Spec.Template.Spec.HostNetwork).Should(BeTrue())
+				Ω(sts.Spec.Template.Spec.DNSPolicy).Should(Equal(corev1.DNSClusterFirstWithHostNet))
+			})
+		})
+		Context("init wait URL change", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				deploy       *appsv1.Deployment
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				foundPravega.Spec.Pravega.InitWaitURL = "http://metadata.example.com/health"
+				Ω(r.deployController(foundPravega)).Should(BeNil())
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+			})
+			It("should add the init container to the controller pod template", func() {
+				Ω(deploy.Spec.Template.Spec.InitContainers).Should(HaveLen(1))
+				Ω(deploy.Spec.Template.Spec.InitContainers[0].Env[0].Value).Should(Equal("http://metadata.example.com/health"))
+			})
+			It("should remove the init container once the URL is cleared", func() {
+				foundPravega.Spec.Pravega.InitWaitURL = ""
+				Ω(r.deployController(foundPravega)).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				Ω(deploy.Spec.Template.Spec.InitContainers).Should(BeEmpty())
+			})
+			It("should not sync the init container while the cluster is upgrading", func() {
+				foundPravega.Status.SetUpgradingConditionTrue("", "")
+				foundPravega.Spec.Pravega.InitWaitURL = ""
+				Ω(r.syncControllerPodTemplate(foundPravega, false)).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				Ω(deploy.Spec.Template.Spec.InitContainers).Should(HaveLen(1))
+			})
+		})
+		Context("DNS settings change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				deploy       *appsv1.Deployment
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				dnsConfig := &corev1.PodDNSConfig{Searches: []string{"storage.example.com"}}
+				foundPravega.Spec.Pravega.ControllerDnsPolicy = corev1.DNSDefault
+				foundPravega.Spec.Pravega.ControllerDnsConfig = dnsConfig
+				foundPravega.Spec.Pravega.SegmentStoreDnsPolicy = corev1.DNSDefault
+				foundPravega.Spec.Pravega.SegmentStoreDnsConfig = dnsConfig
+				err = r.deployController(foundPravega)
+				Ω(err).Should(BeNil())
+				err = r.deploySegmentStore(foundPravega)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should apply the DNS settings to the pod templates", func() {
+				Ω(deploy.Spec.Template.Spec.DNSPolicy).Should(Equal(corev1.DNSDefault))
+				Ω(deploy.Spec.Template.Spec.DNSConfig.Searches).Should(Equal([]string{"storage.example.com"}))
+				Ω(sts.Spec.Template.Spec.DNSPolicy).Should(Equal(corev1.DNSDefault))
+				Ω(sts.Spec.Template.Spec.DNSConfig.Searches).Should(Equal([]string{"storage.example.com"}))
+			})
+			It("should not change the pod templates for the defaulted DNS policy", func() {
+				current := &corev1.PodSpec{DNSPolicy: corev1.DNSClusterFirst}
+				Ω(dnsChanged(current, &corev1.PodSpec{})).Should(BeFalse())
+			})
+		})
+		Context("container security context change", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				deploy       *appsv1.Deployment
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				escalation := false
+				fsGroup := int64(1000)
+				foundPravega.Spec.Pravega.ControllerContainerSecurityContext = &corev1.SecurityContext{AllowPrivilegeEscalation: &escalation}
+				foundPravega.Spec.Pravega.SegmentStoreContainerSecurityContext = &corev1.SecurityContext{AllowPrivilegeEscalation: &escalation}
+				foundPravega.Spec.Pravega.ControllerSecurityContext = &corev1.PodSecurityContext{FSGroup: &fsGroup}
+				foundPravega.Spec.Pravega.SegmentStoreSecurityContext = &corev1.PodSecurityContext{FSGroup: &fsGroup}
+				err = r.deployController(foundPravega)
+				Ω(err).Should(BeNil())
+				err = r.deploySegmentStore(foundPravega)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should apply the container security contexts to the pod templates", func() {
+				Ω(*deploy.Spec.Template.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation).Should(BeFalse())
+				Ω(*sts.Spec.Template.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation).Should(BeFalse())
+			})
+			It("should apply the pod security contexts to the pod templates", func() {
+				Ω(*deploy.Spec.Template.Spec.SecurityContext.FSGroup).Should(Equal(int64(1000)))
+				Ω(*sts.Spec.Template.Spec.SecurityContext.FSGroup).Should(Equal(int64(1000)))
+			})
+			It("should clear the pod security contexts removed from the spec", func() {
+				foundPravega.Spec.Pravega.ControllerSecurityContext = nil
+				foundPravega.Spec.Pravega.SegmentStoreSecurityContext = nil
+				Ω(r.deployController(foundPravega)).Should(BeNil())
+				Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(podSecurityContextChanged(deploy.Spec.Template.Spec.SecurityContext, nil)).Should(BeFalse())
+				Ω(podSecurityContextChanged(sts.Spec.Template.Spec.SecurityContext, nil)).Should(BeFalse())
+			})
+		})
+		Context("service account token automounting change", func() {
+			var (
+				client         client.Client
+				err            error
+				foundPravega   *v1beta1.PravegaCluster
+				deploy         *appsv1.Deployment
+				sts            *appsv1.StatefulSet
+				conflictEvents []corev1.Event
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				automount := false
+				foundPravega.Spec.ExternalAccess.Enabled = true
+				foundPravega.Spec.Pravega.ControllerAutomountServiceAccountToken = &automount
+				foundPravega.Spec.Pravega.SegmentStoreAutomountServiceAccountToken = &automount
+				err = r.deployController(foundPravega)
+				Ω(err).Should(BeNil())
+				err = r.deploySegmentStore(foundPravega)
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				events := &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+				conflictEvents = nil
+				for _, event := range events.Items {
+					if event.Reason == v1beta1.ServiceAccountTokenConflictReason {
+						conflictEvents = append(conflictEvents, event)
+					}
+				}
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should disable the token in the pod templates", func() {
+				Ω(deploy.Spec.Template.Spec.AutomountServiceAccountToken).ShouldNot(BeNil())
+				Ω(*deploy.Spec.Template.Spec.AutomountServiceAccountToken).Should(BeFalse())
+				Ω(sts.Spec.Template.Spec.AutomountServiceAccountToken).ShouldNot(BeNil())
+				Ω(*sts.Spec.Template.Spec.AutomountServiceAccountToken).Should(BeFalse())
+			})
+			It("should publish a warning event as external access needs the token", func() {
+				Ω(conflictEvents).Should(HaveLen(1))
+				Ω(conflictEvents[0].Type).Should(Equal("Warning"))
+				Ω(conflictEvents[0].Message).Should(ContainSubstring("externalAccess"))
+			})
+		})
+		Context("tls secret reload on change", func() {
+			var (
+				client     client.Client
+				err        error
+				secret     *corev1.Secret
+				deployment *appsv1.Deployment
+				sts        *appsv1.StatefulSet
+				before     string
+			)
+
+			getPodTemplates := func() {
+				deployment = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deployment)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.TLS = &v1beta1.TLSPolicy{
+					Static: &v1beta1.StaticTLS{
+						ControllerSecret:   "controller-tls",
+						SegmentStoreSecret: "segmentstore-tls",
+					},
+					ReloadOnChange: true,
+				}
+				secret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "controller-tls", Namespace: Namespace},
+					Data:       map[string][]byte{"tls.crt": []byte("cert-1"), "tls.key": []byte("key-1")},
+				}
+				ssSecret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "segmentstore-tls", Namespace: Namespace},
+					Data:       map[string][]byte{"tls.crt": []byte("cert-1")},
+				}
+				client = fake.NewFakeClient(p, secret, ssSecret)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_ = r.reconcileTLSSecretHashes(p)
+				_ = r.deployCluster(p)
+				getPodTemplates()
+				before = deployment.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]
+			})
+			It("should stamp the secret hashes on the pod templates", func() {
+				Ω(before).ShouldNot(BeEmpty())
+				Ω(sts.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]).ShouldNot(BeEmpty())
+				Ω(sts.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]).ShouldNot(Equal(before))
+			})
+			Context("when the secret data changes", func() {
+				var ssBefore string
+				BeforeEach(func() {
+					ssBefore = sts.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]
+					secret.Data["tls.crt"] = []byte("cert-2")
+					_ = client.Update(context.TODO(), secret)
+					err = r.reconcileTLSSecretHashes(p)
+					_ = r.deployController(p)
+					getPodTemplates()
+				})
+				It("should bump the controller annotation", func() {
+					Ω(err).Should(BeNil())
+					Ω(deployment.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]).ShouldNot(Equal(before))
+					Ω(deployment.Spec.Template.Annotations[pravega.TLSSecretHashAnnotationKey]).Should(Equal(p.Status.TLSSecretHashes.Controller))
+				})
+				It("should not change the segment store hash", func() {
+					Ω(p.Status.TLSSecretHashes.SegmentStore).Should(Equal(ssBefore))
+				})
+			})
+			Context("when reload on change is disabled", func() {
+				BeforeEach(func() {
+					p.Spec.TLS.ReloadOnChange = false
+					err = r.reconcileTLSSecretHashes(p)
+					_ = r.deployController(p)
+					getPodTemplates()
+				})
+				It("should remove the annotation", func() {
+					Ω(err).Should(BeNil())
+					Ω(p.Status.TLSSecretHashes).Should(BeNil())
+					Ω(deployment.Spec.Template.Annotations).ShouldNot(HaveKey(pravega.TLSSecretHashAnnotationKey))
+				})
+			})
+			Context("when the secret is missing", func() {
+				BeforeEach(func() {
+					_ = client.Delete(context.TODO(), secret)
+					err = r.reconcileTLSSecretHashes(p)
+				})
+				It("should error", func() {
+					Ω(err).ShouldNot(BeNil())
+				})
+			})
+		})
+		Context("restart annotation", func() {
+			var (
+				client     client.Client
+				err        error
+				deployment *appsv1.Deployment
+				sts        *appsv1.StatefulSet
+			)
+
+			getPodTemplates := func() {
+				deployment = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deployment)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_ = r.deployCluster(p)
+				p.Annotations = map[string]string{v1beta1.RestartAnnotation: "2026-10-15T10:00:00Z"}
+			})
+			It("should not stamp the pod templates without the annotation", func() {
+				getPodTemplates()
+				Ω(deployment.Spec.Template.Annotations).ShouldNot(HaveKey(pravega.RestartAnnotationKey))
+				Ω(sts.Spec.Template.Annotations).ShouldNot(HaveKey(pravega.RestartAnnotationKey))
+			})
+			Context("when the annotation changes", func() {
+				BeforeEach(func() {
+					err = r.deployCluster(p)
+					getPodTemplates()
+				})
+				It("should stamp the new value on the pod templates", func() {
+					Ω(err).Should(BeNil())
+					Ω(deployment.Spec.Template.Annotations[pravega.RestartAnnotationKey]).Should(Equal("2026-10-15T10:00:00Z"))
+					Ω(sts.Spec.Template.Annotations[pravega.RestartAnnotationKey]).Should(Equal("2026-10-15T10:00:00Z"))
+				})
+			})
+			Context("when the annotation changes during an upgrade", func() {
+				BeforeEach(func() {
+					p.Status.SetUpgradingConditionTrue(v1beta1.UpdatingControllerReason, "0")
+					err = r.deployCluster(p)
+					getPodTemplates()
+				})
+				It("should defer the restart", func() {
+					Ω(err).Should(BeNil())
+					Ω(deployment.Spec.Template.Annotations).ShouldNot(HaveKey(pravega.RestartAnnotationKey))
+					Ω(sts.Spec.Template.Annotations).ShouldNot(HaveKey(pravega.RestartAnnotationKey))
+				})
+				It("should restart once the upgrade completes", func() {
+					p.Status.SetUpgradingConditionFalse()
+					err = r.deployCluster(p)
+					getPodTemplates()
+					Ω(err).Should(BeNil())
+					Ω(deployment.Spec.Template.Annotations[pravega.RestartAnnotationKey]).Should(Equal("2026-10-15T10:00:00Z"))
+					Ω(sts.Spec.Template.Annotations[pravega.RestartAnnotationKey]).Should(Equal("2026-10-15T10:00:00Z"))
+				})
+			})
+		})
+		Context("long term storage reachable condition", func() {
+			var (
+				client  client.Client
+				pvc     *corev1.PersistentVolumeClaim
+				secrets []runtime.Object
+				pods    []corev1.Pod
+				now     time.Time
+			)
+
+			segmentStorePod := func(name string, ready bool, restarts int32) corev1.Pod {
+				readyStatus := corev1.ConditionFalse
+				if ready {
+					readyStatus = corev1.ConditionTrue
+				}
+				return corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: Namespace,
+						Labels:    p.LabelsForSegmentStore(),
+					},
+					Status: corev1.PodStatus{
+						Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: readyStatus}},
+						ContainerStatuses: []corev1.ContainerStatus{{Name: "pravega-segmentstore", RestartCount: restarts}},
+					},
+				}
+			}
+
+			getCondition := func() *v1beta1.ClusterCondition {
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionLtsReachable)
+				return condition
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				pvc = &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      v1beta1.DefaultPravegaLTSClaimName,
+						Namespace: Namespace,
+					},
+					Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+				}
+				pods = []corev1.Pod{
+					segmentStorePod("example-pravega-segment-store-0", false, 3),
+					segmentStorePod("example-pravega-segment-store-1", false, 2),
+				}
+				secrets = nil
+				now = time.Now()
+			})
+			JustBeforeEach(func() {
+				client = fake.NewFakeClient(append([]runtime.Object{pvc}, secrets...)...)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r.syncLtsReachableCondition(p, pods, now)
+			})
+			Context("when all the segment stores restart without becoming ready", func() {
+				It("should report the long term storage unreachable", func() {
+					Ω(p.Status.IsLtsUnreachable()).Should(BeTrue())
+					Ω(getCondition().Reason).Should(Equal(v1beta1.SegmentStoresFailingReason))
+					Ω(getCondition().Message).Should(ContainSubstring("example-pravega-segment-store-0, example-pravega-segment-store-1"))
+				})
+			})
+			Context("when the tier 2 claim is not bound", func() {
+				BeforeEach(func() {
+					pvc.Status.Phase = corev1.ClaimPending
+				})
+				It("should report the long term storage unreachable", func() {
+					Ω(getCondition().Status).Should(Equal(corev1.ConditionFalse))
+					Ω(getCondition().Reason).Should(Equal(v1beta1.LtsClaimNotBoundReason))
+				})
+			})
+			Context("when a segment store is ready", func() {
+				BeforeEach(func() {
+					pods[1] = segmentStorePod("example-pravega-segment-store-1", true, 2)
+				})
+				It("should report the long term storage reachable", func() {
+					Ω(getCondition().Status).Should(Equal(corev1.ConditionTrue))
+				})
+			})
+			Context("while the segment stores start", func() {
+				BeforeEach(func() {
+					pods = []corev1.Pod{segmentStorePod("example-pravega-segment-store-0", false, 0)}
+				})
+				It("should not report the reachability", func() {
+					Ω(getCondition()).Should(BeNil())
+				})
+			})
+			Context("when the long term storage probe is enabled", func() {
+				var (
+					probes   []util.BucketProbe
+					probeErr error
+				)
+				BeforeEach(func() {
+					p.Spec.Pravega.LongTermStorageProbe = true
+					probes, probeErr = nil, nil
+					probeBucket = func(probe util.BucketProbe) error {
+						probes = append(probes, probe)
+						return probeErr
+					}
+				})
+				AfterEach(func() {
+					probeBucket = util.ProbeBucket
+				})
+				Context("with a bound tier 2 claim", func() {
+					It("should report the long term storage reachable, whatever the segment stores", func() {
+						Ω(getCondition().Status).Should(Equal(corev1.ConditionTrue))
+						Ω(p.Status.LtsLastProbeTime).Should(Equal(now.UTC().Format(time.RFC3339)))
+					})
+				})
+				Context("with an unbound tier 2 claim", func() {
+					BeforeEach(func() {
+						pvc.Status.Phase = corev1.ClaimPending
+					})
+					It("should report the long term storage unreachable", func() {
+						Ω(getCondition().Status).Should(Equal(corev1.ConditionFalse))
+						Ω(getCondition().Reason).Should(Equal(v1beta1.LtsClaimNotBoundReason))
+						Ω(getCondition().Message).Should(Equal("tier2 pvc " + v1beta1.DefaultPravegaLTSClaimName + " is not bound"))
+					})
+				})
+				Context("with an S3 bucket", func() {
+					BeforeEach(func() {
+						p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+							S3: &v1beta1.S3Spec{
+								Bucket:    "pravega-tier2",
+								Endpoint:  "http://minio:9000",
+								Region:    "eu-west-1",
+								SecretRef: "s3-keys",
+							},
+						}
+						secrets = []runtime.Object{&corev1.Secret{
+							ObjectMeta: metav1.ObjectMeta{Name: "s3-keys", Namespace: Namespace},
+							Data: map[string][]byte{
+								"ACCESS_KEY_ID": []byte("access"),
+								"SECRET_KEY":    []byte("secret-key"),
+							},
+						}}
+					})
+					It("should probe the bucket with the keys of the secret", func() {
+						Ω(probes).Should(Equal([]util.BucketProbe{{
+							Endpoint:  "http://minio:9000",
+							Region:    "eu-west-1",
+							Bucket:    "pravega-tier2",
+							AccessKey: "access",
+							SecretKey: "secret-key",
+						}}))
+						Ω(getCondition().Status).Should(Equal(corev1.ConditionTrue))
+					})
+					It("should not probe again before the interval elapses", func() {
+						r.syncLtsReachableCondition(p, pods, now.Add(time.Minute))
+						Ω(probes).Should(HaveLen(1))
+						r.syncLtsReachableCondition(p, pods, now.Add(p.LongTermStorageProbeInterval()))
+						Ω(probes).Should(HaveLen(2))
+					})
+					Context("which is denied", func() {
+						BeforeEach(func() {
+							probeErr = fmt.Errorf("access to bucket pravega-tier2 at http://minio:9000 is denied, check the credentials")
+						})
+						It("should report the long term storage unreachable", func() {
+							Ω(getCondition().Status).Should(Equal(corev1.ConditionFalse))
+							Ω(getCondition().Reason).Should(Equal(v1beta1.LtsProbeFailedReason))
+							Ω(getCondition().Message).Should(Equal(probeErr.Error()))
+						})
+					})
+					Context("without the secret", func() {
+						BeforeEach(func() {
+							secrets = nil
+						})
+						It("should report the long term storage unreachable without probing", func() {
+							Ω(probes).Should(BeEmpty())
+							Ω(getCondition().Reason).Should(Equal(v1beta1.LtsProbeFailedReason))
+							Ω(getCondition().Message).Should(HavePrefix("failed to get secret s3-keys"))
+						})
+					})
+				})
+				Context("with an ECS bucket", func() {
+					BeforeEach(func() {
+						p.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+							Ecs: &v1beta1.ECSSpec{
+								ConfigUri:      "https://ecs:9021?namespace=pravega&identity=user&secretKey=query-secret",
+								Bucket:         "pravega-tier2",
+								CaBundleSecret: "ecs-ca",
+							},
+						}
+						secrets = []runtime.Object{&corev1.Secret{
+							ObjectMeta: metav1.ObjectMeta{Name: "ecs-ca", Namespace: Namespace},
+							Data:       map[string][]byte{"ca.crt": []byte("certificate")},
+						}}
+					})
+					It("should probe the bucket in the namespace of the config uri", func() {
+						Ω(probes).Should(HaveLen(1))
+						Ω(probes[0].Namespace).Should(Equal("pravega"))
+						Ω(probes[0].AccessKey).Should(BeEmpty())
+						Ω(string(probes[0].CaBundle)).Should(Equal("certificate\n"))
+					})
+				})
+			})
+		})
+		Context("segment store scale subresource", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				sts          *appsv1.StatefulSet
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+
+				// the scale subresource only updates the segment store replicas of the spec
+				foundPravega.Spec.Pravega.SegmentStoreReplicas = 3
+				_ = client.Update(context.TODO(), foundPravega)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				err = r.syncClusterSize(foundPravega)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			})
+			It("should scale the segment store stateful set", func() {
+				Ω(err).Should(BeNil())
+				Ω(*sts.Spec.Replicas).Should(Equal(int32(3)))
+			})
+			It("should report the segment store pods and their selector", func() {
+				pods := []corev1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-segment-store-0", Labels: foundPravega.LabelsForSegmentStore()}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-segment-store-1", Labels: foundPravega.LabelsForSegmentStore()}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-controller-0", Labels: foundPravega.LabelsForController()}},
+				}
+				syncSegmentStoreScaleStatus(foundPravega, pods)
+				Ω(foundPravega.Status.SegmentStoreReplicas).Should(Equal(int32(2)))
+				Ω(foundPravega.Status.SegmentStoreSelector).Should(Equal("app=pravega-cluster,component=pravega-segmentstore,pravega_cluster=example"))
+			})
+		})
+		Context("segment store cache claims reclaim policy", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				policy       string
+				claims       []string
+			)
+
+			BeforeEach(func() {
+				policy = v1beta1.SegmentStoreCachePVCReclaimPolicyDelete
+			})
+
+			JustBeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				foundPravega = p.DeepCopy()
+				foundPravega.Spec.Version = "0.6.1"
+				foundPravega.WithDefaults()
+				foundPravega.Spec.Pravega.CacheVolumeClaimTemplate = &corev1.PersistentVolumeClaimSpec{}
+				foundPravega.Spec.Pravega.SegmentStoreCachePVCReclaimPolicy = policy
+				foundPravega.Spec.Pravega.SegmentStoreReplicas = 5
+				sts := pravega.MakeSegmentStoreStatefulSet(foundPravega)
+				_ = client.Create(context.TODO(), sts)
+				names := []string{"cache-other-segment-store-4", "data-" + sts.Name + "-4", "cache-" + sts.Name + "-4x"}
+				for i := 0; i < 5; i++ {
+					names = append(names, fmt.Sprintf("cache-%s-%d", sts.Name, i))
+				}
+				for _, name := range names {
+					_ = client.Create(context.TODO(), &corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      name,
+							Namespace: p.Namespace,
+							Labels:    foundPravega.LabelsForSegmentStore(),
+						},
+					})
+				}
+
+				foundPravega.Spec.Pravega.SegmentStoreReplicas = 3
+				err = r.syncSegmentStoreSize(foundPravega)
+				pvcList := &corev1.PersistentVolumeClaimList{}
+				_ = client.List(context.TODO(), pvcList)
+				claims = nil
+				for _, pvc := range pvcList.Items {
+					claims = append(claims, pvc.Name)
+				}
+			})
+
+			Context("Delete policy", func() {
+				It("should delete exactly the two highest ordinal cache claims", func() {
+					Ω(err).Should(BeNil())
+					sts := foundPravega.StatefulSetNameForSegmentstore()
+					Ω(claims).Should(HaveLen(6))
+					Ω(claims).ShouldNot(ContainElement("cache-" + sts + "-3"))
+					Ω(claims).ShouldNot(ContainElement("cache-" + sts + "-4"))
+					Ω(claims).Should(ContainElement("cache-" + sts + "-2"))
+					Ω(claims).Should(ContainElement("cache-other-segment-store-4"))
+					Ω(claims).Should(ContainElement("cache-" + sts + "-4x"))
+				})
+			})
+
+			Context("Retain policy", func() {
+				BeforeEach(func() {
+					policy = v1beta1.SegmentStoreCachePVCReclaimPolicyRetain
+				})
+				It("should keep all the claims", func() {
+					Ω(err).Should(BeNil())
+					Ω(claims).Should(HaveLen(8))
+				})
+			})
+		})
+		Context("segment store volume expansion", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				pvc          *corev1.PersistentVolumeClaim
+				allow        bool
+			)
+
+			JustBeforeEach(func() {
+				storageClass := &storagev1.StorageClass{
+					ObjectMeta:           metav1.ObjectMeta{Name: "journal"},
+					Provisioner:          "kubernetes.io/no-provisioner",
+					AllowVolumeExpansion: &allow,
+				}
+				client = fake.NewFakeClient(p, storageClass)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				foundPravega.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{StorageClassName: "journal", Size: "20Gi"}
+				_ = r.deployCluster(foundPravega)
+				className := "journal"
+				pvc = &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "journal-" + foundPravega.StatefulSetNameForSegmentstore() + "-0",
+						Namespace: p.Namespace,
+						Labels:    foundPravega.LabelsForSegmentStore(),
+					},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						StorageClassName: &className,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+						},
+					},
+				}
+				_ = client.Create(context.TODO(), pvc)
+				foundPravega.Spec.Pravega.SegmentStoreJournalVolume.Size = "50Gi"
+				err = r.deploySegmentStore(foundPravega)
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: pvc.Name, Namespace: pvc.Namespace}, pvc)
+			})
+
+			Context("storage class allowing volume expansion", func() {
+				BeforeEach(func() {
+					allow = true
+				})
+				It("should expand the existing claim", func() {
+					Ω(err).Should(BeNil())
+					size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+					Ω(size.String()).Should(Equal("50Gi"))
+				})
+			})
+
+			Context("storage class not allowing volume expansion", func() {
+				BeforeEach(func() {
+					allow = false
+				})
+				It("should leave the claim unchanged without failing the reconcile", func() {
+					Ω(err).Should(BeNil())
+					size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+					Ω(size.String()).Should(Equal("20Gi"))
+				})
+				It("should set the volume expansion blocked condition and publish a warning event once", func() {
+					_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionVolumeExpansionBlocked)
+					Ω(condition.Status).To(Equal(corev1.ConditionTrue))
+					Ω(condition.Message).Should(ContainSubstring("does not allow volume expansion"))
+					Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+					events := &corev1.EventList{}
+					_ = client.List(context.TODO(), events)
+					count := 0
+					for _, event := range events.Items {
+						if event.Reason == v1beta1.VolumeExpansionNotAllowedReason {
+							count++
+						}
+					}
+					Ω(count).To(Equal(1))
+				})
+				It("should clear the condition once the size is reverted", func() {
+					foundPravega.Spec.Pravega.SegmentStoreJournalVolume.Size = "20Gi"
+					Ω(r.deploySegmentStore(foundPravega)).Should(BeNil())
+					_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionVolumeExpansionBlocked)
+					Ω(condition.Status).To(Equal(corev1.ConditionFalse))
+				})
+			})
+		})
+		Context("segment store pvc labels", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				journal      *corev1.PersistentVolumeClaim
+				tier2        *corev1.PersistentVolumeClaim
+			)
+
+			BeforeEach(func() {
+				tier2 = &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pravega-tier2",
+						Namespace: p.Namespace,
+						Labels:    map[string]string{"owner": "platform"},
+					},
+				}
+				client = fake.NewFakeClient(p, tier2)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				journal = &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "journal-" + foundPravega.StatefulSetNameForSegmentstore() + "-0",
+						Namespace: p.Namespace,
+						Labels:    foundPravega.LabelsForSegmentStore(),
+					},
+				}
+				_ = client.Create(context.TODO(), journal)
+				foundPravega.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{Size: "20Gi"}
+				foundPravega.Spec.Pravega.LongTermStorage = &v1beta1.LongTermStorageSpec{
+					FileSystem: &v1beta1.FileSystemSpec{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pravega-tier2"},
+					},
+				}
+				foundPravega.Spec.Pravega.PVCLabels = map[string]string{"cost-center": "streaming"}
+				err = r.deploySegmentStore(foundPravega)
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: journal.Name, Namespace: journal.Namespace}, journal)
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: tier2.Name, Namespace: tier2.Namespace}, tier2)
+			})
+
+			It("should add the labels to the existing segment store claims", func() {
+				Ω(err).Should(BeNil())
+				Ω(journal.Labels).Should(HaveKeyWithValue("cost-center", "streaming"))
+				Ω(journal.Labels).Should(HaveKeyWithValue("component", "pravega-segmentstore"))
+			})
+
+			It("should add the labels to the tier2 claim and keep its own labels", func() {
+				Ω(tier2.Labels).Should(HaveKeyWithValue("cost-center", "streaming"))
+				Ω(tier2.Labels).Should(HaveKeyWithValue("owner", "platform"))
+			})
+		})
+		Context("configmap reconcile policy", func() {
+			var (
+				client       client.Client
+				err          error
+				foundPravega *v1beta1.PravegaCluster
+				configMap    *corev1.ConfigMap
+			)
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				_ = r.reconcileConfigMap(foundPravega)
+				configMap = &corev1.ConfigMap{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.ConfigMapNameForController(), Namespace: p.Namespace}, configMap)
+				configMap.Data["JAVA_OPTS"] = "-Xmx2g"
+				_ = client.Update(context.TODO(), configMap)
+			})
+
+			Context("Ignore", func() {
+				BeforeEach(func() {
+					foundPravega.Spec.Pravega.ConfigMapReconcilePolicy = v1beta1.ConfigMapReconcilePolicyIgnore
+					err = r.reconcileConfigMap(foundPravega)
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.ConfigMapNameForController(), Namespace: p.Namespace}, configMap)
+				})
+				It("should not error", func() {
+					Ω(err).Should(BeNil())
+				})
+				It("should leave the manual edit in place", func() {
+					Ω(configMap.Data["JAVA_OPTS"]).Should(Equal("-Xmx2g"))
+				})
+				It("should set the ConfigMapReconcileIgnored condition", func() {
+					_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionConfigMapReconcileIgnored)
+					Ω(condition).ShouldNot(BeNil())
+					Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+				})
+				It("should clear the condition and overwrite the edit once the policy is Enforce", func() {
+					foundPravega.Spec.Pravega.ConfigMapReconcilePolicy = v1beta1.ConfigMapReconcilePolicyEnforce
+					err = r.reconcileConfigMap(foundPravega)
+					Ω(err).Should(BeNil())
+					_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionConfigMapReconcileIgnored)
+					Ω(condition.Status).Should(Equal(corev1.ConditionFalse))
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.ConfigMapNameForController(), Namespace: p.Namespace}, configMap)
+					Ω(configMap.Data["JAVA_OPTS"]).ShouldNot(Equal("-Xmx2g"))
+				})
+			})
+		})
+		Context("zookeeper uri change", func() {
+			var (
+				client           client.Client
+				err              error
+				foundPravega     *v1beta1.PravegaCluster
+				deploy           *appsv1.Deployment
+				sts              *appsv1.StatefulSet
+				controllerHash   string
+				segmentStoreHash string
+			)
+
+			getPodTemplates := func() {
+				deploy = &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				sts = &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+			}
+
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.WithDefaults()
+				_ = r.deployCluster(foundPravega)
+				getPodTemplates()
+				controllerHash = deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+				segmentStoreHash = sts.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+				foundPravega.Spec.ZookeeperUri = "zookeeper-2-client:2181"
+				err = r.reconcileConfigMap(foundPravega)
+				getPodTemplates()
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should bump the configmap hash of the pod templates", func() {
+				Ω(controllerHash).ShouldNot(BeEmpty())
+				Ω(segmentStoreHash).ShouldNot(BeEmpty())
+				Ω(deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]).ShouldNot(Equal(controllerHash))
+				Ω(sts.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]).ShouldNot(Equal(segmentStoreHash))
+			})
+			It("should be idempotent", func() {
+				controllerHash = deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+				err = r.reconcileConfigMap(foundPravega)
+				Ω(err).Should(BeNil())
+				err = r.deployCluster(foundPravega)
+				Ω(err).Should(BeNil())
+				getPodTemplates()
+				Ω(deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]).Should(Equal(controllerHash))
+			})
+			It("should leave the hash to the upgrade in progress", func() {
+				controllerHash = deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]
+				foundPravega.Status.SetUpgradingConditionTrue("", "")
+				foundPravega.Spec.ZookeeperUri = "zookeeper-3-client:2181"
+				err = r.reconcileConfigMap(foundPravega)
+				Ω(err).Should(BeNil())
+				getPodTemplates()
+				Ω(deploy.Spec.Template.Annotations[pravega.ConfigMapHashAnnotationKey]).Should(Equal(controllerHash))
+			})
+		})
+		Context("reconcileRenderedConfigMap", func() {
+			var (
+				client    client.Client
+				err       error
+				configMap *corev1.ConfigMap
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.Pravega.Options["controller.retention.frequencyMinutes"] = "10"
+				p.Spec.Pravega.Options["autoScale.authEnabled.tokenSigningKey"] = "not-a-secret"
+				p.Spec.Pravega.SegmentStoreJVMOptions = []string{"-Xmx2g"}
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				err = r.reconcileRenderedConfigMap(p)
+				configMap = &corev1.ConfigMap{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForRendered(), Namespace: p.Namespace}, configMap)
+			})
+
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should be named after the cluster and owned by it", func() {
+				Ω(configMap.Name).Should(Equal(p.Name + "-pravega-rendered"))
+				Ω(configMap.OwnerReferences).Should(HaveLen(1))
+				Ω(configMap.OwnerReferences[0].Name).Should(Equal(p.Name))
+			})
+			It("should hold the merged options of both components", func() {
+				Ω(configMap.Data["controller.options"]).Should(ContainSubstring("controller.retention.frequencyMinutes=10"))
+				Ω(configMap.Data["controller.options"]).Should(ContainSubstring("pravegaservice.clusterName=" + p.Name))
+				Ω(configMap.Data["segmentstore.options"]).Should(ContainSubstring("controller.retention.frequencyMinutes=10"))
+				Ω(configMap.Data["segmentstore.jvmOptions"]).Should(ContainSubstring("-Xmx2g"))
+			})
+			It("should redact the sensitive options", func() {
+				Ω(configMap.Data["controller.options"]).Should(ContainSubstring("autoScale.authEnabled.tokenSigningKey=<redacted>"))
+				Ω(configMap.Data["controller.options"]).ShouldNot(ContainSubstring("not-a-secret"))
+			})
+			It("should reference the secrets read into the environment", func() {
+				p.Spec.Authentication = &v1beta1.AuthenticationParameters{
+					Enabled: true,
+					ControllerTokenSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "controller-token"},
+						Key:                  "signing-key",
+					},
+				}
+				err = r.reconcileRenderedConfigMap(p)
+				Ω(err).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForRendered(), Namespace: p.Namespace}, configMap)
+				Ω(configMap.Data["controller.secrets"]).Should(ContainSubstring("TOKEN_SIGNING_KEY=secret:controller-token/signing-key"))
+			})
+			It("should revert an edit of the configmap", func() {
+				configMap.Data["controller.options"] = "edited"
+				_ = client.Update(context.TODO(), configMap)
+				err = r.reconcileRenderedConfigMap(p)
+				Ω(err).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForRendered(), Namespace: p.Namespace}, configMap)
+				Ω(configMap.Data["controller.options"]).Should(ContainSubstring("controller.retention.frequencyMinutes=10"))
+			})
+		})
+		Context("reconcileGrafanaDashboard", func() {
+			var (
+				client    client.Client
+				err       error
+				configMap *corev1.ConfigMap
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				configMap = &corev1.ConfigMap{}
+			})
+
+			Context("dashboard enabled", func() {
+				BeforeEach(func() {
+					config.GrafanaDashboard = true
+					err = r.reconcileGrafanaDashboard(p)
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForGrafanaDashboard(), Namespace: p.Namespace}, configMap)
+				})
+				AfterEach(func() {
+					config.GrafanaDashboard = false
+				})
+				It("should not error", func() {
+					Ω(err).Should(BeNil())
+				})
+				It("should create the configmap with the grafana sidecar label", func() {
+					Ω(configMap.Labels).Should(HaveKeyWithValue("grafana_dashboard", "1"))
+					Ω(configMap.Labels).Should(HaveKeyWithValue("pravega_cluster", p.Name))
+					Ω(configMap.Labels).Should(HaveKeyWithValue("app", "pravega-cluster"))
+				})
+				It("should hold the dashboard json", func() {
+					Ω(configMap.Data).Should(HaveKey("pravega-" + p.Name + ".json"))
+					Ω(configMap.Data["pravega-"+p.Name+".json"]).Should(ContainSubstring("pravega_segmentstore_segment_write_bytes_total"))
+				})
+				It("should be owned by the cluster", func() {
+					Ω(configMap.OwnerReferences).Should(HaveLen(1))
+					Ω(configMap.OwnerReferences[0].Name).Should(Equal(p.Name))
+				})
+				It("should query the metrics under the metrics prefix", func() {
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{Prefix: "prod.pravega"}
+					err = r.reconcileGrafanaDashboard(p)
+					Ω(err).Should(BeNil())
+					configMap = &corev1.ConfigMap{}
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForGrafanaDashboard(), Namespace: p.Namespace}, configMap)
+					Ω(configMap.Data["pravega-"+p.Name+".json"]).Should(ContainSubstring("prod_pravega_segmentstore_segment_write_bytes_total"))
+					Ω(configMap.Data["pravega-"+p.Name+".json"]).ShouldNot(ContainSubstring("\"pravega_segmentstore"))
+				})
+			})
+
+			Context("dashboard disabled", func() {
+				BeforeEach(func() {
+					err = r.reconcileGrafanaDashboard(p)
+				})
+				It("should not create the configmap", func() {
+					Ω(err).Should(BeNil())
+					err = client.Get(context.TODO(), types.NamespacedName{Name: p.ConfigMapNameForGrafanaDashboard(), Namespace: p.Namespace}, configMap)
+					Ω(errors.IsNotFound(err)).Should(Equal(true))
+				})
+			})
+		})
+		Context("reconcileServiceMonitor", func() {
+			var (
+				smClient *serviceMonitorClient
+				err      error
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				smClient = &serviceMonitorClient{Client: fake.NewFakeClient(p)}
+				r = &ReconcilePravegaCluster{client: smClient, scheme: s}
+			})
+
+			Context("service monitor enabled", func() {
+				BeforeEach(func() {
+					smClient.installed = true
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+						ServiceMonitor: &v1beta1.ServiceMonitorSpec{
+							Enabled:  true,
+							Interval: "15s",
+							Labels:   map[string]string{"release": "prometheus"},
+						},
+					}
+					err = r.reconcileServiceMonitor(p)
+				})
+				It("should create the service monitor", func() {
+					Ω(err).Should(BeNil())
+					Ω(smClient.created).Should(HaveLen(1))
+					monitor := smClient.created[0]
+					Ω(monitor.GetName()).Should(Equal(p.ServiceMonitorName()))
+					Ω(monitor.GetLabels()).Should(HaveKeyWithValue("release", "prometheus"))
+					Ω(monitor.GetLabels()).Should(HaveKeyWithValue("pravega_cluster", p.Name))
+				})
+				It("should be owned by the cluster", func() {
+					Ω(smClient.created[0].GetOwnerReferences()).Should(HaveLen(1))
+					Ω(smClient.created[0].GetOwnerReferences()[0].Name).Should(Equal(p.Name))
+				})
+				It("should scrape the rest port with the configured interval", func() {
+					endpoints, _, _ := unstructured.NestedSlice(smClient.created[0].Object, "spec", "endpoints")
+					Ω(endpoints).Should(HaveLen(1))
+					Ω(endpoints[0]).Should(HaveKeyWithValue("port", "rest"))
+					Ω(endpoints[0]).Should(HaveKeyWithValue("path", "/prometheus"))
+					Ω(endpoints[0]).Should(HaveKeyWithValue("interval", "15s"))
+				})
+				It("should add the rest port to the segment store headless service", func() {
+					svc := pravega.MakeSegmentStoreHeadlessService(p)
+					Ω(hasServicePort(svc.Spec.Ports, "rest")).Should(Equal(true))
+				})
+			})
+
+			Context("prometheus operator not installed", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+						ServiceMonitor: &v1beta1.ServiceMonitorSpec{Enabled: true},
+					}
+					err = r.reconcileServiceMonitor(p)
+				})
+				It("should skip the service monitor without error", func() {
+					Ω(err).Should(BeNil())
+					Ω(smClient.created).Should(BeEmpty())
+				})
+			})
+
+			Context("service monitor disabled", func() {
+				BeforeEach(func() {
+					smClient.installed = true
+					err = r.reconcileServiceMonitor(p)
+				})
+				It("should not create the service monitor", func() {
+					Ω(err).Should(BeNil())
+					Ω(smClient.created).Should(BeEmpty())
+					svc := pravega.MakeSegmentStoreHeadlessService(p)
+					Ω(hasServicePort(svc.Spec.Ports, "rest")).Should(Equal(false))
+				})
+				It("should remove the rest port from the existing segment store headless service", func() {
+					p.Spec.Pravega.Metrics = &v1beta1.MetricsSpec{
+						ServiceMonitor: &v1beta1.ServiceMonitorSpec{Enabled: true},
+					}
+					Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+					p.Spec.Pravega.Metrics = nil
+					Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+					svc := &corev1.Service{}
+					_ = smClient.Get(context.TODO(), types.NamespacedName{Name: p.HeadlessServiceNameForSegmentStore(), Namespace: p.Namespace}, svc)
+					Ω(hasServicePort(svc.Spec.Ports, "rest")).Should(Equal(false))
+					Ω(hasServicePort(svc.Spec.Ports, "server")).Should(Equal(true))
+				})
+			})
+		})
+		Context("syncNodeAnnotationRestart", func() {
+			var (
+				client client.Client
+				err    error
+				node   *corev1.Node
+				pod    *corev1.Pod
+			)
+
+			BeforeEach(func() {
+				config.NodeWatch = true
+				p.WithDefaults()
+				p.Spec.Pravega.SegmentStoreRestartNodeAnnotation = "example.com/driver-version"
+				node = &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "node-1",
+						Annotations: map[string]string{"example.com/driver-version": "2"},
+					},
+				}
+				pod = &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      p.StatefulSetNameForSegmentstore() + "-0",
+						Namespace: Namespace,
+						Labels:    p.LabelsForSegmentStore(),
+					},
+					Spec: corev1.PodSpec{
+						NodeName: "node-1",
+					},
+					Status: corev1.PodStatus{
+						Conditions: []corev1.PodCondition{
+							{
+								Type:   corev1.PodReady,
+								Status: corev1.ConditionTrue,
+							},
+						},
+					},
+				}
+			})
+
+			AfterEach(func() {
+				config.NodeWatch = false
+			})
+
+			Context("pod without a recorded annotation value", func() {
+				BeforeEach(func() {
+					client = fake.NewFakeClient(p, node, pod)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					err = r.syncNodeAnnotationRestart(p)
+				})
+				It("should record the node annotation value on the pod", func() {
+					Ω(err).Should(BeNil())
+					foundPod := &corev1.Pod{}
+					err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: Namespace}, foundPod)
+					Ω(err).Should(BeNil())
+					Ω(foundPod.Annotations["pravega.nodeAnnotationValue"]).To(Equal("2"))
+				})
+			})
+
+			Context("node annotation changed since the pod started", func() {
+				BeforeEach(func() {
+					pod.Annotations = map[string]string{"pravega.nodeAnnotationValue": "1"}
+					client = fake.NewFakeClient(p, node, pod)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					err = r.syncNodeAnnotationRestart(p)
+				})
+				It("should restart the segment store pod", func() {
+					Ω(err).Should(BeNil())
+					foundPod := &corev1.Pod{}
+					err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: Namespace}, foundPod)
+					Ω(errors.IsNotFound(err)).Should(Equal(true))
+				})
+			})
+
+			Context("node annotation unchanged", func() {
+				BeforeEach(func() {
+					pod.Annotations = map[string]string{"pravega.nodeAnnotationValue": "2"}
+					client = fake.NewFakeClient(p, node, pod)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					err = r.syncNodeAnnotationRestart(p)
+				})
+				It("should not restart the segment store pod", func() {
+					Ω(err).Should(BeNil())
+					foundPod := &corev1.Pod{}
+					err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: Namespace}, foundPod)
+					Ω(err).Should(BeNil())
+				})
+			})
+
+			Context("node watch disabled", func() {
+				BeforeEach(func() {
+					config.NodeWatch = false
+					pod.Annotations = map[string]string{"pravega.nodeAnnotationValue": "1"}
+					client = fake.NewFakeClient(p, node, pod)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					err = r.syncNodeAnnotationRestart(p)
+				})
+				It("should not restart the segment store pod", func() {
+					Ω(err).Should(BeNil())
+					foundPod := &corev1.Pod{}
+					err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: Namespace}, foundPod)
+					Ω(err).Should(BeNil())
+				})
+			})
+		})
+		Context("watched namespaces", func() {
+			AfterEach(func() {
+				config.WatchNamespaces = nil
+			})
+
+			It("should watch every namespace by default", func() {
+				Ω(namespaceWatched(Namespace)).Should(BeTrue())
+				Ω(inWatchedNamespaces.Create(event.CreateEvent{Meta: p, Object: p})).Should(BeTrue())
+			})
+			It("should filter the clusters of the other namespaces", func() {
+				config.WatchNamespaces = []string{"team-a", "team-b"}
+				Ω(namespaceWatched("team-b")).Should(BeTrue())
+				Ω(inWatchedNamespaces.Update(event.UpdateEvent{MetaOld: p, ObjectOld: p, MetaNew: p, ObjectNew: p})).Should(BeFalse())
+			})
+			It("should not restart the segment stores of the other namespaces on node changes", func() {
+				p.WithDefaults()
+				p.Spec.Pravega.SegmentStoreRestartNodeAnnotation = "example.com/driver-version"
+				c := fake.NewFakeClient(p)
+				Ω(clustersRestartingOnNodeAnnotation(c)).Should(HaveLen(1))
+				config.WatchNamespaces = []string{"team-a"}
+				Ω(clustersRestartingOnNodeAnnotation(c)).Should(BeEmpty())
+			})
+		})
+		Context("cluster ready event", func() {
+			var (
+				client client.Client
+				events *corev1.EventList
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.Version = "0.9.0"
+				pods := []runtime.Object{p}
+				for i := 0; i < p.GetClusterExpectedSize(); i++ {
+					pods = append(pods, &corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      fmt.Sprintf("pod-%d", i),
+							Namespace: Namespace,
+							Labels:    p.LabelsForPravegaCluster(),
+						},
+						Status: corev1.PodStatus{
+							Conditions: []corev1.PodCondition{
+								{
+									Type:   corev1.PodReady,
+									Status: corev1.ConditionTrue,
+								},
+							},
+						},
+					})
+				}
+				client = fake.NewFakeClient(pods...)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				// The second reconcile must not publish the event again
+				for i := 0; i < 2; i++ {
+					foundPravega := &v1beta1.PravegaCluster{}
+					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					_ = r.reconcileClusterStatus(foundPravega)
+				}
+				events = &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+			})
+			It("should publish a single cluster ready event", func() {
+				Ω(events.Items).Should(HaveLen(1))
+				Ω(events.Items[0].Reason).Should(Equal("ClusterReady"))
+				Ω(events.Items[0].Type).Should(Equal("Normal"))
+			})
+			It("should summarize the cluster in the event", func() {
+				Ω(events.Items[0].Message).Should(ContainSubstring("version 0.9.0"))
+				Ω(events.Items[0].Annotations).Should(HaveKeyWithValue("pravega.version", "0.9.0"))
+				Ω(events.Items[0].Annotations).Should(HaveKeyWithValue("pravega.controllerReplicas", "1"))
+				Ω(events.Items[0].Annotations).Should(HaveKeyWithValue("pravega.segmentStoreReplicas", "1"))
+			})
+		})
+		Context("member versions", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+			)
+
+			pod := func(name string, image string) *corev1.Pod {
+				return &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: Namespace,
+						Labels:    p.LabelsForPravegaCluster(),
+					},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "pravega", Image: image}}},
+				}
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				client = fake.NewFakeClient(p,
+					pod("segmentstore-0", "pravega/pravega:0.9.0"),
+					pod("segmentstore-1", "pravega/pravega:0.8.0"))
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				_ = r.reconcileClusterStatus(foundPravega)
+			})
+			It("should record the image tag of every member", func() {
+				Ω(foundPravega.Status.Members.Versions).Should(Equal(map[string]string{
+					"segmentstore-0": "0.9.0",
+					"segmentstore-1": "0.8.0",
+				}))
+			})
+		})
+		Context("reconcileSegmentStoreHeadlessService", func() {
+			var (
+				client  client.Client
+				err     error
+				service *corev1.Service
+				events  *corev1.EventList
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				existing := pravega.MakeSegmentStoreHeadlessService(p)
+				existing.Annotations = map[string]string{"mesh.example.com/status": "injected"}
+				client = fake.NewFakeClient(p, existing)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations = map[string]string{
+					"mesh.example.com/inject": "true",
+					"service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags": "team=storage",
+				}
+				err = r.reconcileSegmentStoreHeadlessService(p)
+				service = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.HeadlessServiceNameForSegmentStore(), Namespace: p.Namespace}, service)
+				events = &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should keep the existing annotations and add the user ones", func() {
+				Ω(service.Annotations).Should(HaveKeyWithValue("mesh.example.com/status", "injected"))
+				Ω(service.Annotations).Should(HaveKeyWithValue("mesh.example.com/inject", "true"))
+				Ω(service.Annotations).ShouldNot(HaveKey("service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"))
+			})
+			It("should publish a warning event for the colliding annotation", func() {
+				Ω(events.Items).Should(HaveLen(1))
+				Ω(events.Items[0].Type).Should(Equal("Warning"))
+				Ω(events.Items[0].Message).Should(ContainSubstring("service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags"))
+			})
+			It("should not publish the event again while the annotations are unchanged", func() {
+				Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+				_ = client.List(context.TODO(), events)
+				Ω(events.Items).Should(HaveLen(1))
+			})
+			It("should remove the annotations removed from the spec only", func() {
+				delete(p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations, "mesh.example.com/inject")
+				Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+				service = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.HeadlessServiceNameForSegmentStore(), Namespace: p.Namespace}, service)
+				Ω(service.Annotations).ShouldNot(HaveKey("mesh.example.com/inject"))
+				Ω(service.Annotations).Should(HaveKeyWithValue("mesh.example.com/status", "injected"))
+				p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations = nil
+				Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+				service = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.HeadlessServiceNameForSegmentStore(), Namespace: p.Namespace}, service)
+				Ω(service.Annotations).ShouldNot(HaveKey(pravega.AppliedAnnotationsAnnotationKey))
+				Ω(service.Annotations).Should(HaveKeyWithValue("mesh.example.com/status", "injected"))
+			})
+			It("should publish the event when only a colliding annotation is added", func() {
+				p.Spec.Pravega.SegmentStoreHeadlessServiceAnnotations["external-dns.alpha.kubernetes.io/hostname"] = "ss.example.com."
+				Ω(r.reconcileSegmentStoreHeadlessService(p)).Should(BeNil())
+				_ = client.List(context.TODO(), events)
+				Ω(events.Items).Should(HaveLen(2))
+			})
+		})
+		Context("external traffic policy change", func() {
+			var (
+				client          client.Client
+				err             error
+				controllerSvc   *corev1.Service
+				segmentStoreSvc *corev1.Service
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				existing := []runtime.Object{p, pravega.MakeControllerService(p)}
+				for _, svc := range pravega.MakeSegmentStoreExternalServices(p) {
+					existing = append(existing, svc)
+				}
+				client = fake.NewFakeClient(existing...)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				p.Spec.ExternalAccess.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+				err = r.reconcileService(p)
+				controllerSvc = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForController(), Namespace: p.Namespace}, controllerSvc)
+				segmentStoreSvc = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForSegmentStore(0), Namespace: p.Namespace}, segmentStoreSvc)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the policy of the existing services", func() {
+				Ω(controllerSvc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeLocal))
+				Ω(segmentStoreSvc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyTypeLocal))
+			})
+		})
+		Context("load balancer tags change", func() {
+			var (
+				client          client.Client
+				err             error
+				controllerSvc   *corev1.Service
+				segmentStoreSvc *corev1.Service
+			)
+
+			getServices := func() {
+				controllerSvc = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForController(), Namespace: p.Namespace}, controllerSvc)
+				segmentStoreSvc = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForSegmentStore(0), Namespace: p.Namespace}, segmentStoreSvc)
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				existing := []runtime.Object{p, pravega.MakeControllerService(p)}
+				for _, svc := range pravega.MakeSegmentStoreExternalServices(p) {
+					existing = append(existing, svc)
+				}
+				client = fake.NewFakeClient(existing...)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				p.Spec.ExternalAccess.LoadBalancerTags = map[string]string{"team": "streaming"}
+				err = r.reconcileService(p)
+				getServices()
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should tag the existing services", func() {
+				Ω(controllerSvc.Annotations).Should(HaveKeyWithValue(pravega.LoadBalancerTagsAnnotationKey, "team=streaming"))
+				Ω(segmentStoreSvc.Annotations).Should(HaveKeyWithValue(pravega.LoadBalancerTagsAnnotationKey, "team=streaming"))
+			})
+			It("should remove the tags once they are cleared", func() {
+				p.Spec.ExternalAccess.LoadBalancerTags = nil
+				Ω(r.reconcileService(p)).Should(BeNil())
+				getServices()
+				Ω(controllerSvc.Annotations).ShouldNot(HaveKey(pravega.LoadBalancerTagsAnnotationKey))
+				Ω(segmentStoreSvc.Annotations).ShouldNot(HaveKey(pravega.LoadBalancerTagsAnnotationKey))
+			})
+		})
+		Context("external traffic port change", func() {
+			var (
+				client          client.Client
+				err             error
+				segmentStoreSvc *corev1.Service
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				existing := []runtime.Object{p, pravega.MakeControllerService(p)}
+				for _, svc := range pravega.MakeSegmentStoreExternalServices(p) {
+					existing = append(existing, svc)
+				}
+				client = fake.NewFakeClient(existing...)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				p.Spec.Pravega.SegmentStoreExternalTrafficPort = 31000
+				err = r.reconcileService(p)
+				segmentStoreSvc = &corev1.Service{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.ServiceNameForSegmentStore(0), Namespace: p.Namespace}, segmentStoreSvc)
+			})
+			It("should not error", func() {
+				Ω(err).Should(BeNil())
+			})
+			It("should update the port of the existing services", func() {
+				Ω(segmentStoreSvc.Spec.Ports[0].Port).To(Equal(int32(31000)))
+				Ω(segmentStoreSvc.Spec.Ports[0].TargetPort.IntValue()).To(Equal(12345))
+			})
+		})
+		Context("syncSegmentStoreEndpoints", func() {
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				p.Spec.Pravega.SegmentStoreReplicas = 2
+				services := pravega.MakeSegmentStoreExternalServices(p)
+				services[0].Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "ss0.example.com"}}
+				client := fake.NewFakeClient(p, services[0], services[1])
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r.syncSegmentStoreEndpoints(p)
+			})
+			It("should record the endpoints of the provisioned load balancers", func() {
+				Ω(p.Status.SegmentStoreEndpoints).Should(Equal(map[string]string{
+					p.StatefulSetNameForSegmentstore() + "-0": "ss0.example.com:12345",
+				}))
+			})
+			It("should clear the endpoints when external access is disabled", func() {
+				p.Spec.ExternalAccess.Enabled = false
+				r.syncSegmentStoreEndpoints(p)
+				Ω(p.Status.SegmentStoreEndpoints).Should(BeNil())
+			})
+		})
+		Context("segment stores scaled to zero", func() {
+			var client client.Client
+
+			zeroSegmentStoresEvents := func() int {
+				events := &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+				count := 0
+				for _, event := range events.Items {
+					if event.Reason == v1beta1.ZeroSegmentStoresReason {
+						count++
+					}
+				}
+				return count
+			}
+			reconcileReplicas := func() int32 {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, err := r.Reconcile(req)
+				Ω(err).Should(BeNil())
+				foundPravega := &v1beta1.PravegaCluster{}
+				Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+				return foundPravega.Spec.Pravega.SegmentStoreReplicas
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Status.ObservedGeneration = 1
+				p.Spec.Pravega.SegmentStoreReplicas = 0
+			})
+			It("should scale the segment stores back to one and warn", func() {
+				Ω(reconcileReplicas()).Should(Equal(int32(1)))
+				Ω(zeroSegmentStoresEvents()).Should(Equal(1))
+			})
+			It("should silently default the replicas of a new cluster", func() {
+				p.Status.ObservedGeneration = 0
+				Ω(reconcileReplicas()).Should(Equal(int32(1)))
+				Ω(zeroSegmentStoresEvents()).Should(Equal(0))
+			})
+			It("should keep zero segment stores when allowed", func() {
+				p.Spec.Pravega.AllowZeroSegmentStores = true
+				Ω(reconcileReplicas()).Should(Equal(int32(0)))
+				Ω(zeroSegmentStoresEvents()).Should(Equal(0))
+			})
+		})
+		Context("syncSegmentStoreContainerCount", func() {
+			var client client.Client
+
+			containerCountEvents := func() []string {
+				events := &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+				messages := []string{}
+				for _, event := range events.Items {
+					if event.Reason == v1beta1.ContainerCountChangedReason {
+						messages = append(messages, event.Message)
+					}
+				}
+				return messages
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+			})
+			It("should record the count without warning on a new cluster", func() {
+				count := int32(8)
+				p.Spec.Pravega.SegmentStoreContainerCount = &count
+				r.syncSegmentStoreContainerCount(p)
+				Ω(p.Status.SegmentStoreContainerCount).To(Equal(int32(8)))
+				Ω(containerCountEvents()).To(BeEmpty())
+			})
+			It("should warn when the count changes on a deployed cluster", func() {
+				p.Status.CurrentVersion = p.Spec.Version
+				p.Status.SegmentStoreContainerCount = 8
+				count := int32(16)
+				p.Spec.Pravega.SegmentStoreContainerCount = &count
+				r.syncSegmentStoreContainerCount(p)
+				r.syncSegmentStoreContainerCount(p)
+				Ω(p.Status.SegmentStoreContainerCount).To(Equal(int32(16)))
+				Ω(containerCountEvents()).To(Equal([]string{
+					"segmentStoreContainerCount changed from 8 to 16, which Pravega does not support on an existing cluster",
+				}))
+			})
+		})
+		Context("syncControllerEndpoint", func() {
+			var service *corev1.Service
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.ExternalAccess.Enabled = true
+				p.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+				service = pravega.MakeControllerService(p)
+			})
+			It("should not record the endpoint until the load balancer is provisioned", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, service), scheme: s}
+				r.syncControllerEndpoint(p)
+				Ω(p.Status.ControllerEndpoint).Should(BeEmpty())
+			})
+			It("should record the endpoint of the grpc port", func() {
+				service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, service), scheme: s}
+				r.syncControllerEndpoint(p)
+				Ω(p.Status.ControllerEndpoint).Should(Equal("10.0.0.1:9090"))
+				p.Spec.ExternalAccess.Enabled = false
+				r.syncControllerEndpoint(p)
+				Ω(p.Status.ControllerEndpoint).Should(BeEmpty())
+			})
+		})
+		Context("reconcileRunAsIdentity", func() {
+			var (
+				client client.Client
+				err    error
+				secret *corev1.Secret
+				deploy *appsv1.Deployment
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				nonRoot := true
+				p.Spec.Pravega.ControllerSecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: &nonRoot}
+				secret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pravega-identity",
+						Namespace: Namespace,
+					},
+					Data: map[string][]byte{
+						"runAsUser":  []byte("1000"),
+						"runAsGroup": []byte("2000"),
+					},
+				}
+			})
+
+			Context("identity from the secret", func() {
+				BeforeEach(func() {
+					client = fake.NewFakeClient(p, secret, pravega.MakeControllerDeployment(p))
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					p.Spec.Pravega.RunAsIdentitySecret = "pravega-identity"
+					err = r.reconcileRunAsIdentity(p)
+					Ω(err).Should(BeNil())
+					err = r.syncControllerPodTemplate(p, false)
+					deploy = &appsv1.Deployment{}
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				})
+				It("should record the identity in the status", func() {
+					Ω(err).Should(BeNil())
+					Ω(p.Status.RunAsIdentity.RunAsUser).To(Equal(int64(1000)))
+					Ω(*p.Status.RunAsIdentity.RunAsGroup).To(Equal(int64(2000)))
+				})
+				It("should update the controller pod template", func() {
+					securityContext := deploy.Spec.Template.Spec.SecurityContext
+					Ω(*securityContext.RunAsUser).To(Equal(int64(1000)))
+					Ω(*securityContext.RunAsGroup).To(Equal(int64(2000)))
+					Ω(*securityContext.RunAsNonRoot).To(Equal(true))
+				})
+				It("should clear the identity once the secret is removed from the spec", func() {
+					p.Spec.Pravega.RunAsIdentitySecret = ""
+					err = r.reconcileRunAsIdentity(p)
+					Ω(err).Should(BeNil())
+					Ω(p.Status.RunAsIdentity).To(BeNil())
+					err = r.syncControllerPodTemplate(p, false)
+					Ω(err).Should(BeNil())
+					deploy = &appsv1.Deployment{}
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+					securityContext := deploy.Spec.Template.Spec.SecurityContext
+					Ω(securityContext.RunAsUser).To(BeNil())
+					Ω(securityContext.RunAsGroup).To(BeNil())
+					Ω(*securityContext.RunAsNonRoot).To(Equal(true))
+				})
+			})
+
+			Context("root user with runAsNonRoot", func() {
+				BeforeEach(func() {
+					secret.Data["runAsUser"] = []byte("0")
+					client = fake.NewFakeClient(p, secret)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					p.Spec.Pravega.RunAsIdentitySecret = "pravega-identity"
+					err = r.reconcileRunAsIdentity(p)
+				})
+				It("should return error", func() {
+					Ω(err).ShouldNot(BeNil())
+					Ω(err.Error()).Should(ContainSubstring("runAsUser must be non-zero"))
+					Ω(p.Status.RunAsIdentity).To(BeNil())
+				})
+			})
+
+			Context("secret missing", func() {
+				BeforeEach(func() {
+					client = fake.NewFakeClient(p)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					p.Spec.Pravega.RunAsIdentitySecret = "pravega-identity"
+					err = r.reconcileRunAsIdentity(p)
+				})
+				It("should return error", func() {
+					Ω(err).ShouldNot(BeNil())
+				})
+			})
+		})
+		Context("segmentContainerCounts", func() {
+			var counts []v1beta1.SegmentContainerStatus
+
+			BeforeEach(func() {
+				mapping := map[string][]int32{
+					"10.0.0.1": {0, 1, 2},
+					"10.0.0.2": {3},
+					"10.0.0.9": {4},
+				}
+				pods := []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-segment-store-1"},
+						Status:     corev1.PodStatus{PodIP: "10.0.0.2"},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-segment-store-0"},
+						Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "example-pravega-segment-store-2"},
+						Status:     corev1.PodStatus{PodIP: "10.0.0.3"},
+					},
+				}
+				counts = segmentContainerCounts(mapping, pods)
+			})
+			It("should count the containers of each segment store ordinal", func() {
+				Ω(counts).To(Equal([]v1beta1.SegmentContainerStatus{
+					{Ordinal: 0, PodName: "example-pravega-segment-store-0", ContainerCount: 3},
+					{Ordinal: 1, PodName: "example-pravega-segment-store-1", ContainerCount: 1},
+					{Ordinal: 2, PodName: "example-pravega-segment-store-2", ContainerCount: 0},
+				}))
+			})
+		})
+		Context("segmentContainerSyncDue", func() {
+			var (
+				key = types.NamespacedName{Name: "example", Namespace: Namespace}
+				now = time.Now()
+			)
+
+			BeforeEach(func() {
+				forgetSegmentContainerSync(key)
+			})
+
+			It("should read the mapping at most once per interval", func() {
+				Ω(segmentContainerSyncDue(key, now)).To(BeTrue())
+				Ω(segmentContainerSyncDue(key, now.Add(segmentContainerSyncInterval/2))).To(BeFalse())
+				Ω(segmentContainerSyncDue(key, now.Add(segmentContainerSyncInterval))).To(BeTrue())
+			})
+			It("should read the mapping again once the cluster is forgotten", func() {
+				Ω(segmentContainerSyncDue(key, now)).To(BeTrue())
+				forgetSegmentContainerSync(key)
+				Ω(segmentContainerSyncDue(key, now)).To(BeTrue())
+			})
+		})
+		Context("segment store pdb during a segment container rebalance", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				err          error
+			)
+
+			getMaxUnavailable := func() int {
+				pdb := &policyv1beta1.PodDisruptionBudget{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.PdbNameForSegmentstore(), Namespace: Namespace}, pdb)
+				return pdb.Spec.MaxUnavailable.IntValue()
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.Pravega.SegmentStoreReplicas = 3
+				p.Spec.Pravega.SegmentStoreRebalanceProtection = true
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				err = r.reconcileSegmentStorePdb(foundPravega)
+			})
+			It("should allow one disruption without rebalance", func() {
+				Ω(err).Should(BeNil())
+				Ω(getMaxUnavailable()).Should(Equal(1))
+			})
+			It("should allow no disruption while the rebalance is in progress", func() {
+				foundPravega.Status.SegmentContainers = []v1beta1.SegmentContainerStatus{
+					{Ordinal: 0, ContainerCount: 4},
+					{Ordinal: 1, ContainerCount: 4},
+					{Ordinal: 2, ContainerCount: 0},
+				}
+				err = r.reconcileSegmentStorePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(getMaxUnavailable()).Should(Equal(0))
+
+				// the rebalance completes
+				foundPravega.Status.SegmentContainers[0].ContainerCount = 3
+				foundPravega.Status.SegmentContainers[2].ContainerCount = 1
+				err = r.reconcileSegmentStorePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(getMaxUnavailable()).Should(Equal(1))
+			})
+			It("should not tighten the pdb if the protection is disabled", func() {
+				foundPravega.Spec.Pravega.SegmentStoreRebalanceProtection = false
+				foundPravega.Status.SegmentContainers = []v1beta1.SegmentContainerStatus{
+					{Ordinal: 0, ContainerCount: 4},
+					{Ordinal: 1, ContainerCount: 4},
+					{Ordinal: 2, ContainerCount: 0},
+				}
+				err = r.reconcileSegmentStorePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(getMaxUnavailable()).Should(Equal(1))
+			})
+			It("should patch the budget of the spec in place", func() {
+				minAvailable := intstr.FromInt(2)
+				foundPravega.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MinAvailable: &minAvailable}
+				err = r.reconcileSegmentStorePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				pdb := &policyv1beta1.PodDisruptionBudget{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.PdbNameForSegmentstore(), Namespace: Namespace}, pdb)
+				Ω(pdb.Spec.MinAvailable.IntValue()).Should(Equal(2))
+				Ω(pdb.Spec.MaxUnavailable).Should(BeNil())
+
+				foundPravega.Spec.Pravega.SegmentStorePdb = nil
+				err = r.reconcileSegmentStorePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(getMaxUnavailable()).Should(Equal(1))
+			})
+		})
+		Context("disabling the pod disruption budgets", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				err          error
+			)
+
+			getPdb := func(name string) (*policyv1beta1.PodDisruptionBudget, error) {
+				pdb := &policyv1beta1.PodDisruptionBudget{}
+				err := client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: Namespace}, pdb)
+				return pdb, err
+			}
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.Pravega.SegmentStoreReplicas = 3
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				err = r.reconcilePdb(foundPravega)
+			})
+			It("should create the budgets by default", func() {
+				Ω(err).Should(BeNil())
+				_, err = getPdb(foundPravega.PdbNameForSegmentstore())
+				Ω(err).Should(BeNil())
+				_, err = getPdb(foundPravega.PdbNameForController())
+				Ω(err).Should(BeNil())
+			})
+			It("should delete the budgets once disabled and recreate them once enabled again", func() {
+				foundPravega.Spec.Pravega.DisablePdb = true
+				err = r.reconcilePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				_, err = getPdb(foundPravega.PdbNameForSegmentstore())
+				Ω(errors.IsNotFound(err)).Should(BeTrue())
+				_, err = getPdb(foundPravega.PdbNameForController())
+				Ω(errors.IsNotFound(err)).Should(BeTrue())
+
+				foundPravega.Spec.Pravega.DisablePdb = false
+				err = r.reconcilePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				pdb, err := getPdb(foundPravega.PdbNameForSegmentstore())
+				Ω(err).Should(BeNil())
+				Ω(pdb.Spec.MaxUnavailable.IntValue()).Should(Equal(1))
+				pdb, err = getPdb(foundPravega.PdbNameForController())
+				Ω(err).Should(BeNil())
+				Ω(pdb.Spec.MinAvailable.IntValue()).Should(Equal(1))
+			})
+			It("should not delete a budget the operator did not create", func() {
+				foundPravega.Spec.Pravega.DisablePdb = true
+				pdb, _ := getPdb(foundPravega.PdbNameForSegmentstore())
+				pdb.OwnerReferences = nil
+				Ω(client.Update(context.TODO(), pdb)).Should(BeNil())
+				err = r.reconcilePdb(foundPravega)
+				Ω(err).Should(BeNil())
+				_, err = getPdb(foundPravega.PdbNameForSegmentstore())
+				Ω(err).Should(BeNil())
+			})
+		})
+		Context("syncPodsFailedCondition", func() {
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Status.Init()
+			})
+
+			It("should not add the condition while no pod failed", func() {
+				syncPodsFailedCondition(p)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionPodsFailed)
+				Ω(condition).Should(BeNil())
+			})
+			It("should list the failed pods in the condition", func() {
+				p.Status.Members.Failed = []string{"example-pravega-segment-store-1", "example-pravega-segment-store-0"}
+				syncPodsFailedCondition(p)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionPodsFailed)
+				Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(condition.Reason).Should(Equal(v1beta1.PodsNotProgressingReason))
+				Ω(condition.Message).Should(Equal("2 pods not ready for more than 10m0s: example-pravega-segment-store-0, example-pravega-segment-store-1"))
+			})
+			It("should clear the condition once the pods recover", func() {
+				p.Status.Members.Failed = []string{"example-pravega-segment-store-0"}
+				syncPodsFailedCondition(p)
+				p.Status.Members.Failed = nil
+				syncPodsFailedCondition(p)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionPodsFailed)
+				Ω(condition.Status).Should(Equal(corev1.ConditionFalse))
+			})
+		})
+		Context("syncScalingStalledCondition", func() {
+			var (
+				pod  *corev1.Pod
+				pvc  *corev1.PersistentVolumeClaim
+				now  time.Time
+				long time.Time
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				p.Status.Init()
+				p.Status.Replicas = 2
+				p.Status.ReadyReplicas = 1
+				now = time.Now()
+				long = now.Add(-p.ScalingStallTimeout() - time.Minute)
+				pod = &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      p.StatefulSetNameForSegmentstore() + "-0",
+						Namespace: Namespace,
+						Labels:    p.LabelsForSegmentStore(),
+					},
+					Status: corev1.PodStatus{Phase: corev1.PodPending},
+				}
+				pvc = &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cache-" + pod.Name,
+						Namespace: Namespace,
+						Labels:    p.LabelsForSegmentStore(),
+					},
+					Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+				}
+			})
+
+			It("should record the time of the readiness change", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				r.syncScalingStalledCondition(p, nil, true, now)
+				Ω(p.Status.LastReadinessChangeTime).Should(Equal(now.UTC().Format(time.RFC3339)))
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionScalingStalled)
+				Ω(condition).Should(BeNil())
+			})
+			It("should report the pending claims of a stalled scaling", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, pvc), scheme: s}
+				p.Status.LastReadinessChangeTime = long.UTC().Format(time.RFC3339)
+				r.syncScalingStalledCondition(p, []corev1.Pod{*pod}, false, now)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionScalingStalled)
+				Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(condition.Reason).Should(Equal(v1beta1.ClaimPendingReason))
+				Ω(condition.Message).Should(Equal("1 of 2 pods ready for more than 15m0s, pending pvcs: " + pvc.Name))
+			})
+			It("should report the pending pods without pending claims", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				p.Status.LastReadinessChangeTime = long.UTC().Format(time.RFC3339)
+				r.syncScalingStalledCondition(p, []corev1.Pod{*pod}, false, now)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionScalingStalled)
+				Ω(condition.Reason).Should(Equal(v1beta1.PodsPendingReason))
+				Ω(condition.Message).Should(ContainSubstring("pending pods: " + pod.Name))
+			})
+			It("should not report a stall during an upgrade", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				p.Status.SetUpgradingConditionTrue("", "")
+				p.Status.LastReadinessChangeTime = long.UTC().Format(time.RFC3339)
+				r.syncScalingStalledCondition(p, []corev1.Pod{*pod}, false, now)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionScalingStalled)
+				Ω(condition).Should(BeNil())
+			})
+			It("should clear the condition once the scaling progresses", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				p.Status.LastReadinessChangeTime = long.UTC().Format(time.RFC3339)
+				r.syncScalingStalledCondition(p, []corev1.Pod{*pod}, false, now)
+				r.syncScalingStalledCondition(p, []corev1.Pod{*pod}, true, now)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionScalingStalled)
+				Ω(condition.Status).Should(Equal(corev1.ConditionFalse))
+			})
+		})
+		Context("syncThroughputStatus", func() {
+			var (
+				server  *httptest.Server
+				written int64
+				pods    []corev1.Pod
+				start   time.Time
+			)
+
+			BeforeEach(func() {
+				p.WithDefaults()
+				config.ThroughputStatus = true
+				config.ThroughputStatusInterval = time.Minute
+				atomic.StoreInt64(&written, 1000)
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if req.URL.Path != "/prometheus" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					fmt.Fprintf(w, "# TYPE pravega_segmentstore_segment_write_bytes_total counter\n")
+					fmt.Fprintf(w, "pravega_segmentstore_segment_write_bytes_total{segment=\"a\"} %d\n", atomic.LoadInt64(&written))
+					fmt.Fprintf(w, "pravega_segmentstore_segment_write_bytes_total{segment=\"b\"} %d\n", atomic.LoadInt64(&written))
+				}))
+				serverURL, _ := url.Parse(server.URL)
+				host, port, _ := net.SplitHostPort(serverURL.Host)
+				segmentStoreMetricsPort, _ = strconv.Atoi(port)
+				pods = []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "example-pravega-segment-store-0",
+							Labels: map[string]string{"component": "pravega-segmentstore"},
+						},
+						Status: corev1.PodStatus{
+							PodIP: host,
+							Conditions: []corev1.PodCondition{
+								{
+									Type:   corev1.PodReady,
+									Status: corev1.ConditionTrue,
+								},
+							},
+						},
+					},
+				}
+				start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				r.syncThroughputStatus(p, pods, start)
+			})
+
+			AfterEach(func() {
+				server.Close()
+				config.ThroughputStatus = false
+				segmentStoreMetricsPort = util.SegmentStoreRESTPort
+			})
+
+			It("should record the first sample without a rate", func() {
+				Ω(p.Status.Throughput).NotTo(BeNil())
+				Ω(p.Status.Throughput.WriteBytesTotal).To(Equal(int64(2000)))
+				Ω(p.Status.Throughput.WriteBytesPerSecond).To(Equal(int64(0)))
+			})
+
+			It("should not sample again within the interval", func() {
+				atomic.StoreInt64(&written, 4000)
+				r.syncThroughputStatus(p, pods, start.Add(30*time.Second))
+				Ω(p.Status.Throughput.WriteBytesTotal).To(Equal(int64(2000)))
+			})
 
-	Context("Reconcile", func() {
-		var (
-			req reconcile.Request
-			res reconcile.Result
-			p   *v1beta1.PravegaCluster
-		)
+			It("should compute the rate from the previous sample", func() {
+				atomic.StoreInt64(&written, 4000)
+				r.syncThroughputStatus(p, pods, start.Add(time.Minute))
+				Ω(p.Status.Throughput.WriteBytesTotal).To(Equal(int64(8000)))
+				Ω(p.Status.Throughput.WriteBytesPerSecond).To(Equal(int64(100)))
+			})
 
-		BeforeEach(func() {
-			req = reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      Name,
-					Namespace: Namespace,
-				},
-			}
-			p = &v1beta1.PravegaCluster{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      Name,
-					Namespace: Namespace,
-				},
-			}
-			s.AddKnownTypes(v1beta1.SchemeGroupVersion, p)
+			It("should reset the rate when the counters drop", func() {
+				atomic.StoreInt64(&written, 10)
+				r.syncThroughputStatus(p, pods, start.Add(time.Minute))
+				Ω(p.Status.Throughput.WriteBytesTotal).To(Equal(int64(20)))
+				Ω(p.Status.Throughput.WriteBytesPerSecond).To(Equal(int64(0)))
+			})
 		})
-		Context("Should have Reconcile Result false when request namespace does not contain pravega cluster", func() {
+		Context("checkNodeAllocatable", func() {
 			var (
 				client client.Client
 				err    error
+				node   *corev1.Node
 			)
+
 			BeforeEach(func() {
-				client = fake.NewFakeClient(p)
-				r = &ReconcilePravegaCluster{client: client, scheme: s}
-				req.NamespacedName.Namespace = "temp"
-				res, err = r.Reconcile(req)
+				p.WithDefaults()
+				p.Spec.Pravega.SchedulingPreCheck = true
+				node = &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-1",
+					},
+					Status: corev1.NodeStatus{
+						Allocatable: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("1"),
+							corev1.ResourceMemory: resource.MustParse("2Gi"),
+						},
+					},
+				}
 			})
-			It("should have false in reconcile result", func() {
-				Ω(res.Requeue).To(Equal(false))
-				Ω(err).To(BeNil())
+
+			Context("pod requests larger than every node", func() {
+				BeforeEach(func() {
+					client = fake.NewFakeClient(p, node)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					p.Spec.Pravega.SegmentStoreResources.Requests[corev1.ResourceMemory] = resource.MustParse("4Gi")
+					err = r.checkNodeAllocatable(p, p.Spec.Pravega.SegmentStoreResources, v1beta1.InsufficientSegmentstoreResourcesReason, "segment store")
+				})
+				It("should set the insufficient resources condition", func() {
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition).NotTo(BeNil())
+					Ω(condition.Status).To(Equal(corev1.ConditionTrue))
+					Ω(condition.Reason).To(Equal(v1beta1.InsufficientSegmentstoreResourcesReason))
+					Ω(condition.Message).To(ContainSubstring("memory=4Gi"))
+				})
+				It("should clear the condition once a node can fit the pod", func() {
+					bigNode := &corev1.Node{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node-2",
+						},
+						Status: corev1.NodeStatus{
+							Allocatable: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("4"),
+								corev1.ResourceMemory: resource.MustParse("8Gi"),
+							},
+						},
+					}
+					Ω(client.Create(context.TODO(), bigNode)).Should(BeNil())
+					err = r.checkNodeAllocatable(p, p.Spec.Pravega.SegmentStoreResources, v1beta1.InsufficientSegmentstoreResourcesReason, "segment store")
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition.Status).To(Equal(corev1.ConditionFalse))
+				})
+				It("should clear the condition once the requests are lowered without scale-up", func() {
+					sts := pravega.MakeSegmentStoreStatefulSet(p)
+					Ω(client.Create(context.TODO(), sts)).Should(BeNil())
+					p.Spec.Pravega.SegmentStoreResources.Requests[corev1.ResourceMemory] = resource.MustParse("1Gi")
+					err = r.syncSegmentStoreSize(p)
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition.Status).To(Equal(corev1.ConditionFalse))
+				})
+				It("should clear the condition once the pre-check is disabled", func() {
+					p.Spec.Pravega.SchedulingPreCheck = false
+					err = r.checkNodeAllocatable(p, p.Spec.Pravega.SegmentStoreResources, v1beta1.InsufficientSegmentstoreResourcesReason, "segment store")
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition.Status).To(Equal(corev1.ConditionFalse))
+				})
 			})
-		})
 
-		Context("Delete STS with sts not present", func() {
+			Context("pod requests fitting in a node", func() {
+				BeforeEach(func() {
+					client = fake.NewFakeClient(p, node)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					err = r.checkNodeAllocatable(p, p.Spec.Pravega.ControllerResources, v1beta1.InsufficientControllerResourcesReason, "controller")
+				})
+				It("should not set the insufficient resources condition", func() {
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition).To(BeNil())
+				})
+			})
+
+			Context("pre-check disabled", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.SchedulingPreCheck = false
+					client = fake.NewFakeClient(p, node)
+					r = &ReconcilePravegaCluster{client: client, scheme: s}
+					p.Spec.Pravega.SegmentStoreResources.Requests[corev1.ResourceMemory] = resource.MustParse("4Gi")
+					err = r.checkNodeAllocatable(p, p.Spec.Pravega.SegmentStoreResources, v1beta1.InsufficientSegmentstoreResourcesReason, "segment store")
+				})
+				It("should not set the insufficient resources condition", func() {
+					Ω(err).Should(BeNil())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionInsufficientResources)
+					Ω(condition).To(BeNil())
+				})
+			})
+		})
+		Context("checkPravegaImage", func() {
 			var (
-				client       client.Client
-				err          error
-				foundPravega *v1beta1.PravegaCluster
+				client  client.Client
+				checked []string
+				found   bool
+				lookup  error
 			)
 
 			BeforeEach(func() {
+				p.WithDefaults()
+				p.Spec.Pravega.ImageCheck = true
+				checked = nil
+				found, lookup = true, nil
+				imageChecks.results = map[string]imageCheck{}
+				imageExists = func(image string) (bool, error) {
+					checked = append(checked, image)
+					return found, lookup
+				}
 				client = fake.NewFakeClient(p)
 				r = &ReconcilePravegaCluster{client: client, scheme: s}
-				//1st reconcile
-				foundPravega = &v1beta1.PravegaCluster{}
-				_, _ = r.Reconcile(req)
-				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
-				err = r.deleteSTS(foundPravega)
 			})
-			It("shouldn't error", func() {
-				Ω(err).Should(BeNil())
+
+			AfterEach(func() {
+				imageExists = util.ImageExists
+			})
+
+			Context("image missing from the registry", func() {
+				BeforeEach(func() {
+					found = false
+					r.checkPravegaImage(p)
+				})
+				It("should check the image of the requested version", func() {
+					Ω(checked).To(Equal([]string{p.PravegaImage()}))
+				})
+				It("should set the image not found condition", func() {
+					Ω(p.Status.IsImageNotFound()).To(BeTrue())
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionImageNotFound)
+					Ω(condition.Reason).To(Equal(v1beta1.PravegaImageNotFoundReason))
+					Ω(condition.Message).Should(ContainSubstring(p.PravegaImage()))
+				})
+				It("should publish a warning event once", func() {
+					r.checkPravegaImage(p)
+					events := &corev1.EventList{}
+					_ = client.List(context.TODO(), events)
+					Ω(events.Items).To(HaveLen(1))
+					Ω(events.Items[0].Reason).To(Equal(v1beta1.PravegaImageNotFoundReason))
+				})
+				It("should not ask the registry again within the check interval", func() {
+					r.checkPravegaImage(p)
+					Ω(checked).To(HaveLen(1))
+				})
+				It("should clear the condition once the image exists", func() {
+					found = true
+					imageChecks.results = map[string]imageCheck{}
+					r.checkPravegaImage(p)
+					Ω(p.Status.IsImageNotFound()).To(BeFalse())
+				})
+				It("should hold back the creation of the pods", func() {
+					Ω(r.deployCluster(p)).Should(BeNil())
+					err := client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, &appsv1.Deployment{})
+					Ω(errors.IsNotFound(err)).To(BeTrue())
+					err = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, &appsv1.StatefulSet{})
+					Ω(errors.IsNotFound(err)).To(BeTrue())
+				})
+			})
+
+			Context("image found", func() {
+				BeforeEach(func() {
+					r.checkPravegaImage(p)
+					r.checkPravegaImage(p)
+				})
+				It("should only ask the registry once", func() {
+					Ω(checked).To(Equal([]string{p.PravegaImage()}))
+					Ω(p.Status.IsImageNotFound()).To(BeFalse())
+				})
+			})
+
+			Context("registry unreachable", func() {
+				BeforeEach(func() {
+					found, lookup = false, fmt.Errorf("connection refused")
+					r.checkPravegaImage(p)
+				})
+				It("should not hold back the deployment", func() {
+					_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionImageNotFound)
+					Ω(condition).To(BeNil())
+				})
+			})
+
+			Context("check disabled", func() {
+				BeforeEach(func() {
+					p.Spec.Pravega.ImageCheck = false
+					found = false
+					r.checkPravegaImage(p)
+				})
+				It("should not check the registry", func() {
+					Ω(checked).To(BeEmpty())
+				})
 			})
 		})
-		Context("deleteOldSegmentStoreIfExist ", func() {
-			var (
-				client       client.Client
-				err, err1    error
-				foundPravega *v1beta1.PravegaCluster
-			)
+		Context("checkStorageClasses", func() {
+			var storageClass *storagev1.StorageClass
 
 			BeforeEach(func() {
-				client = fake.NewFakeClient(p)
+				p.WithDefaults()
+				config.StorageClassCheck = true
+				p.Spec.Pravega.SegmentStoreJournalVolume = &v1beta1.JournalVolumeSpec{
+					StorageClassName: "fast-ssd",
+					Size:             "50Gi",
+				}
+				storageClass = &storagev1.StorageClass{
+					ObjectMeta:  metav1.ObjectMeta{Name: "fast-ssd"},
+					Provisioner: "kubernetes.io/no-provisioner",
+				}
+			})
+
+			AfterEach(func() {
+				config.StorageClassCheck = false
+			})
+
+			It("should set the condition if the storage class is missing", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				r.checkStorageClasses(p)
+				Ω(p.Status.IsStorageClassNotFound()).To(BeTrue())
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionStorageClassNotFound)
+				Ω(condition.Message).To(Equal("storage class fast-ssd of the journal claims was not found"))
+			})
+
+			It("should hold back the creation of the segment store stateful set only", func() {
+				client := fake.NewFakeClient(p)
 				r = &ReconcilePravegaCluster{client: client, scheme: s}
-				//1st reconcile
-				foundPravega = &v1beta1.PravegaCluster{}
-				_, _ = r.Reconcile(req)
-				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				r.checkStorageClasses(p)
+				Ω(r.deployCluster(p)).Should(BeNil())
+				err := client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, &appsv1.Deployment{})
+				Ω(err).Should(BeNil())
+				err = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, &appsv1.StatefulSet{})
+				Ω(errors.IsNotFound(err)).To(BeTrue())
+			})
+
+			It("should clear the condition once the storage class exists", func() {
+				p.Status.SetStorageClassNotFoundConditionTrue(v1beta1.StorageClassNotFoundReason, "")
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, storageClass), scheme: s}
+				r.checkStorageClasses(p)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionStorageClassNotFound)
+				Ω(condition.Status).To(Equal(corev1.ConditionFalse))
+			})
+
+			It("should read the storage classes through the cluster-scoped reader", func() {
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), clusterReader: fake.NewFakeClient(storageClass), scheme: s}
+				r.checkStorageClasses(p)
+				Ω(p.Status.IsStorageClassNotFound()).To(BeFalse())
+			})
+
+			It("should not check the storage classes if the check is disabled", func() {
+				config.StorageClassCheck = false
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+				r.checkStorageClasses(p)
+				Ω(p.Status.IsStorageClassNotFound()).To(BeFalse())
+			})
+
+			It("should set the condition while a claim is pending on a missing storage class", func() {
+				name := "fast-ssd"
 				pvc := &corev1.PersistentVolumeClaim{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "cache-example-pravega-segmentstore-0",
-						Namespace: p.Namespace,
-					},
-				}
-				r.client.Create(context.TODO(), pvc)
-				svc := &corev1.Service{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "example-pravega-segmentstore-0",
+						Name:      "journal-" + p.StatefulSetNameForSegmentstore() + "-0",
 						Namespace: p.Namespace,
+						Labels:    p.LabelsForSegmentStore(),
 					},
+					Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: &name},
+					Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
 				}
-				r.client.Create(context.TODO(), svc)
-				sts := &appsv1.StatefulSet{}
-				foundPravega.Spec.Version = "0.5.0"
-				foundPravega.Spec.Pravega.SegmentStoreReplicas = 2
-				foundPravega.Spec.ExternalAccess.Enabled = true
-				foundPravega.WithDefaults()
-				sts = pravega.MakeSegmentStoreStatefulSet(foundPravega)
-				extService := &corev1.Service{}
-				pravega.MakeSegmentStoreExternalServices(foundPravega)
-				extService.Name = p.ServiceNameForSegmentStoreBelow07(int32(0))
-				r.client.Create(context.TODO(), extService)
-				svcName := p.ServiceNameForSegmentStoreBelow07(int32(0))
-				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: svcName, Namespace: foundPravega.Namespace}, extService)
-				r.client.Create(context.TODO(), sts)
-				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: sts.Name, Namespace: foundPravega.Namespace}, sts)
-				err = r.deleteOldSegmentStoreIfExists(foundPravega)
-				foundPravega.Spec.ExternalAccess.Enabled = false
-				err1 = r.deleteOldSegmentStoreIfExists(foundPravega)
-			})
-			It("shouldn't error", func() {
-				Ω(err).Should(BeNil())
-			})
-			It("shouldn't error", func() {
-				Ω(err1).Should(BeNil())
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, pvc), scheme: s}
+				r.syncClaimPendingCondition(p)
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionStorageClassNotFound)
+				Ω(condition.Status).To(Equal(corev1.ConditionTrue))
+				Ω(condition.Reason).To(Equal(v1beta1.ClaimPendingReason))
+				Ω(condition.Message).To(ContainSubstring(pvc.Name))
+
+				r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p, pvc, storageClass), scheme: s}
+				r.syncClaimPendingCondition(p)
+				_, condition = p.Status.GetClusterCondition(v1beta1.ClusterConditionStorageClassNotFound)
+				Ω(condition.Status).To(Equal(corev1.ConditionFalse))
 			})
 		})
-		Context("syncControllerSize", func() {
+
+		Context("Reconcile backoff", func() {
 			var (
-				client       client.Client
-				err, err1    error
-				foundPravega *v1beta1.PravegaCluster
+				client client.Client
+				secret *corev1.Secret
 			)
 
 			BeforeEach(func() {
+				p.WithDefaults()
+				// the reconciles fail until the run as identity secret exists
+				p.Spec.Pravega.RunAsIdentitySecret = "identity"
+				secret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "identity", Namespace: p.Namespace},
+					Data:       map[string][]byte{"runAsUser": []byte("1000")},
+				}
 				client = fake.NewFakeClient(p)
 				r = &ReconcilePravegaCluster{client: client, scheme: s}
-				//1st reconcile
-				foundPravega = &v1beta1.PravegaCluster{}
-				_, _ = r.Reconcile(req)
+				Backoff.Reset(req.NamespacedName)
+			})
 
-				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
-				deploy := &appsv1.Deployment{}
+			AfterEach(func() {
+				config.MaxReconcileBackoff = config.DefaultMaxReconcileBackoff
+			})
 
-				r.client.Create(context.TODO(), deploy)
-				name := p.DeploymentNameForController()
-				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
+			failedReconcileDelays := func(count int) []time.Duration {
+				var delays []time.Duration
+				for i := 0; i < count; i++ {
+					res, err := r.Reconcile(req)
+					Ω(err).Should(BeNil())
+					delays = append(delays, res.RequeueAfter)
+				}
+				return delays
+			}
 
-				foundPravega.Spec.Pravega.ControllerReplicas = 2
-				err1 = r.syncControllerSize(foundPravega)
-				_, _ = r.Reconcile(req)
-				err = r.syncControllerSize(foundPravega)
+			It("should requeue the failed reconciles with an increasing delay", func() {
+				Ω(failedReconcileDelays(4)).To(Equal([]time.Duration{
+					MinReconcileBackoff, 2 * MinReconcileBackoff, 4 * MinReconcileBackoff, 8 * MinReconcileBackoff,
+				}))
+			})
 
+			It("should cap the delay to the max reconcile backoff", func() {
+				config.MaxReconcileBackoff = 3 * MinReconcileBackoff
+				Ω(failedReconcileDelays(4)).To(Equal([]time.Duration{
+					MinReconcileBackoff, 2 * MinReconcileBackoff, 3 * MinReconcileBackoff, 3 * MinReconcileBackoff,
+				}))
 			})
-			It("should not give error", func() {
+
+			It("should reset the delay after a successful reconcile", func() {
+				Ω(failedReconcileDelays(3)).To(HaveLen(3))
+				_ = client.Create(context.TODO(), secret)
+				res, err := r.Reconcile(req)
 				Ω(err).Should(BeNil())
+				Ω(res.RequeueAfter).To(Equal(ReconcileTime))
+				_ = client.Delete(context.TODO(), secret)
+				Ω(failedReconcileDelays(1)).To(Equal([]time.Duration{MinReconcileBackoff}))
 			})
-			It("should give error", func() {
-				Ω(strings.ContainsAny(err1.Error(), "failed to get deployment")).Should(Equal(true))
+
+			It("should record the phase the reconcile failed in", func() {
+				Ω(failedReconcileDelays(1)).To(HaveLen(1))
+				foundPravega := &v1beta1.PravegaCluster{}
+				err := client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				Ω(err).Should(BeNil())
+				Ω(foundPravega.Status.ReconcilePhase).Should(Equal(v1beta1.ReconcilePhaseValidating))
 			})
 		})
+
 		Context("Without spec", func() {
 			var (
 				client       client.Client
@@ -251,6 +3397,81 @@ var _ = Describe("PravegaCluster Controller", func() {
 					Ω(foundPravega.Spec.Version).Should(Equal(v1beta1.DefaultPravegaVersion))
 					Ω(foundPravega.Status.CurrentVersion).Should(Equal(v1beta1.DefaultPravegaVersion))
 				})
+
+				It("should set the reconcile phase to Idle", func() {
+					foundPravega := &v1beta1.PravegaCluster{}
+					err = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					Ω(err).Should(BeNil())
+					Ω(foundPravega.Status.ReconcilePhase).Should(Equal(v1beta1.ReconcilePhaseIdle))
+				})
+
+				Context("Scaling the segment store", func() {
+					BeforeEach(func() {
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+						foundPravega.Spec.Pravega.SegmentStoreReplicas = 3
+						client.Update(context.TODO(), foundPravega)
+						res, err = r.Reconcile(req)
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					})
+
+					It("should set the reconcile phase to Scaling until the pods are ready", func() {
+						Ω(err).Should(BeNil())
+						Ω(foundPravega.Status.ReconcilePhase).Should(Equal(v1beta1.ReconcilePhaseScaling))
+					})
+				})
+
+				Context("Entering the scaling phase", func() {
+					BeforeEach(func() {
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+						foundPravega.Spec.Pravega.SegmentStoreReplicas = 3
+						err = r.syncSegmentStoreSize(foundPravega)
+					})
+
+					It("should save the phase before the reconcile ends", func() {
+						Ω(err).Should(BeNil())
+						savedPravega := &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, savedPravega)
+						Ω(savedPravega.Status.ReconcilePhase).Should(Equal(v1beta1.ReconcilePhaseScaling))
+					})
+				})
+
+				Context("Component reconcile times", func() {
+					var components = []string{
+						v1beta1.ComponentController,
+						v1beta1.ComponentSegmentStore,
+						v1beta1.ComponentServices,
+						v1beta1.ComponentConfigMaps,
+					}
+
+					It("should record the reconcile time of each component", func() {
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+						for _, component := range components {
+							Ω(foundPravega.Status.ComponentReconcileTimes).Should(HaveKey(component))
+						}
+					})
+
+					It("should update the reconcile times on the next reconcile", func() {
+						stale := metav1.NewTime(time.Now().Add(-time.Hour))
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+						for _, component := range components {
+							foundPravega.Status.ComponentReconcileTimes[component] = stale
+						}
+						_ = client.Status().Update(context.TODO(), foundPravega)
+						res, err = r.Reconcile(req)
+						Ω(err).Should(BeNil())
+						foundPravega = &v1beta1.PravegaCluster{}
+						_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+						for _, component := range components {
+							reconciled := foundPravega.Status.ComponentReconcileTimes[component]
+							Ω(reconciled.After(stale.Time)).Should(BeTrue(), component)
+						}
+					})
+				})
 			})
 
 			Context("Cluster deployment", func() {
@@ -368,6 +3589,57 @@ var _ = Describe("PravegaCluster Controller", func() {
 					})
 				})
 
+				Context("external services finalizer", func() {
+					var svcs []*corev1.Service
+
+					BeforeEach(func() {
+						p.WithDefaults()
+						svcs = nil
+						for _, svc := range []struct {
+							name        string
+							serviceType corev1.ServiceType
+						}{
+							{"example-pravega-segment-store-0", corev1.ServiceTypeLoadBalancer},
+							{"example-pravega-segment-store-1", corev1.ServiceTypeNodePort},
+							{"example-pravega-segment-store-headless", corev1.ServiceTypeClusterIP},
+						} {
+							svcs = append(svcs, &corev1.Service{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      svc.name,
+									Namespace: p.Namespace,
+									Labels:    p.LabelsForSegmentStore(),
+								},
+								Spec: corev1.ServiceSpec{Type: svc.serviceType},
+							})
+						}
+					})
+
+					It("should add the finalizer", func() {
+						r = &ReconcilePravegaCluster{client: fake.NewFakeClient(p), scheme: s}
+						Ω(r.reconcileFinalizers(p)).Should(BeNil())
+						Ω(p.Finalizers).To(ConsistOf(util.ZkFinalizer, externalServicesFinalizer))
+					})
+
+					It("should delete the external services before removing the finalizer", func() {
+						now := metav1.Now()
+						p.SetDeletionTimestamp(&now)
+						p.Finalizers = []string{externalServicesFinalizer}
+						// the first service was deleted by a previous reconcile
+						client = fake.NewFakeClient(p, svcs[1], svcs[2])
+						r = &ReconcilePravegaCluster{client: client, scheme: s}
+						Ω(r.reconcileFinalizers(p)).Should(BeNil())
+						Ω(p.Finalizers).To(BeEmpty())
+						for _, svc := range svcs {
+							err = client.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, &corev1.Service{})
+							if svc.Spec.Type == corev1.ServiceTypeClusterIP {
+								Ω(err).Should(BeNil())
+							} else {
+								Ω(errors.IsNotFound(err)).Should(BeTrue())
+							}
+						}
+					})
+				})
+
 				Context("cleanUpZookeeperMeta", func() {
 					BeforeEach(func() {
 						p.WithDefaults()
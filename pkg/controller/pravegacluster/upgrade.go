@@ -14,9 +14,11 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
 	"github.com/pravega/pravega-operator/pkg/util"
 	log "github.com/sirupsen/logrus"
@@ -26,10 +28,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// waitingForControllerMessage is the upgrading condition message while the segment store
+// upgrade waits for the upgraded controller
+const waitingForControllerMessage = "waiting for controller before segmentstore upgrade"
+
 type componentSyncVersionFun struct {
 	name string
 	fun  func(p *pravegav1beta1.PravegaCluster) (synced bool, err error)
@@ -72,7 +79,7 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1beta1.PravegaCl
 		syncCompleted, err := r.syncComponentsVersion(p)
 		if err != nil {
 			log.Printf("error syncing cluster version, upgrade failed. %v", err)
-			p.Status.SetErrorConditionTrue("UpgradeFailed", err.Error())
+			p.Status.SetErrorConditionTrue(pravegav1beta1.UpgradeFailedReason, err.Error())
 			// emit an event for Upgrade Failure
 			message := fmt.Sprintf("Error Upgrading from version %v to %v. %v", p.Status.CurrentVersion, p.Status.TargetVersion, err.Error())
 			event := p.NewEvent("UPGRADE_ERROR", pravegav1beta1.UpgradeErrorReason, message, "Error")
@@ -87,6 +94,7 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1beta1.PravegaCl
 		if syncCompleted {
 			// All component versions have been synced
 			p.Status.AddToVersionHistory(p.Status.TargetVersion)
+			p.Status.AddToUpgradeHistory(p.Status.TargetVersion, time.Now(), p.VersionHistoryLimit())
 			p.Status.CurrentVersion = p.Status.TargetVersion
 			log.Printf("Upgrade completed for all pravega components.")
 		}
@@ -95,8 +103,8 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1beta1.PravegaCl
 
 	// No upgrade in progress
 	if p.Spec.Version == p.Status.CurrentVersion {
-		// No intention to upgrade
-		return nil
+		// No intention to upgrade, but the image of a component may have changed
+		return r.syncComponentImages(p)
 	}
 
 	if !p.Status.IsClusterInRollbackFailedState() {
@@ -111,6 +119,15 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1beta1.PravegaCl
 		p.Status.SetErrorConditionFalse()
 	}
 
+	if p.Status.IsImageNotFound() {
+		log.Printf("cannot trigger upgrade to %s as its image was not found", p.Spec.Version)
+		return nil
+	}
+
+	if r.deferDisruptiveAction(p, fmt.Sprintf("upgrade to %s", p.Spec.Version)) {
+		return nil
+	}
+
 	// Need to sync cluster versions
 	log.Printf("syncing cluster version from %s to %s", p.Status.CurrentVersion, p.Spec.Version)
 	// Setting target version and condition.
@@ -124,6 +141,7 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1beta1.PravegaCl
 func (r *ReconcilePravegaCluster) clearUpgradeStatus(p *pravegav1beta1.PravegaCluster) (err error) {
 	p.Status.SetUpgradingConditionFalse()
 	p.Status.TargetVersion = ""
+	p.Status.UpgradePodsRemaining = 0
 	// need to deep copy the status struct, otherwise it will be overwritten
 	// when updating the CR below
 	status := p.Status.DeepCopy()
@@ -156,10 +174,18 @@ func (r *ReconcilePravegaCluster) rollbackClusterVersion(p *pravegav1beta1.Prave
 		return nil
 	}
 
+	// The ConfigMaps are restored before the pods are rolled back, so that the
+	// restarted pods get the options of the rolled back version
+	configMapsRestored, err := r.restoreConfigMapsForRollback(p, version)
+	if err != nil {
+		log.Printf("Error restoring configmaps for rollback to version %v. %v", version, err)
+		return err
+	}
+
 	syncCompleted, err := r.syncComponentsVersion(p)
 	if err != nil {
 		// Error rolling back, set appropriate status and ask for manual intervention
-		p.Status.SetErrorConditionTrue("RollbackFailed", err.Error())
+		p.Status.SetErrorConditionTrue(pravegav1beta1.RollbackFailedReason, err.Error())
 		// emit an event for Rollback Failure
 		message := fmt.Sprintf("Error Rollingback from version %v to %v. %v", p.Status.CurrentVersion, p.Status.TargetVersion, err.Error())
 		event := p.NewEvent("ROLLBACK_ERROR", pravegav1beta1.RollbackErrorReason, message, "Error")
@@ -173,8 +199,8 @@ func (r *ReconcilePravegaCluster) rollbackClusterVersion(p *pravegav1beta1.Prave
 		return err
 	}
 
-	if syncCompleted {
-		// All component versions have been synced
+	if syncCompleted && configMapsRestored {
+		// All component versions and ConfigMaps have been synced
 		p.Status.CurrentVersion = p.Status.TargetVersion
 		// Set Error/UpgradeFailed Condition to 'false', so rollback is not triggered again
 		p.Status.SetErrorConditionFalse()
@@ -185,6 +211,42 @@ func (r *ReconcilePravegaCluster) rollbackClusterVersion(p *pravegav1beta1.Prave
 	return nil
 }
 
+// restoreConfigMapsForRollback regenerates the controller and segment store ConfigMaps
+// from the spec for the version the cluster is rolled back to, and restores those whose
+// content differs, e.g. the ones generated for the failed upgrade. It returns true when
+// all the ConfigMaps already matched the rolled back version.
+func (r *ReconcilePravegaCluster) restoreConfigMapsForRollback(p *pravegav1beta1.PravegaCluster, version string) (restored bool, err error) {
+	rollback := p.DeepCopy()
+	rollback.Spec.Version = version
+	restored = true
+	for _, configMap := range []*corev1.ConfigMap{pravega.MakeControllerConfigMap(rollback), pravega.MakeSegmentstoreConfigMap(rollback)} {
+		controllerutil.SetControllerReference(p, configMap, r.scheme)
+		currentConfigMap := &corev1.ConfigMap{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: configMap.Name, Namespace: p.Namespace}, currentConfigMap)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return false, fmt.Errorf("failed to get configmap (%s): %v", configMap.Name, err)
+			}
+			restored = false
+			err = r.client.Create(context.TODO(), configMap)
+			if err != nil {
+				return false, fmt.Errorf("failed to create configmap (%s): %v", configMap.Name, err)
+			}
+			continue
+		}
+		if util.CompareConfigMap(currentConfigMap, configMap) {
+			continue
+		}
+		restored = false
+		log.Printf("Restoring configmap %s for rollback to version %v", configMap.Name, version)
+		err = r.client.Update(context.TODO(), configMap)
+		if err != nil {
+			return false, fmt.Errorf("failed to restore configmap (%s): %v", configMap.Name, err)
+		}
+	}
+	return restored, nil
+}
+
 func (r *ReconcilePravegaCluster) clearRollbackStatus(p *pravegav1beta1.PravegaCluster) (err error) {
 	log.Printf("clearRollbackStatus")
 	p.Status.SetRollbackConditionFalse()
@@ -202,15 +264,17 @@ func (r *ReconcilePravegaCluster) clearRollbackStatus(p *pravegav1beta1.PravegaC
 }
 
 func (r *ReconcilePravegaCluster) syncComponentsVersion(p *pravegav1beta1.PravegaCluster) (synced bool, err error) {
+	// The controller is upgraded first, the segment stores only roll once the upgraded
+	// controller pods are ready
 	componentSyncFuncs := []componentSyncVersionFun{
-		componentSyncVersionFun{
-			name: "segmentstore",
-			fun:  r.syncStoreVersion,
-		},
 		componentSyncVersionFun{
 			name: "controller",
 			fun:  r.syncControllerVersion,
 		},
+		componentSyncVersionFun{
+			name: "segmentstore",
+			fun:  r.syncSegmentStoreAfterController,
+		},
 	}
 
 	if p.Status.IsClusterInRollbackState() && p.Spec.Pravega.SegmentStoreReplicas > 1 {
@@ -236,6 +300,59 @@ func (r *ReconcilePravegaCluster) syncComponentsVersion(p *pravegav1beta1.Praveg
 	return true, nil
 }
 
+// syncSegmentStoreAfterController syncs the segment store version once the pods of the
+// cluster, including the upgraded controller pods, are ready. Until the segment store
+// rollout has started, the upgrade waits with the WaitingForControllerReason reason, and
+// fails if the pods are not ready within the timeout. Rollbacks do not wait, as the pods
+// of a failed upgrade may never become ready.
+func (r *ReconcilePravegaCluster) syncSegmentStoreAfterController(p *pravegav1beta1.PravegaCluster) (synced bool, err error) {
+	if !p.Status.IsClusterInUpgradingState() || p.Status.IsClusterInReadyState() {
+		return r.syncStoreVersion(p)
+	}
+	started, err := r.isSegmentStoreRolloutStarted(p)
+	if err != nil {
+		return false, err
+	}
+	if started {
+		return r.syncStoreVersion(p)
+	}
+	if isUpgradePaused(p) {
+		// The changed reason resets the timeout once the upgrade is resumed
+		p.Status.SetUpgradingConditionTrue(pravegav1beta1.UpgradePausedReason, waitingForControllerMessage)
+		return false, nil
+	}
+	lastCondition := p.Status.GetLastCondition()
+	if lastCondition == nil || lastCondition.Reason != pravegav1beta1.WaitingForControllerReason {
+		log.Printf("cluster %s is waiting for controller before segmentstore upgrade", p.Name)
+		p.Status.SetUpgradingConditionTrue(pravegav1beta1.WaitingForControllerReason, waitingForControllerMessage)
+		return false, nil
+	}
+	parsedTime, _ := time.Parse(time.RFC3339, lastCondition.LastUpdateTime)
+	if time.Now().After(parsedTime.Add(p.UpgradePodReadyTimeout())) {
+		return false, fmt.Errorf("pods not ready after the controller upgrade: progress deadline exceeded")
+	}
+	return false, nil
+}
+
+// isSegmentStoreRolloutStarted returns true once the segment store stateful set runs
+// the target image, which is the first step of the segment store upgrade
+func (r *ReconcilePravegaCluster) isSegmentStoreRolloutStarted(p *pravegav1beta1.PravegaCluster) (bool, error) {
+	sts := &appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The stateful set of Pravega 0.7 or above is created by the upgrade
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get statefulset (%s): %v", p.StatefulSetNameForSegmentstore(), err)
+	}
+	targetImage, err := p.SegmentStoreTargetImage()
+	if err != nil {
+		return false, err
+	}
+	return len(sts.Spec.Template.Spec.Containers) > 0 && sts.Spec.Template.Spec.Containers[0].Image == targetImage, nil
+}
+
 func (r *ReconcilePravegaCluster) syncComponent(component componentSyncVersionFun, p *pravegav1beta1.PravegaCluster) (synced bool, err error) {
 	isSyncComplete, err := component.fun(p)
 	if err != nil {
@@ -259,11 +376,25 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1beta1.Praveg
 		return false, fmt.Errorf("failed to get deployment (%s): %v", deploy.Name, err)
 	}
 
-	targetImage, err := p.PravegaTargetImage()
+	targetImage, err := p.ControllerTargetImage()
 	if err != nil {
 		return false, err
 	}
 
+	r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingController)
+
+	// Pausing the deployment stops the rolling update before the next pod is
+	// replaced, whether or not the pod template has already been updated
+	paused := isUpgradePaused(p)
+	if deploy.Spec.Paused != paused {
+		log.Printf("setting deployment (%s) paused to %t", deploy.Name, paused)
+		deploy.Spec.Paused = paused
+		err = r.client.Update(context.TODO(), deploy)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	if deploy.Spec.Template.Spec.Containers[0].Image != targetImage {
 		p.Status.UpdateProgress(pravegav1beta1.UpdatingControllerReason, "0")
 
@@ -294,6 +425,15 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1beta1.Praveg
 	// Check whether the upgrade is in progress or has completed
 	if deploy.Status.UpdatedReplicas != deploy.Status.Replicas ||
 		deploy.Status.UpdatedReplicas != deploy.Status.ReadyReplicas {
+		p.Status.UpgradePodsRemaining = deploy.Status.Replicas - deploy.Status.UpdatedReplicas
+		if paused {
+			p.Status.SetUpgradingConditionTrue(pravegav1beta1.UpgradePausedReason, fmt.Sprint(deploy.Status.UpdatedReplicas))
+			return false, nil
+		}
+		if lastCondition := p.Status.GetLastCondition(); lastCondition != nil && lastCondition.Reason == pravegav1beta1.UpgradePausedReason {
+			// The upgrade has been resumed
+			p.Status.UpdateProgress(pravegav1beta1.UpdatingControllerReason, fmt.Sprint(deploy.Status.UpdatedReplicas))
+		}
 		// Update still in progress, check if there is progress made within the timeout.
 		for _, v := range deploy.Status.Conditions {
 			if v.Type == appsv1.DeploymentProgressing &&
@@ -307,7 +447,7 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1beta1.Praveg
 		if err != nil {
 			return false, err
 		}
-		_, err = r.checkUpdatedPods(pods, p.Status.TargetVersion)
+		_, err = r.checkUpdatedPods(pods, p.UpgradePodReadyTimeout())
 		if err != nil {
 			// Abort if there is any errors with the updated pods
 			return false, err
@@ -317,6 +457,7 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1beta1.Praveg
 	}
 
 	// Deployment update completed
+	p.Status.UpgradePodsRemaining = 0
 	return true, nil
 }
 
@@ -329,11 +470,13 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1beta1.Prav
 		return false, fmt.Errorf("failed to get statefulset (%s): %v", sts.Name, err)
 	}
 
-	targetImage, err := p.PravegaTargetImage()
+	targetImage, err := p.SegmentStoreTargetImage()
 	if err != nil {
 		return false, err
 	}
 
+	r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingSegmentStore)
+
 	if sts.Spec.Template.Spec.Containers[0].Image != targetImage {
 		p.Status.UpdateProgress(pravegav1beta1.UpdatingSegmentstoreReason, "0")
 		// Need to update pod template
@@ -360,40 +503,72 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1beta1.Prav
 	// Pod template already updated
 	log.Printf("statefulset (%s) status: %d updated, %d ready, %d target", sts.Name,
 		sts.Status.UpdatedReplicas, sts.Status.ReadyReplicas, sts.Status.Replicas)
+	err = r.syncStuckTerminatingPods(p, sts, time.Now())
+	if err != nil {
+		return false, err
+	}
 	// Check whether the upgrade is in progress or has completed
 	if sts.Status.UpdatedReplicas == sts.Status.Replicas &&
 		sts.Status.UpdatedReplicas == sts.Status.ReadyReplicas {
 		// StatefulSet upgrade completed
+		p.Status.UpgradePodsRemaining = 0
 		return true, nil
 	}
 	// Upgrade still in progress
+	p.Status.UpgradePodsRemaining = sts.Status.Replicas - sts.Status.UpdatedReplicas
+	if isUpgradePaused(p) {
+		// Do not delete the next outdated pod until the upgrade is resumed. The changed
+		// reason resets the progress timeout once it is.
+		log.Printf("upgrade of statefulset (%s) is paused", sts.Name)
+		p.Status.SetUpgradingConditionTrue(pravegav1beta1.UpgradePausedReason, fmt.Sprint(sts.Status.UpdatedReplicas))
+		return false, nil
+	}
+	if segmentStoreRestartManual(p) {
+		// The user deletes the outdated pods, there is no progress to time out on
+		if lastCondition := p.Status.GetLastCondition(); lastCondition == nil || lastCondition.Reason != pravegav1beta1.ManualRestartRequiredReason {
+			r.publishManualRestartEvent(p, fmt.Sprintf("version %s", p.Status.TargetVersion))
+		}
+		p.Status.UpdateProgress(pravegav1beta1.ManualRestartRequiredReason, fmt.Sprint(sts.Status.UpdatedReplicas))
+		return false, nil
+	}
 	// Check if segmentstore fail to have progress within a timeout
 	err = checkSyncTimeout(p, pravegav1beta1.UpdatingSegmentstoreReason, sts.Status.UpdatedReplicas)
 	if err != nil {
 		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
 	}
 
-	// If all replicas are ready, upgrade an old pod
 	pods, err := r.getStsPodsWithVersion(sts, p.Status.TargetVersion)
 	if err != nil {
 		return false, err
 	}
-	ready, err := r.checkUpdatedPods(pods, p.Status.TargetVersion)
+	_, err = r.checkUpdatedPods(pods, p.UpgradePodReadyTimeout())
 	if err != nil {
 		// Abort if there is any errors with the updated pods
 		return false, err
 	}
 
-	if ready {
-		pod, err := r.getOneOutdatedPod(sts, p.Status.TargetVersion)
-		if err != nil {
-			return false, err
-		}
-
-		if pod == nil {
+	// Upgrade as many old pods as can still be unavailable, recomputed on every
+	// iteration from the pods that are currently ready
+	outdated, terminating, err := r.getOutdatedPods(sts, p.Status.TargetVersion)
+	if err != nil {
+		return false, err
+	}
+	limit := segmentStoreUpgradeLimit(p)
+	unavailable := sts.Status.Replicas - sts.Status.ReadyReplicas + terminating
+	if unavailable >= limit {
+		// Wait until next reconcile iteration
+		return false, nil
+	}
+	if len(outdated) == 0 {
+		if unavailable == 0 {
 			return false, fmt.Errorf("could not obtain outdated pod")
 		}
-
+		return false, nil
+	}
+	if count := int(limit - unavailable); len(outdated) > count {
+		outdated = outdated[:count]
+	}
+	for _, pod := range outdated {
 		log.Infof("upgrading pod: %s", pod.Name)
 
 		err = r.client.Delete(context.TODO(), pod)
@@ -406,6 +581,259 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1beta1.Prav
 	return false, nil
 }
 
+// syncStuckTerminatingPods sets the error condition while pods of the segment store stateful
+// set have been terminating for longer than the terminating pod timeout past their deletion
+// grace period, and clears it once none is. With the ForceDeleteStuckPods flag, the stuck
+// pods controlled by the stateful set of the cluster are deleted with no grace period.
+func (r *ReconcilePravegaCluster) syncStuckTerminatingPods(p *pravegav1beta1.PravegaCluster, sts *appsv1.StatefulSet, now time.Time) error {
+	timeout := p.UpgradeTerminatingPodTimeout()
+	stuck, err := r.getStuckTerminatingPods(sts, timeout, now)
+	if err != nil {
+		return err
+	}
+	if len(stuck) == 0 {
+		if p.Status.IsPodStuckTerminating() {
+			p.Status.SetErrorConditionFalse()
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(stuck))
+	for _, pod := range stuck {
+		names = append(names, pod.Name)
+	}
+	message := fmt.Sprintf("pods %s terminating for longer than %v", strings.Join(names, ", "), timeout)
+	log.Printf("statefulset (%s) upgrade blocked: %s", sts.Name, message)
+	p.Status.SetErrorConditionTrue(pravegav1beta1.PodStuckTerminatingReason, message)
+
+	if !config.ForceDeleteStuckPods || !metav1.IsControlledBy(sts, p) {
+		return nil
+	}
+	for _, pod := range stuck {
+		if !metav1.IsControlledBy(pod, sts) {
+			continue
+		}
+		log.Printf("force deleting pod %s stuck terminating", pod.Name)
+		err = r.client.Delete(context.TODO(), pod, client.GracePeriodSeconds(0))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// getStuckTerminatingPods returns the pods of the stateful set, sorted by name, whose
+// deletion grace period ended for longer than the timeout
+func (r *ReconcilePravegaCluster) getStuckTerminatingPods(sts *appsv1.StatefulSet, timeout time.Duration, now time.Time) ([]*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: sts.Spec.Template.Labels,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	podList := &corev1.PodList{}
+	podlistOps := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), podList, podlistOps)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(podList.Items, func(i int, j int) bool {
+		return podList.Items[i].Name < podList.Items[j].Name
+	})
+
+	var stuck []*corev1.Pod
+	for _, podItem := range podList.Items {
+		// the deletion timestamp is the end of the deletion grace period
+		if podItem.DeletionTimestamp == nil || now.Sub(podItem.DeletionTimestamp.Time) <= timeout {
+			continue
+		}
+		stuck = append(stuck, podItem.DeepCopy())
+	}
+	return stuck, nil
+}
+
+// segmentStoreUpgradeLimit returns the number of segment store pods that can be
+// unavailable at once during an upgrade: the configured maximum, bounded by the segment
+// store disruption budget. At least one pod is upgraded at a time, even when the budget
+// allows no disruption, e.g. with a single segment store.
+func segmentStoreUpgradeLimit(p *pravegav1beta1.PravegaCluster) int32 {
+	limit := p.SegmentStoreUpgradeMaxUnavailable()
+	if !p.Spec.Pravega.DisablePdb {
+		replicas := int(p.Spec.Pravega.SegmentStoreReplicas)
+		budget := pravega.MakeSegmentstorePodDisruptionBudget(p).Spec
+		var allowed int
+		if budget.MinAvailable != nil {
+			minAvailable, _ := intstr.GetValueFromIntOrPercent(budget.MinAvailable, replicas, true)
+			allowed = replicas - minAvailable
+		} else if budget.MaxUnavailable != nil {
+			allowed, _ = intstr.GetValueFromIntOrPercent(budget.MaxUnavailable, replicas, true)
+		}
+		if int32(allowed) < limit {
+			limit = int32(allowed)
+		}
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// syncComponentImages rolls out a change of the Controller or Segment Store image that
+// comes without a version change, e.g. a patched Segment Store image. Only the pods of
+// the component whose image changed are restarted, one at a time. A change of the pull
+// policy alone is applied to the pod templates without restarting the Segment Store pods.
+func (r *ReconcilePravegaCluster) syncComponentImages(p *pravegav1beta1.PravegaCluster) (err error) {
+	err = r.syncSegmentStoreImage(p)
+	if err != nil {
+		return err
+	}
+	return r.syncControllerImage(p)
+}
+
+func (r *ReconcilePravegaCluster) syncControllerImage(p *pravegav1beta1.PravegaCluster) (err error) {
+	deploy := &appsv1.Deployment{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get deployment (%s): %v", p.DeploymentNameForController(), err)
+	}
+	image := p.ControllerImage()
+	pullPolicy := p.ControllerImagePullPolicy()
+	container := &deploy.Spec.Template.Spec.Containers[0]
+	if container.Image == image && container.ImagePullPolicy == pullPolicy {
+		return nil
+	}
+	if container.Image != image && p.Status.IsImageNotFound() {
+		log.Printf("cannot update deployment (%s) pod template image to '%s' as it was not found", deploy.Name, image)
+		return nil
+	}
+	if r.deferDisruptiveAction(p, fmt.Sprintf("controller image update to %s with pull policy %s", image, pullPolicy)) {
+		return nil
+	}
+	// The deployment rolls the controller pods
+	log.Printf("updating deployment (%s) pod template image to '%s' with pull policy %s", deploy.Name, image, pullPolicy)
+	r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingController)
+	container.Image = image
+	container.ImagePullPolicy = pullPolicy
+	err = r.client.Update(context.TODO(), deploy)
+	if err != nil {
+		return fmt.Errorf("failed to update deployment (%s): %v", deploy.Name, err)
+	}
+	return nil
+}
+
+func (r *ReconcilePravegaCluster) syncSegmentStoreImage(p *pravegav1beta1.PravegaCluster) (err error) {
+	sts := &appsv1.StatefulSet{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get statefulset (%s): %v", p.StatefulSetNameForSegmentstore(), err)
+	}
+	image := p.SegmentStoreImage()
+	container := &sts.Spec.Template.Spec.Containers[0]
+	if container.Image != image {
+		if p.Status.IsImageNotFound() {
+			log.Printf("cannot update statefulset (%s) template image to '%s' as it was not found", sts.Name, image)
+			return nil
+		}
+		if r.deferDisruptiveAction(p, fmt.Sprintf("segment store image update to %s", image)) {
+			return nil
+		}
+		log.Printf("updating statefulset (%s) template image to '%s'", sts.Name, image)
+		r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingSegmentStore)
+		container.Image = image
+		container.ImagePullPolicy = p.SegmentStoreImagePullPolicy()
+		err = r.client.Update(context.TODO(), sts)
+		if err != nil {
+			return fmt.Errorf("failed to update statefulset (%s): %v", sts.Name, err)
+		}
+		if segmentStoreRestartManual(p) {
+			r.publishManualRestartEvent(p, fmt.Sprintf("the image %s", image))
+		}
+		return nil
+	}
+	if pullPolicy := p.SegmentStoreImagePullPolicy(); container.ImagePullPolicy != pullPolicy {
+		// The pull policy only matters when the image is pulled, the pods pick it up the
+		// next time they are recreated
+		log.Printf("updating statefulset (%s) template image pull policy to %s", sts.Name, pullPolicy)
+		container.ImagePullPolicy = pullPolicy
+		err = r.client.Update(context.TODO(), sts)
+		if err != nil {
+			return fmt.Errorf("failed to update statefulset (%s): %v", sts.Name, err)
+		}
+	}
+
+	if segmentStoreRestartManual(p) {
+		return nil
+	}
+	// The stateful set uses the OnDelete update strategy, the pods running the previous
+	// image or an outdated revision of the pod template are deleted one at a time, once
+	// all the segment store pods are ready
+	if sts.Spec.Replicas == nil || sts.Status.ReadyReplicas != *sts.Spec.Replicas {
+		return nil
+	}
+	pod, err := r.getOneOutdatedPod(sts, image)
+	if err != nil || pod == nil {
+		return err
+	}
+	log.Printf("restarting pod %s with image '%s' and revision %s", pod.Name, image, sts.Status.UpdateRevision)
+	r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingSegmentStore)
+	return r.client.Delete(context.TODO(), pod)
+}
+
+// getOneOutdatedPod returns a pod of the stateful set whose container does not run
+// the given image, or which was not created from the update revision of the stateful
+// set, or nil if all of them are up to date
+func (r *ReconcilePravegaCluster) getOneOutdatedPod(sts *appsv1.StatefulSet, image string) (*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: sts.Spec.Template.Labels,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	podList := &corev1.PodList{}
+	podlistOps := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), podList, podlistOps)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(podList.Items, func(i int, j int) bool {
+		return podList.Items[i].Name < podList.Items[j].Name
+	})
+
+	for _, podItem := range podList.Items {
+		revision := sts.Status.UpdateRevision
+		if revision != "" && podItem.Labels[appsv1.ControllerRevisionHashLabelKey] != revision {
+			return podItem.DeepCopy(), nil
+		}
+		if len(podItem.Spec.Containers) == 0 || podItem.Spec.Containers[0].Image == image {
+			continue
+		}
+		return podItem.DeepCopy(), nil
+	}
+	return nil, nil
+}
+
+// isUpgradePaused returns true if the user paused the upgrade in progress. Rollbacks
+// are never paused.
+func isUpgradePaused(p *pravegav1beta1.PravegaCluster) bool {
+	return p.Spec.PauseUpgrade && p.Status.IsClusterInUpgradingState()
+}
+
 //this function is to check are we doing a rollback in case of a upgrade failure while upgrading from a version below 07 to a version above 07
 func (r *ReconcilePravegaCluster) IsClusterRollbackingFrom07(p *pravegav1beta1.PravegaCluster) bool {
 	if util.IsVersionBelow07(p.Spec.Version) && r.IsAbove07STSPresent(p) {
@@ -475,6 +903,7 @@ func (r *ReconcilePravegaCluster) deleteExternalServices(p *pravegav1beta1.Prave
 
 //To handle upgrade/rollback from Pravega version < 0.7 to Pravega Version >= 0.7
 func (r *ReconcilePravegaCluster) syncSegmentStoreVersionTo07(p *pravegav1beta1.PravegaCluster) (synced bool, err error) {
+	r.setReconcilePhase(p, pravegav1beta1.ReconcilePhaseUpgradingSegmentStore)
 	p.Status.UpdateProgress(pravegav1beta1.UpdatingSegmentstoreReason, "0")
 	newsts := pravega.MakeSegmentStoreStatefulSet(p)
 	controllerutil.SetControllerReference(p, newsts, r.scheme)
@@ -536,7 +965,7 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersionTo07(p *pravegav1beta1.
 			return false, err
 		}
 		//checking if any of above pods have gone into error sate
-		_, err = r.checkUpdatedPods(pods, p.Status.TargetVersion)
+		_, err = r.checkUpdatedPods(pods, p.UpgradePodReadyTimeout())
 		if err != nil {
 			// Abort if there is any errors with the updated pods
 			return false, fmt.Errorf("updating statefulset (%s) failed due to %v", newsts.Name, err)
@@ -607,7 +1036,8 @@ func (r *ReconcilePravegaCluster) transitionToNewSTS(p *pravegav1beta1.PravegaCl
 		return fmt.Errorf("updating statefulset (%s) failed due to %v", oldsts.Name, err)
 	}
 	if r.IsClusterUpgradingTo07(p) {
-		err = r.syncStatefulSetPvc(oldsts)
+		// The cache claims are no longer used from version 07, whatever their reclaim policy
+		err = r.syncStatefulSetPvc(oldsts, false)
 		if err != nil {
 			return fmt.Errorf("updating statefulset (%s) failed due to %v", oldsts.Name, err)
 		}
@@ -625,17 +1055,25 @@ func (r *ReconcilePravegaCluster) transitionToNewSTS(p *pravegav1beta1.PravegaCl
 	return nil
 }
 
-func (r *ReconcilePravegaCluster) checkUpdatedPods(pods []*corev1.Pod, version string) (bool, error) {
+// checkUpdatedPods returns true if all the updated pods are ready. It returns an error if
+// one of them is faulty, or has not been ready for longer than the timeout
+func (r *ReconcilePravegaCluster) checkUpdatedPods(pods []*corev1.Pod, timeout time.Duration) (bool, error) {
+	ready := true
+	now := time.Now()
 	for _, pod := range pods {
-		if !util.IsPodReady(pod) {
-			// At least one updated pod is still not ready, check if it is faulty.
-			if faulty, err := util.IsPodFaulty(pod); faulty {
-				return false, err
-			}
-			return false, nil
+		if util.IsPodReady(pod) {
+			continue
+		}
+		ready = false
+		if faulty, err := util.IsPodFaulty(pod); faulty {
+			return false, err
+		}
+		if waited := util.PodNotReadyDuration(pod, now); waited > timeout {
+			return false, fmt.Errorf("pod %s not ready after waiting %v, the pod ready timeout is %v",
+				pod.Name, waited.Round(time.Second), timeout)
 		}
 	}
-	return true, nil
+	return ready, nil
 }
 
 func (r *ReconcilePravegaCluster) getOneOutdatedPod(sts *appsv1.StatefulSet, version string) (*corev1.Pod, error) {
@@ -669,6 +1107,46 @@ func (r *ReconcilePravegaCluster) getOneOutdatedPod(sts *appsv1.StatefulSet, ver
 	return nil, nil
 }
 
+// getOutdatedPods returns the pods of the stateful set which do not run the given
+// version and are not terminating yet, sorted by name, along with the number of
+// terminating pods that are still reported ready
+func (r *ReconcilePravegaCluster) getOutdatedPods(sts *appsv1.StatefulSet, version string) (outdated []*corev1.Pod, terminating int32, err error) {
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: sts.Spec.Template.Labels,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	podList := &corev1.PodList{}
+	podlistOps := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), podList, podlistOps)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(podList.Items, func(i int, j int) bool {
+		return podList.Items[i].Name < podList.Items[j].Name
+	})
+
+	for _, podItem := range podList.Items {
+		if podItem.DeletionTimestamp != nil {
+			if util.IsPodReady(&podItem) {
+				terminating++
+			}
+			continue
+		}
+		if util.GetPodVersion(&podItem) == version {
+			continue
+		}
+		outdated = append(outdated, podItem.DeepCopy())
+	}
+	return outdated, terminating, nil
+}
+
 func (r *ReconcilePravegaCluster) getStsPodsWithVersion(sts *appsv1.StatefulSet, version string) ([]*corev1.Pod, error) {
 	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
 		MatchLabels: sts.Spec.Template.Labels,
@@ -721,7 +1199,7 @@ func checkSyncTimeout(p *pravegav1beta1.PravegaCluster, reason string, updatedRe
 		// if reason and message are the same as before, which means there is no progress since the last reconciling,
 		// then check if it reaches the timeout.
 		parsedTime, _ := time.Parse(time.RFC3339, lastCondition.LastUpdateTime)
-		if time.Now().After(parsedTime.Add(time.Duration(10 * time.Minute))) {
+		if time.Now().After(parsedTime.Add(p.UpgradePodReadyTimeout())) {
 			// timeout
 			return fmt.Errorf("progress deadline exceeded")
 		}
@@ -12,10 +12,13 @@ package pravegacluster
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
 
 	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
@@ -25,6 +28,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -155,7 +159,7 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				})
 			})
 
-			Context("Upgrade Segmentstore", func() {
+			Context("Upgrade Controller", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 				)
@@ -168,14 +172,18 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set upgrade condition reason to UpgradingSegmentstoreReason and message to 0", func() {
+				It("should set upgrade condition reason to UpgradingControllerReason and message to 0", func() {
 					_, upgradeCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionUpgrading)
-					Ω(upgradeCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
+					Ω(upgradeCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
 					Ω(upgradeCondition.Message).Should(Equal("0"))
 				})
+
+				It("should set the reconcile phase to UpgradingController", func() {
+					Ω(foundPravega.Status.ReconcilePhase).Should(Equal(pravegav1beta1.ReconcilePhaseUpgradingController))
+				})
 			})
 
-			Context("Upgrade Controller", func() {
+			Context("Wait for the Controller before upgrading Segmentstore", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 					sts          *appsv1.StatefulSet
@@ -183,26 +191,66 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				BeforeEach(func() {
 					foundPravega = &v1beta1.PravegaCluster{}
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
-					// Segmentstore
-					sts = &appsv1.StatefulSet{}
-					name := p.StatefulSetNameForSegmentstore()
-					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
-					targetImage, _ := foundPravega.PravegaTargetImage()
-					sts.Spec.Template.Spec.Containers[0].Image = targetImage
-					r.client.Update(context.TODO(), sts)
+					// Controller
+					deploy := &appsv1.Deployment{}
+					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+					targetImage, _ := foundPravega.ControllerTargetImage()
+					deploy.Spec.Template.Spec.Containers[0].Image = targetImage
+					r.client.Update(context.TODO(), deploy)
+					// no pod is ready in the fake cluster
+					foundPravega.Status.SetPodsReadyConditionFalse()
+					client.Update(context.TODO(), foundPravega)
 
 					_, _ = r.Reconcile(req)
-					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
+					sts = &appsv1.StatefulSet{}
+					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+					foundPravega = &v1beta1.PravegaCluster{}
+					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				})
 
+				It("should set upgrade condition reason to WaitingForControllerReason", func() {
+					_, upgradeCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionUpgrading)
+					Ω(upgradeCondition.Status).Should(Equal(corev1.ConditionTrue))
+					Ω(upgradeCondition.Reason).Should(Equal(pravegav1beta1.WaitingForControllerReason))
+					Ω(upgradeCondition.Message).Should(Equal("waiting for controller before segmentstore upgrade"))
+				})
+
+				It("should not update the segment store template image", func() {
+					Ω(sts.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/pravega:0.5.0"))
+				})
+			})
+
+			Context("Upgrade Segmentstore", func() {
+				var (
+					foundPravega *v1beta1.PravegaCluster
+				)
+				BeforeEach(func() {
+					foundPravega = &v1beta1.PravegaCluster{}
+					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					// Controller
+					deploy := &appsv1.Deployment{}
+					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+					targetImage, _ := foundPravega.ControllerTargetImage()
+					deploy.Spec.Template.Spec.Containers[0].Image = targetImage
+					r.client.Update(context.TODO(), deploy)
+					// bypass the pods ready check in the upgrade logic
+					foundPravega.Status.SetPodsReadyConditionTrue()
+					client.Update(context.TODO(), foundPravega)
+
+					_, _ = r.Reconcile(req)
 					foundPravega = &v1beta1.PravegaCluster{}
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set upgrade condition reason to UpgradingControllerReason and message to 0", func() {
+				It("should set upgrade condition reason to UpgradingSegmentstoreReason and message to 0", func() {
 					_, upgradeCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionUpgrading)
-					Ω(upgradeCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
+					Ω(upgradeCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
 					Ω(upgradeCondition.Message).Should(Equal("0"))
 				})
+
+				It("should set the reconcile phase to UpgradingSegmentStore", func() {
+					Ω(foundPravega.Status.ReconcilePhase).Should(Equal(pravegav1beta1.ReconcilePhaseUpgradingSegmentStore))
+				})
 			})
 			Context("Upgrade Segmentstore to 0.7 from version below 0.7", func() {
 				var (
@@ -212,6 +260,12 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 
 					foundPravega = &v1beta1.PravegaCluster{}
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					// Controller
+					deploy := &appsv1.Deployment{}
+					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+					targetImage, _ := foundPravega.ControllerTargetImage()
+					deploy.Spec.Template.Spec.Containers[0].Image = targetImage
+					r.client.Update(context.TODO(), deploy)
 					foundPravega.Spec.Version = "0.7.0"
 					foundPravega.Spec.Pravega.SegmentStoreReplicas = 4
 					foundPravega.Status.SetPodsReadyConditionTrue()
@@ -278,6 +332,123 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				})
 			})
 		})
+		Context("Component image change", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+				err          error
+			)
+
+			BeforeEach(func() {
+				p.Spec = v1beta1.ClusterSpec{
+					Version: "0.5.0",
+				}
+				p.WithDefaults()
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreImage = &v1beta1.ImageSpec{Repository: "example/pravega-patched"}
+				err = r.syncComponentImages(foundPravega)
+			})
+
+			It("should update the segment store template image only", func() {
+				Ω(err).Should(BeNil())
+				sts := &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(sts.Spec.Template.Spec.Containers[0].Image).Should(Equal("example/pravega-patched:0.5.0"))
+				deploy := &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				Ω(deploy.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/pravega:0.5.0"))
+			})
+
+			It("should restart a pod running the previous image once the pods are ready", func() {
+				sts := &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				sts.Status.ReadyReplicas = *sts.Spec.Replicas
+				_ = client.Update(context.TODO(), sts)
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      sts.Name + "-0",
+						Namespace: p.Namespace,
+						Labels:    sts.Spec.Template.Labels,
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "pravega-segmentstore", Image: "pravega/pravega:0.5.0"}},
+					},
+				}
+				_ = client.Create(context.TODO(), pod)
+				err = r.syncComponentImages(foundPravega)
+				Ω(err).Should(BeNil())
+				err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, &corev1.Pod{})
+				Ω(err).ShouldNot(BeNil())
+			})
+
+			It("should restart a pod created from a previous revision once the pods are ready", func() {
+				sts := &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				sts.Status.ReadyReplicas = *sts.Spec.Replicas
+				sts.Status.UpdateRevision = "revision-2"
+				_ = client.Update(context.TODO(), sts)
+				pod := func(name string, revision string) *corev1.Pod {
+					labels := map[string]string{appsv1.ControllerRevisionHashLabelKey: revision}
+					for k, v := range sts.Spec.Template.Labels {
+						labels[k] = v
+					}
+					return &corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      name,
+							Namespace: p.Namespace,
+							Labels:    labels,
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "pravega-segmentstore", Image: "example/pravega-patched:0.5.0"}},
+						},
+					}
+				}
+				_ = client.Create(context.TODO(), pod(sts.Name+"-0", "revision-2"))
+				_ = client.Create(context.TODO(), pod(sts.Name+"-1", "revision-1"))
+				_ = client.Create(context.TODO(), pod(sts.Name+"-2", "revision-1"))
+				err = r.syncComponentImages(foundPravega)
+				Ω(err).Should(BeNil())
+				err = client.Get(context.TODO(), types.NamespacedName{Name: sts.Name + "-0", Namespace: p.Namespace}, &corev1.Pod{})
+				Ω(err).Should(BeNil())
+				err = client.Get(context.TODO(), types.NamespacedName{Name: sts.Name + "-1", Namespace: p.Namespace}, &corev1.Pod{})
+				Ω(err).ShouldNot(BeNil())
+				err = client.Get(context.TODO(), types.NamespacedName{Name: sts.Name + "-2", Namespace: p.Namespace}, &corev1.Pod{})
+				Ω(err).Should(BeNil())
+			})
+
+			It("should apply a pull policy change without restarting the segment store pods", func() {
+				sts := &appsv1.StatefulSet{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				sts.Status.ReadyReplicas = *sts.Spec.Replicas
+				_ = client.Update(context.TODO(), sts)
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      sts.Name + "-0",
+						Namespace: p.Namespace,
+						Labels:    sts.Spec.Template.Labels,
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "pravega-segmentstore", Image: "example/pravega-patched:0.5.0"}},
+					},
+				}
+				_ = client.Create(context.TODO(), pod)
+				foundPravega.Spec.Pravega.Image.PullPolicy = corev1.PullIfNotPresent
+				err = r.syncComponentImages(foundPravega)
+				Ω(err).Should(BeNil())
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.StatefulSetNameForSegmentstore(), Namespace: p.Namespace}, sts)
+				Ω(sts.Spec.Template.Spec.Containers[0].ImagePullPolicy).Should(Equal(corev1.PullIfNotPresent))
+				deploy := &appsv1.Deployment{}
+				_ = client.Get(context.TODO(), types.NamespacedName{Name: p.DeploymentNameForController(), Namespace: p.Namespace}, deploy)
+				Ω(deploy.Spec.Template.Spec.Containers[0].ImagePullPolicy).Should(Equal(corev1.PullIfNotPresent))
+				err = client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, &corev1.Pod{})
+				Ω(err).Should(BeNil())
+			})
+		})
+
 		Context("syncClusterVersion when cluster in upgrading state", func() {
 			var (
 				err          error
@@ -429,6 +600,7 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				foundPravega.Status.TargetVersion = "0.5.0"
 				sts.Status.UpdatedReplicas = 5
 				sts.Status.Replicas = 3
+				sts.Status.ReadyReplicas = 3
 				r.client.Update(context.TODO(), sts)
 				r.client.Update(context.TODO(), foundPravega)
 				_, err1 = r.syncSegmentStoreVersion(foundPravega)
@@ -441,6 +613,322 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 			})
 		})
 
+		Context("syncSegmentStoreVersion with the upgrade paused", func() {
+			var (
+				synced, resumedSynced bool
+				err, resumedErr       error
+				pausedCondition       *v1beta1.ClusterCondition
+				pausedPodsRemaining   int32
+				foundPravega          *v1beta1.PravegaCluster
+				client                client.Client
+			)
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				sts := pravega.MakeSegmentStoreStatefulSet(foundPravega)
+				r.client.Create(context.TODO(), sts)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: sts.Name, Namespace: foundPravega.Namespace}, sts)
+				sts.Status.Replicas = 3
+				sts.Status.ReadyReplicas = 3
+				sts.Status.UpdatedReplicas = 1
+				r.client.Update(context.TODO(), sts)
+				foundPravega.Status.TargetVersion = foundPravega.Spec.Version
+				foundPravega.Status.SetUpgradingConditionTrue(v1beta1.UpdatingSegmentstoreReason, "1")
+				foundPravega.Spec.PauseUpgrade = true
+				synced, err = r.syncSegmentStoreVersion(foundPravega)
+				_, pausedCondition = foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionUpgrading)
+				pausedCondition = pausedCondition.DeepCopy()
+				pausedPodsRemaining = foundPravega.Status.UpgradePodsRemaining
+				foundPravega.Spec.PauseUpgrade = false
+				resumedSynced, resumedErr = r.syncSegmentStoreVersion(foundPravega)
+			})
+			It("should not advance the upgrade while paused", func() {
+				Ω(err).Should(BeNil())
+				Ω(synced).Should(BeFalse())
+			})
+			It("should keep the upgrading condition true with the paused reason", func() {
+				Ω(pausedCondition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(pausedCondition.Reason).Should(Equal(v1beta1.UpgradePausedReason))
+				Ω(pausedCondition.Message).Should(Equal("1"))
+			})
+			It("should report the pods remaining", func() {
+				Ω(pausedPodsRemaining).Should(Equal(int32(2)))
+			})
+			It("should look for the next pod to upgrade once resumed", func() {
+				Ω(resumedSynced).Should(BeFalse())
+				Ω(resumedErr.Error()).Should(ContainSubstring("could not obtain outdated pod"))
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionUpgrading)
+				Ω(condition.Reason).Should(Equal(v1beta1.UpdatingSegmentstoreReason))
+			})
+		})
+		Context("syncSegmentStoreVersion with the OnDelete update strategy", func() {
+			var (
+				synced       bool
+				err, err1    error
+				foundPravega *v1beta1.PravegaCluster
+				client       client.Client
+				manualEvents []corev1.Event
+			)
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				foundPravega.Spec.Pravega.SegmentStoreUpdateStrategy = v1beta1.SegmentStoreUpdateStrategyOnDelete
+				sts := pravega.MakeSegmentStoreStatefulSet(foundPravega)
+				r.client.Create(context.TODO(), sts)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: sts.Name, Namespace: foundPravega.Namespace}, sts)
+				sts.Status.Replicas = 3
+				sts.Status.ReadyReplicas = 3
+				sts.Status.UpdatedReplicas = 1
+				r.client.Update(context.TODO(), sts)
+				foundPravega.Status.TargetVersion = foundPravega.Spec.Version
+				foundPravega.Status.SetUpgradingConditionTrue(v1beta1.UpdatingSegmentstoreReason, "1")
+				synced, err = r.syncSegmentStoreVersion(foundPravega)
+				_, err1 = r.syncSegmentStoreVersion(foundPravega)
+				events := &corev1.EventList{}
+				_ = client.List(context.TODO(), events)
+				manualEvents = nil
+				for _, event := range events.Items {
+					if event.Reason == v1beta1.ManualRestartRequiredReason {
+						manualEvents = append(manualEvents, event)
+					}
+				}
+			})
+			It("should wait for the user without looking for an outdated pod", func() {
+				Ω(err).Should(BeNil())
+				Ω(err1).Should(BeNil())
+				Ω(synced).Should(BeFalse())
+				Ω(foundPravega.Status.UpgradePodsRemaining).Should(Equal(int32(2)))
+			})
+			It("should set the upgrading condition with the manual restart reason", func() {
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionUpgrading)
+				Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(condition.Reason).Should(Equal(v1beta1.ManualRestartRequiredReason))
+				Ω(condition.Message).Should(Equal("1"))
+			})
+			It("should publish a single warning event", func() {
+				Ω(manualEvents).Should(HaveLen(1))
+				Ω(manualEvents[0].Type).Should(Equal("Warning"))
+			})
+		})
+		Context("syncSegmentStoreVersion with several pods upgraded at once", func() {
+			var (
+				client       client.Client
+				foundPravega *v1beta1.PravegaCluster
+			)
+			// upgrade creates four outdated segment store pods, of which the given number
+			// is ready, and returns how many of them were deleted by a single iteration
+			upgrade := func(ready int32) (int, error) {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				sts := pravega.MakeSegmentStoreStatefulSet(foundPravega)
+				r.client.Create(context.TODO(), sts)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: sts.Name, Namespace: foundPravega.Namespace}, sts)
+				sts.Status.Replicas = 4
+				sts.Status.ReadyReplicas = ready
+				r.client.Update(context.TODO(), sts)
+				for i := 0; i < 4; i++ {
+					pod := &corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        fmt.Sprintf("%s-%d", sts.Name, i),
+							Namespace:   foundPravega.Namespace,
+							Labels:      sts.Spec.Template.Labels,
+							Annotations: map[string]string{"pravega.version": "0.4.0"},
+						},
+					}
+					_ = client.Create(context.TODO(), pod)
+				}
+				foundPravega.Status.TargetVersion = foundPravega.Spec.Version
+				foundPravega.Status.SetUpgradingConditionTrue(v1beta1.UpdatingSegmentstoreReason, "0")
+				_, err := r.syncSegmentStoreVersion(foundPravega)
+				pods := &corev1.PodList{}
+				_ = client.List(context.TODO(), pods)
+				return 4 - len(pods.Items), err
+			}
+			BeforeEach(func() {
+				p.Spec.Pravega = &v1beta1.PravegaSpec{SegmentStoreReplicas: 4}
+			})
+			It("should upgrade one pod at a time by default", func() {
+				deleted, err := upgrade(4)
+				Ω(err).Should(BeNil())
+				Ω(deleted).Should(Equal(1))
+			})
+			It("should not exceed the disruption budget", func() {
+				maxUnavailable := int32(3)
+				p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{SegmentStoreMaxUnavailable: &maxUnavailable}
+				budget := intstr.FromInt(2)
+				p.Spec.Pravega.SegmentStorePdb = &v1beta1.SegmentStorePdbSpec{MaxUnavailable: &budget}
+				deleted, err := upgrade(4)
+				Ω(err).Should(BeNil())
+				Ω(deleted).Should(Equal(2))
+			})
+			It("should upgrade up to the maximum when the budget allows it", func() {
+				maxUnavailable := int32(3)
+				p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{SegmentStoreMaxUnavailable: &maxUnavailable}
+				p.Spec.Pravega.DisablePdb = true
+				deleted, err := upgrade(4)
+				Ω(err).Should(BeNil())
+				Ω(deleted).Should(Equal(3))
+			})
+			It("should count the pods that are not ready", func() {
+				maxUnavailable := int32(3)
+				p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{SegmentStoreMaxUnavailable: &maxUnavailable}
+				p.Spec.Pravega.DisablePdb = true
+				deleted, err := upgrade(2)
+				Ω(err).Should(BeNil())
+				Ω(deleted).Should(Equal(1))
+			})
+		})
+
+		Context("syncStuckTerminatingPods", func() {
+			var (
+				client client.Client
+				sts    *appsv1.StatefulSet
+				now    time.Time
+			)
+			// terminating creates a pod of the stateful set whose deletion grace period
+			// ended the given time ago
+			terminating := func(name string, ago time.Duration, controlled bool) {
+				deletion := metav1.NewTime(now.Add(-ago))
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              name,
+						Namespace:         p.Namespace,
+						Labels:            sts.Spec.Template.Labels,
+						DeletionTimestamp: &deletion,
+					},
+				}
+				if controlled {
+					pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(sts, appsv1.SchemeGroupVersion.WithKind("StatefulSet"))}
+				}
+				Ω(client.Create(context.TODO(), pod)).Should(Succeed())
+			}
+			podNames := func() []string {
+				pods := &corev1.PodList{}
+				_ = client.List(context.TODO(), pods)
+				names := []string{}
+				for _, pod := range pods.Items {
+					names = append(names, pod.Name)
+				}
+				return names
+			}
+			BeforeEach(func() {
+				now = time.Now()
+				p.UID = "cluster-uid"
+				p.WithDefaults()
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				sts = pravega.MakeSegmentStoreStatefulSet(p)
+				sts.UID = "sts-uid"
+				sts.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(p, v1beta1.SchemeGroupVersion.WithKind("PravegaCluster"))}
+				terminating(sts.Name+"-0", 10*time.Minute, true)
+				terminating(sts.Name+"-1", 10*time.Minute, false)
+				terminating(sts.Name+"-2", time.Minute, true)
+			})
+			AfterEach(func() {
+				config.ForceDeleteStuckPods = false
+			})
+			It("should report the pods terminating past the timeout without deleting them", func() {
+				Ω(r.syncStuckTerminatingPods(p, sts, now)).Should(Succeed())
+				Ω(p.Status.IsPodStuckTerminating()).Should(BeTrue())
+				_, condition := p.Status.GetClusterCondition(v1beta1.ClusterConditionError)
+				Ω(condition.Message).Should(Equal(fmt.Sprintf("pods %s-0, %s-1 terminating for longer than 5m0s", sts.Name, sts.Name)))
+				Ω(podNames()).Should(HaveLen(3))
+			})
+			It("should only force delete the stuck pods controlled by the stateful set", func() {
+				config.ForceDeleteStuckPods = true
+				Ω(r.syncStuckTerminatingPods(p, sts, now)).Should(Succeed())
+				Ω(p.Status.IsPodStuckTerminating()).Should(BeTrue())
+				Ω(podNames()).Should(ConsistOf(sts.Name+"-1", sts.Name+"-2"))
+			})
+			It("should not force delete the pods of a stateful set not controlled by the cluster", func() {
+				config.ForceDeleteStuckPods = true
+				sts.OwnerReferences = nil
+				Ω(r.syncStuckTerminatingPods(p, sts, now)).Should(Succeed())
+				Ω(podNames()).Should(HaveLen(3))
+			})
+			It("should follow the configured timeout", func() {
+				timeout := int32(1200)
+				p.Spec.UpgradeConfig = &v1beta1.UpgradeConfig{TerminatingPodTimeoutSeconds: &timeout}
+				Ω(r.syncStuckTerminatingPods(p, sts, now)).Should(Succeed())
+				Ω(p.Status.IsClusterInErrorState()).Should(BeFalse())
+			})
+			It("should clear the condition once no pod is stuck", func() {
+				Ω(r.syncStuckTerminatingPods(p, sts, now)).Should(Succeed())
+				Ω(r.syncStuckTerminatingPods(p, sts, now.Add(-10*time.Minute))).Should(Succeed())
+				Ω(p.Status.IsClusterInErrorState()).Should(BeFalse())
+			})
+			It("should not clear another error condition", func() {
+				p.Status.SetErrorConditionTrue(v1beta1.UpgradeFailedReason, "failed")
+				Ω(r.syncStuckTerminatingPods(p, sts, now.Add(-10*time.Minute))).Should(Succeed())
+				Ω(p.Status.IsClusterInUpgradeFailedState()).Should(BeTrue())
+			})
+		})
+
+		Context("syncControllerVersion with the upgrade paused", func() {
+			var (
+				err, resumedErr     error
+				pausedDeploy        *appsv1.Deployment
+				pausedCondition     *v1beta1.ClusterCondition
+				pausedPodsRemaining int32
+				foundPravega        *v1beta1.PravegaCluster
+				client              client.Client
+			)
+			BeforeEach(func() {
+				client = fake.NewFakeClient(p)
+				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				_, _ = r.Reconcile(req)
+				foundPravega = &v1beta1.PravegaCluster{}
+				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+				deploy := pravega.MakeControllerDeployment(foundPravega)
+				r.client.Create(context.TODO(), deploy)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: deploy.Name, Namespace: foundPravega.Namespace}, deploy)
+				deploy.Status.Replicas = 2
+				deploy.Status.ReadyReplicas = 2
+				deploy.Status.UpdatedReplicas = 1
+				r.client.Update(context.TODO(), deploy)
+				foundPravega.Status.TargetVersion = foundPravega.Spec.Version
+				foundPravega.Status.SetUpgradingConditionTrue(v1beta1.UpdatingControllerReason, "1")
+				foundPravega.Spec.PauseUpgrade = true
+				_, err = r.syncControllerVersion(foundPravega)
+				pausedDeploy = &appsv1.Deployment{}
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: deploy.Name, Namespace: foundPravega.Namespace}, pausedDeploy)
+				_, pausedCondition = foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionUpgrading)
+				pausedCondition = pausedCondition.DeepCopy()
+				pausedPodsRemaining = foundPravega.Status.UpgradePodsRemaining
+				foundPravega.Spec.PauseUpgrade = false
+				_, resumedErr = r.syncControllerVersion(foundPravega)
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: deploy.Name, Namespace: foundPravega.Namespace}, deploy)
+			})
+			It("should pause the deployment", func() {
+				Ω(err).Should(BeNil())
+				Ω(pausedDeploy.Spec.Paused).Should(BeTrue())
+			})
+			It("should keep the upgrading condition true with the paused reason", func() {
+				Ω(pausedCondition.Status).Should(Equal(corev1.ConditionTrue))
+				Ω(pausedCondition.Reason).Should(Equal(v1beta1.UpgradePausedReason))
+			})
+			It("should report the pods remaining", func() {
+				Ω(pausedPodsRemaining).Should(Equal(int32(1)))
+			})
+			It("should resume the deployment", func() {
+				Ω(resumedErr).Should(BeNil())
+				deploy := &appsv1.Deployment{}
+				_ = r.client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.DeploymentNameForController(), Namespace: foundPravega.Namespace}, deploy)
+				Ω(deploy.Spec.Paused).Should(BeFalse())
+				_, condition := foundPravega.Status.GetClusterCondition(v1beta1.ClusterConditionUpgrading)
+				Ω(condition.Reason).Should(Equal(v1beta1.UpdatingControllerReason))
+			})
+		})
+
 		Context("syncSegmentStoreVersionTo07 without old sts", func() {
 			var (
 				err          error
@@ -616,9 +1104,9 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				r.client.Get(context.TODO(), types.NamespacedName{Name: "test", Namespace: "default"}, testpod)
 				r.client.Get(context.TODO(), types.NamespacedName{Name: "test1", Namespace: "default"}, testpod1)
 				pod = append(pod, testpod)
-				result1, err = r.checkUpdatedPods(pod, "0.7.1")
+				result1, err = r.checkUpdatedPods(pod, 10*time.Minute)
 				pod1 = append(pod1, testpod1)
-				result2, err1 = r.checkUpdatedPods(pod1, "0.7.1")
+				result2, err1 = r.checkUpdatedPods(pod1, 10*time.Minute)
 			})
 			It("It should return false and non nil error", func() {
 				Ω(strings.ContainsAny(err.Error(), "failed because of CrashLoopBackOff")).Should(Equal(true))
@@ -629,6 +1117,35 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				Ω(result2).Should(Equal(false))
 			})
 		})
+
+		Context("checkUpdatedPods with a pod not ready for a while", func() {
+			var testpod *v1.Pod
+
+			BeforeEach(func() {
+				testpod = &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:         "default",
+						Name:              "test-segmentstore-0",
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-20 * time.Minute)),
+					},
+					Status: v1.PodStatus{
+						Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionFalse}},
+					},
+				}
+			})
+			It("should return an error naming the pod once the timeout is exceeded", func() {
+				ready, err := r.checkUpdatedPods([]*corev1.Pod{testpod}, 10*time.Minute)
+				Ω(ready).Should(Equal(false))
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).Should(ContainSubstring("pod test-segmentstore-0 not ready after waiting 20m"))
+				Ω(err.Error()).Should(ContainSubstring("the pod ready timeout is 10m0s"))
+			})
+			It("should keep waiting within the timeout", func() {
+				ready, err := r.checkUpdatedPods([]*corev1.Pod{testpod}, 30*time.Minute)
+				Ω(ready).Should(Equal(false))
+				Ω(err).Should(BeNil())
+			})
+		})
 	})
 	var _ = Describe("Rollback Test", func() {
 		var (
@@ -742,7 +1259,7 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				})
 			})
 
-			Context("Rollback Controller", func() {
+			Context("Rollback SegmentStore", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 				)
@@ -752,14 +1269,14 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set rollback condition reason to UpdatingController and message to 0", func() {
+				It("should set rollback condition reason to UpdatingSegmentStore and message to 0", func() {
 					_, rollbackCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionRollback)
-					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
+					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
 					Ω(rollbackCondition.Message).Should(Equal("0"))
 				})
 			})
 
-			Context("Rollback SegmentStore", func() {
+			Context("Rollback Controller", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 				)
@@ -770,9 +1287,9 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set rollback condition reason to UpdatingSegmentStore and message to 0", func() {
+				It("should set rollback condition reason to UpdatingController and message to 0", func() {
 					_, rollbackCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionRollback)
-					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
+					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
 					Ω(rollbackCondition.Message).Should(Equal("0"))
 				})
 			})
@@ -805,6 +1322,34 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					Ω(errorCondition.Status).To(Equal(corev1.ConditionFalse))
 				})
 			})
+
+			Context("Rollback with the ConfigMap of the failed upgrade", func() {
+				var (
+					err          error
+					foundPravega *v1beta1.PravegaCluster
+					foundCm      *corev1.ConfigMap
+				)
+				BeforeEach(func() {
+					foundPravega = &v1beta1.PravegaCluster{}
+					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+					foundPravega.WithDefaults()
+					upgradeCm := pravega.MakeControllerConfigMap(foundPravega)
+					upgradeCm.Data["JAVA_OPTS"] = "-Dcontroller.upgrade.only=true"
+					_ = client.Update(context.TODO(), upgradeCm)
+					err = r.rollbackClusterVersion(foundPravega, "0.5.0")
+					foundCm = &corev1.ConfigMap{}
+					_ = client.Get(context.TODO(), types.NamespacedName{Name: foundPravega.ConfigMapNameForController(), Namespace: Namespace}, foundCm)
+				})
+
+				It("should restore the ConfigMap of the rolled back version", func() {
+					Ω(err).Should(BeNil())
+					Ω(foundCm.Data).To(Equal(pravega.MakeControllerConfigMap(foundPravega).Data))
+				})
+				It("should keep the rollback condition until the ConfigMap is verified", func() {
+					_, rollbackCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionRollback)
+					Ω(rollbackCondition.Status).To(Equal(corev1.ConditionTrue))
+				})
+			})
 			Context("Rollback to version below 0.7 from above 0.7", func() {
 				var (
 					p1 *v1beta1.PravegaCluster
@@ -953,7 +1498,7 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 				})
 			})
 
-			Context("Rollback SegmentStore", func() {
+			Context("Rollback Controller", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 				)
@@ -963,14 +1508,14 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set rollback condition reason to UpdatingSegmentStore and message to 0", func() {
+				It("should set rollback condition reason to UpdatingController and message to 0", func() {
 					_, rollbackCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionRollback)
-					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
+					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
 					Ω(rollbackCondition.Message).Should(Equal("0"))
 				})
 			})
 
-			Context("Rollback Controller", func() {
+			Context("Rollback SegmentStore", func() {
 				var (
 					foundPravega *v1beta1.PravegaCluster
 				)
@@ -981,9 +1526,9 @@ var _ = Describe("Pravega Cluster Version Sync", func() {
 					_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
 				})
 
-				It("should set rollback condition reason to UpdatingController and message to 0", func() {
+				It("should set rollback condition reason to UpdatingSegmentStore and message to 0", func() {
 					_, rollbackCondition := foundPravega.Status.GetClusterCondition(pravegav1beta1.ClusterConditionRollback)
-					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingControllerReason))
+					Ω(rollbackCondition.Reason).Should(Equal(pravegav1beta1.UpdatingSegmentstoreReason))
 					Ω(rollbackCondition.Message).Should(Equal("0"))
 				})
 			})
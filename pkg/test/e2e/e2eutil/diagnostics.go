@@ -0,0 +1,163 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package e2eutil
+
+import (
+	goctx "context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	framework "github.com/operator-framework/operator-sdk/pkg/test"
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	// DiagnosticsLogLines is the number of trailing log lines dumped for each container
+	DiagnosticsLogLines int64 = 100
+	// DiagnosticsEvents is the number of most recent namespace events dumped
+	DiagnosticsEvents = 30
+	// OperatorLabels select the operator pods, which run in the namespace of the cluster
+	OperatorLabels = map[string]string{"name": "pravega-operator"}
+)
+
+// DumpClusterDiagnostics logs the status and conditions of the cluster, the state and
+// recent logs of its pods and of the operator pods, and the recent events of its
+// namespace. It is called by the waiters when they time out, and never fails: any error
+// collecting the diagnostics is logged instead.
+func DumpClusterDiagnostics(t *testing.T, f *framework.Framework, p *api.PravegaCluster) {
+	t.Logf("dumping diagnostics of pravega cluster: %s", p.Name)
+	dumpClusterStatus(t, f, p)
+	dumpPods(t, f, p.Namespace, p.LabelsForPravegaCluster())
+	dumpPods(t, f, p.Namespace, OperatorLabels)
+	dumpEvents(t, f, p.Namespace)
+	t.Logf("end of diagnostics of pravega cluster: %s", p.Name)
+}
+
+func dumpClusterStatus(t *testing.T, f *framework.Framework, p *api.PravegaCluster) {
+	cluster := &api.PravegaCluster{}
+	err := f.Client.Get(goctx.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: p.Name}, cluster)
+	if err != nil {
+		t.Logf("\tfailed to get pravega cluster %s: %v", p.Name, err)
+		return
+	}
+	status, err := json.MarshalIndent(cluster.Status, "\t", "  ")
+	if err != nil {
+		t.Logf("\tfailed to marshal the status of pravega cluster %s: %v", p.Name, err)
+	} else {
+		t.Logf("\tpravega cluster %s (generation %d) status:\n\t%s", p.Name, cluster.Generation, status)
+	}
+	for _, condition := range cluster.Status.Conditions {
+		t.Logf("\tcondition %s=%s, reason: %s, message: %s, last transition: %s",
+			condition.Type, condition.Status, condition.Reason, condition.Message, condition.LastTransitionTime)
+	}
+}
+
+func dumpPods(t *testing.T, f *framework.Framework, namespace string, podLabels map[string]string) {
+	selector := labels.SelectorFromSet(podLabels).String()
+	podList, err := f.KubeClient.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		t.Logf("\tfailed to list pods (%s): %v", selector, err)
+		return
+	}
+	if len(podList.Items) == 0 {
+		t.Logf("\tno pods (%s) in namespace %s", selector, namespace)
+		return
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		t.Logf("\tpod %s on node %s, phase: %s, deleting: %t", pod.Name, pod.Spec.NodeName, pod.Status.Phase, pod.DeletionTimestamp != nil)
+		for _, condition := range pod.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				t.Logf("\t\tcondition %s=%s, reason: %s, message: %s", condition.Type, condition.Status, condition.Reason, condition.Message)
+			}
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			t.Logf("\t\tcontainer %s, ready: %t, restarts: %d, state: %s", status.Name, status.Ready, status.RestartCount, containerState(status.State))
+			dumpContainerLogs(t, f, pod, status.Name, false)
+			if status.RestartCount > 0 {
+				dumpContainerLogs(t, f, pod, status.Name, true)
+			}
+		}
+	}
+}
+
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return "waiting (" + state.Waiting.Reason + ": " + state.Waiting.Message + ")"
+	case state.Terminated != nil:
+		return "terminated (" + state.Terminated.Reason + ": " + state.Terminated.Message + ")"
+	case state.Running != nil:
+		return "running"
+	default:
+		return "unknown"
+	}
+}
+
+func dumpContainerLogs(t *testing.T, f *framework.Framework, pod *corev1.Pod, container string, previous bool) {
+	lines := DiagnosticsLogLines
+	logs, err := f.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+		Previous:  previous,
+	}).Do().Raw()
+	instance := "current"
+	if previous {
+		instance = "previous"
+	}
+	if err != nil {
+		t.Logf("\t\tfailed to get the %s logs of container %s/%s: %v", instance, pod.Name, container, err)
+		return
+	}
+	t.Logf("\t\tlast %d lines of the %s logs of container %s/%s:\n\t\t%s", lines, instance, pod.Name, container,
+		strings.Replace(strings.TrimSpace(string(logs)), "\n", "\n\t\t", -1))
+}
+
+func dumpEvents(t *testing.T, f *framework.Framework, namespace string) {
+	eventList, err := f.KubeClient.CoreV1().Events(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Logf("\tfailed to list the events of namespace %s: %v", namespace, err)
+		return
+	}
+	events := eventList.Items
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(&events[i]).Time.Before(eventTime(&events[j]).Time)
+	})
+	if len(events) > DiagnosticsEvents {
+		events = events[len(events)-DiagnosticsEvents:]
+	}
+	t.Logf("\tlast %d events of namespace %s:", len(events), namespace)
+	for i := range events {
+		event := &events[i]
+		t.Logf("\t\t%s %s %s/%s %s: %s (x%d)", eventTime(event).Format("15:04:05"), event.Type,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message, event.Count)
+	}
+}
+
+// eventTime returns the last time the event occurred, which older event sources only
+// record in the first timestamp
+func eventTime(event *corev1.Event) metav1.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp
+	case !event.EventTime.IsZero():
+		return metav1.Time{Time: event.EventTime.Time}
+	default:
+		return event.FirstTimestamp
+	}
+}
@@ -12,6 +12,7 @@ package e2eutil
 
 import (
 	goctx "context"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -21,12 +22,16 @@ import (
 	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
 	"github.com/pravega/pravega-operator/pkg/util"
 	zkapi "github.com/pravega/zookeeper-operator/pkg/apis/zookeeper/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
 )
 
 var (
@@ -38,6 +43,13 @@ var (
 	UpgradeTimeout       = time.Minute * 10
 	TerminateTimeout     = time.Minute * 2
 	VerificationTimeout  = time.Minute * 5
+
+	// The operator fails an upgrade after 10 minutes without progress
+	UpgradeFailureTimeout = time.Minute * 15
+	RollbackTimeout       = time.Minute * 10
+
+	// The operator reports pods as failed after 10 minutes without being ready
+	PodsFailedTimeout = time.Minute * 15
 )
 
 func InitialSetup(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, namespace string) error {
@@ -116,6 +128,34 @@ func CreatePravegaCluster(t *testing.T, f *framework.Framework, ctx *framework.T
 	return pravega, nil
 }
 
+// CreateTLSSecret creates the secret holding a self-signed certificate for the cluster
+// returned by NewTlsEnabledCluster. The secret is deleted by the cleanup of the test
+// context, or by DeleteTLSSecret
+func CreateTLSSecret(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, namespace string) (*corev1.Secret, error) {
+	t.Logf("creating tls secret: %s", TLSSecretName)
+	secret, err := newTLSSecret(namespace)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Client.Create(goctx.TODO(), secret, &framework.CleanupOptions{TestContext: ctx, Timeout: CleanupTimeout, RetryInterval: CleanupRetryInterval})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls secret: %v", err)
+	}
+	t.Logf("created tls secret: %s", secret.Name)
+	return secret, nil
+}
+
+// DeleteTLSSecret deletes the secret created by CreateTLSSecret, if not already deleted
+func DeleteTLSSecret(t *testing.T, f *framework.Framework, secret *corev1.Secret) error {
+	t.Logf("deleting tls secret: %s", secret.Name)
+	err := f.Client.Delete(goctx.TODO(), secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete tls secret (%s): %v", secret.Name, err)
+	}
+	t.Logf("deleted tls secret: %s", secret.Name)
+	return nil
+}
+
 // CreateZKCluster creates a ZookeeperCluster CR with the desired spec
 func CreateZKCluster(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, z *zkapi.ZookeeperCluster) (*zkapi.ZookeeperCluster, error) {
 	t.Logf("creating zookeeper cluster: %s", z.Name)
@@ -208,6 +248,94 @@ func UpdatePravegaCluster(t *testing.T, f *framework.Framework, ctx *framework.T
 	return nil
 }
 
+// ScaleSegmentStore sets the segment store replicas of the cluster and waits until all its pods are ready
+func ScaleSegmentStore(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, size int32) error {
+	return scalePravegaCluster(t, f, ctx, p, func(cluster *api.PravegaCluster) {
+		cluster.Spec.Pravega.SegmentStoreReplicas = size
+	})
+}
+
+// ScaleController sets the controller replicas of the cluster and waits until all its pods are ready
+func ScaleController(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, size int32) error {
+	return scalePravegaCluster(t, f, ctx, p, func(cluster *api.PravegaCluster) {
+		cluster.Spec.Pravega.ControllerReplicas = size
+	})
+}
+
+// scalePravegaCluster applies scale to the latest PravegaCluster CR, fetching it again
+// if the update conflicts, and waits for the new number of pods to become ready
+func scalePravegaCluster(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, scale func(*api.PravegaCluster)) error {
+	var cluster *api.PravegaCluster
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() (err error) {
+		cluster, err = GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return err
+		}
+		scale(cluster)
+		t.Logf("scaling pravega cluster: %s (controllers: %d; segment stores: %d)",
+			cluster.Name, cluster.Spec.Pravega.ControllerReplicas, cluster.Spec.Pravega.SegmentStoreReplicas)
+		return f.Client.Update(goctx.TODO(), cluster)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update CR: %v", err)
+	}
+
+	size := cluster.Spec.Pravega.ControllerReplicas + cluster.Spec.Pravega.SegmentStoreReplicas
+	return WaitForPravegaClusterToBecomeReady(t, f, ctx, cluster, int(size))
+}
+
+// ScaleSegmentStoreSubresource sets the segment store replicas of the cluster through its
+// scale subresource, as kubectl scale does, and waits until all its pods are ready
+func ScaleSegmentStoreSubresource(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, size int32) error {
+	client, err := dynamic.NewForConfig(f.KubeConfig)
+	if err != nil {
+		return err
+	}
+	t.Logf("scaling pravega cluster through the scale subresource: %s (segment stores: %d)", p.Name, size)
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, size))
+	_, err = client.Resource(api.SchemeGroupVersion.WithResource("pravegaclusters")).Namespace(p.Namespace).
+		Patch(p.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+	if err != nil {
+		return fmt.Errorf("failed to patch the scale subresource: %v", err)
+	}
+
+	cluster, err := GetPravegaCluster(t, f, ctx, p)
+	if err != nil {
+		return err
+	}
+	if cluster.Spec.Pravega.SegmentStoreReplicas != size {
+		return fmt.Errorf("expected %d segment store replicas in the spec, got %d", size, cluster.Spec.Pravega.SegmentStoreReplicas)
+	}
+	return WaitForPravegaClusterToBecomeReady(t, f, ctx, cluster, int(cluster.Spec.Pravega.ControllerReplicas+size))
+}
+
+// WaitForSegmentStoreScaleStatus waits until the scale subresource of the cluster reports
+// the given number of segment store replicas along with their selector
+func WaitForSegmentStoreScaleStatus(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, size int64) error {
+	client, err := dynamic.NewForConfig(f.KubeConfig)
+	if err != nil {
+		return err
+	}
+	t.Logf("waiting for the scale subresource of pravega cluster %s to report %d replicas", p.Name, size)
+	err = wait.Poll(RetryInterval, ReadyTimeout, func() (done bool, err error) {
+		scale, err := client.Resource(api.SchemeGroupVersion.WithResource("pravegaclusters")).Namespace(p.Namespace).
+			Get(p.Name, metav1.GetOptions{}, "scale")
+		if err != nil {
+			return false, err
+		}
+		replicas, _, _ := unstructured.NestedInt64(scale.Object, "status", "replicas")
+		selector, _, _ := unstructured.NestedString(scale.Object, "status", "selector")
+		t.Logf("\tscale replicas: %d, selector: %q", replicas, selector)
+		return replicas == size && selector != "", nil
+	})
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return err
+	}
+	t.Logf("scale subresource of pravega cluster %s reports %d replicas", p.Name, size)
+	return nil
+}
+
 // GetPravegaCluster returns the latest PravegaCluster CR
 func GetPravegaCluster(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) (*api.PravegaCluster, error) {
 	pravega := &api.PravegaCluster{}
@@ -218,6 +346,17 @@ func GetPravegaCluster(t *testing.T, f *framework.Framework, ctx *framework.Test
 	return pravega, nil
 }
 
+// GetPravegaClusterCondition returns the condition of the given type from the latest
+// PravegaCluster CR, or nil if the status does not hold it
+func GetPravegaClusterCondition(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, conditionType api.ClusterConditionType) (*api.ClusterCondition, error) {
+	cluster, err := GetPravegaCluster(t, f, ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	_, condition := cluster.Status.GetClusterCondition(conditionType)
+	return condition, nil
+}
+
 // GetBKCluster returns the latest BookkeeperCluster CR
 func GetBKCluster(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, b *bkapi.BookkeeperCluster) (*bkapi.BookkeeperCluster, error) {
 	bookkeeper := &bkapi.BookkeeperCluster{}
@@ -249,15 +388,19 @@ func WaitForPravegaClusterToBecomeReady(t *testing.T, f *framework.Framework, ct
 		}
 
 		t.Logf("\twaiting for pods to become ready (%d/%d), pods (%v)", cluster.Status.ReadyReplicas, size, cluster.Status.Members.Ready)
+		if cluster.Status.IsLtsUnreachable() {
+			_, lts := cluster.Status.GetClusterCondition(api.ClusterConditionLtsReachable)
+			t.Logf("\tlong term storage unreachable: %s: %s", lts.Reason, lts.Message)
+		}
 
-		_, condition := cluster.Status.GetClusterCondition(api.ClusterConditionPodsReady)
-		if condition != nil && condition.Status == corev1.ConditionTrue && cluster.Status.ReadyReplicas == int32(size) {
+		if cluster.Status.IsClusterInReadyState() && cluster.Status.ReadyReplicas == int32(size) {
 			return true, nil
 		}
 		return false, nil
 	})
 
 	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
 		return err
 	}
 
@@ -265,6 +408,58 @@ func WaitForPravegaClusterToBecomeReady(t *testing.T, f *framework.Framework, ct
 	return nil
 }
 
+// WaitForSegmentStoreEndpoints will wait until the cluster status lists the external
+// endpoint of every segment store
+func WaitForSegmentStoreEndpoints(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, size int) (map[string]string, error) {
+	t.Logf("waiting for segment store endpoints: %s", p.Name)
+
+	var endpoints map[string]string
+	err := wait.Poll(RetryInterval, ReadyTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		endpoints = cluster.Status.SegmentStoreEndpoints
+		t.Logf("\twaiting for segment store endpoints (%d/%d), endpoints (%v)", len(endpoints), size, endpoints)
+		return len(endpoints) == size, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return nil, err
+	}
+
+	t.Logf("segment store endpoints listed: %s", p.Name)
+	return endpoints, nil
+}
+
+// WaitForControllerEndpoint will wait until the cluster status lists the external
+// endpoint of the controller
+func WaitForControllerEndpoint(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) (string, error) {
+	t.Logf("waiting for controller endpoint: %s", p.Name)
+
+	var endpoint string
+	err := wait.Poll(RetryInterval, ReadyTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		endpoint = cluster.Status.ControllerEndpoint
+		t.Logf("\twaiting for controller endpoint, endpoint (%s)", endpoint)
+		return endpoint != "", nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return "", err
+	}
+
+	t.Logf("controller endpoint listed: %s", p.Name)
+	return endpoint, nil
+}
+
 // WaitForBooClusterToBecomeReady will wait until all Bookkeeper cluster pods are ready
 func WaitForBookkeeperClusterToBecomeReady(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, b *bkapi.BookkeeperCluster, size int) error {
 	t.Logf("waiting for cluster pods to become ready: %s", b.Name)
@@ -276,7 +471,6 @@ func WaitForBookkeeperClusterToBecomeReady(t *testing.T, f *framework.Framework,
 		}
 
 		t.Logf("\twaiting for pods to become ready (%d/%d), pods (%v)", cluster.Status.ReadyReplicas, size, cluster.Status.Members.Ready)
-
 		_, condition := cluster.Status.GetClusterCondition(bkapi.ClusterConditionPodsReady)
 		if condition != nil && condition.Status == corev1.ConditionTrue && cluster.Status.ReadyReplicas == int32(size) {
 			return true, nil
@@ -303,7 +497,6 @@ func WaitForZookeeperClusterToBecomeReady(t *testing.T, f *framework.Framework,
 		}
 
 		t.Logf("\twaiting for pods to become ready (%d/%d), pods (%v)", cluster.Status.ReadyReplicas, size, cluster.Status.Members.Ready)
-
 		_, condition := cluster.Status.GetClusterCondition(zkapi.ClusterConditionPodsReady)
 		if condition != nil && condition.Status == corev1.ConditionTrue && cluster.Status.ReadyReplicas == int32(size) {
 			return true, nil
@@ -329,16 +522,15 @@ func WaitForPravegaClusterToUpgrade(t *testing.T, f *framework.Framework, ctx *f
 			return false, err
 		}
 
-		_, upgradeCondition := cluster.Status.GetClusterCondition(api.ClusterConditionUpgrading)
-		_, errorCondition := cluster.Status.GetClusterCondition(api.ClusterConditionError)
-
-		t.Logf("\twaiting for cluster to upgrade (upgrading: %s; error: %s)", upgradeCondition.Status, errorCondition.Status)
+		upgrading := cluster.Status.IsClusterInUpgradingState()
+		t.Logf("\twaiting for cluster to upgrade (upgrading: %t; error: %t)", upgrading, cluster.Status.IsClusterInErrorState())
 
-		if errorCondition.Status == corev1.ConditionTrue {
+		if cluster.Status.IsClusterInErrorState() {
+			_, errorCondition := cluster.Status.GetClusterCondition(api.ClusterConditionError)
 			return false, fmt.Errorf("failed upgrading cluster: [%s] %s", errorCondition.Reason, errorCondition.Message)
 		}
 
-		if upgradeCondition.Status == corev1.ConditionFalse && cluster.Status.CurrentVersion == targetVersion {
+		if !upgrading && cluster.Status.CurrentVersion == targetVersion {
 			// Cluster upgraded
 			return true, nil
 		}
@@ -346,6 +538,7 @@ func WaitForPravegaClusterToUpgrade(t *testing.T, f *framework.Framework, ctx *f
 	})
 
 	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
 		return err
 	}
 
@@ -353,6 +546,243 @@ func WaitForPravegaClusterToUpgrade(t *testing.T, f *framework.Framework, ctx *f
 	return nil
 }
 
+// WaitForPravegaClusterMembersToReachVersion waits until the status reports all the
+// members of the cluster with the image tag of the target version
+func WaitForPravegaClusterMembersToReachVersion(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, targetVersion string) error {
+	t.Logf("waiting for cluster members to reach version %s: %s", targetVersion, p.Name)
+
+	err := wait.Poll(RetryInterval, UpgradeTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		var outdated []string
+		for member, version := range cluster.Status.Members.Versions {
+			if version != targetVersion {
+				outdated = append(outdated, fmt.Sprintf("%s (%s)", member, version))
+			}
+		}
+		t.Logf("	waiting for members to reach version %s, outdated members (%v)", targetVersion, outdated)
+
+		if len(outdated) == 0 && len(cluster.Status.Members.Versions) == int(cluster.Status.Replicas) {
+			return true, nil
+		}
+		return false, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return err
+	}
+
+	t.Logf("pravega cluster members on version %s: %s", targetVersion, p.Name)
+	return nil
+}
+
+// WaitForPravegaClusterToFailUpgrade waits until the operator marks the upgrade of the cluster as failed
+func WaitForPravegaClusterToFailUpgrade(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
+	t.Logf("waiting for cluster upgrade to fail: %s", p.Name)
+
+	err := wait.Poll(RetryInterval, UpgradeFailureTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		failed := cluster.Status.IsClusterInUpgradeFailedState()
+		t.Logf("\twaiting for cluster upgrade to fail (upgrade failed: %t)", failed)
+		return failed, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return err
+	}
+
+	t.Logf("pravega cluster upgrade failed: %s", p.Name)
+	return nil
+}
+
+// WaitForPravegaClusterPodsToFail waits until the operator reports failed pods in the
+// cluster, and returns their names
+func WaitForPravegaClusterPodsToFail(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) ([]string, error) {
+	t.Logf("waiting for cluster pods to fail: %s", p.Name)
+
+	var failed []string
+	err := wait.Poll(RetryInterval, PodsFailedTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		failed = cluster.Status.Members.Failed
+		podsFailed := cluster.Status.IsClusterInPodsFailedState()
+		t.Logf("\twaiting for cluster pods to fail (failed: %t, members: %v)", podsFailed, failed)
+		return podsFailed, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return nil, err
+	}
+
+	t.Logf("pravega cluster pods failed: %s", p.Name)
+	return failed, nil
+}
+
+// WaitForPravegaClusterScalingToStall waits until the operator reports the scaling of the
+// cluster as stalled, and returns the condition
+func WaitForPravegaClusterScalingToStall(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) (*api.ClusterCondition, error) {
+	t.Logf("waiting for cluster scaling to stall: %s", p.Name)
+
+	var condition *api.ClusterCondition
+	err := wait.Poll(RetryInterval, ReadyTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		t.Logf("	waiting for cluster scaling to stall (ready: %d/%d, since: %s)",
+			cluster.Status.ReadyReplicas, cluster.Status.Replicas, cluster.Status.LastReadinessChangeTime)
+		if !cluster.Status.IsClusterScalingStalled() {
+			return false, nil
+		}
+		_, condition = cluster.Status.GetClusterCondition(api.ClusterConditionScalingStalled)
+		return true, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return nil, err
+	}
+
+	t.Logf("pravega cluster scaling stalled: %s: %s", condition.Reason, condition.Message)
+	return condition, nil
+}
+
+// SetPodFinalizers replaces the finalizers of the pod, e.g. to keep it terminating once
+// deleted. Nil finalizers remove them all.
+func SetPodFinalizers(t *testing.T, f *framework.Framework, namespace, name string, finalizers []string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"finalizers": finalizers},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.KubeClient.CoreV1().Pods(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return fmt.Errorf("failed to set the finalizers of pod %s: %v", name, err)
+	}
+	t.Logf("set the finalizers of pod %s to %v", name, finalizers)
+	return nil
+}
+
+// WaitForPravegaClusterPodStuckTerminating waits until the error condition reports a pod
+// stuck terminating during the upgrade, and returns the condition
+func WaitForPravegaClusterPodStuckTerminating(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) (*api.ClusterCondition, error) {
+	t.Logf("waiting for cluster to report a pod stuck terminating: %s", p.Name)
+
+	var condition *api.ClusterCondition
+	err := wait.Poll(RetryInterval, UpgradeTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		t.Logf("\twaiting for cluster to report a pod stuck terminating (upgrading: %t)", cluster.Status.IsClusterInUpgradingState())
+		if cluster.Status.IsClusterInUpgradeFailedOrRollbackState() {
+			return false, fmt.Errorf("cluster upgrade failed before reporting a pod stuck terminating")
+		}
+		if !cluster.Status.IsPodStuckTerminating() {
+			return false, nil
+		}
+		_, condition = cluster.Status.GetClusterCondition(api.ClusterConditionError)
+		return true, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return nil, err
+	}
+
+	t.Logf("pravega cluster reported a pod stuck terminating: %s", condition.Message)
+	return condition, nil
+}
+
+// WaitForPravegaClusterPodStuckTerminatingToClear waits until the error condition no longer
+// reports a pod stuck terminating
+func WaitForPravegaClusterPodStuckTerminatingToClear(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
+	t.Logf("waiting for cluster to clear the pod stuck terminating: %s", p.Name)
+
+	err := wait.Poll(RetryInterval, ReadyTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+		return !cluster.Status.IsPodStuckTerminating(), nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return err
+	}
+
+	t.Logf("pravega cluster cleared the pod stuck terminating: %s", p.Name)
+	return nil
+}
+
+// WaitForPravegaClusterToRollback waits until the cluster is rolled back to the given version
+func WaitForPravegaClusterToRollback(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, version string) error {
+	t.Logf("waiting for cluster to rollback: %s", p.Name)
+
+	err := wait.Poll(RetryInterval, RollbackTimeout, func() (done bool, err error) {
+		cluster, err := GetPravegaCluster(t, f, ctx, p)
+		if err != nil {
+			return false, err
+		}
+
+		// The rollback condition is only added once the operator starts the rollback
+		_, rollbackCondition := cluster.Status.GetClusterCondition(api.ClusterConditionRollback)
+		if rollbackCondition == nil {
+			return false, nil
+		}
+
+		rollingBack := cluster.Status.IsClusterInRollbackState()
+		t.Logf("\twaiting for cluster to rollback (rollback: %t; error: %t)", rollingBack, cluster.Status.IsClusterInErrorState())
+
+		// The error condition stays true with the UpgradeFailed reason while the rollback runs
+		if cluster.Status.IsClusterInRollbackFailedState() {
+			_, errorCondition := cluster.Status.GetClusterCondition(api.ClusterConditionError)
+			return false, fmt.Errorf("failed rolling back cluster: [%s] %s", errorCondition.Reason, errorCondition.Message)
+		}
+
+		if !rollingBack && cluster.Status.CurrentVersion == version {
+			// Cluster rolled back
+			return true, nil
+		}
+		return false, nil
+	})
+
+	if err != nil {
+		DumpClusterDiagnostics(t, f, p)
+		return err
+	}
+
+	t.Logf("pravega cluster rolled back: %s", p.Name)
+	return nil
+}
+
+// GetConfigMap returns the ConfigMap with the given name in the namespace of the cluster
+func GetConfigMap(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, name string) (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+	err := f.Client.Get(goctx.TODO(), types.NamespacedName{Namespace: p.Namespace, Name: name}, configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain configmap (%s): %v", name, err)
+	}
+	return configMap, nil
+}
+
 func WaitForCMPravegaClusterToUpgrade(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
 	t.Logf("waiting for cluster to upgrade post cm changes: %s", p.Name)
 
@@ -461,6 +891,41 @@ func WaitForPravegaClusterToTerminate(t *testing.T, f *framework.Framework, ctx
 	return nil
 }
 
+// WaitForPravegaClusterServicesToTerminate will wait until all the services of the pravega
+// cluster are deleted, including its external services
+func WaitForPravegaClusterServicesToTerminate(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
+	t.Logf("waiting for pravega cluster services to terminate: %s", p.Name)
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(p.LabelsForPravegaCluster()).String(),
+	}
+
+	err := wait.Poll(RetryInterval, TerminateTimeout, func() (done bool, err error) {
+		serviceList, err := f.KubeClient.CoreV1().Services(p.Namespace).List(listOptions)
+		if err != nil {
+			return false, err
+		}
+
+		var names []string
+		for i := range serviceList.Items {
+			svc := &serviceList.Items[i]
+			names = append(names, svc.Name)
+		}
+		t.Logf("waiting for services to terminate (%v)", names)
+		if len(names) != 0 {
+			return false, nil
+		}
+		return true, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	t.Logf("pravega cluster services terminated: %s", p.Name)
+	return nil
+}
+
 // WaitForZKClusterToTerminate will wait until all zookeeper cluster pods are terminated
 func WaitForZKClusterToTerminate(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, z *zkapi.ZookeeperCluster) error {
 	t.Logf("waiting for zookeeper cluster to terminate: %s", z.Name)
@@ -579,10 +1044,38 @@ func WaitForBKClusterToTerminate(t *testing.T, f *framework.Framework, ctx *fram
 	return nil
 }
 
-// WriteAndReadData writes sample data and reads it back from the given Pravega cluster
+// WriteAndReadData writes sample data and reads it back from the given Pravega cluster,
+// over TLS if its controller is secured
 func WriteAndReadData(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
 	t.Logf("writing and reading data from pravega cluster: %s", p.Name)
 	testJob := NewTestWriteReadJob(p.Namespace, p.ServiceNameForController())
+	if p.Spec.TLS.IsSecureController() {
+		testJob = NewTestWriteReadJobWithTLS(p.Namespace, p.ServiceNameForController(), p.Spec.TLS.Static.ControllerSecret)
+	}
+	return runTestWriteReadJob(t, f, ctx, p, testJob)
+}
+
+// WriteAndReadDataExternal writes sample data and reads it back from the given Pravega
+// cluster, through the external endpoint of its controller listed in the cluster status
+func WriteAndReadDataExternal(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster) error {
+	cluster, err := GetPravegaCluster(t, f, ctx, p)
+	if err != nil {
+		return err
+	}
+	if cluster.Spec.ExternalAccess == nil || !cluster.Spec.ExternalAccess.Enabled {
+		return fmt.Errorf("external access is not enabled on pravega cluster %s", p.Name)
+	}
+	endpoint := cluster.Status.ControllerEndpoint
+	if endpoint == "" {
+		return fmt.Errorf("the controller external endpoint of pravega cluster %s is not provisioned yet", p.Name)
+	}
+	t.Logf("writing and reading data from pravega cluster: %s, through %s", p.Name, endpoint)
+	testJob := NewTestWriteReadJobForEndpoint(p.Namespace, endpoint)
+	return runTestWriteReadJob(t, f, ctx, p, testJob)
+}
+
+// runTestWriteReadJob runs a job writing and reading data, and waits for it to succeed
+func runTestWriteReadJob(t *testing.T, f *framework.Framework, ctx *framework.TestCtx, p *api.PravegaCluster, testJob *batchv1.Job) error {
 	err := f.Client.Create(goctx.TODO(), testJob, &framework.CleanupOptions{TestContext: ctx, Timeout: CleanupTimeout, RetryInterval: CleanupRetryInterval})
 	if err != nil {
 		return fmt.Errorf("failed to create job: %s", err)
@@ -607,6 +1100,18 @@ func WriteAndReadData(t *testing.T, f *framework.Framework, ctx *framework.TestC
 	}
 
 	t.Logf("pravega cluster validated: %s", p.Name)
+	return DeleteTestJob(t, f, testJob)
+}
+
+// DeleteTestJob deletes a verification Job and its pods, if not already deleted
+// once finished
+func DeleteTestJob(t *testing.T, f *framework.Framework, job *batchv1.Job) error {
+	t.Logf("deleting job: %s", job.Name)
+	propagation := metav1.DeletePropagationBackground
+	err := f.KubeClient.BatchV1().Jobs(job.Namespace).Delete(job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete job (%s): %v", job.Name, err)
+	}
 	return nil
 }
 
@@ -11,7 +11,14 @@
 package e2eutil
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
@@ -37,6 +44,104 @@ func NewDefaultCluster(namespace string) *api.PravegaCluster {
 	}
 }
 
+// TLSSecretName is the name of the secret holding the self-signed certificate of the
+// TLS-enabled test clusters
+const TLSSecretName = "pravega-tls"
+
+// The keys of the certificate, the private key and the CA certificate in the TLS secret
+const (
+	tlsCertificateKey   = "tls.crt"
+	tlsPrivateKeyKey    = "tls.key"
+	tlsCaCertificateKey = "ca.crt"
+)
+
+// tlsMountDir is where the test jobs mount the TLS secret
+const tlsMountDir = "/etc/pravega-tls"
+
+// NewTlsEnabledCluster returns a cluster whose Controller and Segment Store serve TLS
+// with the certificate of the secret created by CreateTLSSecret
+func NewTlsEnabledCluster(namespace string) *api.PravegaCluster {
+	cluster := NewDefaultCluster(namespace)
+	keys := &api.TLSSecretKeys{
+		Certificate:   tlsCertificateKey,
+		PrivateKey:    tlsPrivateKeyKey,
+		CaCertificate: tlsCaCertificateKey,
+	}
+	cluster.Spec.TLS = &api.TLSPolicy{
+		Static: &api.StaticTLS{
+			ControllerSecret:   TLSSecretName,
+			ControllerKeys:     keys,
+			SegmentStoreSecret: TLSSecretName,
+			SegmentStoreKeys:   keys.DeepCopy(),
+		},
+	}
+	cluster.Spec.Pravega = &api.PravegaSpec{
+		Options: map[string]string{
+			"controller.security.tls.enable":                   "true",
+			"pravegaservice.security.tls.enable":               "true",
+			"autoScale.controller.connect.security.tls.enable": "true",
+		},
+	}
+	return cluster
+}
+
+// newTLSSecret returns a secret holding a self-signed certificate, valid for the
+// Controller service and the Segment Store pods of the default cluster, which is its
+// own CA certificate
+func newTLSSecret(namespace string) (*corev1.Secret, error) {
+	cluster := NewDefaultCluster(namespace)
+	controller := cluster.ServiceNameForController()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: controller},
+		DNSNames: []string{
+			controller,
+			fmt.Sprintf("%s.%s", controller, namespace),
+			fmt.Sprintf("%s.%s.svc", controller, namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", controller, namespace),
+			fmt.Sprintf("*.%s.%s.svc.cluster.local", cluster.HeadlessServiceNameForSegmentStore(), namespace),
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %v", err)
+	}
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TLSSecretName,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			tlsCertificateKey:   certPEM,
+			tlsPrivateKeyKey:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey}),
+			tlsCaCertificateKey: certPEM,
+		},
+	}, nil
+}
+
 func NewClusterWithVersion(namespace, version string) *api.PravegaCluster {
 	cluster := NewDefaultCluster(namespace)
 	cluster.Spec = api.ClusterSpec{
@@ -79,13 +184,62 @@ func newTestJob(namespace string, command string) *batchv1.Job {
 	}
 }
 
-// NewTestWriteReadJob returns a Job that can test pravega cluster by running a sample
+// TestJobTTLSecondsAfterFinished is the time after which the finished verification
+// Jobs are deleted, along with their pods
+const TestJobTTLSecondsAfterFinished = int32(300)
+
+// NewTestWriteReadJob returns a Job that can test pravega cluster by running a sample.
+// The Job is deleted TestJobTTLSecondsAfterFinished after it finishes.
 func NewTestWriteReadJob(namespace string, controllerUri string) *batchv1.Job {
+	return NewTestWriteReadJobForEndpoint(namespace, controllerUri+":9090")
+}
+
+// NewTestWriteReadJobForEndpoint returns a job writing and reading data through the
+// controller at the given host:port, e.g. its external endpoint
+func NewTestWriteReadJobForEndpoint(namespace string, controllerEndpoint string) *batchv1.Job {
 	command := fmt.Sprintf("cd /samples/pravega-client-examples "+
-		"&& bin/helloWorldWriter -u tcp://%s:9090 "+
-		"&& bin/helloWorldReader -u tcp://%s:9090",
-		controllerUri, controllerUri)
-	return newTestJob(namespace, command)
+		"&& bin/helloWorldWriter -u tcp://%s "+
+		"&& bin/helloWorldReader -u tcp://%s",
+		controllerEndpoint, controllerEndpoint)
+	job := newTestJob(namespace, command)
+	ttl := TestJobTTLSecondsAfterFinished
+	job.Spec.TTLSecondsAfterFinished = &ttl
+	return job
+}
+
+// NewTestWriteReadJobWithTLS returns a job writing and reading data through the
+// TLS-enabled controller, trusting the CA certificate of the given TLS secret
+func NewTestWriteReadJobWithTLS(namespace string, controllerUri string, secret string) *batchv1.Job {
+	endpoint := controllerUri + ":9090"
+	truststore := "/tmp/truststore.jks"
+	command := fmt.Sprintf("keytool -importcert -noprompt -alias pravega -file %s/%s -keystore %s -storepass changeit "+
+		"&& export JAVA_OPTS=\"-Djavax.net.ssl.trustStore=%s -Djavax.net.ssl.trustStorePassword=changeit\" "+
+		"&& cd /samples/pravega-client-examples "+
+		"&& bin/helloWorldWriter -u tls://%s "+
+		"&& bin/helloWorldReader -u tls://%s",
+		tlsMountDir, tlsCaCertificateKey, truststore, truststore, endpoint, endpoint)
+	job := newTestJob(namespace, command)
+	ttl := TestJobTTLSecondsAfterFinished
+	job.Spec.TTLSecondsAfterFinished = &ttl
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = []corev1.Volume{
+		{
+			Name: "tls-secret",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secret,
+				},
+			},
+		},
+	}
+	podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+		{
+			Name:      "tls-secret",
+			MountPath: tlsMountDir,
+			ReadOnly:  true,
+		},
+	}
+	return job
 }
 
 func NewTier2(namespace string) *corev1.PersistentVolumeClaim {
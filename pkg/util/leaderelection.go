@@ -0,0 +1,141 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package util
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// The defaults of the lease timings, which are the ones of controller-runtime
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderElectionOptions configures the Lease based leader election of the operator
+// replicas. When Lease is false, the operator uses the leader-for-life election of
+// the operator-sdk, which holds a ConfigMap until the leader pod is deleted.
+type LeaderElectionOptions struct {
+	// Lease enables the Lease based leader election
+	Lease bool
+	// LeaseDuration is how long the other replicas wait before taking over a lease
+	// which is not renewed
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing its lease before giving up
+	RenewDeadline time.Duration
+	// RetryPeriod is the delay between two attempts to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// AddFlags registers the leader election flags on the flag set
+func (o *LeaderElectionOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Lease, "leader-election-lease", false, "Enable the Lease based leader election, instead of the leader-for-life ConfigMap lock. Requires permissions on leases in the operator namespace.")
+	fs.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", DefaultLeaseDuration, "Duration the other operator replicas wait before taking over a lease which is not renewed.")
+	fs.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", DefaultRenewDeadline, "Duration the leader retries renewing its lease before giving up the leadership.")
+	fs.DurationVar(&o.RetryPeriod, "leader-election-retry-period", DefaultRetryPeriod, "Delay between two attempts to acquire or renew the lease.")
+}
+
+// Validate returns an error if the lease timings are rejected by the leader election,
+// i.e. if they are not positive, or if the lease duration is not greater than the
+// renew deadline, or if the renew deadline is not greater than the jittered retry period
+func (o *LeaderElectionOptions) Validate() error {
+	if o.LeaseDuration <= 0 || o.RenewDeadline <= 0 || o.RetryPeriod <= 0 {
+		return fmt.Errorf("the leader election lease duration, renew deadline and retry period must be positive")
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		return fmt.Errorf("the leader election lease duration (%v) must be greater than the renew deadline (%v)", o.LeaseDuration, o.RenewDeadline)
+	}
+	if o.RenewDeadline <= time.Duration(leaderelection.JitterFactor*float64(o.RetryPeriod)) {
+		return fmt.Errorf("the leader election renew deadline (%v) must be greater than %v times the retry period (%v)", o.RenewDeadline, leaderelection.JitterFactor, o.RetryPeriod)
+	}
+	return nil
+}
+
+// RunAsLeader calls run once the operator holds the Lease with the given name in the
+// namespace, and returns its error. The stop channel handed to run is closed when stop
+// is, or once run returns. If the lease is lost, RunAsLeader returns an error without
+// waiting for run, so that the operator exits and no longer reconciles the clusters.
+func RunAsLeader(cfg *rest.Config, namespace, name string, o LeaderElectionOptions, stop <-chan struct{}, run func(stop <-chan struct{}) error) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: hostname + "_" + string(uuid.NewUUID()),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	started, done := make(chan struct{}), make(chan error, 1)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: o.LeaseDuration,
+		RenewDeadline: o.RenewDeadline,
+		RetryPeriod:   o.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				log.Printf("Acquired the leader election lease %s/%s", namespace, name)
+				close(started)
+				done <- run(leaderCtx.Done())
+				cancel()
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Waiting for the leader election lease %s/%s", namespace, name)
+	elector.Run(ctx)
+	select {
+	case <-started:
+	default:
+		// stopped before acquiring the lease
+		return nil
+	}
+	if ctx.Err() == nil {
+		return fmt.Errorf("lost the leader election lease %s/%s", namespace, name)
+	}
+	return <-done
+}
@@ -0,0 +1,76 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	"flag"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("leader election", func() {
+
+	parse := func(args ...string) (LeaderElectionOptions, error) {
+		var o LeaderElectionOptions
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.AddFlags(fs)
+		err := fs.Parse(args)
+		return o, err
+	}
+
+	Context("AddFlags", func() {
+		It("should default to the leader-for-life election and the controller-runtime timings", func() {
+			o, err := parse()
+			Ω(err).Should(BeNil())
+			Ω(o).To(Equal(LeaderElectionOptions{
+				Lease:         false,
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			}))
+			Ω(o.Validate()).Should(BeNil())
+		})
+		It("should parse the flags into the options", func() {
+			o, err := parse("-leader-election-lease",
+				"-leader-election-lease-duration=30s",
+				"-leader-election-renew-deadline=20s",
+				"-leader-election-retry-period=5s")
+			Ω(err).Should(BeNil())
+			Ω(o).To(Equal(LeaderElectionOptions{
+				Lease:         true,
+				LeaseDuration: 30 * time.Second,
+				RenewDeadline: 20 * time.Second,
+				RetryPeriod:   5 * time.Second,
+			}))
+			Ω(o.Validate()).Should(BeNil())
+		})
+		It("should reject an invalid duration", func() {
+			_, err := parse("-leader-election-lease-duration=15")
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Validate", func() {
+		It("should reject a lease duration not greater than the renew deadline", func() {
+			o := LeaderElectionOptions{Lease: true, LeaseDuration: 10 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 2 * time.Second}
+			Ω(o.Validate()).Should(MatchError(ContainSubstring("must be greater than the renew deadline")))
+		})
+		It("should reject a renew deadline not greater than the jittered retry period", func() {
+			o := LeaderElectionOptions{Lease: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second, RetryPeriod: 9 * time.Second}
+			Ω(o.Validate()).Should(MatchError(ContainSubstring("times the retry period")))
+		})
+		It("should reject a non positive retry period", func() {
+			o := LeaderElectionOptions{Lease: true, LeaseDuration: 15 * time.Second, RenewDeadline: 10 * time.Second}
+			Ω(o.Validate()).Should(MatchError(ContainSubstring("must be positive")))
+		})
+	})
+})
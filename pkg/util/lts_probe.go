@@ -0,0 +1,188 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ltsProbeTimeout = 10 * time.Second
+
+	// defaultBucketRegion is the region used to sign the requests when none is configured,
+	// which S3 compatible object stores such as MinIO and ECS accept
+	defaultBucketRegion = "us-east-1"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+// BucketProbe describes how to reach the bucket of an S3 compatible object store
+type BucketProbe struct {
+	// Endpoint is the http(s) URL of the object store. Its query is ignored
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Namespace is the ECS namespace of the bucket, if any
+	Namespace string
+	// AccessKey and SecretKey sign the request. It is sent anonymously if they are empty,
+	// e.g. when the segment stores assume an IAM role, and only the reachability of the
+	// object store is checked then
+	AccessKey string
+	SecretKey string
+	// SessionToken is the token of temporary credentials, if any
+	SessionToken string
+	// CaBundle holds PEM encoded certificates trusted along with the system ones
+	CaBundle []byte
+}
+
+// ProbeBucket sends a HEAD request for the bucket, signed with the AWS signature version 4,
+// and returns an error describing why the bucket cannot be used. The keys never appear in
+// the error.
+func ProbeBucket(probe BucketProbe) error {
+	endpoint, err := url.Parse(probe.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", probe.Endpoint)
+	}
+	// The query, e.g. of an ECS config URI, may hold credentials
+	endpoint.RawQuery, endpoint.User = "", nil
+	target := *endpoint
+	target.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + probe.Bucket
+
+	req, err := http.NewRequest(http.MethodHead, target.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for bucket %s: %v", probe.Bucket, err)
+	}
+	if probe.Namespace != "" {
+		req.Header.Set("x-emc-namespace", probe.Namespace)
+	}
+	if probe.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", probe.SessionToken)
+	}
+	if probe.AccessKey != "" {
+		region := probe.Region
+		if region == "" {
+			region = defaultBucketRegion
+		}
+		signV4(req, region, probe.AccessKey, probe.SecretKey, time.Now())
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(probe.CaBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(probe.CaBundle) {
+			return fmt.Errorf("no valid certificate in the CA bundle of %s", endpoint.Host)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	httpClient := &http.Client{Timeout: ltsProbeTimeout, Transport: transport}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", endpoint.String(), err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized:
+		if probe.AccessKey == "" {
+			return nil
+		}
+		return fmt.Errorf("access to bucket %s at %s is denied, check the credentials", probe.Bucket, endpoint.String())
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("bucket %s not found at %s", probe.Bucket, endpoint.String())
+	default:
+		return fmt.Errorf("unexpected status %s for bucket %s at %s", resp.Status, probe.Bucket, endpoint.String())
+	}
+}
+
+// ProbeAddress opens, and closes, a TCP connection to the host and port of the URL,
+// e.g. to the HDFS name node
+func ProbeAddress(rawURL string, defaultPort string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid uri %q", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+	conn, err := net.DialTimeout("tcp", address, ltsProbeTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", address, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// signV4 signs the request, which has no body, with the AWS signature version 4 of the s3
+// service. All the headers set on the request are signed, along with the host.
+func signV4(req *http.Request, region, accessKey, secretKey string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", emptyPayloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request", stringToSign} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
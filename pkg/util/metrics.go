@@ -0,0 +1,56 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package util
+
+import (
+	"flag"
+	"fmt"
+)
+
+// DefaultMetricsBindAddress is the address of the operator metrics endpoint when none
+// is configured, which is the controller-runtime one
+const DefaultMetricsBindAddress = ":8080"
+
+// disabledMetricsBindAddress is the bind address from which the manager does not serve
+// any metrics endpoint
+const disabledMetricsBindAddress = "0"
+
+// MetricsOptions configures the endpoint serving the metrics of the operator process
+// itself, e.g. of its controllers and work queues, not the ones of the Pravega clusters
+type MetricsOptions struct {
+	// Enabled enables serving the metrics endpoint
+	Enabled bool
+	// BindAddress is the address the metrics endpoint listens on
+	BindAddress string
+}
+
+// AddFlags registers the flags of the metrics options
+func (o *MetricsOptions) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "enable-metrics", true, "Enable the endpoint serving the metrics of the operator.")
+	fs.StringVar(&o.BindAddress, "metrics-bind-address", DefaultMetricsBindAddress, "Address the endpoint serving the metrics of the operator listens on, e.g. :8080.")
+}
+
+// Validate returns an error if the metrics are enabled without a bind address
+func (o *MetricsOptions) Validate() error {
+	if o.Enabled && (o.BindAddress == "" || o.BindAddress == disabledMetricsBindAddress) {
+		return fmt.Errorf("the metrics bind address must be set when the metrics are enabled, use -enable-metrics=false to disable them")
+	}
+	return nil
+}
+
+// ManagerBindAddress returns the metrics bind address of the manager options, which
+// disables the metrics endpoint if the metrics are not enabled
+func (o *MetricsOptions) ManagerBindAddress() string {
+	if !o.Enabled {
+		return disabledMetricsBindAddress
+	}
+	return o.BindAddress
+}
@@ -0,0 +1,63 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("operator metrics", func() {
+
+	parse := func(args ...string) (MetricsOptions, error) {
+		var o MetricsOptions
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.AddFlags(fs)
+		err := fs.Parse(args)
+		return o, err
+	}
+
+	Context("AddFlags", func() {
+		It("should default to serving the metrics on the controller-runtime address", func() {
+			o, err := parse()
+			Ω(err).Should(BeNil())
+			Ω(o).To(Equal(MetricsOptions{Enabled: true, BindAddress: ":8080"}))
+			Ω(o.Validate()).Should(BeNil())
+			Ω(o.ManagerBindAddress()).To(Equal(":8080"))
+		})
+		It("should parse the bind address", func() {
+			o, err := parse("--metrics-bind-address=127.0.0.1:9090")
+			Ω(err).Should(BeNil())
+			Ω(o).To(Equal(MetricsOptions{Enabled: true, BindAddress: "127.0.0.1:9090"}))
+			Ω(o.Validate()).Should(BeNil())
+			Ω(o.ManagerBindAddress()).To(Equal("127.0.0.1:9090"))
+		})
+		It("should disable the metrics endpoint", func() {
+			o, err := parse("--enable-metrics=false", "--metrics-bind-address=:9090")
+			Ω(err).Should(BeNil())
+			Ω(o.Enabled).To(BeFalse())
+			Ω(o.Validate()).Should(BeNil())
+			Ω(o.ManagerBindAddress()).To(Equal("0"))
+		})
+		It("should reject an invalid boolean", func() {
+			_, err := parse("--enable-metrics=maybe")
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Validate", func() {
+		It("should reject enabled metrics without a bind address", func() {
+			o := MetricsOptions{Enabled: true}
+			Ω(o.Validate()).Should(MatchError(ContainSubstring("must be set when the metrics are enabled")))
+		})
+	})
+})
@@ -11,11 +11,15 @@
 package util
 
 import (
+	"bufio"
 	"fmt"
+	"math"
+	"net/http"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	v "github.com/hashicorp/go-version"
 	corev1 "k8s.io/api/core/v1"
@@ -29,6 +33,15 @@ var (
 
 const (
 	MajorMinorVersionRegexp string = `^v?(?P<Version>[0-9]+\.[0-9]+\.[0-9]+)`
+
+	// PrometheusMetricsPath is the REST path of the Prometheus endpoint of the
+	// Pravega components, served when Prometheus metrics are enabled
+	PrometheusMetricsPath = "/prometheus"
+
+	// SegmentStoreRESTPort is the port of the segment store REST server
+	SegmentStoreRESTPort = 6061
+
+	metricsRequestTimeout = 5 * time.Second
 )
 
 func init() {
@@ -125,6 +138,22 @@ func GetPodVersion(pod *v1.Pod) string {
 	return pod.GetAnnotations()["pravega.version"]
 }
 
+// GetPodImageTag returns the tag of the image of the Pravega container of the pod, or
+// its version annotation if the image is not tagged, e.g. pinned by digest
+func GetPodImageTag(pod *v1.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return GetPodVersion(pod)
+	}
+	image := pod.Spec.Containers[0].Image
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return GetPodVersion(pod)
+}
+
 func CompareVersions(v1, v2, operator string) (bool, error) {
 	normv1, err := NormalizeVersion(v1)
 	if err != nil {
@@ -170,41 +199,57 @@ type OrderedMap struct {
 	keys []string
 }
 
+// jvmValueOptions are the JVM options whose value directly follows their name
+var jvmValueOptions = []string{"-Xms", "-Xmx", "-Xmn", "-Xss"}
+
+// set stores the value of the key, keeping the position of an existing key
+func (om *OrderedMap) set(key, value string) {
+	if _, ok := om.m[key]; !ok {
+		om.keys = append(om.keys, key)
+	}
+	om.m[key] = value
+}
+
 // This method will parse the JVM options into a key value pair and store it
 // in the OrderedMap
 func UpdateOneJVMOption(arg string, om *OrderedMap) {
-	// Parse "-Xms"
-	if strings.HasPrefix(arg, "-Xms") {
-		if _, ok := om.m["-Xms"]; !ok {
-			om.keys = append(om.keys, "-Xms")
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return
+	}
+
+	// Parse "-Xms", "-Xmx", "-Xmn" and "-Xss"
+	for _, name := range jvmValueOptions {
+		if strings.HasPrefix(arg, name) {
+			om.set(name, arg[len(name):])
+			return
+		}
+	}
+
+	// Parse system properties, the value keeps its "=" as a property may have none
+	if strings.HasPrefix(arg, "-D") {
+		if i := strings.Index(arg, "="); i > 0 {
+			om.set(arg[:i], arg[i:])
+			return
 		}
-		om.m["-Xms"] = arg[4:]
+		om.set(arg, "")
 		return
 	}
 
 	// Parse option starting with "-XX"
-	if strings.HasPrefix(arg, "-XX:") {
+	if strings.HasPrefix(arg, "-XX:") && len(arg) > 5 {
 		if arg[4] == '+' || arg[4] == '-' {
-			if _, ok := om.m[arg[5:]]; !ok {
-				om.keys = append(om.keys, arg[5:])
-			}
-			om.m[arg[5:]] = string(arg[4])
+			om.set(arg[5:], string(arg[4]))
 			return
 		}
-		s := strings.Split(arg[4:], "=")
-		if _, ok := om.m[s[0]]; !ok {
-			om.keys = append(om.keys, s[0])
+		if s := strings.SplitN(arg[4:], "=", 2); len(s) == 2 {
+			om.set(s[0], s[1])
+			return
 		}
-		om.m[s[0]] = s[1]
-		return
 	}
 
 	// Not in those formats, just keep the option as a key
-	if _, ok := om.m[arg]; !ok {
-		om.keys = append(om.keys, arg)
-	}
-	om.m[arg] = ""
-	return
+	om.set(arg, "")
 }
 
 // Concatenate the key value pair to be a JVM option string.
@@ -213,8 +258,9 @@ func GenerateJVMOption(k, v string) string {
 		return k
 	}
 
-	if k == "-Xms" {
-		return fmt.Sprintf("%v%v", k, v)
+	// "-Xms", "-Xmx", "-Xmn", "-Xss" and system properties
+	if strings.HasPrefix(k, "-") {
+		return k + v
 	}
 
 	if v == "+" || v == "-" {
@@ -224,6 +270,57 @@ func GenerateJVMOption(k, v string) string {
 	return fmt.Sprintf("-XX:%v=%v", k, v)
 }
 
+// GetJVMMemoryOption returns the size in bytes of the last JVM option having the given
+// prefix, e.g. "-Xmx" or "-XX:MaxDirectMemorySize=", as the JVM uses the last occurrence.
+// The returned boolean is false when the option is not set.
+func GetJVMMemoryOption(jvmOpts []string, prefix string) (int64, bool, error) {
+	value := ""
+	found := false
+	for _, option := range jvmOpts {
+		if strings.HasPrefix(option, prefix) {
+			value = option[len(prefix):]
+			found = true
+		}
+	}
+	if !found {
+		return 0, false, nil
+	}
+	size, err := ParseJVMMemorySize(value)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to parse JVM option %s%s: %v", prefix, value, err)
+	}
+	return size, true, nil
+}
+
+// ParseJVMMemorySize converts a JVM memory size, e.g. "512m" or "4G", to bytes
+func ParseJVMMemorySize(size string) (int64, error) {
+	matches := jvmMemorySizeRegexp.FindStringSubmatch(size)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid memory size %q", size)
+	}
+	value, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	multiplier := int64(1)
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	case "t":
+		multiplier = 1 << 40
+	}
+	if value > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("memory size %q is too large", size)
+	}
+	return value * multiplier, nil
+}
+
+var jvmMemorySizeRegexp = regexp.MustCompile(`^([0-9]+)([kKmMgGtT]?)$`)
+
 // This method will override the default JVM options with user provided custom options
 func OverrideDefaultJVMOptions(defaultOpts []string, customOpts []string) []string {
 
@@ -256,6 +353,76 @@ func OverrideDefaultJVMOptions(defaultOpts []string, customOpts []string) []stri
 	return jvmOpts
 }
 
+// GetPrometheusCounter scrapes the Prometheus endpoint at baseURL and returns the sum
+// of the samples of the given metric, over all its label sets.
+func GetPrometheusCounter(baseURL string, metric string) (float64, error) {
+	httpClient := &http.Client{Timeout: metricsRequestTimeout}
+	resp, err := httpClient.Get(strings.TrimSuffix(baseURL, "/") + PrometheusMetricsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to get metrics: unexpected status %s", resp.Status)
+	}
+	sum := 0.0
+	found := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// A sample is "name{labels} value [timestamp]", label values may hold spaces
+		name, rest := line, ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name, rest = line[:i], line[i:]
+		}
+		if name != metric {
+			continue
+		}
+		if strings.HasPrefix(rest, "{") {
+			rest = rest[strings.LastIndex(rest, "}")+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("failed to parse sample of metric %s: %s", metric, line)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse sample of metric %s: %v", metric, err)
+		}
+		sum += value
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read metrics: %v", err)
+	}
+	if !found {
+		return 0, fmt.Errorf("metric %s not found", metric)
+	}
+	return sum, nil
+}
+
+// FitsOnAnyNode checks whether a pod with the given resource requests fits in
+// the allocatable resources of at least one of the nodes
+func FitsOnAnyNode(requests corev1.ResourceList, nodes []corev1.Node) bool {
+	for _, node := range nodes {
+		fits := true
+		for name, quantity := range requests {
+			allocatable, ok := node.Status.Allocatable[name]
+			if !ok || allocatable.Cmp(quantity) < 0 {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return true
+		}
+	}
+	return false
+}
+
 func IsPodReady(pod *corev1.Pod) bool {
 	for _, condition := range pod.Status.Conditions {
 		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
@@ -265,7 +432,37 @@ func IsPodReady(pod *corev1.Pod) bool {
 	return false
 }
 
+// IsPodFailed returns true if the pod has not been ready for longer than the threshold,
+// e.g. a pod in CrashLoopBackOff or a pod that cannot be scheduled. Terminating pods are
+// not failed.
+func IsPodFailed(pod *corev1.Pod, threshold time.Duration, now time.Time) bool {
+	return PodNotReadyDuration(pod, now) > threshold
+}
+
+// PodNotReadyDuration returns how long the pod has not been ready, since its last ready
+// transition or its creation. It is zero for ready and terminating pods, and for pods
+// without a creation time.
+func PodNotReadyDuration(pod *corev1.Pod, now time.Time) time.Duration {
+	if pod.DeletionTimestamp != nil || IsPodReady(pod) {
+		return 0
+	}
+	notReadySince := pod.CreationTimestamp.Time
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && !condition.LastTransitionTime.IsZero() {
+			notReadySince = condition.LastTransitionTime.Time
+		}
+	}
+	if notReadySince.IsZero() {
+		return 0
+	}
+	return now.Sub(notReadySince)
+}
+
 func IsPodFaulty(pod *corev1.Pod) (bool, error) {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		// The pod has not started yet, e.g. it waits to be scheduled
+		return false, nil
+	}
 	if pod.Status.ContainerStatuses[0].State.Waiting != nil && (pod.Status.ContainerStatuses[0].State.Waiting.Reason == "ImagePullBackOff" ||
 		pod.Status.ContainerStatuses[0].State.Waiting.Reason == "CrashLoopBackOff") {
 		return true, fmt.Errorf("pod %s update failed because of %s", pod.Name, pod.Status.ContainerStatuses[0].State.Waiting.Reason)
@@ -324,3 +521,75 @@ func PodAntiAffinity(component string, clusterName string) *corev1.Affinity {
 		},
 	}
 }
+
+// MergeAffinity merges the affinity set by the user with the one generated by the
+// operator, without modifying them. The node affinity and the pod affinity are the ones
+// of the user, as the operator only generates pod anti-affinity. The pod anti-affinity
+// holds the required terms of the user, then the preferred terms of the operator
+// followed by the ones of the user. A term of the user takes precedence over a
+// preferred term of the operator with the same label selector and topology key, which
+// is dropped, e.g. to make the spread across nodes required or to change its weight.
+func MergeAffinity(operator *corev1.Affinity, user *corev1.Affinity) *corev1.Affinity {
+	if user == nil {
+		return operator.DeepCopy()
+	}
+	if operator == nil {
+		return user.DeepCopy()
+	}
+	merged := user.DeepCopy()
+	if operator.PodAntiAffinity == nil {
+		return merged
+	}
+	if merged.PodAntiAffinity == nil {
+		merged.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	userTerms := merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	for _, weighted := range merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		userTerms = append(userTerms, weighted.PodAffinityTerm)
+	}
+	var preferred []corev1.WeightedPodAffinityTerm
+	for _, weighted := range operator.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if !sameAffinityTarget(weighted.PodAffinityTerm, userTerms) {
+			preferred = append(preferred, *weighted.DeepCopy())
+		}
+	}
+	merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(preferred,
+		merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution...)
+	for _, term := range operator.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if !sameAffinityTarget(term, merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) {
+			merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+				merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, *term.DeepCopy())
+		}
+	}
+	return merged
+}
+
+// sameAffinityTarget reports whether one of the terms selects the same pods, over the same
+// topology, as the given term
+func sameAffinityTarget(term corev1.PodAffinityTerm, terms []corev1.PodAffinityTerm) bool {
+	for _, other := range terms {
+		if term.TopologyKey == other.TopologyKey &&
+			reflect.DeepEqual(term.LabelSelector, other.LabelSelector) &&
+			reflect.DeepEqual(term.Namespaces, other.Namespaces) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWatchNamespaces splits the comma-separated namespaces watched by the operator,
+// ignoring the blanks and the duplicates. It returns nil, i.e. all the namespaces, if
+// none is set.
+func ParseWatchNamespaces(value string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, namespace := range strings.Split(value, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
@@ -10,12 +10,17 @@
 package util
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -117,6 +122,67 @@ var _ = Describe("pravegacluster", func() {
 		})
 
 	})
+	Context("OverrideDefaultJVMOptions with duplicate options", func() {
+		var result []string
+		BeforeEach(func() {
+			jvmOpts := []string{
+				"-Xms512m",
+				"-XX:+UseContainerSupport",
+				"-Dpravegaservice.clusterName=default",
+				"-Dmetrics.enable=false",
+			}
+			customOpts := []string{
+				"-Xmx1g",
+				"-Dmetrics.enable=true",
+				"-XX:-UseContainerSupport",
+				"-Xmx2g",
+				"-XX:+PrintFlagsFinal",
+				"-XX:MaxRAMPercentage=75.0",
+				"-XX:NotAnOption",
+				"-Dflag",
+				" ",
+			}
+			result = OverrideDefaultJVMOptions(jvmOpts, customOpts)
+		})
+		It("should keep the last value of each option", func() {
+			Ω(result).To(Equal([]string{
+				"-Xms512m",
+				"-XX:-UseContainerSupport",
+				"-Dpravegaservice.clusterName=default",
+				"-Dmetrics.enable=true",
+				"-Xmx2g",
+				"-XX:+PrintFlagsFinal",
+				"-XX:MaxRAMPercentage=75.0",
+				"-XX:NotAnOption",
+				"-Dflag",
+			}))
+		})
+	})
+	Context("GetJVMMemoryOption", func() {
+		jvmOpts := []string{"-Xms1g", "-Xmx2g", "-XX:MaxDirectMemorySize=512m", "-Xmx4G"}
+		It("should return the last occurrence in bytes", func() {
+			size, found, err := GetJVMMemoryOption(jvmOpts, "-Xmx")
+			Ω(err).Should(BeNil())
+			Ω(found).Should(Equal(true))
+			Ω(size).Should(Equal(int64(4 << 30)))
+		})
+		It("should parse the direct memory size", func() {
+			size, found, err := GetJVMMemoryOption(jvmOpts, "-XX:MaxDirectMemorySize=")
+			Ω(err).Should(BeNil())
+			Ω(found).Should(Equal(true))
+			Ω(size).Should(Equal(int64(512 << 20)))
+		})
+		It("should report an unset option", func() {
+			_, found, err := GetJVMMemoryOption([]string{"-Xms1g"}, "-Xmx")
+			Ω(err).Should(BeNil())
+			Ω(found).Should(Equal(false))
+		})
+		It("should fail on an invalid size", func() {
+			_, found, err := GetJVMMemoryOption([]string{"-Xmx2gb"}, "-Xmx")
+			Ω(found).Should(Equal(true))
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
 	Context("RemoveString", func() {
 		var opts []string
 		BeforeEach(func() {
@@ -159,6 +225,65 @@ var _ = Describe("pravegacluster", func() {
 		})
 
 	})
+	Context("MergeAffinity", func() {
+		var (
+			operator *corev1.Affinity
+			zone     *corev1.NodeAffinity
+		)
+		BeforeEach(func() {
+			operator = PodAntiAffinity("segstore", "pravega")
+			zone = &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      "topology.kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"us-east-1a"},
+						}},
+					}},
+				},
+			}
+		})
+		It("should return the operator affinity without user affinity", func() {
+			Ω(MergeAffinity(operator, nil)).To(Equal(operator))
+		})
+		It("should keep both the operator anti-affinity and the user node affinity", func() {
+			merged := MergeAffinity(operator, &corev1.Affinity{NodeAffinity: zone})
+			Ω(merged.NodeAffinity).To(Equal(zone))
+			Ω(merged.PodAntiAffinity).To(Equal(operator.PodAntiAffinity))
+		})
+		It("should append the user anti-affinity terms to the operator ones", func() {
+			term := corev1.WeightedPodAffinityTerm{
+				Weight: 50,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bookkeeper"}},
+					TopologyKey:   "kubernetes.io/hostname",
+				},
+			}
+			merged := MergeAffinity(operator, &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{term},
+			}})
+			Ω(merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(Equal([]corev1.WeightedPodAffinityTerm{
+				operator.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0], term,
+			}))
+		})
+		It("should not duplicate the operator term held by the user affinity", func() {
+			Ω(MergeAffinity(operator, PodAntiAffinity("segstore", "pravega"))).To(Equal(operator))
+		})
+		It("should let a user term override the operator term of the same target", func() {
+			required := operator.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+			merged := MergeAffinity(operator, &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{required},
+			}})
+			Ω(merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal([]corev1.PodAffinityTerm{required}))
+			Ω(merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+		})
+		It("should not modify its arguments", func() {
+			user := &corev1.Affinity{NodeAffinity: zone}
+			MergeAffinity(operator, user)
+			Ω(user.PodAntiAffinity).To(BeNil())
+		})
+	})
 
 	Context("DownwardAPIEnv()", func() {
 
@@ -259,6 +384,53 @@ var _ = Describe("pravegacluster", func() {
 
 		})
 	})
+	Context("podFailed", func() {
+		var (
+			now     time.Time
+			testpod *v1.Pod
+		)
+		BeforeEach(func() {
+			now = time.Now()
+			testpod = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+				Status: v1.PodStatus{
+					Conditions: []v1.PodCondition{
+						{
+							Type:               v1.PodReady,
+							Status:             v1.ConditionFalse,
+							LastTransitionTime: metav1.NewTime(now.Add(-20 * time.Minute)),
+						},
+					}},
+			}
+		})
+		It("pod failed should be true once unready for longer than the threshold", func() {
+			Ω(IsPodFailed(testpod, 10*time.Minute, now)).To(Equal(true))
+		})
+		It("pod failed should be false within the threshold", func() {
+			testpod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-5 * time.Minute))
+			Ω(IsPodFailed(testpod, 10*time.Minute, now)).To(Equal(false))
+		})
+		It("pod failed should use the creation time without ready condition", func() {
+			testpod.Status.Conditions = nil
+			Ω(IsPodFailed(testpod, 10*time.Minute, now)).To(Equal(true))
+		})
+		It("pod failed should be false for a ready pod", func() {
+			testpod.Status.Conditions[0].Status = v1.ConditionTrue
+			Ω(IsPodFailed(testpod, 10*time.Minute, now)).To(Equal(false))
+		})
+		It("pod failed should be false for a terminating pod", func() {
+			deletion := metav1.NewTime(now)
+			testpod.DeletionTimestamp = &deletion
+			Ω(IsPodFailed(testpod, 10*time.Minute, now)).To(Equal(false))
+		})
+		It("pod not ready duration should be measured from the last ready transition", func() {
+			Ω(PodNotReadyDuration(testpod, now)).To(Equal(20 * time.Minute))
+		})
+		It("pod not ready duration should be zero without any known time", func() {
+			testpod.CreationTimestamp = metav1.Time{}
+			testpod.Status.Conditions = nil
+			Ω(PodNotReadyDuration(testpod, now)).To(Equal(time.Duration(0)))
+		})
+	})
 	Context("CompareConfigMap", func() {
 		var output1, output2 bool
 		BeforeEach(func() {
@@ -315,4 +487,131 @@ var _ = Describe("pravegacluster", func() {
 			Ω(out).To(Equal("0.7.0"))
 		})
 	})
+
+	Context("GetPodImageTag", func() {
+		var testpod *v1.Pod
+		BeforeEach(func() {
+			testpod = &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Annotations: map[string]string{"pravega.version": "0.7.0"}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "pravega"}}},
+			}
+		})
+		It("should return the image tag", func() {
+			testpod.Spec.Containers[0].Image = "registry.example.com:5000/pravega/pravega:0.9.0"
+			Ω(GetPodImageTag(testpod)).To(Equal("0.9.0"))
+		})
+		It("should ignore the digest", func() {
+			testpod.Spec.Containers[0].Image = "pravega/pravega:0.9.0@sha256:abcdef"
+			Ω(GetPodImageTag(testpod)).To(Equal("0.9.0"))
+		})
+		It("should fall back to the version annotation without a tag", func() {
+			testpod.Spec.Containers[0].Image = "registry.example.com:5000/pravega/pravega@sha256:abcdef"
+			Ω(GetPodImageTag(testpod)).To(Equal("0.7.0"))
+		})
+	})
+
+	Context("FitsOnAnyNode", func() {
+		var fits, tooBig, noNodes bool
+		BeforeEach(func() {
+			nodes := []v1.Node{
+				{
+					Status: v1.NodeStatus{
+						Allocatable: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("2"),
+							v1.ResourceMemory: resource.MustParse("4Gi"),
+						},
+					},
+				},
+				{
+					Status: v1.NodeStatus{
+						Allocatable: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("8"),
+							v1.ResourceMemory: resource.MustParse("2Gi"),
+						},
+					},
+				},
+			}
+			fits = FitsOnAnyNode(v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1500m"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			}, nodes)
+			tooBig = FitsOnAnyNode(v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			}, nodes)
+			noNodes = FitsOnAnyNode(v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("1"),
+			}, []v1.Node{})
+		})
+		It("should fit requests within one node allocatable", func() {
+			Ω(fits).To(Equal(true))
+		})
+		It("should not fit requests spread across nodes", func() {
+			Ω(tooBig).To(Equal(false))
+		})
+		It("should not fit without nodes", func() {
+			Ω(noNodes).To(Equal(false))
+		})
+	})
+
+	Context("GetPrometheusCounter", func() {
+		var (
+			value  float64
+			err    error
+			server *httptest.Server
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != PrometheusMetricsPath {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, `# HELP pravega_segmentstore_segment_write_bytes_total Bytes written
+# TYPE pravega_segmentstore_segment_write_bytes_total counter
+pravega_segmentstore_segment_write_bytes_total{segment="scope/stream 1/0"} 1024.0
+pravega_segmentstore_segment_write_bytes_total{segment="scope/stream/1"} 2048 1577836800000
+pravega_segmentstore_segment_write_bytes_total_created 1577836800
+pravega_segmentstore_segment_read_bytes_total 4096
+`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("metric present", func() {
+			BeforeEach(func() {
+				value, err = GetPrometheusCounter(server.URL, "pravega_segmentstore_segment_write_bytes_total")
+			})
+			It("should sum the samples of every label set", func() {
+				Ω(err).Should(BeNil())
+				Ω(value).To(Equal(3072.0))
+			})
+		})
+
+		Context("metric missing", func() {
+			BeforeEach(func() {
+				value, err = GetPrometheusCounter(server.URL, "pravega_segmentstore_segment_write_events_total")
+			})
+			It("should return error", func() {
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(ContainSubstring("not found"))
+			})
+		})
+	})
+
+	Context("ParseWatchNamespaces", func() {
+		It("should watch all the namespaces if none is set", func() {
+			Ω(ParseWatchNamespaces("")).To(BeNil())
+			Ω(ParseWatchNamespaces(" , ")).To(BeNil())
+		})
+		It("should watch a single namespace", func() {
+			Ω(ParseWatchNamespaces("team-a")).To(Equal([]string{"team-a"}))
+		})
+		It("should split a comma-separated list", func() {
+			Ω(ParseWatchNamespaces("team-a, team-b,,team-a")).To(Equal([]string{"team-a", "team-b"}))
+		})
+	})
 })
@@ -0,0 +1,167 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DockerHubRegistry is the registry of the images whose name has no registry host
+	DockerHubRegistry = "registry-1.docker.io"
+
+	registryRequestTimeout = 10 * time.Second
+)
+
+// manifestMediaTypes are the manifest formats accepted from the registry, so that
+// both single and multi-architecture images are found
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ParseImageReference splits an image name such as "pravega/pravega:0.7.0" into its
+// registry host, repository and tag or digest. Images without a registry host are
+// looked up on Docker Hub, and Docker Hub images without a namespace in "library".
+func ParseImageReference(image string) (registry, repository, reference string) {
+	name := image
+	reference = "latest"
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, reference = name[:i], name[i+1:]
+	}
+
+	registry = DockerHubRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			registry, name = host, name[i+1:]
+		}
+	}
+	if registry == DockerHubRegistry || registry == "docker.io" || registry == "index.docker.io" {
+		registry = DockerHubRegistry
+		if !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+	}
+	return registry, name, reference
+}
+
+// ImageExists checks, with a manifest HEAD request to its registry, that the image
+// exists. It returns false only if the registry reports the manifest as unknown.
+// Registries requiring credentials cannot be checked and return an error.
+func ImageExists(image string) (bool, error) {
+	registry, repository, reference := ParseImageReference(image)
+	return ManifestExists("https://"+registry, repository, reference)
+}
+
+// ManifestExists sends a manifest HEAD request to the registry at baseURL. If the
+// registry requires a bearer token, an anonymous pull token is requested first.
+func ManifestExists(baseURL string, repository string, reference string) (bool, error) {
+	httpClient := &http.Client{Timeout: registryRequestTimeout}
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", strings.TrimSuffix(baseURL, "/"), repository, reference)
+
+	resp, err := headManifest(httpClient, manifestURL, "")
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := getRegistryToken(httpClient, resp.Header.Get("Www-Authenticate"), repository)
+		if err != nil {
+			return false, err
+		}
+		resp, err = headManifest(httpClient, manifestURL, token)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to get manifest of %s:%s: unexpected status %s", repository, reference, resp.Status)
+	}
+}
+
+func headManifest(httpClient *http.Client, manifestURL string, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest request: %v", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %v", err)
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// getRegistryToken requests an anonymous pull token from the realm of the bearer
+// challenge returned by the registry
+func getRegistryToken(httpClient *http.Client, challenge string, repository string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("failed to get manifest: registry requires credentials")
+	}
+	params := map[string]string{}
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("failed to get registry token: no realm in challenge %q", challenge)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+
+	resp, err := httpClient.Get(params["realm"] + "?" + query.Encode())
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get registry token: unexpected status %s", resp.Status)
+	}
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %v", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", fmt.Errorf("failed to get registry token: empty token")
+	}
+	return body.Token, nil
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("registry", func() {
+
+	Context("ParseImageReference", func() {
+		It("should default to Docker Hub and the library namespace", func() {
+			registry, repository, reference := ParseImageReference("busybox")
+			Ω(registry).To(Equal(DockerHubRegistry))
+			Ω(repository).To(Equal("library/busybox"))
+			Ω(reference).To(Equal("latest"))
+		})
+		It("should parse a Docker Hub image with a tag", func() {
+			registry, repository, reference := ParseImageReference("pravega/pravega:0.7.0")
+			Ω(registry).To(Equal(DockerHubRegistry))
+			Ω(repository).To(Equal("pravega/pravega"))
+			Ω(reference).To(Equal("0.7.0"))
+		})
+		It("should parse a registry host with a port", func() {
+			registry, repository, reference := ParseImageReference("registry.local:5000/team/pravega:0.8.0")
+			Ω(registry).To(Equal("registry.local:5000"))
+			Ω(repository).To(Equal("team/pravega"))
+			Ω(reference).To(Equal("0.8.0"))
+		})
+		It("should parse a digest", func() {
+			registry, repository, reference := ParseImageReference("localhost/pravega@sha256:abcd")
+			Ω(registry).To(Equal("localhost"))
+			Ω(repository).To(Equal("pravega"))
+			Ω(reference).To(Equal("sha256:abcd"))
+		})
+	})
+
+	Context("ManifestExists", func() {
+		var (
+			exists bool
+			err    error
+			server *httptest.Server
+		)
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("registry without authentication", func() {
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if req.Method != http.MethodHead || req.URL.Path != "/v2/pravega/pravega/manifests/0.7.0" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				}))
+			})
+			It("should find the existing tag", func() {
+				exists, err = ManifestExists(server.URL, "pravega/pravega", "0.7.0")
+				Ω(err).Should(BeNil())
+				Ω(exists).To(Equal(true))
+			})
+			It("should not find a mistyped tag", func() {
+				exists, err = ManifestExists(server.URL, "pravega/pravega", "0.7.O")
+				Ω(err).Should(BeNil())
+				Ω(exists).To(Equal(false))
+			})
+		})
+
+		Context("registry requiring a bearer token", func() {
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					switch {
+					case req.URL.Path == "/token":
+						if req.URL.Query().Get("scope") != "repository:pravega/pravega:pull" {
+							w.WriteHeader(http.StatusForbidden)
+							return
+						}
+						fmt.Fprint(w, `{"token": "anonymous"}`)
+					case req.Header.Get("Authorization") != "Bearer anonymous":
+						w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, req.Host))
+						w.WriteHeader(http.StatusUnauthorized)
+					case req.URL.Path == "/v2/pravega/pravega/manifests/0.7.0":
+						w.WriteHeader(http.StatusOK)
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+			})
+			It("should find the existing tag with the token", func() {
+				exists, err = ManifestExists(server.URL, "pravega/pravega", "0.7.0")
+				Ω(err).Should(BeNil())
+				Ω(exists).To(Equal(true))
+			})
+			It("should not find a mistyped tag", func() {
+				exists, err = ManifestExists(server.URL, "pravega/pravega", "0.7.O")
+				Ω(err).Should(BeNil())
+				Ω(exists).To(Equal(false))
+			})
+		})
+
+		Context("registry requiring credentials", func() {
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Www-Authenticate", `Basic realm="registry"`)
+					w.WriteHeader(http.StatusUnauthorized)
+				}))
+				exists, err = ManifestExists(server.URL, "pravega/pravega", "0.7.0")
+			})
+			It("should return error", func() {
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).Should(ContainSubstring("requires credentials"))
+				Ω(exists).To(Equal(false))
+			})
+		})
+	})
+})
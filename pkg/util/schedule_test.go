@@ -0,0 +1,75 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("schedule", func() {
+	// Wednesday
+	base := time.Date(2020, 10, 14, 10, 17, 0, 0, time.UTC)
+
+	Context("ParseSchedule", func() {
+		It("should reject a schedule with a wrong number of fields", func() {
+			_, err := ParseSchedule("0 2 * *")
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("must have 5 fields"))
+		})
+		It("should reject a value out of range", func() {
+			_, err := ParseSchedule("0 24 * * *")
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("hour field \"24\" is out of range 0-23"))
+		})
+		It("should reject an invalid step", func() {
+			_, err := ParseSchedule("*/0 * * * *")
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("invalid step"))
+		})
+		It("should reject a value which is not a number", func() {
+			_, err := ParseSchedule("0 2 * * sat")
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("invalid value in day of week field"))
+		})
+	})
+
+	Context("Next", func() {
+		next := func(expr string) time.Time {
+			s, err := ParseSchedule(expr)
+			Ω(err).Should(BeNil())
+			return s.Next(base)
+		}
+		It("should return the next day of week", func() {
+			Ω(next("0 2 * * 6")).Should(Equal(time.Date(2020, 10, 17, 2, 0, 0, 0, time.UTC)))
+		})
+		It("should treat 7 as Sunday", func() {
+			Ω(next("0 0 * * 7")).Should(Equal(time.Date(2020, 10, 18, 0, 0, 0, 0, time.UTC)))
+		})
+		It("should apply steps", func() {
+			Ω(next("*/15 * * * *")).Should(Equal(time.Date(2020, 10, 14, 10, 30, 0, 0, time.UTC)))
+			Ω(next("5/20 * * * *")).Should(Equal(time.Date(2020, 10, 14, 10, 25, 0, 0, time.UTC)))
+		})
+		It("should apply ranges and lists", func() {
+			Ω(next("30 1-3,22 * * *")).Should(Equal(time.Date(2020, 10, 14, 22, 30, 0, 0, time.UTC)))
+		})
+		It("should match either the day of month or the day of week", func() {
+			Ω(next("0 0 13 * 5")).Should(Equal(time.Date(2020, 10, 16, 0, 0, 0, 0, time.UTC)))
+		})
+		It("should be strictly after the given time", func() {
+			Ω(next("17 10 * * *")).Should(Equal(time.Date(2020, 10, 15, 10, 17, 0, 0, time.UTC)))
+		})
+		It("should return the zero time for a schedule that never matches", func() {
+			Ω(next("0 0 30 2 *").IsZero()).Should(BeTrue())
+		})
+	})
+})
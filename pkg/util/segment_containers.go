@@ -0,0 +1,150 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// SegmentContainerMappingPath is the znode, under the root of the cluster, where the
+// controller stores the segment containers it assigned to each segment store
+const SegmentContainerMappingPath = "cluster/segmentContainerHostMapping"
+
+// GetSegmentContainerMapping reads the segment containers assigned to each segment store
+// by the controller of the cluster from zookeeper. The returned map is keyed by the
+// segment store host, as registered in the cluster, with the list of container ids.
+func GetSegmentContainerMapping(zkUri string, clusterName string) (map[string][]int32, error) {
+	conn, _, err := zk.Connect([]string{zkUri}, time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to zookeeper: %v", err)
+	}
+	defer conn.Close()
+	path := fmt.Sprintf("/%s/%s/%s", PravegaPath, clusterName, SegmentContainerMappingPath)
+	data, _, err := conn.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment container mapping (%s): %v", path, err)
+	}
+	mapping, err := DecodeSegmentContainerMapping(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode segment container mapping (%s): %v", path, err)
+	}
+	return mapping, nil
+}
+
+// DecodeSegmentContainerMapping decodes the HostContainerMap serialized by the controller.
+// Pravega writes it, and each of its hosts, with its versioned serializer: a format
+// version byte, then a revision id byte and the length of the revision, followed by the
+// revision data. The data of the map is the number of hosts, then for each of them the
+// serialized host, whose address comes first, and the set of its container ids.
+func DecodeSegmentContainerMapping(data []byte) (map[string][]int32, error) {
+	r, err := newRevisionReader(data)
+	if err != nil {
+		return nil, err
+	}
+	count, err := r.readCompactInt()
+	if err != nil {
+		return nil, err
+	}
+	mapping := map[string][]int32{}
+	for i := 0; i < count; i++ {
+		length, err := r.readCompactInt()
+		if err != nil {
+			return nil, err
+		}
+		hostData, err := r.read(length)
+		if err != nil {
+			return nil, err
+		}
+		host, err := newRevisionReader(hostData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host: %v", err)
+		}
+		address, err := host.readUTF()
+		if err != nil {
+			return nil, fmt.Errorf("invalid host: %v", err)
+		}
+		size, err := r.readCompactInt()
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < size; j++ {
+			container, err := r.read(4)
+			if err != nil {
+				return nil, err
+			}
+			mapping[address] = append(mapping[address], int32(binary.BigEndian.Uint32(container)))
+		}
+	}
+	return mapping, nil
+}
+
+// revisionReader reads the data of the first revision of an object serialized by the
+// versioned serializer of Pravega. Later revisions only append fields, which are ignored
+type revisionReader struct {
+	data []byte
+}
+
+func newRevisionReader(data []byte) (*revisionReader, error) {
+	// format version and revision id
+	if len(data) < 6 {
+		return nil, fmt.Errorf("truncated header of %d bytes", len(data))
+	}
+	length := int(binary.BigEndian.Uint32(data[2:6]))
+	if length < 0 || length > len(data)-6 {
+		return nil, fmt.Errorf("revision length %d exceeds the %d bytes left", length, len(data)-6)
+	}
+	return &revisionReader{data: data[6 : 6+length]}, nil
+}
+
+func (r *revisionReader) read(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, fmt.Errorf("cannot read %d bytes, %d left", n, len(r.data))
+	}
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value, nil
+}
+
+// readCompactInt reads an int written on 1 to 4 bytes, whose number is set by the 2 high
+// bits of the first one
+func (r *revisionReader) readCompactInt() (int, error) {
+	first, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	rest, err := r.read(int(first[0] >> 6))
+	if err != nil {
+		return 0, err
+	}
+	value := int(first[0] & 0x3F)
+	for _, b := range rest {
+		value = value<<8 | int(b)
+	}
+	return value, nil
+}
+
+// readUTF reads a string written by the writeUTF method of java.io.DataOutput, whose
+// encoding matches UTF-8 for host names and addresses
+func (r *revisionReader) readUTF() (string, error) {
+	length, err := r.read(2)
+	if err != nil {
+		return "", err
+	}
+	value, err := r.read(int(binary.BigEndian.Uint16(length)))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
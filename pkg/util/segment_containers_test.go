@@ -0,0 +1,91 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+package util
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Segment containers", func() {
+	// revision wraps data as written by the versioned serializer of Pravega, with the
+	// format version 0 and the revision 0
+	revision := func(data ...byte) []byte {
+		n := len(data)
+		return append([]byte{0, 0, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+	}
+	host := func(address string) []byte {
+		data := append([]byte{0, byte(len(address))}, address...)
+		// port 12345 and empty endpoint id
+		data = append(data, 0, 0, 0x30, 0x39, 0, 0)
+		return revision(data...)
+	}
+
+	Context("DecodeSegmentContainerMapping", func() {
+		var (
+			mapping map[string][]int32
+			err     error
+		)
+
+		Context("mapping of two hosts", func() {
+			BeforeEach(func() {
+				first := host("10.0.0.1")
+				second := host("10.0.0.2")
+				data := []byte{2, byte(len(first))}
+				data = append(data, first...)
+				data = append(data, 3, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 1, 0x2C)
+				data = append(data, byte(len(second)))
+				data = append(data, second...)
+				data = append(data, 1, 0, 0, 0, 3)
+				mapping, err = DecodeSegmentContainerMapping(revision(data...))
+			})
+			It("should return the containers of each host", func() {
+				Ω(err).Should(BeNil())
+				Ω(mapping).To(HaveLen(2))
+				Ω(mapping["10.0.0.1"]).To(Equal([]int32{0, 1, 300}))
+				Ω(mapping["10.0.0.2"]).To(Equal([]int32{3}))
+			})
+		})
+
+		Context("empty mapping", func() {
+			BeforeEach(func() {
+				mapping, err = DecodeSegmentContainerMapping(revision(0))
+			})
+			It("should return no host", func() {
+				Ω(err).Should(BeNil())
+				Ω(mapping).To(BeEmpty())
+			})
+		})
+
+		Context("truncated mapping", func() {
+			BeforeEach(func() {
+				first := host("10.0.0.1")
+				data := []byte{1, byte(len(first))}
+				data = append(data, first...)
+				data = append(data, 3, 0, 0, 0, 0)
+				mapping, err = DecodeSegmentContainerMapping(revision(data...))
+			})
+			It("should return error", func() {
+				Ω(err).ShouldNot(BeNil())
+				Ω(mapping).To(BeNil())
+			})
+		})
+	})
+
+	Context("GetSegmentContainerMapping", func() {
+		var err error
+		BeforeEach(func() {
+			_, err = GetSegmentContainerMapping("zookeeper-client:2181", "pravega")
+		})
+		It("should return error without zookeeper", func() {
+			Ω(err).ShouldNot(BeNil())
+		})
+	})
+})
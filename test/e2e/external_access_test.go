@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package e2e
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	framework "github.com/operator-framework/operator-sdk/pkg/test"
+	pravega_e2eutil "github.com/pravega/pravega-operator/pkg/test/e2e/e2eutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Test that the segment store and controller endpoints are listed in the status
+// once the load balancers are provisioned, and that data can be written and read
+// through them
+func testExternalAccessEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doCleanup := true
+	ctx := framework.NewTestCtx(t)
+	defer func() {
+		if doCleanup {
+			ctx.Cleanup()
+		}
+	}()
+
+	namespace, err := ctx.GetNamespace()
+	g.Expect(err).NotTo(HaveOccurred())
+	f := framework.Global
+
+	//creating the setup for running the test
+	err = pravega_e2eutil.InitialSetup(t, f, ctx, namespace)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cluster := pravega_e2eutil.NewDefaultCluster(namespace)
+	cluster.WithDefaults()
+	cluster.Spec.ExternalAccess.Enabled = true
+	cluster.Spec.ExternalAccess.Type = corev1.ServiceTypeLoadBalancer
+
+	pravega, err := pravega_e2eutil.CreatePravegaCluster(t, f, ctx, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// A default Pravega cluster should have 2 pods: 1 controller, 1 segment store
+	podSize := 2
+	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	endpoints, err := pravega_e2eutil.WaitForSegmentStoreEndpoints(t, f, ctx, pravega, int(pravega.Spec.Pravega.SegmentStoreReplicas))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoints).To(HaveKey(pravega.StatefulSetNameForSegmentstore() + "-0"))
+
+	_, err = pravega_e2eutil.WaitForControllerEndpoint(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Check that the cluster can be reached through the load balancers
+	err = pravega_e2eutil.WriteAndReadDataExternal(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Delete cluster
+	err = pravega_e2eutil.DeletePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// No need to do cleanup since the cluster CR has already been deleted
+	doCleanup = false
+
+	err = pravega_e2eutil.WaitForPravegaClusterToTerminate(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The load balancer services are deleted along with the cluster
+	err = pravega_e2eutil.WaitForPravegaClusterServicesToTerminate(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+}
@@ -57,11 +57,16 @@ func testPravegaCluster(t *testing.T) {
 	}
 
 	testFuncs := map[string]func(t *testing.T){
-		"testCreateRecreateCluster": testCreateRecreateCluster,
-		"testScaleCluster":          testScaleCluster,
-		"testUpgradeCluster":        testUpgradeCluster,
-		"testWebhook":               testWebhook,
-		"testCMUpgradeCluster":      testCMUpgradeCluster,
+		"testCreateRecreateCluster":   testCreateRecreateCluster,
+		"testScaleCluster":            testScaleCluster,
+		"testUpgradeCluster":          testUpgradeCluster,
+		"testWebhook":                 testWebhook,
+		"testCMUpgradeCluster":        testCMUpgradeCluster,
+		"testRollbackCluster":         testRollbackCluster,
+		"testExternalAccessEndpoints": testExternalAccessEndpoints,
+		"testScalingStalled":          testScalingStalled,
+		"testTLSCluster":              testTLSCluster,
+		"testStuckTerminatingPod":     testStuckTerminatingPod,
 	}
 
 	for name, f := range testFuncs {
@@ -0,0 +1,113 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package e2e
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	framework "github.com/operator-framework/operator-sdk/pkg/test"
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	pravega_e2eutil "github.com/pravega/pravega-operator/pkg/test/e2e/e2eutil"
+)
+
+func testRollbackCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doCleanup := true
+	ctx := framework.NewTestCtx(t)
+	defer func() {
+		if doCleanup {
+			ctx.Cleanup()
+		}
+	}()
+
+	namespace, err := ctx.GetNamespace()
+	g.Expect(err).NotTo(HaveOccurred())
+	f := framework.Global
+
+	//creating the setup for running the test
+	err = pravega_e2eutil.InitialSetup(t, f, ctx, namespace)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cluster := pravega_e2eutil.NewDefaultCluster(namespace)
+
+	cluster.WithDefaults()
+	initialVersion := "0.6.1"
+	upgradeVersion := "0.7.0"
+	cluster.Spec.Version = initialVersion
+	cluster.Spec.Pravega.Image = &api.ImageSpec{
+		Repository: "pravega/pravega",
+		PullPolicy: "IfNotPresent",
+	}
+
+	pravega, err := pravega_e2eutil.CreatePravegaCluster(t, f, ctx, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// A default Pravega cluster should have 2 pods:  1 controller, 1 segment store
+	podSize := 2
+	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// This is to get the latest Pravega cluster object
+	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	controllerCm, err := pravega_e2eutil.GetConfigMap(t, f, ctx, pravega, pravega.ConfigMapNameForController())
+	g.Expect(err).NotTo(HaveOccurred())
+	segmentStoreCm, err := pravega_e2eutil.GetConfigMap(t, f, ctx, pravega, pravega.ConfigMapNameForSegmentstore())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The image cannot be pulled, so that the upgrade fails, and the options
+	// of the upgrade are written to the ConfigMaps
+	pravega.Spec.Version = upgradeVersion
+	pravega.Spec.Pravega.Image.Repository = "pravega/pravega-nonexistent"
+	pravega.Spec.Pravega.Options["bookkeeper.bkAckQuorumSize"] = "2"
+
+	err = pravega_e2eutil.UpdatePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = pravega_e2eutil.WaitForPravegaClusterToFailUpgrade(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Rolling back to the previous spec
+	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pravega.Spec.Version = initialVersion
+	pravega.Spec.Pravega.Image.Repository = "pravega/pravega"
+	delete(pravega.Spec.Pravega.Options, "bookkeeper.bkAckQuorumSize")
+
+	err = pravega_e2eutil.UpdatePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = pravega_e2eutil.WaitForPravegaClusterToRollback(t, f, ctx, pravega, initialVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The ConfigMaps must have reverted to the ones of the initial version
+	foundControllerCm, err := pravega_e2eutil.GetConfigMap(t, f, ctx, pravega, pravega.ConfigMapNameForController())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(foundControllerCm.Data).To(Equal(controllerCm.Data))
+
+	foundSegmentStoreCm, err := pravega_e2eutil.GetConfigMap(t, f, ctx, pravega, pravega.ConfigMapNameForSegmentstore())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(foundSegmentStoreCm.Data).To(Equal(segmentStoreCm.Data))
+
+	// Delete cluster
+	err = pravega_e2eutil.DeletePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// No need to do cleanup since the cluster CR has already been deleted
+	doCleanup = false
+
+	err = pravega_e2eutil.WaitForPravegaClusterToTerminate(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+}
@@ -48,32 +48,26 @@ func testScaleCluster(t *testing.T) {
 	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	// This is to get the latest Pravega cluster object
-	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
-	g.Expect(err).NotTo(HaveOccurred())
-
 	// Scale up Pravega cluster, increase segment store size by 1
-	pravega.Spec.Pravega.SegmentStoreReplicas = 2
-	podSize = 3
-
-	err = pravega_e2eutil.UpdatePravegaCluster(t, f, ctx, pravega)
+	err = pravega_e2eutil.ScaleSegmentStore(t, f, ctx, pravega, 2)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
+	// Scale down Pravega cluster back to default
+	err = pravega_e2eutil.ScaleSegmentStore(t, f, ctx, pravega, 1)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	// This is to get the latest Pravega cluster object
-	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
+	// Scale up the segment store through the scale subresource, as kubectl scale does
+	err = pravega_e2eutil.ScaleSegmentStoreSubresource(t, f, ctx, pravega, 2)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	// Scale down Pravega cluster back to default
-	pravega.Spec.Pravega.SegmentStoreReplicas = 1
-	podSize = 2
+	err = pravega_e2eutil.WaitForSegmentStoreScaleStatus(t, f, ctx, pravega, 2)
+	g.Expect(err).NotTo(HaveOccurred())
 
-	err = pravega_e2eutil.UpdatePravegaCluster(t, f, ctx, pravega)
+	// Scale down the segment store back to default through the scale subresource
+	err = pravega_e2eutil.ScaleSegmentStoreSubresource(t, f, ctx, pravega, 1)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
+	err = pravega_e2eutil.WaitForSegmentStoreScaleStatus(t, f, ctx, pravega, 1)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	// Delete cluster
@@ -0,0 +1,69 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package e2e
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	framework "github.com/operator-framework/operator-sdk/pkg/test"
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	pravega_e2eutil "github.com/pravega/pravega-operator/pkg/test/e2e/e2eutil"
+)
+
+func testScalingStalled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doCleanup := true
+	ctx := framework.NewTestCtx(t)
+	defer func() {
+		if doCleanup {
+			ctx.Cleanup()
+		}
+	}()
+
+	namespace, err := ctx.GetNamespace()
+	g.Expect(err).NotTo(HaveOccurred())
+	f := framework.Global
+
+	//creating the setup for running the test
+	err = pravega_e2eutil.InitialSetup(t, f, ctx, namespace)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The journal claims of the segment stores reference a storage class which does
+	// not exist, so they stay pending and the segment stores never start
+	timeout := int32(60)
+	cluster := pravega_e2eutil.NewDefaultCluster(namespace)
+	cluster.WithDefaults()
+	cluster.Spec.ScalingStallTimeoutSeconds = &timeout
+	cluster.Spec.Pravega.SegmentStoreJournalVolume = &api.JournalVolumeSpec{
+		StorageClassName: "e2e-missing-storage-class",
+		Size:             "1Gi",
+	}
+
+	pravega, err := pravega_e2eutil.CreatePravegaCluster(t, f, ctx, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	condition, err := pravega_e2eutil.WaitForPravegaClusterScalingToStall(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition.Reason).To(Equal(api.ClaimPendingReason))
+	g.Expect(condition.Message).To(ContainSubstring("pending pvcs: journal-"))
+
+	// Delete cluster
+	err = pravega_e2eutil.DeletePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// No need to do cleanup since the cluster CR has already been deleted
+	doCleanup = false
+
+	err = pravega_e2eutil.WaitForPravegaClusterToTerminate(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+}
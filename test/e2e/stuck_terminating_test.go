@@ -0,0 +1,102 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package e2e
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	framework "github.com/operator-framework/operator-sdk/pkg/test"
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	pravega_e2eutil "github.com/pravega/pravega-operator/pkg/test/e2e/e2eutil"
+)
+
+// stuckFinalizer keeps the segment store pod terminating once the upgrade deletes it,
+// as a volume which fails to detach would
+const stuckFinalizer = "e2e.pravega.io/stuck"
+
+func testStuckTerminatingPod(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doCleanup := true
+	ctx := framework.NewTestCtx(t)
+	defer func() {
+		if doCleanup {
+			ctx.Cleanup()
+		}
+	}()
+
+	namespace, err := ctx.GetNamespace()
+	g.Expect(err).NotTo(HaveOccurred())
+	f := framework.Global
+
+	//creating the setup for running the test
+	err = pravega_e2eutil.InitialSetup(t, f, ctx, namespace)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	cluster := pravega_e2eutil.NewDefaultCluster(namespace)
+
+	cluster.WithDefaults()
+	initialVersion := "0.7.0"
+	upgradeVersion := "0.7.1"
+	timeout := int32(60)
+	cluster.Spec.Version = initialVersion
+	cluster.Spec.UpgradeConfig = &api.UpgradeConfig{TerminatingPodTimeoutSeconds: &timeout}
+	cluster.Spec.Pravega.Image = &api.ImageSpec{
+		Repository: "pravega/pravega",
+		PullPolicy: "IfNotPresent",
+	}
+
+	pravega, err := pravega_e2eutil.CreatePravegaCluster(t, f, ctx, cluster)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// A default Pravega cluster should have 2 pods:  1 controller, 1 segment store
+	podSize := 2
+	err = pravega_e2eutil.WaitForPravegaClusterToBecomeReady(t, f, ctx, pravega, podSize)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// This is to get the latest Pravega cluster object
+	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	segmentStorePod := pravega.StatefulSetNameForSegmentstore() + "-0"
+	err = pravega_e2eutil.SetPodFinalizers(t, f, namespace, segmentStorePod, []string{stuckFinalizer})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	pravega.Spec.Version = upgradeVersion
+	err = pravega_e2eutil.UpdatePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The upgrade deletes the segment store pod, which stays terminating until the
+	// finalizer is removed
+	condition, err := pravega_e2eutil.WaitForPravegaClusterPodStuckTerminating(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(condition.Message).To(ContainSubstring(segmentStorePod))
+
+	err = pravega_e2eutil.SetPodFinalizers(t, f, namespace, segmentStorePod, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = pravega_e2eutil.WaitForPravegaClusterPodStuckTerminatingToClear(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	err = pravega_e2eutil.WaitForPravegaClusterToUpgrade(t, f, ctx, pravega, upgradeVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Delete cluster
+	err = pravega_e2eutil.DeletePravegaCluster(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// No need to do cleanup since the cluster CR has already been deleted
+	doCleanup = false
+
+	err = pravega_e2eutil.WaitForPravegaClusterToTerminate(t, f, ctx, pravega)
+	g.Expect(err).NotTo(HaveOccurred())
+}
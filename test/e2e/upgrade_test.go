@@ -71,6 +71,9 @@ func testUpgradeCluster(t *testing.T) {
 	err = pravega_e2eutil.WaitForPravegaClusterToUpgrade(t, f, ctx, pravega, upgradeVersion)
 	g.Expect(err).NotTo(HaveOccurred())
 
+	err = pravega_e2eutil.WaitForPravegaClusterMembersToReachVersion(t, f, ctx, pravega, upgradeVersion)
+	g.Expect(err).NotTo(HaveOccurred())
+
 	// This is to get the latest Pravega cluster object
 	pravega, err = pravega_e2eutil.GetPravegaCluster(t, f, ctx, pravega)
 	g.Expect(err).NotTo(HaveOccurred())